@@ -10,7 +10,9 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/oklog/ulid/v2"
 	"github.com/urfave/cli/v2"
 
 	"github.com/hpungsan/moss/internal/config"
@@ -31,15 +33,26 @@ func newCLIApp(db *sql.DB, cfg *config.Config) *cli.App {
 			fetchCmd(db, cfg),
 			updateCmd(db, cfg),
 			deleteCmd(db),
-			listCmd(db),
-			inventoryCmd(db),
-			latestCmd(db),
+			listCmd(db, cfg),
+			inventoryCmd(db, cfg),
+			searchCmd(db, cfg),
+			latestCmd(db, cfg),
 			exportCmd(db, cfg),
 			importCmd(db, cfg),
-			purgeCmd(db),
+			purgeCmd(db, cfg),
+			reindexCmd(db),
 			toolsCmd(cfg),
 			serveCmd(db, cfg),
 		},
+		// Each --tag value is one literal tag; only --tags splits on commas
+		DisableSliceFlagSeparator: true,
+	}
+	// Every command that prints JSON accepts --compact-json. It is a per-command
+	// flag rather than a global one so isCLIMode can keep keying off the first argument.
+	for _, cmd := range app.Commands {
+		if cmd.Name != "serve" {
+			cmd.Flags = append(cmd.Flags, compactJSONFlag())
+		}
 	}
 	// Disable default exit error handler to allow proper error return in tests
 	app.ExitErrHandler = func(_ *cli.Context, _ error) {}
@@ -50,24 +63,24 @@ func newCLIApp(db *sql.DB, cfg *config.Config) *cli.App {
 func storeCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 	return &cli.Command{
 		Name:  "store",
-		Usage: "Store a new capsule (reads capsule_text from stdin)",
+		Usage: "Store a new capsule (reads capsule_text from stdin or --file)",
 		Flags: []cli.Flag{
 			&cli.StringFlag{Name: "workspace", Aliases: []string{"w"}, Value: "default", Usage: "Workspace name"},
 			&cli.StringFlag{Name: "name", Aliases: []string{"n"}, Usage: "Capsule name (optional)"},
 			&cli.StringFlag{Name: "title", Aliases: []string{"t"}, Usage: "Capsule title (defaults to name)"},
 			&cli.StringFlag{Name: "tags", Usage: "Comma-separated tags"},
-			&cli.StringFlag{Name: "mode", Aliases: []string{"m"}, Value: "error", Usage: "Collision mode: error|replace"},
+			&cli.StringSliceFlag{Name: "tag", Usage: "Single tag, taken literally (repeatable; merged with --tags)"},
+			&cli.StringFlag{Name: "mode", Aliases: []string{"m"}, Value: "error", Usage: "Collision mode: error|replace|rename"},
 			&cli.BoolFlag{Name: "allow-thin", Usage: "Allow capsules without all required sections"},
+			fileFlag(),
 		},
 		Action: func(c *cli.Context) error {
-			// Require stdin input
-			if !stdinHasData() {
-				return outputError(errors.NewInvalidRequest("capsule_text must be piped via stdin"))
-			}
-
-			capsuleText, err := readStdin(cfg.CapsuleMaxChars)
+			capsuleText, ok, err := readCapsuleText(c, cfg)
 			if err != nil {
-				return outputError(errors.NewInvalidRequest(err.Error()))
+				return outputError(err)
+			}
+			if !ok {
+				return outputError(errors.NewInvalidRequest("capsule_text must be piped via stdin or given with --file"))
 			}
 			if capsuleText == "" {
 				return outputError(errors.NewInvalidRequest("capsule_text is required"))
@@ -86,8 +99,8 @@ func storeCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 			if title := c.String("title"); title != "" {
 				input.Title = &title
 			}
-			if tags := c.String("tags"); tags != "" {
-				input.Tags = parseTags(tags)
+			if tags := collectTags(c); len(tags) > 0 {
+				input.Tags = tags
 			}
 
 			output, err := ops.Store(c.Context, db, cfg, input)
@@ -95,13 +108,13 @@ func storeCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 				return outputError(err)
 			}
 
-			return outputJSON(output)
+			return outputJSON(c, output)
 		},
 	}
 }
 
 // fetchCmd creates the fetch command.
-func fetchCmd(db *sql.DB, _ *config.Config) *cli.Command {
+func fetchCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 	return &cli.Command{
 		Name:      "fetch",
 		Usage:     "Fetch a capsule by ID or name",
@@ -109,6 +122,9 @@ func fetchCmd(db *sql.DB, _ *config.Config) *cli.Command {
 		Flags: append(addressingFlags(),
 			&cli.BoolFlag{Name: "include-deleted", Usage: "Include soft-deleted capsules"},
 			&cli.BoolFlag{Name: "no-text", Usage: "Exclude capsule_text from output"},
+			&cli.BoolFlag{Name: "prefix", Usage: "Treat id as a prefix (implied for ids shorter than a full ULID)"},
+			&cli.StringFlag{Name: "fields", Usage: "Comma-separated output fields to return (id is always included)"},
+			&cli.StringFlag{Name: "fallback", Usage: "Comma-separated workspaces to try in order if --name isn't in --workspace"},
 		),
 		Action: func(c *cli.Context) error {
 			addr, err := parseAddressing(c)
@@ -117,10 +133,13 @@ func fetchCmd(db *sql.DB, _ *config.Config) *cli.Command {
 			}
 
 			input := ops.FetchInput{
-				ID:             addr.ID,
-				Workspace:      addr.Workspace,
-				Name:           addr.Name,
-				IncludeDeleted: c.Bool("include-deleted"),
+				ID:                 addr.ID,
+				Workspace:          addr.Workspace,
+				Name:               addr.Name,
+				IncludeDeleted:     includeDeleted(c, cfg),
+				Prefix:             c.Bool("prefix") || (addr.ID != "" && len(addr.ID) < ulid.EncodedSize),
+				Fields:             parseTags(c.String("fields")),
+				FallbackWorkspaces: parseTags(c.String("fallback")),
 			}
 
 			if c.Bool("no-text") {
@@ -133,7 +152,7 @@ func fetchCmd(db *sql.DB, _ *config.Config) *cli.Command {
 				return outputError(err)
 			}
 
-			return outputJSON(output)
+			return outputJSON(c, output)
 		},
 	}
 }
@@ -142,12 +161,14 @@ func fetchCmd(db *sql.DB, _ *config.Config) *cli.Command {
 func updateCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 	return &cli.Command{
 		Name:      "update",
-		Usage:     "Update an existing capsule (optionally reads capsule_text from stdin)",
+		Usage:     "Update an existing capsule (optionally reads capsule_text from stdin or --file)",
 		ArgsUsage: "[id]",
 		Flags: append(addressingFlags(),
 			&cli.StringFlag{Name: "title", Aliases: []string{"t"}, Usage: "New title"},
 			&cli.StringFlag{Name: "tags", Usage: "New comma-separated tags"},
+			&cli.StringSliceFlag{Name: "tag", Usage: "New single tag, taken literally (repeatable; merged with --tags)"},
 			&cli.BoolFlag{Name: "allow-thin", Usage: "Allow capsules without all required sections"},
+			fileFlag(),
 		),
 		Action: func(c *cli.Context) error {
 			addr, err := parseAddressing(c)
@@ -162,22 +183,20 @@ func updateCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 				AllowThin: c.Bool("allow-thin"),
 			}
 
-			// Read capsule_text from stdin if piped
-			if stdinHasData() {
-				text, err := readStdin(cfg.CapsuleMaxChars)
-				if err != nil {
-					return outputError(errors.NewInvalidRequest(err.Error()))
-				}
-				if text != "" {
-					input.CapsuleText = &text
-				}
+			// Read capsule_text from --file or stdin if given
+			text, ok, err := readCapsuleText(c, cfg)
+			if err != nil {
+				return outputError(err)
+			}
+			if ok && text != "" {
+				input.CapsuleText = &text
 			}
 
 			if title := c.String("title"); title != "" {
 				input.Title = &title
 			}
-			if c.IsSet("tags") {
-				tags := parseTags(c.String("tags"))
+			if c.IsSet("tags") || c.IsSet("tag") {
+				tags := collectTags(c)
 				input.Tags = &tags
 			}
 
@@ -186,7 +205,7 @@ func updateCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 				return outputError(err)
 			}
 
-			return outputJSON(output)
+			return outputJSON(c, output)
 		},
 	}
 }
@@ -215,13 +234,13 @@ func deleteCmd(db *sql.DB) *cli.Command {
 				return outputError(err)
 			}
 
-			return outputJSON(output)
+			return outputJSON(c, output)
 		},
 	}
 }
 
 // listCmd creates the list command.
-func listCmd(db *sql.DB) *cli.Command {
+func listCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 	return &cli.Command{
 		Name:  "list",
 		Usage: "List capsules in a workspace",
@@ -240,7 +259,7 @@ func listCmd(db *sql.DB) *cli.Command {
 				Workspace:      c.String("workspace"),
 				Limit:          c.Int("limit"),
 				Offset:         c.Int("offset"),
-				IncludeDeleted: c.Bool("include-deleted"),
+				IncludeDeleted: includeDeleted(c, cfg),
 			}
 
 			output, err := ops.List(c.Context, db, input)
@@ -248,13 +267,13 @@ func listCmd(db *sql.DB) *cli.Command {
 				return outputError(err)
 			}
 
-			return outputJSON(output)
+			return outputJSON(c, output)
 		},
 	}
 }
 
 // inventoryCmd creates the inventory command.
-func inventoryCmd(db *sql.DB) *cli.Command {
+func inventoryCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 	return &cli.Command{
 		Name:  "inventory",
 		Usage: "List all capsules across workspaces with optional filters",
@@ -274,10 +293,11 @@ func inventoryCmd(db *sql.DB) *cli.Command {
 			input := ops.InventoryInput{
 				Limit:          c.Int("limit"),
 				Offset:         c.Int("offset"),
-				IncludeDeleted: c.Bool("include-deleted"),
+				IncludeDeleted: includeDeleted(c, cfg),
 				Workspace:      optionalString(c, "workspace"),
 				Tag:            optionalString(c, "tag"),
 				NamePrefix:     optionalString(c, "name-prefix"),
+				NormalizeTags:  cfg.NormalizeTags,
 			}
 
 			output, err := ops.Inventory(c.Context, db, input)
@@ -285,13 +305,74 @@ func inventoryCmd(db *sql.DB) *cli.Command {
 				return outputError(err)
 			}
 
-			return outputJSON(output)
+			return outputJSON(c, output)
+		},
+	}
+}
+
+// searchCmd creates the search command.
+func searchCmd(db *sql.DB, cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "search",
+		Usage:     "Full-text search across capsules",
+		ArgsUsage: "<query>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "workspace", Aliases: []string{"w"}, Usage: "Filter by workspace"},
+			&cli.StringFlag{Name: "tag", Usage: "Filter by tag"},
+			&cli.StringFlag{Name: "sort", Value: "relevance", Usage: "Order: relevance|updated_at"},
+			&cli.IntFlag{Name: "limit", Aliases: []string{"l"}, Value: ops.DefaultSearchLimit, Usage: "Maximum items to return (with --stream: all unless set)"},
+			&cli.IntFlag{Name: "offset", Aliases: []string{"o"}, Value: 0, Usage: "Items to skip"},
+			&cli.BoolFlag{Name: "include-deleted", Usage: "Include soft-deleted capsules"},
+			&cli.BoolFlag{Name: "stream", Usage: "Print one JSON result per line as results are read, instead of one buffered page"},
+		},
+		Action: func(c *cli.Context) error {
+			if err := validatePagination(c); err != nil {
+				return outputError(err)
+			}
+
+			input := ops.SearchInput{
+				Query:          strings.Join(c.Args().Slice(), " "),
+				Workspace:      optionalString(c, "workspace"),
+				Tag:            optionalString(c, "tag"),
+				Sort:           c.String("sort"),
+				Limit:          c.Int("limit"),
+				Offset:         c.Int("offset"),
+				IncludeDeleted: includeDeleted(c, cfg),
+				MinTermLen:     cfg.MinSearchTermLen,
+				NormalizeTags:  cfg.NormalizeTags,
+			}
+
+			if c.Bool("stream") {
+				if !c.IsSet("limit") {
+					input.Limit = 0
+				}
+				// JSONL: always compact, written as each row is scanned
+				err := ops.SearchStream(c.Context, db, input, func(item ops.SearchResultItem) error {
+					data, err := json.Marshal(item)
+					if err != nil {
+						return err
+					}
+					_, err = os.Stdout.Write(append(data, '\n'))
+					return err
+				})
+				if err != nil {
+					return outputError(err)
+				}
+				return nil
+			}
+
+			output, err := ops.Search(c.Context, db, input)
+			if err != nil {
+				return outputError(err)
+			}
+
+			return outputJSON(c, output)
 		},
 	}
 }
 
 // latestCmd creates the latest command.
-func latestCmd(db *sql.DB) *cli.Command {
+func latestCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 	return &cli.Command{
 		Name:  "latest",
 		Usage: "Get the most recently updated capsule in a workspace",
@@ -299,11 +380,13 @@ func latestCmd(db *sql.DB) *cli.Command {
 			&cli.StringFlag{Name: "workspace", Aliases: []string{"w"}, Value: "default", Usage: "Workspace name"},
 			&cli.BoolFlag{Name: "include-text", Usage: "Include capsule_text in output"},
 			&cli.BoolFlag{Name: "include-deleted", Usage: "Include soft-deleted capsules"},
+			&cli.StringFlag{Name: "by", Value: "updated", Usage: "Latest by: updated|created"},
 		},
 		Action: func(c *cli.Context) error {
 			input := ops.LatestInput{
 				Workspace:      c.String("workspace"),
-				IncludeDeleted: c.Bool("include-deleted"),
+				IncludeDeleted: includeDeleted(c, cfg),
+				By:             c.String("by"),
 			}
 
 			if c.Bool("include-text") {
@@ -316,7 +399,7 @@ func latestCmd(db *sql.DB) *cli.Command {
 				return outputError(err)
 			}
 
-			return outputJSON(output)
+			return outputJSON(c, output)
 		},
 	}
 }
@@ -325,17 +408,29 @@ func latestCmd(db *sql.DB) *cli.Command {
 func exportCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 	return &cli.Command{
 		Name:  "export",
-		Usage: "Export capsules to a JSONL file",
+		Usage: "Export capsules to a JSONL, JSON, or CSV file",
 		Flags: []cli.Flag{
-			&cli.StringFlag{Name: "path", Aliases: []string{"p"}, Usage: "Export file path (default: ~/.moss/exports/<workspace>-<timestamp>.jsonl)"},
+			&cli.StringFlag{Name: "path", Aliases: []string{"p"}, Usage: "Export file path (default: ~/.moss/exports/<workspace>-<timestamp>.jsonl, or .json / .csv)"},
 			&cli.StringFlag{Name: "workspace", Aliases: []string{"w"}, Usage: "Filter by workspace"},
+			&cli.StringSliceFlag{Name: "id", Usage: "Export only this capsule ID (repeatable, max 500)"},
 			&cli.BoolFlag{Name: "include-deleted", Usage: "Include soft-deleted capsules"},
+			&cli.StringFlag{Name: "format", Aliases: []string{"f"}, Value: "jsonl", Usage: "File layout: jsonl|json|csv"},
+			&cli.StringFlag{Name: "order-by", Value: "created", Usage: "Record order: created|workspace"},
+			&cli.BoolFlag{Name: "compact", Usage: "Omit fields recomputed on import (jsonl/json only)"},
+			&cli.StringFlag{Name: "resume-from", Usage: "Export only capsules after this ID (a previous resume_cursor; created order only)"},
+			&cli.BoolFlag{Name: "append", Usage: "Append to --path in place instead of replacing it (jsonl/csv only)"},
 		},
 		Action: func(c *cli.Context) error {
 			input := ops.ExportInput{
 				Path:           c.String("path"),
 				IncludeDeleted: c.Bool("include-deleted"),
 				Workspace:      optionalString(c, "workspace"),
+				IDs:            c.StringSlice("id"),
+				Format:         ops.ExportFormat(c.String("format")),
+				OrderBy:        ops.ExportOrder(c.String("order-by")),
+				Compact:        c.Bool("compact"),
+				ResumeFrom:     optionalString(c, "resume-from"),
+				Append:         c.Bool("append"),
 			}
 
 			output, err := ops.Export(c.Context, db, cfg, input)
@@ -343,7 +438,7 @@ func exportCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 				return outputError(err)
 			}
 
-			return outputJSON(output)
+			return outputJSON(c, output)
 		},
 	}
 }
@@ -354,8 +449,8 @@ func importCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 		Name:  "import",
 		Usage: "Import capsules from a JSONL file",
 		Flags: []cli.Flag{
-			&cli.StringFlag{Name: "path", Aliases: []string{"p"}, Required: true, Usage: "Import file path"},
-			&cli.StringFlag{Name: "mode", Aliases: []string{"m"}, Value: "error", Usage: "Collision mode: error|replace|rename"},
+			&cli.StringFlag{Name: "path", Aliases: []string{"p"}, Required: true, Usage: "Import file path (.jsonl or .json)"},
+			&cli.StringFlag{Name: "mode", Aliases: []string{"m"}, Value: "error", Usage: "Collision mode: error|replace|rename|skip|merge"},
 		},
 		Action: func(c *cli.Context) error {
 			input := ops.ImportInput{
@@ -368,13 +463,13 @@ func importCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 				return outputError(err)
 			}
 
-			return outputJSON(output)
+			return outputJSON(c, output)
 		},
 	}
 }
 
 // purgeCmd creates the purge command.
-func purgeCmd(db *sql.DB) *cli.Command {
+func purgeCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 	return &cli.Command{
 		Name:  "purge",
 		Usage: "Permanently delete soft-deleted capsules",
@@ -385,6 +480,7 @@ func purgeCmd(db *sql.DB) *cli.Command {
 		Action: func(c *cli.Context) error {
 			input := ops.PurgeInput{
 				Workspace: optionalString(c, "workspace"),
+				Archive:   cfg.ArchiveOnPurge,
 			}
 
 			if olderThan := c.String("older-than"); olderThan != "" {
@@ -400,7 +496,30 @@ func purgeCmd(db *sql.DB) *cli.Command {
 				return outputError(err)
 			}
 
-			return outputJSON(output)
+			return outputJSON(c, output)
+		},
+	}
+}
+
+// reindexCmd creates the reindex command.
+func reindexCmd(db *sql.DB) *cli.Command {
+	return &cli.Command{
+		Name:  "reindex",
+		Usage: "Check the search index and rebuild it if out of sync",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "check-only", Usage: "Only report consistency; never rebuild"},
+			&cli.BoolFlag{Name: "force", Usage: "Rebuild even if the index looks consistent"},
+		},
+		Action: func(c *cli.Context) error {
+			output, err := ops.Reindex(c.Context, db, ops.ReindexInput{
+				CheckOnly: c.Bool("check-only"),
+				Force:     c.Bool("force"),
+			})
+			if err != nil {
+				return outputError(err)
+			}
+
+			return outputJSON(c, output)
 		},
 	}
 }
@@ -472,7 +591,7 @@ func toolsCmd(cfg *config.Config) *cli.Command {
 				Disabled: len(tools) - enabledCount,
 			}
 
-			return outputJSON(output)
+			return outputJSON(c, output)
 		},
 	}
 }
@@ -497,17 +616,20 @@ func serveCmd(db *sql.DB, cfg *config.Config) *cli.Command {
 			}
 
 			srv := web.NewServer(db, cfg, Version, bind, port)
-			return web.Run(srv, bind)
+			return web.Run(srv, bind, time.Duration(cfg.ShutdownTimeout)*time.Second)
 		},
 	}
 }
 
 // Helper functions
 
-// outputJSON marshals result to stdout as JSON.
-func outputJSON(v any) error {
+// outputJSON writes result to stdout as JSON, indented with two spaces, or on
+// one line when --compact-json is set.
+func outputJSON(c *cli.Context, v any) error {
 	enc := json.NewEncoder(os.Stdout)
-	enc.SetIndent("", "  ")
+	if !c.Bool("compact-json") {
+		enc.SetIndent("", "  ")
+	}
 	return enc.Encode(v)
 }
 
@@ -561,6 +683,42 @@ func stdinHasData() bool {
 	return (stat.Mode() & os.ModeCharDevice) == 0
 }
 
+// compactJSONFlag returns the --compact-json flag added to every JSON-printing
+// command. Not --compact: export already uses that for its record format.
+func compactJSONFlag() cli.Flag {
+	return &cli.BoolFlag{Name: "compact-json", Usage: "Print JSON output on one line instead of indented"}
+}
+
+// fileFlag returns the --file flag shared by store and update.
+func fileFlag() cli.Flag {
+	return &cli.StringFlag{Name: "file", Usage: "Read capsule_text from a .md/.txt file (same path rules as import)"}
+}
+
+// readCapsuleText returns capsule_text from --file or piped stdin; ok is false
+// when neither was given. Supplying both is ambiguous and rejected.
+func readCapsuleText(c *cli.Context, cfg *config.Config) (text string, ok bool, err error) {
+	path := c.String("file")
+	piped := stdinHasData()
+
+	switch {
+	case path != "" && piped:
+		return "", false, errors.NewInvalidRequest("--file and piped stdin are mutually exclusive")
+	case path != "":
+		text, err := ops.ReadCapsuleFile(path, cfg.CapsuleMaxChars, cfg)
+		if err != nil {
+			return "", false, err
+		}
+		return text, true, nil
+	case piped:
+		text, err := readStdin(cfg.CapsuleMaxChars)
+		if err != nil {
+			return "", false, errors.NewInvalidRequest(err.Error())
+		}
+		return text, true, nil
+	}
+	return "", false, nil
+}
+
 // validatePagination checks that limit and offset are non-negative.
 func validatePagination(c *cli.Context) error {
 	if c.Int("limit") < 0 {
@@ -572,6 +730,15 @@ func validatePagination(c *cli.Context) error {
 	return nil
 }
 
+// includeDeleted returns --include-deleted if it was passed (including
+// --include-deleted=false), else config.DefaultIncludeDeleted.
+func includeDeleted(c *cli.Context, cfg *config.Config) bool {
+	if c.IsSet("include-deleted") {
+		return c.Bool("include-deleted")
+	}
+	return cfg.DefaultIncludeDeleted
+}
+
 // optionalString returns a pointer to the flag value if set and non-empty, nil otherwise.
 func optionalString(c *cli.Context, flag string) *string {
 	if v := c.String(flag); v != "" {
@@ -611,6 +778,27 @@ func parseTags(s string) []string {
 	return tags
 }
 
+// collectTags merges --tags (comma-separated) with repeated --tag values
+// (one literal tag each), dropping blanks and duplicates in first-seen order.
+func collectTags(c *cli.Context) []string {
+	tags := parseTags(c.String("tags"))
+	for _, t := range c.StringSlice("tag") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+
+	seen := make(map[string]bool, len(tags))
+	var deduped []string
+	for _, t := range tags {
+		if !seen[t] {
+			seen[t] = true
+			deduped = append(deduped, t)
+		}
+	}
+	return deduped
+}
+
 // parseDuration parses "7d" format to days.
 func parseDuration(s string) (int, error) {
 	if numStr, ok := strings.CutSuffix(s, "d"); ok {
@@ -5,8 +5,10 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -358,6 +360,72 @@ func TestCLIList(t *testing.T) {
 	}
 }
 
+// TestCLICompactJSON tests that output is indented by default and that
+// --compact-json prints the same content on one line.
+func TestCLICompactJSON(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+	cfg := testConfig()
+
+	name := "compact-test"
+	if _, err := ops.Store(context.Background(), database, cfg, ops.StoreInput{
+		Workspace:   "default",
+		Name:        &name,
+		CapsuleText: validCapsuleText(),
+	}); err != nil {
+		t.Fatalf("failed to store test capsule: %v", err)
+	}
+
+	app := newCLIApp(database, cfg)
+
+	run := func(args ...string) string {
+		t.Helper()
+		oldStdout := os.Stdout
+		r, w := createPipe(t)
+		os.Stdout = w
+
+		err := app.Run(append([]string{"moss"}, args...))
+
+		w.Close()
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		os.Stdout = oldStdout
+
+		if err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+		return buf.String()
+	}
+
+	for _, args := range [][]string{
+		{"fetch", "--name=compact-test"},
+		{"list"},
+		{"inventory"},
+		{"latest"},
+	} {
+		indented := run(args...)
+		compact := run(append(args, "--compact-json")...)
+
+		if !strings.Contains(indented, "\n  \"") {
+			t.Errorf("%v: default output should be indented with two spaces, got:\n%s", args, indented)
+		}
+		if strings.Count(strings.TrimSpace(compact), "\n") != 0 {
+			t.Errorf("%v: --compact-json output should be a single line, got:\n%s", args, compact)
+		}
+
+		var indentedParsed, compactParsed any
+		if err := json.Unmarshal([]byte(indented), &indentedParsed); err != nil {
+			t.Fatalf("%v: failed to parse indented output: %v", args, err)
+		}
+		if err := json.Unmarshal([]byte(compact), &compactParsed); err != nil {
+			t.Fatalf("%v: failed to parse compact output: %v", args, err)
+		}
+		if !reflect.DeepEqual(indentedParsed, compactParsed) {
+			t.Errorf("%v: --compact-json changed the output structure", args)
+		}
+	}
+}
+
 // TestCLIDelete tests the delete command.
 func TestCLIDelete(t *testing.T) {
 	database, cleanup := setupTestDB(t)
@@ -587,6 +655,39 @@ func TestCLIPurge(t *testing.T) {
 	}
 }
 
+// TestCLIReindex tests the reindex command.
+func TestCLIReindex(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+	cfg := testConfig()
+
+	app := newCLIApp(database, cfg)
+
+	oldStdout := os.Stdout
+	r, w := createPipe(t)
+	os.Stdout = w
+
+	err := app.Run([]string{"moss", "reindex", "--check-only"})
+
+	w.Close()
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("reindex command failed: %v", err)
+	}
+
+	var output ops.ReindexOutput
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+
+	if !output.Before.Consistent || output.Rebuilt {
+		t.Errorf("expected consistent and not rebuilt, got %+v", output)
+	}
+}
+
 // TestCLIInventory tests the inventory command.
 func TestCLIInventory(t *testing.T) {
 	database, cleanup := setupTestDB(t)
@@ -662,6 +763,85 @@ func TestCLIInventory(t *testing.T) {
 	})
 }
 
+// TestCLISearch tests the search command, buffered and with --stream.
+func TestCLISearch(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+	cfg := testConfig()
+
+	// More matches than the default page size
+	for i := range 25 {
+		name := fmt.Sprintf("search-%02d", i)
+		_, err := ops.Store(context.Background(), database, cfg, ops.StoreInput{
+			Workspace:   "default",
+			Name:        &name,
+			CapsuleText: validCapsuleText(),
+		})
+		if err != nil {
+			t.Fatalf("failed to store test capsule: %v", err)
+		}
+	}
+
+	app := newCLIApp(database, cfg)
+
+	run := func(t *testing.T, args ...string) []byte {
+		t.Helper()
+		oldStdout := os.Stdout
+		r, w := createPipe(t)
+		os.Stdout = w
+
+		err := app.Run(append([]string{"moss", "search"}, args...))
+
+		w.Close()
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		os.Stdout = oldStdout
+
+		if err != nil {
+			t.Fatalf("search command failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	t.Run("buffered page", func(t *testing.T) {
+		var output ops.SearchOutput
+		if err := json.Unmarshal(run(t, "objective"), &output); err != nil {
+			t.Fatalf("failed to parse output: %v", err)
+		}
+		if len(output.Items) != ops.DefaultSearchLimit || output.Pagination.Total != 25 {
+			t.Errorf("items = %d, total = %d; want %d, 25", len(output.Items), output.Pagination.Total, ops.DefaultSearchLimit)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSuffix(string(run(t, "--stream", "objective")), "\n"), "\n")
+		if len(lines) != 25 {
+			t.Fatalf("streamed %d lines, want all 25 matches", len(lines))
+		}
+		seen := map[string]bool{}
+		for i, line := range lines {
+			var item ops.SearchResultItem
+			if err := json.Unmarshal([]byte(line), &item); err != nil {
+				t.Fatalf("line %d is not a JSON search item: %v", i+1, err)
+			}
+			if item.ID == "" || item.FetchKey.MossWorkspace != "default" || !strings.Contains(item.Snippet, "<b>") {
+				t.Errorf("line %d = %+v, want id, fetch_key, and a highlighted snippet", i+1, item)
+			}
+			seen[item.ID] = true
+		}
+		if len(seen) != 25 {
+			t.Errorf("streamed %d distinct capsules, want 25", len(seen))
+		}
+	})
+
+	t.Run("stream with limit", func(t *testing.T) {
+		out := strings.TrimSuffix(string(run(t, "--stream", "--limit=3", "objective")), "\n")
+		if n := len(strings.Split(out, "\n")); n != 3 {
+			t.Errorf("streamed %d lines, want 3", n)
+		}
+	})
+}
+
 // TestCLIUpdate tests the update command.
 func TestCLIUpdate(t *testing.T) {
 	database, cleanup := setupTestDB(t)
@@ -1060,3 +1240,204 @@ func TestReadStdinWithLimit(t *testing.T) {
 		}
 	})
 }
+
+// TestCLIStoreTagFlags tests repeatable --tag, alone and mixed with --tags.
+func TestCLIStoreTagFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "repeated tag is literal",
+			args: []string{"--tag=lang:go,rust", "--tag", "auth"},
+			want: []string{"lang:go,rust", "auth"},
+		},
+		{
+			name: "mixed with tags",
+			args: []string{"--tags=foo,bar", "--tag=a,b"},
+			want: []string{"foo", "bar", "a,b"},
+		},
+		{
+			name: "duplicates dropped",
+			args: []string{"--tags=foo, bar", "--tag=bar", "--tag= foo ", "--tag=baz", "--tag=baz"},
+			want: []string{"foo", "bar", "baz"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, cleanup := setupTestDB(t)
+			defer cleanup()
+			app := newCLIApp(database, testConfig())
+
+			oldStdout := os.Stdout
+			r, w := createPipe(t)
+			os.Stdout = w
+
+			oldStdin := os.Stdin
+			stdinR, stdinW := createPipe(t)
+			os.Stdin = stdinR
+			go func() {
+				_, _ = stdinW.WriteString(validCapsuleText())
+				stdinW.Close()
+			}()
+
+			err := app.Run(append([]string{"moss", "store"}, tt.args...))
+
+			os.Stdin = oldStdin
+			w.Close()
+			var buf bytes.Buffer
+			_, _ = buf.ReadFrom(r)
+			os.Stdout = oldStdout
+
+			if err != nil {
+				t.Fatalf("store command failed: %v", err)
+			}
+			var output ops.StoreOutput
+			if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+				t.Fatalf("failed to parse output: %v\nOutput: %s", err, buf.String())
+			}
+
+			fetched, err := ops.Fetch(context.Background(), database, ops.FetchInput{ID: output.ID})
+			if err != nil {
+				t.Fatalf("failed to fetch stored capsule: %v", err)
+			}
+			if strings.Join(fetched.Tags, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("tags = %q, want %q", fetched.Tags, tt.want)
+			}
+		})
+	}
+}
+
+// TestCLIUpdateTagFlag tests that --tag alone replaces tags on update.
+func TestCLIUpdateTagFlag(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+	cfg := testConfig()
+
+	name := "tag-update-test"
+	storeOutput, err := ops.Store(context.Background(), database, cfg, ops.StoreInput{
+		Workspace:   "default",
+		Name:        &name,
+		CapsuleText: validCapsuleText(),
+		Tags:        []string{"old"},
+	})
+	if err != nil {
+		t.Fatalf("failed to store test capsule: %v", err)
+	}
+
+	app := newCLIApp(database, cfg)
+
+	oldStdout := os.Stdout
+	r, w := createPipe(t)
+	os.Stdout = w
+
+	err = app.Run([]string{"moss", "update", "--name=tag-update-test", "--tag=x,y", "--tag=z"})
+
+	w.Close()
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("update command failed: %v", err)
+	}
+
+	fetched, err := ops.Fetch(context.Background(), database, ops.FetchInput{ID: storeOutput.ID})
+	if err != nil {
+		t.Fatalf("failed to fetch updated capsule: %v", err)
+	}
+	if strings.Join(fetched.Tags, "|") != "x,y|z" {
+		t.Errorf("tags = %q, want [x,y z]", fetched.Tags)
+	}
+}
+
+// TestCLIStoreFromFile tests --file for store and update, and its exclusion with piped stdin.
+func TestCLIStoreFromFile(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+	app := newCLIApp(database, testConfig())
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "capsule.md")
+	if err := os.WriteFile(path, []byte(validCapsuleText()+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write capsule file: %v", err)
+	}
+
+	// A non-pipe stdin so only --file supplies text
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", os.DevNull, err)
+	}
+	defer devNull.Close()
+	oldStdin := os.Stdin
+	os.Stdin = devNull
+	defer func() { os.Stdin = oldStdin }()
+
+	oldStdout := os.Stdout
+	r, w := createPipe(t)
+	os.Stdout = w
+	err = app.Run([]string{"moss", "store", "--name=from-file", "--file", path})
+	w.Close()
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("store --file failed: %v", err)
+	}
+	var output ops.StoreOutput
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("failed to parse output: %v\nOutput: %s", err, buf.String())
+	}
+	fetched, err := ops.Fetch(context.Background(), database, ops.FetchInput{ID: output.ID})
+	if err != nil {
+		t.Fatalf("failed to fetch stored capsule: %v", err)
+	}
+	if fetched.CapsuleText != validCapsuleText() {
+		t.Errorf("capsule_text = %q, want file contents (trimmed)", fetched.CapsuleText)
+	}
+
+	// Update from a second file
+	updated := validCapsuleText() + "\n- from file"
+	updatePath := filepath.Join(dir, "update.txt")
+	if err := os.WriteFile(updatePath, []byte(updated), 0o600); err != nil {
+		t.Fatalf("failed to write update file: %v", err)
+	}
+	r, w = createPipe(t)
+	os.Stdout = w
+	err = app.Run([]string{"moss", "update", "--name=from-file", "--file", updatePath})
+	w.Close()
+	buf.Reset()
+	_, _ = buf.ReadFrom(r)
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("update --file failed: %v", err)
+	}
+	fetched, err = ops.Fetch(context.Background(), database, ops.FetchInput{ID: output.ID})
+	if err != nil {
+		t.Fatalf("failed to fetch updated capsule: %v", err)
+	}
+	if fetched.CapsuleText != updated {
+		t.Errorf("capsule_text = %q, want updated file contents", fetched.CapsuleText)
+	}
+
+	// Wrong extension is rejected
+	err = app.Run([]string{"moss", "store", "--name=bad-ext", "--file", filepath.Join(dir, "capsule.json")})
+	if err == nil || !strings.Contains(err.Error(), "INVALID_REQUEST") {
+		t.Errorf("expected INVALID_REQUEST for wrong extension, got %v", err)
+	}
+
+	// --file plus piped stdin is ambiguous
+	stdinR, stdinW := createPipe(t)
+	os.Stdin = stdinR
+	go func() {
+		_, _ = stdinW.WriteString(validCapsuleText())
+		stdinW.Close()
+	}()
+	err = app.Run([]string{"moss", "store", "--name=both", "--file", path})
+	if err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Errorf("expected mutual-exclusion error, got %v", err)
+	}
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/config"
 	"github.com/hpungsan/moss/internal/db"
 	"github.com/hpungsan/moss/internal/mcp"
+	"github.com/hpungsan/moss/internal/ops"
 )
 
 // Version is set via -ldflags at build time.
@@ -16,11 +20,54 @@ var Version = "dev"
 // cliCommands contains known CLI subcommands.
 var cliCommands = map[string]bool{
 	"store": true, "fetch": true, "update": true, "delete": true,
-	"list": true, "inventory": true, "latest": true,
-	"export": true, "import": true, "purge": true,
+	"list": true, "inventory": true, "search": true, "latest": true,
+	"export": true, "import": true, "purge": true, "reindex": true,
 	"tools": true, "serve": true, "help": true,
 }
 
+// dataDirEnv is the environment variable that overrides the base data directory.
+const dataDirEnv = "MOSS_DATA_DIR"
+
+// resolveDataDir determines the base data directory (database + global config).
+// Precedence: leading --data-dir flag, then MOSS_DATA_DIR, then ~/.moss.
+// The flag is stripped from the returned args so mode detection and the CLI
+// parser see the subcommand at args[1].
+func resolveDataDir(args []string, getenv func(string) string) (string, []string, error) {
+	dir := ""
+	rest := args
+	if len(args) >= 2 {
+		arg := args[1]
+		switch {
+		case arg == "--data-dir":
+			if len(args) < 3 || args[2] == "" {
+				return "", nil, fmt.Errorf("--data-dir requires a value")
+			}
+			dir = args[2]
+			rest = append([]string{args[0]}, args[3:]...)
+		case strings.HasPrefix(arg, "--data-dir="):
+			dir = strings.TrimPrefix(arg, "--data-dir=")
+			if dir == "" {
+				return "", nil, fmt.Errorf("--data-dir requires a value")
+			}
+			rest = append([]string{args[0]}, args[2:]...)
+		}
+	}
+
+	if dir == "" {
+		dir = getenv(dataDirEnv)
+	}
+
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil, fmt.Errorf("could not determine home directory: %w", err)
+		}
+		dir = filepath.Join(homeDir, ".moss")
+	}
+
+	return dir, rest, nil
+}
+
 // isCLIMode determines if we should run CLI vs MCP server.
 func isCLIMode() bool {
 	if len(os.Args) < 2 {
@@ -70,6 +117,14 @@ func printBanner() {
 }
 
 func main() {
+	// Resolve --data-dir / MOSS_DATA_DIR first; strips the flag from os.Args
+	globalDir, args, err := resolveDataDir(os.Args, os.Getenv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Args = args
+
 	// No args + interactive terminal → show banner and exit
 	if len(os.Args) < 2 && isTerminal() {
 		printBanner()
@@ -86,33 +141,34 @@ func main() {
 		return
 	}
 
-	homeDir, err := os.UserHomeDir()
+	// Load config from global (data dir) and repo (.moss/config.json, walking upward)
+	cwd, err := os.Getwd()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: could not determine home directory: %v\n", err)
+		fmt.Fprintf(os.Stderr, "error: could not determine working directory: %v\n", err)
 		os.Exit(1)
 	}
 
-	globalDir := filepath.Join(homeDir, ".moss")
-
-	database, err := db.Init(globalDir)
+	cfg, err := config.LoadWithRepo(globalDir, cwd)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: failed to initialize database: %v\n", err)
+		fmt.Fprintf(os.Stderr, "error: failed to load config: %v\n", err)
 		os.Exit(1)
 	}
-	defer database.Close()
+	// Default export path and the exports allowlist follow the data directory
+	cfg.DataDir = globalDir
 
-	// Load config from global (~/.moss) and repo (.moss/config.json, walking upward)
-	cwd, err := os.Getwd()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: could not determine working directory: %v\n", err)
+	// The normalization locale must be set before Init, which recomputes
+	// stored handles when it changes
+	if err := capsule.SetNormalizeLocale(cfg.NormalizeLocale); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
 
-	cfg, err := config.LoadWithRepo(globalDir, cwd)
+	database, err := db.Init(globalDir)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: failed to load config: %v\n", err)
+		fmt.Fprintf(os.Stderr, "error: failed to initialize database: %v\n", err)
 		os.Exit(1)
 	}
+	defer database.Close()
 
 	// Warn about unknown disabled_tools entries
 	if unknown := mcp.ValidateDisabledTools(cfg.DisabledTools); len(unknown) > 0 {
@@ -127,6 +183,24 @@ func main() {
 	// Apply database pool settings from config (if configured)
 	db.ConfigurePool(database, cfg)
 
+	// Store new capsule text compressed (if configured)
+	db.ConfigureTextCompression(cfg)
+
+	// Replay writes to the standby database (if configured)
+	if err := db.ConfigureMirror(database, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.CloseMirror() //nolint:errcheck
+
+	// Enable the search result cache (if configured)
+	ops.ConfigureSearchCache(cfg.SearchCacheSize)
+
+	// Soft-delete capsules whose expires_at has passed (reads already hide them)
+	if _, err := ops.SweepExpired(context.Background(), database); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to sweep expired capsules: %v\n", err)
+	}
+
 	// CLI mode: known subcommand
 	if isCLIMode() {
 		app := newCLIApp(database, cfg)
@@ -145,7 +219,7 @@ func main() {
 	}
 
 	// MCP server mode (default)
-	if err := mcp.Run(database, cfg, Version); err != nil {
+	if err := mcp.Run(database, cfg, globalDir, Version); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func envFunc(vals map[string]string) func(string) string {
+	return func(k string) string { return vals[k] }
+}
+
+func TestResolveDataDir_Flag(t *testing.T) {
+	dir, args, err := resolveDataDir([]string{"moss", "--data-dir", "/srv/moss", "list"}, envFunc(nil))
+	if err != nil {
+		t.Fatalf("resolveDataDir failed: %v", err)
+	}
+	if dir != "/srv/moss" {
+		t.Errorf("dir = %q, want /srv/moss", dir)
+	}
+	if want := []string{"moss", "list"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+
+	// --data-dir=value form
+	dir, args, err = resolveDataDir([]string{"moss", "--data-dir=/srv/moss"}, envFunc(nil))
+	if err != nil {
+		t.Fatalf("resolveDataDir failed: %v", err)
+	}
+	if dir != "/srv/moss" {
+		t.Errorf("dir = %q, want /srv/moss", dir)
+	}
+	if want := []string{"moss"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v (MCP mode)", args, want)
+	}
+}
+
+func TestResolveDataDir_Env(t *testing.T) {
+	args := []string{"moss", "list"}
+	dir, rest, err := resolveDataDir(args, envFunc(map[string]string{dataDirEnv: "/env/moss"}))
+	if err != nil {
+		t.Fatalf("resolveDataDir failed: %v", err)
+	}
+	if dir != "/env/moss" {
+		t.Errorf("dir = %q, want /env/moss", dir)
+	}
+	if !reflect.DeepEqual(rest, args) {
+		t.Errorf("args = %v, want unchanged %v", rest, args)
+	}
+}
+
+func TestResolveDataDir_FlagWinsOverEnv(t *testing.T) {
+	dir, _, err := resolveDataDir(
+		[]string{"moss", "--data-dir", "/flag/moss", "list"},
+		envFunc(map[string]string{dataDirEnv: "/env/moss"}),
+	)
+	if err != nil {
+		t.Fatalf("resolveDataDir failed: %v", err)
+	}
+	if dir != "/flag/moss" {
+		t.Errorf("dir = %q, want /flag/moss", dir)
+	}
+}
+
+func TestResolveDataDir_DefaultFallback(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	dir, args, err := resolveDataDir([]string{"moss", "list"}, envFunc(nil))
+	if err != nil {
+		t.Fatalf("resolveDataDir failed: %v", err)
+	}
+	if want := filepath.Join(home, ".moss"); dir != want {
+		t.Errorf("dir = %q, want %q", dir, want)
+	}
+	if want := []string{"moss", "list"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+}
+
+func TestResolveDataDir_MissingValue(t *testing.T) {
+	for _, args := range [][]string{
+		{"moss", "--data-dir"},
+		{"moss", "--data-dir="},
+	} {
+		if _, _, err := resolveDataDir(args, envFunc(nil)); err == nil {
+			t.Errorf("resolveDataDir(%v) expected error", args)
+		}
+	}
+}
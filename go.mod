@@ -8,6 +8,7 @@ require (
 	github.com/stretchr/testify v1.9.0
 	github.com/urfave/cli/v2 v2.27.7
 	github.com/yuin/goldmark v1.7.16
+	golang.org/x/text v0.30.0
 	modernc.org/sqlite v1.44.3
 )
 
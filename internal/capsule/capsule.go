@@ -30,6 +30,18 @@ type Capsule struct {
 	// TokensEstimate is the estimated token count for LLM context budgeting
 	TokensEstimate int
 
+	// ContentSHA256 is the hex SHA-256 of CapsuleText, maintained by the db layer on every write
+	ContentSHA256 string
+
+	// LineCount is the number of lines in CapsuleText
+	LineCount int
+
+	// WordCount is the number of whitespace-separated words in CapsuleText
+	WordCount int
+
+	// SectionCount is the number of markdown section headers in CapsuleText
+	SectionCount int
+
 	// Tags is a list of tags for categorization (stored as JSON in DB)
 	Tags []string
 
@@ -45,6 +57,16 @@ type Capsule struct {
 	// Role indicates the agent role (e.g., "design-intent", "qa-reviewer")
 	Role *string
 
+	// SourceType classifies the origin: "agent", "human", or "import" (nullable)
+	SourceType *string
+
+	// SourceRef is an optional URL or reference for the origin (e.g., PR link, session ID)
+	SourceRef *string
+
+	// Note is an optional free-form annotation kept apart from CapsuleText;
+	// it is not linted, searched, or composed (nullable)
+	Note *string
+
 	// CreatedAt is the Unix timestamp when the capsule was created
 	CreatedAt int64
 
@@ -53,4 +75,7 @@ type Capsule struct {
 
 	// DeletedAt is the Unix timestamp for soft delete (nullable)
 	DeletedAt *int64
+
+	// ExpiresAt is the Unix timestamp after which the capsule is treated as deleted (nullable)
+	ExpiresAt *int64
 }
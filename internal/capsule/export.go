@@ -27,32 +27,52 @@ type ExportRecord struct {
 	RunID          *string  `json:"run_id"`
 	Phase          *string  `json:"phase"`
 	Role           *string  `json:"role"`
+	SourceType     *string  `json:"source_type"`
+	SourceRef      *string  `json:"source_ref"`
+	Note           *string  `json:"note,omitempty"`
 	CreatedAt      int64    `json:"created_at"`
 	UpdatedAt      int64    `json:"updated_at"`
 	DeletedAt      *int64   `json:"deleted_at"`
+	ExpiresAt      *int64   `json:"expires_at,omitempty"`
+}
+
+// CompactExportRecord marshals an ExportRecord without the fields import
+// recomputes (workspace_norm, name_norm, capsule_chars, tokens_estimate).
+// The nil shadow fields sit shallower than the embedded ones, so they win the
+// JSON name and omitempty drops them; every other field passes through.
+type CompactExportRecord struct {
+	*ExportRecord
+	WorkspaceNorm  *struct{} `json:"workspace_norm,omitempty"`
+	NameNorm       *struct{} `json:"name_norm,omitempty"`
+	CapsuleChars   *struct{} `json:"capsule_chars,omitempty"`
+	TokensEstimate *struct{} `json:"tokens_estimate,omitempty"`
 }
 
 // ToCapsule converts an ExportRecord to a Capsule, recomputing derived fields.
 func (r *ExportRecord) ToCapsule() *Capsule {
 	c := &Capsule{
-		ID:             r.ID,
-		WorkspaceRaw:   r.WorkspaceRaw,
-		WorkspaceNorm:  Normalize(r.WorkspaceRaw), // Recompute
-		NameRaw:        r.NameRaw,
-		Title:          r.Title,
-		CapsuleText:    r.CapsuleText,
-		CapsuleChars:   CountChars(r.CapsuleText),     // Recompute
-		TokensEstimate: EstimateTokens(r.CapsuleText), // Recompute
-		Tags:           r.Tags,
-		Source:         r.Source,
-		RunID:          emptyToNil(r.RunID), // Normalize: "" → nil
-		Phase:          emptyToNil(r.Phase), // Normalize: "" → nil
-		Role:           emptyToNil(r.Role),  // Normalize: "" → nil
-		CreatedAt:      r.CreatedAt,
-		UpdatedAt:      r.UpdatedAt,
-		DeletedAt:      r.DeletedAt,
+		ID:            r.ID,
+		WorkspaceRaw:  r.WorkspaceRaw,
+		WorkspaceNorm: Normalize(r.WorkspaceRaw), // Recompute
+		NameRaw:       r.NameRaw,
+		Title:         r.Title,
+		CapsuleText:   r.CapsuleText,
+		Tags:          r.Tags,
+		Source:        r.Source,
+		RunID:         emptyToNil(r.RunID), // Normalize: "" → nil
+		Phase:         emptyToNil(r.Phase), // Normalize: "" → nil
+		Role:          emptyToNil(r.Role),  // Normalize: "" → nil
+		SourceType:    emptyToNil(r.SourceType),
+		SourceRef:     emptyToNil(r.SourceRef),
+		Note:          emptyToNil(r.Note),
+		CreatedAt:     r.CreatedAt,
+		UpdatedAt:     r.UpdatedAt,
+		DeletedAt:     r.DeletedAt,
+		ExpiresAt:     r.ExpiresAt,
 	}
 
+	c.RecomputeMetrics() // capsule_chars, tokens_estimate, line/word/section counts
+
 	// Recompute name_norm from name_raw
 	if r.NameRaw != nil {
 		norm := Normalize(*r.NameRaw)
@@ -91,8 +111,12 @@ func CapsuleToExportRecord(c *Capsule) *ExportRecord {
 		RunID:          c.RunID,
 		Phase:          c.Phase,
 		Role:           c.Role,
+		SourceType:     c.SourceType,
+		SourceRef:      c.SourceRef,
+		Note:           c.Note,
 		CreatedAt:      c.CreatedAt,
 		UpdatedAt:      c.UpdatedAt,
 		DeletedAt:      c.DeletedAt,
+		ExpiresAt:      c.ExpiresAt,
 	}
 }
@@ -0,0 +1,18 @@
+package capsule
+
+import (
+	"fmt"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// TimestampFromID returns the creation time embedded in a ULID capsule ID, as
+// Unix seconds to match CreatedAt. IDs that aren't ULIDs (e.g. imported from
+// another system) return an error.
+func TimestampFromID(id string) (int64, error) {
+	u, err := ulid.ParseStrict(id)
+	if err != nil {
+		return 0, fmt.Errorf("id %q is not a ULID: %w", id, err)
+	}
+	return int64(u.Time() / 1000), nil
+}
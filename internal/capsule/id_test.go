@@ -0,0 +1,43 @@
+package capsule
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func TestTimestampFromID(t *testing.T) {
+	created := time.Date(2025, 3, 14, 15, 9, 26, 535_000_000, time.UTC)
+	id := ulid.MustNew(ulid.Timestamp(created), ulid.DefaultEntropy()).String()
+
+	got, err := TimestampFromID(id)
+	if err != nil {
+		t.Fatalf("TimestampFromID failed: %v", err)
+	}
+	// Milliseconds are truncated, like created_at
+	if got != created.Unix() {
+		t.Errorf("TimestampFromID = %d, want %d", got, created.Unix())
+	}
+
+	// Lowercase is valid Crockford base32
+	if got, err := TimestampFromID(strings.ToLower(id)); err != nil || got != created.Unix() {
+		t.Errorf("lowercase TimestampFromID = %d, %v; want %d", got, err, created.Unix())
+	}
+}
+
+func TestTimestampFromID_NotULID(t *testing.T) {
+	for _, id := range []string{
+		"",
+		"550e8400-e29b-41d4-a716-446655440000", // UUID
+		"not-a-ulid",
+		"01ARZ3NDEKTSV4RRFFQ69G5FA",   // one char short
+		"01ARZ3NDEKTSV4RRFFQ69G5FAU!", // one char long
+		"01ARZ3NDEKTSV4RRFFQ69G5FAI",  // I isn't in the alphabet
+	} {
+		if _, err := TimestampFromID(id); err == nil {
+			t.Errorf("TimestampFromID(%q) should fail", id)
+		}
+	}
+}
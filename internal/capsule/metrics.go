@@ -0,0 +1,53 @@
+package capsule
+
+import "strings"
+
+// ReadingWordsPerMinute is the reading speed assumed by ReadingTime.
+const ReadingWordsPerMinute = 200
+
+// Metrics are size counts derived from capsule_text.
+type Metrics struct {
+	Chars          int // runes, as CountChars
+	TokensEstimate int // as EstimateTokens
+	Lines          int // a trailing newline does not start another line
+	Words          int // whitespace-separated
+	Sections       int // markdown headers outside fenced code blocks, as ParseSections
+}
+
+// RecomputeMetrics sets the size fields (CapsuleChars, TokensEstimate, LineCount,
+// WordCount, SectionCount) from CapsuleText.
+func (c *Capsule) RecomputeMetrics() {
+	m := ComputeMetrics(c.CapsuleText)
+	c.CapsuleChars = m.Chars
+	c.TokensEstimate = m.TokensEstimate
+	c.LineCount = m.Lines
+	c.WordCount = m.Words
+	c.SectionCount = m.Sections
+}
+
+// ComputeMetrics counts chars, tokens, lines, words, and sections in text.
+func ComputeMetrics(text string) Metrics {
+	words := len(strings.Fields(text))
+	lines := 0
+	for range strings.Lines(text) {
+		lines++
+	}
+	return Metrics{
+		Chars:          CountChars(text),
+		TokensEstimate: EstimateTokensFromWords(words),
+		Lines:          lines,
+		Words:          words,
+		Sections:       len(ParseSections(text)),
+	}
+}
+
+// ReadingTime estimates minutes to read text at ReadingWordsPerMinute,
+// rounded up so any non-empty text takes at least a minute.
+func ReadingTime(text string) int {
+	return ReadingTimeFromWords(len(strings.Fields(text)))
+}
+
+// ReadingTimeFromWords applies the ReadingTime estimate to a precomputed word count.
+func ReadingTimeFromWords(words int) int {
+	return (words + ReadingWordsPerMinute - 1) / ReadingWordsPerMinute
+}
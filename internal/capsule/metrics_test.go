@@ -0,0 +1,98 @@
+package capsule
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComputeMetrics(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Metrics
+	}{
+		{
+			name: "empty",
+			text: "",
+			want: Metrics{},
+		},
+		{
+			name: "standard capsule",
+			text: testCapsule,
+			want: Metrics{Chars: 222, TokensEstimate: 42, Lines: 18, Words: 32, Sections: 6},
+		},
+		{
+			name: "no trailing newline",
+			text: "one two\nthree",
+			want: Metrics{Chars: 13, TokensEstimate: 4, Lines: 2, Words: 3},
+		},
+		{
+			name: "blank lines count",
+			text: "a\n\n\nb\n",
+			want: Metrics{Chars: 6, TokensEstimate: 3, Lines: 4, Words: 2},
+		},
+		{
+			name: "empty sections",
+			text: "## Objective\n## Decisions\n\n## Next actions\n",
+			want: Metrics{Chars: 43, TokensEstimate: 10, Lines: 4, Words: 7, Sections: 3},
+		},
+		{
+			name: "headers in fences are not sections",
+			text: "## Notes\n```\n## not a section\n```\n",
+			want: Metrics{Chars: 34, TokensEstimate: 11, Lines: 4, Words: 8, Sections: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComputeMetrics(tt.text)
+			if got != tt.want {
+				t.Errorf("ComputeMetrics() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecomputeMetrics(t *testing.T) {
+	c := &Capsule{CapsuleText: "## Status\nDone.\n", CapsuleChars: 99, LineCount: 99}
+	c.RecomputeMetrics()
+
+	if c.CapsuleChars != 16 || c.TokensEstimate != 4 || c.LineCount != 2 || c.WordCount != 3 || c.SectionCount != 1 {
+		t.Errorf("metrics = (chars %d, tokens %d, lines %d, words %d, sections %d), want (16, 4, 2, 3, 1)",
+			c.CapsuleChars, c.TokensEstimate, c.LineCount, c.WordCount, c.SectionCount)
+	}
+}
+
+func TestToCapsule_RecomputesMetrics(t *testing.T) {
+	// Import ignores any counts in the record and recomputes from the text
+	r := &ExportRecord{ID: "01IMPORT", WorkspaceRaw: "default", CapsuleText: testCapsule, CapsuleChars: 1, TokensEstimate: 1}
+	c := r.ToCapsule()
+
+	if c.CapsuleChars != 222 || c.LineCount != 18 || c.WordCount != 32 || c.SectionCount != 6 {
+		t.Errorf("metrics = (chars %d, lines %d, words %d, sections %d), want (222, 18, 32, 6)",
+			c.CapsuleChars, c.LineCount, c.WordCount, c.SectionCount)
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"whitespace only", "  \n\t ", 0},
+		{"short text rounds up", testCapsule, 1},
+		{"exactly one minute", strings.Repeat("word ", 200), 1},
+		{"just over", strings.Repeat("word ", 201), 2},
+		{"long text", strings.Repeat("word ", 1000), 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReadingTime(tt.text); got != tt.want {
+				t.Errorf("ReadingTime() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
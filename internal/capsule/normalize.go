@@ -1,25 +1,70 @@
 package capsule
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"math"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
 )
 
 // whitespaceRegex matches one or more whitespace characters
 var whitespaceRegex = regexp.MustCompile(`\s+`)
 
+// trailingSpaceRegex matches horizontal whitespace at the end of a line
+var trailingSpaceRegex = regexp.MustCompile(`(?m)[ \t]+$`)
+
+// blankRunRegex matches 3+ consecutive newlines (2+ blank lines)
+var blankRunRegex = regexp.MustCompile(`\n{3,}`)
+
+// normalizeLocale is the locale whose lowercasing rules Normalize applies
+// before case folding; nil means language-independent folding only.
+var normalizeLocale atomic.Pointer[language.Tag]
+
+// SetNormalizeLocale sets the BCP 47 locale Normalize lowercases with before
+// folding (e.g. "tr" maps "İ" to "i" and "I" to "ı"). Empty restores the
+// language-independent default. Call before opening the database: stored
+// name_norm/workspace_norm values are recomputed when the locale changes.
+func SetNormalizeLocale(locale string) error {
+	if locale == "" {
+		normalizeLocale.Store(nil)
+		return nil
+	}
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return fmt.Errorf("invalid normalize_locale %q: %w", locale, err)
+	}
+	normalizeLocale.Store(&tag)
+	return nil
+}
+
+// NormalizeLocale returns the locale set by SetNormalizeLocale, or "" for the default.
+func NormalizeLocale() string {
+	if tag := normalizeLocale.Load(); tag != nil {
+		return tag.String()
+	}
+	return ""
+}
+
 // Normalize normalizes a string per DESIGN.md §4.2:
 // 1. Trim leading/trailing whitespace
-// 2. Lowercase
+// 2. Unicode case folding (after locale lowercasing, if a locale is set)
 // 3. Collapse internal whitespace to single spaces
 func Normalize(s string) string {
 	// Trim leading/trailing whitespace
 	s = strings.TrimSpace(s)
 
-	// Lowercase
-	s = strings.ToLower(s)
+	// Case-fold; Casers are stateful, so build them per call
+	if tag := normalizeLocale.Load(); tag != nil {
+		s = cases.Lower(*tag).String(s)
+	}
+	s = cases.Fold().String(s)
 
 	// Collapse internal whitespace to single spaces
 	s = whitespaceRegex.ReplaceAllString(s, " ")
@@ -27,6 +72,43 @@ func Normalize(s string) string {
 	return s
 }
 
+// NormalizeTags applies Normalize to each tag, dropping blanks and duplicates
+// (first occurrence wins). Returns nil for nil input.
+func NormalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = Normalize(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// NormalizeText cleans up capsule text formatting without changing its content:
+// 1. Convert CRLF line endings to LF
+// 2. Strip trailing whitespace from each line
+// 3. Collapse runs of blank lines to a single blank line
+func NormalizeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = trailingSpaceRegex.ReplaceAllString(text, "")
+	text = blankRunRegex.ReplaceAllString(text, "\n\n")
+	return text
+}
+
+// ContentSHA256 returns the lowercase hex SHA-256 of the capsule text.
+// Clients compare it against a cached value to skip re-fetching unchanged text.
+func ContentSHA256(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
 // CountChars returns the character count as runes (not bytes).
 // This correctly handles multi-byte UTF-8 characters.
 func CountChars(text string) int {
@@ -36,6 +118,10 @@ func CountChars(text string) int {
 // EstimateTokens estimates token count using a word-based heuristic.
 // Uses 1.3x multiplier on word count as per DESIGN.md.
 func EstimateTokens(text string) int {
-	words := strings.Fields(strings.TrimSpace(text))
-	return int(math.Ceil(float64(len(words)) * 1.3))
+	return EstimateTokensFromWords(len(strings.Fields(text)))
+}
+
+// EstimateTokensFromWords applies the EstimateTokens heuristic to a precomputed word count.
+func EstimateTokensFromWords(words int) int {
+	return int(math.Ceil(float64(words) * 1.3))
 }
@@ -1,6 +1,7 @@
 package capsule
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -50,6 +51,21 @@ func TestNormalize(t *testing.T) {
 			input: "  HÉLLO   WÖRLD  ",
 			want:  "héllo wörld",
 		},
+		{
+			name:  "full case folding",
+			input: "Straße",
+			want:  "strasse",
+		},
+		{
+			name:  "greek final sigma folds with medial sigma",
+			input: "ΟΔΥΣΣΕΥΣ",
+			want:  "οδυσσευσ",
+		},
+		{
+			name:  "dotted capital I without locale keeps the dot",
+			input: "İstanbul",
+			want:  "i\u0307stanbul",
+		},
 	}
 
 	for _, tt := range tests {
@@ -60,6 +76,110 @@ func TestNormalize(t *testing.T) {
 			}
 		})
 	}
+
+	// Differently-cased spellings of the same handle normalize identically
+	if Normalize("STRASSE") != Normalize("straße") || Normalize("ὈΔΥΣΣΕΎΣ") != Normalize("ὀδυσσεύς") {
+		t.Error("case variants should normalize to the same handle")
+	}
+}
+
+func TestNormalize_Locale(t *testing.T) {
+	t.Cleanup(func() { _ = SetNormalizeLocale("") })
+
+	if err := SetNormalizeLocale("tr"); err != nil {
+		t.Fatalf("SetNormalizeLocale(tr) failed: %v", err)
+	}
+	if got := NormalizeLocale(); got != "tr" {
+		t.Errorf("NormalizeLocale() = %q, want tr", got)
+	}
+	if got := Normalize("İSTANBUL"); got != "istanbul" {
+		t.Errorf("Normalize(İSTANBUL) with tr = %q, want istanbul", got)
+	}
+	if got := Normalize("DIYARBAKIR"); got != "dıyarbakır" {
+		t.Errorf("Normalize(DIYARBAKIR) with tr = %q, want dıyarbakır", got)
+	}
+
+	if err := SetNormalizeLocale("not a locale!"); err == nil {
+		t.Error("expected error for invalid locale")
+	}
+
+	if err := SetNormalizeLocale(""); err != nil {
+		t.Fatalf("SetNormalizeLocale(\"\") failed: %v", err)
+	}
+	if got := Normalize("DIYARBAKIR"); got != "diyarbakir" {
+		t.Errorf("Normalize(DIYARBAKIR) without locale = %q, want diyarbakir", got)
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "crlf to lf",
+			input: "## A\r\nbody\r\n",
+			want:  "## A\nbody\n",
+		},
+		{
+			name:  "trailing whitespace stripped",
+			input: "## A  \nbody\t\n",
+			want:  "## A\nbody\n",
+		},
+		{
+			name:  "blank line runs collapsed",
+			input: "## A\n\n\n\n## B\n\n## C",
+			want:  "## A\n\n## B\n\n## C",
+		},
+		{
+			name:  "whitespace-only lines count as blank",
+			input: "## A\r\n  \r\n\t\r\n\r\n## B",
+			want:  "## A\n\n## B",
+		},
+		{
+			name:  "leading indentation preserved",
+			input: "- item\n  - nested",
+			want:  "- item\n  - nested",
+		},
+		{
+			name:  "already clean",
+			input: "## A\nbody",
+			want:  "## A\nbody",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeText(tt.input)
+			if got != tt.want {
+				t.Errorf("NormalizeText(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []string
+		want  []string
+	}{
+		{"nil stays nil", nil, nil},
+		{"case and whitespace folded", []string{"Security", " security ", "SECURITY"}, []string{"security"}},
+		{"internal whitespace collapsed", []string{"Code  Review"}, []string{"code review"}},
+		{"blanks dropped, order kept", []string{"B", "  ", "a", "b"}, []string{"b", "a"}},
+		{"empty stays empty", []string{}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeTags(tt.input)
+			if (got == nil) != (tt.want == nil) || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("NormalizeTags(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
 }
 
 func TestCountChars(t *testing.T) {
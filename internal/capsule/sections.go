@@ -236,6 +236,23 @@ func InsertContent(text string, section *Section, content string) string {
 	return text[:section.ContentStart] + existingContent + "\n\n" + content + "\n" + text[section.ContentEnd:]
 }
 
+// ReplaceContent replaces a section's content, keeping its header and the
+// blank lines that separate it from the next section. Blank content leaves
+// the section empty. Returns the modified text.
+func ReplaceContent(text string, section *Section, content string) string {
+	existing := text[section.ContentStart:section.ContentEnd]
+	trailing := existing[len(strings.TrimRight(existing, " \t\n")):]
+	if !strings.Contains(trailing, "\n") {
+		trailing = "\n"
+	}
+
+	content = strings.TrimRight(content, " \t\n")
+	if content == "" {
+		return text[:section.ContentStart] + strings.TrimPrefix(trailing, "\n") + text[section.ContentEnd:]
+	}
+	return text[:section.ContentStart] + content + trailing + text[section.ContentEnd:]
+}
+
 // isPlaceholderContent checks if content is only placeholder text.
 // Content with any non-placeholder text returns false.
 func isPlaceholderContent(content string) bool {
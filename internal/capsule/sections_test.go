@@ -252,6 +252,31 @@ Completed successfully
 	}
 }
 
+func TestReplaceContent(t *testing.T) {
+	text := "## Objective\nGoal here\nand more\n\n## Status\nIn progress\n"
+	sections := ParseSections(text)
+
+	tests := []struct {
+		name    string
+		section string
+		content string
+		want    string
+	}{
+		{"middle keeps separator", "Objective", "New goal", "## Objective\nNew goal\n\n## Status\nIn progress\n"},
+		{"last section", "Status", "Done\n\n", "## Objective\nGoal here\nand more\n\n## Status\nDone\n"},
+		{"blank clears middle", "Objective", "  ", "## Objective\n\n## Status\nIn progress\n"},
+		{"blank clears last", "Status", "", "## Objective\nGoal here\nand more\n\n## Status\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ReplaceContent(text, FindSectionExact(sections, tt.section), tt.content)
+			if got != tt.want {
+				t.Errorf("ReplaceContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestInsertContent_LastSection(t *testing.T) {
 	text := `## Objective
 Goal here
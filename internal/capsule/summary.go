@@ -1,5 +1,10 @@
 package capsule
 
+import (
+	"strings"
+	"unicode/utf8"
+)
+
 // CapsuleSummary represents a capsule's metadata without the full text content.
 // Used for browse operations (list, inventory, latest) to reduce data transfer.
 type CapsuleSummary struct {
@@ -27,6 +32,21 @@ type CapsuleSummary struct {
 	// TokensEstimate is the estimated token count for LLM context budgeting
 	TokensEstimate int `json:"tokens_estimate"`
 
+	// ContentSHA256 is the hex SHA-256 of capsule_text, for cheap change detection
+	ContentSHA256 string `json:"content_sha256"`
+
+	// LineCount is the number of lines in capsule_text
+	LineCount int `json:"line_count"`
+
+	// WordCount is the number of whitespace-separated words in capsule_text
+	WordCount int `json:"word_count"`
+
+	// SectionCount is the number of markdown section headers in capsule_text
+	SectionCount int `json:"section_count"`
+
+	// Abstract is a one-line summary computed on read (see Abstract); not stored
+	Abstract string `json:"abstract,omitempty"`
+
 	// Tags is a list of tags for categorization
 	Tags []string `json:"tags,omitempty"`
 
@@ -42,6 +62,12 @@ type CapsuleSummary struct {
 	// Role indicates the agent role (e.g., "design-intent", "qa-reviewer")
 	Role *string `json:"role,omitempty"`
 
+	// SourceType classifies the origin: "agent", "human", or "import"
+	SourceType *string `json:"source_type,omitempty"`
+
+	// SourceRef is an optional URL or reference for the origin
+	SourceRef *string `json:"source_ref,omitempty"`
+
 	// CreatedAt is the Unix timestamp when the capsule was created
 	CreatedAt int64 `json:"created_at"`
 
@@ -63,13 +89,63 @@ func (c *Capsule) ToSummary() CapsuleSummary {
 		Title:          c.Title,
 		CapsuleChars:   c.CapsuleChars,
 		TokensEstimate: c.TokensEstimate,
+		ContentSHA256:  c.ContentSHA256,
+		LineCount:      c.LineCount,
+		WordCount:      c.WordCount,
+		SectionCount:   c.SectionCount,
+		Abstract:       Abstract(c.CapsuleText, AbstractMaxChars),
 		Tags:           c.Tags,
 		Source:         c.Source,
 		RunID:          c.RunID,
 		Phase:          c.Phase,
 		Role:           c.Role,
+		SourceType:     c.SourceType,
+		SourceRef:      c.SourceRef,
 		CreatedAt:      c.CreatedAt,
 		UpdatedAt:      c.UpdatedAt,
 		DeletedAt:      c.DeletedAt,
 	}
 }
+
+const (
+	// AbstractMaxChars is the length (runes) summaries truncate Abstract to.
+	AbstractMaxChars = 160
+
+	// AbstractScanChars is how much of capsule_text summary queries read to
+	// compute the abstract, so list queries don't load whole capsules.
+	AbstractScanChars = 2000
+)
+
+// Abstract returns a one-line abstract of text: the first line of the
+// Objective section that isn't a heading, fence, or placeholder, falling back
+// to the first such line anywhere in text. The line is trimmed and truncated to
+// maxChars runes with "…" (maxChars <= 0 means no limit). Returns "" when text
+// has no content lines.
+func Abstract(text string, maxChars int) string {
+	line := ""
+	if section := FindSection(ParseSections(text), "Objective"); section != nil {
+		line = firstContentLine(text[section.ContentStart:section.ContentEnd])
+	}
+	if line == "" {
+		line = firstContentLine(text)
+	}
+
+	if maxChars > 0 && utf8.RuneCountInString(line) > maxChars {
+		runes := []rune(line)
+		line = strings.TrimSpace(string(runes[:maxChars-1])) + "…"
+	}
+	return line
+}
+
+// firstContentLine returns the first trimmed line of text that isn't blank,
+// a markdown heading, a code fence delimiter, or a placeholder like "(pending)".
+func firstContentLine(text string) string {
+	for line := range strings.Lines(text) {
+		line = strings.TrimSpace(line)
+		if isPlaceholderContent(line) || headerPattern.MatchString(line) || fencePattern.MatchString(line) {
+			continue
+		}
+		return line
+	}
+	return ""
+}
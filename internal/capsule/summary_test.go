@@ -0,0 +1,63 @@
+package capsule
+
+import "testing"
+
+func TestAbstract(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxChars int
+		want     string
+	}{
+		{
+			name:     "full capsule",
+			text:     testCapsule,
+			maxChars: AbstractMaxChars,
+			want:     "Test the append functionality",
+		},
+		{
+			name:     "objective synonym and leading placeholder",
+			text:     "# Auth\n\n## Goal\n(pending)\nShip login\n\n## Decisions\nJWT\n",
+			maxChars: AbstractMaxChars,
+			want:     "Ship login",
+		},
+		{
+			name:     "missing objective falls back to first content line",
+			text:     "# Notes\n\n## Decisions\nUse Redis for sessions\n",
+			maxChars: AbstractMaxChars,
+			want:     "Use Redis for sessions",
+		},
+		{
+			name:     "placeholder objective falls back",
+			text:     "## Objective\nTBD\n\n## Current status\nBlocked on review\n",
+			maxChars: AbstractMaxChars,
+			want:     "Blocked on review",
+		},
+		{
+			name:     "only placeholders",
+			text:     "## Objective\n(pending)\n\n## Decisions\nnone\n\n## Open questions\n-\n",
+			maxChars: AbstractMaxChars,
+			want:     "",
+		},
+		{
+			name:     "truncated to max chars",
+			text:     "## Objective\nRefactor the authentication flow\n",
+			maxChars: 12,
+			want:     "Refactor th…",
+		},
+		{
+			name:     "no limit",
+			text:     "plain text capsule",
+			maxChars: 0,
+			want:     "plain text capsule",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Abstract(tt.text, tt.maxChars); got != tt.want {
+				t.Errorf("Abstract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
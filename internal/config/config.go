@@ -3,8 +3,10 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -13,8 +15,23 @@ type Config struct {
 	// CapsuleMaxChars is the maximum character count for capsule text
 	CapsuleMaxChars int `json:"capsule_max_chars"`
 
+	// CapsuleMaxBytes caps capsule text by UTF-8 size, for storage budgets
+	// that multibyte text can blow through under the char limit. Both limits
+	// apply, so the stricter one wins. 0 means disabled.
+	CapsuleMaxBytes int `json:"capsule_max_bytes,omitempty"`
+
+	// MaxSections caps the "##" sections in capsule text, to catch runaway
+	// output with dozens of spurious headings. Skipped with allow_thin.
+	// 0 means unlimited.
+	MaxSections int `json:"max_sections,omitempty"`
+
+	// DataDir is the resolved base data directory (~/.moss, --data-dir, or
+	// MOSS_DATA_DIR), set by main after loading. Never read from config.json.
+	// Empty means ~/.moss.
+	DataDir string `json:"-"`
+
 	// AllowedPaths is an allowlist of directories for import/export operations.
-	// Paths outside ~/.moss/exports require either being in this list or AllowUnsafePaths=true.
+	// Paths outside <data dir>/exports require either being in this list or AllowUnsafePaths=true.
 	// Paths should be absolute (relative paths are ignored).
 	AllowedPaths []string `json:"allowed_paths,omitempty"`
 
@@ -32,8 +49,18 @@ type Config struct {
 	// 0 means use sql.DB default. Typically set equal to DBMaxOpenConns.
 	DBMaxIdleConns int `json:"db_max_idle_conns,omitempty"`
 
+	// MirrorPath is an absolute path to a second SQLite file that every write
+	// is replayed against, as a warm standby. Best-effort: mirror failures are
+	// logged and never fail the write. Empty disables it. Read at startup.
+	MirrorPath string `json:"mirror_path,omitempty"`
+
+	// CompressText stores capsule text gzip-compressed when that makes it
+	// smaller. Reads and search are unaffected; rows written before it was
+	// turned on (or after it is turned off) stay as they are. Read at startup.
+	CompressText bool `json:"compress_text,omitempty"`
+
 	// DisabledTools is a list of MCP tool names to exclude from registration.
-	// All 15 tools are enabled by default. Unknown tool names are logged as warnings.
+	// All tools are enabled by default. Unknown tool names are logged as warnings.
 	DisabledTools []string `json:"disabled_tools,omitempty"`
 
 	// DisabledTypes is a list of type names to disable entirely.
@@ -41,19 +68,140 @@ type Config struct {
 	// Known types: "capsule". Unknown type names are logged as warnings.
 	DisabledTypes []string `json:"disabled_types,omitempty"`
 
+	// MaxCapsulesPerWorkspace caps active capsules per workspace on store.
+	// Replacing an existing capsule doesn't count against it. 0 means unlimited.
+	MaxCapsulesPerWorkspace int `json:"max_capsules_per_workspace,omitempty"`
+
+	// MinSearchTermLen rejects searches whose longest term is shorter than this
+	// (e.g. 3 blocks "a" or "of*"). 1 (the default) means no restriction.
+	MinSearchTermLen int `json:"min_search_term_len,omitempty"`
+
+	// NormalizeOnStore normalizes capsule_text formatting on store/update
+	// (CRLF→LF, trailing whitespace stripped, blank-line runs collapsed).
+	// Off by default so stored text matches input exactly.
+	NormalizeOnStore bool `json:"normalize_on_store,omitempty"`
+
+	// NormalizeTags lowercases, trims, and collapses whitespace in tags on
+	// store/update and in tag filters, so "Security" and " security " match.
+	// Off by default; existing tags are not rewritten.
+	NormalizeTags bool `json:"normalize_tags,omitempty"`
+
+	// NormalizeLocale is a BCP 47 tag (e.g. "tr") whose lowercasing rules are
+	// applied before Unicode case folding of workspace and name handles.
+	// Empty means language-independent folding. Changing it recomputes stored
+	// handles on the next start. Global config only: LoadWithRepo rejects it
+	// in a repo config.
+	NormalizeLocale string `json:"normalize_locale,omitempty"`
+
+	// EnvelopeResponses wraps every MCP tool result in {tool, ok, data, error}
+	// so generic clients see one top-level shape. Off by default for
+	// backward compatibility.
+	EnvelopeResponses bool `json:"envelope_responses,omitempty"`
+
+	// MaxResponseBytes caps the JSON payload of an MCP tool result. A larger
+	// result (e.g. a huge fetch_many or compose) is replaced by a
+	// RESPONSE_TOO_LARGE error so it can't flood the client's context.
+	// 0 means unlimited.
+	MaxResponseBytes int `json:"max_response_bytes,omitempty"`
+
+	// WorkspaceDefaultTags maps a workspace to tags added to every capsule
+	// stored in it (e.g. {"security": ["sec"]}). Keys are matched after
+	// workspace normalization; defaults are additive to explicit tags.
+	WorkspaceDefaultTags map[string][]string `json:"workspace_default_tags,omitempty"`
+
+	// ArchiveOnPurge makes purge move capsules into the archived_capsules
+	// table instead of discarding them. Archived capsules leave the live store
+	// (and search) but can be listed and restored.
+	ArchiveOnPurge bool `json:"archive_on_purge,omitempty"`
+
+	// DefaultIncludeDeleted makes fetch, list, inventory, latest, and search
+	// include soft-deleted capsules when a request doesn't pass include_deleted.
+	// An explicit include_deleted (true or false) always wins.
+	DefaultIncludeDeleted bool `json:"default_include_deleted,omitempty"`
+
+	// EmptyQueryListsAll makes a search with an empty query list every capsule
+	// matching the filters, newest first, instead of failing. Off by default.
+	EmptyQueryListsAll bool `json:"empty_query_lists_all,omitempty"`
+
+	// StoreDedupeWindowSeconds is the default dedupe window for unnamed stores:
+	// storing the same text and metadata (title, tags, run_id, phase, role) as
+	// an active unnamed capsule in the same workspace updated within this many seconds returns that capsule instead of a new
+	// one. 0 disables it unless a store asks for a window.
+	StoreDedupeWindowSeconds int `json:"store_dedupe_window_seconds,omitempty"`
+
+	// SearchCacheSize is how many search result pages to keep in an in-memory
+	// LRU, so repeated searches skip the FTS query and snippet work. Any write
+	// by this process invalidates the whole cache; writes by other processes
+	// on the same DB do not. 0 disables it. Read at startup.
+	SearchCacheSize int `json:"search_cache_size,omitempty"`
+
 	// UIPort is the port for the web UI server (moss serve).
 	UIPort int `json:"ui_port,omitempty"`
 
 	// UIBind is the bind address for the web UI server (moss serve).
 	UIBind string `json:"ui_bind,omitempty"`
+
+	// WebRateLimit throttles web UI requests per client IP (moss serve).
+	// Zero RequestsPerSecond disables rate limiting.
+	WebRateLimit RateLimit `json:"web_rate_limit,omitempty"`
+
+	// ListColumns chooses and orders the data columns of the web list and
+	// inventory tables (after Name / ID), e.g. ["title", "phase", "role", "updated"].
+	// Unknown names are ignored; empty keeps each table's default columns.
+	ListColumns []string `json:"list_columns,omitempty"`
+
+	// IDDisplayLen is how many ID characters the web UI shows for unnamed
+	// capsules before truncating with "...".
+	IDDisplayLen int `json:"id_display_len,omitempty"`
+
+	// ShutdownTimeout is how many seconds moss serve waits for in-flight
+	// requests to finish after SIGINT/SIGTERM before closing them.
+	ShutdownTimeout int `json:"shutdown_timeout_seconds,omitempty"`
+
+	// ExportFileMode is the permission mode export files are created with,
+	// written as an octal string (e.g. "0640"). Applied exactly, regardless of umask.
+	ExportFileMode FileMode `json:"export_file_mode,omitempty"`
+}
+
+// FileMode is a Unix permission mode that reads and writes as an octal JSON
+// string ("0600"). Only permission bits (0-0777) are accepted.
+type FileMode os.FileMode
+
+// MarshalJSON encodes the mode as an octal string.
+func (m FileMode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("%04o", uint32(m)))
+}
+
+// UnmarshalJSON decodes an octal string such as "0600" or "640".
+func (m *FileMode) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("file mode must be an octal string like \"0600\"")
+	}
+	n, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || n > 0o777 {
+		return fmt.Errorf("invalid file mode %q: want octal permission bits like \"0600\"", s)
+	}
+	*m = FileMode(n)
+	return nil
+}
+
+// RateLimit configures a token bucket: RequestsPerSecond refill rate, up to Burst tokens.
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requests_per_second,omitempty"`
+	Burst             int     `json:"burst,omitempty"`
 }
 
 // DefaultConfig returns the default configuration.
 func DefaultConfig() *Config {
 	return &Config{
-		CapsuleMaxChars: 12000,
-		UIPort:          8314,
-		UIBind:          "127.0.0.1",
+		CapsuleMaxChars:  12000,
+		MinSearchTermLen: 1,
+		UIPort:           8314,
+		UIBind:           "127.0.0.1",
+		IDDisplayLen:     10,
+		ShutdownTimeout:  5,
+		ExportFileMode:   0o600,
 	}
 }
 
@@ -80,6 +228,11 @@ func LoadWithRepo(globalDir, startDir string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	// The locale decides how handles in the shared global DB are normalized, so
+	// letting repos disagree would renormalize the whole DB on every switch
+	if repo.NormalizeLocale != "" {
+		return nil, fmt.Errorf("%s: normalize_locale can only be set in the global config", repoConfigPath)
+	}
 
 	// Apply defaults, then global, then repo
 	return Merge(Merge(DefaultConfig(), global), repo), nil
@@ -145,6 +298,21 @@ func Merge(base, overlay *Config) *Config {
 		result.CapsuleMaxChars = base.CapsuleMaxChars
 	}
 
+	result.CapsuleMaxBytes = overlay.CapsuleMaxBytes
+	if result.CapsuleMaxBytes == 0 {
+		result.CapsuleMaxBytes = base.CapsuleMaxBytes
+	}
+
+	result.MaxSections = overlay.MaxSections
+	if result.MaxSections == 0 {
+		result.MaxSections = base.MaxSections
+	}
+
+	result.MaxResponseBytes = overlay.MaxResponseBytes
+	if result.MaxResponseBytes == 0 {
+		result.MaxResponseBytes = base.MaxResponseBytes
+	}
+
 	result.DBMaxOpenConns = overlay.DBMaxOpenConns
 	if result.DBMaxOpenConns == 0 {
 		result.DBMaxOpenConns = base.DBMaxOpenConns
@@ -155,6 +323,26 @@ func Merge(base, overlay *Config) *Config {
 		result.DBMaxIdleConns = base.DBMaxIdleConns
 	}
 
+	result.MaxCapsulesPerWorkspace = overlay.MaxCapsulesPerWorkspace
+	if result.MaxCapsulesPerWorkspace == 0 {
+		result.MaxCapsulesPerWorkspace = base.MaxCapsulesPerWorkspace
+	}
+
+	result.MinSearchTermLen = overlay.MinSearchTermLen
+	if result.MinSearchTermLen == 0 {
+		result.MinSearchTermLen = base.MinSearchTermLen
+	}
+
+	result.StoreDedupeWindowSeconds = overlay.StoreDedupeWindowSeconds
+	if result.StoreDedupeWindowSeconds == 0 {
+		result.StoreDedupeWindowSeconds = base.StoreDedupeWindowSeconds
+	}
+
+	result.SearchCacheSize = overlay.SearchCacheSize
+	if result.SearchCacheSize == 0 {
+		result.SearchCacheSize = base.SearchCacheSize
+	}
+
 	result.UIPort = overlay.UIPort
 	if result.UIPort == 0 {
 		result.UIPort = base.UIPort
@@ -165,14 +353,75 @@ func Merge(base, overlay *Config) *Config {
 		result.UIBind = base.UIBind
 	}
 
+	result.WebRateLimit = overlay.WebRateLimit
+	if result.WebRateLimit.RequestsPerSecond == 0 {
+		result.WebRateLimit = base.WebRateLimit
+	}
+
+	result.IDDisplayLen = overlay.IDDisplayLen
+	if result.IDDisplayLen == 0 {
+		result.IDDisplayLen = base.IDDisplayLen
+	}
+
+	result.ShutdownTimeout = overlay.ShutdownTimeout
+	if result.ShutdownTimeout == 0 {
+		result.ShutdownTimeout = base.ShutdownTimeout
+	}
+
+	result.ExportFileMode = overlay.ExportFileMode
+	if result.ExportFileMode == 0 {
+		result.ExportFileMode = base.ExportFileMode
+	}
+
+	result.NormalizeLocale = overlay.NormalizeLocale
+	if result.NormalizeLocale == "" {
+		result.NormalizeLocale = base.NormalizeLocale
+	}
+
+	result.MirrorPath = overlay.MirrorPath
+	if result.MirrorPath == "" {
+		result.MirrorPath = base.MirrorPath
+	}
+
 	// Booleans: overlay wins if true, else base
 	result.AllowUnsafePaths = base.AllowUnsafePaths || overlay.AllowUnsafePaths
+	result.NormalizeOnStore = base.NormalizeOnStore || overlay.NormalizeOnStore
+	result.NormalizeTags = base.NormalizeTags || overlay.NormalizeTags
+	result.EnvelopeResponses = base.EnvelopeResponses || overlay.EnvelopeResponses
+	result.ArchiveOnPurge = base.ArchiveOnPurge || overlay.ArchiveOnPurge
+	result.DefaultIncludeDeleted = base.DefaultIncludeDeleted || overlay.DefaultIncludeDeleted
+	result.EmptyQueryListsAll = base.EmptyQueryListsAll || overlay.EmptyQueryListsAll
+	result.CompressText = base.CompressText || overlay.CompressText
 
 	// Arrays: merge and deduplicate
 	result.AllowedPaths = mergeStringSlice(base.AllowedPaths, overlay.AllowedPaths)
 	result.DisabledTools = mergeStringSlice(base.DisabledTools, overlay.DisabledTools)
 	result.DisabledTypes = mergeStringSlice(base.DisabledTypes, overlay.DisabledTypes)
 
+	// Ordered lists: overlay replaces base when set
+	result.ListColumns = overlay.ListColumns
+	if len(result.ListColumns) == 0 {
+		result.ListColumns = base.ListColumns
+	}
+
+	// Maps: union of workspaces, tag lists merged per workspace
+	result.WorkspaceDefaultTags = mergeTagMap(base.WorkspaceDefaultTags, overlay.WorkspaceDefaultTags)
+
+	return result
+}
+
+// mergeTagMap combines two workspace→tags maps, merging the lists of shared keys.
+func mergeTagMap(a, b map[string][]string) map[string][]string {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	result := make(map[string][]string, len(a)+len(b))
+	for k, v := range a {
+		result[k] = mergeStringSlice(v, nil)
+	}
+	for k, v := range b {
+		result[k] = mergeStringSlice(result[k], v)
+	}
 	return result
 }
 
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -126,6 +128,36 @@ func TestLoadWithRepo_BothPresent(t *testing.T) {
 	}
 }
 
+func TestLoadWithRepo_RejectsRepoNormalizeLocale(t *testing.T) {
+	globalDir := t.TempDir()
+	repoRoot := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(globalDir, "config.json"), []byte(`{"normalize_locale": "tr"}`), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	mossDir := filepath.Join(repoRoot, ".moss")
+	if err := os.MkdirAll(mossDir, 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+
+	// Global-only: allowed
+	cfg, err := LoadWithRepo(globalDir, repoRoot)
+	if err != nil {
+		t.Fatalf("LoadWithRepo() error = %v", err)
+	}
+	if cfg.NormalizeLocale != "tr" {
+		t.Errorf("NormalizeLocale = %q, want tr (global)", cfg.NormalizeLocale)
+	}
+
+	// Set in the repo config: rejected
+	if err := os.WriteFile(filepath.Join(mossDir, "config.json"), []byte(`{"normalize_locale": "az"}`), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := LoadWithRepo(globalDir, repoRoot); err == nil || !strings.Contains(err.Error(), "normalize_locale") {
+		t.Errorf("LoadWithRepo() error = %v, want normalize_locale rejection", err)
+	}
+}
+
 func TestLoadWithRepo_OnlyGlobal(t *testing.T) {
 	globalDir := t.TempDir()
 	repoDir := t.TempDir() // No config file
@@ -206,6 +238,75 @@ func TestMerge_ScalarOverride(t *testing.T) {
 	if result.DBMaxOpenConns != 5 {
 		t.Errorf("DBMaxOpenConns = %d, want 5 (base, overlay is zero)", result.DBMaxOpenConns)
 	}
+
+	result = Merge(&Config{CapsuleMaxBytes: 48000}, &Config{})
+	if result.CapsuleMaxBytes != 48000 {
+		t.Errorf("CapsuleMaxBytes = %d, want 48000 (base, overlay is zero)", result.CapsuleMaxBytes)
+	}
+	if DefaultConfig().CapsuleMaxBytes != 0 {
+		t.Errorf("default CapsuleMaxBytes = %d, want 0 (disabled)", DefaultConfig().CapsuleMaxBytes)
+	}
+
+	result = Merge(&Config{MaxCapsulesPerWorkspace: 50}, &Config{})
+	if result.MaxCapsulesPerWorkspace != 50 {
+		t.Errorf("MaxCapsulesPerWorkspace = %d, want 50 (base, overlay is zero)", result.MaxCapsulesPerWorkspace)
+	}
+
+	result = Merge(&Config{MaxSections: 12}, &Config{})
+	if result.MaxSections != 12 {
+		t.Errorf("MaxSections = %d, want 12 (base, overlay is zero)", result.MaxSections)
+	}
+
+	result = Merge(&Config{MaxResponseBytes: 1000}, &Config{MaxResponseBytes: 5000})
+	if result.MaxResponseBytes != 5000 {
+		t.Errorf("MaxResponseBytes = %d, want 5000 (overlay)", result.MaxResponseBytes)
+	}
+
+	result = Merge(&Config{StoreDedupeWindowSeconds: 30}, &Config{StoreDedupeWindowSeconds: 120})
+	if result.StoreDedupeWindowSeconds != 120 {
+		t.Errorf("StoreDedupeWindowSeconds = %d, want 120 (overlay)", result.StoreDedupeWindowSeconds)
+	}
+
+	result = Merge(DefaultConfig(), &Config{MinSearchTermLen: 3})
+	if result.MinSearchTermLen != 3 {
+		t.Errorf("MinSearchTermLen = %d, want 3 (overlay)", result.MinSearchTermLen)
+	}
+	result = Merge(DefaultConfig(), &Config{})
+	if result.MinSearchTermLen != 1 {
+		t.Errorf("MinSearchTermLen = %d, want 1 (default)", result.MinSearchTermLen)
+	}
+
+	result = Merge(DefaultConfig(), &Config{IDDisplayLen: 26})
+	if result.IDDisplayLen != 26 {
+		t.Errorf("IDDisplayLen = %d, want 26 (overlay)", result.IDDisplayLen)
+	}
+	result = Merge(DefaultConfig(), &Config{})
+	if result.IDDisplayLen != 10 {
+		t.Errorf("IDDisplayLen = %d, want 10 (default)", result.IDDisplayLen)
+	}
+
+	result = Merge(DefaultConfig(), &Config{ShutdownTimeout: 30})
+	if result.ShutdownTimeout != 30 {
+		t.Errorf("ShutdownTimeout = %d, want 30 (overlay)", result.ShutdownTimeout)
+	}
+	result = Merge(DefaultConfig(), &Config{})
+	if result.ShutdownTimeout != 5 {
+		t.Errorf("ShutdownTimeout = %d, want 5 (default)", result.ShutdownTimeout)
+	}
+
+	result = Merge(&Config{NormalizeLocale: "de"}, &Config{NormalizeLocale: "tr"})
+	if result.NormalizeLocale != "tr" {
+		t.Errorf("NormalizeLocale = %q, want tr (overlay)", result.NormalizeLocale)
+	}
+	result = Merge(&Config{NormalizeLocale: "de"}, &Config{})
+	if result.NormalizeLocale != "de" {
+		t.Errorf("NormalizeLocale = %q, want de (base)", result.NormalizeLocale)
+	}
+
+	result = Merge(&Config{MirrorPath: "/backup/moss.db"}, &Config{})
+	if result.MirrorPath != "/backup/moss.db" {
+		t.Errorf("MirrorPath = %q, want /backup/moss.db (base)", result.MirrorPath)
+	}
 }
 
 func TestMerge_BooleanOr(t *testing.T) {
@@ -217,6 +318,63 @@ func TestMerge_BooleanOr(t *testing.T) {
 	if !result.AllowUnsafePaths {
 		t.Error("AllowUnsafePaths should be true (base OR overlay)")
 	}
+
+	result = Merge(&Config{}, &Config{NormalizeOnStore: true})
+	if !result.NormalizeOnStore {
+		t.Error("NormalizeOnStore should be true (base OR overlay)")
+	}
+
+	result = Merge(&Config{EnvelopeResponses: true}, &Config{})
+	if !result.EnvelopeResponses {
+		t.Error("EnvelopeResponses should be true (base OR overlay)")
+	}
+
+	result = Merge(&Config{}, &Config{NormalizeTags: true})
+	if !result.NormalizeTags {
+		t.Error("NormalizeTags should be true (base OR overlay)")
+	}
+
+	result = Merge(&Config{ArchiveOnPurge: true}, &Config{})
+	if !result.ArchiveOnPurge {
+		t.Error("ArchiveOnPurge should be true (base OR overlay)")
+	}
+
+	result = Merge(&Config{}, &Config{DefaultIncludeDeleted: true})
+	if !result.DefaultIncludeDeleted {
+		t.Error("DefaultIncludeDeleted should be true (base OR overlay)")
+	}
+
+	result = Merge(&Config{ListColumns: []string{"title"}}, &Config{ListColumns: []string{"phase", "role"}})
+	if !slices.Equal(result.ListColumns, []string{"phase", "role"}) {
+		t.Errorf("ListColumns = %v, want overlay [phase role] (ordered, not merged)", result.ListColumns)
+	}
+
+	result = Merge(&Config{}, &Config{EmptyQueryListsAll: true})
+	if !result.EmptyQueryListsAll {
+		t.Error("EmptyQueryListsAll should be true (base OR overlay)")
+	}
+
+	result = Merge(&Config{CompressText: true}, &Config{})
+	if !result.CompressText {
+		t.Error("CompressText should be true (base OR overlay)")
+	}
+}
+
+func TestMerge_WebRateLimit(t *testing.T) {
+	base := &Config{WebRateLimit: RateLimit{RequestsPerSecond: 5, Burst: 10}}
+
+	// Overlay without a rate keeps base
+	result := Merge(base, &Config{})
+	if result.WebRateLimit != base.WebRateLimit {
+		t.Errorf("WebRateLimit = %+v, want base %+v", result.WebRateLimit, base.WebRateLimit)
+	}
+
+	// Overlay with a rate replaces the whole setting
+	overlay := &Config{WebRateLimit: RateLimit{RequestsPerSecond: 1}}
+	result = Merge(base, overlay)
+	if result.WebRateLimit != overlay.WebRateLimit {
+		t.Errorf("WebRateLimit = %+v, want overlay %+v", result.WebRateLimit, overlay.WebRateLimit)
+	}
 }
 
 func TestMerge_ArrayMergeDedup(t *testing.T) {
@@ -407,3 +565,68 @@ func TestLoadWithRepo_DisabledTypesMerge(t *testing.T) {
 		}
 	}
 }
+
+func TestMerge_WorkspaceDefaultTags(t *testing.T) {
+	base := &Config{WorkspaceDefaultTags: map[string][]string{
+		"security": {"sec"},
+		"infra":    {"ops"},
+	}}
+	overlay := &Config{WorkspaceDefaultTags: map[string][]string{
+		"security": {"sec", "review"},
+		"docs":     {"writing"},
+	}}
+
+	result := Merge(base, overlay)
+
+	want := map[string]string{
+		"security": "sec,review",
+		"infra":    "ops",
+		"docs":     "writing",
+	}
+	if len(result.WorkspaceDefaultTags) != len(want) {
+		t.Fatalf("WorkspaceDefaultTags = %v, want %d workspaces", result.WorkspaceDefaultTags, len(want))
+	}
+	for ws, tags := range want {
+		got := result.WorkspaceDefaultTags[ws]
+		if joined := strings.Join(got, ","); joined != tags {
+			t.Errorf("WorkspaceDefaultTags[%q] = %v, want %s", ws, got, tags)
+		}
+	}
+
+	if result := Merge(&Config{}, &Config{}); result.WorkspaceDefaultTags != nil {
+		t.Errorf("WorkspaceDefaultTags = %v, want nil when unset", result.WorkspaceDefaultTags)
+	}
+}
+
+func TestLoad_ExportFileMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.json")
+
+	cfg, err := Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ExportFileMode != 0o600 {
+		t.Errorf("default ExportFileMode = %o, want 600", cfg.ExportFileMode)
+	}
+
+	if err := os.WriteFile(configPath, []byte(`{"export_file_mode": "0640"}`), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	cfg, err = Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ExportFileMode != 0o640 {
+		t.Errorf("ExportFileMode = %o, want 640", cfg.ExportFileMode)
+	}
+
+	for _, bad := range []string{`"0999"`, `"4755"`, `420`} {
+		if err := os.WriteFile(configPath, []byte(`{"export_file_mode": `+bad+`}`), 0600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		if _, err := Load(tmpDir); err == nil {
+			t.Errorf("export_file_mode %s should fail to load", bad)
+		}
+	}
+}
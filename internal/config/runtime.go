@@ -0,0 +1,166 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// RuntimeKeys are the config keys that can be changed while Moss is running.
+// Everything else is read once at startup (tool registration, DB pool,
+// normalization locale, web server) or is security-sensitive (allowed paths).
+var RuntimeKeys = []string{
+	"archive_on_purge",
+	"capsule_max_bytes",
+	"capsule_max_chars",
+	"default_include_deleted",
+	"empty_query_lists_all",
+	"max_capsules_per_workspace",
+	"max_response_bytes",
+	"max_sections",
+	"min_search_term_len",
+	"normalize_on_store",
+	"normalize_tags",
+}
+
+var (
+	// ErrUnknownKey is returned for a key that is not a config field.
+	ErrUnknownKey = errors.New("unknown config key")
+
+	// ErrNotRuntimeKey is returned for a valid key that is not in RuntimeKeys.
+	ErrNotRuntimeKey = errors.New("config key cannot be changed at runtime; edit config.json and restart")
+)
+
+// IsKnownKey reports whether key is the JSON name of a Config field.
+func IsKnownKey(key string) bool {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// WithValue returns a copy of c with the runtime key set to the JSON value.
+// c itself is never modified, so callers can swap the copy in atomically.
+func (c *Config) WithValue(key string, value json.RawMessage) (*Config, error) {
+	if !slices.Contains(RuntimeKeys, key) {
+		if IsKnownKey(key) {
+			return nil, fmt.Errorf("%w: %s", ErrNotRuntimeKey, key)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, key)
+	}
+
+	next := *c
+	var err error
+	switch key {
+	case "archive_on_purge":
+		next.ArchiveOnPurge, err = decodeBool(key, value)
+	case "capsule_max_bytes":
+		next.CapsuleMaxBytes, err = decodeInt(key, value, 0)
+	case "capsule_max_chars":
+		next.CapsuleMaxChars, err = decodeInt(key, value, 1)
+	case "default_include_deleted":
+		next.DefaultIncludeDeleted, err = decodeBool(key, value)
+	case "empty_query_lists_all":
+		next.EmptyQueryListsAll, err = decodeBool(key, value)
+	case "max_capsules_per_workspace":
+		next.MaxCapsulesPerWorkspace, err = decodeInt(key, value, 0)
+	case "max_response_bytes":
+		next.MaxResponseBytes, err = decodeInt(key, value, 0)
+	case "max_sections":
+		next.MaxSections, err = decodeInt(key, value, 0)
+	case "min_search_term_len":
+		next.MinSearchTermLen, err = decodeInt(key, value, 1)
+	case "normalize_on_store":
+		next.NormalizeOnStore, err = decodeBool(key, value)
+	case "normalize_tags":
+		next.NormalizeTags, err = decodeBool(key, value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// SaveValue sets key to value in baseDir/config.json, keeping every other key
+// as written. The file is created if missing and replaced atomically.
+func SaveValue(baseDir, key string, value json.RawMessage) (string, error) {
+	configPath := filepath.Join(baseDir, "config.json")
+
+	fields := map[string]json.RawMessage{}
+	data, err := os.ReadFile(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return "", fmt.Errorf("parse %s: %w", configPath, err)
+		}
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, value); err != nil {
+		return "", err
+	}
+	fields[key] = compact.Bytes()
+
+	out, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	out = append(out, '\n')
+
+	if err := os.MkdirAll(baseDir, 0700); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(baseDir, ".config-*.json")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) //nolint:errcheck // no-op once renamed
+
+	if _, err := tmp.Write(out); err != nil {
+		tmp.Close() //nolint:errcheck
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close() //nolint:errcheck
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmpPath, configPath); err != nil {
+		return "", err
+	}
+	return configPath, nil
+}
+
+func decodeInt(key string, value json.RawMessage, minValue int) (int, error) {
+	var n int
+	if err := json.Unmarshal(value, &n); err != nil {
+		return 0, fmt.Errorf("%s must be an integer", key)
+	}
+	if n < minValue {
+		return 0, fmt.Errorf("%s must be at least %d", key, minValue)
+	}
+	return n, nil
+}
+
+func decodeBool(key string, value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err != nil {
+		return false, fmt.Errorf("%s must be a boolean", key)
+	}
+	return b, nil
+}
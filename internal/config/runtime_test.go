@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithValue(t *testing.T) {
+	cfg := DefaultConfig()
+
+	next, err := cfg.WithValue("normalize_tags", json.RawMessage(`true`))
+	if err != nil {
+		t.Fatalf("WithValue() error = %v", err)
+	}
+	if !next.NormalizeTags {
+		t.Error("NormalizeTags = false, want true")
+	}
+	if cfg.NormalizeTags {
+		t.Error("WithValue modified the receiver")
+	}
+
+	if _, err := cfg.WithValue("ui_port", json.RawMessage(`9000`)); !errors.Is(err, ErrNotRuntimeKey) {
+		t.Errorf("ui_port error = %v, want ErrNotRuntimeKey", err)
+	}
+	if _, err := cfg.WithValue("fts_tokenizer", json.RawMessage(`"porter"`)); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("fts_tokenizer error = %v, want ErrUnknownKey", err)
+	}
+	if _, err := cfg.WithValue("max_capsules_per_workspace", json.RawMessage(`-1`)); err == nil {
+		t.Error("negative max_capsules_per_workspace should be rejected")
+	}
+}
+
+func TestWithValue_HandlerReadKeys(t *testing.T) {
+	cfg := DefaultConfig()
+
+	next, err := cfg.WithValue("max_response_bytes", json.RawMessage(`4096`))
+	if err != nil {
+		t.Fatalf("max_response_bytes error = %v", err)
+	}
+	if next.MaxResponseBytes != 4096 {
+		t.Errorf("MaxResponseBytes = %d, want 4096", next.MaxResponseBytes)
+	}
+
+	next, err = next.WithValue("max_sections", json.RawMessage(`12`))
+	if err != nil {
+		t.Fatalf("max_sections error = %v", err)
+	}
+	if next.MaxSections != 12 {
+		t.Errorf("MaxSections = %d, want 12", next.MaxSections)
+	}
+
+	next, err = next.WithValue("empty_query_lists_all", json.RawMessage(`true`))
+	if err != nil {
+		t.Fatalf("empty_query_lists_all error = %v", err)
+	}
+	if !next.EmptyQueryListsAll {
+		t.Error("EmptyQueryListsAll = false, want true")
+	}
+
+	if _, err := cfg.WithValue("max_sections", json.RawMessage(`-1`)); err == nil {
+		t.Error("negative max_sections should be rejected")
+	}
+	if _, err := cfg.WithValue("max_response_bytes", json.RawMessage(`"big"`)); err == nil {
+		t.Error("non-integer max_response_bytes should be rejected")
+	}
+}
+
+func TestSaveValue_KeepsOtherKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"ui_port": 9000, "capsule_max_chars": 500}`), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	path, err := SaveValue(tmpDir, "capsule_max_chars", json.RawMessage(`800`))
+	if err != nil {
+		t.Fatalf("SaveValue() error = %v", err)
+	}
+	if path != configPath {
+		t.Errorf("path = %q, want %q", path, configPath)
+	}
+
+	cfg, err := Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.CapsuleMaxChars != 800 || cfg.UIPort != 9000 {
+		t.Errorf("CapsuleMaxChars = %d, UIPort = %d; want 800, 9000", cfg.CapsuleMaxChars, cfg.UIPort)
+	}
+}
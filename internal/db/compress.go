@@ -0,0 +1,86 @@
+package db
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/hpungsan/moss/internal/config"
+	"modernc.org/sqlite"
+)
+
+// compressTextEnabled is set by ConfigureTextCompression.
+var compressTextEnabled atomic.Bool
+
+// moss_text(capsule_text, capsule_text_gz) returns a row's plaintext:
+// capsule_text_gz decompressed when it is set (compressed rows leave
+// capsule_text empty), else capsule_text. Reads, the FTS triggers, and the FTS
+// content view all go through it, so callers never see compressed text.
+//
+// Because the schema calls it, only connections that register moss_text can
+// write capsules: a stock sqlite3 client can read the database, but any
+// INSERT, UPDATE, or DELETE on capsules fails with "no such function:
+// moss_text". Write through moss (CLI, MCP, web UI) instead.
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("moss_text", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		gz, ok := args[1].([]byte)
+		if !ok || gz == nil {
+			return args[0], nil
+		}
+		return decompressText(gz)
+	})
+}
+
+// ConfigureTextCompression applies config.CompressText: when on, capsule text
+// written from then on is stored gzip-compressed in capsule_text_gz. Existing
+// rows keep their format, and reads handle both. Call after Init.
+func ConfigureTextCompression(cfg *config.Config) {
+	compressTextEnabled.Store(cfg != nil && cfg.CompressText)
+}
+
+// storedText returns the capsule_text and capsule_text_gz values to write for
+// text. Text is compressed only when compression is on and it actually
+// shrinks; otherwise it is stored as is with a NULL capsule_text_gz.
+func storedText(text string) (string, []byte, error) {
+	if !compressTextEnabled.Load() {
+		return text, nil, nil
+	}
+	gz, err := compressText(text)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(gz) >= len(text) {
+		return text, nil, nil
+	}
+	return "", gz, nil
+}
+
+// compressText gzips text.
+func compressText(text string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(w, text); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressText reverses compressText.
+func decompressText(gz []byte) (string, error) {
+	r, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return "", fmt.Errorf("capsule_text_gz: %w", err)
+	}
+	defer r.Close()
+	text, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("capsule_text_gz: %w", err)
+	}
+	return string(text), nil
+}
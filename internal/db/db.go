@@ -1,18 +1,21 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 
+	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/config"
 	_ "modernc.org/sqlite"
 )
 
 // CurrentSchemaVersion is the latest schema version.
 // Bump this when adding migrations.
-const CurrentSchemaVersion = 2
+const CurrentSchemaVersion = 14
 
 // Init initializes the SQLite database at baseDir/moss.db.
 // The baseDir parameter allows tests to use t.TempDir() instead of ~/.moss.
@@ -31,8 +34,22 @@ func Init(baseDir string) (*sql.DB, error) {
 	}
 	_ = os.Chmod(exportsDir, 0700)
 
-	// Open database with pragmas in connection string (applies to all connections)
 	dbPath := filepath.Join(baseDir, "moss.db")
+	db, err := open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set file permissions after file exists (best-effort)
+	_ = os.Chmod(dbPath, 0600)
+
+	return db, nil
+}
+
+// open opens the SQLite file at dbPath in WAL mode and brings its schema
+// and normalized handles up to date.
+func open(dbPath string) (*sql.DB, error) {
+	// Open database with pragmas in connection string (applies to all connections)
 	dsn := dbPath + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
 	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
@@ -51,8 +68,11 @@ func Init(baseDir string) (*sql.DB, error) {
 		return nil, err
 	}
 
-	// Set file permissions after file exists (best-effort)
-	_ = os.Chmod(dbPath, 0600)
+	// Recompute normalized handles if the normalization locale changed
+	if err := syncNormalization(db); err != nil {
+		db.Close()
+		return nil, err
+	}
 
 	return db, nil
 }
@@ -179,12 +199,603 @@ func migrate(db *sql.DB) error {
 		}
 	}
 
+	// Migration 2 -> 3: Capsule links (directed, typed relationships)
+	if version < 3 {
+		linksSchema := `
+		CREATE TABLE IF NOT EXISTS capsule_links (
+		  from_id    TEXT NOT NULL,
+		  to_id      TEXT NOT NULL,
+		  relation   TEXT NOT NULL,
+		  created_at INTEGER NOT NULL,
+		  PRIMARY KEY (from_id, to_id, relation)
+		);
+
+		-- Incoming lookups (outgoing lookups use the primary key prefix)
+		CREATE INDEX IF NOT EXISTS idx_capsule_links_to_id
+		ON capsule_links(to_id);
+
+		-- Drop links when either endpoint is permanently deleted (purge)
+		CREATE TRIGGER IF NOT EXISTS capsule_links_cleanup AFTER DELETE ON capsules BEGIN
+			DELETE FROM capsule_links WHERE from_id = OLD.id OR to_id = OLD.id;
+		END;
+		`
+		if _, err := db.Exec(linksSchema); err != nil {
+			return fmt.Errorf("migration 3 (capsule links) failed: %w", err)
+		}
+		if err := SetUserVersion(db, 3); err != nil {
+			return err
+		}
+	}
+
+	// Migration 3 -> 4: Index name_raw in FTS5
+	// FTS5 tables can't be altered, so drop and recreate the table and its triggers.
+	if version < 4 {
+		ftsSchema := `
+		DROP TRIGGER IF EXISTS capsules_fts_insert;
+		DROP TRIGGER IF EXISTS capsules_fts_delete;
+		DROP TRIGGER IF EXISTS capsules_fts_update;
+		DROP TABLE IF EXISTS capsules_fts;
+
+		CREATE VIRTUAL TABLE capsules_fts USING fts5(
+			capsule_text,
+			title,
+			name_raw,
+			content='capsules',
+			content_rowid='rowid',
+			prefix='2 3 4'
+		);
+
+		CREATE TRIGGER capsules_fts_insert AFTER INSERT ON capsules BEGIN
+			INSERT INTO capsules_fts(rowid, capsule_text, title, name_raw)
+			VALUES (NEW.rowid, NEW.capsule_text, NEW.title, NEW.name_raw);
+		END;
+
+		CREATE TRIGGER capsules_fts_delete AFTER DELETE ON capsules BEGIN
+			INSERT INTO capsules_fts(capsules_fts, rowid, capsule_text, title, name_raw)
+			VALUES ('delete', OLD.rowid, OLD.capsule_text, OLD.title, OLD.name_raw);
+		END;
+
+		CREATE TRIGGER capsules_fts_update AFTER UPDATE OF capsule_text, title, name_raw ON capsules BEGIN
+			INSERT INTO capsules_fts(capsules_fts, rowid, capsule_text, title, name_raw)
+			VALUES ('delete', OLD.rowid, OLD.capsule_text, OLD.title, OLD.name_raw);
+			INSERT INTO capsules_fts(rowid, capsule_text, title, name_raw)
+			VALUES (NEW.rowid, NEW.capsule_text, NEW.title, NEW.name_raw);
+		END;
+		`
+		if _, err := db.Exec(ftsSchema); err != nil {
+			return fmt.Errorf("migration 4 (FTS5 name_raw) failed: %w", err)
+		}
+		if _, err := db.Exec("INSERT INTO capsules_fts(capsules_fts) VALUES('rebuild')"); err != nil {
+			return fmt.Errorf("migration 4 (FTS5 rebuild) failed: %w", err)
+		}
+		if err := SetUserVersion(db, 4); err != nil {
+			return err
+		}
+	}
+
+	// Migration 4 -> 5: Structured source provenance
+	// source_type: "agent" | "human" | "import"; source_ref: optional URL/reference.
+	// The legacy free-text source column is kept.
+	// SQLite has no ADD COLUMN IF NOT EXISTS, so check first to keep this re-runnable.
+	if version < 5 {
+		for _, col := range []string{"source_type", "source_ref"} {
+			exists, err := hasColumn(db, "capsules", col)
+			if err != nil {
+				return fmt.Errorf("migration 5 (source provenance) failed: %w", err)
+			}
+			if exists {
+				continue
+			}
+			if _, err := db.Exec("ALTER TABLE capsules ADD COLUMN " + col + " TEXT"); err != nil {
+				return fmt.Errorf("migration 5 (source provenance) failed: %w", err)
+			}
+		}
+		if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_capsules_source_type ON capsules(source_type)"); err != nil {
+			return fmt.Errorf("migration 5 (source provenance index) failed: %w", err)
+		}
+		if err := SetUserVersion(db, 5); err != nil {
+			return err
+		}
+	}
+
+	// Migration 5 -> 6: content_sha256 for change detection
+	// SQLite has no built-in SHA-256, so existing rows are hashed in Go.
+	if version < 6 {
+		exists, err := hasColumn(db, "capsules", "content_sha256")
+		if err != nil {
+			return fmt.Errorf("migration 6 (content hash) failed: %w", err)
+		}
+		if !exists {
+			if _, err := db.Exec("ALTER TABLE capsules ADD COLUMN content_sha256 TEXT NOT NULL DEFAULT ''"); err != nil {
+				return fmt.Errorf("migration 6 (content hash) failed: %w", err)
+			}
+		}
+		if err := backfillContentSHA256(db); err != nil {
+			return fmt.Errorf("migration 6 (content hash backfill) failed: %w", err)
+		}
+		if err := SetUserVersion(db, 6); err != nil {
+			return err
+		}
+	}
+
+	// Migration 6 -> 7: per-capsule expiry (expires_at, Unix seconds; NULL = never)
+	if version < 7 {
+		exists, err := hasColumn(db, "capsules", "expires_at")
+		if err != nil {
+			return fmt.Errorf("migration 7 (expiry) failed: %w", err)
+		}
+		if !exists {
+			if _, err := db.Exec("ALTER TABLE capsules ADD COLUMN expires_at INTEGER"); err != nil {
+				return fmt.Errorf("migration 7 (expiry) failed: %w", err)
+			}
+		}
+		// The sweep only looks at active capsules that have an expiry
+		if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_capsules_expires_at ON capsules(expires_at)
+			WHERE expires_at IS NOT NULL AND deleted_at IS NULL`); err != nil {
+			return fmt.Errorf("migration 7 (expiry index) failed: %w", err)
+		}
+		if err := SetUserVersion(db, 7); err != nil {
+			return err
+		}
+	}
+
+	// Migration 7 -> 8: settings table
+	// Records the normalize locale that produced name_norm/workspace_norm, so
+	// syncNormalization can recompute them after Unicode case folding was
+	// introduced and whenever the configured locale changes.
+	if version < 8 {
+		if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS settings (
+			key   TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		)`); err != nil {
+			return fmt.Errorf("migration 8 (settings) failed: %w", err)
+		}
+		if err := SetUserVersion(db, 8); err != nil {
+			return err
+		}
+	}
+
+	// Migration 8 -> 9: line/word/section counts alongside capsule_chars
+	// Counts follow capsule.ComputeMetrics, so existing rows are computed in Go.
+	if version < 9 {
+		for _, col := range []string{"line_count", "word_count", "section_count"} {
+			exists, err := hasColumn(db, "capsules", col)
+			if err != nil {
+				return fmt.Errorf("migration 9 (text metrics) failed: %w", err)
+			}
+			if !exists {
+				if _, err := db.Exec("ALTER TABLE capsules ADD COLUMN " + col + " INTEGER NOT NULL DEFAULT 0"); err != nil {
+					return fmt.Errorf("migration 9 (text metrics) failed: %w", err)
+				}
+			}
+		}
+		if err := backfillTextMetrics(db); err != nil {
+			return fmt.Errorf("migration 9 (text metrics backfill) failed: %w", err)
+		}
+		if err := SetUserVersion(db, 9); err != nil {
+			return err
+		}
+	}
+
+	// Migration 9 -> 10: archived_capsules (purge with config.ArchiveOnPurge)
+	// Same columns as capsules plus archived_at; not indexed by FTS.
+	if version < 10 {
+		if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS archived_capsules (
+		  id              TEXT PRIMARY KEY,
+		  workspace_raw   TEXT NOT NULL,
+		  workspace_norm  TEXT NOT NULL,
+		  name_raw        TEXT,
+		  name_norm       TEXT,
+		  title           TEXT,
+		  capsule_text    TEXT NOT NULL,
+		  capsule_chars   INTEGER NOT NULL,
+		  tokens_estimate INTEGER NOT NULL,
+		  content_sha256  TEXT NOT NULL DEFAULT '',
+		  line_count      INTEGER NOT NULL DEFAULT 0,
+		  word_count      INTEGER NOT NULL DEFAULT 0,
+		  section_count   INTEGER NOT NULL DEFAULT 0,
+		  tags_json       TEXT,
+		  source          TEXT,
+		  run_id          TEXT,
+		  phase           TEXT,
+		  role            TEXT,
+		  source_type     TEXT,
+		  source_ref      TEXT,
+		  created_at      INTEGER NOT NULL,
+		  updated_at      INTEGER NOT NULL,
+		  deleted_at      INTEGER,
+		  expires_at      INTEGER,
+		  archived_at     INTEGER NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_archived_capsules_workspace_archived
+		ON archived_capsules(workspace_norm, archived_at DESC)`); err != nil {
+			return fmt.Errorf("migration 10 (archived capsules) failed: %w", err)
+		}
+		if err := SetUserVersion(db, 10); err != nil {
+			return err
+		}
+	}
+
+	// Migration 10 -> 11: fts5vocab over capsules_fts (TopTerms)
+	// The instance view reports each term occurrence with its rowid, so term
+	// frequencies can be scoped to a workspace by joining capsules.
+	if version < 11 {
+		if _, err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS capsules_fts_vocab USING fts5vocab(capsules_fts, instance)`); err != nil {
+			return fmt.Errorf("migration 11 (fts vocab) failed: %w", err)
+		}
+		if err := SetUserVersion(db, 11); err != nil {
+			return err
+		}
+	}
+
+	// Migration 11 -> 12: note column
+	// A free-form annotation kept out of capsule_text, so it is not in the FTS
+	// index. archived_capsules mirrors capsules, so it gets the column too.
+	if version < 12 {
+		for _, table := range []string{"capsules", "archived_capsules"} {
+			exists, err := hasColumn(db, table, "note")
+			if err != nil {
+				return fmt.Errorf("migration 12 (note) failed: %w", err)
+			}
+			if exists {
+				continue
+			}
+			if _, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN note TEXT"); err != nil {
+				return fmt.Errorf("migration 12 (note) failed: %w", err)
+			}
+		}
+		if err := SetUserVersion(db, 12); err != nil {
+			return err
+		}
+	}
+
+	// Migration 12 -> 13: compressed capsule text (config.CompressText)
+	// Compressed rows keep '' in capsule_text and gzip in capsule_text_gz, so
+	// the FTS index can't read capsules directly any more: its content table
+	// becomes a view that decompresses through moss_text, and the triggers
+	// index moss_text too. archived_capsules mirrors capsules, so it gets the
+	// column as well.
+	if version < 13 {
+		for _, table := range []string{"capsules", "archived_capsules"} {
+			exists, err := hasColumn(db, table, "capsule_text_gz")
+			if err != nil {
+				return fmt.Errorf("migration 13 (compressed text) failed: %w", err)
+			}
+			if exists {
+				continue
+			}
+			if _, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN capsule_text_gz BLOB"); err != nil {
+				return fmt.Errorf("migration 13 (compressed text) failed: %w", err)
+			}
+		}
+
+		ftsSchema := `
+		DROP TABLE IF EXISTS capsules_fts_vocab;
+		DROP TRIGGER IF EXISTS capsules_fts_insert;
+		DROP TRIGGER IF EXISTS capsules_fts_delete;
+		DROP TRIGGER IF EXISTS capsules_fts_update;
+		DROP TABLE IF EXISTS capsules_fts;
+		DROP VIEW IF EXISTS capsules_fts_content;
+
+		CREATE VIEW capsules_fts_content AS
+		SELECT rowid AS capsule_rowid, moss_text(capsule_text, capsule_text_gz) AS capsule_text, title, name_raw
+		FROM capsules;
+
+		CREATE VIRTUAL TABLE capsules_fts USING fts5(
+			capsule_text,
+			title,
+			name_raw,
+			content='capsules_fts_content',
+			content_rowid='capsule_rowid',
+			prefix='2 3 4'
+		);
+
+		CREATE VIRTUAL TABLE capsules_fts_vocab USING fts5vocab(capsules_fts, instance);
+
+		CREATE TRIGGER capsules_fts_insert AFTER INSERT ON capsules BEGIN
+			INSERT INTO capsules_fts(rowid, capsule_text, title, name_raw)
+			VALUES (NEW.rowid, moss_text(NEW.capsule_text, NEW.capsule_text_gz), NEW.title, NEW.name_raw);
+		END;
+
+		CREATE TRIGGER capsules_fts_delete AFTER DELETE ON capsules BEGIN
+			INSERT INTO capsules_fts(capsules_fts, rowid, capsule_text, title, name_raw)
+			VALUES ('delete', OLD.rowid, moss_text(OLD.capsule_text, OLD.capsule_text_gz), OLD.title, OLD.name_raw);
+		END;
+
+		CREATE TRIGGER capsules_fts_update AFTER UPDATE OF capsule_text, capsule_text_gz, title, name_raw ON capsules BEGIN
+			INSERT INTO capsules_fts(capsules_fts, rowid, capsule_text, title, name_raw)
+			VALUES ('delete', OLD.rowid, moss_text(OLD.capsule_text, OLD.capsule_text_gz), OLD.title, OLD.name_raw);
+			INSERT INTO capsules_fts(rowid, capsule_text, title, name_raw)
+			VALUES (NEW.rowid, moss_text(NEW.capsule_text, NEW.capsule_text_gz), NEW.title, NEW.name_raw);
+		END;
+		`
+		if _, err := db.Exec(ftsSchema); err != nil {
+			return fmt.Errorf("migration 13 (FTS5 content view) failed: %w", err)
+		}
+		if _, err := db.Exec("INSERT INTO capsules_fts(capsules_fts) VALUES('rebuild')"); err != nil {
+			return fmt.Errorf("migration 13 (FTS5 rebuild) failed: %w", err)
+		}
+		if err := SetUserVersion(db, 13); err != nil {
+			return err
+		}
+	}
+
+	// Migration 13 -> 14: Compose provenance (which capsules a stored bundle was composed from)
+	if version < 14 {
+		sourcesSchema := `
+		CREATE TABLE IF NOT EXISTS compose_sources (
+		  bundle_id  TEXT NOT NULL,
+		  source_id  TEXT NOT NULL,
+		  position   INTEGER NOT NULL,
+		  created_at INTEGER NOT NULL,
+		  PRIMARY KEY (bundle_id, source_id)
+		);
+
+		-- "What bundles include X" lookups (a bundle's own sources use the primary key prefix)
+		CREATE INDEX IF NOT EXISTS idx_compose_sources_source_id
+		ON compose_sources(source_id);
+
+		-- Drop provenance when a bundle or source is permanently deleted (purge)
+		CREATE TRIGGER IF NOT EXISTS compose_sources_cleanup AFTER DELETE ON capsules BEGIN
+			DELETE FROM compose_sources WHERE bundle_id = OLD.id OR source_id = OLD.id;
+		END;
+		`
+		if _, err := db.Exec(sourcesSchema); err != nil {
+			return fmt.Errorf("migration 14 (compose sources) failed: %w", err)
+		}
+		if err := SetUserVersion(db, 14); err != nil {
+			return err
+		}
+	}
+
 	// Future migrations go here:
-	// if version < 3 { ... }
+	// if version < 15 { ... }
 
 	return nil
 }
 
+// backfillContentSHA256 hashes capsule_text for rows that don't have a content_sha256 yet.
+// Runs in one transaction so a failed backfill leaves no half-hashed table.
+func backfillContentSHA256(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	rows, err := tx.Query("SELECT id, capsule_text FROM capsules WHERE content_sha256 = ''")
+	if err != nil {
+		return err
+	}
+	hashes := make(map[string]string)
+	for rows.Next() {
+		var id, text string
+		if err := rows.Scan(&id, &text); err != nil {
+			rows.Close()
+			return err
+		}
+		hashes[id] = capsule.ContentSHA256(text)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	for id, hash := range hashes {
+		if _, err := tx.Exec("UPDATE capsules SET content_sha256 = ? WHERE id = ?", hash, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// backfillTextMetrics computes line_count, word_count, and section_count for every row.
+// Runs in one transaction so a failed backfill leaves no half-counted table.
+func backfillTextMetrics(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	rows, err := tx.Query("SELECT id, capsule_text FROM capsules")
+	if err != nil {
+		return err
+	}
+	metrics := make(map[string]capsule.Metrics)
+	for rows.Next() {
+		var id, text string
+		if err := rows.Scan(&id, &text); err != nil {
+			rows.Close()
+			return err
+		}
+		metrics[id] = capsule.ComputeMetrics(text)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	for id, m := range metrics {
+		if _, err := tx.Exec("UPDATE capsules SET line_count = ?, word_count = ?, section_count = ? WHERE id = ?",
+			m.Lines, m.Words, m.Sections, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// normalizeLocaleSetting is the settings key holding the locale name_norm and
+// workspace_norm were computed with ("" = language-independent folding).
+const normalizeLocaleSetting = "normalize_locale"
+
+// syncNormalization recomputes workspace_norm and name_norm for every capsule
+// when capsule.NormalizeLocale differs from the locale recorded in settings
+// (a missing record means pre-folding ToLower values). Runs in one transaction.
+//
+// Folding can merge handles that used to differ ("STRASSE" and "Straße"). Only
+// capsules whose handle changes are reassigned: a capsule already holding the
+// merged handle keeps it, whatever its age, and changed active capsules take it
+// in created order only while it is free, otherwise getting a -N suffix as with
+// import mode:rename. Each such rename is logged with the capsule ID and its old
+// and new name so the owner can find it.
+func syncNormalization(db *sql.DB) error {
+	locale := capsule.NormalizeLocale()
+
+	var stored string
+	err := db.QueryRow("SELECT value FROM settings WHERE key = ?", normalizeLocaleSetting).Scan(&stored)
+	if err == nil && stored == locale {
+		return nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read normalize locale: %w", err)
+	}
+
+	if err := renormalize(db, locale); err != nil {
+		return fmt.Errorf("failed to renormalize capsule handles: %w", err)
+	}
+	return nil
+}
+
+// renormalize rewrites normalized handles with capsule.Normalize and records locale.
+func renormalize(db *sql.DB, locale string) error {
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	type handle struct {
+		id            string
+		workspaceNorm string
+		nameRaw       string
+		nameNorm      *string
+		active        bool
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, workspace_raw, workspace_norm, name_raw, name_norm, deleted_at IS NULL
+		FROM capsules
+		ORDER BY created_at ASC, rowid ASC
+	`)
+	if err != nil {
+		return err
+	}
+	var changed []handle
+	for rows.Next() {
+		var (
+			id, wsRaw, wsNorm string
+			nameRaw, nameNorm sql.NullString
+			active            bool
+		)
+		if err := rows.Scan(&id, &wsRaw, &wsNorm, &nameRaw, &nameNorm, &active); err != nil {
+			rows.Close()
+			return err
+		}
+		h := handle{id: id, workspaceNorm: capsule.Normalize(wsRaw), active: active}
+		if nameRaw.Valid {
+			n := capsule.Normalize(nameRaw.String)
+			h.nameRaw, h.nameNorm = nameRaw.String, &n
+		}
+		if h.workspaceNorm != wsNorm || derefNull(h.nameNorm) != nameNorm.String {
+			changed = append(changed, h)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	// Release the changed rows' handles first so reassignment order can't
+	// collide with a value that is itself about to move.
+	for _, h := range changed {
+		if _, err := tx.ExecContext(ctx, "UPDATE capsules SET name_norm = NULL WHERE id = ?", h.id); err != nil {
+			return err
+		}
+	}
+
+	type rename struct{ id, workspaceNorm, from, to string }
+	var renamed []rename
+	for _, h := range changed {
+		if h.nameNorm == nil {
+			if _, err := tx.ExecContext(ctx, "UPDATE capsules SET workspace_norm = ? WHERE id = ?", h.workspaceNorm, h.id); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if h.active {
+			unique, err := FindUniqueName(ctx, tx, h.workspaceNorm, *h.nameNorm)
+			if err != nil {
+				return err
+			}
+			if unique != *h.nameNorm {
+				if _, err := tx.ExecContext(ctx,
+					"UPDATE capsules SET workspace_norm = ?, name_raw = ?, name_norm = ? WHERE id = ?",
+					h.workspaceNorm, unique, unique, h.id); err != nil {
+					return err
+				}
+				renamed = append(renamed, rename{h.id, h.workspaceNorm, h.nameRaw, unique})
+				continue
+			}
+		}
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE capsules SET workspace_norm = ?, name_norm = ? WHERE id = ?",
+			h.workspaceNorm, *h.nameNorm, h.id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, normalizeLocaleSetting, locale); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, r := range renamed {
+		log.Printf("moss: renormalizing handles renamed capsule %s in workspace %q from %q to %q (name collided after folding)",
+			r.id, r.workspaceNorm, r.from, r.to)
+	}
+	return nil
+}
+
+// derefNull returns the string s points to, or "" if nil.
+func derefNull(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// hasColumn reports whether table has a column with the given name.
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
 // verifyWALMode checks that WAL mode is active (set via connection string).
 func verifyWALMode(db *sql.DB) error {
 	var journalMode string
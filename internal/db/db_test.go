@@ -1,9 +1,15 @@
 package db
 
 import (
+	"bytes"
+	"database/sql"
+	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/hpungsan/moss/internal/capsule"
 )
 
 func TestInit(t *testing.T) {
@@ -144,6 +150,8 @@ func TestInit_SchemaIndexes(t *testing.T) {
 		"idx_capsules_workspace_run_id",
 		"idx_capsules_phase",
 		"idx_capsules_role",
+		"idx_capsules_source_type",
+		"idx_capsules_expires_at",
 	}
 
 	for _, idx := range indexes {
@@ -154,3 +162,276 @@ func TestInit_SchemaIndexes(t *testing.T) {
 		}
 	}
 }
+
+func TestInit_MigrationFTSNameRaw(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	db1, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("first Init() error = %v", err)
+	}
+
+	// Simulate a v3 database: FTS without name_raw, existing row
+	v3 := `
+	DROP TRIGGER capsules_fts_insert;
+	DROP TRIGGER capsules_fts_delete;
+	DROP TRIGGER capsules_fts_update;
+	DROP TABLE capsules_fts;
+	CREATE VIRTUAL TABLE capsules_fts USING fts5(
+		capsule_text, title, content='capsules', content_rowid='rowid', prefix='2 3 4'
+	);
+	INSERT INTO capsules (id, workspace_raw, workspace_norm, name_raw, name_norm, capsule_text,
+		capsule_chars, tokens_estimate, created_at, updated_at)
+	VALUES ('01MIGRATE', 'default', 'default', 'wombat-plan', 'wombat-plan', 'body', 4, 1, 1, 1);
+	`
+	if _, err := db1.Exec(v3); err != nil {
+		t.Fatalf("failed to set up v3 schema: %v", err)
+	}
+	if err := SetUserVersion(db1, 3); err != nil {
+		t.Fatalf("SetUserVersion() error = %v", err)
+	}
+	db1.Close()
+
+	db2, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("second Init() error = %v", err)
+	}
+	defer db2.Close()
+
+	// Existing rows are backfilled into the rebuilt index
+	var id string
+	err = db2.QueryRow(`
+		SELECT c.id FROM capsules c
+		INNER JOIN capsules_fts ON c.rowid = capsules_fts.rowid
+		WHERE capsules_fts MATCH 'wombat'`).Scan(&id)
+	if err != nil {
+		t.Fatalf("name_raw not searchable after migration: %v", err)
+	}
+	if id != "01MIGRATE" {
+		t.Errorf("id = %q, want 01MIGRATE", id)
+	}
+}
+
+func TestInit_MigrationSourceProvenance(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	db1, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("first Init() error = %v", err)
+	}
+
+	// Simulate a v4 database: no provenance columns, existing row
+	v4 := `
+	DROP INDEX idx_capsules_source_type;
+	ALTER TABLE capsules DROP COLUMN source_type;
+	ALTER TABLE capsules DROP COLUMN source_ref;
+	INSERT INTO capsules (id, workspace_raw, workspace_norm, capsule_text,
+		capsule_chars, tokens_estimate, source, created_at, updated_at)
+	VALUES ('01MIGRATE5', 'default', 'default', 'body', 4, 1, 'legacy', 1, 1);
+	`
+	if _, err := db1.Exec(v4); err != nil {
+		t.Fatalf("failed to set up v4 schema: %v", err)
+	}
+	if err := SetUserVersion(db1, 4); err != nil {
+		t.Fatalf("SetUserVersion() error = %v", err)
+	}
+	db1.Close()
+
+	db2, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("second Init() error = %v", err)
+	}
+	defer db2.Close()
+
+	// Existing rows keep legacy source and get NULL provenance
+	var (
+		source     string
+		sourceType sql.NullString
+		sourceRef  sql.NullString
+	)
+	err = db2.QueryRow("SELECT source, source_type, source_ref FROM capsules WHERE id = '01MIGRATE5'").
+		Scan(&source, &sourceType, &sourceRef)
+	if err != nil {
+		t.Fatalf("provenance columns missing after migration: %v", err)
+	}
+	if source != "legacy" || sourceType.Valid || sourceRef.Valid {
+		t.Errorf("got source=%q source_type=%v source_ref=%v, want legacy/NULL/NULL", source, sourceType, sourceRef)
+	}
+}
+
+func TestInit_MigrationContentSHA256Backfill(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	db1, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("first Init() error = %v", err)
+	}
+
+	// Simulate a v5 database: no checksum column, existing row
+	v5 := `
+	ALTER TABLE capsules DROP COLUMN content_sha256;
+	INSERT INTO capsules (id, workspace_raw, workspace_norm, capsule_text,
+		capsule_chars, tokens_estimate, created_at, updated_at)
+	VALUES ('01MIGRATE6', 'default', 'default', 'body', 4, 1, 1, 1);
+	`
+	if _, err := db1.Exec(v5); err != nil {
+		t.Fatalf("failed to set up v5 schema: %v", err)
+	}
+	if err := SetUserVersion(db1, 5); err != nil {
+		t.Fatalf("SetUserVersion() error = %v", err)
+	}
+	db1.Close()
+
+	db2, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("second Init() error = %v", err)
+	}
+	defer db2.Close()
+
+	var sum string
+	if err := db2.QueryRow("SELECT content_sha256 FROM capsules WHERE id = '01MIGRATE6'").Scan(&sum); err != nil {
+		t.Fatalf("content_sha256 missing after migration: %v", err)
+	}
+	// sha256("body")
+	want := "230d8358dc8e8890b4c58deeb62912ee2f20357ae92a5cc861b98e68fe31acb5"
+	if sum != want {
+		t.Errorf("content_sha256 = %q, want %q", sum, want)
+	}
+}
+
+func TestInit_MigrationTextMetricsBackfill(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	db1, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("first Init() error = %v", err)
+	}
+
+	// Simulate a v8 database: no metric columns, existing row
+	v8 := `
+	ALTER TABLE capsules DROP COLUMN line_count;
+	ALTER TABLE capsules DROP COLUMN word_count;
+	ALTER TABLE capsules DROP COLUMN section_count;
+	INSERT INTO capsules (id, workspace_raw, workspace_norm, capsule_text,
+		capsule_chars, tokens_estimate, created_at, updated_at)
+	VALUES ('01MIGRATE9', 'default', 'default', '## Status' || char(10) || 'two words' || char(10), 20, 5, 1, 1);
+	`
+	if _, err := db1.Exec(v8); err != nil {
+		t.Fatalf("failed to set up v8 schema: %v", err)
+	}
+	if err := SetUserVersion(db1, 8); err != nil {
+		t.Fatalf("SetUserVersion() error = %v", err)
+	}
+	db1.Close()
+
+	db2, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("second Init() error = %v", err)
+	}
+	defer db2.Close()
+
+	var lines, words, sections int
+	if err := db2.QueryRow("SELECT line_count, word_count, section_count FROM capsules WHERE id = '01MIGRATE9'").Scan(&lines, &words, &sections); err != nil {
+		t.Fatalf("metric columns missing after migration: %v", err)
+	}
+	if lines != 2 || words != 4 || sections != 1 {
+		t.Errorf("(lines, words, sections) = (%d, %d, %d), want (2, 4, 1)", lines, words, sections)
+	}
+}
+
+func TestInit_RenormalizesHandles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	db1, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("first Init() error = %v", err)
+	}
+
+	// Simulate a v7 database: handles lowercased with strings.ToLower, no recorded locale.
+	// "STRASSE" and "Straße" fold to the same handle; the older capsule keeps it.
+	v7 := `
+	DELETE FROM settings;
+	INSERT INTO capsules (id, workspace_raw, workspace_norm, name_raw, name_norm, capsule_text,
+		capsule_chars, tokens_estimate, created_at, updated_at)
+	VALUES
+		('01FOLD1', 'Großprojekt', 'großprojekt', 'STRASSE', 'strasse', 'body', 4, 1, 1, 1),
+		('01FOLD2', 'Großprojekt', 'großprojekt', 'Straße', 'straße', 'body', 4, 1, 2, 2),
+		('01FOLD3', 'Großprojekt', 'großprojekt', NULL, NULL, 'body', 4, 1, 3, 3);
+	`
+	if _, err := db1.Exec(v7); err != nil {
+		t.Fatalf("failed to set up v7 rows: %v", err)
+	}
+	db1.Close()
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	db2, err := Init(tmpDir)
+	log.SetOutput(os.Stderr)
+	if err != nil {
+		t.Fatalf("second Init() error = %v", err)
+	}
+
+	// The collision rename is logged with the ID and both names
+	if out := logs.String(); !strings.Contains(out, "01FOLD2") || !strings.Contains(out, `from "Straße" to "strasse-1"`) {
+		t.Errorf("rename log = %q, want 01FOLD2 renamed from Straße to strasse-1", out)
+	}
+	if n := strings.Count(logs.String(), "renamed capsule"); n != 1 {
+		t.Errorf("logged %d renames, want 1", n)
+	}
+
+	handles := func(db *sql.DB) map[string]string {
+		t.Helper()
+		rows, err := db.Query("SELECT id, workspace_norm, COALESCE(name_raw, ''), COALESCE(name_norm, '') FROM capsules")
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		defer rows.Close()
+		got := make(map[string]string)
+		for rows.Next() {
+			var id, ws, raw, norm string
+			if err := rows.Scan(&id, &ws, &raw, &norm); err != nil {
+				t.Fatalf("scan failed: %v", err)
+			}
+			got[id] = ws + "|" + raw + "|" + norm
+		}
+		return got
+	}
+
+	want := map[string]string{
+		"01FOLD1": "grossprojekt|STRASSE|strasse",
+		"01FOLD2": "grossprojekt|strasse-1|strasse-1",
+		"01FOLD3": "grossprojekt||",
+	}
+	got := handles(db2)
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("%s = %q, want %q", id, got[id], w)
+		}
+	}
+
+	// Stored with the default (language-independent) folding
+	if _, err := db2.Exec(`INSERT INTO capsules (id, workspace_raw, workspace_norm, name_raw, name_norm, capsule_text,
+		capsule_chars, tokens_estimate, created_at, updated_at)
+		VALUES ('01FOLD4', 'İzmir', ?, 'IRMAK', 'irmak', 'body', 4, 1, 4, 4)`, capsule.Normalize("İzmir")); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	db2.Close()
+
+	// Changing the locale recomputes handles on the next Init
+	t.Cleanup(func() { _ = capsule.SetNormalizeLocale("") })
+	if err := capsule.SetNormalizeLocale("tr"); err != nil {
+		t.Fatalf("SetNormalizeLocale failed: %v", err)
+	}
+	db3, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("third Init() error = %v", err)
+	}
+	defer db3.Close()
+	if got := handles(db3)["01FOLD4"]; got != "izmir|IRMAK|ırmak" {
+		t.Errorf("01FOLD4 with tr locale = %q, want %q", got, "izmir|IRMAK|ırmak")
+	}
+	var stored string
+	if err := db3.QueryRow("SELECT value FROM settings WHERE key = 'normalize_locale'").Scan(&stored); err != nil || stored != "tr" {
+		t.Errorf("recorded locale = %q, %v; want tr", stored, err)
+	}
+}
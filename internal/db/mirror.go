@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hpungsan/moss/internal/config"
+)
+
+// activeMirror is nil unless ConfigureMirror opened a mirror database.
+var activeMirror atomic.Pointer[sql.DB]
+
+// mirrorMu keeps replayed statements in the order they ran on the primary.
+var mirrorMu sync.Mutex
+
+// mirrorStmt is a write statement waiting to be replayed on the mirror.
+type mirrorStmt struct {
+	query string
+	args  []any
+}
+
+// pendingMirror holds the mirrored writes of each open transaction until
+// Commit replays them; Rollback discards them.
+var (
+	pendingMu     sync.Mutex
+	pendingMirror = make(map[*sql.Tx][]mirrorStmt)
+)
+
+// ConfigureMirror opens cfg.MirrorPath as a warm standby: from then on every
+// statement that changes the primary database is replayed against it. A
+// missing mirror file is first seeded with a snapshot of primary (VACUUM
+// INTO). An existing one is reseeded when its schema version or normalize
+// locale differs from primary, since migrations and renormalization run in
+// Init without a mirror; otherwise it is assumed to be in sync, so delete it
+// to force a reseed. An empty MirrorPath disables mirroring. Call after Init.
+func ConfigureMirror(primary *sql.DB, cfg *config.Config) error {
+	if cfg == nil || cfg.MirrorPath == "" {
+		return CloseMirror()
+	}
+	if !filepath.IsAbs(cfg.MirrorPath) {
+		return fmt.Errorf("mirror_path must be absolute: %s", cfg.MirrorPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.MirrorPath), 0700); err != nil {
+		return fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+	_, statErr := os.Stat(cfg.MirrorPath)
+	if statErr == nil {
+		stale, err := mirrorStale(primary, cfg.MirrorPath)
+		if err != nil {
+			return err
+		}
+		if stale {
+			if err := removeMirror(cfg.MirrorPath); err != nil {
+				return err
+			}
+			statErr = os.ErrNotExist
+		}
+	}
+	if errors.Is(statErr, os.ErrNotExist) {
+		if _, err := primary.Exec("VACUUM INTO ?", cfg.MirrorPath); err != nil {
+			return fmt.Errorf("failed to seed mirror: %w", err)
+		}
+	} else if statErr != nil {
+		return fmt.Errorf("failed to stat mirror: %w", statErr)
+	}
+
+	mirror, err := open(cfg.MirrorPath)
+	if err != nil {
+		return fmt.Errorf("mirror: %w", err)
+	}
+	_ = os.Chmod(cfg.MirrorPath, 0600)
+
+	if old := activeMirror.Swap(mirror); old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// mirrorStale reports whether the mirror at path missed writes Init made
+// before mirroring started: a schema migration or a renormalization.
+func mirrorStale(primary *sql.DB, path string) (bool, error) {
+	mirror, err := open(path)
+	if err != nil {
+		return false, fmt.Errorf("mirror: %w", err)
+	}
+	defer mirror.Close()
+
+	for _, q := range []string{
+		"PRAGMA user_version",
+		"SELECT COALESCE((SELECT value FROM settings WHERE key = '" + normalizeLocaleSetting + "'), '')",
+	} {
+		var want, got string
+		if err := primary.QueryRow(q).Scan(&want); err != nil {
+			return false, fmt.Errorf("failed to read primary state: %w", err)
+		}
+		// A mirror too old to have a settings table is stale
+		if err := mirror.QueryRow(q).Scan(&got); err != nil || got != want {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// removeMirror closes the active mirror, which may hold path open, and deletes
+// the file with its WAL so VACUUM INTO can seed a fresh one.
+func removeMirror(path string) error {
+	if err := CloseMirror(); err != nil {
+		return fmt.Errorf("failed to close mirror: %w", err)
+	}
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove stale mirror: %w", err)
+		}
+	}
+	return nil
+}
+
+// CloseMirror stops mirroring and closes the mirror database, if any.
+func CloseMirror() error {
+	if old := activeMirror.Swap(nil); old != nil {
+		return old.Close()
+	}
+	return nil
+}
+
+// execMirrored runs a write statement on q and, once it succeeds, replays it
+// on the mirror. Mirror failures are logged, never returned.
+func execMirrored(ctx context.Context, q Querier, query string, args ...any) (sql.Result, error) {
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	replayOnMirror(ctx, q, query, args...)
+	return result, nil
+}
+
+// replayOnMirror runs a write statement that already succeeded on q against
+// the mirror, if one is configured. A statement run on a *sql.Tx is held until
+// Commit, so the mirror never sees writes the primary rolls back. Used
+// directly for writes that go through QueryRowContext (INSERT ... RETURNING).
+func replayOnMirror(ctx context.Context, q Querier, query string, args ...any) {
+	mirror := activeMirror.Load()
+	if mirror == nil {
+		return
+	}
+
+	if tx, ok := q.(*sql.Tx); ok {
+		pendingMu.Lock()
+		pendingMirror[tx] = append(pendingMirror[tx], mirrorStmt{query: query, args: args})
+		pendingMu.Unlock()
+		return
+	}
+
+	mirrorMu.Lock()
+	defer mirrorMu.Unlock()
+	if _, err := mirror.ExecContext(context.WithoutCancel(ctx), query, args...); err != nil {
+		log.Printf("moss: mirror write failed: %v", err)
+	}
+}
+
+// takePending removes and returns the mirrored writes buffered for tx.
+func takePending(tx *sql.Tx) []mirrorStmt {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	stmts := pendingMirror[tx]
+	delete(pendingMirror, tx)
+	return stmts
+}
+
+// Commit commits tx and then replays its mirrored writes on the mirror in one
+// mirror transaction. Use it (with Rollback) for every transaction that writes
+// through this package; a bare tx.Commit leaves its writes off the mirror.
+func Commit(tx *sql.Tx) error {
+	stmts := takePending(tx)
+	if len(stmts) == 0 {
+		return tx.Commit()
+	}
+
+	// Held across the primary commit so transactions reach the mirror in
+	// the order they committed
+	mirrorMu.Lock()
+	defer mirrorMu.Unlock()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	mirror := activeMirror.Load()
+	if mirror == nil {
+		return nil
+	}
+	if err := replayTx(mirror, stmts); err != nil {
+		log.Printf("moss: mirror write failed: %v", err)
+	}
+	return nil
+}
+
+// Rollback rolls back tx and discards its buffered mirror writes. Safe to
+// defer alongside Commit: after a commit it returns sql.ErrTxDone.
+func Rollback(tx *sql.Tx) error {
+	takePending(tx)
+	return tx.Rollback()
+}
+
+// replayTx runs stmts on the mirror atomically.
+func replayTx(mirror *sql.DB, stmts []mirrorStmt) error {
+	mtx, err := mirror.Begin()
+	if err != nil {
+		return err
+	}
+	defer mtx.Rollback() //nolint:errcheck
+
+	for _, s := range stmts {
+		if _, err := mtx.Exec(s.query, s.args...); err != nil {
+			return err
+		}
+	}
+	return mtx.Commit()
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/config"
+)
+
+func TestConfigureMirror_ReplaysWrites(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	// Written before mirroring starts: reaches the mirror through the seed snapshot
+	if err := Insert(ctx, db, newTestCapsule("01SEEDED", "default", "Seeded content")); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	mirrorPath := filepath.Join(t.TempDir(), "standby", "moss.db")
+	if err := ConfigureMirror(db, &config.Config{MirrorPath: mirrorPath}); err != nil {
+		t.Fatalf("ConfigureMirror failed: %v", err)
+	}
+	t.Cleanup(func() { CloseMirror() }) //nolint:errcheck
+
+	c := newTestCapsule("01MIRRORED", "default", "Mirrored content")
+	c.NameRaw = stringPtr("auth")
+	c.NameNorm = stringPtr(capsule.Normalize("auth"))
+	if _, err := Upsert(ctx, db, c); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	// Writes inside a transaction are mirrored once it commits
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	if err := SoftDelete(ctx, tx, "01SEEDED"); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+	if err := Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	// ...and dropped if it rolls back
+	tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	if err := Insert(ctx, tx, newTestCapsule("01ROLLEDBACK", "default", "Rolled back")); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := Rollback(tx); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	if err := CloseMirror(); err != nil {
+		t.Fatalf("CloseMirror failed: %v", err)
+	}
+	mirror, err := open(mirrorPath)
+	if err != nil {
+		t.Fatalf("open mirror failed: %v", err)
+	}
+	defer mirror.Close()
+
+	got, err := GetByName(ctx, mirror, "default", "auth", false)
+	if err != nil {
+		t.Fatalf("capsule missing from mirror: %v", err)
+	}
+	if got.ID != "01MIRRORED" || got.CapsuleText != "Mirrored content" {
+		t.Errorf("mirror capsule = %s %q, want 01MIRRORED", got.ID, got.CapsuleText)
+	}
+
+	seeded, err := GetByID(ctx, mirror, "01SEEDED", true)
+	if err != nil {
+		t.Fatalf("seeded capsule missing from mirror: %v", err)
+	}
+	if seeded.DeletedAt == nil {
+		t.Error("soft delete was not replayed on the mirror")
+	}
+	if _, err := GetByID(ctx, mirror, "01ROLLEDBACK", true); err == nil {
+		t.Error("insert from a rolled-back transaction reached the mirror")
+	}
+
+	// Search works on the mirror: its FTS index followed the replayed writes
+	results, _, err := SearchFullText(ctx, mirror, "mirrored", SearchFilters{}, 10, 0, false, 0, SearchOrderRelevance, nil)
+	if err != nil {
+		t.Fatalf("SearchFullText on mirror failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("mirror search results = %d, want 1", len(results))
+	}
+}
+
+func TestCommit_HoldsMirrorWritesUntilCommit(t *testing.T) {
+	db, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	mirrorPath := filepath.Join(t.TempDir(), "moss.db")
+	if err := ConfigureMirror(db, &config.Config{MirrorPath: mirrorPath}); err != nil {
+		t.Fatalf("ConfigureMirror failed: %v", err)
+	}
+	t.Cleanup(func() { CloseMirror() }) //nolint:errcheck
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	defer Rollback(tx) //nolint:errcheck
+	if err := Insert(ctx, tx, newTestCapsule("01PENDING", "default", "Pending")); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	mirror := activeMirror.Load()
+	if _, err := GetByID(ctx, mirror, "01PENDING", true); err == nil {
+		t.Fatal("uncommitted insert reached the mirror")
+	}
+
+	if err := Commit(tx); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if _, err := GetByID(ctx, mirror, "01PENDING", true); err != nil {
+		t.Errorf("committed insert missing from mirror: %v", err)
+	}
+	if len(pendingMirror) != 0 {
+		t.Errorf("pending mirror buffers = %d, want 0", len(pendingMirror))
+	}
+}
+
+func TestConfigureMirror_ReseedsAfterMigration(t *testing.T) {
+	db, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	mirrorPath := filepath.Join(t.TempDir(), "moss.db")
+	if err := ConfigureMirror(db, &config.Config{MirrorPath: mirrorPath}); err != nil {
+		t.Fatalf("ConfigureMirror failed: %v", err)
+	}
+	if err := CloseMirror(); err != nil {
+		t.Fatalf("CloseMirror failed: %v", err)
+	}
+
+	// Simulate a restart after Init migrated the primary and renormalized a
+	// handle, neither of which went through the mirror
+	if err := Insert(ctx, db, newTestCapsule("01UNMIRRORED", "default", "Written while mirroring was off")); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	version, err := GetUserVersion(db)
+	if err != nil {
+		t.Fatalf("GetUserVersion failed: %v", err)
+	}
+	if err := SetUserVersion(db, version+1); err != nil {
+		t.Fatalf("SetUserVersion failed: %v", err)
+	}
+
+	if err := ConfigureMirror(db, &config.Config{MirrorPath: mirrorPath}); err != nil {
+		t.Fatalf("ConfigureMirror failed: %v", err)
+	}
+	t.Cleanup(func() { CloseMirror() }) //nolint:errcheck
+
+	mirror := activeMirror.Load()
+	if got, err := GetUserVersion(mirror); err != nil || got != version+1 {
+		t.Errorf("mirror user_version = %d (%v), want %d", got, err, version+1)
+	}
+	if _, err := GetByID(ctx, mirror, "01UNMIRRORED", true); err != nil {
+		t.Errorf("reseeded mirror is missing the primary's rows: %v", err)
+	}
+}
+
+func TestConfigureMirror_RejectsRelativePath(t *testing.T) {
+	db, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer db.Close()
+
+	if err := ConfigureMirror(db, &config.Config{MirrorPath: "standby.db"}); err == nil {
+		CloseMirror() //nolint:errcheck
+		t.Fatal("expected an error for a relative mirror_path")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -45,25 +46,34 @@ func Insert(ctx context.Context, q Querier, c *capsule.Capsule) error {
 	runID := toNullString(c.RunID)
 	phase := toNullString(c.Phase)
 	role := toNullString(c.Role)
+	sourceType := toNullString(c.SourceType)
+	sourceRef := toNullString(c.SourceRef)
+	note := toNullString(c.Note)
+	text, textGz, err := storedText(c.CapsuleText)
+	if err != nil {
+		return errors.NewInternal(err)
+	}
 
 	query := `
 		INSERT INTO capsules (
 			id, workspace_raw, workspace_norm, name_raw, name_norm,
-			title, capsule_text, capsule_chars, tokens_estimate,
-			tags_json, source, run_id, phase, role,
-			created_at, updated_at, deleted_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL)
+			title, capsule_text, capsule_text_gz, capsule_chars, tokens_estimate, content_sha256,
+			line_count, word_count, section_count,
+			tags_json, source, run_id, phase, role, source_type, source_ref, note,
+			created_at, updated_at, deleted_at, expires_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, ?)
 	`
 
-	_, err := q.ExecContext(ctx, query,
+	_, err = execMirrored(ctx, q, query,
 		c.ID, c.WorkspaceRaw, c.WorkspaceNorm, nameRaw, nameNorm,
-		title, c.CapsuleText, c.CapsuleChars, c.TokensEstimate,
-		tagsJSON, source, runID, phase, role,
-		c.CreatedAt, c.UpdatedAt,
+		title, text, textGz, c.CapsuleChars, c.TokensEstimate, capsule.ContentSHA256(c.CapsuleText),
+		c.LineCount, c.WordCount, c.SectionCount,
+		tagsJSON, source, runID, phase, role, sourceType, sourceRef, note,
+		c.CreatedAt, c.UpdatedAt, toNullInt64(c.ExpiresAt),
 	)
 	if err != nil {
 		if isNameUniquenessViolation(err) && c.NameRaw != nil {
-			return errors.NewNameAlreadyExists(c.WorkspaceRaw, *c.NameRaw)
+			return errors.NewNameAlreadyExists(c.WorkspaceRaw, *c.NameRaw, nameHolderID(ctx, q, c.WorkspaceNorm, *c.NameNorm))
 		}
 		return errors.NewInternal(err)
 	}
@@ -96,7 +106,7 @@ type UpsertResult struct {
 // For unnamed capsules (name is nil): Always inserts (no conflict possible).
 //
 // On update, preserves: id, workspace_raw/norm, name_raw/norm, created_at
-// On update, changes: capsule_text, title, tags, source, run_id, phase, role, updated_at, metrics
+// On update, changes: capsule_text, title, tags, source, source_type/ref, note, run_id, phase, role, expires_at, updated_at, metrics
 func Upsert(ctx context.Context, q Querier, c *capsule.Capsule) (*UpsertResult, error) {
 	// Convert tags to JSON
 	var tagsJSON sql.NullString
@@ -116,6 +126,13 @@ func Upsert(ctx context.Context, q Querier, c *capsule.Capsule) (*UpsertResult,
 	runID := toNullString(c.RunID)
 	phase := toNullString(c.Phase)
 	role := toNullString(c.Role)
+	sourceType := toNullString(c.SourceType)
+	sourceRef := toNullString(c.SourceRef)
+	note := toNullString(c.Note)
+	text, textGz, err := storedText(c.CapsuleText)
+	if err != nil {
+		return nil, errors.NewInternal(err)
+	}
 
 	// Use SQLite UPSERT syntax with partial index conflict target.
 	// The conflict target matches our unique partial index:
@@ -132,36 +149,50 @@ func Upsert(ctx context.Context, q Querier, c *capsule.Capsule) (*UpsertResult,
 	query := `
 		INSERT INTO capsules (
 			id, workspace_raw, workspace_norm, name_raw, name_norm,
-			title, capsule_text, capsule_chars, tokens_estimate,
-			tags_json, source, run_id, phase, role,
-			created_at, updated_at, deleted_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL)
+			title, capsule_text, capsule_text_gz, capsule_chars, tokens_estimate, content_sha256,
+			line_count, word_count, section_count,
+			tags_json, source, run_id, phase, role, source_type, source_ref, note,
+			created_at, updated_at, deleted_at, expires_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, ?)
 		ON CONFLICT(workspace_norm, name_norm) WHERE name_norm IS NOT NULL AND deleted_at IS NULL
 		DO UPDATE SET
 			title = excluded.title,
 			capsule_text = excluded.capsule_text,
+			capsule_text_gz = excluded.capsule_text_gz,
 			capsule_chars = excluded.capsule_chars,
 			tokens_estimate = excluded.tokens_estimate,
+			content_sha256 = excluded.content_sha256,
+			line_count = excluded.line_count,
+			word_count = excluded.word_count,
+			section_count = excluded.section_count,
 			tags_json = excluded.tags_json,
 			source = excluded.source,
 			run_id = excluded.run_id,
 			phase = excluded.phase,
 			role = excluded.role,
+			source_type = excluded.source_type,
+			source_ref = excluded.source_ref,
+			note = excluded.note,
+			expires_at = excluded.expires_at,
 			updated_at = excluded.updated_at
 		RETURNING id
 	`
 
-	var resultID string
-	err := q.QueryRowContext(ctx, query,
+	args := []any{
 		c.ID, c.WorkspaceRaw, c.WorkspaceNorm, nameRaw, nameNorm,
-		title, c.CapsuleText, c.CapsuleChars, c.TokensEstimate,
-		tagsJSON, source, runID, phase, role,
-		c.CreatedAt, c.UpdatedAt,
-	).Scan(&resultID)
+		title, text, textGz, c.CapsuleChars, c.TokensEstimate, capsule.ContentSHA256(c.CapsuleText),
+		c.LineCount, c.WordCount, c.SectionCount,
+		tagsJSON, source, runID, phase, role, sourceType, sourceRef, note,
+		c.CreatedAt, c.UpdatedAt, toNullInt64(c.ExpiresAt),
+	}
+
+	var resultID string
+	err = q.QueryRowContext(ctx, query, args...).Scan(&resultID)
 
 	if err != nil {
 		return nil, errors.NewInternal(err)
 	}
+	replayOnMirror(ctx, q, query, args...)
 
 	return &UpsertResult{
 		ID:        resultID,
@@ -170,21 +201,25 @@ func Upsert(ctx context.Context, q Querier, c *capsule.Capsule) (*UpsertResult,
 }
 
 // GetByID retrieves a capsule by its ULID.
-// If includeDeleted is false, soft-deleted capsules are excluded.
+// If includeDeleted is false, soft-deleted capsules are excluded, and so are
+// expired capsules the sweep hasn't soft-deleted yet.
 func GetByID(ctx context.Context, q Querier, id string, includeDeleted bool) (*capsule.Capsule, error) {
 	query := `
 		SELECT id, workspace_raw, workspace_norm, name_raw, name_norm,
-			title, capsule_text, capsule_chars, tokens_estimate,
-			tags_json, source, run_id, phase, role,
-			created_at, updated_at, deleted_at
+			title, moss_text(capsule_text, capsule_text_gz) AS capsule_text, capsule_chars, tokens_estimate, content_sha256,
+			line_count, word_count, section_count,
+			tags_json, source, run_id, phase, role, source_type, source_ref, note,
+			created_at, updated_at, deleted_at, expires_at
 		FROM capsules
 		WHERE id = ?
 	`
+	args := []any{id}
 	if !includeDeleted {
-		query += " AND deleted_at IS NULL"
+		query += " AND deleted_at IS NULL AND " + notExpiredCondition
+		args = append(args, time.Now().Unix())
 	}
 
-	row := q.QueryRowContext(ctx, query, id)
+	row := q.QueryRowContext(ctx, query, args...)
 	c, err := scanCapsule(row)
 	if err == sql.ErrNoRows {
 		return nil, errors.NewNotFound(id)
@@ -196,26 +231,77 @@ func GetByID(ctx context.Context, q Querier, id string, includeDeleted bool) (*c
 	return c, nil
 }
 
-// GetByName retrieves a capsule by normalized workspace and name.
+// GetByIDPrefix retrieves a capsule whose ID starts with prefix (case-insensitive:
+// ULIDs are uppercase, so prefix is uppercased first).
+// Returns ErrAmbiguousAddressing if more than one capsule matches, ErrNotFound if none.
 // If includeDeleted is false, soft-deleted capsules are excluded.
+func GetByIDPrefix(ctx context.Context, q Querier, prefix string, includeDeleted bool) (*capsule.Capsule, error) {
+	// A range on id rather than LIKE, which SQLite can't serve from the
+	// primary key index (LIKE is case-insensitive, the index is not)
+	prefix = strings.ToUpper(prefix)
+	query := "SELECT id FROM capsules WHERE id >= ?"
+	args := []any{prefix}
+	if upper := prefixUpperBound(prefix); upper != "" {
+		query += " AND id < ?"
+		args = append(args, upper)
+	}
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL AND " + notExpiredCondition
+		args = append(args, time.Now().Unix())
+	}
+	query += " LIMIT 2"
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewInternal(err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	switch len(ids) {
+	case 0:
+		return nil, errors.NewNotFound(prefix)
+	case 1:
+		return GetByID(ctx, q, ids[0], includeDeleted)
+	default:
+		return nil, errors.NewAmbiguousIDPrefix(prefix)
+	}
+}
+
+// GetByName retrieves a capsule by normalized workspace and name.
+// If includeDeleted is false, soft-deleted and expired capsules are excluded.
 func GetByName(ctx context.Context, q Querier, workspaceNorm, nameNorm string, includeDeleted bool) (*capsule.Capsule, error) {
 	query := `
 		SELECT id, workspace_raw, workspace_norm, name_raw, name_norm,
-			title, capsule_text, capsule_chars, tokens_estimate,
-			tags_json, source, run_id, phase, role,
-			created_at, updated_at, deleted_at
+			title, moss_text(capsule_text, capsule_text_gz) AS capsule_text, capsule_chars, tokens_estimate, content_sha256,
+			line_count, word_count, section_count,
+			tags_json, source, run_id, phase, role, source_type, source_ref, note,
+			created_at, updated_at, deleted_at, expires_at
 		FROM capsules
 		WHERE workspace_norm = ? AND name_norm = ?
 	`
+	args := []any{workspaceNorm, nameNorm}
 	if !includeDeleted {
-		query += " AND deleted_at IS NULL LIMIT 1"
+		query += " AND deleted_at IS NULL AND " + notExpiredCondition + " LIMIT 1"
+		args = append(args, time.Now().Unix())
 	} else {
 		// If both active and soft-deleted capsules exist for the same name, prefer the active one.
 		// If no active capsule exists, return the most recently updated deleted capsule.
 		query += " ORDER BY (deleted_at IS NULL) DESC, updated_at DESC LIMIT 1"
 	}
 
-	row := q.QueryRowContext(ctx, query, workspaceNorm, nameNorm)
+	row := q.QueryRowContext(ctx, query, args...)
 	c, err := scanCapsule(row)
 	if err == sql.ErrNoRows {
 		return nil, errors.NewNotFound(workspaceNorm + "/" + nameNorm)
@@ -247,10 +333,117 @@ func CheckNameExists(ctx context.Context, q Querier, workspaceNorm, nameNorm str
 	return true, nil
 }
 
+// nameHolderID returns the ID of the active capsule named nameNorm in the
+// workspace, for NAME_ALREADY_EXISTS details. Returns "" if none is found or
+// the lookup fails: the collision is still reported, just without the ID.
+func nameHolderID(ctx context.Context, q Querier, workspaceNorm, nameNorm string) string {
+	var id string
+	err := q.QueryRowContext(ctx, `
+		SELECT id FROM capsules
+		WHERE workspace_norm = ? AND name_norm = ? AND deleted_at IS NULL
+		LIMIT 1`, workspaceNorm, nameNorm).Scan(&id)
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+// ExistsByID reports whether a capsule with the given ID exists.
+// If includeDeleted is false, soft-deleted and expired capsules don't count.
+func ExistsByID(ctx context.Context, q Querier, id string, includeDeleted bool) (bool, error) {
+	query := "SELECT 1 FROM capsules WHERE id = ?"
+	args := []any{id}
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL AND " + notExpiredCondition
+		args = append(args, time.Now().Unix())
+	}
+
+	var exists int
+	err := q.QueryRowContext(ctx, query+" LIMIT 1", args...).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.NewInternal(err)
+	}
+	return true, nil
+}
+
+// IDByName returns the ID of the capsule with the given normalized workspace
+// and name, or "" if there is none. It picks the same capsule as GetByName.
+func IDByName(ctx context.Context, q Querier, workspaceNorm, nameNorm string, includeDeleted bool) (string, error) {
+	query := "SELECT id FROM capsules WHERE workspace_norm = ? AND name_norm = ?"
+	args := []any{workspaceNorm, nameNorm}
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL AND " + notExpiredCondition + " LIMIT 1"
+		args = append(args, time.Now().Unix())
+	} else {
+		query += " ORDER BY (deleted_at IS NULL) DESC, updated_at DESC LIMIT 1"
+	}
+
+	var id string
+	err := q.QueryRowContext(ctx, query, args...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.NewInternal(err)
+	}
+	return id, nil
+}
+
+// RecentUnnamedDuplicateID returns the ID of the most recently updated active
+// unnamed capsule in c's workspace with the same text, title, tags (in order),
+// run_id, phase and role as c and an updated_at at or after since, or "" if
+// there is none.
+func RecentUnnamedDuplicateID(ctx context.Context, q Querier, c *capsule.Capsule, since int64) (string, error) {
+	var tagsJSON sql.NullString
+	if len(c.Tags) > 0 {
+		data, err := json.Marshal(c.Tags)
+		if err != nil {
+			return "", errors.NewInternal(err)
+		}
+		tagsJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	query := `
+		SELECT id FROM capsules
+		WHERE workspace_norm = ? AND name_norm IS NULL AND content_sha256 = ? AND updated_at >= ?
+			AND title IS ? AND tags_json IS ? AND run_id IS ? AND phase IS ? AND role IS ?
+			AND deleted_at IS NULL AND ` + notExpiredCondition + `
+		ORDER BY updated_at DESC, rowid DESC LIMIT 1`
+
+	var id string
+	err := q.QueryRowContext(ctx, query,
+		c.WorkspaceNorm, capsule.ContentSHA256(c.CapsuleText), since,
+		toNullString(c.Title), tagsJSON, toNullString(c.RunID), toNullString(c.Phase), toNullString(c.Role),
+		time.Now().Unix(),
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.NewInternal(err)
+	}
+	return id, nil
+}
+
+// CountActiveInWorkspace returns the number of active capsules in a workspace.
+func CountActiveInWorkspace(ctx context.Context, q Querier, workspaceNorm string) (int, error) {
+	var count int
+	err := q.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM capsules WHERE workspace_norm = ? AND deleted_at IS NULL",
+		workspaceNorm).Scan(&count)
+	if err != nil {
+		return 0, errors.NewInternal(err)
+	}
+	return count, nil
+}
+
 // UpdateByID updates mutable fields of an existing capsule.
 // Sets updated_at to current timestamp.
 // Does NOT change: id, workspace, name
-func UpdateByID(ctx context.Context, db *sql.DB, c *capsule.Capsule) error {
+func UpdateByID(ctx context.Context, q Querier, c *capsule.Capsule) error {
 	// Convert tags to JSON
 	var tagsJSON sql.NullString
 	if len(c.Tags) > 0 {
@@ -267,21 +460,31 @@ func UpdateByID(ctx context.Context, db *sql.DB, c *capsule.Capsule) error {
 	runID := toNullString(c.RunID)
 	phase := toNullString(c.Phase)
 	role := toNullString(c.Role)
+	sourceType := toNullString(c.SourceType)
+	sourceRef := toNullString(c.SourceRef)
+	note := toNullString(c.Note)
+
+	text, textGz, err := storedText(c.CapsuleText)
+	if err != nil {
+		return errors.NewInternal(err)
+	}
 
 	now := time.Now().Unix()
 
 	query := `
 		UPDATE capsules
-		SET capsule_text = ?, title = ?, tags_json = ?, source = ?,
-			run_id = ?, phase = ?, role = ?,
-			capsule_chars = ?, tokens_estimate = ?, updated_at = ?
+		SET capsule_text = ?, capsule_text_gz = ?, title = ?, tags_json = ?, source = ?,
+			run_id = ?, phase = ?, role = ?, source_type = ?, source_ref = ?, note = ?,
+			capsule_chars = ?, tokens_estimate = ?, content_sha256 = ?,
+			line_count = ?, word_count = ?, section_count = ?, expires_at = ?, updated_at = ?
 		WHERE id = ? AND deleted_at IS NULL
 	`
 
-	result, err := db.ExecContext(ctx, query,
-		c.CapsuleText, title, tagsJSON, source,
-		runID, phase, role,
-		c.CapsuleChars, c.TokensEstimate, now,
+	result, err := execMirrored(ctx, q, query,
+		text, textGz, title, tagsJSON, source,
+		runID, phase, role, sourceType, sourceRef, note,
+		c.CapsuleChars, c.TokensEstimate, capsule.ContentSHA256(c.CapsuleText),
+		c.LineCount, c.WordCount, c.SectionCount, toNullInt64(c.ExpiresAt), now,
 		c.ID,
 	)
 	if err != nil {
@@ -304,7 +507,7 @@ func UpdateByID(ctx context.Context, db *sql.DB, c *capsule.Capsule) error {
 
 // SoftDelete marks a capsule as deleted by setting deleted_at.
 // Also bumps updated_at so deletion is reflected in "latest" ordering.
-func SoftDelete(ctx context.Context, db *sql.DB, id string) error {
+func SoftDelete(ctx context.Context, q Querier, id string) error {
 	now := time.Now().Unix()
 
 	query := `
@@ -313,7 +516,7 @@ func SoftDelete(ctx context.Context, db *sql.DB, id string) error {
 		WHERE id = ? AND deleted_at IS NULL
 	`
 
-	result, err := db.ExecContext(ctx, query, now, now, id)
+	result, err := execMirrored(ctx, q, query, now, now, id)
 	if err != nil {
 		return errors.NewInternal(err)
 	}
@@ -329,26 +532,74 @@ func SoftDelete(ctx context.Context, db *sql.DB, id string) error {
 	return nil
 }
 
+// notExpiredCondition excludes capsules past their expires_at; bind the current Unix time.
+const notExpiredCondition = "(expires_at IS NULL OR expires_at > ?)"
+
+// SweepExpired soft-deletes active capsules whose expires_at is at or before now.
+// Like SoftDelete, it bumps updated_at. Returns the number of capsules swept.
+func SweepExpired(ctx context.Context, q Querier, now int64) (int, error) {
+	result, err := execMirrored(ctx, q, `
+		UPDATE capsules
+		SET deleted_at = ?, updated_at = ?
+		WHERE deleted_at IS NULL AND expires_at IS NOT NULL AND expires_at <= ?
+	`, now, now, now)
+	if err != nil {
+		return 0, errors.NewInternal(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewInternal(err)
+	}
+	return int(rowsAffected), nil
+}
+
+// SweepExpiredName is SweepExpired limited to the capsule holding one
+// (workspace, name) handle, so a store can free an expired name without
+// touching the rest of the table. Returns the number of capsules swept (0 or 1).
+func SweepExpiredName(ctx context.Context, q Querier, workspaceNorm, nameNorm string, now int64) (int, error) {
+	result, err := execMirrored(ctx, q, `
+		UPDATE capsules
+		SET deleted_at = ?, updated_at = ?
+		WHERE workspace_norm = ? AND name_norm = ?
+		  AND deleted_at IS NULL AND expires_at IS NOT NULL AND expires_at <= ?
+	`, now, now, workspaceNorm, nameNorm, now)
+	if err != nil {
+		return 0, errors.NewInternal(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewInternal(err)
+	}
+	return int(rowsAffected), nil
+}
+
 // scanCapsule scans a single row into a Capsule struct.
 func scanCapsule(row *sql.Row) (*capsule.Capsule, error) {
 	var (
-		c         capsule.Capsule
-		nameRaw   sql.NullString
-		nameNorm  sql.NullString
-		title     sql.NullString
-		tagsJSON  sql.NullString
-		source    sql.NullString
-		runID     sql.NullString
-		phase     sql.NullString
-		role      sql.NullString
-		deletedAt sql.NullInt64
+		c          capsule.Capsule
+		nameRaw    sql.NullString
+		nameNorm   sql.NullString
+		title      sql.NullString
+		tagsJSON   sql.NullString
+		source     sql.NullString
+		runID      sql.NullString
+		phase      sql.NullString
+		role       sql.NullString
+		sourceType sql.NullString
+		sourceRef  sql.NullString
+		note       sql.NullString
+		deletedAt  sql.NullInt64
+		expiresAt  sql.NullInt64
 	)
 
 	err := row.Scan(
 		&c.ID, &c.WorkspaceRaw, &c.WorkspaceNorm, &nameRaw, &nameNorm,
-		&title, &c.CapsuleText, &c.CapsuleChars, &c.TokensEstimate,
-		&tagsJSON, &source, &runID, &phase, &role,
-		&c.CreatedAt, &c.UpdatedAt, &deletedAt,
+		&title, &c.CapsuleText, &c.CapsuleChars, &c.TokensEstimate, &c.ContentSHA256,
+		&c.LineCount, &c.WordCount, &c.SectionCount,
+		&tagsJSON, &source, &runID, &phase, &role, &sourceType, &sourceRef, &note,
+		&c.CreatedAt, &c.UpdatedAt, &deletedAt, &expiresAt,
 	)
 	if err != nil {
 		return nil, err
@@ -362,11 +613,17 @@ func scanCapsule(row *sql.Row) (*capsule.Capsule, error) {
 	c.RunID = fromNullString(runID)
 	c.Phase = fromNullString(phase)
 	c.Role = fromNullString(role)
+	c.SourceType = fromNullString(sourceType)
+	c.SourceRef = fromNullString(sourceRef)
+	c.Note = fromNullString(note)
 
-	// Convert deleted_at
+	// Convert deleted_at and expires_at
 	if deletedAt.Valid {
 		c.DeletedAt = &deletedAt.Int64
 	}
+	if expiresAt.Valid {
+		c.ExpiresAt = &expiresAt.Int64
+	}
 
 	// Parse tags JSON
 	if tagsJSON.Valid && tagsJSON.String != "" {
@@ -386,6 +643,14 @@ func toNullString(s *string) sql.NullString {
 	return sql.NullString{String: *s, Valid: true}
 }
 
+// toNullInt64 converts a *int64 to sql.NullInt64.
+func toNullInt64(n *int64) sql.NullInt64 {
+	if n == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *n, Valid: true}
+}
+
 // fromNullString converts a sql.NullString to *string.
 func fromNullString(ns sql.NullString) *string {
 	if !ns.Valid {
@@ -394,6 +659,19 @@ func fromNullString(ns sql.NullString) *string {
 	return &ns.String
 }
 
+// prefixUpperBound returns the smallest string greater than every string that
+// starts with prefix, or "" if there is none (empty or all 0xff bytes).
+func prefixUpperBound(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return ""
+}
+
 // escapeLikePattern escapes SQL LIKE wildcards (%, _) and the escape char (\).
 func escapeLikePattern(s string) string {
 	s = strings.ReplaceAll(s, "\\", "\\\\")
@@ -404,27 +682,32 @@ func escapeLikePattern(s string) string {
 
 // scanCapsuleSummary scans a single row into a CapsuleSummary struct.
 // Expects columns: id, workspace_raw, workspace_norm, name_raw, name_norm,
-// title, capsule_chars, tokens_estimate, tags_json, source, run_id, phase, role,
-// created_at, updated_at, deleted_at
+// title, capsule_chars, tokens_estimate, content_sha256, line_count, word_count, section_count,
+// tags_json, source, run_id, phase, role, source_type, source_ref, created_at, updated_at, deleted_at,
+// and summaryAbstractColumn
 func scanCapsuleSummary(scanner interface{ Scan(...any) error }) (*capsule.CapsuleSummary, error) {
 	var (
-		s         capsule.CapsuleSummary
-		nameRaw   sql.NullString
-		nameNorm  sql.NullString
-		title     sql.NullString
-		tagsJSON  sql.NullString
-		source    sql.NullString
-		runID     sql.NullString
-		phase     sql.NullString
-		role      sql.NullString
-		deletedAt sql.NullInt64
+		s          capsule.CapsuleSummary
+		nameRaw    sql.NullString
+		nameNorm   sql.NullString
+		title      sql.NullString
+		tagsJSON   sql.NullString
+		source     sql.NullString
+		runID      sql.NullString
+		phase      sql.NullString
+		role       sql.NullString
+		sourceType sql.NullString
+		sourceRef  sql.NullString
+		deletedAt  sql.NullInt64
+		head       string
 	)
 
 	err := scanner.Scan(
 		&s.ID, &s.Workspace, &s.WorkspaceNorm, &nameRaw, &nameNorm,
-		&title, &s.CapsuleChars, &s.TokensEstimate,
-		&tagsJSON, &source, &runID, &phase, &role,
-		&s.CreatedAt, &s.UpdatedAt, &deletedAt,
+		&title, &s.CapsuleChars, &s.TokensEstimate, &s.ContentSHA256,
+		&s.LineCount, &s.WordCount, &s.SectionCount,
+		&tagsJSON, &source, &runID, &phase, &role, &sourceType, &sourceRef,
+		&s.CreatedAt, &s.UpdatedAt, &deletedAt, &head,
 	)
 	if err != nil {
 		return nil, err
@@ -438,6 +721,9 @@ func scanCapsuleSummary(scanner interface{ Scan(...any) error }) (*capsule.Capsu
 	s.RunID = fromNullString(runID)
 	s.Phase = fromNullString(phase)
 	s.Role = fromNullString(role)
+	s.SourceType = fromNullString(sourceType)
+	s.SourceRef = fromNullString(sourceRef)
+	s.Abstract = capsule.Abstract(head, capsule.AbstractMaxChars)
 
 	// Convert deleted_at
 	if deletedAt.Valid {
@@ -454,17 +740,23 @@ func scanCapsuleSummary(scanner interface{ Scan(...any) error }) (*capsule.Capsu
 	return &s, nil
 }
 
+// summaryAbstractColumn selects the head of capsule_text that summary scans
+// turn into CapsuleSummary.Abstract. table qualifies the column ("" or "c.").
+func summaryAbstractColumn(table string) string {
+	return fmt.Sprintf("substr(moss_text(%[1]scapsule_text, %[1]scapsule_text_gz), 1, %[2]d)", table, capsule.AbstractScanChars)
+}
+
 // ListFilters contains optional filters for list operations.
 type ListFilters struct {
 	RunID *string
 	Phase *string
 	Role  *string
+	Named *bool // true: named only, false: unnamed only, nil: both
 }
 
-// ListByWorkspace retrieves capsule summaries for a workspace with pagination.
-// Returns summaries (no capsule_text) + total count.
-// Ordered by updated_at DESC, id DESC (stable pagination).
-func ListByWorkspace(ctx context.Context, db *sql.DB, workspaceNorm string, filters ListFilters, limit, offset int, includeDeleted bool) ([]capsule.CapsuleSummary, int, error) {
+// listByWorkspaceWhere builds the WHERE clause and args shared by the
+// ListByWorkspace variants.
+func listByWorkspaceWhere(workspaceNorm string, filters ListFilters, includeDeleted bool) (string, []any) {
 	// Build WHERE conditions
 	conditions := []string{"workspace_norm = ?"}
 	args := []any{workspaceNorm}
@@ -484,8 +776,18 @@ func ListByWorkspace(ctx context.Context, db *sql.DB, workspaceNorm string, filt
 		conditions = append(conditions, "role = ?")
 		args = append(args, *filters.Role)
 	}
+	if filters.Named != nil {
+		conditions = append(conditions, namedCondition(*filters.Named))
+	}
 
-	whereClause := " WHERE " + strings.Join(conditions, " AND ")
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// ListByWorkspace retrieves capsule summaries for a workspace with pagination.
+// Returns summaries (no capsule_text) + total count.
+// Ordered by updated_at DESC, id DESC (stable pagination).
+func ListByWorkspace(ctx context.Context, db Querier, workspaceNorm string, filters ListFilters, limit, offset int, includeDeleted bool) ([]capsule.CapsuleSummary, int, error) {
+	whereClause, args := listByWorkspaceWhere(workspaceNorm, filters, includeDeleted)
 
 	// Build count query
 	countQuery := "SELECT COUNT(*) FROM capsules" + whereClause
@@ -497,8 +799,9 @@ func ListByWorkspace(ctx context.Context, db *sql.DB, workspaceNorm string, filt
 	// Build list query
 	listQuery := `
 		SELECT id, workspace_raw, workspace_norm, name_raw, name_norm,
-			title, capsule_chars, tokens_estimate, tags_json, source,
-			run_id, phase, role, created_at, updated_at, deleted_at
+			title, capsule_chars, tokens_estimate, content_sha256, line_count, word_count, section_count, tags_json, source,
+			run_id, phase, role, source_type, source_ref, created_at, updated_at, deleted_at,
+			` + summaryAbstractColumn("") + `
 		FROM capsules` + whereClause + " ORDER BY updated_at DESC, id DESC LIMIT ? OFFSET ?"
 
 	listArgs := append(args, limit, offset)
@@ -531,6 +834,8 @@ type InventoryFilters struct {
 	RunID      *string // filter by run_id
 	Phase      *string // filter by phase
 	Role       *string // filter by role
+	SourceType *string // filter by source_type
+	Named      *bool   // true: name_norm IS NOT NULL, false: IS NULL
 }
 
 // HasFilters returns true if at least one meaningful filter is set.
@@ -542,13 +847,20 @@ func (f InventoryFilters) HasFilters() bool {
 		(f.NamePrefix != nil && strings.TrimSpace(*f.NamePrefix) != "") ||
 		(f.RunID != nil && strings.TrimSpace(*f.RunID) != "") ||
 		(f.Phase != nil && strings.TrimSpace(*f.Phase) != "") ||
-		(f.Role != nil && strings.TrimSpace(*f.Role) != "")
+		(f.Role != nil && strings.TrimSpace(*f.Role) != "") ||
+		(f.SourceType != nil && strings.TrimSpace(*f.SourceType) != "")
 }
 
-// ListAll retrieves capsule summaries across all workspaces with optional filters.
-// Returns summaries (no capsule_text) + total count.
-// Ordered by updated_at DESC, id DESC (stable pagination).
-func ListAll(ctx context.Context, db *sql.DB, filters InventoryFilters, limit, offset int, includeDeleted bool) ([]capsule.CapsuleSummary, int, error) {
+// namedCondition selects named (name_norm set) or unnamed (auto-ID) capsules.
+func namedCondition(named bool) string {
+	if named {
+		return "name_norm IS NOT NULL"
+	}
+	return "name_norm IS NULL"
+}
+
+// listAllWhere builds the WHERE clause and args shared by the ListAll variants.
+func listAllWhere(filters InventoryFilters, includeDeleted bool) (string, []any) {
 	// Build WHERE clauses
 	var conditions []string
 	var args []any
@@ -580,11 +892,25 @@ func ListAll(ctx context.Context, db *sql.DB, filters InventoryFilters, limit, o
 		conditions = append(conditions, "role = ?")
 		args = append(args, *filters.Role)
 	}
+	if filters.SourceType != nil {
+		conditions = append(conditions, "source_type = ?")
+		args = append(args, *filters.SourceType)
+	}
+	if filters.Named != nil {
+		conditions = append(conditions, namedCondition(*filters.Named))
+	}
 
-	whereClause := ""
-	if len(conditions) > 0 {
-		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	if len(conditions) == 0 {
+		return "", args
 	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// ListAll retrieves capsule summaries across all workspaces with optional filters.
+// Returns summaries (no capsule_text) + total count.
+// Ordered by updated_at DESC, id DESC (stable pagination).
+func ListAll(ctx context.Context, db Querier, filters InventoryFilters, limit, offset int, includeDeleted bool) ([]capsule.CapsuleSummary, int, error) {
+	whereClause, args := listAllWhere(filters, includeDeleted)
 
 	// Build count query
 	countQuery := "SELECT COUNT(*) FROM capsules" + whereClause
@@ -596,8 +922,9 @@ func ListAll(ctx context.Context, db *sql.DB, filters InventoryFilters, limit, o
 	// Build list query
 	listQuery := `
 		SELECT id, workspace_raw, workspace_norm, name_raw, name_norm,
-			title, capsule_chars, tokens_estimate, tags_json, source,
-			run_id, phase, role, created_at, updated_at, deleted_at
+			title, capsule_chars, tokens_estimate, content_sha256, line_count, word_count, section_count, tags_json, source,
+			run_id, phase, role, source_type, source_ref, created_at, updated_at, deleted_at,
+			` + summaryAbstractColumn("") + `
 		FROM capsules` + whereClause + " ORDER BY updated_at DESC, id DESC LIMIT ? OFFSET ?"
 
 	listArgs := append(args, limit, offset)
@@ -622,107 +949,290 @@ func ListAll(ctx context.Context, db *sql.DB, filters InventoryFilters, limit, o
 	return summaries, total, nil
 }
 
-// LatestFilters contains optional filters for latest queries.
-type LatestFilters struct {
-	RunID *string
-	Phase *string
-	Role  *string
+// ListByWorkspaceWithText is ListByWorkspace but returns full capsules,
+// including capsule_text. Callers are responsible for capping limit.
+func ListByWorkspaceWithText(ctx context.Context, db Querier, workspaceNorm string, filters ListFilters, limit, offset int, includeDeleted bool) ([]capsule.Capsule, int, error) {
+	whereClause, args := listByWorkspaceWhere(workspaceNorm, filters, includeDeleted)
+	return listCapsulesWithText(ctx, db, whereClause, args, limit, offset)
 }
 
-// GetLatestSummary retrieves the most recent capsule summary in a workspace.
-// Returns summary (no capsule_text).
-// Returns nil, nil if workspace is empty (not an error).
-func GetLatestSummary(ctx context.Context, db *sql.DB, workspaceNorm string, filters LatestFilters, includeDeleted bool) (*capsule.CapsuleSummary, error) {
-	conditions := []string{"workspace_norm = ?"}
-	args := []any{workspaceNorm}
+// ListAllWithText is ListAll but returns full capsules, including capsule_text.
+// Callers are responsible for capping limit.
+func ListAllWithText(ctx context.Context, db Querier, filters InventoryFilters, limit, offset int, includeDeleted bool) ([]capsule.Capsule, int, error) {
+	whereClause, args := listAllWhere(filters, includeDeleted)
+	return listCapsulesWithText(ctx, db, whereClause, args, limit, offset)
+}
 
-	if !includeDeleted {
-		conditions = append(conditions, "deleted_at IS NULL")
+// listCapsulesWithText runs the count and page queries for the WithText list variants.
+func listCapsulesWithText(ctx context.Context, db Querier, whereClause string, args []any, limit, offset int) ([]capsule.Capsule, int, error) {
+	countQuery := "SELECT COUNT(*) FROM capsules" + whereClause
+	var total int
+	if err := db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, errors.NewInternal(err)
 	}
-	if filters.RunID != nil {
-		conditions = append(conditions, "run_id = ?")
-		args = append(args, *filters.RunID)
+
+	listQuery := `
+		SELECT id, workspace_raw, workspace_norm, name_raw, name_norm,
+			title, moss_text(capsule_text, capsule_text_gz) AS capsule_text, capsule_chars, tokens_estimate, content_sha256,
+			line_count, word_count, section_count,
+			tags_json, source, run_id, phase, role, source_type, source_ref, note,
+			created_at, updated_at, deleted_at, expires_at
+		FROM capsules` + whereClause + " ORDER BY updated_at DESC, id DESC LIMIT ? OFFSET ?"
+
+	listArgs := append(args, limit, offset)
+	rows, err := db.QueryContext(ctx, listQuery, listArgs...)
+	if err != nil {
+		return nil, 0, errors.NewInternal(err)
 	}
-	if filters.Phase != nil {
-		conditions = append(conditions, "phase = ?")
-		args = append(args, *filters.Phase)
+	defer rows.Close()
+
+	var capsules []capsule.Capsule
+	for rows.Next() {
+		c, err := ScanCapsuleFromRows(rows)
+		if err != nil {
+			return nil, 0, errors.NewInternal(err)
+		}
+		capsules = append(capsules, *c)
 	}
-	if filters.Role != nil {
-		conditions = append(conditions, "role = ?")
-		args = append(args, *filters.Role)
+	if err := rows.Err(); err != nil {
+		return nil, 0, errors.NewInternal(err)
+	}
+
+	return capsules, total, nil
+}
+
+// ListRecentlyDeleted returns soft-deleted capsules, most recently deleted
+// first, optionally scoped to one workspace. Purged capsules are gone from
+// capsules and never appear.
+func ListRecentlyDeleted(ctx context.Context, q Querier, workspaceNorm *string, limit int) ([]capsule.CapsuleSummary, error) {
+	whereClause := " WHERE deleted_at IS NOT NULL"
+	var args []any
+	if workspaceNorm != nil {
+		whereClause += " AND workspace_norm = ?"
+		args = append(args, *workspaceNorm)
 	}
 
 	query := `
 		SELECT id, workspace_raw, workspace_norm, name_raw, name_norm,
-			title, capsule_chars, tokens_estimate, tags_json, source,
-			run_id, phase, role, created_at, updated_at, deleted_at
-		FROM capsules
-		WHERE ` + strings.Join(conditions, " AND ") + `
-		ORDER BY updated_at DESC, id DESC LIMIT 1`
+			title, capsule_chars, tokens_estimate, content_sha256, line_count, word_count, section_count, tags_json, source,
+			run_id, phase, role, source_type, source_ref, created_at, updated_at, deleted_at,
+			` + summaryAbstractColumn("") + `
+		FROM capsules` + whereClause + " ORDER BY deleted_at DESC, id DESC LIMIT ?"
 
-	row := db.QueryRowContext(ctx, query, args...)
-	s, err := scanCapsuleSummary(row)
-	if err == sql.ErrNoRows {
-		return nil, nil // Empty workspace is not an error
-	}
+	rows, err := q.QueryContext(ctx, query, append(args, limit)...)
 	if err != nil {
 		return nil, errors.NewInternal(err)
 	}
+	defer rows.Close()
 
-	return s, nil
-}
+	var summaries []capsule.CapsuleSummary
+	for rows.Next() {
+		s, err := scanCapsuleSummary(rows)
+		if err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		summaries = append(summaries, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewInternal(err)
+	}
 
-// GetLatestFull retrieves the most recent full capsule (including text) in a workspace.
-// Returns nil, nil if workspace is empty (not an error).
-func GetLatestFull(ctx context.Context, db *sql.DB, workspaceNorm string, filters LatestFilters, includeDeleted bool) (*capsule.Capsule, error) {
-	conditions := []string{"workspace_norm = ?"}
-	args := []any{workspaceNorm}
+	return summaries, nil
+}
 
+// RecentActivity retrieves the most recently changed capsule summaries across all workspaces.
+// Ordered by updated_at DESC, id DESC. Soft deletes bump updated_at, so deletions appear
+// in the feed when includeDeleted is true.
+func RecentActivity(ctx context.Context, db Querier, limit int, includeDeleted bool) ([]capsule.CapsuleSummary, error) {
+	whereClause := ""
 	if !includeDeleted {
-		conditions = append(conditions, "deleted_at IS NULL")
-	}
-	if filters.RunID != nil {
-		conditions = append(conditions, "run_id = ?")
-		args = append(args, *filters.RunID)
-	}
-	if filters.Phase != nil {
-		conditions = append(conditions, "phase = ?")
-		args = append(args, *filters.Phase)
-	}
-	if filters.Role != nil {
-		conditions = append(conditions, "role = ?")
-		args = append(args, *filters.Role)
+		whereClause = " WHERE deleted_at IS NULL"
 	}
 
 	query := `
 		SELECT id, workspace_raw, workspace_norm, name_raw, name_norm,
-			title, capsule_text, capsule_chars, tokens_estimate,
-			tags_json, source, run_id, phase, role,
-			created_at, updated_at, deleted_at
-		FROM capsules
-		WHERE ` + strings.Join(conditions, " AND ") + `
-		ORDER BY updated_at DESC, id DESC LIMIT 1`
+			title, capsule_chars, tokens_estimate, content_sha256, line_count, word_count, section_count, tags_json, source,
+			run_id, phase, role, source_type, source_ref, created_at, updated_at, deleted_at,
+			` + summaryAbstractColumn("") + `
+		FROM capsules` + whereClause + " ORDER BY updated_at DESC, id DESC LIMIT ?"
 
-	row := db.QueryRowContext(ctx, query, args...)
-	c, err := scanCapsule(row)
-	if err == sql.ErrNoRows {
-		return nil, nil // Empty workspace is not an error
-	}
+	rows, err := db.QueryContext(ctx, query, limit)
 	if err != nil {
 		return nil, errors.NewInternal(err)
 	}
+	defer rows.Close()
+
+	var summaries []capsule.CapsuleSummary
+	for rows.Next() {
+		s, err := scanCapsuleSummary(rows)
+		if err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		summaries = append(summaries, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	return summaries, nil
+}
+
+// LatestBy selects which timestamp defines "latest".
+type LatestBy string
+
+const (
+	LatestByUpdated LatestBy = "updated" // most recently updated (default)
+	LatestByCreated LatestBy = "created" // most recently created (append-only logs)
+)
+
+// LatestFilters contains optional filters for latest queries.
+type LatestFilters struct {
+	RunID *string
+	Phase *string
+	Role  *string
+	By    LatestBy // empty means LatestByUpdated
+
+	// PreferNamed ranks named capsules ahead of unnamed ones, so an unnamed
+	// capsule is returned only when no named capsule matches.
+	PreferNamed bool
+}
+
+// latestOrderBy returns the ORDER BY clause for the given filters' By and PreferNamed.
+func latestOrderBy(filters LatestFilters) string {
+	order := "updated_at DESC, id DESC"
+	if filters.By == LatestByCreated {
+		order = "created_at DESC, id DESC"
+	}
+	return preferNamedOrder(filters) + order
+}
+
+// firstOrderBy returns the ORDER BY clause for the oldest capsule under the given filters.
+func firstOrderBy(filters LatestFilters) string {
+	order := "updated_at ASC, id ASC"
+	if filters.By == LatestByCreated {
+		order = "created_at ASC, id ASC"
+	}
+	return preferNamedOrder(filters) + order
+}
+
+// preferNamedOrder is the leading ORDER BY term for PreferNamed, or "".
+func preferNamedOrder(filters LatestFilters) string {
+	if filters.PreferNamed {
+		return "name_norm IS NOT NULL DESC, "
+	}
+	return ""
+}
+
+// latestWhere builds the WHERE clause shared by the latest and first queries.
+func latestWhere(workspaceNorm string, filters LatestFilters, includeDeleted bool) (string, []any) {
+	conditions := []string{"workspace_norm = ?"}
+	args := []any{workspaceNorm}
+
+	if !includeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if filters.RunID != nil {
+		conditions = append(conditions, "run_id = ?")
+		args = append(args, *filters.RunID)
+	}
+	if filters.Phase != nil {
+		conditions = append(conditions, "phase = ?")
+		args = append(args, *filters.Phase)
+	}
+	if filters.Role != nil {
+		conditions = append(conditions, "role = ?")
+		args = append(args, *filters.Role)
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetLatestSummary retrieves the most recent capsule summary in a workspace.
+// Returns summary (no capsule_text).
+// Returns nil, nil if workspace is empty (not an error).
+func GetLatestSummary(ctx context.Context, db Querier, workspaceNorm string, filters LatestFilters, includeDeleted bool) (*capsule.CapsuleSummary, error) {
+	return getOneSummary(ctx, db, workspaceNorm, filters, includeDeleted, latestOrderBy(filters))
+}
+
+// GetFirstSummary retrieves the oldest capsule summary in a workspace (mirror of GetLatestSummary).
+// Returns nil, nil if workspace is empty (not an error).
+func GetFirstSummary(ctx context.Context, db Querier, workspaceNorm string, filters LatestFilters, includeDeleted bool) (*capsule.CapsuleSummary, error) {
+	return getOneSummary(ctx, db, workspaceNorm, filters, includeDeleted, firstOrderBy(filters))
+}
+
+// getOneSummary returns the first capsule summary under orderBy, or nil, nil if none match.
+func getOneSummary(ctx context.Context, db Querier, workspaceNorm string, filters LatestFilters, includeDeleted bool, orderBy string) (*capsule.CapsuleSummary, error) {
+	where, args := latestWhere(workspaceNorm, filters, includeDeleted)
+
+	query := `
+		SELECT id, workspace_raw, workspace_norm, name_raw, name_norm,
+			title, capsule_chars, tokens_estimate, content_sha256, line_count, word_count, section_count, tags_json, source,
+			run_id, phase, role, source_type, source_ref, created_at, updated_at, deleted_at,
+			` + summaryAbstractColumn("") + `
+		FROM capsules` + where + `
+		ORDER BY ` + orderBy + ` LIMIT 1`
+
+	row := db.QueryRowContext(ctx, query, args...)
+	s, err := scanCapsuleSummary(row)
+	if err == sql.ErrNoRows {
+		return nil, nil // Empty workspace is not an error
+	}
+	if err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	return s, nil
+}
+
+// GetLatestFull retrieves the most recent full capsule (including text) in a workspace.
+// Returns nil, nil if workspace is empty (not an error).
+func GetLatestFull(ctx context.Context, db Querier, workspaceNorm string, filters LatestFilters, includeDeleted bool) (*capsule.Capsule, error) {
+	return getOneFull(ctx, db, workspaceNorm, filters, includeDeleted, latestOrderBy(filters))
+}
+
+// GetFirstFull retrieves the oldest full capsule (including text) in a workspace.
+// Returns nil, nil if workspace is empty (not an error).
+func GetFirstFull(ctx context.Context, db Querier, workspaceNorm string, filters LatestFilters, includeDeleted bool) (*capsule.Capsule, error) {
+	return getOneFull(ctx, db, workspaceNorm, filters, includeDeleted, firstOrderBy(filters))
+}
+
+// getOneFull returns the first full capsule under orderBy, or nil, nil if none match.
+func getOneFull(ctx context.Context, db Querier, workspaceNorm string, filters LatestFilters, includeDeleted bool, orderBy string) (*capsule.Capsule, error) {
+	where, args := latestWhere(workspaceNorm, filters, includeDeleted)
+
+	query := `
+		SELECT id, workspace_raw, workspace_norm, name_raw, name_norm,
+			title, moss_text(capsule_text, capsule_text_gz) AS capsule_text, capsule_chars, tokens_estimate, content_sha256,
+			line_count, word_count, section_count,
+			tags_json, source, run_id, phase, role, source_type, source_ref, note,
+			created_at, updated_at, deleted_at, expires_at
+		FROM capsules` + where + `
+		ORDER BY ` + orderBy + ` LIMIT 1`
+
+	row := db.QueryRowContext(ctx, query, args...)
+	c, err := scanCapsule(row)
+	if err == sql.ErrNoRows {
+		return nil, nil // Empty workspace is not an error
+	}
+	if err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	return c, nil
+}
+
+// =============================================================================
+// Export/Import/Purge Functions
+// =============================================================================
 
-	return c, nil
-}
-
-// =============================================================================
-// Export/Import/Purge Functions
-// =============================================================================
-
 // StreamForExport returns a row iterator for exporting capsules.
 // The caller is responsible for closing the returned rows.
-// Capsules are ordered by created_at ASC for stable export order.
-func StreamForExport(ctx context.Context, db *sql.DB, workspace *string, includeDeleted bool) (*sql.Rows, error) {
+// Capsules are ordered by created_at ASC for stable export order, or with
+// byWorkspace by workspace then name so exports of the same data diff cleanly.
+// A non-empty ids restricts the export to those capsules (ids are matched exactly).
+// A non-nil resumeFrom keeps only capsules after that ID in created_at, id
+// order (nothing if the ID doesn't exist); it is meant for the created order.
+func StreamForExport(ctx context.Context, db Querier, workspace *string, ids []string, resumeFrom *string, includeDeleted, byWorkspace bool) (*sql.Rows, error) {
 	var conditions []string
 	var args []any
 
@@ -733,18 +1243,35 @@ func StreamForExport(ctx context.Context, db *sql.DB, workspace *string, include
 		conditions = append(conditions, "workspace_norm = ?")
 		args = append(args, capsule.Normalize(*workspace))
 	}
+	if len(ids) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+		conditions = append(conditions, "id IN ("+placeholders+")")
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	if resumeFrom != nil {
+		// Everything after the cursor capsule in created_at, id order
+		conditions = append(conditions, "(created_at, id) > (SELECT created_at, id FROM capsules WHERE id = ?)")
+		args = append(args, *resumeFrom)
+	}
 
 	query := `
 		SELECT id, workspace_raw, workspace_norm, name_raw, name_norm,
-			title, capsule_text, capsule_chars, tokens_estimate,
-			tags_json, source, run_id, phase, role,
-			created_at, updated_at, deleted_at
+			title, moss_text(capsule_text, capsule_text_gz) AS capsule_text, capsule_chars, tokens_estimate, content_sha256,
+			line_count, word_count, section_count,
+			tags_json, source, run_id, phase, role, source_type, source_ref, note,
+			created_at, updated_at, deleted_at, expires_at
 		FROM capsules
 	`
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
-	query += " ORDER BY created_at ASC, id ASC"
+	if byWorkspace {
+		query += " ORDER BY workspace_norm ASC, name_norm ASC NULLS LAST, id ASC"
+	} else {
+		query += " ORDER BY created_at ASC, id ASC"
+	}
 
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -758,23 +1285,28 @@ func StreamForExport(ctx context.Context, db *sql.DB, workspace *string, include
 // This is used for streaming export.
 func ScanCapsuleFromRows(rows *sql.Rows) (*capsule.Capsule, error) {
 	var (
-		c         capsule.Capsule
-		nameRaw   sql.NullString
-		nameNorm  sql.NullString
-		title     sql.NullString
-		tagsJSON  sql.NullString
-		source    sql.NullString
-		runID     sql.NullString
-		phase     sql.NullString
-		role      sql.NullString
-		deletedAt sql.NullInt64
+		c          capsule.Capsule
+		nameRaw    sql.NullString
+		nameNorm   sql.NullString
+		title      sql.NullString
+		tagsJSON   sql.NullString
+		source     sql.NullString
+		runID      sql.NullString
+		phase      sql.NullString
+		role       sql.NullString
+		sourceType sql.NullString
+		sourceRef  sql.NullString
+		note       sql.NullString
+		deletedAt  sql.NullInt64
+		expiresAt  sql.NullInt64
 	)
 
 	err := rows.Scan(
 		&c.ID, &c.WorkspaceRaw, &c.WorkspaceNorm, &nameRaw, &nameNorm,
-		&title, &c.CapsuleText, &c.CapsuleChars, &c.TokensEstimate,
-		&tagsJSON, &source, &runID, &phase, &role,
-		&c.CreatedAt, &c.UpdatedAt, &deletedAt,
+		&title, &c.CapsuleText, &c.CapsuleChars, &c.TokensEstimate, &c.ContentSHA256,
+		&c.LineCount, &c.WordCount, &c.SectionCount,
+		&tagsJSON, &source, &runID, &phase, &role, &sourceType, &sourceRef, &note,
+		&c.CreatedAt, &c.UpdatedAt, &deletedAt, &expiresAt,
 	)
 	if err != nil {
 		return nil, err
@@ -788,11 +1320,17 @@ func ScanCapsuleFromRows(rows *sql.Rows) (*capsule.Capsule, error) {
 	c.RunID = fromNullString(runID)
 	c.Phase = fromNullString(phase)
 	c.Role = fromNullString(role)
+	c.SourceType = fromNullString(sourceType)
+	c.SourceRef = fromNullString(sourceRef)
+	c.Note = fromNullString(note)
 
-	// Convert deleted_at
+	// Convert deleted_at and expires_at
 	if deletedAt.Valid {
 		c.DeletedAt = &deletedAt.Int64
 	}
+	if expiresAt.Valid {
+		c.ExpiresAt = &expiresAt.Int64
+	}
 
 	// Parse tags JSON
 	if tagsJSON.Valid && tagsJSON.String != "" {
@@ -804,6 +1342,51 @@ func ScanCapsuleFromRows(rows *sql.Rows) (*capsule.Capsule, error) {
 	return &c, nil
 }
 
+// ChangesSince returns full capsules, soft-deleted included, with updated_at
+// after since, ordered by updated_at ASC, id ASC for incremental sync. after,
+// if set, resumes past that (updated_at, id) position. Purged capsules no
+// longer exist and are never returned.
+func ChangesSince(ctx context.Context, db Querier, since int64, limit int, after *SearchCursor) ([]capsule.Capsule, error) {
+	conditions := []string{"updated_at > ?"}
+	args := []any{since}
+	if after != nil {
+		conditions = append(conditions, "(updated_at, id) > (?, ?)")
+		args = append(args, after.UpdatedAt, after.ID)
+	}
+
+	query := `
+		SELECT id, workspace_raw, workspace_norm, name_raw, name_norm,
+			title, moss_text(capsule_text, capsule_text_gz) AS capsule_text, capsule_chars, tokens_estimate, content_sha256,
+			line_count, word_count, section_count,
+			tags_json, source, run_id, phase, role, source_type, source_ref, note,
+			created_at, updated_at, deleted_at, expires_at
+		FROM capsules
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY updated_at ASC, id ASC
+		LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewInternal(err)
+	}
+	defer rows.Close()
+
+	var capsules []capsule.Capsule
+	for rows.Next() {
+		c, err := ScanCapsuleFromRows(rows)
+		if err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		capsules = append(capsules, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	return capsules, nil
+}
+
 // UpdateFull updates all fields of an existing capsule by ID.
 // Unlike UpdateByID, this can update workspace and name, and respects provided timestamps.
 // Used during import to restore exact capsule state.
@@ -826,30 +1409,39 @@ func UpdateFull(ctx context.Context, q Querier, c *capsule.Capsule) error {
 	runID := toNullString(c.RunID)
 	phase := toNullString(c.Phase)
 	role := toNullString(c.Role)
+	sourceType := toNullString(c.SourceType)
+	sourceRef := toNullString(c.SourceRef)
+	note := toNullString(c.Note)
 	var deletedAt sql.NullInt64
 	if c.DeletedAt != nil {
 		deletedAt = sql.NullInt64{Int64: *c.DeletedAt, Valid: true}
 	}
+	text, textGz, err := storedText(c.CapsuleText)
+	if err != nil {
+		return errors.NewInternal(err)
+	}
 
 	query := `
 		UPDATE capsules
 		SET workspace_raw = ?, workspace_norm = ?, name_raw = ?, name_norm = ?,
-			title = ?, capsule_text = ?, capsule_chars = ?, tokens_estimate = ?,
-			tags_json = ?, source = ?, run_id = ?, phase = ?, role = ?,
-			created_at = ?, updated_at = ?, deleted_at = ?
+			title = ?, capsule_text = ?, capsule_text_gz = ?, capsule_chars = ?, tokens_estimate = ?, content_sha256 = ?,
+			line_count = ?, word_count = ?, section_count = ?,
+			tags_json = ?, source = ?, run_id = ?, phase = ?, role = ?, source_type = ?, source_ref = ?, note = ?,
+			created_at = ?, updated_at = ?, deleted_at = ?, expires_at = ?
 		WHERE id = ?
 	`
 
-	result, err := q.ExecContext(ctx, query,
+	result, err := execMirrored(ctx, q, query,
 		c.WorkspaceRaw, c.WorkspaceNorm, nameRaw, nameNorm,
-		title, c.CapsuleText, c.CapsuleChars, c.TokensEstimate,
-		tagsJSON, source, runID, phase, role,
-		c.CreatedAt, c.UpdatedAt, deletedAt,
+		title, text, textGz, c.CapsuleChars, c.TokensEstimate, capsule.ContentSHA256(c.CapsuleText),
+		c.LineCount, c.WordCount, c.SectionCount,
+		tagsJSON, source, runID, phase, role, sourceType, sourceRef, note,
+		c.CreatedAt, c.UpdatedAt, deletedAt, toNullInt64(c.ExpiresAt),
 		c.ID,
 	)
 	if err != nil {
 		if isNameUniquenessViolation(err) && c.NameRaw != nil {
-			return errors.NewNameAlreadyExists(c.WorkspaceRaw, *c.NameRaw)
+			return errors.NewNameAlreadyExists(c.WorkspaceRaw, *c.NameRaw, nameHolderID(ctx, q, c.WorkspaceNorm, *c.NameNorm))
 		}
 		return errors.NewInternal(err)
 	}
@@ -866,8 +1458,9 @@ func UpdateFull(ctx context.Context, q Querier, c *capsule.Capsule) error {
 }
 
 // FindUniqueName finds the next available unique name by appending -N suffix.
-// Used during import with mode:rename to avoid name collisions.
 // Returns the original baseName if it doesn't exist, otherwise tries baseName-1, baseName-2, etc.
+// The result is only a hint: another writer can take the name before it is inserted.
+// Use InsertWithUniqueName to insert under contention.
 func FindUniqueName(ctx context.Context, q Querier, workspaceNorm, baseName string) (string, error) {
 	// First check if baseName itself is available
 	exists, err := CheckNameExists(ctx, q, workspaceNorm, baseName)
@@ -898,6 +1491,42 @@ func FindUniqueName(ctx context.Context, q Querier, workspaceNorm, baseName stri
 	return "", errors.NewConflict("could not find unique name after 1000 attempts")
 }
 
+// maxUniqueNameAttempts bounds how many times InsertWithUniqueName retries after
+// losing a race for a name.
+const maxUniqueNameAttempts = 10
+
+// InsertWithUniqueName inserts c, appending a -N suffix to its name if the name is
+// taken (both name_raw and name_norm are set to the suffixed name). The unique index
+// is the source of truth: if a concurrent writer claims the chosen name between the
+// availability check and the insert, the insert fails with ErrNameAlreadyExists and
+// the search restarts, up to maxUniqueNameAttempts times. Unnamed capsules are
+// inserted as is. On success, c holds the name that was stored.
+func InsertWithUniqueName(ctx context.Context, q Querier, c *capsule.Capsule) error {
+	if c.NameNorm == nil {
+		return Insert(ctx, q, c)
+	}
+
+	baseRaw, baseName := c.NameRaw, *c.NameNorm
+	for range maxUniqueNameAttempts {
+		name, err := FindUniqueName(ctx, q, c.WorkspaceNorm, baseName)
+		if err != nil {
+			return err
+		}
+		if name == baseName {
+			c.NameRaw, c.NameNorm = baseRaw, &baseName
+		} else {
+			c.NameRaw, c.NameNorm = &name, &name
+		}
+
+		err = Insert(ctx, q, c)
+		if !errors.Is(err, errors.ErrNameAlreadyExists) {
+			return err
+		}
+	}
+
+	return errors.NewConflict(fmt.Sprintf("could not claim a unique name for %q after %d attempts", baseName, maxUniqueNameAttempts))
+}
+
 // itoa converts an integer to a string without importing strconv.
 func itoa(n int) string {
 	if n == 0 {
@@ -916,8 +1545,10 @@ func itoa(n int) string {
 //   - workspace: only purge capsules in this workspace
 //   - olderThanDays: only purge capsules deleted more than N days ago
 //
+// With archive, matching rows are first copied into archived_capsules in the
+// same transaction, so they leave the live store but can be restored.
 // Returns the number of capsules purged.
-func PurgeDeleted(ctx context.Context, db *sql.DB, workspace *string, olderThanDays *int) (int, error) {
+func PurgeDeleted(ctx context.Context, db *sql.DB, workspace *string, olderThanDays *int, archive bool) (int, error) {
 	var conditions []string
 	var args []any
 
@@ -938,9 +1569,24 @@ func PurgeDeleted(ctx context.Context, db *sql.DB, workspace *string, olderThanD
 		args = append(args, cutoff)
 	}
 
-	query := "DELETE FROM capsules WHERE " + strings.Join(conditions, " AND ")
+	whereClause := " WHERE " + strings.Join(conditions, " AND ")
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errors.NewInternal(err)
+	}
+	defer Rollback(tx) //nolint:errcheck
+
+	if archive {
+		// OR REPLACE: an ID re-imported after an earlier archive supersedes the old copy
+		archiveQuery := "INSERT OR REPLACE INTO archived_capsules (" + capsuleColumns + ", archived_at) SELECT " +
+			capsuleColumns + ", ? FROM capsules" + whereClause
+		if _, err := execMirrored(ctx, tx, archiveQuery, append([]any{time.Now().Unix()}, args...)...); err != nil {
+			return 0, errors.NewInternal(err)
+		}
+	}
 
-	result, err := db.ExecContext(ctx, query, args...)
+	result, err := execMirrored(ctx, tx, "DELETE FROM capsules"+whereClause, args...)
 	if err != nil {
 		return 0, errors.NewInternal(err)
 	}
@@ -950,27 +1596,163 @@ func PurgeDeleted(ctx context.Context, db *sql.DB, workspace *string, olderThanD
 		return 0, errors.NewInternal(err)
 	}
 
+	if err := Commit(tx); err != nil {
+		return 0, errors.NewInternal(err)
+	}
+
 	return int(rowsAffected), nil
 }
 
+// capsuleColumns lists every stored capsules column, in the order scanCapsule reads them,
+// followed by capsule_text_gz (copied as is; scans read moss_text instead).
+// archived_capsules has the same columns plus archived_at.
+const capsuleColumns = `id, workspace_raw, workspace_norm, name_raw, name_norm,
+	title, capsule_text, capsule_chars, tokens_estimate, content_sha256,
+	line_count, word_count, section_count,
+	tags_json, source, run_id, phase, role, source_type, source_ref, note,
+	created_at, updated_at, deleted_at, expires_at, capsule_text_gz`
+
+// ArchivedSummary is a capsule summary from archived_capsules.
+type ArchivedSummary struct {
+	capsule.CapsuleSummary
+	ArchivedAt int64 `json:"archived_at"`
+}
+
+// archivedScanner appends archived_at to the columns scanCapsuleSummary reads.
+type archivedScanner struct {
+	rows       *sql.Rows
+	archivedAt *int64
+}
+
+func (s archivedScanner) Scan(dest ...any) error {
+	return s.rows.Scan(append(dest, s.archivedAt)...)
+}
+
+// ListArchived returns archived capsule summaries, most recently archived first.
+// workspaceNorm optionally filters by workspace.
+func ListArchived(ctx context.Context, db Querier, workspaceNorm *string, limit, offset int) ([]ArchivedSummary, int, error) {
+	whereClause := ""
+	var args []any
+	if workspaceNorm != nil {
+		whereClause = " WHERE workspace_norm = ?"
+		args = append(args, *workspaceNorm)
+	}
+
+	var total int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM archived_capsules"+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, errors.NewInternal(err)
+	}
+
+	listQuery := `
+		SELECT id, workspace_raw, workspace_norm, name_raw, name_norm,
+			title, capsule_chars, tokens_estimate, content_sha256, line_count, word_count, section_count, tags_json, source,
+			run_id, phase, role, source_type, source_ref, created_at, updated_at, deleted_at,
+			` + summaryAbstractColumn("") + `, archived_at
+		FROM archived_capsules` + whereClause + " ORDER BY archived_at DESC, id DESC LIMIT ? OFFSET ?"
+
+	rows, err := db.QueryContext(ctx, listQuery, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, errors.NewInternal(err)
+	}
+	defer rows.Close()
+
+	var archived []ArchivedSummary
+	for rows.Next() {
+		var archivedAt int64
+		s, err := scanCapsuleSummary(archivedScanner{rows: rows, archivedAt: &archivedAt})
+		if err != nil {
+			return nil, 0, errors.NewInternal(err)
+		}
+		archived = append(archived, ArchivedSummary{CapsuleSummary: *s, ArchivedAt: archivedAt})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, errors.NewInternal(err)
+	}
+
+	return archived, total, nil
+}
+
+// RestoreFromArchive moves an archived capsule back into capsules as active
+// (deleted_at cleared, updated_at bumped) and removes it from the archive.
+// Fails with NOT_FOUND if the ID isn't archived, CONFLICT if a capsule with the
+// same ID exists again, or NAME_ALREADY_EXISTS if its name is now taken.
+// Links dropped by the purge are not restored.
+func RestoreFromArchive(ctx context.Context, db *sql.DB, id string) (*capsule.Capsule, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.NewInternal(err)
+	}
+	defer Rollback(tx) //nolint:errcheck
+
+	var (
+		workspaceRaw, workspaceNorm string
+		nameRaw, nameNorm           sql.NullString
+	)
+	err = tx.QueryRowContext(ctx,
+		"SELECT workspace_raw, workspace_norm, name_raw, name_norm FROM archived_capsules WHERE id = ?", id,
+	).Scan(&workspaceRaw, &workspaceNorm, &nameRaw, &nameNorm)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFound(id)
+	}
+	if err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	if _, err := GetByID(ctx, tx, id, true); err == nil {
+		return nil, errors.NewConflict("a capsule with id " + id + " already exists")
+	} else if !errors.Is(err, errors.ErrNotFound) {
+		return nil, err
+	}
+	if nameNorm.Valid {
+		exists, err := CheckNameExists(ctx, tx, workspaceNorm, nameNorm.String)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, errors.NewNameAlreadyExists(workspaceRaw, nameRaw.String, nameHolderID(ctx, tx, workspaceNorm, nameNorm.String))
+		}
+	}
+
+	if _, err := execMirrored(ctx, tx,
+		"INSERT INTO capsules ("+capsuleColumns+") SELECT "+capsuleColumns+" FROM archived_capsules WHERE id = ?", id); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+	if _, err := execMirrored(ctx, tx,
+		"UPDATE capsules SET deleted_at = NULL, updated_at = ? WHERE id = ?", time.Now().Unix(), id); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+	if _, err := execMirrored(ctx, tx, "DELETE FROM archived_capsules WHERE id = ?", id); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	// includeDeleted: an already-expired capsule is restored but hidden from reads
+	c, err := GetByID(ctx, tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Commit(tx); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	return c, nil
+}
+
 // GetByIDIncludeDeleted retrieves a capsule by ID, optionally including deleted ones.
 // This is an alias for GetByID for clarity in import logic.
 func GetByIDIncludeDeleted(ctx context.Context, q Querier, id string) (*capsule.Capsule, error) {
 	return GetByID(ctx, q, id, true)
 }
 
-// BulkSoftDelete sets deleted_at on all active capsules matching the given filters.
-// Only targets active capsules (deleted_at IS NULL is hardcoded).
-// Also bumps updated_at so deletion is reflected in "latest" ordering.
-// Requires at least one filter (defense-in-depth against accidental mass deletion).
-func BulkSoftDelete(ctx context.Context, db *sql.DB, filters InventoryFilters) (int, error) {
-	if !filters.HasFilters() {
-		return 0, errors.NewInvalidRequest("at least one filter is required for bulk delete")
-	}
-
-	now := time.Now().Unix()
-
+// bulkConditions builds the WHERE conditions shared by every filter-based bulk
+// operation and CountBulkActive, so they all select exactly the rows HasFilters
+// vouched for. deleted picks soft-deleted capsules (BulkRestore) instead of
+// active ones. Blank filter values are ignored.
+func bulkConditions(filters InventoryFilters, deleted bool) ([]string, []any) {
 	conditions := []string{"deleted_at IS NULL"}
+	if deleted {
+		conditions = []string{"deleted_at IS NOT NULL"}
+	}
 	var args []any
 
 	if filters.Workspace != nil && strings.TrimSpace(*filters.Workspace) != "" {
@@ -997,17 +1779,55 @@ func BulkSoftDelete(ctx context.Context, db *sql.DB, filters InventoryFilters) (
 		conditions = append(conditions, "role = ?")
 		args = append(args, strings.TrimSpace(*filters.Role))
 	}
+	if filters.SourceType != nil && strings.TrimSpace(*filters.SourceType) != "" {
+		conditions = append(conditions, "source_type = ?")
+		args = append(args, strings.TrimSpace(*filters.SourceType))
+	}
+	if filters.Named != nil {
+		conditions = append(conditions, namedCondition(*filters.Named))
+	}
 
-	query := "UPDATE capsules SET deleted_at = ?, updated_at = ? WHERE " + strings.Join(conditions, " AND ")
-	// Prepend deleted_at and updated_at values to args
-	args = append([]any{now, now}, args...)
+	return conditions, args
+}
 
-	result, err := db.ExecContext(ctx, query, args...)
-	if err != nil {
-		return 0, errors.NewInternal(err)
+// CountBulkActive returns how many active capsules BulkSoftDelete or BulkUpdate
+// would affect for the given filters, without modifying anything.
+// Requires at least one filter, matching the real operations.
+func CountBulkActive(ctx context.Context, q Querier, filters InventoryFilters) (int, error) {
+	if !filters.HasFilters() {
+		return 0, errors.NewInvalidRequest("at least one filter is required for bulk operations")
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	conditions, args := bulkConditions(filters, false)
+	var n int
+	if err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM capsules WHERE "+strings.Join(conditions, " AND "), args...).Scan(&n); err != nil {
+		return 0, errors.NewInternal(err)
+	}
+	return n, nil
+}
+
+// BulkSoftDelete sets deleted_at on all active capsules matching the given filters.
+// Only targets active capsules (deleted_at IS NULL is hardcoded).
+// Also bumps updated_at so deletion is reflected in "latest" ordering.
+// Requires at least one filter (defense-in-depth against accidental mass deletion).
+func BulkSoftDelete(ctx context.Context, db Querier, filters InventoryFilters) (int, error) {
+	if !filters.HasFilters() {
+		return 0, errors.NewInvalidRequest("at least one filter is required for bulk delete")
+	}
+
+	now := time.Now().Unix()
+
+	conditions, args := bulkConditions(filters, false)
+	query := "UPDATE capsules SET deleted_at = ?, updated_at = ? WHERE " + strings.Join(conditions, " AND ")
+	// Prepend deleted_at and updated_at values to args
+	args = append([]any{now, now}, args...)
+
+	result, err := execMirrored(ctx, db, query, args...)
+	if err != nil {
+		return 0, errors.NewInternal(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return 0, errors.NewInternal(err)
 	}
@@ -1015,20 +1835,485 @@ func BulkSoftDelete(ctx context.Context, db *sql.DB, filters InventoryFilters) (
 	return int(rowsAffected), nil
 }
 
+// DuplicateCandidate is an active unnamed capsule whose text matches at least
+// one other active unnamed capsule in the same workspace.
+type DuplicateCandidate struct {
+	ID            string
+	WorkspaceRaw  string
+	WorkspaceNorm string
+	ContentSHA256 string
+	UpdatedAt     int64
+}
+
+// ListUnnamedDuplicates returns active unnamed capsules that share content_sha256
+// with another active unnamed capsule in the same workspace. Rows are grouped by
+// workspace and checksum, most recently updated first within each group
+// (ties go to the later insert, since updated_at has one-second resolution).
+// Named capsules are never returned. A nil workspaceNorm scans all workspaces.
+func ListUnnamedDuplicates(ctx context.Context, q Querier, workspaceNorm *string) ([]DuplicateCandidate, error) {
+	conditions := []string{"deleted_at IS NULL", "name_norm IS NULL", "content_sha256 != ''"}
+	var args []any
+	if workspaceNorm != nil {
+		conditions = append(conditions, "workspace_norm = ?")
+		args = append(args, *workspaceNorm)
+	}
+
+	query := `
+		SELECT id, workspace_raw, workspace_norm, content_sha256, updated_at
+		FROM (
+			SELECT id, workspace_raw, workspace_norm, content_sha256, updated_at, rowid AS seq,
+				COUNT(*) OVER (PARTITION BY workspace_norm, content_sha256) AS group_size
+			FROM capsules
+			WHERE ` + strings.Join(conditions, " AND ") + `
+		)
+		WHERE group_size > 1
+		ORDER BY workspace_norm, content_sha256, updated_at DESC, seq DESC
+	`
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewInternal(err)
+	}
+	defer rows.Close()
+
+	var candidates []DuplicateCandidate
+	for rows.Next() {
+		var d DuplicateCandidate
+		if err := rows.Scan(&d.ID, &d.WorkspaceRaw, &d.WorkspaceNorm, &d.ContentSHA256, &d.UpdatedAt); err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		candidates = append(candidates, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	return candidates, nil
+}
+
 // BulkUpdateFields contains the fields to update in a bulk update operation.
 type BulkUpdateFields struct {
-	Phase *string
-	Role  *string
-	Tags  *[]string
+	Phase  *string
+	Role   *string
+	Tags   *[]string
+	Source *string
+	Title  *string
+}
+
+// BulkRestore clears deleted_at on all soft-deleted capsules matching the given filters.
+// Only targets deleted capsules (deleted_at IS NOT NULL is hardcoded).
+// Capsules whose name is now held by an active capsule are skipped rather than failing
+// the batch. Candidates are restored most-recently-deleted first, so when several deleted
+// capsules share a name, the newest one wins and the rest are skipped.
+// Also bumps updated_at so restoration is reflected in "latest" ordering.
+// Requires at least one filter (defense-in-depth against accidental mass restores).
+func BulkRestore(ctx context.Context, db *sql.DB, filters InventoryFilters) (restored, skipped int, err error) {
+	if !filters.HasFilters() {
+		return 0, 0, errors.NewInvalidRequest("at least one filter is required for bulk restore")
+	}
+
+	now := time.Now().Unix()
+
+	conditions, args := bulkConditions(filters, true)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, errors.NewInternal(err)
+	}
+	defer Rollback(tx) //nolint:errcheck
+
+	query := "SELECT id, workspace_norm, name_norm FROM capsules WHERE " +
+		strings.Join(conditions, " AND ") + " ORDER BY deleted_at DESC, id DESC"
+
+	type candidate struct {
+		id            string
+		workspaceNorm string
+		nameNorm      sql.NullString
+	}
+	var candidates []candidate
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, 0, errors.NewInternal(err)
+	}
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.id, &c.workspaceNorm, &c.nameNorm); err != nil {
+			rows.Close()
+			return 0, 0, errors.NewInternal(err)
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, 0, errors.NewInternal(err)
+	}
+	rows.Close()
+
+	for _, c := range candidates {
+		// Checked inside the transaction, so names restored earlier in this batch count too
+		if c.nameNorm.Valid {
+			exists, err := CheckNameExists(ctx, tx, c.workspaceNorm, c.nameNorm.String)
+			if err != nil {
+				return 0, 0, err
+			}
+			if exists {
+				skipped++
+				continue
+			}
+		}
+
+		if _, err := execMirrored(ctx, tx,
+			"UPDATE capsules SET deleted_at = NULL, updated_at = ? WHERE id = ?", now, c.id); err != nil {
+			return 0, 0, errors.NewInternal(err)
+		}
+		restored++
+	}
+
+	if err := Commit(tx); err != nil {
+		return 0, 0, errors.NewInternal(err)
+	}
+
+	return restored, skipped, nil
+}
+
+// BulkMove moves all active capsules matching the given filters into the workspace
+// newWorkspaceRaw, recomputing workspace_norm. Runs in a single transaction: if any
+// named capsule would collide with an active capsule in the destination (including
+// another capsule moved in the same batch), nothing is moved and the first conflict
+// is returned as ErrNameAlreadyExists. Candidates are checked oldest first.
+// Also bumps updated_at. Requires at least one filter.
+func BulkMove(ctx context.Context, db *sql.DB, filters InventoryFilters, newWorkspaceRaw string, maxPerWorkspace int) (int, error) {
+	if !filters.HasFilters() {
+		return 0, errors.NewInvalidRequest("at least one filter is required for bulk move")
+	}
+
+	newWorkspaceNorm := capsule.Normalize(newWorkspaceRaw)
+	if newWorkspaceNorm == "" {
+		return 0, errors.NewInvalidRequest("destination workspace must not be empty")
+	}
+
+	now := time.Now().Unix()
+	conditions, args := bulkConditions(filters, false)
+	whereClause := " WHERE " + strings.Join(conditions, " AND ")
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errors.NewInternal(err)
+	}
+	defer Rollback(tx) //nolint:errcheck
+
+	type candidate struct {
+		id       string
+		nameRaw  sql.NullString
+		nameNorm sql.NullString
+	}
+	var candidates []candidate
+	incoming := 0 // candidates not already in the destination
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, name_raw, name_norm, workspace_norm FROM capsules"+whereClause+" ORDER BY created_at ASC, id ASC", args...)
+	if err != nil {
+		return 0, errors.NewInternal(err)
+	}
+	for rows.Next() {
+		var c candidate
+		var workspaceNorm string
+		if err := rows.Scan(&c.id, &c.nameRaw, &c.nameNorm, &workspaceNorm); err != nil {
+			rows.Close()
+			return 0, errors.NewInternal(err)
+		}
+		candidates = append(candidates, c)
+		if workspaceNorm != newWorkspaceNorm {
+			incoming++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, errors.NewInternal(err)
+	}
+	rows.Close()
+
+	// Same limit batch move enforces per capsule, checked once for the whole set
+	if maxPerWorkspace > 0 && incoming > 0 {
+		count, err := CountActiveInWorkspace(ctx, tx, newWorkspaceNorm)
+		if err != nil {
+			return 0, err
+		}
+		if count+incoming > maxPerWorkspace {
+			return 0, errors.NewQuotaExceeded(newWorkspaceRaw, maxPerWorkspace)
+		}
+	}
+
+	for _, c := range candidates {
+		// Checked inside the transaction, so capsules moved earlier in this batch count too
+		if c.nameNorm.Valid {
+			var holder string
+			err := tx.QueryRowContext(ctx, `
+				SELECT id FROM capsules
+				WHERE workspace_norm = ? AND name_norm = ? AND deleted_at IS NULL AND id != ?
+				LIMIT 1`, newWorkspaceNorm, c.nameNorm.String, c.id).Scan(&holder)
+			if err == nil {
+				return 0, errors.NewNameAlreadyExists(newWorkspaceRaw, c.nameRaw.String, holder)
+			}
+			if err != sql.ErrNoRows {
+				return 0, errors.NewInternal(err)
+			}
+		}
+
+		if _, err := execMirrored(ctx, tx,
+			"UPDATE capsules SET workspace_raw = ?, workspace_norm = ?, updated_at = ? WHERE id = ?",
+			newWorkspaceRaw, newWorkspaceNorm, now, c.id); err != nil {
+			return 0, errors.NewInternal(err)
+		}
+	}
+
+	if err := Commit(tx); err != nil {
+		return 0, errors.NewInternal(err)
+	}
+
+	return len(candidates), nil
+}
+
+// RenameTag replaces oldTag with newTag on every active capsule carrying oldTag,
+// optionally scoped to a workspace. If a capsule already has newTag, the
+// duplicate is dropped (merge). Runs in a single transaction and returns the
+// number of capsules changed.
+func RenameTag(ctx context.Context, db *sql.DB, oldTag, newTag string, workspace *string) (int, error) {
+	now := time.Now().Unix()
+
+	conditions := []string{
+		"deleted_at IS NULL",
+		"EXISTS(SELECT 1 FROM json_each(tags_json) WHERE value = ?)",
+	}
+	args := []any{oldTag}
+	if workspace != nil && strings.TrimSpace(*workspace) != "" {
+		conditions = append(conditions, "workspace_norm = ?")
+		args = append(args, strings.TrimSpace(*workspace))
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errors.NewInternal(err)
+	}
+	defer Rollback(tx) //nolint:errcheck
+
+	query := "SELECT id, tags_json FROM capsules WHERE " + strings.Join(conditions, " AND ")
+
+	type candidate struct {
+		id   string
+		tags []string
+	}
+	var candidates []candidate
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, errors.NewInternal(err)
+	}
+	for rows.Next() {
+		var c candidate
+		var tagsJSON string
+		if err := rows.Scan(&c.id, &tagsJSON); err != nil {
+			rows.Close()
+			return 0, errors.NewInternal(err)
+		}
+		if err := json.Unmarshal([]byte(tagsJSON), &c.tags); err != nil {
+			rows.Close()
+			return 0, errors.NewInternal(err)
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, errors.NewInternal(err)
+	}
+	rows.Close()
+
+	for _, c := range candidates {
+		data, err := json.Marshal(renameTagInList(c.tags, oldTag, newTag))
+		if err != nil {
+			return 0, errors.NewInternal(err)
+		}
+		if _, err := execMirrored(ctx, tx,
+			"UPDATE capsules SET tags_json = ?, updated_at = ? WHERE id = ?", string(data), now, c.id); err != nil {
+			return 0, errors.NewInternal(err)
+		}
+	}
+
+	if err := Commit(tx); err != nil {
+		return 0, errors.NewInternal(err)
+	}
+
+	return len(candidates), nil
+}
+
+// BulkAddTags adds tags to every active capsule matching filters, keeping each
+// capsule's existing tags (and their order) and skipping tags it already has.
+// Only capsules whose tags change are written (updated_at bumped) and counted.
+func BulkAddTags(ctx context.Context, db *sql.DB, filters InventoryFilters, tags []string) (int, error) {
+	return bulkEditTags(ctx, db, filters, "", nil, func(current []string) []string {
+		out := slices.Clone(current)
+		for _, tag := range tags {
+			if !slices.Contains(out, tag) {
+				out = append(out, tag)
+			}
+		}
+		return out
+	})
+}
+
+// BulkRemoveTags removes tags from every active capsule matching filters.
+// Only capsules carrying at least one of the tags are written and counted; a
+// capsule left without tags gets tags_json NULL.
+func BulkRemoveTags(ctx context.Context, db *sql.DB, filters InventoryFilters, tags []string) (int, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tags)), ", ")
+	carrying := "EXISTS(SELECT 1 FROM json_each(tags_json) WHERE value IN (" + placeholders + "))"
+	args := make([]any, len(tags))
+	for i, tag := range tags {
+		args[i] = tag
+	}
+	return bulkEditTags(ctx, db, filters, carrying, args, func(current []string) []string {
+		return slices.DeleteFunc(slices.Clone(current), func(t string) bool { return slices.Contains(tags, t) })
+	})
+}
+
+// bulkEditTags read-modify-writes tags_json of the active capsules matching
+// filters (and extra, when set) in one transaction. edit returns the new tag
+// list; rows it leaves unchanged are not written. Returns the number written.
+func bulkEditTags(ctx context.Context, db *sql.DB, filters InventoryFilters, extra string, extraArgs []any, edit func([]string) []string) (int, error) {
+	if !filters.HasFilters() {
+		return 0, errors.NewInvalidRequest("at least one filter is required for bulk tag edits")
+	}
+
+	now := time.Now().Unix()
+
+	conditions, args := bulkConditions(filters, false)
+	if extra != "" {
+		conditions = append(conditions, extra)
+		args = append(args, extraArgs...)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errors.NewInternal(err)
+	}
+	defer Rollback(tx) //nolint:errcheck
+
+	type candidate struct {
+		id   string
+		tags []string
+	}
+	var candidates []candidate
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, tags_json FROM capsules WHERE "+strings.Join(conditions, " AND "), args...)
+	if err != nil {
+		return 0, errors.NewInternal(err)
+	}
+	for rows.Next() {
+		var c candidate
+		var tagsJSON sql.NullString
+		if err := rows.Scan(&c.id, &tagsJSON); err != nil {
+			rows.Close()
+			return 0, errors.NewInternal(err)
+		}
+		if tagsJSON.Valid && tagsJSON.String != "" {
+			if err := json.Unmarshal([]byte(tagsJSON.String), &c.tags); err != nil {
+				rows.Close()
+				return 0, errors.NewInternal(err)
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, errors.NewInternal(err)
+	}
+	rows.Close()
+
+	updated := 0
+	for _, c := range candidates {
+		tags := edit(c.tags)
+		if slices.Equal(tags, c.tags) {
+			continue
+		}
+		var tagsJSON any // NULL when no tags are left
+		if len(tags) > 0 {
+			data, err := json.Marshal(tags)
+			if err != nil {
+				return 0, errors.NewInternal(err)
+			}
+			tagsJSON = string(data)
+		}
+		if _, err := execMirrored(ctx, tx,
+			"UPDATE capsules SET tags_json = ?, updated_at = ? WHERE id = ?", tagsJSON, now, c.id); err != nil {
+			return 0, errors.NewInternal(err)
+		}
+		updated++
+	}
+
+	if err := Commit(tx); err != nil {
+		return 0, errors.NewInternal(err)
+	}
+
+	return updated, nil
+}
+
+// renameTagInList replaces oldTag with newTag, keeping the first occurrence of
+// each tag and preserving order.
+func renameTagInList(tags []string, oldTag, newTag string) []string {
+	seen := make(map[string]bool, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if t == oldTag {
+			t = newTag
+		}
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out
 }
 
 // SearchFilters contains optional filters for search operations.
 type SearchFilters struct {
-	Workspace *string
-	Tag       *string
-	RunID     *string
-	Phase     *string
-	Role      *string
+	Workspaces []string // normalized; matches any of them, empty means all workspaces
+	Tag        *string
+	RunID      *string
+	Phase      *string
+	Role       *string
+	SourceType *string
+	ExcludeID  string // omit this capsule (e.g. the source of a related-capsules query)
+
+	Tags          []string // matched per TagMatch; combined with Tag
+	TagMatch      TagMatch // how Tags match; empty means TagMatchAny
+	NamePrefix    *string  // normalized; name_norm LIKE 'prefix%'
+	UpdatedAfter  *int64   // updated_at >= this Unix time
+	UpdatedBefore *int64   // updated_at < this Unix time
+}
+
+// TagMatch selects whether SearchFilters.Tags require any or all of the tags.
+type TagMatch string
+
+const (
+	TagMatchAny TagMatch = "any" // at least one tag (default)
+	TagMatchAll TagMatch = "all" // every tag
+)
+
+// SearchOrder selects how SearchFullText orders results.
+type SearchOrder string
+
+const (
+	SearchOrderRelevance SearchOrder = "relevance"  // BM25 rank (default)
+	SearchOrderUpdated   SearchOrder = "updated_at" // most recently updated first
+)
+
+// SearchCursor is a keyset position in SearchOrderUpdated or ChangesSince
+// results: the page starts after the row with this (updated_at, id).
+type SearchCursor struct {
+	UpdatedAt int64
+	ID        string
 }
 
 // SearchResult contains a capsule summary with match snippet.
@@ -1037,158 +2322,540 @@ type SearchResult struct {
 	Snippet string // Highlighted match context (~300 chars max)
 }
 
-// SearchFullText performs full-text search across capsules.
-// Returns results ranked by relevance (BM25) with match snippets.
-// Title matches are weighted 5x higher than body matches.
-func SearchFullText(ctx context.Context, db *sql.DB, query string, filters SearchFilters, limit, offset int, includeDeleted bool) ([]SearchResult, int, error) {
+// searchWhere builds the WHERE clause shared by SearchFullText and SearchFacetCounts.
+// FTS5 MATCH is required for the JOIN to work.
+func searchWhere(query string, filters SearchFilters, includeDeleted bool) (string, []any) {
+	conditions := []string{"capsules_fts MATCH ?"}
+	args := []any{query}
+
+	if !includeDeleted {
+		conditions = append(conditions, "c.deleted_at IS NULL")
+	}
+	switch len(filters.Workspaces) {
+	case 0:
+	case 1:
+		conditions = append(conditions, "c.workspace_norm = ?")
+		args = append(args, filters.Workspaces[0])
+	default:
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(filters.Workspaces)), ", ")
+		conditions = append(conditions, "c.workspace_norm IN ("+placeholders+")")
+		for _, ws := range filters.Workspaces {
+			args = append(args, ws)
+		}
+	}
+	if filters.Tag != nil {
+		conditions = append(conditions, "EXISTS(SELECT 1 FROM json_each(c.tags_json) WHERE value = ?)")
+		args = append(args, *filters.Tag)
+	}
+	if len(filters.Tags) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(filters.Tags)), ", ")
+		for _, tag := range filters.Tags {
+			args = append(args, tag)
+		}
+		if filters.TagMatch == TagMatchAll {
+			conditions = append(conditions, "(SELECT COUNT(DISTINCT value) FROM json_each(c.tags_json) WHERE value IN ("+placeholders+")) = ?")
+			args = append(args, len(filters.Tags))
+		} else {
+			conditions = append(conditions, "EXISTS(SELECT 1 FROM json_each(c.tags_json) WHERE value IN ("+placeholders+"))")
+		}
+	}
+	if filters.NamePrefix != nil {
+		conditions = append(conditions, "c.name_norm LIKE ? ESCAPE '\\'")
+		args = append(args, escapeLikePattern(*filters.NamePrefix)+"%")
+	}
+	if filters.UpdatedAfter != nil {
+		conditions = append(conditions, "c.updated_at >= ?")
+		args = append(args, *filters.UpdatedAfter)
+	}
+	if filters.UpdatedBefore != nil {
+		conditions = append(conditions, "c.updated_at < ?")
+		args = append(args, *filters.UpdatedBefore)
+	}
+	if filters.RunID != nil {
+		conditions = append(conditions, "c.run_id = ?")
+		args = append(args, *filters.RunID)
+	}
+	if filters.Phase != nil {
+		conditions = append(conditions, "c.phase = ?")
+		args = append(args, *filters.Phase)
+	}
+	if filters.Role != nil {
+		conditions = append(conditions, "c.role = ?")
+		args = append(args, *filters.Role)
+	}
+	if filters.SourceType != nil {
+		conditions = append(conditions, "c.source_type = ?")
+		args = append(args, *filters.SourceType)
+	}
+	if filters.ExcludeID != "" {
+		conditions = append(conditions, "c.id != ?")
+		args = append(args, filters.ExcludeID)
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// SearchFullText performs full-text search across capsules.
+// Returns results ranked by relevance (BM25) with match snippets.
+// Title matches are weighted 5x higher than body matches.
+// snippetTokens is the snippet() window around each match; with snippetTokens <= 0
+// snippet() is not computed and snippets are empty;
+// ranking and order are unchanged.
+// order defaults to SearchOrderRelevance. after is only valid with SearchOrderUpdated
+// and restricts the page to rows past the cursor; the returned total still counts
+// every match, so pages stay stable when newer capsules are written mid-traversal.
+func SearchFullText(ctx context.Context, db *sql.DB, query string, filters SearchFilters, limit, offset int, includeDeleted bool, snippetTokens int, order SearchOrder, after *SearchCursor) ([]SearchResult, int, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, 0, errors.NewInvalidRequest("query is required")
+	}
+	if utf8.RuneCountInString(query) > MaxSearchQueryChars {
+		return nil, 0, errors.NewInvalidRequest(fmt.Sprintf("query exceeds maximum length of %d characters", MaxSearchQueryChars))
+	}
+
+	orderBy, err := searchOrderBy(order)
+	if err != nil {
+		return nil, 0, err
+	}
+	if after != nil && orderBy != searchOrderByUpdated {
+		return nil, 0, errors.NewInvalidRequest("cursor requires sort by updated_at")
+	}
+
+	// Use a read-only transaction to ensure COUNT and page results come from the
+	// same snapshot (prevents inconsistencies under concurrent writes).
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, 0, errors.NewInternal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	whereClause, args := searchWhere(query, filters, includeDeleted)
+
+	// Count query
+	countQuery := `
+		SELECT COUNT(*)
+		FROM capsules c
+		INNER JOIN capsules_fts ON c.rowid = capsules_fts.rowid` + whereClause
+
+	var total int
+	if err := tx.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		// Check for FTS5 syntax errors
+		if isFTSSyntaxError(err) {
+			return nil, 0, errors.NewInvalidRequest("invalid search syntax")
+		}
+		return nil, 0, errors.NewInternal(err)
+	}
+
+	// The cursor narrows only the page, not the count
+	pageWhere, pageArgs := whereClause, args
+	if after != nil {
+		pageWhere += " AND (c.updated_at, c.id) < (?, ?)"
+		pageArgs = append(slices.Clone(args), after.UpdatedAt, after.ID)
+	}
+
+	searchQuery := searchSelect(snippetTokens) + pageWhere + `
+		ORDER BY ` + orderBy + `
+		LIMIT ? OFFSET ?`
+
+	searchArgs := append(pageArgs, limit, offset)
+	rows, err := tx.QueryContext(ctx, searchQuery, searchArgs...)
+	if err != nil {
+		if isFTSSyntaxError(err) {
+			return nil, 0, errors.NewInvalidRequest("invalid search syntax")
+		}
+		return nil, 0, errors.NewInternal(err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		r, err := scanSearchResult(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, errors.NewInternal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, errors.NewInternal(err)
+	}
+
+	return results, total, nil
+}
+
+// SearchFullTextEach runs the same query as SearchFullText but passes each row
+// to fn as it is scanned instead of collecting a page, and skips the COUNT.
+// limit <= 0 means no limit. An error from fn stops the scan and is returned
+// unchanged.
+func SearchFullTextEach(ctx context.Context, db Querier, query string, filters SearchFilters, limit, offset int, includeDeleted bool, snippetTokens int, order SearchOrder, fn func(SearchResult) error) error {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return errors.NewInvalidRequest("query is required")
+	}
+	if utf8.RuneCountInString(query) > MaxSearchQueryChars {
+		return errors.NewInvalidRequest(fmt.Sprintf("query exceeds maximum length of %d characters", MaxSearchQueryChars))
+	}
+	orderBy, err := searchOrderBy(order)
+	if err != nil {
+		return err
+	}
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
+	}
+
+	whereClause, args := searchWhere(query, filters, includeDeleted)
+	searchQuery := searchSelect(snippetTokens) + whereClause + `
+		ORDER BY ` + orderBy + `
+		LIMIT ? OFFSET ?`
+
+	rows, err := db.QueryContext(ctx, searchQuery, append(args, limit, offset)...)
+	if err != nil {
+		if isFTSSyntaxError(err) {
+			return errors.NewInvalidRequest("invalid search syntax")
+		}
+		return errors.NewInternal(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r, err := scanSearchResult(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return errors.NewInternal(err)
+	}
+	return nil
+}
+
+const searchOrderByUpdated = "c.updated_at DESC, c.id DESC"
+
+// searchOrderBy returns the ORDER BY clause for a search order ("" = relevance).
+// ORDER BY bm25 ASC because bm25() returns negative values (more negative = better match).
+func searchOrderBy(order SearchOrder) (string, error) {
+	switch order {
+	case "", SearchOrderRelevance:
+		return "bm25(capsules_fts, 1.0, 5.0, 5.0) ASC, c.updated_at DESC, c.id DESC", nil
+	case SearchOrderUpdated:
+		return searchOrderByUpdated, nil
+	default:
+		return "", errors.NewInvalidRequest(fmt.Sprintf("unknown search order %q", order))
+	}
+}
+
+// searchSelect returns the SELECT ... FROM of a search query, up to the WHERE
+// clause. The columns match scanSearchResult.
+// snippet() params: table, column (-1 = best-matching of capsule_text/title/name_raw), start mark, end mark, ellipsis, max tokens
+// bm25() params: table, weights for capsule_text, title, name_raw (higher = more important)
+func searchSelect(snippetTokens int) string {
+	snippetColumn := "''"
+	if snippetTokens > 0 {
+		snippetColumn = fmt.Sprintf("snippet(capsules_fts, -1, '[[[B]]]', '[[[/B]]]', '...', %d)", snippetTokens)
+	}
+	return `
+		SELECT c.id, c.workspace_raw, c.workspace_norm, c.name_raw, c.name_norm,
+			c.title, c.capsule_chars, c.tokens_estimate, c.content_sha256, c.line_count, c.word_count, c.section_count, c.tags_json, c.source,
+			c.run_id, c.phase, c.role, c.source_type, c.source_ref, c.created_at, c.updated_at, c.deleted_at,
+			` + summaryAbstractColumn("c.") + `, ` + snippetColumn + ` as snippet
+		FROM capsules c
+		INNER JOIN capsules_fts ON c.rowid = capsules_fts.rowid`
+}
+
+// scanSearchResult scans one row selected by searchSelect.
+func scanSearchResult(rows *sql.Rows) (SearchResult, error) {
+	var (
+		s          capsule.CapsuleSummary
+		nameRaw    sql.NullString
+		nameNorm   sql.NullString
+		title      sql.NullString
+		tagsJSON   sql.NullString
+		source     sql.NullString
+		runID      sql.NullString
+		phase      sql.NullString
+		role       sql.NullString
+		sourceType sql.NullString
+		sourceRef  sql.NullString
+		deletedAt  sql.NullInt64
+		head       string
+		snippet    string
+	)
+
+	err := rows.Scan(
+		&s.ID, &s.Workspace, &s.WorkspaceNorm, &nameRaw, &nameNorm,
+		&title, &s.CapsuleChars, &s.TokensEstimate, &s.ContentSHA256,
+		&s.LineCount, &s.WordCount, &s.SectionCount,
+		&tagsJSON, &source, &runID, &phase, &role, &sourceType, &sourceRef,
+		&s.CreatedAt, &s.UpdatedAt, &deletedAt, &head,
+		&snippet,
+	)
+	if err != nil {
+		return SearchResult{}, errors.NewInternal(err)
+	}
+
+	// Convert nullable fields
+	s.Name = fromNullString(nameRaw)
+	s.NameNorm = fromNullString(nameNorm)
+	s.Title = fromNullString(title)
+	s.Source = fromNullString(source)
+	s.RunID = fromNullString(runID)
+	s.Phase = fromNullString(phase)
+	s.Role = fromNullString(role)
+	s.SourceType = fromNullString(sourceType)
+	s.SourceRef = fromNullString(sourceRef)
+	s.Abstract = capsule.Abstract(head, capsule.AbstractMaxChars)
+
+	// Convert deleted_at
+	if deletedAt.Valid {
+		s.DeletedAt = &deletedAt.Int64
+	}
+
+	// Parse tags JSON
+	if tagsJSON.Valid && tagsJSON.String != "" {
+		if err := json.Unmarshal([]byte(tagsJSON.String), &s.Tags); err != nil {
+			return SearchResult{}, errors.NewInternal(err)
+		}
+	}
+
+	return SearchResult{Summary: s, Snippet: snippet}, nil
+}
+
+// SearchFacets holds per-value hit counts over a full search rowset.
+type SearchFacets struct {
+	Workspaces map[string]int // keyed by normalized workspace
+	Phases     map[string]int // capsules without a phase are not counted
+	Tags       map[string]int // a capsule counts once per distinct tag
+}
+
+// SearchFacetCounts counts search hits grouped by workspace, phase, and tag.
+// Uses the same MATCH and filters as SearchFullText but ignores pagination,
+// so counts cover every matching capsule, not just the current page.
+func SearchFacetCounts(ctx context.Context, db *sql.DB, query string, filters SearchFilters, includeDeleted bool) (*SearchFacets, error) {
 	query = strings.TrimSpace(query)
 	if query == "" {
-		return nil, 0, errors.NewInvalidRequest("query is required")
+		return nil, errors.NewInvalidRequest("query is required")
 	}
 	if utf8.RuneCountInString(query) > MaxSearchQueryChars {
-		return nil, 0, errors.NewInvalidRequest(fmt.Sprintf("query exceeds maximum length of %d characters", MaxSearchQueryChars))
+		return nil, errors.NewInvalidRequest(fmt.Sprintf("query exceeds maximum length of %d characters", MaxSearchQueryChars))
 	}
 
-	// Use a read-only transaction to ensure COUNT and page results come from the
-	// same snapshot (prevents inconsistencies under concurrent writes).
+	// Read-only transaction so all three facets see the same snapshot
 	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
 	if err != nil {
-		return nil, 0, errors.NewInternal(err)
+		return nil, errors.NewInternal(err)
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	// Build WHERE conditions
-	// FTS5 MATCH is required for the JOIN to work
-	conditions := []string{"capsules_fts MATCH ?"}
-	args := []any{query}
+	whereClause, args := searchWhere(query, filters, includeDeleted)
+	from := `
+		FROM capsules c
+		INNER JOIN capsules_fts ON c.rowid = capsules_fts.rowid`
 
-	if !includeDeleted {
-		conditions = append(conditions, "c.deleted_at IS NULL")
-	}
-	if filters.Workspace != nil {
-		conditions = append(conditions, "c.workspace_norm = ?")
-		args = append(args, *filters.Workspace)
-	}
-	if filters.Tag != nil {
-		conditions = append(conditions, "EXISTS(SELECT 1 FROM json_each(c.tags_json) WHERE value = ?)")
-		args = append(args, *filters.Tag)
-	}
-	if filters.RunID != nil {
-		conditions = append(conditions, "c.run_id = ?")
-		args = append(args, *filters.RunID)
+	facets := &SearchFacets{}
+	if facets.Workspaces, err = facetCounts(ctx, tx,
+		`SELECT c.workspace_norm, COUNT(*)`+from+whereClause+`
+		GROUP BY c.workspace_norm`, args); err != nil {
+		return nil, err
 	}
-	if filters.Phase != nil {
-		conditions = append(conditions, "c.phase = ?")
-		args = append(args, *filters.Phase)
+	if facets.Phases, err = facetCounts(ctx, tx,
+		`SELECT c.phase, COUNT(*)`+from+whereClause+` AND c.phase IS NOT NULL
+		GROUP BY c.phase`, args); err != nil {
+		return nil, err
 	}
-	if filters.Role != nil {
-		conditions = append(conditions, "c.role = ?")
-		args = append(args, *filters.Role)
+	if facets.Tags, err = facetCounts(ctx, tx,
+		`SELECT t.value, COUNT(DISTINCT c.id)`+from+`
+		INNER JOIN json_each(c.tags_json) t`+whereClause+`
+		GROUP BY t.value`, args); err != nil {
+		return nil, err
 	}
 
-	whereClause := " WHERE " + strings.Join(conditions, " AND ")
-
-	// Count query
-	countQuery := `
-		SELECT COUNT(*)
-		FROM capsules c
-		INNER JOIN capsules_fts ON c.rowid = capsules_fts.rowid` + whereClause
-
-	var total int
-	if err := tx.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
-		// Check for FTS5 syntax errors
-		if isFTSSyntaxError(err) {
-			return nil, 0, errors.NewInvalidRequest("invalid search syntax")
-		}
-		return nil, 0, errors.NewInternal(err)
+	if err := tx.Commit(); err != nil {
+		return nil, errors.NewInternal(err)
 	}
 
-	// Search query with snippets
-	// snippet() params: table, column (-1 for all), start mark, end mark, ellipsis, max tokens
-	// bm25() params: table, weight for capsule_text, weight for title (higher = more important)
-	// ORDER BY bm25 ASC because bm25() returns negative values (more negative = better match)
-	searchQuery := `
-		SELECT c.id, c.workspace_raw, c.workspace_norm, c.name_raw, c.name_norm,
-			c.title, c.capsule_chars, c.tokens_estimate, c.tags_json, c.source,
-			c.run_id, c.phase, c.role, c.created_at, c.updated_at, c.deleted_at,
-			snippet(capsules_fts, -1, '[[[B]]]', '[[[/B]]]', '...', 64) as snippet
-		FROM capsules c
-		INNER JOIN capsules_fts ON c.rowid = capsules_fts.rowid` + whereClause + `
-		ORDER BY bm25(capsules_fts, 1.0, 5.0) ASC, c.updated_at DESC, c.id DESC
-		LIMIT ? OFFSET ?`
+	return facets, nil
+}
 
-	searchArgs := append(args, limit, offset)
-	rows, err := tx.QueryContext(ctx, searchQuery, searchArgs...)
+// facetCounts runs a two-column (key, count) grouped query into a map.
+func facetCounts(ctx context.Context, tx *sql.Tx, query string, args []any) (map[string]int, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		if isFTSSyntaxError(err) {
-			return nil, 0, errors.NewInvalidRequest("invalid search syntax")
+			return nil, errors.NewInvalidRequest("invalid search syntax")
 		}
-		return nil, 0, errors.NewInternal(err)
+		return nil, errors.NewInternal(err)
 	}
 	defer rows.Close()
 
-	var results []SearchResult
+	counts := make(map[string]int)
 	for rows.Next() {
 		var (
-			s         capsule.CapsuleSummary
-			nameRaw   sql.NullString
-			nameNorm  sql.NullString
-			title     sql.NullString
-			tagsJSON  sql.NullString
-			source    sql.NullString
-			runID     sql.NullString
-			phase     sql.NullString
-			role      sql.NullString
-			deletedAt sql.NullInt64
-			snippet   string
-		)
-
-		err := rows.Scan(
-			&s.ID, &s.Workspace, &s.WorkspaceNorm, &nameRaw, &nameNorm,
-			&title, &s.CapsuleChars, &s.TokensEstimate,
-			&tagsJSON, &source, &runID, &phase, &role,
-			&s.CreatedAt, &s.UpdatedAt, &deletedAt,
-			&snippet,
+			key   string
+			count int
 		)
-		if err != nil {
-			return nil, 0, errors.NewInternal(err)
+		if err := rows.Scan(&key, &count); err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		counts[key] = count
+	}
+	if err := rows.Err(); err != nil {
+		if isFTSSyntaxError(err) {
+			return nil, errors.NewInvalidRequest("invalid search syntax")
 		}
+		return nil, errors.NewInternal(err)
+	}
+
+	return counts, nil
+}
+
+// FTSSampleSize caps how many rowids FTSReport lists per discrepancy kind.
+const FTSSampleSize = 20
+
+// FTSReport describes how capsules_fts compares to the capsules table.
+// The index covers every row (soft-deleted capsules stay searchable with
+// include_deleted), so it is compared against all rows, not just active ones.
+type FTSReport struct {
+	CapsuleRows    int     // rows in capsules
+	IndexedRows    int     // documents in the FTS index
+	Missing        int     // capsule rows absent from the index
+	Orphaned       int     // index documents with no capsule row
+	MissingSample  []int64 // up to FTSSampleSize missing rowids
+	OrphanedSample []int64 // up to FTSSampleSize orphaned rowids
+}
+
+// Consistent reports whether the index and table hold the same rowids.
+func (r *FTSReport) Consistent() bool {
+	return r.Missing == 0 && r.Orphaned == 0 && r.CapsuleRows == r.IndexedRows
+}
+
+// VerifyFTSConsistency compares capsules against the capsules_fts index.
+// capsules_fts is an external-content table, so SELECTs on it read from
+// capsules; the index's own rowids come from the capsules_fts_docsize shadow table.
+// Only rowid membership is checked: an index entry with stale text is not detected
+// (RebuildFTS fixes both).
+func VerifyFTSConsistency(ctx context.Context, db *sql.DB) (*FTSReport, error) {
+	// Read-only transaction so counts and samples come from the same snapshot
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, errors.NewInternal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	report := &FTSReport{}
 
-		// Convert nullable fields
-		s.Name = fromNullString(nameRaw)
-		s.NameNorm = fromNullString(nameNorm)
-		s.Title = fromNullString(title)
-		s.Source = fromNullString(source)
-		s.RunID = fromNullString(runID)
-		s.Phase = fromNullString(phase)
-		s.Role = fromNullString(role)
+	const (
+		missingWhere  = ` FROM capsules c WHERE NOT EXISTS (SELECT 1 FROM capsules_fts_docsize d WHERE d.id = c.rowid)`
+		orphanedWhere = ` FROM capsules_fts_docsize d WHERE NOT EXISTS (SELECT 1 FROM capsules c WHERE c.rowid = d.id)`
+	)
 
-		// Convert deleted_at
-		if deletedAt.Valid {
-			s.DeletedAt = &deletedAt.Int64
+	counts := []struct {
+		query string
+		dest  *int
+	}{
+		{`SELECT COUNT(*) FROM capsules`, &report.CapsuleRows},
+		{`SELECT COUNT(*) FROM capsules_fts_docsize`, &report.IndexedRows},
+		{`SELECT COUNT(*)` + missingWhere, &report.Missing},
+		{`SELECT COUNT(*)` + orphanedWhere, &report.Orphaned},
+	}
+	for _, c := range counts {
+		if err := tx.QueryRowContext(ctx, c.query).Scan(c.dest); err != nil {
+			return nil, errors.NewInternal(err)
 		}
+	}
 
-		// Parse tags JSON
-		if tagsJSON.Valid && tagsJSON.String != "" {
-			if err := json.Unmarshal([]byte(tagsJSON.String), &s.Tags); err != nil {
-				return nil, 0, errors.NewInternal(err)
-			}
+	if report.Missing > 0 {
+		if report.MissingSample, err = sampleRowIDs(ctx, tx, `SELECT c.rowid`+missingWhere+` ORDER BY c.rowid LIMIT ?`); err != nil {
+			return nil, err
 		}
+	}
+	if report.Orphaned > 0 {
+		if report.OrphanedSample, err = sampleRowIDs(ctx, tx, `SELECT d.id`+orphanedWhere+` ORDER BY d.id LIMIT ?`); err != nil {
+			return nil, err
+		}
+	}
 
-		results = append(results, SearchResult{
-			Summary: s,
-			Snippet: snippet,
-		})
+	if err := tx.Commit(); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	return report, nil
+}
+
+// sampleRowIDs runs a single-column rowid query limited to FTSSampleSize.
+func sampleRowIDs(ctx context.Context, tx *sql.Tx, query string) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, query, FTSSampleSize)
+	if err != nil {
+		return nil, errors.NewInternal(err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		ids = append(ids, id)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, 0, errors.NewInternal(err)
+		return nil, errors.NewInternal(err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, 0, errors.NewInternal(err)
+	return ids, nil
+}
+
+// RebuildFTS repopulates capsules_fts from the capsules table from scratch.
+func RebuildFTS(ctx context.Context, db *sql.DB) error {
+	if _, err := execMirrored(ctx, db, "INSERT INTO capsules_fts(capsules_fts) VALUES('rebuild')"); err != nil {
+		return errors.NewInternal(err)
+	}
+	return nil
+}
+
+// TermFrequency is an indexed term and the number of capsules containing it.
+type TermFrequency struct {
+	Term      string `json:"term"`
+	Documents int    `json:"documents"`
+}
+
+// TopTerms returns the terms of active capsules in a workspace, ordered by
+// document frequency (ties alphabetical), read from the capsules_fts_vocab
+// instance view. Terms for which skip returns true are passed over before
+// limit is applied.
+func TopTerms(ctx context.Context, db Querier, workspaceNorm string, limit int, skip func(term string) bool) ([]TermFrequency, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT v.term, COUNT(DISTINCT v.doc) AS documents
+		FROM capsules_fts_vocab v
+		INNER JOIN capsules c ON c.rowid = v.doc
+		WHERE c.workspace_norm = ? AND c.deleted_at IS NULL
+		GROUP BY v.term
+		ORDER BY documents DESC, v.term ASC`, workspaceNorm)
+	if err != nil {
+		return nil, errors.NewInternal(err)
 	}
+	defer rows.Close()
 
-	return results, total, nil
+	var terms []TermFrequency
+	for len(terms) < limit && rows.Next() {
+		var tf TermFrequency
+		if err := rows.Scan(&tf.Term, &tf.Documents); err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		if skip != nil && skip(tf.Term) {
+			continue
+		}
+		terms = append(terms, tf)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+	return terms, nil
 }
 
 // isFTSSyntaxError checks if an error is an FTS5 user syntax error.
@@ -1245,7 +2912,7 @@ func isFTSSyntaxError(err error) bool {
 // Only targets active capsules (deleted_at IS NULL is hardcoded).
 // Empty string values in fields mean "clear the field" (set to NULL).
 // Requires at least one filter (defense-in-depth against accidental mass updates).
-func BulkUpdate(ctx context.Context, db *sql.DB, filters InventoryFilters, fields BulkUpdateFields) (int, error) {
+func BulkUpdate(ctx context.Context, db Querier, filters InventoryFilters, fields BulkUpdateFields) (int, error) {
 	if !filters.HasFilters() {
 		return 0, errors.NewInvalidRequest("at least one filter is required for bulk update")
 	}
@@ -1284,52 +2951,233 @@ func BulkUpdate(ctx context.Context, db *sql.DB, filters InventoryFilters, field
 			setArgs = append(setArgs, string(data))
 		}
 	}
+	if fields.Source != nil {
+		if *fields.Source == "" {
+			setClauses = append(setClauses, "source = NULL")
+		} else {
+			setClauses = append(setClauses, "source = ?")
+			setArgs = append(setArgs, *fields.Source)
+		}
+	}
+	if fields.Title != nil {
+		if *fields.Title == "" {
+			setClauses = append(setClauses, "title = NULL")
+		} else {
+			setClauses = append(setClauses, "title = ?")
+			setArgs = append(setArgs, *fields.Title)
+		}
+	}
 
 	// Always include updated_at
 	setClauses = append(setClauses, "updated_at = ?")
 	setArgs = append(setArgs, now)
 
-	// Build WHERE clause from filters
-	conditions := []string{"deleted_at IS NULL"}
-	var filterArgs []any
+	conditions, filterArgs := bulkConditions(filters, false)
+	query := "UPDATE capsules SET " + strings.Join(setClauses, ", ") + " WHERE " + strings.Join(conditions, " AND ")
+	args := append(setArgs, filterArgs...)
 
-	if filters.Workspace != nil && strings.TrimSpace(*filters.Workspace) != "" {
-		conditions = append(conditions, "workspace_norm = ?")
-		filterArgs = append(filterArgs, strings.TrimSpace(*filters.Workspace))
+	result, err := execMirrored(ctx, db, query, args...)
+	if err != nil {
+		return 0, errors.NewInternal(err)
 	}
-	if filters.Tag != nil && strings.TrimSpace(*filters.Tag) != "" {
-		conditions = append(conditions, "EXISTS(SELECT 1 FROM json_each(tags_json) WHERE value = ?)")
-		filterArgs = append(filterArgs, strings.TrimSpace(*filters.Tag))
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewInternal(err)
 	}
-	if filters.NamePrefix != nil && strings.TrimSpace(*filters.NamePrefix) != "" {
-		conditions = append(conditions, "name_norm LIKE ? ESCAPE '\\'")
-		filterArgs = append(filterArgs, escapeLikePattern(strings.TrimSpace(*filters.NamePrefix))+"%")
+
+	return int(rowsAffected), nil
+}
+
+// =============================================================================
+// Link Functions
+// =============================================================================
+
+// Link directions as reported by ListLinks.
+const (
+	LinkOutgoing = "outgoing"
+	LinkIncoming = "incoming"
+)
+
+// CapsuleLink is a link between two capsules, seen from one endpoint.
+// Summary describes the capsule at the other end of the link.
+type CapsuleLink struct {
+	Direction string // LinkOutgoing or LinkIncoming
+	Relation  string
+	CreatedAt int64
+	Summary   capsule.CapsuleSummary
+}
+
+// AddLink creates a directed link from fromID to toID with the given relation.
+// Adding a link that already exists is a no-op; created reports whether a new row was inserted.
+// Callers are responsible for verifying that both endpoints exist.
+func AddLink(ctx context.Context, q Querier, fromID, toID, relation string) (bool, error) {
+	query := `
+		INSERT INTO capsule_links (from_id, to_id, relation, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(from_id, to_id, relation) DO NOTHING
+	`
+
+	result, err := execMirrored(ctx, q, query, fromID, toID, relation, time.Now().Unix())
+	if err != nil {
+		return false, errors.NewInternal(err)
 	}
-	if filters.RunID != nil && strings.TrimSpace(*filters.RunID) != "" {
-		conditions = append(conditions, "run_id = ?")
-		filterArgs = append(filterArgs, strings.TrimSpace(*filters.RunID))
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.NewInternal(err)
 	}
-	if filters.Phase != nil && strings.TrimSpace(*filters.Phase) != "" {
-		conditions = append(conditions, "phase = ?")
-		filterArgs = append(filterArgs, strings.TrimSpace(*filters.Phase))
+
+	return rowsAffected > 0, nil
+}
+
+// RemoveLink deletes the link from fromID to toID with the given relation.
+// Returns ErrNotFound if no such link exists.
+func RemoveLink(ctx context.Context, q Querier, fromID, toID, relation string) error {
+	query := `DELETE FROM capsule_links WHERE from_id = ? AND to_id = ? AND relation = ?`
+
+	result, err := execMirrored(ctx, q, query, fromID, toID, relation)
+	if err != nil {
+		return errors.NewInternal(err)
 	}
-	if filters.Role != nil && strings.TrimSpace(*filters.Role) != "" {
-		conditions = append(conditions, "role = ?")
-		filterArgs = append(filterArgs, strings.TrimSpace(*filters.Role))
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewInternal(err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewLinkNotFound(fromID, toID, relation)
 	}
 
-	query := "UPDATE capsules SET " + strings.Join(setClauses, ", ") + " WHERE " + strings.Join(conditions, " AND ")
-	args := append(setArgs, filterArgs...)
+	return nil
+}
+
+// ListLinks returns all links touching the given capsule, both outgoing and incoming.
+// Each entry carries a summary of the capsule at the other end of the link.
+// If includeDeleted is false, links to soft-deleted capsules are excluded.
+// Ordered outgoing first, then by link creation time (oldest first).
+func ListLinks(ctx context.Context, q Querier, id string, includeDeleted bool) ([]CapsuleLink, error) {
+	deletedFilter := ""
+	if !includeDeleted {
+		deletedFilter = " AND c.deleted_at IS NULL"
+	}
+
+	summaryColumns := `c.id, c.workspace_raw, c.workspace_norm, c.name_raw, c.name_norm,
+			c.title, c.capsule_chars, c.tokens_estimate, c.content_sha256, c.line_count, c.word_count, c.section_count, c.tags_json, c.source,
+			c.run_id, c.phase, c.role, c.source_type, c.source_ref, c.created_at, c.updated_at, c.deleted_at,
+			` + summaryAbstractColumn("c.")
 
-	result, err := db.ExecContext(ctx, query, args...)
+	query := `
+		SELECT '` + LinkOutgoing + `', l.relation, l.created_at, ` + summaryColumns + `
+		FROM capsule_links l
+		INNER JOIN capsules c ON c.id = l.to_id
+		WHERE l.from_id = ?` + deletedFilter + `
+		UNION ALL
+		SELECT '` + LinkIncoming + `', l.relation, l.created_at, ` + summaryColumns + `
+		FROM capsule_links l
+		INNER JOIN capsules c ON c.id = l.from_id
+		WHERE l.to_id = ?` + deletedFilter + `
+		ORDER BY 1 DESC, 3 ASC, 4 ASC`
+
+	rows, err := q.QueryContext(ctx, query, id, id)
 	if err != nil {
-		return 0, errors.NewInternal(err)
+		return nil, errors.NewInternal(err)
 	}
+	defer rows.Close()
 
-	rowsAffected, err := result.RowsAffected()
+	var links []CapsuleLink
+	for rows.Next() {
+		var link CapsuleLink
+		s, err := scanCapsuleSummary(prefixScanner{
+			rows:   rows,
+			prefix: []any{&link.Direction, &link.Relation, &link.CreatedAt},
+		})
+		if err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		link.Summary = *s
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	return links, nil
+}
+
+// prefixScanner scans leading columns into prefix before handing the rest to dest.
+// This lets scanCapsuleSummary be reused for queries that select extra columns first.
+type prefixScanner struct {
+	rows   *sql.Rows
+	prefix []any
+}
+
+// Scan implements the scanner interface expected by scanCapsuleSummary.
+func (p prefixScanner) Scan(dest ...any) error {
+	return p.rows.Scan(append(p.prefix, dest...)...)
+}
+
+// =============================================================================
+// Compose Provenance Functions
+// =============================================================================
+
+// SetComposeSources records sourceIDs, in bundle order, as the capsules bundleID
+// was composed from, replacing any earlier record for bundleID. A source listed
+// twice is recorded once, at its first position.
+func SetComposeSources(ctx context.Context, q Querier, bundleID string, sourceIDs []string) error {
+	if _, err := execMirrored(ctx, q, "DELETE FROM compose_sources WHERE bundle_id = ?", bundleID); err != nil {
+		return errors.NewInternal(err)
+	}
+
+	now := time.Now().Unix()
+	for i, sourceID := range sourceIDs {
+		if _, err := execMirrored(ctx, q, `
+			INSERT INTO compose_sources (bundle_id, source_id, position, created_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(bundle_id, source_id) DO NOTHING`, bundleID, sourceID, i, now); err != nil {
+			return errors.NewInternal(err)
+		}
+	}
+
+	return nil
+}
+
+// ListBundlesContaining returns summaries of the stored bundles that were
+// composed from the given capsule, most recently updated first.
+// If includeDeleted is false, soft-deleted bundles are excluded.
+func ListBundlesContaining(ctx context.Context, q Querier, id string, includeDeleted bool) ([]capsule.CapsuleSummary, error) {
+	deletedFilter := ""
+	if !includeDeleted {
+		deletedFilter = " AND c.deleted_at IS NULL"
+	}
+
+	query := `
+		SELECT c.id, c.workspace_raw, c.workspace_norm, c.name_raw, c.name_norm,
+			c.title, c.capsule_chars, c.tokens_estimate, c.content_sha256, c.line_count, c.word_count, c.section_count, c.tags_json, c.source,
+			c.run_id, c.phase, c.role, c.source_type, c.source_ref, c.created_at, c.updated_at, c.deleted_at,
+			` + summaryAbstractColumn("c.") + `
+		FROM compose_sources s
+		INNER JOIN capsules c ON c.id = s.bundle_id
+		WHERE s.source_id = ?` + deletedFilter + `
+		ORDER BY c.updated_at DESC, c.id DESC`
+
+	rows, err := q.QueryContext(ctx, query, id)
 	if err != nil {
-		return 0, errors.NewInternal(err)
+		return nil, errors.NewInternal(err)
 	}
+	defer rows.Close()
 
-	return int(rowsAffected), nil
+	var bundles []capsule.CapsuleSummary
+	for rows.Next() {
+		s, err := scanCapsuleSummary(rows)
+		if err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		bundles = append(bundles, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	return bundles, nil
 }
@@ -101,6 +101,58 @@ func TestGetByID_NotFound(t *testing.T) {
 	}
 }
 
+func TestGetByIDPrefix(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	// 01HXAB sorts right after every 01HXAA... ID, so it tests the range's upper bound
+	for _, id := range []string{"01HXAAZZ", "01HXAB00"} {
+		if err := Insert(ctx, db, newTestCapsule(id, "default", "Content")); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	// Lowercase input matches the uppercase ID
+	c, err := GetByIDPrefix(ctx, db, "01hxaa", false)
+	if err != nil {
+		t.Fatalf("GetByIDPrefix failed: %v", err)
+	}
+	if c.ID != "01HXAAZZ" {
+		t.Errorf("ID = %q, want 01HXAAZZ", c.ID)
+	}
+
+	if _, err := GetByIDPrefix(ctx, db, "01HXA", false); !errors.Is(err, errors.ErrAmbiguousAddressing) {
+		t.Errorf("expected ErrAmbiguousAddressing, got: %v", err)
+	}
+	if _, err := GetByIDPrefix(ctx, db, "01HXAC", false); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	// The range is served by the primary key index, not a table scan
+	rows, err := db.Query("EXPLAIN QUERY PLAN SELECT id FROM capsules WHERE id >= ? AND id < ?", "01HXAA", "01HXAB")
+	if err != nil {
+		t.Fatalf("EXPLAIN failed: %v", err)
+	}
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var id, parent, notused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+		plan = append(plan, detail)
+	}
+	if got := strings.Join(plan, "; "); !strings.Contains(got, "SEARCH") {
+		t.Errorf("query plan = %q, want an index SEARCH", got)
+	}
+}
+
 func TestGetByName(t *testing.T) {
 	tmpDir := t.TempDir()
 	db, err := Init(tmpDir)
@@ -304,6 +356,73 @@ func TestSoftDelete(t *testing.T) {
 	}
 }
 
+func TestSweepExpired(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	now := time.Now().Unix()
+	past, future := now-60, now+3600
+
+	expired := newTestCapsule("01EXP001", "default", "Expired content")
+	expired.NameRaw = stringPtr("stale")
+	expired.NameNorm = stringPtr("stale")
+	expired.ExpiresAt = &past
+	live := newTestCapsule("01EXP002", "default", "Live content")
+	live.ExpiresAt = &future
+	forever := newTestCapsule("01EXP003", "default", "No expiry")
+	for _, c := range []*capsule.Capsule{expired, live, forever} {
+		if err := Insert(ctx, db, c); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	// Expired but not yet swept: hidden from active reads, visible with includeDeleted
+	if _, err := GetByID(ctx, db, expired.ID, false); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("GetByID of expired capsule should return ErrNotFound, got: %v", err)
+	}
+	if _, err := GetByName(ctx, db, "default", "stale", false); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("GetByName of expired capsule should return ErrNotFound, got: %v", err)
+	}
+	retrieved, err := GetByID(ctx, db, expired.ID, true)
+	if err != nil {
+		t.Fatalf("GetByID with includeDeleted failed: %v", err)
+	}
+	if retrieved.DeletedAt != nil || retrieved.ExpiresAt == nil || *retrieved.ExpiresAt != past {
+		t.Errorf("before sweep DeletedAt = %v, ExpiresAt = %v; want nil, %d", retrieved.DeletedAt, retrieved.ExpiresAt, past)
+	}
+
+	swept, err := SweepExpired(ctx, db, now)
+	if err != nil {
+		t.Fatalf("SweepExpired failed: %v", err)
+	}
+	if swept != 1 {
+		t.Errorf("swept = %d, want 1", swept)
+	}
+
+	retrieved, err = GetByID(ctx, db, expired.ID, true)
+	if err != nil {
+		t.Fatalf("GetByID with includeDeleted failed: %v", err)
+	}
+	if retrieved.DeletedAt == nil || *retrieved.DeletedAt != now {
+		t.Errorf("DeletedAt = %v, want %d", retrieved.DeletedAt, now)
+	}
+	for _, id := range []string{live.ID, forever.ID} {
+		if _, err := GetByID(ctx, db, id, false); err != nil {
+			t.Errorf("capsule %s should still be active: %v", id, err)
+		}
+	}
+
+	// Sweeping again finds nothing
+	if swept, err := SweepExpired(ctx, db, now); err != nil || swept != 0 {
+		t.Errorf("second sweep = %d, %v; want 0, nil", swept, err)
+	}
+}
+
 func TestSoftDelete_NotFound(t *testing.T) {
 	tmpDir := t.TempDir()
 	db, err := Init(tmpDir)
@@ -423,6 +542,15 @@ func TestInsert_UniqueConstraint(t *testing.T) {
 	if !errors.Is(err, errors.ErrNameAlreadyExists) {
 		t.Errorf("Insert should return ErrNameAlreadyExists, got: %v", err)
 	}
+
+	// Details point at the capsule holding the name
+	mossErr, ok := err.(*errors.MossError)
+	if !ok {
+		t.Fatalf("error is not a MossError: %v", err)
+	}
+	if mossErr.Details["id"] != "01FIRST1" {
+		t.Errorf("Details[id] = %v, want 01FIRST1", mossErr.Details["id"])
+	}
 }
 
 func TestGetByName_IncludeDeleted(t *testing.T) {
@@ -1137,6 +1265,141 @@ func TestGetLatestFull_EmptyWorkspace(t *testing.T) {
 	}
 }
 
+// =============================================================================
+// GetFirstSummary / GetFirstFull Tests
+// =============================================================================
+
+func TestGetFirstSummary_Basic(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer db.Close()
+
+	// Insert 3 capsules with different updated_at
+	c1 := newTestCapsule("01FFF001", "default", "First")
+	c1.UpdatedAt = 1500
+	if err := Insert(context.Background(), db, c1); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	c2 := newTestCapsule("01FFF002", "default", "Second")
+	c2.UpdatedAt = 1000
+	if err := Insert(context.Background(), db, c2); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	c3 := newTestCapsule("01FFF003", "default", "Third")
+	c3.UpdatedAt = 2000
+	if err := Insert(context.Background(), db, c3); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	summary, err := GetFirstSummary(context.Background(), db, "default", LatestFilters{}, false)
+	if err != nil {
+		t.Fatalf("GetFirstSummary failed: %v", err)
+	}
+
+	if summary == nil {
+		t.Fatal("summary should not be nil")
+	}
+	if summary.ID != "01FFF002" {
+		t.Errorf("ID = %q, want 01FFF002 (oldest)", summary.ID)
+	}
+}
+
+func TestGetFirstSummary_EmptyWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer db.Close()
+
+	summary, err := GetFirstSummary(context.Background(), db, "empty", LatestFilters{}, false)
+	if err != nil {
+		t.Fatalf("GetFirstSummary failed: %v", err)
+	}
+
+	if summary != nil {
+		t.Errorf("summary = %v, want nil for empty workspace", summary)
+	}
+}
+
+func TestGetFirstSummary_StableOrdering(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer db.Close()
+
+	// Insert capsules with same updated_at, higher ID first
+	sameTime := int64(1000)
+	c1 := newTestCapsule("01GGG003", "default", "First")
+	c1.UpdatedAt = sameTime
+	if err := Insert(context.Background(), db, c1); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	c2 := newTestCapsule("01GGG001", "default", "Second")
+	c2.UpdatedAt = sameTime
+	if err := Insert(context.Background(), db, c2); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	summary, err := GetFirstSummary(context.Background(), db, "default", LatestFilters{}, false)
+	if err != nil {
+		t.Fatalf("GetFirstSummary failed: %v", err)
+	}
+
+	// Should return lower ID when updated_at is same
+	if summary.ID != "01GGG001" {
+		t.Errorf("ID = %q, want 01GGG001 (lower ID as tiebreaker)", summary.ID)
+	}
+}
+
+func TestGetFirstFull_Basic(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer db.Close()
+
+	c1 := newTestCapsule("01HHH001", "default", "Oldest content")
+	c1.UpdatedAt = 1000
+	if err := Insert(context.Background(), db, c1); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	c2 := newTestCapsule("01HHH002", "default", "Newer content")
+	c2.UpdatedAt = 2000
+	if err := Insert(context.Background(), db, c2); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	capsule, err := GetFirstFull(context.Background(), db, "default", LatestFilters{}, false)
+	if err != nil {
+		t.Fatalf("GetFirstFull failed: %v", err)
+	}
+
+	if capsule == nil {
+		t.Fatal("capsule should not be nil")
+	}
+	if capsule.CapsuleText != "Oldest content" {
+		t.Errorf("CapsuleText = %q, want 'Oldest content'", capsule.CapsuleText)
+	}
+
+	capsule, err = GetFirstFull(context.Background(), db, "empty", LatestFilters{}, false)
+	if err != nil {
+		t.Fatalf("GetFirstFull failed: %v", err)
+	}
+	if capsule != nil {
+		t.Errorf("capsule = %v, want nil for empty workspace", capsule)
+	}
+}
+
 // =============================================================================
 // StreamForExport Tests
 // =============================================================================
@@ -1163,7 +1426,7 @@ func TestStreamForExport_All(t *testing.T) {
 		}
 	}
 
-	rows, err := StreamForExport(context.Background(), db, nil, false)
+	rows, err := StreamForExport(context.Background(), db, nil, nil, nil, false, false)
 	if err != nil {
 		t.Fatalf("StreamForExport failed: %v", err)
 	}
@@ -1206,7 +1469,7 @@ func TestStreamForExport_WorkspaceFilter(t *testing.T) {
 	}
 
 	ws := "target"
-	rows, err := StreamForExport(context.Background(), db, &ws, false)
+	rows, err := StreamForExport(context.Background(), db, &ws, nil, nil, false, false)
 	if err != nil {
 		t.Fatalf("StreamForExport failed: %v", err)
 	}
@@ -1251,7 +1514,7 @@ func TestStreamForExport_IncludeDeleted(t *testing.T) {
 	}
 
 	// Without includeDeleted
-	rows, err := StreamForExport(context.Background(), db, nil, false)
+	rows, err := StreamForExport(context.Background(), db, nil, nil, nil, false, false)
 	if err != nil {
 		t.Fatalf("StreamForExport failed: %v", err)
 	}
@@ -1265,7 +1528,7 @@ func TestStreamForExport_IncludeDeleted(t *testing.T) {
 	}
 
 	// With includeDeleted
-	rows, err = StreamForExport(context.Background(), db, nil, true)
+	rows, err = StreamForExport(context.Background(), db, nil, nil, nil, true, false)
 	if err != nil {
 		t.Fatalf("StreamForExport failed: %v", err)
 	}
@@ -1369,6 +1632,44 @@ func TestUpdateFull_NotFound(t *testing.T) {
 	}
 }
 
+func TestUpdateFull_NameCollision(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	holder := newTestCapsule("01HOLDER", "default", "Holder content")
+	holder.NameRaw = stringPtr("auth")
+	holder.NameNorm = stringPtr("auth")
+	other := newTestCapsule("01OTHER1", "default", "Other content")
+	for _, c := range []*capsule.Capsule{holder, other} {
+		if err := Insert(ctx, db, c); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	// Renaming other onto holder's name collides
+	other.NameRaw = stringPtr("Auth")
+	other.NameNorm = stringPtr("auth")
+	err = UpdateFull(ctx, db, other)
+	if !errors.Is(err, errors.ErrNameAlreadyExists) {
+		t.Fatalf("UpdateFull should return ErrNameAlreadyExists, got: %v", err)
+	}
+	mossErr, ok := err.(*errors.MossError)
+	if !ok {
+		t.Fatalf("error is not a MossError: %v", err)
+	}
+	if mossErr.Details["id"] != "01HOLDER" {
+		t.Errorf("Details[id] = %v, want 01HOLDER", mossErr.Details["id"])
+	}
+	if mossErr.Details["name"] != "Auth" {
+		t.Errorf("Details[name] = %v, want Auth", mossErr.Details["name"])
+	}
+}
+
 // =============================================================================
 // FindUniqueName Tests
 // =============================================================================
@@ -1455,6 +1756,56 @@ func TestFindUniqueName_SkipsGaps(t *testing.T) {
 	}
 }
 
+func TestInsertWithUniqueName(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	// Free name: inserted unchanged, keeping the raw form
+	c1 := newTestCapsule("01IWU001", "default", "Content")
+	c1.NameRaw = stringPtr("Auth")
+	c1.NameNorm = stringPtr("auth")
+	if err := InsertWithUniqueName(ctx, db, c1); err != nil {
+		t.Fatalf("InsertWithUniqueName failed: %v", err)
+	}
+	if *c1.NameRaw != "Auth" || *c1.NameNorm != "auth" {
+		t.Errorf("name = (%q, %q), want (Auth, auth)", *c1.NameRaw, *c1.NameNorm)
+	}
+
+	// Taken name: suffixed
+	c2 := newTestCapsule("01IWU002", "default", "Content")
+	c2.NameRaw = stringPtr("Auth")
+	c2.NameNorm = stringPtr("auth")
+	if err := InsertWithUniqueName(ctx, db, c2); err != nil {
+		t.Fatalf("InsertWithUniqueName failed: %v", err)
+	}
+	if *c2.NameRaw != "auth-1" || *c2.NameNorm != "auth-1" {
+		t.Errorf("name = (%q, %q), want auth-1", *c2.NameRaw, *c2.NameNorm)
+	}
+
+	got, err := GetByName(ctx, db, "default", "auth-1", false)
+	if err != nil {
+		t.Fatalf("GetByName failed: %v", err)
+	}
+	if got.ID != c2.ID {
+		t.Errorf("auth-1 ID = %s, want %s", got.ID, c2.ID)
+	}
+
+	// Unnamed capsules are inserted as is
+	c3 := newTestCapsule("01IWU003", "default", "Content")
+	if err := InsertWithUniqueName(ctx, db, c3); err != nil {
+		t.Fatalf("InsertWithUniqueName failed: %v", err)
+	}
+	if c3.NameNorm != nil {
+		t.Errorf("NameNorm = %q, want nil", *c3.NameNorm)
+	}
+}
+
 func TestBulkSoftDelete_RequiresMeaningfulFilter(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbConn, err := Init(tmpDir)
@@ -1480,6 +1831,65 @@ func TestBulkSoftDelete_RequiresMeaningfulFilter(t *testing.T) {
 	}
 }
 
+// TestBulk_SourceTypeOnlyFilter guards against bulk ops treating source_type as
+// a filter in HasFilters but leaving it out of their WHERE clause.
+func TestBulk_SourceTypeOnlyFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbConn, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer dbConn.Close()
+
+	ctx := context.Background()
+	agent := "agent"
+	for _, id := range []string{"01BULKSRC1", "01BULKSRC2", "01BULKSRC3"} {
+		c := newTestCapsule(id, "default", "Test content")
+		c.SourceType = &agent
+		if err := Insert(ctx, dbConn, c); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	deleted := newTestCapsule("01BULKSRC4", "default", "Test content")
+	deleted.SourceType = &agent
+	if err := Insert(ctx, dbConn, deleted); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := SoftDelete(ctx, dbConn, deleted.ID); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+
+	none := InventoryFilters{SourceType: stringPtr("import")}
+
+	if n, err := CountBulkActive(ctx, dbConn, none); err != nil || n != 0 {
+		t.Errorf("CountBulkActive = %d, %v; want 0", n, err)
+	}
+	if n, err := BulkSoftDelete(ctx, dbConn, none); err != nil || n != 0 {
+		t.Errorf("BulkSoftDelete = %d, %v; want 0", n, err)
+	}
+	if n, err := BulkUpdate(ctx, dbConn, none, BulkUpdateFields{Phase: stringPtr("x")}); err != nil || n != 0 {
+		t.Errorf("BulkUpdate = %d, %v; want 0", n, err)
+	}
+	if n, err := BulkAddTags(ctx, dbConn, none, []string{"x"}); err != nil || n != 0 {
+		t.Errorf("BulkAddTags = %d, %v; want 0", n, err)
+	}
+	if n, err := BulkMove(ctx, dbConn, none, "elsewhere", 0); err != nil || n != 0 {
+		t.Errorf("BulkMove = %d, %v; want 0", n, err)
+	}
+	if restored, _, err := BulkRestore(ctx, dbConn, none); err != nil || restored != 0 {
+		t.Errorf("BulkRestore = %d, %v; want 0", restored, err)
+	}
+
+	// Nothing changed; the matching source_type still selects every active capsule
+	if n, err := CountBulkActive(ctx, dbConn, InventoryFilters{SourceType: &agent}); err != nil || n != 3 {
+		t.Errorf("CountBulkActive(agent) = %d, %v; want 3", n, err)
+	}
+	named := true
+	if n, err := CountBulkActive(ctx, dbConn, InventoryFilters{SourceType: &agent, Named: &named}); err != nil || n != 0 {
+		t.Errorf("CountBulkActive(agent, named) = %d, %v; want 0", n, err)
+	}
+}
+
 func TestBulkUpdate_RequiresMeaningfulFilter(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbConn, err := Init(tmpDir)
@@ -1538,7 +1948,7 @@ func TestPurgeDeleted_Basic(t *testing.T) {
 	}
 
 	// Purge all deleted
-	count, err := PurgeDeleted(context.Background(), db, nil, nil)
+	count, err := PurgeDeleted(context.Background(), db, nil, nil, false)
 	if err != nil {
 		t.Fatalf("PurgeDeleted failed: %v", err)
 	}
@@ -1582,7 +1992,7 @@ func TestPurgeDeleted_WorkspaceFilter(t *testing.T) {
 
 	// Purge only ws1
 	ws := "ws1"
-	count, err := PurgeDeleted(context.Background(), db, &ws, nil)
+	count, err := PurgeDeleted(context.Background(), db, &ws, nil, false)
 	if err != nil {
 		t.Fatalf("PurgeDeleted failed: %v", err)
 	}
@@ -1629,7 +2039,7 @@ func TestPurgeDeleted_OlderThanDays(t *testing.T) {
 
 	// Purge capsules deleted more than 7 days ago
 	days := 7
-	count, err := PurgeDeleted(context.Background(), db, nil, &days)
+	count, err := PurgeDeleted(context.Background(), db, nil, &days, false)
 	if err != nil {
 		t.Fatalf("PurgeDeleted failed: %v", err)
 	}
@@ -1664,7 +2074,7 @@ func TestPurgeDeleted_NoDeleted(t *testing.T) {
 		t.Fatalf("Insert failed: %v", err)
 	}
 
-	count, err := PurgeDeleted(context.Background(), db, nil, nil)
+	count, err := PurgeDeleted(context.Background(), db, nil, nil, false)
 	if err != nil {
 		t.Fatalf("PurgeDeleted failed: %v", err)
 	}
@@ -1688,7 +2098,7 @@ func TestSearchFullText_QueryBounds(t *testing.T) {
 	}
 
 	t.Run("empty query", func(t *testing.T) {
-		_, _, err := SearchFullText(context.Background(), dbConn, "   \t\n  ", SearchFilters{}, 10, 0, false)
+		_, _, err := SearchFullText(context.Background(), dbConn, "   \t\n  ", SearchFilters{}, 10, 0, false, 64, SearchOrderRelevance, nil)
 		if !errors.Is(err, errors.ErrInvalidRequest) {
 			t.Fatalf("expected ErrInvalidRequest, got %v", err)
 		}
@@ -1696,7 +2106,7 @@ func TestSearchFullText_QueryBounds(t *testing.T) {
 
 	t.Run("query too long", func(t *testing.T) {
 		longQuery := strings.Repeat("a", MaxSearchQueryChars+1)
-		_, _, err := SearchFullText(context.Background(), dbConn, longQuery, SearchFilters{}, 10, 0, false)
+		_, _, err := SearchFullText(context.Background(), dbConn, longQuery, SearchFilters{}, 10, 0, false, 64, SearchOrderRelevance, nil)
 		if !errors.Is(err, errors.ErrInvalidRequest) {
 			t.Fatalf("expected ErrInvalidRequest, got %v", err)
 		}
@@ -1782,3 +2192,107 @@ func TestIsFTSSyntaxError(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyFTSConsistency_Orphaned(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbConn, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer dbConn.Close()
+
+	ctx := context.Background()
+	c := newTestCapsule("01FTSCHK01", "default", "Indexed content")
+	if err := Insert(ctx, dbConn, c); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	report, err := VerifyFTSConsistency(ctx, dbConn)
+	if err != nil {
+		t.Fatalf("VerifyFTSConsistency failed: %v", err)
+	}
+	if !report.Consistent() || report.CapsuleRows != 1 || report.IndexedRows != 1 {
+		t.Fatalf("fresh report = %+v, want 1/1 consistent", report)
+	}
+
+	// Index a document with no backing capsule row
+	if _, err := dbConn.Exec(`INSERT INTO capsules_fts(rowid, capsule_text, title, name_raw) VALUES (99999, 'ghost', NULL, NULL)`); err != nil {
+		t.Fatalf("failed to insert orphan: %v", err)
+	}
+
+	report, err = VerifyFTSConsistency(ctx, dbConn)
+	if err != nil {
+		t.Fatalf("VerifyFTSConsistency failed: %v", err)
+	}
+	if report.Consistent() {
+		t.Fatal("expected inconsistent report")
+	}
+	if report.Orphaned != 1 || report.Missing != 0 || len(report.OrphanedSample) != 1 || report.OrphanedSample[0] != 99999 {
+		t.Errorf("report = %+v, want one orphan at rowid 99999", report)
+	}
+
+	if err := RebuildFTS(ctx, dbConn); err != nil {
+		t.Fatalf("RebuildFTS failed: %v", err)
+	}
+	report, err = VerifyFTSConsistency(ctx, dbConn)
+	if err != nil {
+		t.Fatalf("VerifyFTSConsistency failed: %v", err)
+	}
+	if !report.Consistent() {
+		t.Errorf("after rebuild report = %+v, want consistent", report)
+	}
+}
+
+func TestComposeSources_PurgeCleanup(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Init(tmpDir)
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	for _, c := range []*capsule.Capsule{
+		newTestCapsule("01SOURCE", "default", "Source content"),
+		newTestCapsule("01BUNDL1", "default", "Bundle one"),
+		newTestCapsule("01BUNDL2", "default", "Bundle two"),
+	} {
+		if err := Insert(ctx, db, c); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	// Listing a source twice records it once
+	if err := SetComposeSources(ctx, db, "01BUNDL1", []string{"01SOURCE", "01SOURCE"}); err != nil {
+		t.Fatalf("SetComposeSources failed: %v", err)
+	}
+	if err := SetComposeSources(ctx, db, "01BUNDL2", []string{"01SOURCE"}); err != nil {
+		t.Fatalf("SetComposeSources failed: %v", err)
+	}
+
+	bundles, err := ListBundlesContaining(ctx, db, "01SOURCE", false)
+	if err != nil {
+		t.Fatalf("ListBundlesContaining failed: %v", err)
+	}
+	if len(bundles) != 2 {
+		t.Fatalf("bundles = %d, want 2", len(bundles))
+	}
+
+	// Permanently deleting a bundle drops its provenance rows
+	if _, err := db.Exec("DELETE FROM capsules WHERE id = ?", "01BUNDL1"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	var rows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM compose_sources WHERE bundle_id = ?", "01BUNDL1").Scan(&rows); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if rows != 0 {
+		t.Errorf("compose_sources rows for purged bundle = %d, want 0", rows)
+	}
+	bundles, err = ListBundlesContaining(ctx, db, "01SOURCE", true)
+	if err != nil {
+		t.Fatalf("ListBundlesContaining failed: %v", err)
+	}
+	if len(bundles) != 1 || bundles[0].ID != "01BUNDL2" {
+		t.Errorf("bundles after purge = %+v, want [01BUNDL2]", bundles)
+	}
+}
@@ -13,10 +13,12 @@ const (
 	ErrInvalidRequest      ErrorCode = "INVALID_REQUEST"      // 400
 	ErrNotFound            ErrorCode = "NOT_FOUND"            // 404
 	ErrNameAlreadyExists   ErrorCode = "NAME_ALREADY_EXISTS"  // 409
+	ErrQuotaExceeded       ErrorCode = "QUOTA_EXCEEDED"       // 403
 	ErrConflict            ErrorCode = "CONFLICT"             // 409 (for future optimistic concurrency)
 	ErrCapsuleTooLarge     ErrorCode = "CAPSULE_TOO_LARGE"    // 413
 	ErrFileTooLarge        ErrorCode = "FILE_TOO_LARGE"       // 413
 	ErrComposeTooLarge     ErrorCode = "COMPOSE_TOO_LARGE"    // 413
+	ErrResponseTooLarge    ErrorCode = "RESPONSE_TOO_LARGE"   // 413
 	ErrCapsuleTooThin      ErrorCode = "CAPSULE_TOO_THIN"     // 422
 	ErrCancelled           ErrorCode = "CANCELLED"            // 499
 	ErrInternal            ErrorCode = "INTERNAL"             // 500
@@ -44,6 +46,16 @@ func NewAmbiguousAddressing() *MossError {
 	}
 }
 
+// NewAmbiguousIDPrefix creates a 400 error for when an ID prefix matches more than one capsule.
+func NewAmbiguousIDPrefix(prefix string) *MossError {
+	return &MossError{
+		Code:    ErrAmbiguousAddressing,
+		Status:  400,
+		Message: fmt.Sprintf("id prefix %q matches multiple capsules; use a longer prefix", prefix),
+		Details: map[string]any{"prefix": prefix},
+	}
+}
+
 // NewInvalidRequest creates a 400 error for invalid request parameters.
 func NewInvalidRequest(msg string) *MossError {
 	return &MossError{
@@ -73,13 +85,38 @@ func NewFileNotFound(path string) *MossError {
 	}
 }
 
-// NewNameAlreadyExists creates a 409 error for name collisions.
-func NewNameAlreadyExists(workspace, name string) *MossError {
+// NewLinkNotFound creates a 404 error for when a capsule link cannot be found.
+func NewLinkNotFound(fromID, toID, relation string) *MossError {
+	return &MossError{
+		Code:    ErrNotFound,
+		Status:  404,
+		Message: fmt.Sprintf("link not found: %s -[%s]-> %s", fromID, relation, toID),
+		Details: map[string]any{"from_id": fromID, "to_id": toID, "relation": relation},
+	}
+}
+
+// NewNameAlreadyExists creates a 409 error for name collisions. existingID is
+// the ID of the capsule holding the name; it is left out of the details if empty.
+func NewNameAlreadyExists(workspace, name, existingID string) *MossError {
+	details := map[string]any{"workspace": workspace, "name": name}
+	if existingID != "" {
+		details["id"] = existingID
+	}
 	return &MossError{
 		Code:    ErrNameAlreadyExists,
 		Status:  409,
 		Message: fmt.Sprintf("capsule with name %q already exists in workspace %q", name, workspace),
-		Details: map[string]any{"workspace": workspace, "name": name},
+		Details: details,
+	}
+}
+
+// NewQuotaExceeded creates a 403 error when a workspace is at its capsule limit.
+func NewQuotaExceeded(workspace string, max int) *MossError {
+	return &MossError{
+		Code:    ErrQuotaExceeded,
+		Status:  403,
+		Message: fmt.Sprintf("workspace %q is at its capsule limit (%d); delete or purge capsules to free space", workspace, max),
+		Details: map[string]any{"workspace": workspace, "max_capsules": max},
 	}
 }
 
@@ -102,6 +139,16 @@ func NewCapsuleTooLarge(max, actual int) *MossError {
 	}
 }
 
+// NewCapsuleTooLargeBytes creates a 413 error when capsule text exceeds the byte limit.
+func NewCapsuleTooLargeBytes(max, actual int) *MossError {
+	return &MossError{
+		Code:    ErrCapsuleTooLarge,
+		Status:  413,
+		Message: fmt.Sprintf("capsule exceeds maximum size: %d bytes (max %d)", actual, max),
+		Details: map[string]any{"max_bytes": max, "actual_bytes": actual},
+	}
+}
+
 // NewFileTooLarge creates a 413 error when a file exceeds size limit.
 func NewFileTooLarge(maxBytes, actualBytes int64) *MossError {
 	return &MossError{
@@ -122,6 +169,27 @@ func NewComposeTooLarge(max, actual int) *MossError {
 	}
 }
 
+// NewComposeTooLargeBytes creates a 413 error when a composed bundle exceeds the byte limit.
+func NewComposeTooLargeBytes(max, actual int) *MossError {
+	return &MossError{
+		Code:    ErrComposeTooLarge,
+		Status:  413,
+		Message: fmt.Sprintf("composed bundle exceeds maximum size: %d bytes (max %d)", actual, max),
+		Details: map[string]any{"max_bytes": max, "actual_bytes": actual},
+	}
+}
+
+// NewResponseTooLarge creates a 413 error when a tool result exceeds
+// config max_response_bytes.
+func NewResponseTooLarge(max, actual int) *MossError {
+	return &MossError{
+		Code:    ErrResponseTooLarge,
+		Status:  413,
+		Message: fmt.Sprintf("response exceeds maximum size: %d bytes (max %d)", actual, max),
+		Details: map[string]any{"max_bytes": max, "actual_bytes": actual},
+	}
+}
+
 // NewCapsuleTooThin creates a 422 error when capsule is missing required sections.
 func NewCapsuleTooThin(missing []string) *MossError {
 	return &MossError{
@@ -132,6 +200,17 @@ func NewCapsuleTooThin(missing []string) *MossError {
 	}
 }
 
+// NewTooManySections creates a 400 error when capsule text has more "##"
+// sections than config max_sections allows.
+func NewTooManySections(max, actual int) *MossError {
+	return &MossError{
+		Code:    ErrInvalidRequest,
+		Status:  400,
+		Message: fmt.Sprintf("capsule has %d sections, exceeds max_sections %d", actual, max),
+		Details: map[string]any{"max_sections": max, "actual_sections": actual},
+	}
+}
+
 // NewCancelled creates a 499 error for context cancellation.
 func NewCancelled(operation string) *MossError {
 	return &MossError{
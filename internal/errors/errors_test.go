@@ -29,6 +29,20 @@ func TestNewAmbiguousAddressing(t *testing.T) {
 	}
 }
 
+func TestNewAmbiguousIDPrefix(t *testing.T) {
+	err := NewAmbiguousIDPrefix("01HX")
+
+	if err.Code != ErrAmbiguousAddressing {
+		t.Errorf("Code = %q, want %q", err.Code, ErrAmbiguousAddressing)
+	}
+	if err.Status != 400 {
+		t.Errorf("Status = %d, want 400", err.Status)
+	}
+	if err.Details["prefix"] != "01HX" {
+		t.Errorf("Details[prefix] = %v, want 01HX", err.Details["prefix"])
+	}
+}
+
 func TestNewInvalidRequest(t *testing.T) {
 	err := NewInvalidRequest("capsule_text is required")
 
@@ -57,8 +71,22 @@ func TestNewNotFound(t *testing.T) {
 	}
 }
 
+func TestNewLinkNotFound(t *testing.T) {
+	err := NewLinkNotFound("01A", "01B", "reviews")
+
+	if err.Code != ErrNotFound {
+		t.Errorf("Code = %q, want %q", err.Code, ErrNotFound)
+	}
+	if err.Status != 404 {
+		t.Errorf("Status = %d, want 404", err.Status)
+	}
+	if err.Details["relation"] != "reviews" {
+		t.Errorf("Details[relation] = %v, want %q", err.Details["relation"], "reviews")
+	}
+}
+
 func TestNewNameAlreadyExists(t *testing.T) {
-	err := NewNameAlreadyExists("default", "auth")
+	err := NewNameAlreadyExists("default", "auth", "01EXISTING")
 
 	if err.Code != ErrNameAlreadyExists {
 		t.Errorf("Code = %q, want %q", err.Code, ErrNameAlreadyExists)
@@ -72,6 +100,28 @@ func TestNewNameAlreadyExists(t *testing.T) {
 	if err.Details["name"] != "auth" {
 		t.Errorf("Details[name] = %v, want %q", err.Details["name"], "auth")
 	}
+	if err.Details["id"] != "01EXISTING" {
+		t.Errorf("Details[id] = %v, want %q", err.Details["id"], "01EXISTING")
+	}
+
+	// Unknown holder: no id key rather than an empty one
+	if _, ok := NewNameAlreadyExists("default", "auth", "").Details["id"]; ok {
+		t.Error("Details[id] should be omitted when the existing ID is unknown")
+	}
+}
+
+func TestNewQuotaExceeded(t *testing.T) {
+	err := NewQuotaExceeded("shared", 100)
+
+	if err.Code != ErrQuotaExceeded {
+		t.Errorf("Code = %q, want %q", err.Code, ErrQuotaExceeded)
+	}
+	if err.Status != 403 {
+		t.Errorf("Status = %d, want 403", err.Status)
+	}
+	if err.Details["max_capsules"] != 100 {
+		t.Errorf("Details[max_capsules] = %v, want 100", err.Details["max_capsules"])
+	}
 }
 
 func TestNewConflict(t *testing.T) {
@@ -137,6 +187,23 @@ func TestNewComposeTooLarge(t *testing.T) {
 	}
 }
 
+func TestNewResponseTooLarge(t *testing.T) {
+	err := NewResponseTooLarge(1000, 2500)
+
+	if err.Code != ErrResponseTooLarge {
+		t.Errorf("Code = %q, want %q", err.Code, ErrResponseTooLarge)
+	}
+	if err.Status != 413 {
+		t.Errorf("Status = %d, want 413", err.Status)
+	}
+	if err.Details["max_bytes"] != 1000 {
+		t.Errorf("Details[max_bytes] = %v, want 1000", err.Details["max_bytes"])
+	}
+	if err.Details["actual_bytes"] != 2500 {
+		t.Errorf("Details[actual_bytes] = %v, want 2500", err.Details["actual_bytes"])
+	}
+}
+
 func TestNewCapsuleTooThin(t *testing.T) {
 	missing := []string{"Objective", "Next actions"}
 	err := NewCapsuleTooThin(missing)
@@ -152,6 +219,20 @@ func TestNewCapsuleTooThin(t *testing.T) {
 	}
 }
 
+func TestNewTooManySections(t *testing.T) {
+	err := NewTooManySections(10, 14)
+
+	if err.Code != ErrInvalidRequest {
+		t.Errorf("Code = %q, want %q", err.Code, ErrInvalidRequest)
+	}
+	if err.Status != 400 {
+		t.Errorf("Status = %d, want 400", err.Status)
+	}
+	if err.Details["max_sections"] != 10 || err.Details["actual_sections"] != 14 {
+		t.Errorf("Details = %v, want max_sections 10 and actual_sections 14", err.Details)
+	}
+}
+
 func TestNewInternal(t *testing.T) {
 	t.Run("with error", func(t *testing.T) {
 		originalErr := fmt.Errorf("database connection failed")
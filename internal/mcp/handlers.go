@@ -5,8 +5,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	stderrors "errors"
+	"sync"
+	"sync/atomic"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 
 	"github.com/hpungsan/moss/internal/config"
 	"github.com/hpungsan/moss/internal/errors"
@@ -16,12 +19,33 @@ import (
 // Handlers holds dependencies for MCP tool handlers.
 type Handlers struct {
 	db  *sql.DB
-	cfg *config.Config
+	cfg atomic.Pointer[config.Config] // swapped whole by capsule_set_config
+
+	configDir    string     // where capsule_set_config persists changes
+	configMu     sync.Mutex // serializes capsule_set_config read-modify-write
+	enabledTools []string   // registered tool names, set by NewServer
 }
 
 // NewHandlers creates a new Handlers instance.
 func NewHandlers(db *sql.DB, cfg *config.Config) *Handlers {
-	return &Handlers{db: db, cfg: cfg}
+	h := &Handlers{db: db, enabledTools: []string{}}
+	h.cfg.Store(cfg)
+	return h
+}
+
+// config returns the current configuration. Handlers call it once per request
+// so a concurrent capsule_set_config can't change settings mid-operation.
+func (h *Handlers) config() *config.Config {
+	return h.cfg.Load()
+}
+
+// includeDeleted resolves a read's include_deleted: the request's value if
+// given, else config.DefaultIncludeDeleted.
+func (h *Handlers) includeDeleted(v *bool) bool {
+	if v != nil {
+		return *v
+	}
+	return h.config().DefaultIncludeDeleted
 }
 
 // Request types for each tool
@@ -37,17 +61,45 @@ type StoreRequest struct {
 	RunID       *string  `json:"run_id,omitempty"`
 	Phase       *string  `json:"phase,omitempty"`
 	Role        *string  `json:"role,omitempty"`
+	SourceType  *string  `json:"source_type,omitempty"`
+	SourceRef   *string  `json:"source_ref,omitempty"`
+	Note        *string  `json:"note,omitempty"`
+	ExpiresAt   *int64   `json:"expires_at,omitempty"`
 	Mode        string   `json:"mode,omitempty"`
 	AllowThin   bool     `json:"allow_thin,omitempty"`
+
+	DedupeWindowSeconds int `json:"dedupe_window_seconds,omitempty"`
 }
 
 // FetchRequest represents the arguments for fetch.
 type FetchRequest struct {
+	ID                 string   `json:"id,omitempty"`
+	Workspace          string   `json:"workspace,omitempty"`
+	Name               string   `json:"name,omitempty"`
+	IncludeDeleted     *bool    `json:"include_deleted,omitempty"`
+	IncludeText        *bool    `json:"include_text,omitempty"`
+	WithLinks          bool     `json:"with_links,omitempty"`
+	WithBundles        bool     `json:"with_bundles,omitempty"`
+	Prefix             bool     `json:"prefix,omitempty"`
+	KnownSHA           *string  `json:"known_sha,omitempty"`
+	Fields             []string `json:"fields,omitempty"`
+	FallbackWorkspaces []string `json:"fallback_workspaces,omitempty"`
+}
+
+// ExistsRequest represents the arguments for exists.
+type ExistsRequest struct {
+	ID             string `json:"id,omitempty"`
+	Workspace      string `json:"workspace,omitempty"`
+	Name           string `json:"name,omitempty"`
+	IncludeDeleted *bool  `json:"include_deleted,omitempty"`
+}
+
+// FetchRecordRequest represents the arguments for fetch_record.
+type FetchRecordRequest struct {
 	ID             string `json:"id,omitempty"`
 	Workspace      string `json:"workspace,omitempty"`
 	Name           string `json:"name,omitempty"`
 	IncludeDeleted bool   `json:"include_deleted,omitempty"`
-	IncludeText    *bool  `json:"include_text,omitempty"`
 }
 
 // FetchManyRequest represents the arguments for fetch_many.
@@ -76,6 +128,10 @@ type UpdateRequest struct {
 	RunID       *string   `json:"run_id,omitempty"`
 	Phase       *string   `json:"phase,omitempty"`
 	Role        *string   `json:"role,omitempty"`
+	SourceType  *string   `json:"source_type,omitempty"`
+	SourceRef   *string   `json:"source_ref,omitempty"`
+	Note        *string   `json:"note,omitempty"`
+	ExpiresAt   *int64    `json:"expires_at,omitempty"`
 	AllowThin   bool      `json:"allow_thin,omitempty"`
 }
 
@@ -86,14 +142,16 @@ type DeleteRequest struct {
 	Name      string `json:"name,omitempty"`
 }
 
-// LatestRequest represents the arguments for latest.
+// LatestRequest represents the arguments for latest (and first).
 type LatestRequest struct {
 	Workspace      string  `json:"workspace,omitempty"`
 	RunID          *string `json:"run_id,omitempty"`
 	Phase          *string `json:"phase,omitempty"`
 	Role           *string `json:"role,omitempty"`
 	IncludeText    *bool   `json:"include_text,omitempty"`
-	IncludeDeleted bool    `json:"include_deleted,omitempty"`
+	IncludeDeleted *bool   `json:"include_deleted,omitempty"`
+	By             string  `json:"by,omitempty"`
+	PreferNamed    bool    `json:"prefer_named,omitempty"`
 }
 
 // ListRequest represents the arguments for list.
@@ -102,9 +160,11 @@ type ListRequest struct {
 	RunID          *string `json:"run_id,omitempty"`
 	Phase          *string `json:"phase,omitempty"`
 	Role           *string `json:"role,omitempty"`
+	Named          *bool   `json:"named,omitempty"`
 	Limit          int     `json:"limit,omitempty"`
 	Offset         int     `json:"offset,omitempty"`
-	IncludeDeleted bool    `json:"include_deleted,omitempty"`
+	IncludeDeleted *bool   `json:"include_deleted,omitempty"`
+	IncludeText    bool    `json:"include_text,omitempty"`
 }
 
 // InventoryRequest represents the arguments for inventory.
@@ -115,16 +175,25 @@ type InventoryRequest struct {
 	RunID          *string `json:"run_id,omitempty"`
 	Phase          *string `json:"phase,omitempty"`
 	Role           *string `json:"role,omitempty"`
+	SourceType     *string `json:"source_type,omitempty"`
+	Named          *bool   `json:"named,omitempty"`
 	Limit          int     `json:"limit,omitempty"`
 	Offset         int     `json:"offset,omitempty"`
-	IncludeDeleted bool    `json:"include_deleted,omitempty"`
+	IncludeDeleted *bool   `json:"include_deleted,omitempty"`
+	IncludeText    bool    `json:"include_text,omitempty"`
 }
 
 // ExportRequest represents the arguments for export.
 type ExportRequest struct {
-	Path           string  `json:"path,omitempty"`
-	Workspace      *string `json:"workspace,omitempty"`
-	IncludeDeleted bool    `json:"include_deleted,omitempty"`
+	Path           string   `json:"path,omitempty"`
+	Workspace      *string  `json:"workspace,omitempty"`
+	IDs            []string `json:"ids,omitempty"`
+	IncludeDeleted bool     `json:"include_deleted,omitempty"`
+	Format         string   `json:"format,omitempty"`
+	OrderBy        string   `json:"order_by,omitempty"`
+	Compact        bool     `json:"compact,omitempty"`
+	ResumeFrom     *string  `json:"resume_from,omitempty"`
+	Append         bool     `json:"append,omitempty"`
 }
 
 // ImportRequest represents the arguments for import.
@@ -133,12 +202,59 @@ type ImportRequest struct {
 	Mode string `json:"mode,omitempty"`
 }
 
+// ExportStringRequest represents the arguments for export_string.
+type ExportStringRequest struct {
+	Workspace      *string `json:"workspace,omitempty"`
+	IncludeDeleted bool    `json:"include_deleted,omitempty"`
+}
+
+// ImportStringRequest represents the arguments for import_string.
+type ImportStringRequest struct {
+	Data string `json:"data"`
+	Mode string `json:"mode,omitempty"`
+}
+
 // PurgeRequest represents the arguments for purge.
 type PurgeRequest struct {
 	Workspace     *string `json:"workspace,omitempty"`
 	OlderThanDays *int    `json:"older_than_days,omitempty"`
 }
 
+// RecentlyDeletedRequest represents the arguments for recently_deleted.
+type RecentlyDeletedRequest struct {
+	Workspace *string `json:"workspace,omitempty"`
+	Limit     int     `json:"limit,omitempty"`
+}
+
+// ListArchivedRequest represents the arguments for list_archived.
+type ListArchivedRequest struct {
+	Workspace *string `json:"workspace,omitempty"`
+	Limit     int     `json:"limit,omitempty"`
+	Offset    int     `json:"offset,omitempty"`
+}
+
+// RestoreArchivedRequest represents the arguments for restore_archived.
+type RestoreArchivedRequest struct {
+	ID string `json:"id"`
+}
+
+// PruneDuplicatesRequest represents the arguments for prune_duplicates.
+type PruneDuplicatesRequest struct {
+	Workspace *string `json:"workspace,omitempty"`
+	DryRun    bool    `json:"dry_run,omitempty"`
+}
+
+// ReindexRequest represents the arguments for reindex.
+type ReindexRequest struct {
+	CheckOnly bool `json:"check_only,omitempty"`
+	Force     bool `json:"force,omitempty"`
+}
+
+// DeleteManyRequest represents the arguments for delete_many.
+type DeleteManyRequest struct {
+	IDs []string `json:"ids"`
+}
+
 // BulkDeleteRequest represents the arguments for bulk_delete.
 type BulkDeleteRequest struct {
 	Workspace  *string `json:"workspace,omitempty"`
@@ -147,6 +263,35 @@ type BulkDeleteRequest struct {
 	RunID      *string `json:"run_id,omitempty"`
 	Phase      *string `json:"phase,omitempty"`
 	Role       *string `json:"role,omitempty"`
+	DryRun     bool    `json:"dry_run,omitempty"`
+}
+
+// BulkRestoreRequest represents the arguments for bulk_restore.
+type BulkRestoreRequest struct {
+	Workspace  *string `json:"workspace,omitempty"`
+	Tag        *string `json:"tag,omitempty"`
+	NamePrefix *string `json:"name_prefix,omitempty"`
+	RunID      *string `json:"run_id,omitempty"`
+	Phase      *string `json:"phase,omitempty"`
+	Role       *string `json:"role,omitempty"`
+}
+
+// BulkMoveRequest represents the arguments for bulk_move.
+type BulkMoveRequest struct {
+	ToWorkspace string  `json:"to_workspace"`
+	Workspace   *string `json:"workspace,omitempty"`
+	Tag         *string `json:"tag,omitempty"`
+	NamePrefix  *string `json:"name_prefix,omitempty"`
+	RunID       *string `json:"run_id,omitempty"`
+	Phase       *string `json:"phase,omitempty"`
+	Role        *string `json:"role,omitempty"`
+}
+
+// RenameTagRequest represents the arguments for rename_tag.
+type RenameTagRequest struct {
+	OldTag    string  `json:"old_tag"`
+	NewTag    string  `json:"new_tag"`
+	Workspace *string `json:"workspace,omitempty"`
 }
 
 // BulkUpdateRequest represents the arguments for bulk_update.
@@ -159,22 +304,60 @@ type BulkUpdateRequest struct {
 	Phase      *string `json:"phase,omitempty"`
 	Role       *string `json:"role,omitempty"`
 	// Updates
-	SetPhase *string   `json:"set_phase,omitempty"`
-	SetRole  *string   `json:"set_role,omitempty"`
-	SetTags  *[]string `json:"set_tags,omitempty"`
+	SetPhase  *string   `json:"set_phase,omitempty"`
+	SetRole   *string   `json:"set_role,omitempty"`
+	SetTags   *[]string `json:"set_tags,omitempty"`
+	SetSource *string   `json:"set_source,omitempty"`
+	SetTitle  *string   `json:"set_title,omitempty"`
+	// Options
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// BulkTagsRequest represents the arguments for bulk_add_tags and bulk_remove_tags.
+type BulkTagsRequest struct {
+	// Filters
+	Workspace  *string `json:"workspace,omitempty"`
+	Tag        *string `json:"tag,omitempty"`
+	NamePrefix *string `json:"name_prefix,omitempty"`
+	RunID      *string `json:"run_id,omitempty"`
+	Phase      *string `json:"phase,omitempty"`
+	Role       *string `json:"role,omitempty"`
+	// Tags to add or remove
+	Tags []string `json:"tags"`
 }
 
 // SearchRequest represents the arguments for search.
 type SearchRequest struct {
-	Query          string  `json:"query"`
-	Workspace      *string `json:"workspace,omitempty"`
-	Tag            *string `json:"tag,omitempty"`
-	RunID          *string `json:"run_id,omitempty"`
-	Phase          *string `json:"phase,omitempty"`
-	Role           *string `json:"role,omitempty"`
-	Limit          int     `json:"limit,omitempty"`
-	Offset         int     `json:"offset,omitempty"`
-	IncludeDeleted bool    `json:"include_deleted,omitempty"`
+	Query            string   `json:"query"`
+	Workspace        *string  `json:"workspace,omitempty"`
+	Workspaces       []string `json:"workspaces,omitempty"`
+	Tag              *string  `json:"tag,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+	TagMatch         string   `json:"tag_match,omitempty"`
+	NamePrefix       *string  `json:"name_prefix,omitempty"`
+	UpdatedAfter     *int64   `json:"updated_after,omitempty"`
+	UpdatedBefore    *int64   `json:"updated_before,omitempty"`
+	RunID            *string  `json:"run_id,omitempty"`
+	Phase            *string  `json:"phase,omitempty"`
+	Role             *string  `json:"role,omitempty"`
+	SourceType       *string  `json:"source_type,omitempty"`
+	Limit            int      `json:"limit,omitempty"`
+	Offset           int      `json:"offset,omitempty"`
+	Sort             string   `json:"sort,omitempty"`
+	Cursor           *string  `json:"cursor,omitempty"`
+	IncludeDeleted   *bool    `json:"include_deleted,omitempty"`
+	WithSnippet      *bool    `json:"with_snippet,omitempty"`
+	SnippetTokens    int      `json:"snippet_tokens,omitempty"`
+	SnippetFormats   []string `json:"snippet_formats,omitempty"`
+	Facets           bool     `json:"facets,omitempty"`
+	GroupByWorkspace bool     `json:"group_by_workspace,omitempty"`
+}
+
+// ChangesRequest represents the arguments for changes.
+type ChangesRequest struct {
+	Since  int64   `json:"since"`
+	Limit  int     `json:"limit,omitempty"`
+	Cursor *string `json:"cursor,omitempty"`
 }
 
 // AppendRequest represents the arguments for append.
@@ -186,12 +369,33 @@ type AppendRequest struct {
 	Content   string `json:"content"`
 }
 
+// PatchRequest represents the arguments for patch.
+type PatchRequest struct {
+	ID        string         `json:"id,omitempty"`
+	Workspace string         `json:"workspace,omitempty"`
+	Name      string         `json:"name,omitempty"`
+	Ops       []PatchOpInput `json:"ops"`
+	AllowThin bool           `json:"allow_thin,omitempty"`
+}
+
+// PatchOpInput is one edit in a patch request.
+type PatchOpInput struct {
+	Op      string `json:"op"`
+	Section string `json:"section,omitempty"`
+	Value   string `json:"value,omitempty"`
+}
+
 // ComposeRequest represents the arguments for compose.
 type ComposeRequest struct {
-	Items    []ComposeRef    `json:"items"`
-	Format   string          `json:"format,omitempty"`
-	Sections []string        `json:"sections,omitempty"`
-	StoreAs  *ComposeStoreAs `json:"store_as,omitempty"`
+	Items              []ComposeRef    `json:"items"`
+	Format             string          `json:"format,omitempty"`
+	Sections           []string        `json:"sections,omitempty"`
+	StoreAs            *ComposeStoreAs `json:"store_as,omitempty"`
+	HeaderTemplate     *string         `json:"header_template,omitempty"`
+	DryRun             bool            `json:"dry_run,omitempty"`
+	OrderBy            string          `json:"order_by,omitempty"`
+	IntraPartSeparator *string         `json:"intra_part_separator,omitempty"`
+	TrailingNewline    *bool           `json:"trailing_newline,omitempty"`
 }
 
 // ComposeRef identifies a capsule in compose.
@@ -203,9 +407,99 @@ type ComposeRef struct {
 
 // ComposeStoreAs specifies how to persist the composed bundle.
 type ComposeStoreAs struct {
+	Workspace   string  `json:"workspace,omitempty"`
+	Name        string  `json:"name"`
+	Mode        string  `json:"mode,omitempty"`
+	TitlePrefix *string `json:"title_prefix,omitempty"`
+	TitleSuffix *string `json:"title_suffix,omitempty"`
+}
+
+// LinkRequest represents the arguments for link and unlink.
+type LinkRequest struct {
+	From     LinkRef `json:"from"`
+	To       LinkRef `json:"to"`
+	Relation string  `json:"relation"`
+}
+
+// LinkRef identifies a link endpoint.
+type LinkRef struct {
+	ID        string `json:"id,omitempty"`
+	Workspace string `json:"workspace,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// LinksRequest represents the arguments for links.
+type LinksRequest struct {
+	ID             string `json:"id,omitempty"`
+	Workspace      string `json:"workspace,omitempty"`
+	Name           string `json:"name,omitempty"`
+	IncludeDeleted bool   `json:"include_deleted,omitempty"`
+}
+
+// BundlesRequest represents the arguments for bundles.
+type BundlesRequest struct {
+	ID             string `json:"id,omitempty"`
+	Workspace      string `json:"workspace,omitempty"`
+	Name           string `json:"name,omitempty"`
+	IncludeDeleted bool   `json:"include_deleted,omitempty"`
+}
+
+// RelatedRequest represents the arguments for related.
+type RelatedRequest struct {
+	ID            string `json:"id,omitempty"`
+	Workspace     string `json:"workspace,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Limit         int    `json:"limit,omitempty"`
+	SameWorkspace bool   `json:"same_workspace,omitempty"`
+}
+
+// TopTermsRequest represents the arguments for top_terms.
+type TopTermsRequest struct {
 	Workspace string `json:"workspace,omitempty"`
-	Name      string `json:"name"`
-	Mode      string `json:"mode,omitempty"`
+	Limit     int    `json:"limit,omitempty"`
+}
+
+// BatchRequest represents the arguments for batch.
+type BatchRequest struct {
+	Ops []BatchOpRequest `json:"ops"`
+}
+
+// BatchOpRequest is one batch operation. Op selects which fields apply:
+// store/update take the same fields as their tools, delete takes only the
+// address, and move takes the address plus to_workspace and/or to_name.
+type BatchOpRequest struct {
+	Op          string    `json:"op"`
+	ID          string    `json:"id,omitempty"`
+	Workspace   string    `json:"workspace,omitempty"`
+	Name        *string   `json:"name,omitempty"`
+	Title       *string   `json:"title,omitempty"`
+	CapsuleText *string   `json:"capsule_text,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"`
+	Source      *string   `json:"source,omitempty"`
+	RunID       *string   `json:"run_id,omitempty"`
+	Phase       *string   `json:"phase,omitempty"`
+	Role        *string   `json:"role,omitempty"`
+	SourceType  *string   `json:"source_type,omitempty"`
+	SourceRef   *string   `json:"source_ref,omitempty"`
+	Note        *string   `json:"note,omitempty"`
+	ExpiresAt   *int64    `json:"expires_at,omitempty"`
+	Mode        string    `json:"mode,omitempty"`
+	AllowThin   bool      `json:"allow_thin,omitempty"`
+	ToWorkspace *string   `json:"to_workspace,omitempty"`
+	ToName      *string   `json:"to_name,omitempty"`
+}
+
+// SetConfigRequest represents the arguments for set_config.
+type SetConfigRequest struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// GetConfigResponse is the result of get_config.
+type GetConfigResponse struct {
+	Config       *config.Config `json:"config"`        // effective config (defaults, global, repo merged)
+	RuntimeKeys  []string       `json:"runtime_keys"`  // keys capsule_set_config accepts
+	EnabledTools []string       `json:"enabled_tools"` // registered tools, sorted
 }
 
 // Handler implementations
@@ -219,11 +513,14 @@ func (h *Handlers) HandleStore(ctx context.Context, req mcp.CallToolRequest) (*m
 
 	// Map to ops input
 	mode := ops.StoreModeError
-	if input.Mode == "replace" {
+	switch input.Mode {
+	case "replace":
 		mode = ops.StoreModeReplace
+	case "rename":
+		mode = ops.StoreModeRename
 	}
 
-	result, err := ops.Store(ctx, h.db, h.cfg, ops.StoreInput{
+	result, err := ops.Store(ctx, h.db, h.config(), ops.StoreInput{
 		Workspace:   input.Workspace,
 		Name:        input.Name,
 		Title:       input.Title,
@@ -233,8 +530,14 @@ func (h *Handlers) HandleStore(ctx context.Context, req mcp.CallToolRequest) (*m
 		RunID:       input.RunID,
 		Phase:       input.Phase,
 		Role:        input.Role,
+		SourceType:  input.SourceType,
+		SourceRef:   input.SourceRef,
+		Note:        input.Note,
+		ExpiresAt:   input.ExpiresAt,
 		Mode:        mode,
 		AllowThin:   input.AllowThin,
+
+		DedupeWindowSeconds: input.DedupeWindowSeconds,
 	})
 	if err != nil {
 		return errorResult(err), nil
@@ -251,11 +554,57 @@ func (h *Handlers) HandleFetch(ctx context.Context, req mcp.CallToolRequest) (*m
 	}
 
 	result, err := ops.Fetch(ctx, h.db, ops.FetchInput{
+		ID:                 input.ID,
+		Workspace:          input.Workspace,
+		Name:               input.Name,
+		IncludeDeleted:     h.includeDeleted(input.IncludeDeleted),
+		IncludeText:        input.IncludeText,
+		WithLinks:          input.WithLinks,
+		WithBundles:        input.WithBundles,
+		Prefix:             input.Prefix,
+		KnownSHA:           input.KnownSHA,
+		Fields:             input.Fields,
+		FallbackWorkspaces: input.FallbackWorkspaces,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleExists handles the exists tool call.
+func (h *Handlers) HandleExists(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[ExistsRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.Exists(ctx, h.db, ops.ExistsInput{
+		ID:             input.ID,
+		Workspace:      input.Workspace,
+		Name:           input.Name,
+		IncludeDeleted: h.includeDeleted(input.IncludeDeleted),
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleFetchRecord handles the fetch_record tool call.
+func (h *Handlers) HandleFetchRecord(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[FetchRecordRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.FetchRecord(ctx, h.db, ops.FetchRecordInput{
 		ID:             input.ID,
 		Workspace:      input.Workspace,
 		Name:           input.Name,
 		IncludeDeleted: input.IncludeDeleted,
-		IncludeText:    input.IncludeText,
 	})
 	if err != nil {
 		return errorResult(err), nil
@@ -300,7 +649,7 @@ func (h *Handlers) HandleUpdate(ctx context.Context, req mcp.CallToolRequest) (*
 		return errorResult(errors.NewInvalidRequest(err.Error())), nil
 	}
 
-	result, err := ops.Update(ctx, h.db, h.cfg, ops.UpdateInput{
+	result, err := ops.Update(ctx, h.db, h.config(), ops.UpdateInput{
 		ID:          input.ID,
 		Workspace:   input.Workspace,
 		Name:        input.Name,
@@ -311,6 +660,10 @@ func (h *Handlers) HandleUpdate(ctx context.Context, req mcp.CallToolRequest) (*
 		RunID:       input.RunID,
 		Phase:       input.Phase,
 		Role:        input.Role,
+		SourceType:  input.SourceType,
+		SourceRef:   input.SourceRef,
+		Note:        input.Note,
+		ExpiresAt:   input.ExpiresAt,
 		AllowThin:   input.AllowThin,
 	})
 	if err != nil {
@@ -352,7 +705,33 @@ func (h *Handlers) HandleLatest(ctx context.Context, req mcp.CallToolRequest) (*
 		Phase:          input.Phase,
 		Role:           input.Role,
 		IncludeText:    input.IncludeText,
-		IncludeDeleted: input.IncludeDeleted,
+		IncludeDeleted: h.includeDeleted(input.IncludeDeleted),
+		By:             input.By,
+		PreferNamed:    input.PreferNamed,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleFirst handles the first tool call. It takes the same arguments as latest.
+func (h *Handlers) HandleFirst(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[LatestRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.First(ctx, h.db, ops.LatestInput{
+		Workspace:      input.Workspace,
+		RunID:          input.RunID,
+		Phase:          input.Phase,
+		Role:           input.Role,
+		IncludeText:    input.IncludeText,
+		IncludeDeleted: h.includeDeleted(input.IncludeDeleted),
+		By:             input.By,
+		PreferNamed:    input.PreferNamed,
 	})
 	if err != nil {
 		return errorResult(err), nil
@@ -373,9 +752,11 @@ func (h *Handlers) HandleList(ctx context.Context, req mcp.CallToolRequest) (*mc
 		RunID:          input.RunID,
 		Phase:          input.Phase,
 		Role:           input.Role,
+		Named:          input.Named,
 		Limit:          input.Limit,
 		Offset:         input.Offset,
-		IncludeDeleted: input.IncludeDeleted,
+		IncludeDeleted: h.includeDeleted(input.IncludeDeleted),
+		IncludeText:    input.IncludeText,
 	})
 	if err != nil {
 		return errorResult(err), nil
@@ -398,9 +779,13 @@ func (h *Handlers) HandleInventory(ctx context.Context, req mcp.CallToolRequest)
 		RunID:          input.RunID,
 		Phase:          input.Phase,
 		Role:           input.Role,
+		SourceType:     input.SourceType,
+		Named:          input.Named,
 		Limit:          input.Limit,
 		Offset:         input.Offset,
-		IncludeDeleted: input.IncludeDeleted,
+		IncludeDeleted: h.includeDeleted(input.IncludeDeleted),
+		IncludeText:    input.IncludeText,
+		NormalizeTags:  h.config().NormalizeTags,
 	})
 	if err != nil {
 		return errorResult(err), nil
@@ -416,10 +801,16 @@ func (h *Handlers) HandleExport(ctx context.Context, req mcp.CallToolRequest) (*
 		return errorResult(errors.NewInvalidRequest(err.Error())), nil
 	}
 
-	result, err := ops.Export(ctx, h.db, h.cfg, ops.ExportInput{
+	result, err := ops.Export(ctx, h.db, h.config(), ops.ExportInput{
 		Path:           input.Path,
 		Workspace:      input.Workspace,
+		IDs:            input.IDs,
 		IncludeDeleted: input.IncludeDeleted,
+		Format:         ops.ExportFormat(input.Format),
+		OrderBy:        ops.ExportOrder(input.OrderBy),
+		Compact:        input.Compact,
+		ResumeFrom:     input.ResumeFrom,
+		Append:         input.Append,
 	})
 	if err != nil {
 		return errorResult(err), nil
@@ -435,18 +826,9 @@ func (h *Handlers) HandleImport(ctx context.Context, req mcp.CallToolRequest) (*
 		return errorResult(errors.NewInvalidRequest(err.Error())), nil
 	}
 
-	// Map to ops input
-	mode := ops.ImportModeError
-	switch input.Mode {
-	case "replace":
-		mode = ops.ImportModeReplace
-	case "rename":
-		mode = ops.ImportModeRename
-	}
-
-	result, err := ops.Import(ctx, h.db, h.cfg, ops.ImportInput{
+	result, err := ops.Import(ctx, h.db, h.config(), ops.ImportInput{
 		Path: input.Path,
-		Mode: mode,
+		Mode: ops.ImportMode(input.Mode),
 	})
 	if err != nil {
 		return errorResult(err), nil
@@ -455,16 +837,16 @@ func (h *Handlers) HandleImport(ctx context.Context, req mcp.CallToolRequest) (*
 	return successResult(result)
 }
 
-// HandlePurge handles the purge tool call.
-func (h *Handlers) HandlePurge(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	input, err := decode[PurgeRequest](req)
+// HandleExportString handles the export_string tool call.
+func (h *Handlers) HandleExportString(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[ExportStringRequest](req)
 	if err != nil {
 		return errorResult(errors.NewInvalidRequest(err.Error())), nil
 	}
 
-	result, err := ops.Purge(ctx, h.db, ops.PurgeInput{
-		Workspace:     input.Workspace,
-		OlderThanDays: input.OlderThanDays,
+	result, err := ops.ExportString(ctx, h.db, ops.ExportStringInput{
+		Workspace:      input.Workspace,
+		IncludeDeleted: input.IncludeDeleted,
 	})
 	if err != nil {
 		return errorResult(err), nil
@@ -473,20 +855,16 @@ func (h *Handlers) HandlePurge(ctx context.Context, req mcp.CallToolRequest) (*m
 	return successResult(result)
 }
 
-// HandleBulkDelete handles the bulk_delete tool call.
-func (h *Handlers) HandleBulkDelete(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	input, err := decode[BulkDeleteRequest](req)
+// HandleImportString handles the import_string tool call.
+func (h *Handlers) HandleImportString(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[ImportStringRequest](req)
 	if err != nil {
 		return errorResult(errors.NewInvalidRequest(err.Error())), nil
 	}
 
-	result, err := ops.BulkDelete(ctx, h.db, ops.BulkDeleteInput{
-		Workspace:  input.Workspace,
-		Tag:        input.Tag,
-		NamePrefix: input.NamePrefix,
-		RunID:      input.RunID,
-		Phase:      input.Phase,
-		Role:       input.Role,
+	result, err := ops.ImportString(ctx, h.db, ops.ImportStringInput{
+		Data: input.Data,
+		Mode: ops.ImportMode(input.Mode),
 	})
 	if err != nil {
 		return errorResult(err), nil
@@ -495,23 +873,17 @@ func (h *Handlers) HandleBulkDelete(ctx context.Context, req mcp.CallToolRequest
 	return successResult(result)
 }
 
-// HandleBulkUpdate handles the bulk_update tool call.
-func (h *Handlers) HandleBulkUpdate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	input, err := decode[BulkUpdateRequest](req)
+// HandlePurge handles the purge tool call.
+func (h *Handlers) HandlePurge(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[PurgeRequest](req)
 	if err != nil {
 		return errorResult(errors.NewInvalidRequest(err.Error())), nil
 	}
 
-	result, err := ops.BulkUpdate(ctx, h.db, ops.BulkUpdateInput{
-		Workspace:  input.Workspace,
-		Tag:        input.Tag,
-		NamePrefix: input.NamePrefix,
-		RunID:      input.RunID,
-		Phase:      input.Phase,
-		Role:       input.Role,
-		SetPhase:   input.SetPhase,
-		SetRole:    input.SetRole,
-		SetTags:    input.SetTags,
+	result, err := ops.Purge(ctx, h.db, ops.PurgeInput{
+		Workspace:     input.Workspace,
+		OlderThanDays: input.OlderThanDays,
+		Archive:       h.config().ArchiveOnPurge,
 	})
 	if err != nil {
 		return errorResult(err), nil
@@ -520,23 +892,16 @@ func (h *Handlers) HandleBulkUpdate(ctx context.Context, req mcp.CallToolRequest
 	return successResult(result)
 }
 
-// HandleSearch handles the search tool call.
-func (h *Handlers) HandleSearch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	input, err := decode[SearchRequest](req)
+// HandleRecentlyDeleted handles the recently_deleted tool call.
+func (h *Handlers) HandleRecentlyDeleted(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[RecentlyDeletedRequest](req)
 	if err != nil {
 		return errorResult(errors.NewInvalidRequest(err.Error())), nil
 	}
 
-	result, err := ops.Search(ctx, h.db, ops.SearchInput{
-		Query:          input.Query,
-		Workspace:      input.Workspace,
-		Tag:            input.Tag,
-		RunID:          input.RunID,
-		Phase:          input.Phase,
-		Role:           input.Role,
-		Limit:          input.Limit,
-		Offset:         input.Offset,
-		IncludeDeleted: input.IncludeDeleted,
+	result, err := ops.RecentlyDeleted(ctx, h.db, ops.RecentlyDeletedInput{
+		Workspace: input.Workspace,
+		Limit:     input.Limit,
 	})
 	if err != nil {
 		return errorResult(err), nil
@@ -545,19 +910,17 @@ func (h *Handlers) HandleSearch(ctx context.Context, req mcp.CallToolRequest) (*
 	return successResult(result)
 }
 
-// HandleAppend handles the append tool call.
-func (h *Handlers) HandleAppend(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	input, err := decode[AppendRequest](req)
+// HandleListArchived handles the list_archived tool call.
+func (h *Handlers) HandleListArchived(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[ListArchivedRequest](req)
 	if err != nil {
 		return errorResult(errors.NewInvalidRequest(err.Error())), nil
 	}
 
-	result, err := ops.Append(ctx, h.db, h.cfg, ops.AppendInput{
-		ID:        input.ID,
+	result, err := ops.ListArchived(ctx, h.db, ops.ListArchivedInput{
 		Workspace: input.Workspace,
-		Name:      input.Name,
-		Section:   input.Section,
-		Content:   input.Content,
+		Limit:     input.Limit,
+		Offset:    input.Offset,
 	})
 	if err != nil {
 		return errorResult(err), nil
@@ -566,43 +929,32 @@ func (h *Handlers) HandleAppend(ctx context.Context, req mcp.CallToolRequest) (*
 	return successResult(result)
 }
 
-// HandleCompose handles the compose tool call.
-func (h *Handlers) HandleCompose(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	input, err := decode[ComposeRequest](req)
+// HandleRestoreArchived handles the restore_archived tool call.
+func (h *Handlers) HandleRestoreArchived(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[RestoreArchivedRequest](req)
 	if err != nil {
 		return errorResult(errors.NewInvalidRequest(err.Error())), nil
 	}
 
-	// Convert refs
-	refs := make([]ops.ComposeRef, len(input.Items))
-	for i, item := range input.Items {
-		refs[i] = ops.ComposeRef{
-			ID:        item.ID,
-			Workspace: item.Workspace,
-			Name:      item.Name,
-		}
+	result, err := ops.RestoreFromArchive(ctx, h.db, ops.RestoreFromArchiveInput{ID: input.ID})
+	if err != nil {
+		return errorResult(err), nil
 	}
 
-	// Build ops input
-	opsInput := ops.ComposeInput{
-		Items:    refs,
-		Format:   input.Format,
-		Sections: input.Sections,
-	}
+	return successResult(result)
+}
 
-	if input.StoreAs != nil {
-		mode := ops.StoreModeError
-		if input.StoreAs.Mode == "replace" {
-			mode = ops.StoreModeReplace
-		}
-		opsInput.StoreAs = &ops.ComposeStoreAs{
-			Workspace: input.StoreAs.Workspace,
-			Name:      input.StoreAs.Name,
-			Mode:      mode,
-		}
+// HandlePruneDuplicates handles the prune_duplicates tool call.
+func (h *Handlers) HandlePruneDuplicates(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[PruneDuplicatesRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
 	}
 
-	result, err := ops.Compose(ctx, h.db, h.cfg, opsInput)
+	result, err := ops.PruneDuplicates(ctx, h.db, ops.PruneDuplicatesInput{
+		Workspace: input.Workspace,
+		DryRun:    input.DryRun,
+	})
 	if err != nil {
 		return errorResult(err), nil
 	}
@@ -610,13 +962,611 @@ func (h *Handlers) HandleCompose(ctx context.Context, req mcp.CallToolRequest) (
 	return successResult(result)
 }
 
-// Result helpers
-
-// errorResult creates an MCP error result from any error.
-// Uses IsError: true so MCP clients recognize failures properly.
-// Note: Internal error details are not exposed to prevent leaking sensitive info.
-func errorResult(err error) *mcp.CallToolResult {
-	var payload map[string]any
+// HandleReindex handles the reindex tool call.
+func (h *Handlers) HandleReindex(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[ReindexRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.Reindex(ctx, h.db, ops.ReindexInput{
+		CheckOnly: input.CheckOnly,
+		Force:     input.Force,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleBulkDelete handles the bulk_delete tool call.
+func (h *Handlers) HandleBulkDelete(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[BulkDeleteRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.BulkDelete(ctx, h.db, ops.BulkDeleteInput{
+		Workspace:     input.Workspace,
+		Tag:           input.Tag,
+		NamePrefix:    input.NamePrefix,
+		RunID:         input.RunID,
+		Phase:         input.Phase,
+		Role:          input.Role,
+		NormalizeTags: h.config().NormalizeTags,
+		DryRun:        input.DryRun,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleDeleteMany handles the delete_many tool call.
+func (h *Handlers) HandleDeleteMany(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[DeleteManyRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.DeleteMany(ctx, h.db, input.IDs)
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleBulkRestore handles the bulk_restore tool call.
+func (h *Handlers) HandleBulkRestore(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[BulkRestoreRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.BulkRestore(ctx, h.db, ops.BulkRestoreInput{
+		Workspace:     input.Workspace,
+		Tag:           input.Tag,
+		NamePrefix:    input.NamePrefix,
+		RunID:         input.RunID,
+		Phase:         input.Phase,
+		Role:          input.Role,
+		NormalizeTags: h.config().NormalizeTags,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleBulkMove handles the bulk_move tool call.
+func (h *Handlers) HandleBulkMove(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[BulkMoveRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	cfg := h.config()
+	result, err := ops.BulkMove(ctx, h.db, ops.BulkMoveInput{
+		Workspace:       input.Workspace,
+		Tag:             input.Tag,
+		NamePrefix:      input.NamePrefix,
+		RunID:           input.RunID,
+		Phase:           input.Phase,
+		Role:            input.Role,
+		ToWorkspace:     input.ToWorkspace,
+		NormalizeTags:   cfg.NormalizeTags,
+		MaxPerWorkspace: cfg.MaxCapsulesPerWorkspace,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleRenameTag handles the rename_tag tool call.
+func (h *Handlers) HandleRenameTag(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[RenameTagRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.RenameTag(ctx, h.db, ops.RenameTagInput{
+		OldTag:        input.OldTag,
+		NewTag:        input.NewTag,
+		Workspace:     input.Workspace,
+		NormalizeTags: h.config().NormalizeTags,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleBulkAddTags handles the bulk_add_tags tool call.
+func (h *Handlers) HandleBulkAddTags(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[BulkTagsRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.BulkAddTags(ctx, h.db, h.bulkTagsInput(input))
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleBulkRemoveTags handles the bulk_remove_tags tool call.
+func (h *Handlers) HandleBulkRemoveTags(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[BulkTagsRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.BulkRemoveTags(ctx, h.db, h.bulkTagsInput(input))
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// bulkTagsInput maps a BulkTagsRequest to the ops input shared by both bulk tag tools.
+func (h *Handlers) bulkTagsInput(input BulkTagsRequest) ops.BulkTagsInput {
+	return ops.BulkTagsInput{
+		Workspace:     input.Workspace,
+		Tag:           input.Tag,
+		NamePrefix:    input.NamePrefix,
+		RunID:         input.RunID,
+		Phase:         input.Phase,
+		Role:          input.Role,
+		Tags:          input.Tags,
+		NormalizeTags: h.config().NormalizeTags,
+	}
+}
+
+// HandleBulkUpdate handles the bulk_update tool call.
+func (h *Handlers) HandleBulkUpdate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[BulkUpdateRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.BulkUpdate(ctx, h.db, ops.BulkUpdateInput{
+		Workspace:     input.Workspace,
+		Tag:           input.Tag,
+		NamePrefix:    input.NamePrefix,
+		RunID:         input.RunID,
+		Phase:         input.Phase,
+		Role:          input.Role,
+		SetPhase:      input.SetPhase,
+		SetRole:       input.SetRole,
+		SetTags:       input.SetTags,
+		SetSource:     input.SetSource,
+		SetTitle:      input.SetTitle,
+		NormalizeTags: h.config().NormalizeTags,
+		DryRun:        input.DryRun,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleSearch handles the search tool call.
+func (h *Handlers) HandleSearch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[SearchRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	cfg := h.config()
+	result, err := ops.Search(ctx, h.db, ops.SearchInput{
+		Query:              input.Query,
+		Workspace:          input.Workspace,
+		Workspaces:         input.Workspaces,
+		Tag:                input.Tag,
+		Tags:               input.Tags,
+		TagMatch:           input.TagMatch,
+		NamePrefix:         input.NamePrefix,
+		UpdatedAfter:       input.UpdatedAfter,
+		UpdatedBefore:      input.UpdatedBefore,
+		RunID:              input.RunID,
+		Phase:              input.Phase,
+		Role:               input.Role,
+		SourceType:         input.SourceType,
+		Limit:              input.Limit,
+		Offset:             input.Offset,
+		Sort:               input.Sort,
+		Cursor:             input.Cursor,
+		IncludeDeleted:     h.includeDeleted(input.IncludeDeleted),
+		WithSnippet:        input.WithSnippet,
+		SnippetTokens:      input.SnippetTokens,
+		SnippetFormats:     input.SnippetFormats,
+		Facets:             input.Facets,
+		GroupByWorkspace:   input.GroupByWorkspace,
+		MinTermLen:         cfg.MinSearchTermLen,
+		NormalizeTags:      cfg.NormalizeTags,
+		EmptyQueryListsAll: cfg.EmptyQueryListsAll,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleChanges handles the changes tool call.
+func (h *Handlers) HandleChanges(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[ChangesRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.Changes(ctx, h.db, ops.ChangesInput{
+		Since:  input.Since,
+		Limit:  input.Limit,
+		Cursor: input.Cursor,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleAppend handles the append tool call.
+func (h *Handlers) HandleAppend(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[AppendRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.Append(ctx, h.db, h.config(), ops.AppendInput{
+		ID:        input.ID,
+		Workspace: input.Workspace,
+		Name:      input.Name,
+		Section:   input.Section,
+		Content:   input.Content,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandlePatch handles the patch tool call.
+func (h *Handlers) HandlePatch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[PatchRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	patchOps := make([]ops.PatchOp, len(input.Ops))
+	for i, op := range input.Ops {
+		patchOps[i] = ops.PatchOp{
+			Op:      ops.PatchOpType(op.Op),
+			Section: op.Section,
+			Value:   op.Value,
+		}
+	}
+
+	result, err := ops.Patch(ctx, h.db, h.config(), ops.PatchInput{
+		ID:        input.ID,
+		Workspace: input.Workspace,
+		Name:      input.Name,
+		Ops:       patchOps,
+		AllowThin: input.AllowThin,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleCompose handles the compose tool call.
+func (h *Handlers) HandleCompose(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[ComposeRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	// Convert refs
+	refs := make([]ops.ComposeRef, len(input.Items))
+	for i, item := range input.Items {
+		refs[i] = ops.ComposeRef{
+			ID:        item.ID,
+			Workspace: item.Workspace,
+			Name:      item.Name,
+		}
+	}
+
+	// Build ops input
+	opsInput := ops.ComposeInput{
+		Items:              refs,
+		Format:             input.Format,
+		Sections:           input.Sections,
+		HeaderTemplate:     input.HeaderTemplate,
+		DryRun:             input.DryRun,
+		OrderBy:            ops.ComposeOrder(input.OrderBy),
+		IntraPartSeparator: input.IntraPartSeparator,
+		TrailingNewline:    input.TrailingNewline,
+	}
+
+	if input.StoreAs != nil {
+		mode := ops.StoreModeError
+		if input.StoreAs.Mode == "replace" {
+			mode = ops.StoreModeReplace
+		}
+		opsInput.StoreAs = &ops.ComposeStoreAs{
+			Workspace:   input.StoreAs.Workspace,
+			Name:        input.StoreAs.Name,
+			Mode:        mode,
+			TitlePrefix: input.StoreAs.TitlePrefix,
+			TitleSuffix: input.StoreAs.TitleSuffix,
+		}
+	}
+
+	result, err := ops.Compose(ctx, h.db, h.config(), opsInput)
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleLink handles the link tool call.
+func (h *Handlers) HandleLink(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[LinkRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.Link(ctx, h.db, toLinkInput(input))
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleUnlink handles the unlink tool call.
+func (h *Handlers) HandleUnlink(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[LinkRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.Unlink(ctx, h.db, toLinkInput(input))
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleLinks handles the links tool call.
+func (h *Handlers) HandleLinks(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[LinksRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.Links(ctx, h.db, ops.LinksInput{
+		ID:             input.ID,
+		Workspace:      input.Workspace,
+		Name:           input.Name,
+		IncludeDeleted: input.IncludeDeleted,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleBundles handles the bundles tool call.
+func (h *Handlers) HandleBundles(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[BundlesRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.Bundles(ctx, h.db, ops.BundlesInput{
+		ID:             input.ID,
+		Workspace:      input.Workspace,
+		Name:           input.Name,
+		IncludeDeleted: input.IncludeDeleted,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleRelated handles the related tool call.
+func (h *Handlers) HandleRelated(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[RelatedRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.Related(ctx, h.db, ops.RelatedInput{
+		ID:            input.ID,
+		Workspace:     input.Workspace,
+		Name:          input.Name,
+		Limit:         input.Limit,
+		SameWorkspace: input.SameWorkspace,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleTopTerms handles the top_terms tool call.
+func (h *Handlers) HandleTopTerms(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[TopTermsRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	result, err := ops.TopTerms(ctx, h.db, ops.TopTermsInput{
+		Workspace: input.Workspace,
+		Limit:     input.Limit,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleBatch handles the batch tool call.
+func (h *Handlers) HandleBatch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[BatchRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	batch := make([]ops.BatchOp, len(input.Ops))
+	for i, op := range input.Ops {
+		batch[i] = toBatchOp(op)
+	}
+
+	result, err := ops.Batch(ctx, h.db, h.config(), batch)
+	if err != nil {
+		return errorResult(err), nil
+	}
+
+	return successResult(result)
+}
+
+// HandleGetConfig handles the get_config tool call.
+func (h *Handlers) HandleGetConfig(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg := h.config()
+	return successResult(GetConfigResponse{
+		Config:       cfg,
+		RuntimeKeys:  config.RuntimeKeys,
+		EnabledTools: h.enabledTools,
+	})
+}
+
+// HandleSetConfig handles the set_config tool call.
+func (h *Handlers) HandleSetConfig(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	input, err := decode[SetConfigRequest](req)
+	if err != nil {
+		return errorResult(errors.NewInvalidRequest(err.Error())), nil
+	}
+
+	h.configMu.Lock()
+	defer h.configMu.Unlock()
+
+	next, result, err := ops.SetConfig(h.config(), h.configDir, ops.SetConfigInput{
+		Key:   input.Key,
+		Value: input.Value,
+	})
+	if err != nil {
+		return errorResult(err), nil
+	}
+	h.cfg.Store(next)
+
+	return successResult(result)
+}
+
+// toBatchOp converts a BatchOpRequest to the ops tagged union.
+// Unknown op types pass through without a payload; ops.Batch rejects them.
+func toBatchOp(r BatchOpRequest) ops.BatchOp {
+	op := ops.BatchOp{Op: ops.BatchOpType(r.Op)}
+	name := ""
+	if r.Name != nil {
+		name = *r.Name
+	}
+
+	switch op.Op {
+	case ops.BatchOpStore:
+		in := &ops.StoreInput{
+			Workspace:  r.Workspace,
+			Name:       r.Name,
+			Title:      r.Title,
+			Source:     r.Source,
+			RunID:      r.RunID,
+			Phase:      r.Phase,
+			Role:       r.Role,
+			SourceType: r.SourceType,
+			SourceRef:  r.SourceRef,
+			Note:       r.Note,
+			ExpiresAt:  r.ExpiresAt,
+			Mode:       ops.StoreMode(r.Mode),
+			AllowThin:  r.AllowThin,
+		}
+		if r.CapsuleText != nil {
+			in.CapsuleText = *r.CapsuleText
+		}
+		if r.Tags != nil {
+			in.Tags = *r.Tags
+		}
+		op.Store = in
+	case ops.BatchOpUpdate:
+		op.Update = &ops.UpdateInput{
+			ID:          r.ID,
+			Workspace:   r.Workspace,
+			Name:        name,
+			CapsuleText: r.CapsuleText,
+			Title:       r.Title,
+			Tags:        r.Tags,
+			Source:      r.Source,
+			RunID:       r.RunID,
+			Phase:       r.Phase,
+			Role:        r.Role,
+			SourceType:  r.SourceType,
+			SourceRef:   r.SourceRef,
+			Note:        r.Note,
+			ExpiresAt:   r.ExpiresAt,
+			AllowThin:   r.AllowThin,
+		}
+	case ops.BatchOpDelete:
+		op.Delete = &ops.DeleteInput{ID: r.ID, Workspace: r.Workspace, Name: name}
+	case ops.BatchOpMove:
+		op.Move = &ops.MoveInput{
+			ID:          r.ID,
+			Workspace:   r.Workspace,
+			Name:        name,
+			ToWorkspace: r.ToWorkspace,
+			ToName:      r.ToName,
+		}
+	}
+	return op
+}
+
+// toLinkInput converts a LinkRequest to ops.LinkInput.
+func toLinkInput(input LinkRequest) ops.LinkInput {
+	return ops.LinkInput{
+		From:     ops.LinkRef(input.From),
+		To:       ops.LinkRef(input.To),
+		Relation: input.Relation,
+	}
+}
+
+// Result helpers
+
+// errorResult creates an MCP error result from any error.
+// Uses IsError: true so MCP clients recognize failures properly.
+// Note: Internal error details are not exposed to prevent leaking sensitive info.
+func errorResult(err error) *mcp.CallToolResult {
+	var payload map[string]any
 
 	var mossErr *errors.MossError
 	if stderrors.As(err, &mossErr) {
@@ -658,3 +1608,76 @@ func errorResult(err error) *mcp.CallToolResult {
 func successResult(data any) (*mcp.CallToolResult, error) {
 	return mcp.NewToolResultJSON(data)
 }
+
+// withResponseCap wraps a tool handler so a success result whose JSON payload
+// exceeds config.MaxResponseBytes is replaced by RESPONSE_TOO_LARGE. The cap is
+// read per call, so capsule_set_config changes apply immediately.
+func (h *Handlers) withResponseCap(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, req)
+		if err != nil || result == nil || result.IsError {
+			return result, err
+		}
+		limit := h.config().MaxResponseBytes
+		if limit <= 0 {
+			return result, nil
+		}
+		size := 0
+		for _, c := range result.Content {
+			if text, ok := c.(mcp.TextContent); ok {
+				size += len(text.Text)
+			}
+		}
+		if size > limit {
+			return errorResult(errors.NewResponseTooLarge(limit, size)), nil
+		}
+		return result, nil
+	}
+}
+
+// Envelope is the uniform top-level shape of every tool result when
+// config.EnvelopeResponses is enabled. Data holds the tool's usual payload on
+// success; Error holds the usual error object ({code, message, status}) on failure.
+type Envelope struct {
+	Tool  string `json:"tool"`
+	OK    bool   `json:"ok"`
+	Data  any    `json:"data"`
+	Error any    `json:"error"`
+}
+
+// withEnvelope wraps a tool handler so its result is re-shaped by envelopeResult.
+func withEnvelope(tool string, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, req)
+		if err != nil || result == nil {
+			return result, err
+		}
+		return envelopeResult(tool, result)
+	}
+}
+
+// envelopeResult converts a result built by successResult or errorResult into an Envelope.
+func envelopeResult(tool string, result *mcp.CallToolResult) (*mcp.CallToolResult, error) {
+	env := Envelope{Tool: tool, OK: !result.IsError}
+	if result.IsError {
+		// errorResult payloads are {"error": {...}}
+		var payload struct {
+			Error any `json:"error"`
+		}
+		if len(result.Content) > 0 {
+			if text, ok := result.Content[0].(mcp.TextContent); ok {
+				_ = json.Unmarshal([]byte(text.Text), &payload)
+			}
+		}
+		env.Error = payload.Error
+	} else {
+		env.Data = result.StructuredContent
+	}
+
+	wrapped, err := successResult(env)
+	if err != nil {
+		return nil, err
+	}
+	wrapped.IsError = result.IsError
+	return wrapped, nil
+}
@@ -8,7 +8,6 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
 	"testing"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -16,6 +15,7 @@ import (
 	"github.com/hpungsan/moss/internal/config"
 	"github.com/hpungsan/moss/internal/db"
 	"github.com/hpungsan/moss/internal/errors"
+	"github.com/hpungsan/moss/internal/testutil"
 )
 
 // testSetup creates a temporary database and config for testing.
@@ -47,64 +47,6 @@ func makeRequest(args map[string]any) mcp.CallToolRequest {
 	}
 }
 
-type cancelAfterDoneCallsCtx struct {
-	context.Context
-	cancelAfter int
-
-	mu    sync.Mutex
-	calls int
-	err   error
-
-	done chan struct{}
-	once sync.Once
-}
-
-func newCancelAfterDoneCallsCtx(ctx context.Context, cancelAfter int) *cancelAfterDoneCallsCtx {
-	c := &cancelAfterDoneCallsCtx{
-		Context:     ctx,
-		cancelAfter: cancelAfter,
-		done:        make(chan struct{}),
-	}
-	go func() {
-		select {
-		case <-ctx.Done():
-			c.cancel(ctx.Err())
-		case <-c.done:
-		}
-	}()
-	return c
-}
-
-func (c *cancelAfterDoneCallsCtx) cancel(err error) {
-	c.once.Do(func() {
-		c.mu.Lock()
-		c.err = err
-		c.mu.Unlock()
-		close(c.done)
-	})
-}
-
-func (c *cancelAfterDoneCallsCtx) Done() <-chan struct{} {
-	c.mu.Lock()
-	c.calls++
-	shouldCancel := c.cancelAfter > 0 && c.calls >= c.cancelAfter
-	c.mu.Unlock()
-
-	if shouldCancel {
-		c.cancel(context.Canceled)
-	}
-	return c.done
-}
-
-func (c *cancelAfterDoneCallsCtx) Err() error {
-	if err := c.Context.Err(); err != nil {
-		return err
-	}
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.err
-}
-
 // validCapsuleText returns a capsule text with all required sections.
 func validCapsuleText() string {
 	return `## Objective
@@ -226,6 +168,91 @@ func TestHandleStore(t *testing.T) {
 	}
 }
 
+// TestHandleStore_NameCollisionDetails tests that NAME_ALREADY_EXISTS carries
+// the ID of the capsule holding the name.
+func TestHandleStore_NameCollisionDetails(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	args := map[string]any{
+		"capsule_text": validCapsuleText(),
+		"workspace":    "test",
+		"name":         "auth",
+	}
+	first, err := h.HandleStore(ctx, makeRequest(args))
+	if err != nil {
+		t.Fatalf("setup store failed: %v", err)
+	}
+	existingID := parseOutput(t, first)["id"]
+
+	result, err := h.HandleStore(ctx, makeRequest(args))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	assertErrorCode(t, result, "NAME_ALREADY_EXISTS")
+
+	var payload struct {
+		Error struct {
+			Details map[string]any `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &payload); err != nil {
+		t.Fatalf("failed to unmarshal error payload: %v", err)
+	}
+	if payload.Error.Details["id"] != existingID {
+		t.Errorf("details.id = %v, want %v", payload.Error.Details["id"], existingID)
+	}
+	if payload.Error.Details["name"] != "auth" || payload.Error.Details["workspace"] != "test" {
+		t.Errorf("details = %v, want workspace test and name auth", payload.Error.Details)
+	}
+}
+
+// TestHandleFetchRecord tests that fetch_record returns the raw export record.
+func TestHandleFetchRecord(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	storeReq := makeRequest(map[string]any{
+		"capsule_text": validCapsuleText(),
+		"workspace":    "test",
+		"name":         "record-test",
+	})
+	if storeResult, _ := h.HandleStore(ctx, storeReq); storeResult.IsError {
+		t.Fatalf("setup store failed: %v", extractErrorMessage(storeResult))
+	}
+
+	result, err := h.HandleFetchRecord(ctx, makeRequest(map[string]any{
+		"workspace": "test",
+		"name":      "record-test",
+	}))
+	if err != nil {
+		t.Fatalf("fetch_record handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("fetch_record failed: %v", extractErrorMessage(result))
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &record); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	// Export record shape: raw/norm pairs and explicit nulls, no fetch_key
+	for _, key := range []string{"workspace_raw", "name_raw", "capsule_text", "deleted_at"} {
+		if _, ok := record[key]; !ok {
+			t.Errorf("missing key %q in record", key)
+		}
+	}
+	if _, ok := record["fetch_key"]; ok {
+		t.Error("record should not include fetch_key")
+	}
+}
+
 // TestHandleFetch tests the fetch handler.
 func TestHandleFetch(t *testing.T) {
 	database, cfg, cleanup := testSetup(t)
@@ -325,6 +352,61 @@ func TestHandleFetch(t *testing.T) {
 	}
 }
 
+// TestHandleFetch_KnownSHA tests that a matching known_sha omits capsule_text.
+func TestHandleFetch_KnownSHA(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	storeResult, _ := h.HandleStore(ctx, makeRequest(map[string]any{
+		"capsule_text": validCapsuleText(),
+		"name":         "sha-test",
+	}))
+	if storeResult.IsError {
+		t.Fatalf("setup store failed: %v", extractErrorMessage(storeResult))
+	}
+
+	fetch := func(args map[string]any) map[string]any {
+		t.Helper()
+		result, err := h.HandleFetch(ctx, makeRequest(args))
+		if err != nil {
+			t.Fatalf("handler returned error: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("fetch failed: %v", extractErrorMessage(result))
+		}
+		var out map[string]any
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &out); err != nil {
+			t.Fatalf("failed to unmarshal fetch result: %v", err)
+		}
+		return out
+	}
+
+	first := fetch(map[string]any{"name": "sha-test"})
+	sum, _ := first["content_sha256"].(string)
+	if len(sum) != 64 {
+		t.Fatalf("content_sha256 = %q, want 64 hex chars", sum)
+	}
+
+	same := fetch(map[string]any{"name": "sha-test", "known_sha": sum})
+	if same["unchanged"] != true {
+		t.Errorf("unchanged = %v, want true", same["unchanged"])
+	}
+	if _, ok := same["capsule_text"]; ok {
+		t.Error("capsule_text should be omitted when unchanged")
+	}
+
+	stale := fetch(map[string]any{"name": "sha-test", "known_sha": "deadbeef"})
+	if _, ok := stale["unchanged"]; ok {
+		t.Error("unchanged should be omitted when known_sha differs")
+	}
+	if stale["capsule_text"] != validCapsuleText() {
+		t.Error("capsule_text should be returned when known_sha differs")
+	}
+}
+
 // TestHandleFetchMany tests the fetch_many handler.
 func TestHandleFetchMany(t *testing.T) {
 	database, cfg, cleanup := testSetup(t)
@@ -458,7 +540,7 @@ func TestHandleFetchMany_CancelledContextReturnsCancelled(t *testing.T) {
 	req := makeRequest(map[string]any{"items": items})
 
 	// Cancel after a small number of ctx.Done() checks so cancellation happens mid-loop.
-	ctx := newCancelAfterDoneCallsCtx(context.Background(), 10)
+	ctx := testutil.CancelAfterDoneCalls(context.Background(), 10)
 
 	result, err := h.HandleFetchMany(ctx, req)
 	if err != nil {
@@ -480,6 +562,112 @@ func TestHandleFetchMany_CancelledContextReturnsCancelled(t *testing.T) {
 	}
 }
 
+func TestHandleCompose_CancelledContextReturnsCancelled(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	setupCtx := context.Background()
+
+	const n = 50
+	items := make([]any, 0, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("compose-%02d", i)
+		result, err := h.HandleStore(setupCtx, makeRequest(map[string]any{
+			"capsule_text": validCapsuleText(),
+			"workspace":    "test",
+			"name":         name,
+		}))
+		if err != nil {
+			t.Fatalf("setup store handler returned error: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("setup store failed: %v", extractErrorMessage(result))
+		}
+		items = append(items, map[string]any{"workspace": "test", "name": name})
+	}
+
+	// Sections keep the full bundle under the size limit, so only cancellation can stop it
+	req := makeRequest(map[string]any{"items": items, "sections": []any{"Objective"}})
+
+	// Cancel after a small number of ctx.Done() checks so cancellation happens mid-loop.
+	ctx := testutil.CancelAfterDoneCalls(context.Background(), 10)
+
+	result, err := h.HandleCompose(ctx, req)
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	assertErrorCode(t, result, "CANCELLED")
+	if msg := extractErrorMessage(result); !strings.Contains(msg, `"message":"compose cancelled"`) {
+		t.Fatalf("error=%s, want message %q", msg, "compose cancelled")
+	}
+
+	// Uncancelled, the same request succeeds
+	result, err = h.HandleCompose(context.Background(), req)
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %v", extractErrorMessage(result))
+	}
+}
+
+// TestHandleBundles tests that capsule_bundles lists bundles stored by compose.
+func TestHandleBundles(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	for _, name := range []string{"part-a", "part-b"} {
+		if _, err := h.HandleStore(ctx, makeRequest(map[string]any{
+			"capsule_text": validCapsuleText(),
+			"workspace":    "test",
+			"name":         name,
+		})); err != nil {
+			t.Fatalf("setup store failed: %v", err)
+		}
+	}
+	composed, err := h.HandleCompose(ctx, makeRequest(map[string]any{
+		"items": []any{
+			map[string]any{"workspace": "test", "name": "part-a"},
+			map[string]any{"workspace": "test", "name": "part-b"},
+		},
+		"store_as": map[string]any{"workspace": "test", "name": "bundle"},
+	}))
+	if err != nil {
+		t.Fatalf("compose handler returned error: %v", err)
+	}
+	bundleID := parseOutput(t, composed)["stored"].(map[string]any)["id"]
+
+	for _, name := range []string{"part-a", "part-b"} {
+		result, err := h.HandleBundles(ctx, makeRequest(map[string]any{"workspace": "test", "name": name}))
+		if err != nil {
+			t.Fatalf("bundles handler returned error: %v", err)
+		}
+		bundles, _ := parseOutput(t, result)["bundles"].([]any)
+		if len(bundles) != 1 || bundles[0].(map[string]any)["id"] != bundleID {
+			t.Errorf("bundles for %s = %v, want [%v]", name, bundles, bundleID)
+		}
+	}
+
+	// fetch with_bundles reports the same bundle
+	result, err := h.HandleFetch(ctx, makeRequest(map[string]any{"workspace": "test", "name": "part-a", "with_bundles": true}))
+	if err != nil {
+		t.Fatalf("fetch handler returned error: %v", err)
+	}
+	if bundles, _ := parseOutput(t, result)["bundles"].([]any); len(bundles) != 1 {
+		t.Errorf("fetch bundles = %v, want 1", bundles)
+	}
+
+	result, err = h.HandleBundles(ctx, makeRequest(map[string]any{"id": "01NOPE"}))
+	if err != nil {
+		t.Fatalf("bundles handler returned error: %v", err)
+	}
+	assertErrorCode(t, result, "NOT_FOUND")
+}
+
 // TestHandleUpdate tests the update handler.
 func TestHandleUpdate(t *testing.T) {
 	database, cfg, cleanup := testSetup(t)
@@ -645,58 +833,363 @@ func TestHandleDelete(t *testing.T) {
 	}
 }
 
-// TestHandleLatest tests the latest handler with contract assertions.
-func TestHandleLatest(t *testing.T) {
+// TestHandleRelated tests the related handler excludes the source capsule.
+func TestHandleRelated(t *testing.T) {
 	database, cfg, cleanup := testSetup(t)
 	defer cleanup()
 
 	h := NewHandlers(database, cfg)
 	ctx := context.Background()
 
-	// Test empty workspace returns null item
-	t.Run("empty workspace returns null", func(t *testing.T) {
-		req := makeRequest(map[string]any{"workspace": "empty"})
-		result, err := h.HandleLatest(ctx, req)
-		if err != nil {
-			t.Fatalf("handler returned error: %v", err)
-		}
-		output := parseOutput(t, result)
-		if output["item"] != nil {
-			t.Errorf("expected null item for empty workspace")
+	for _, name := range []string{"source", "sibling"} {
+		result, _ := h.HandleStore(ctx, makeRequest(map[string]any{
+			"capsule_text": validCapsuleText(),
+			"name":         name,
+		}))
+		if result.IsError {
+			t.Fatalf("setup store failed: %v", extractErrorMessage(result))
 		}
-	})
+	}
 
-	// Store a capsule, then delete it
-	storeReq := makeRequest(map[string]any{
+	result, err := h.HandleRelated(ctx, makeRequest(map[string]any{"name": "source"}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %v", extractErrorMessage(result))
+	}
+	var output struct {
+		Items []struct {
+			Name string `json:"name"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+		t.Fatalf("failed to unmarshal related result: %v", err)
+	}
+	if len(output.Items) != 1 || output.Items[0].Name != "sibling" {
+		t.Errorf("items = %+v, want only sibling", output.Items)
+	}
+
+	result, _ = h.HandleRelated(ctx, makeRequest(map[string]any{"name": "missing"}))
+	if !result.IsError {
+		t.Fatal("expected error result for missing source")
+	}
+	assertErrorCode(t, result, "NOT_FOUND")
+}
+
+// TestHandleTopTerms tests the top_terms handler ranks terms for a workspace.
+func TestHandleTopTerms(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	result, _ := h.HandleStore(ctx, makeRequest(map[string]any{
 		"capsule_text": validCapsuleText(),
-		"workspace":    "test",
-		"name":         "latest-test",
-	})
-	if _, err := h.HandleStore(ctx, storeReq); err != nil {
-		t.Fatalf("setup store failed: %v", err)
+		"workspace":    "proj",
+	}))
+	if result.IsError {
+		t.Fatalf("setup store failed: %v", extractErrorMessage(result))
 	}
 
-	// Test include_text:false (default) omits capsule_text
-	t.Run("include_text:false omits capsule_text", func(t *testing.T) {
-		req := makeRequest(map[string]any{
-			"workspace":    "test",
-			"include_text": false,
-		})
-		result, err := h.HandleLatest(ctx, req)
+	result, err := h.HandleTopTerms(ctx, makeRequest(map[string]any{"workspace": "proj", "limit": 3}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %v", extractErrorMessage(result))
+	}
+	var output struct {
+		Workspace string `json:"workspace"`
+		Terms     []struct {
+			Term      string `json:"term"`
+			Documents int    `json:"documents"`
+		} `json:"terms"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+		t.Fatalf("failed to unmarshal top_terms result: %v", err)
+	}
+	if output.Workspace != "proj" || len(output.Terms) != 3 || output.Terms[0].Documents != 1 {
+		t.Errorf("output = %+v, want 3 terms for proj", output)
+	}
+}
+
+func TestHandleExists(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	result, _ := h.HandleStore(ctx, makeRequest(map[string]any{
+		"capsule_text": validCapsuleText(),
+		"workspace":    "proj",
+		"name":         "auth",
+	}))
+	if result.IsError {
+		t.Fatalf("setup store failed: %v", extractErrorMessage(result))
+	}
+
+	for _, tc := range []struct {
+		name       string
+		args       map[string]any
+		wantExists bool
+	}{
+		{"existing", map[string]any{"workspace": "proj", "name": "auth"}, true},
+		{"missing", map[string]any{"workspace": "proj", "name": "nope"}, false},
+	} {
+		result, err := h.HandleExists(ctx, makeRequest(tc.args))
 		if err != nil {
-			t.Fatalf("handler returned error: %v", err)
+			t.Fatalf("%s: handler returned error: %v", tc.name, err)
 		}
-		output := parseOutput(t, result)
-		item := output["item"].(map[string]any)
-		if item["capsule_text"] != nil && item["capsule_text"] != "" {
-			t.Error("include_text:false should omit capsule_text")
+		if result.IsError {
+			t.Fatalf("%s: expected success, got error: %v", tc.name, extractErrorMessage(result))
 		}
-	})
+		var output struct {
+			Exists bool   `json:"exists"`
+			ID     string `json:"id"`
+		}
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+			t.Fatalf("failed to unmarshal exists result: %v", err)
+		}
+		if output.Exists != tc.wantExists || (output.ID != "") != tc.wantExists {
+			t.Errorf("%s: output = %+v, want exists=%v", tc.name, output, tc.wantExists)
+		}
+	}
 
-	// Test include_text:true includes capsule_text
-	t.Run("include_text:true includes capsule_text", func(t *testing.T) {
-		req := makeRequest(map[string]any{
-			"workspace":    "test",
+	result, _ = h.HandleExists(ctx, makeRequest(map[string]any{"id": "01ABC", "name": "auth"}))
+	assertErrorCode(t, result, "AMBIGUOUS_ADDRESSING")
+}
+
+// TestHandlePatch tests that the patch handler applies ops and names a failing op.
+func TestHandlePatch(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	result, _ := h.HandleStore(ctx, makeRequest(map[string]any{
+		"capsule_text": validCapsuleText(),
+		"name":         "auth",
+	}))
+	if result.IsError {
+		t.Fatalf("setup store failed: %v", extractErrorMessage(result))
+	}
+
+	result, err := h.HandlePatch(ctx, makeRequest(map[string]any{
+		"name": "auth",
+		"ops": []any{
+			map[string]any{"op": "set-title", "value": "Patched"},
+			map[string]any{"op": "add-tag", "value": "backend"},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %v", extractErrorMessage(result))
+	}
+	var output struct {
+		Applied int `json:"applied"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+		t.Fatalf("failed to unmarshal patch result: %v", err)
+	}
+	if output.Applied != 2 {
+		t.Errorf("applied = %d, want 2", output.Applied)
+	}
+
+	result, _ = h.HandlePatch(ctx, makeRequest(map[string]any{
+		"name": "auth",
+		"ops":  []any{map[string]any{"op": "append-section", "section": "Risks", "value": "none"}},
+	}))
+	assertErrorCode(t, result, "INVALID_REQUEST")
+	if msg := extractErrorMessage(result); !strings.Contains(msg, "ops[0]") {
+		t.Errorf("error message = %q, want ops[0] position", msg)
+	}
+}
+
+// TestHandleBatch tests the batch handler's commit and rollback paths.
+func TestHandleBatch(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	storeResult, _ := h.HandleStore(ctx, makeRequest(map[string]any{
+		"capsule_text": validCapsuleText(),
+		"name":         "old",
+	}))
+	if storeResult.IsError {
+		t.Fatalf("setup store failed: %v", extractErrorMessage(storeResult))
+	}
+
+	// Op 1 is thin, so the whole batch rolls back
+	result, err := h.HandleBatch(ctx, makeRequest(map[string]any{
+		"ops": []any{
+			map[string]any{"op": "delete", "name": "old"},
+			map[string]any{"op": "store", "name": "new", "capsule_text": "too thin"},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected error result for failing batch")
+	}
+	assertErrorCode(t, result, "CAPSULE_TOO_THIN")
+	fetchResult, _ := h.HandleFetch(ctx, makeRequest(map[string]any{"name": "old"}))
+	if fetchResult.IsError {
+		t.Errorf("rolled-back delete should leave capsule active: %v", extractErrorMessage(fetchResult))
+	}
+
+	// Valid batch commits both ops
+	result, err = h.HandleBatch(ctx, makeRequest(map[string]any{
+		"ops": []any{
+			map[string]any{"op": "delete", "name": "old"},
+			map[string]any{"op": "store", "name": "new", "capsule_text": validCapsuleText(), "tags": []any{"v2"}},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %v", extractErrorMessage(result))
+	}
+	var output struct {
+		Results []struct {
+			Op string `json:"op"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+		t.Fatalf("failed to unmarshal batch result: %v", err)
+	}
+	if len(output.Results) != 2 || output.Results[0].Op != "delete" || output.Results[1].Op != "store" {
+		t.Errorf("results = %+v, want [delete, store]", output.Results)
+	}
+
+	// Unknown op type is rejected
+	result, _ = h.HandleBatch(ctx, makeRequest(map[string]any{
+		"ops": []any{map[string]any{"op": "rename"}},
+	}))
+	if !result.IsError {
+		t.Fatal("expected error result for unknown op")
+	}
+	assertErrorCode(t, result, "INVALID_REQUEST")
+}
+
+// TestHandleFirst tests the first handler returns the oldest capsule.
+func TestHandleFirst(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	t.Run("empty workspace returns null", func(t *testing.T) {
+		req := makeRequest(map[string]any{"workspace": "empty"})
+		result, err := h.HandleFirst(ctx, req)
+		if err != nil {
+			t.Fatalf("handler returned error: %v", err)
+		}
+		output := parseOutput(t, result)
+		if output["item"] != nil {
+			t.Errorf("expected null item for empty workspace")
+		}
+	})
+
+	var firstID string
+	for i, name := range []string{"start", "end"} {
+		storeReq := makeRequest(map[string]any{
+			"capsule_text": validCapsuleText(),
+			"workspace":    "run",
+			"name":         name,
+		})
+		result, err := h.HandleStore(ctx, storeReq)
+		if err != nil {
+			t.Fatalf("setup store failed: %v", err)
+		}
+		id := parseOutput(t, result)["id"].(string)
+		if _, err := database.Exec("UPDATE capsules SET updated_at = ? WHERE id = ?", 1000*(i+1), id); err != nil {
+			t.Fatalf("failed to set updated_at: %v", err)
+		}
+		if i == 0 {
+			firstID = id
+		}
+	}
+
+	t.Run("returns oldest", func(t *testing.T) {
+		req := makeRequest(map[string]any{"workspace": "run", "include_text": true})
+		result, err := h.HandleFirst(ctx, req)
+		if err != nil {
+			t.Fatalf("handler returned error: %v", err)
+		}
+		output := parseOutput(t, result)
+		item := output["item"].(map[string]any)
+		if item["id"] != firstID {
+			t.Errorf("id = %v, want %s", item["id"], firstID)
+		}
+		if item["capsule_text"] == nil || item["capsule_text"] == "" {
+			t.Error("include_text:true should include capsule_text")
+		}
+	})
+}
+
+// TestHandleLatest tests the latest handler with contract assertions.
+func TestHandleLatest(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	// Test empty workspace returns null item
+	t.Run("empty workspace returns null", func(t *testing.T) {
+		req := makeRequest(map[string]any{"workspace": "empty"})
+		result, err := h.HandleLatest(ctx, req)
+		if err != nil {
+			t.Fatalf("handler returned error: %v", err)
+		}
+		output := parseOutput(t, result)
+		if output["item"] != nil {
+			t.Errorf("expected null item for empty workspace")
+		}
+	})
+
+	// Store a capsule, then delete it
+	storeReq := makeRequest(map[string]any{
+		"capsule_text": validCapsuleText(),
+		"workspace":    "test",
+		"name":         "latest-test",
+	})
+	if _, err := h.HandleStore(ctx, storeReq); err != nil {
+		t.Fatalf("setup store failed: %v", err)
+	}
+
+	// Test include_text:false (default) omits capsule_text
+	t.Run("include_text:false omits capsule_text", func(t *testing.T) {
+		req := makeRequest(map[string]any{
+			"workspace":    "test",
+			"include_text": false,
+		})
+		result, err := h.HandleLatest(ctx, req)
+		if err != nil {
+			t.Fatalf("handler returned error: %v", err)
+		}
+		output := parseOutput(t, result)
+		item := output["item"].(map[string]any)
+		if item["capsule_text"] != nil && item["capsule_text"] != "" {
+			t.Error("include_text:false should omit capsule_text")
+		}
+	})
+
+	// Test include_text:true includes capsule_text
+	t.Run("include_text:true includes capsule_text", func(t *testing.T) {
+		req := makeRequest(map[string]any{
+			"workspace":    "test",
 			"include_text": true,
 		})
 		result, err := h.HandleLatest(ctx, req)
@@ -988,104 +1481,697 @@ func TestHandleExportImport(t *testing.T) {
 	})
 	exportResult, err := h.HandleExport(ctx, exportReq)
 	if err != nil {
-		t.Fatalf("export handler returned error: %v", err)
+		t.Fatalf("export handler returned error: %v", err)
+	}
+	if exportResult.IsError {
+		t.Fatalf("export failed: %v", extractErrorMessage(exportResult))
+	}
+
+	// Verify export file exists
+	if _, err := os.Stat(exportPath); os.IsNotExist(err) {
+		t.Fatal("export file not created")
+	}
+
+	// Create new database for import test
+	database2, cfg2, cleanup2 := testSetup(t)
+	defer cleanup2()
+	h2 := NewHandlers(database2, cfg2)
+
+	// Import
+	importReq := makeRequest(map[string]any{
+		"path": exportPath,
+		"mode": "error",
+	})
+	importResult, err := h2.HandleImport(ctx, importReq)
+	if err != nil {
+		t.Fatalf("import handler returned error: %v", err)
+	}
+	if importResult.IsError {
+		t.Fatalf("import failed: %v", extractErrorMessage(importResult))
+	}
+
+	// Verify imported capsule exists
+	fetchReq := makeRequest(map[string]any{
+		"workspace": "test",
+		"name":      "export-test",
+	})
+	fetchResult, _ := h2.HandleFetch(ctx, fetchReq)
+	if fetchResult.IsError {
+		t.Error("imported capsule not found")
+	}
+}
+
+// TestHandlePurge tests the purge handler.
+func TestHandlePurge(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	// Store and delete a capsule
+	storeReq := makeRequest(map[string]any{
+		"capsule_text": validCapsuleText(),
+		"workspace":    "test",
+		"name":         "purge-test",
+	})
+	if _, err := h.HandleStore(ctx, storeReq); err != nil {
+		t.Fatalf("setup store failed: %v", err)
+	}
+
+	deleteReq := makeRequest(map[string]any{
+		"workspace": "test",
+		"name":      "purge-test",
+	})
+	if _, err := h.HandleDelete(ctx, deleteReq); err != nil {
+		t.Fatalf("setup delete failed: %v", err)
+	}
+
+	// Purge
+	purgeReq := makeRequest(map[string]any{})
+	purgeResult, err := h.HandlePurge(ctx, purgeReq)
+	if err != nil {
+		t.Fatalf("purge handler returned error: %v", err)
+	}
+	if purgeResult.IsError {
+		t.Fatalf("purge failed: %v", extractErrorMessage(purgeResult))
+	}
+
+	// Verify capsule is gone even with include_deleted
+	fetchReq := makeRequest(map[string]any{
+		"workspace":       "test",
+		"name":            "purge-test",
+		"include_deleted": true,
+	})
+	fetchResult, _ := h.HandleFetch(ctx, fetchReq)
+	if !fetchResult.IsError {
+		t.Error("purged capsule should not be found")
+	}
+}
+
+func TestHandlePruneDuplicates(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	for range 3 {
+		result, _ := h.HandleStore(ctx, makeRequest(map[string]any{
+			"capsule_text": validCapsuleText(),
+			"workspace":    "dups",
+		}))
+		if result.IsError {
+			t.Fatalf("setup store failed: %v", extractErrorMessage(result))
+		}
+	}
+
+	result, err := h.HandlePruneDuplicates(ctx, makeRequest(map[string]any{"dry_run": true}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %v", extractErrorMessage(result))
+	}
+	output := parseOutput(t, result)
+	if output["pruned"] != float64(2) || output["dry_run"] != true {
+		t.Errorf("dry run output = %v, want pruned=2 dry_run=true", output)
+	}
+	if groups, ok := output["groups"].([]any); !ok || len(groups) != 1 {
+		t.Errorf("groups = %v, want one group", output["groups"])
+	}
+
+	result, _ = h.HandlePruneDuplicates(ctx, makeRequest(map[string]any{"workspace": "dups"}))
+	if result.IsError {
+		t.Fatalf("expected success, got error: %v", extractErrorMessage(result))
+	}
+	output = parseOutput(t, result)
+	if output["pruned"] != float64(2) {
+		t.Errorf("pruned = %v, want 2", output["pruned"])
+	}
+	if _, ok := output["groups"]; ok {
+		t.Error("groups should be omitted outside dry run")
+	}
+}
+
+func TestHandleExportImportString(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	result, _ := h.HandleStore(ctx, makeRequest(map[string]any{
+		"capsule_text": validCapsuleText(),
+		"workspace":    "clip",
+		"name":         "board",
+	}))
+	if result.IsError {
+		t.Fatalf("setup store failed: %v", extractErrorMessage(result))
+	}
+
+	result, err := h.HandleExportString(ctx, makeRequest(map[string]any{"workspace": "clip"}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %v", extractErrorMessage(result))
+	}
+	output := parseOutput(t, result)
+	if output["count"] != float64(1) {
+		t.Errorf("count = %v, want 1", output["count"])
+	}
+	data, _ := output["data"].(string)
+
+	// Re-importing into the same database collides by default; rename keeps both
+	result, _ = h.HandleImportString(ctx, makeRequest(map[string]any{"data": data, "mode": "rename"}))
+	if result.IsError {
+		t.Fatalf("expected success, got error: %v", extractErrorMessage(result))
+	}
+	output = parseOutput(t, result)
+	if output["imported"] != float64(1) {
+		t.Errorf("imported = %v, want 1", output["imported"])
+	}
+
+	result, _ = h.HandleImportString(ctx, makeRequest(map[string]any{"data": "not base64!"}))
+	assertErrorCode(t, result, "INVALID_REQUEST")
+}
+
+// TestHandleBulkDelete tests the bulk_delete handler happy path.
+func TestHandleBulkDelete(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	// Store two capsules in target workspace and one in other
+	for _, ws := range []string{"target", "target", "other"} {
+		storeReq := makeRequest(map[string]any{
+			"capsule_text": validCapsuleText(),
+			"workspace":    ws,
+		})
+		if _, err := h.HandleStore(ctx, storeReq); err != nil {
+			t.Fatalf("setup store failed: %v", err)
+		}
+	}
+
+	// Bulk delete target workspace
+	req := makeRequest(map[string]any{
+		"workspace": "target",
+	})
+	result, err := h.HandleBulkDelete(ctx, req)
+	if err != nil {
+		t.Fatalf("bulk_delete handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("bulk_delete failed: %v", extractErrorMessage(result))
+	}
+
+	// Verify response JSON shape
+	var output map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	deleted, ok := output["deleted"].(float64)
+	if !ok || deleted != 2 {
+		t.Errorf("deleted = %v, want 2", output["deleted"])
+	}
+	message, ok := output["message"].(string)
+	if !ok || message == "" {
+		t.Error("message should be a non-empty string")
+	}
+
+	// Verify other workspace capsule still active
+	fetchReq := makeRequest(map[string]any{
+		"workspace": "other",
+	})
+	listResult, err := h.HandleList(ctx, fetchReq)
+	if err != nil {
+		t.Fatalf("list handler returned error: %v", err)
+	}
+	var listOutput map[string]any
+	if err := json.Unmarshal([]byte(listResult.Content[0].(mcp.TextContent).Text), &listOutput); err != nil {
+		t.Fatalf("failed to unmarshal list response: %v", err)
+	}
+	pagination := listOutput["pagination"].(map[string]any)
+	if total := pagination["total"].(float64); total != 1 {
+		t.Errorf("other workspace total = %v, want 1", total)
+	}
+}
+
+// TestHandleBulkRestore tests the bulk_restore handler response shape.
+func TestHandleBulkRestore(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	for _, ws := range []string{"target", "target"} {
+		storeReq := makeRequest(map[string]any{
+			"capsule_text": validCapsuleText(),
+			"workspace":    ws,
+		})
+		if _, err := h.HandleStore(ctx, storeReq); err != nil {
+			t.Fatalf("setup store failed: %v", err)
+		}
+	}
+	if _, err := h.HandleBulkDelete(ctx, makeRequest(map[string]any{"workspace": "target"})); err != nil {
+		t.Fatalf("setup bulk_delete failed: %v", err)
+	}
+
+	result, err := h.HandleBulkRestore(ctx, makeRequest(map[string]any{"workspace": "target"}))
+	if err != nil {
+		t.Fatalf("bulk_restore handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("bulk_restore failed: %v", extractErrorMessage(result))
+	}
+
+	var output map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if restored, ok := output["restored"].(float64); !ok || restored != 2 {
+		t.Errorf("restored = %v, want 2", output["restored"])
+	}
+	if skipped, ok := output["skipped"].(float64); !ok || skipped != 0 {
+		t.Errorf("skipped = %v, want 0", output["skipped"])
+	}
+
+	// No filters is rejected
+	result, err = h.HandleBulkRestore(ctx, makeRequest(map[string]any{}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if !result.IsError {
+		t.Error("expected error result for no filters")
+	}
+}
+
+// TestHandleBulkMove tests the bulk_move handler response shape and collision error.
+func TestHandleBulkMove(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	for _, ws := range []string{"src", "dst"} {
+		storeReq := makeRequest(map[string]any{
+			"capsule_text": validCapsuleText(),
+			"workspace":    ws,
+			"name":         "plan",
+		})
+		if _, err := h.HandleStore(ctx, storeReq); err != nil {
+			t.Fatalf("setup store failed: %v", err)
+		}
+	}
+
+	// Collides with dst/plan
+	result, err := h.HandleBulkMove(ctx, makeRequest(map[string]any{"workspace": "src", "to_workspace": "dst"}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	assertErrorCode(t, result, "NAME_ALREADY_EXISTS")
+
+	result, err = h.HandleBulkMove(ctx, makeRequest(map[string]any{"workspace": "src", "to_workspace": "archive"}))
+	if err != nil {
+		t.Fatalf("bulk_move handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("bulk_move failed: %v", extractErrorMessage(result))
+	}
+
+	var output map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if moved, ok := output["moved"].(float64); !ok || moved != 1 {
+		t.Errorf("moved = %v, want 1", output["moved"])
+	}
+	if output["workspace"] != "archive" {
+		t.Errorf("workspace = %v, want archive", output["workspace"])
+	}
+
+	// No filters is rejected
+	result, err = h.HandleBulkMove(ctx, makeRequest(map[string]any{"to_workspace": "archive"}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	assertErrorCode(t, result, "INVALID_REQUEST")
+}
+
+// TestHandleChanges tests the changes handler response shape.
+func TestHandleChanges(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	storeReq := makeRequest(map[string]any{
+		"capsule_text": validCapsuleText(),
+		"name":         "synced",
+	})
+	if _, err := h.HandleStore(ctx, storeReq); err != nil {
+		t.Fatalf("setup store failed: %v", err)
+	}
+
+	result, err := h.HandleChanges(ctx, makeRequest(map[string]any{"since": 0}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("changes failed: %v", extractErrorMessage(result))
+	}
+
+	var output map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	items, ok := output["items"].([]any)
+	if !ok || len(items) != 1 {
+		t.Fatalf("items = %v, want 1 record", output["items"])
+	}
+	if item := items[0].(map[string]any); item["name_raw"] != "synced" || item["capsule_text"] == "" {
+		t.Errorf("item = %v, want the full synced record", item)
+	}
+	if output["has_more"] != false || output["next_cursor"] == "" {
+		t.Errorf("has_more = %v, next_cursor = %v; want false and a cursor", output["has_more"], output["next_cursor"])
+	}
+
+	result, err = h.HandleChanges(ctx, makeRequest(map[string]any{"since": -5}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	assertErrorCode(t, result, "INVALID_REQUEST")
+}
+
+// TestHandleGetSetConfig tests reading config and changing it at runtime.
+// TestDefaultIncludeDeleted tests that config.DefaultIncludeDeleted applies to
+// reads that omit include_deleted, and that an explicit false overrides it.
+func TestDefaultIncludeDeleted(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+	cfg.DefaultIncludeDeleted = true
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	result, _ := h.HandleStore(ctx, makeRequest(map[string]any{
+		"capsule_text": validCapsuleText(),
+		"name":         "gone",
+	}))
+	if result.IsError {
+		t.Fatalf("setup store failed: %v", extractErrorMessage(result))
+	}
+	result, _ = h.HandleDelete(ctx, makeRequest(map[string]any{"name": "gone"}))
+	if result.IsError {
+		t.Fatalf("setup delete failed: %v", extractErrorMessage(result))
+	}
+
+	countItems := func(result *mcp.CallToolResult) int {
+		t.Helper()
+		var output struct {
+			Items []json.RawMessage `json:"items"`
+			Item  json.RawMessage   `json:"item"`
+		}
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+			t.Fatalf("failed to unmarshal result: %v", err)
+		}
+		if output.Items != nil {
+			return len(output.Items)
+		}
+		if len(output.Item) > 0 && string(output.Item) != "null" {
+			return 1
+		}
+		return 0
+	}
+
+	reads := []struct {
+		name    string
+		handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
+		args    map[string]any
+	}{
+		{"list", h.HandleList, map[string]any{}},
+		{"inventory", h.HandleInventory, map[string]any{}},
+		{"latest", h.HandleLatest, map[string]any{}},
+		{"search", h.HandleSearch, map[string]any{"query": "objective"}},
+	}
+	for _, r := range reads {
+		t.Run(r.name, func(t *testing.T) {
+			result, _ := r.handler(ctx, makeRequest(r.args))
+			if result.IsError {
+				t.Fatalf("default read failed: %v", extractErrorMessage(result))
+			}
+			if n := countItems(result); n != 1 {
+				t.Errorf("default read returned %d items, want the deleted capsule", n)
+			}
+
+			args := map[string]any{"include_deleted": false}
+			for k, v := range r.args {
+				args[k] = v
+			}
+			result, _ = r.handler(ctx, makeRequest(args))
+			if result.IsError {
+				t.Fatalf("explicit false read failed: %v", extractErrorMessage(result))
+			}
+			if n := countItems(result); n != 0 {
+				t.Errorf("include_deleted:false returned %d items, want 0", n)
+			}
+		})
+	}
+
+	t.Run("fetch", func(t *testing.T) {
+		result, _ := h.HandleFetch(ctx, makeRequest(map[string]any{"name": "gone"}))
+		if result.IsError {
+			t.Errorf("default fetch should find the deleted capsule: %v", extractErrorMessage(result))
+		}
+		result, _ = h.HandleFetch(ctx, makeRequest(map[string]any{"name": "gone", "include_deleted": false}))
+		if !result.IsError {
+			t.Fatal("include_deleted:false fetch should not find the deleted capsule")
+		}
+		assertErrorCode(t, result, "NOT_FOUND")
+	})
+}
+
+func TestHandleGetSetConfig(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	h.configDir = t.TempDir()
+	ctx := context.Background()
+
+	getConfig := func() map[string]any {
+		t.Helper()
+		result, err := h.HandleGetConfig(ctx, makeRequest(map[string]any{}))
+		if err != nil {
+			t.Fatalf("handler returned error: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("get_config failed: %v", extractErrorMessage(result))
+		}
+		return parseOutput(t, result)
+	}
+
+	output := getConfig()
+	if got := output["config"].(map[string]any)["capsule_max_chars"]; got != float64(12000) {
+		t.Errorf("capsule_max_chars = %v, want 12000", got)
+	}
+	if keys, ok := output["runtime_keys"].([]any); !ok || len(keys) != len(config.RuntimeKeys) {
+		t.Errorf("runtime_keys = %v, want %v", output["runtime_keys"], config.RuntimeKeys)
+	}
+
+	// Allowed key: applies immediately and is saved to config.json
+	result, err := h.HandleSetConfig(ctx, makeRequest(map[string]any{"key": "capsule_max_chars", "value": 100}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("set_config failed: %v", extractErrorMessage(result))
+	}
+	if got := getConfig()["config"].(map[string]any)["capsule_max_chars"]; got != float64(100) {
+		t.Errorf("capsule_max_chars after set = %v, want 100", got)
+	}
+	storeResult, err := h.HandleStore(ctx, makeRequest(map[string]any{"capsule_text": validCapsuleText()}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	assertErrorCode(t, storeResult, "CAPSULE_TOO_LARGE")
+
+	saved, err := config.Load(h.configDir)
+	if err != nil {
+		t.Fatalf("config.Load failed: %v", err)
+	}
+	if saved.CapsuleMaxChars != 100 {
+		t.Errorf("saved capsule_max_chars = %d, want 100", saved.CapsuleMaxChars)
+	}
+	if cfg.CapsuleMaxChars != 12000 {
+		t.Errorf("original config was modified: capsule_max_chars = %d", cfg.CapsuleMaxChars)
+	}
+
+	// Disallowed keys are rejected and change nothing
+	for _, args := range []map[string]any{
+		{"key": "allowed_paths", "value": []any{"/"}},
+		{"key": "disabled_tools", "value": []any{"capsule_store"}},
+		{"key": "fts_tokenizer", "value": "porter"},
+		{"key": "capsule_max_chars", "value": "lots"},
+		{"key": "capsule_max_chars", "value": 0},
+	} {
+		result, err := h.HandleSetConfig(ctx, makeRequest(args))
+		if err != nil {
+			t.Fatalf("handler returned error: %v", err)
+		}
+		assertErrorCode(t, result, "INVALID_REQUEST")
+	}
+	if got := getConfig()["config"].(map[string]any)["capsule_max_chars"]; got != float64(100) {
+		t.Errorf("capsule_max_chars after rejected sets = %v, want 100", got)
+	}
+}
+
+// TestHandleRenameTag tests the rename_tag handler response shape.
+func TestHandleRenameTag(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	storeReq := makeRequest(map[string]any{
+		"capsule_text": validCapsuleText(),
+		"workspace":    "tags",
+		"tags":         []any{"old", "keep"},
+	})
+	if _, err := h.HandleStore(ctx, storeReq); err != nil {
+		t.Fatalf("setup store failed: %v", err)
+	}
+
+	result, err := h.HandleRenameTag(ctx, makeRequest(map[string]any{"old_tag": "old", "new_tag": "keep"}))
+	if err != nil {
+		t.Fatalf("rename_tag handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("rename_tag failed: %v", extractErrorMessage(result))
+	}
+
+	var output map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if renamed, ok := output["renamed"].(float64); !ok || renamed != 1 {
+		t.Errorf("renamed = %v, want 1", output["renamed"])
+	}
+
+	// Missing new_tag is rejected
+	result, err = h.HandleRenameTag(ctx, makeRequest(map[string]any{"old_tag": "keep"}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
 	}
-	if exportResult.IsError {
-		t.Fatalf("export failed: %v", extractErrorMessage(exportResult))
+	if !result.IsError {
+		t.Error("expected error result for missing new_tag")
 	}
+}
 
-	// Verify export file exists
-	if _, err := os.Stat(exportPath); os.IsNotExist(err) {
-		t.Fatal("export file not created")
-	}
+func TestHandleReindex(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
 
-	// Create new database for import test
-	database2, cfg2, cleanup2 := testSetup(t)
-	defer cleanup2()
-	h2 := NewHandlers(database2, cfg2)
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
 
-	// Import
-	importReq := makeRequest(map[string]any{
-		"path": exportPath,
-		"mode": "error",
-	})
-	importResult, err := h2.HandleImport(ctx, importReq)
+	result, err := h.HandleReindex(ctx, makeRequest(map[string]any{"force": true}))
 	if err != nil {
-		t.Fatalf("import handler returned error: %v", err)
+		t.Fatalf("reindex handler returned error: %v", err)
 	}
-	if importResult.IsError {
-		t.Fatalf("import failed: %v", extractErrorMessage(importResult))
+	if result.IsError {
+		t.Fatalf("reindex failed: %v", extractErrorMessage(result))
 	}
 
-	// Verify imported capsule exists
-	fetchReq := makeRequest(map[string]any{
-		"workspace": "test",
-		"name":      "export-test",
-	})
-	fetchResult, _ := h2.HandleFetch(ctx, fetchReq)
-	if fetchResult.IsError {
-		t.Error("imported capsule not found")
+	var output map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if rebuilt, ok := output["rebuilt"].(bool); !ok || !rebuilt {
+		t.Errorf("rebuilt = %v, want true", output["rebuilt"])
+	}
+
+	// check_only and force together are rejected
+	result, err = h.HandleReindex(ctx, makeRequest(map[string]any{"check_only": true, "force": true}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	if !result.IsError {
+		t.Error("expected error result for check_only+force")
 	}
 }
 
-// TestHandlePurge tests the purge handler.
-func TestHandlePurge(t *testing.T) {
+// TestHandleBulkDelete_NoFilters tests that empty arguments return INVALID_REQUEST.
+// TestHandleDeleteMany tests the delete_many handler with a mix of IDs.
+func TestHandleDeleteMany(t *testing.T) {
 	database, cfg, cleanup := testSetup(t)
 	defer cleanup()
 
 	h := NewHandlers(database, cfg)
 	ctx := context.Background()
 
-	// Store and delete a capsule
-	storeReq := makeRequest(map[string]any{
+	storeResult, err := h.HandleStore(ctx, makeRequest(map[string]any{
 		"capsule_text": validCapsuleText(),
-		"workspace":    "test",
-		"name":         "purge-test",
-	})
-	if _, err := h.HandleStore(ctx, storeReq); err != nil {
+	}))
+	if err != nil {
 		t.Fatalf("setup store failed: %v", err)
 	}
-
-	deleteReq := makeRequest(map[string]any{
-		"workspace": "test",
-		"name":      "purge-test",
-	})
-	if _, err := h.HandleDelete(ctx, deleteReq); err != nil {
-		t.Fatalf("setup delete failed: %v", err)
+	var stored map[string]any
+	if err := json.Unmarshal([]byte(storeResult.Content[0].(mcp.TextContent).Text), &stored); err != nil {
+		t.Fatalf("failed to unmarshal store response: %v", err)
 	}
+	id := stored["id"].(string)
 
-	// Purge
-	purgeReq := makeRequest(map[string]any{})
-	purgeResult, err := h.HandlePurge(ctx, purgeReq)
+	result, err := h.HandleDeleteMany(ctx, makeRequest(map[string]any{
+		"ids": []any{id, "01MISSING000000000000000000"},
+	}))
 	if err != nil {
-		t.Fatalf("purge handler returned error: %v", err)
+		t.Fatalf("delete_many handler returned error: %v", err)
 	}
-	if purgeResult.IsError {
-		t.Fatalf("purge failed: %v", extractErrorMessage(purgeResult))
+	if result.IsError {
+		t.Fatalf("delete_many failed: %v", extractErrorMessage(result))
 	}
 
-	// Verify capsule is gone even with include_deleted
-	fetchReq := makeRequest(map[string]any{
-		"workspace":       "test",
-		"name":            "purge-test",
-		"include_deleted": true,
-	})
-	fetchResult, _ := h.HandleFetch(ctx, fetchReq)
-	if !fetchResult.IsError {
-		t.Error("purged capsule should not be found")
+	var output struct {
+		Items []struct {
+			ID      string `json:"id"`
+			Deleted bool   `json:"deleted"`
+			Error   *struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		} `json:"items"`
+		Deleted int `json:"deleted"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if output.Deleted != 1 || len(output.Items) != 2 {
+		t.Fatalf("output = %+v, want 1 deleted of 2 items", output)
+	}
+	if !output.Items[0].Deleted || output.Items[0].Error != nil {
+		t.Errorf("Items[0] = %+v, want deleted without error", output.Items[0])
+	}
+	if output.Items[1].Deleted || output.Items[1].Error == nil || output.Items[1].Error.Code != "NOT_FOUND" {
+		t.Errorf("Items[1] = %+v, want NOT_FOUND", output.Items[1])
 	}
 }
 
-// TestHandleBulkDelete tests the bulk_delete handler happy path.
-func TestHandleBulkDelete(t *testing.T) {
+func TestHandleBulkDelete_DryRun(t *testing.T) {
 	database, cfg, cleanup := testSetup(t)
 	defer cleanup()
 
 	h := NewHandlers(database, cfg)
 	ctx := context.Background()
 
-	// Store two capsules in target workspace and one in other
-	for _, ws := range []string{"target", "target", "other"} {
+	for _, ws := range []string{"target", "target"} {
 		storeReq := makeRequest(map[string]any{
 			"capsule_text": validCapsuleText(),
 			"workspace":    ws,
@@ -1095,11 +2181,10 @@ func TestHandleBulkDelete(t *testing.T) {
 		}
 	}
 
-	// Bulk delete target workspace
-	req := makeRequest(map[string]any{
+	result, err := h.HandleBulkDelete(ctx, makeRequest(map[string]any{
 		"workspace": "target",
-	})
-	result, err := h.HandleBulkDelete(ctx, req)
+		"dry_run":   true,
+	}))
 	if err != nil {
 		t.Fatalf("bulk_delete handler returned error: %v", err)
 	}
@@ -1107,40 +2192,23 @@ func TestHandleBulkDelete(t *testing.T) {
 		t.Fatalf("bulk_delete failed: %v", extractErrorMessage(result))
 	}
 
-	// Verify response JSON shape
 	var output map[string]any
 	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
 		t.Fatalf("failed to unmarshal response: %v", err)
 	}
-
-	deleted, ok := output["deleted"].(float64)
-	if !ok || deleted != 2 {
-		t.Errorf("deleted = %v, want 2", output["deleted"])
-	}
-	message, ok := output["message"].(string)
-	if !ok || message == "" {
-		t.Error("message should be a non-empty string")
+	if wouldAffect, ok := output["would_affect"].(float64); !ok || wouldAffect != 2 {
+		t.Errorf("would_affect = %v, want 2", output["would_affect"])
 	}
 
-	// Verify other workspace capsule still active
-	fetchReq := makeRequest(map[string]any{
-		"workspace": "other",
-	})
-	listResult, err := h.HandleList(ctx, fetchReq)
-	if err != nil {
-		t.Fatalf("list handler returned error: %v", err)
-	}
-	var listOutput map[string]any
-	if err := json.Unmarshal([]byte(listResult.Content[0].(mcp.TextContent).Text), &listOutput); err != nil {
-		t.Fatalf("failed to unmarshal list response: %v", err)
+	var active int
+	if err := database.QueryRow("SELECT COUNT(*) FROM capsules WHERE deleted_at IS NULL").Scan(&active); err != nil {
+		t.Fatalf("count failed: %v", err)
 	}
-	pagination := listOutput["pagination"].(map[string]any)
-	if total := pagination["total"].(float64); total != 1 {
-		t.Errorf("other workspace total = %v, want 1", total)
+	if active != 2 {
+		t.Errorf("active = %d after dry run, want 2", active)
 	}
 }
 
-// TestHandleBulkDelete_NoFilters tests that empty arguments return INVALID_REQUEST.
 func TestHandleBulkDelete_NoFilters(t *testing.T) {
 	database, cfg, cleanup := testSetup(t)
 	defer cleanup()
@@ -1216,6 +2284,69 @@ func TestHandleBulkUpdate(t *testing.T) {
 }
 
 // TestHandleBulkUpdate_NoFilters tests that empty filters return INVALID_REQUEST.
+func TestHandleBulkAddRemoveTags(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	storeReq := makeRequest(map[string]any{
+		"capsule_text": validCapsuleText(),
+		"workspace":    "target",
+		"name":         "tagged",
+		"tags":         []any{"keep"},
+	})
+	if result, err := h.HandleStore(ctx, storeReq); err != nil || result.IsError {
+		t.Fatalf("setup store failed: %v", err)
+	}
+
+	result, err := h.HandleBulkAddTags(ctx, makeRequest(map[string]any{
+		"workspace": "target",
+		"tags":      []any{"review", "q3"},
+	}))
+	if err != nil {
+		t.Fatalf("bulk_add_tags handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("bulk_add_tags failed: %v", extractErrorMessage(result))
+	}
+
+	result, err = h.HandleBulkRemoveTags(ctx, makeRequest(map[string]any{
+		"workspace": "target",
+		"tags":      []any{"review"},
+	}))
+	if err != nil {
+		t.Fatalf("bulk_remove_tags handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("bulk_remove_tags failed: %v", extractErrorMessage(result))
+	}
+
+	var output map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if output["updated"] != float64(1) {
+		t.Errorf("updated = %v, want 1", output["updated"])
+	}
+
+	c, err := db.GetByName(ctx, database, "target", "tagged", false)
+	if err != nil {
+		t.Fatalf("GetByName failed: %v", err)
+	}
+	if strings.Join(c.Tags, ",") != "keep,q3" {
+		t.Errorf("tags = %v, want [keep q3]", c.Tags)
+	}
+
+	// No filter is rejected
+	result, err = h.HandleBulkAddTags(ctx, makeRequest(map[string]any{"tags": []any{"x"}}))
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	assertErrorCode(t, result, "INVALID_REQUEST")
+}
+
 func TestHandleBulkUpdate_NoFilters(t *testing.T) {
 	database, cfg, cleanup := testSetup(t)
 	defer cleanup()
@@ -1263,7 +2394,7 @@ func TestServerRegistration(t *testing.T) {
 	database, cfg, cleanup := testSetup(t)
 	defer cleanup()
 
-	s := NewServer(database, cfg, "test")
+	s := NewServer(database, cfg, t.TempDir(), "test")
 	tools := s.ListTools()
 	if tools == nil {
 		t.Fatal("expected tools to be registered, got nil")
@@ -1273,19 +2404,46 @@ func TestServerRegistration(t *testing.T) {
 		"capsule_store",
 		"capsule_fetch",
 		"capsule_fetch_many",
+		"capsule_exists",
+		"capsule_fetch_record",
 		"capsule_update",
 		"capsule_delete",
+		"capsule_delete_many",
 		"capsule_latest",
+		"capsule_first",
 		"capsule_list",
 		"capsule_inventory",
 		"capsule_search",
+		"capsule_changes",
 		"capsule_export",
 		"capsule_import",
+		"capsule_export_string",
+		"capsule_import_string",
 		"capsule_purge",
+		"capsule_prune_duplicates",
+		"capsule_reindex",
 		"capsule_bulk_delete",
+		"capsule_bulk_restore",
+		"capsule_bulk_move",
+		"capsule_rename_tag",
 		"capsule_bulk_update",
+		"capsule_bulk_add_tags",
+		"capsule_bulk_remove_tags",
 		"capsule_compose",
 		"capsule_append",
+		"capsule_patch",
+		"capsule_link",
+		"capsule_unlink",
+		"capsule_links",
+		"capsule_bundles",
+		"capsule_related",
+		"capsule_top_terms",
+		"capsule_batch",
+		"capsule_get_config",
+		"capsule_set_config",
+		"capsule_list_archived",
+		"capsule_restore_archived",
+		"capsule_recently_deleted",
 	}
 
 	if len(tools) != len(expectedTools) {
@@ -1304,12 +2462,12 @@ func TestServerRegistration_WithDisabledTools(t *testing.T) {
 	defer cleanup()
 
 	cfg.DisabledTools = []string{"capsule_purge", "capsule_bulk_delete", "capsule_bulk_update"}
-	s := NewServer(database, cfg, "test")
+	s := NewServer(database, cfg, t.TempDir(), "test")
 	tools := s.ListTools()
 
-	// Should have 13 tools (16 - 3 disabled)
-	if len(tools) != 13 {
-		t.Errorf("registered tool count = %d, want 13", len(tools))
+	// Should have 40 tools (43 - 3 disabled)
+	if len(tools) != 40 {
+		t.Errorf("registered tool count = %d, want 40", len(tools))
 	}
 
 	// Disabled tools should not be registered
@@ -1333,7 +2491,7 @@ func TestServerRegistration_AllToolsDisabled(t *testing.T) {
 
 	// Disable all tools
 	cfg.DisabledTools = AllToolNames()
-	s := NewServer(database, cfg, "test")
+	s := NewServer(database, cfg, t.TempDir(), "test")
 	tools := s.ListTools()
 
 	if len(tools) != 0 {
@@ -1347,12 +2505,12 @@ func TestServerRegistration_DuplicateDisabled(t *testing.T) {
 
 	// Duplicates should be handled gracefully (map lookup)
 	cfg.DisabledTools = []string{"capsule_purge", "capsule_purge", "capsule_purge"}
-	s := NewServer(database, cfg, "test")
+	s := NewServer(database, cfg, t.TempDir(), "test")
 	tools := s.ListTools()
 
-	// Should have 15 tools (16 - 1 disabled, duplicates ignored)
-	if len(tools) != 15 {
-		t.Errorf("registered tool count = %d, want 15", len(tools))
+	// Should have 42 tools (43 - 1 disabled, duplicates ignored)
+	if len(tools) != 42 {
+		t.Errorf("registered tool count = %d, want 42", len(tools))
 	}
 
 	if _, ok := tools["capsule_purge"]; ok {
@@ -1401,9 +2559,9 @@ func TestValidateDisabledTools(t *testing.T) {
 func TestAllToolNames(t *testing.T) {
 	names := AllToolNames()
 
-	// Should return 16 tool names
-	if len(names) != 16 {
-		t.Errorf("AllToolNames() returned %d names, want 16", len(names))
+	// Should return 43 tool names
+	if len(names) != 43 {
+		t.Errorf("AllToolNames() returned %d names, want 43", len(names))
 	}
 
 	// All returned names should be valid
@@ -1503,7 +2661,7 @@ func TestExpandTypesToTools(t *testing.T) {
 		{
 			name:    "capsule type",
 			types:   []string{"capsule"},
-			wantLen: 16, // All current tools are capsule_*
+			wantLen: 43, // All current tools are capsule_*
 		},
 		{
 			name:    "unknown type",
@@ -1538,7 +2696,7 @@ func TestServerRegistration_DisabledType(t *testing.T) {
 
 	// Disable entire capsule type
 	cfg.DisabledTypes = []string{"capsule"}
-	s := NewServer(database, cfg, "test")
+	s := NewServer(database, cfg, t.TempDir(), "test")
 	tools := s.ListTools()
 
 	// All tools should be disabled (all are capsule_*)
@@ -1554,7 +2712,7 @@ func TestServerRegistration_DisabledTypeAndTool(t *testing.T) {
 	// Disable type and also list a tool (redundant but valid)
 	cfg.DisabledTypes = []string{"capsule"}
 	cfg.DisabledTools = []string{"capsule_store"}
-	s := NewServer(database, cfg, "test")
+	s := NewServer(database, cfg, t.TempDir(), "test")
 	tools := s.ListTools()
 
 	// All tools should be disabled
@@ -1695,3 +2853,225 @@ func extractErrorMessage(result *mcp.CallToolResult) string {
 
 	return text.Text
 }
+
+// TestEnvelopeResponses tests the {tool, ok, data, error} wrapper on success
+// and error results from two different tools.
+func TestEnvelopeResponses(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+	cfg.EnvelopeResponses = true
+
+	s := NewServer(database, cfg, t.TempDir(), "test")
+	tools := s.ListTools()
+	ctx := context.Background()
+
+	call := func(tool string, args map[string]any) (*mcp.CallToolResult, map[string]any) {
+		t.Helper()
+		result, err := tools[tool].Handler(ctx, makeRequest(args))
+		if err != nil {
+			t.Fatalf("%s handler returned error: %v", tool, err)
+		}
+		var env map[string]any
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &env); err != nil {
+			t.Fatalf("failed to unmarshal envelope: %v", err)
+		}
+		for _, key := range []string{"tool", "ok", "data", "error"} {
+			if _, ok := env[key]; !ok {
+				t.Errorf("%s envelope missing %q: %v", tool, key, env)
+			}
+		}
+		if env["tool"] != tool {
+			t.Errorf("tool = %v, want %s", env["tool"], tool)
+		}
+		return result, env
+	}
+
+	// Success: store
+	result, env := call("capsule_store", map[string]any{"capsule_text": validCapsuleText(), "name": "env"})
+	if result.IsError || env["ok"] != true || env["error"] != nil {
+		t.Fatalf("store envelope = %v, want ok with null error", env)
+	}
+	data, ok := env["data"].(map[string]any)
+	if !ok || data["id"] == nil {
+		t.Errorf("store data = %v, want the usual store payload", env["data"])
+	}
+
+	// Success: inventory (a differently shaped payload under the same envelope)
+	result, env = call("capsule_inventory", map[string]any{})
+	if result.IsError || env["ok"] != true {
+		t.Fatalf("inventory envelope = %v, want ok", env)
+	}
+	if data, ok := env["data"].(map[string]any); !ok || data["items"] == nil {
+		t.Errorf("inventory data = %v, want items", env["data"])
+	}
+
+	// Errors: fetch (not found) and inventory (invalid input)
+	for _, tc := range []struct {
+		tool string
+		args map[string]any
+		code string
+	}{
+		{"capsule_fetch", map[string]any{"workspace": "default", "name": "missing"}, "NOT_FOUND"},
+		{"capsule_inventory", map[string]any{"limit": "ten"}, "INVALID_REQUEST"},
+	} {
+		result, env := call(tc.tool, tc.args)
+		if !result.IsError || env["ok"] != false || env["data"] != nil {
+			t.Errorf("%s envelope = %v, want IsError with ok:false and null data", tc.tool, env)
+		}
+		errObj, ok := env["error"].(map[string]any)
+		if !ok || errObj["code"] != tc.code {
+			t.Errorf("%s error = %v, want code %s", tc.tool, env["error"], tc.code)
+		}
+	}
+}
+
+// TestEnvelopeResponses_DefaultOff tests that results are unwrapped by default.
+func TestEnvelopeResponses_DefaultOff(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	s := NewServer(database, cfg, t.TempDir(), "test")
+	result, err := s.ListTools()["capsule_inventory"].Handler(context.Background(), makeRequest(map[string]any{}))
+	if err != nil {
+		t.Fatalf("inventory handler returned error: %v", err)
+	}
+	var output map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &output); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if _, ok := output["items"]; !ok {
+		t.Errorf("output = %v, want the bare inventory payload", output)
+	}
+	if _, ok := output["ok"]; ok {
+		t.Error("envelope should be off by default")
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+	cfg.MaxResponseBytes = 5000
+
+	s := NewServer(database, cfg, t.TempDir(), "test")
+	tools := s.ListTools()
+	ctx := context.Background()
+
+	// Small responses pass through untouched
+	bigText := validCapsuleText() + "\n" + strings.Repeat("padding ", 300)
+	var items []any
+	for _, name := range []string{"a", "b", "c"} {
+		result, err := tools["capsule_store"].Handler(ctx, makeRequest(map[string]any{
+			"capsule_text": bigText,
+			"name":         name,
+		}))
+		if err != nil {
+			t.Fatalf("store handler returned error: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("store failed: %v", extractErrorMessage(result))
+		}
+		items = append(items, map[string]any{"workspace": "default", "name": name})
+	}
+
+	// One capsule fits under the cap
+	result, err := tools["capsule_fetch_many"].Handler(ctx, makeRequest(map[string]any{"items": items[:1]}))
+	if err != nil {
+		t.Fatalf("fetch_many handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("single-item fetch_many failed: %v", extractErrorMessage(result))
+	}
+
+	// Three don't
+	result, err = tools["capsule_fetch_many"].Handler(ctx, makeRequest(map[string]any{"items": items}))
+	if err != nil {
+		t.Fatalf("fetch_many handler returned error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected RESPONSE_TOO_LARGE for oversized fetch_many")
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &payload); err != nil {
+		t.Fatalf("failed to unmarshal error: %v", err)
+	}
+	errObj := payload["error"].(map[string]any)
+	if errObj["code"] != "RESPONSE_TOO_LARGE" {
+		t.Errorf("code = %v, want RESPONSE_TOO_LARGE", errObj["code"])
+	}
+	details := errObj["details"].(map[string]any)
+	if details["max_bytes"] != float64(5000) {
+		t.Errorf("max_bytes = %v, want 5000", details["max_bytes"])
+	}
+	if actual, _ := details["actual_bytes"].(float64); actual <= 5000 {
+		t.Errorf("actual_bytes = %v, want > 5000", details["actual_bytes"])
+	}
+}
+
+func TestServer_CapsuleResources(t *testing.T) {
+	database, cfg, cleanup := testSetup(t)
+	defer cleanup()
+
+	h := NewHandlers(database, cfg)
+	ctx := context.Background()
+
+	var unnamedID string
+	for _, args := range []map[string]any{
+		{"workspace": "Proj", "name": "Auth", "capsule_text": validCapsuleText()},
+		{"workspace": "proj", "capsule_text": validCapsuleText()},
+	} {
+		result, _ := h.HandleStore(ctx, makeRequest(args))
+		if result.IsError {
+			t.Fatalf("setup store failed: %v", extractErrorMessage(result))
+		}
+		unnamedID = parseOutput(t, result)["id"].(string)
+	}
+
+	s := NewServer(database, cfg, t.TempDir(), "test")
+	call := func(method string, params map[string]any) map[string]any {
+		t.Helper()
+		msg, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+		resp, _ := json.Marshal(s.HandleMessage(ctx, msg))
+		var out map[string]any
+		if err := json.Unmarshal(resp, &out); err != nil {
+			t.Fatalf("unmarshal %s response: %v", method, err)
+		}
+		return out
+	}
+
+	list := call("resources/list", map[string]any{})
+	result, ok := list["result"].(map[string]any)
+	if !ok {
+		t.Fatalf("resources/list failed: %v", list)
+	}
+	uris := map[string]bool{}
+	for _, r := range result["resources"].([]any) {
+		uris[r.(map[string]any)["uri"].(string)] = true
+	}
+	namedURI, unnamedURI := "moss://proj/auth", "moss://proj/"+unnamedID
+	if !uris[namedURI] || !uris[unnamedURI] {
+		t.Fatalf("resources = %v, want %s and %s", uris, namedURI, unnamedURI)
+	}
+
+	for _, uri := range []string{namedURI, unnamedURI} {
+		read := call("resources/read", map[string]any{"uri": uri})
+		result, ok := read["result"].(map[string]any)
+		if !ok {
+			t.Fatalf("resources/read %s failed: %v", uri, read)
+		}
+		text := result["contents"].([]any)[0].(map[string]any)["text"].(string)
+		if !strings.HasPrefix(text, "## Objective") {
+			t.Errorf("read %s text = %q, want capsule text", uri, text)
+		}
+	}
+
+	if read := call("resources/read", map[string]any{"uri": "moss://proj/missing"}); read["error"] == nil {
+		t.Errorf("reading a missing capsule should fail: %v", read)
+	}
+
+	// Disabling the capsule type drops the resources
+	cfg.DisabledTypes = []string{"capsule"}
+	s = NewServer(database, cfg, t.TempDir(), "test")
+	if list := call("resources/list", map[string]any{}); list["error"] == nil {
+		t.Errorf("resources/list should be unsupported with capsules disabled: %v", list)
+	}
+}
@@ -0,0 +1,120 @@
+package mcp
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// maxResourceList caps how many capsules resources/list returns (most recently updated first).
+const maxResourceList = 500
+
+// capsuleResourceTemplate matches capsule URIs for resources/read.
+// Named capsules use moss://{workspace}/{name}; unnamed ones use their ID as the name segment.
+var capsuleResourceTemplate = mcp.NewResourceTemplate(
+	"moss://{workspace}/{name}",
+	"capsule",
+	mcp.WithTemplateDescription("A Moss capsule addressed by workspace and name (or ID for unnamed capsules)"),
+	mcp.WithTemplateMIMEType("text/markdown"),
+)
+
+// capsuleURI builds the resource URI for a capsule from its normalized address.
+func capsuleURI(workspaceNorm string, nameNorm *string, id string) string {
+	name := id
+	if nameNorm != nil {
+		name = *nameNorm
+	}
+	return "moss://" + url.PathEscape(workspaceNorm) + "/" + url.PathEscape(name)
+}
+
+// ListResources returns active capsules as MCP resources, most recently updated first.
+func (h *Handlers) ListResources(ctx context.Context) ([]mcp.Resource, error) {
+	summaries, _, err := db.ListAll(ctx, h.db, db.InventoryFilters{}, maxResourceList, 0, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]mcp.Resource, 0, len(summaries))
+	for _, s := range summaries {
+		name := s.ID
+		if s.Name != nil {
+			name = *s.Name
+		}
+		if s.Title != nil {
+			name = *s.Title
+		}
+		resources = append(resources, mcp.NewResource(
+			capsuleURI(s.WorkspaceNorm, s.NameNorm, s.ID),
+			name,
+			mcp.WithResourceDescription("Capsule in workspace "+s.Workspace),
+			mcp.WithMIMEType("text/markdown"),
+		))
+	}
+	return resources, nil
+}
+
+// HandleReadResource returns the text of the capsule addressed by a moss:// URI.
+// The name segment is looked up as a capsule name first, then as an ID within
+// the same workspace.
+func (h *Handlers) HandleReadResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	uri := req.Params.URI
+	rest, ok := strings.CutPrefix(uri, "moss://")
+	if !ok {
+		return nil, errors.NewInvalidRequest("resource URI must start with moss://")
+	}
+	wsSeg, nameSeg, ok := strings.Cut(rest, "/")
+	if !ok || wsSeg == "" || nameSeg == "" || strings.Contains(nameSeg, "/") {
+		return nil, errors.NewInvalidRequest("resource URI must be moss://{workspace}/{name}")
+	}
+	workspace, err := url.PathUnescape(wsSeg)
+	if err != nil {
+		return nil, errors.NewInvalidRequest("invalid workspace in resource URI")
+	}
+	name, err := url.PathUnescape(nameSeg)
+	if err != nil {
+		return nil, errors.NewInvalidRequest("invalid name in resource URI")
+	}
+	workspaceNorm := capsule.Normalize(workspace)
+
+	c, err := db.GetByName(ctx, h.db, workspaceNorm, capsule.Normalize(name), false)
+	if errors.Is(err, errors.ErrNotFound) {
+		// Unnamed capsules are listed by ID
+		byID, idErr := db.GetByID(ctx, h.db, name, false)
+		if idErr == nil && byID.WorkspaceNorm == workspaceNorm {
+			c, err = byID, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: "text/markdown",
+			Text:     c.CapsuleText,
+		},
+	}, nil
+}
+
+// appendCapsuleResources fills the first page of resources/list with capsules.
+// mcp-go only lists statically registered resources, so capsules are added after
+// the fact; errors are logged rather than failing the list.
+func (h *Handlers) appendCapsuleResources(ctx context.Context, _ any, req *mcp.ListResourcesRequest, result *mcp.ListResourcesResult) {
+	if result == nil || req.Params.Cursor != "" {
+		return
+	}
+	resources, err := h.ListResources(ctx)
+	if err != nil {
+		log.Printf("moss: list capsule resources: %v", err)
+		return
+	}
+	result.Resources = append(result.Resources, resources...)
+}
@@ -3,6 +3,7 @@ package mcp
 import (
 	"context"
 	"database/sql"
+	"slices"
 	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -34,6 +35,14 @@ var toolRegistry = map[string]toolEntry{
 		def:     fetchManyToolDef,
 		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleFetchMany },
 	},
+	"capsule_exists": {
+		def:     existsToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleExists },
+	},
+	"capsule_fetch_record": {
+		def:     fetchRecordToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleFetchRecord },
+	},
 	"capsule_update": {
 		def:     updateToolDef,
 		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleUpdate },
@@ -42,10 +51,18 @@ var toolRegistry = map[string]toolEntry{
 		def:     deleteToolDef,
 		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleDelete },
 	},
+	"capsule_delete_many": {
+		def:     deleteManyToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleDeleteMany },
+	},
 	"capsule_latest": {
 		def:     latestToolDef,
 		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleLatest },
 	},
+	"capsule_first": {
+		def:     firstToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleFirst },
+	},
 	"capsule_list": {
 		def:     listToolDef,
 		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleList },
@@ -58,6 +75,10 @@ var toolRegistry = map[string]toolEntry{
 		def:     searchToolDef,
 		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleSearch },
 	},
+	"capsule_changes": {
+		def:     changesToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleChanges },
+	},
 	"capsule_export": {
 		def:     exportToolDef,
 		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleExport },
@@ -66,18 +87,66 @@ var toolRegistry = map[string]toolEntry{
 		def:     importToolDef,
 		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleImport },
 	},
+	"capsule_export_string": {
+		def:     exportStringToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleExportString },
+	},
+	"capsule_import_string": {
+		def:     importStringToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleImportString },
+	},
 	"capsule_purge": {
 		def:     purgeToolDef,
 		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandlePurge },
 	},
+	"capsule_recently_deleted": {
+		def:     recentlyDeletedToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleRecentlyDeleted },
+	},
+	"capsule_list_archived": {
+		def:     listArchivedToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleListArchived },
+	},
+	"capsule_restore_archived": {
+		def:     restoreArchivedToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleRestoreArchived },
+	},
+	"capsule_prune_duplicates": {
+		def:     pruneDuplicatesToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandlePruneDuplicates },
+	},
+	"capsule_reindex": {
+		def:     reindexToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleReindex },
+	},
 	"capsule_bulk_delete": {
 		def:     bulkDeleteToolDef,
 		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleBulkDelete },
 	},
+	"capsule_bulk_restore": {
+		def:     bulkRestoreToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleBulkRestore },
+	},
+	"capsule_bulk_move": {
+		def:     bulkMoveToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleBulkMove },
+	},
 	"capsule_bulk_update": {
 		def:     bulkUpdateToolDef,
 		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleBulkUpdate },
 	},
+	"capsule_bulk_add_tags": {
+		def:     bulkAddTagsToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleBulkAddTags },
+	},
+	"capsule_bulk_remove_tags": {
+		def:     bulkRemoveTagsToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleBulkRemoveTags },
+	},
+	"capsule_rename_tag": {
+		def:     renameTagToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleRenameTag },
+	},
 	"capsule_compose": {
 		def:     composeToolDef,
 		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleCompose },
@@ -86,6 +155,46 @@ var toolRegistry = map[string]toolEntry{
 		def:     appendToolDef,
 		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleAppend },
 	},
+	"capsule_patch": {
+		def:     patchToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandlePatch },
+	},
+	"capsule_link": {
+		def:     linkToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleLink },
+	},
+	"capsule_unlink": {
+		def:     unlinkToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleUnlink },
+	},
+	"capsule_links": {
+		def:     linksToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleLinks },
+	},
+	"capsule_bundles": {
+		def:     bundlesToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleBundles },
+	},
+	"capsule_related": {
+		def:     relatedToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleRelated },
+	},
+	"capsule_top_terms": {
+		def:     topTermsToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleTopTerms },
+	},
+	"capsule_batch": {
+		def:     batchToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleBatch },
+	},
+	"capsule_get_config": {
+		def:     getConfigToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleGetConfig },
+	},
+	"capsule_set_config": {
+		def:     setConfigToolDef,
+		handler: func(h *Handlers) server.ToolHandlerFunc { return h.HandleSetConfig },
+	},
 }
 
 // AllToolNames returns a list of all valid tool names.
@@ -156,41 +265,67 @@ func ExpandTypesToTools(types []string) []string {
 	return tools
 }
 
-// NewServer creates a new MCP server with Moss tools registered.
+// NewServer creates a new MCP server with Moss tools and capsule resources registered.
 // Tools listed in cfg.DisabledTools or belonging to cfg.DisabledTypes
-// are excluded from registration.
-func NewServer(db *sql.DB, cfg *config.Config, version string) *server.MCPServer {
+// are excluded from registration; disabling the "capsule" type also drops the
+// resources. Every result is subject to cfg.MaxResponseBytes, and with
+// cfg.EnvelopeResponses it is wrapped in an Envelope.
+// configDir is where capsule_set_config persists changes (the global ~/.moss).
+func NewServer(db *sql.DB, cfg *config.Config, configDir, version string) *server.MCPServer {
+	h := NewHandlers(db, cfg)
+	h.configDir = configDir
+
+	hooks := &server.Hooks{}
+	capsulesEnabled := !slices.Contains(cfg.DisabledTypes, "capsule")
+	if capsulesEnabled {
+		hooks.AddAfterListResources(h.appendCapsuleResources)
+	}
+
 	s := server.NewMCPServer(
 		"moss",
 		version,
 		server.WithToolCapabilities(true),
+		server.WithHooks(hooks),
 	)
 
-	h := NewHandlers(db, cfg)
-
-	// Build set of disabled tools: first expand types, then add individual tools
-	disabled := make(map[string]bool)
-	for _, tool := range ExpandTypesToTools(cfg.DisabledTypes) {
-		disabled[tool] = true
-	}
-	for _, name := range cfg.DisabledTools {
-		disabled[name] = true
-	}
-
 	// Register tools (skip disabled)
+	disabled := disabledToolSet(cfg)
 	for name, entry := range toolRegistry {
 		if disabled[name] {
 			continue
 		}
-		s.AddTool(entry.def, entry.handler(h))
+		handler := h.withResponseCap(entry.handler(h))
+		if cfg.EnvelopeResponses {
+			handler = withEnvelope(name, handler)
+		}
+		s.AddTool(entry.def, handler)
+		h.enabledTools = append(h.enabledTools, name)
+	}
+	slices.Sort(h.enabledTools)
+
+	// Expose capsules as resources for resource-aware clients
+	if capsulesEnabled {
+		s.AddResourceTemplate(capsuleResourceTemplate, h.HandleReadResource)
 	}
 
 	return s
 }
 
+// disabledToolSet expands cfg.DisabledTypes to their tools and adds cfg.DisabledTools.
+func disabledToolSet(cfg *config.Config) map[string]bool {
+	disabled := make(map[string]bool)
+	for _, tool := range ExpandTypesToTools(cfg.DisabledTypes) {
+		disabled[tool] = true
+	}
+	for _, name := range cfg.DisabledTools {
+		disabled[name] = true
+	}
+	return disabled
+}
+
 // Run starts the MCP server using stdio transport.
-func Run(db *sql.DB, cfg *config.Config, version string) error {
-	s := NewServer(db, cfg, version)
+func Run(db *sql.DB, cfg *config.Config, configDir, version string) error {
+	s := NewServer(db, cfg, configDir, version)
 	return server.ServeStdio(s)
 }
 
@@ -2,7 +2,7 @@ package mcp
 
 import "github.com/mark3labs/mcp-go/mcp"
 
-// Tool definitions for all 15 Moss MCP tools.
+// Tool definitions for all Moss MCP tools.
 // Addressing params (id, workspace, name) are all optional in schema;
 // "exactly one addressing mode" rule is enforced by handlers via ops.ValidateAddress().
 
@@ -38,13 +38,29 @@ var storeToolDef = mcp.NewTool("capsule_store",
 	mcp.WithString("role",
 		mcp.Description("Agent role (e.g., 'design-intent', 'qa-reviewer')"),
 	),
+	mcp.WithString("source_type",
+		mcp.Description("Structured origin: 'agent', 'human', or 'import'"),
+		mcp.Enum("agent", "human", "import"),
+	),
+	mcp.WithString("source_ref",
+		mcp.Description("Optional URL or reference for the origin (e.g., PR link, session ID)"),
+	),
+	mcp.WithString("note",
+		mcp.Description("Optional free-form note kept outside capsule_text: not linted, searched, or included in compose"),
+	),
+	mcp.WithNumber("expires_at",
+		mcp.Description("Unix timestamp (seconds) after which the capsule reads as deleted and is soft-deleted by the expiry sweep. Omit or 0 for no expiry."),
+	),
 	mcp.WithString("mode",
-		mcp.Description("Collision behavior: 'error' (default) fails on name collision, 'replace' overwrites existing"),
-		mcp.Enum("error", "replace"),
+		mcp.Description("Collision behavior: 'error' (default) fails on name collision, 'replace' overwrites existing, 'rename' stores under the next free name-N suffix"),
+		mcp.Enum("error", "replace", "rename"),
 	),
 	mcp.WithBoolean("allow_thin",
 		mcp.Description("If true, skip section validation. Use sparingly for quick notes."),
 	),
+	mcp.WithNumber("dedupe_window_seconds",
+		mcp.Description("Unnamed capsules only: if an active unnamed capsule in the workspace with identical text, title, tags, run_id, phase and role was updated within this many seconds, return it with was_duplicate:true instead of storing a copy (for retries). Default: config store_dedupe_window_seconds (0 = off)"),
+	),
 )
 
 var fetchToolDef = mcp.NewTool("capsule_fetch",
@@ -61,11 +77,67 @@ var fetchToolDef = mcp.NewTool("capsule_fetch",
 		mcp.Description("Capsule name within workspace."),
 	),
 	mcp.WithBoolean("include_deleted",
-		mcp.Description("Include soft-deleted capsules in lookup"),
+		mcp.Description("Include soft-deleted capsules in lookup (default: config default_include_deleted, normally false)"),
 	),
 	mcp.WithBoolean("include_text",
 		mcp.Description("Include capsule_text in response (default: true)"),
 	),
+	mcp.WithBoolean("with_links",
+		mcp.Description("Include summaries of linked capsules (outgoing and incoming)"),
+	),
+	mcp.WithBoolean("with_bundles",
+		mcp.Description("Include summaries of stored bundles (capsule_compose with store_as) composed from this capsule"),
+	),
+	mcp.WithBoolean("prefix",
+		mcp.Description("Treat id as a prefix (min 4 chars). Errors if it matches more than one capsule."),
+	),
+	mcp.WithString("known_sha",
+		mcp.Description("content_sha256 from a previous fetch. If it still matches, capsule_text is omitted and unchanged=true is returned."),
+	),
+	mcp.WithArray("fields",
+		mcp.Description("Return only these output fields (e.g. ['title', 'tags']); id is always included. Unknown names are rejected"),
+		mcp.Items(map[string]any{"type": "string"}),
+	),
+	mcp.WithArray("fallback_workspaces",
+		mcp.Description("Workspaces to try in order if name isn't found in workspace (e.g. ['global']). The first hit wins and matched_workspace reports it. Name addressing only"),
+		mcp.Items(map[string]any{"type": "string"}),
+	),
+)
+
+var existsToolDef = mcp.NewTool("capsule_exists",
+	mcp.WithDescription("Check whether a capsule exists without fetching it. Returns {exists, id}; a missing capsule is exists=false, not an error. Use exactly one addressing mode: id OR (workspace+name)."),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("id",
+		mcp.Description("Capsule ID (ULID). Mutually exclusive with workspace+name."),
+	),
+	mcp.WithString("workspace",
+		mcp.Description("Workspace namespace (default: 'default')"),
+	),
+	mcp.WithString("name",
+		mcp.Description("Capsule name within workspace."),
+	),
+	mcp.WithBoolean("include_deleted",
+		mcp.Description("Count soft-deleted capsules as existing (default: config default_include_deleted, normally false)"),
+	),
+)
+
+var fetchRecordToolDef = mcp.NewTool("capsule_fetch_record",
+	mcp.WithDescription("Fetch a capsule as its canonical export record (the exact JSON object capsule_export writes for it). Use for hashing or signing. Use exactly one addressing mode: id OR (workspace+name)."),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("id",
+		mcp.Description("Capsule ID (ULID). Mutually exclusive with workspace+name."),
+	),
+	mcp.WithString("workspace",
+		mcp.Description("Workspace namespace (default: 'default')"),
+	),
+	mcp.WithString("name",
+		mcp.Description("Capsule name within workspace."),
+	),
+	mcp.WithBoolean("include_deleted",
+		mcp.Description("Include soft-deleted capsules in lookup"),
+	),
 )
 
 var fetchManyToolDef = mcp.NewTool("capsule_fetch_many",
@@ -126,6 +198,18 @@ var updateToolDef = mcp.NewTool("capsule_update",
 	mcp.WithString("role",
 		mcp.Description("New agent role"),
 	),
+	mcp.WithString("source_type",
+		mcp.Description("New structured origin: 'agent', 'human', or 'import' (empty string clears)"),
+	),
+	mcp.WithString("source_ref",
+		mcp.Description("New origin URL or reference (empty string clears)"),
+	),
+	mcp.WithString("note",
+		mcp.Description("New free-form note (empty string clears)"),
+	),
+	mcp.WithNumber("expires_at",
+		mcp.Description("New expiry as a Unix timestamp in seconds (0 clears)"),
+	),
 	mcp.WithBoolean("allow_thin",
 		mcp.Description("If true, skip section validation for capsule_text"),
 	),
@@ -145,8 +229,19 @@ var deleteToolDef = mcp.NewTool("capsule_delete",
 	),
 )
 
+var deleteManyToolDef = mcp.NewTool("capsule_delete_many",
+	mcp.WithDescription("Soft-delete an explicit list of capsules by ID. Returns a per-ID result; missing or already-deleted IDs are reported with a code instead of aborting the batch."),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithArray("ids",
+		mcp.Required(),
+		mcp.Description("Capsule IDs (ULIDs) to delete"),
+		mcp.Items(map[string]any{"type": "string"}),
+	),
+)
+
 var latestToolDef = mcp.NewTool("capsule_latest",
-	mcp.WithDescription("Get the most recently updated capsule in a workspace. Quick way to resume work."),
+	mcp.WithDescription("Get the most recently updated (or, with by:'created', created) capsule in a workspace. Quick way to resume work."),
 	mcp.WithReadOnlyHintAnnotation(true),
 	mcp.WithDestructiveHintAnnotation(false),
 	mcp.WithString("workspace",
@@ -165,7 +260,45 @@ var latestToolDef = mcp.NewTool("capsule_latest",
 		mcp.Description("Include capsule_text in response (default: false for summary)"),
 	),
 	mcp.WithBoolean("include_deleted",
-		mcp.Description("Include soft-deleted capsules in lookup"),
+		mcp.Description("Include soft-deleted capsules in lookup (default: config default_include_deleted, normally false)"),
+	),
+	mcp.WithString("by",
+		mcp.Description("Which timestamp defines latest: 'updated' (default) or 'created' (most recently created, for append-only logs)"),
+		mcp.Enum("updated", "created"),
+	),
+	mcp.WithBoolean("prefer_named",
+		mcp.Description("Return the latest named capsule even if a newer unnamed one exists; unnamed capsules are returned only when none is named (default: false)"),
+	),
+)
+
+var firstToolDef = mcp.NewTool("capsule_first",
+	mcp.WithDescription("Get the oldest (least recently updated, or with by:'created', earliest created) capsule in a workspace. Mirror of capsule_latest for reconstructing the start of a run."),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("workspace",
+		mcp.Description("Workspace namespace (default: 'default')"),
+	),
+	mcp.WithString("run_id",
+		mcp.Description("Filter by orchestration run ID"),
+	),
+	mcp.WithString("phase",
+		mcp.Description("Filter by workflow phase"),
+	),
+	mcp.WithString("role",
+		mcp.Description("Filter by agent role"),
+	),
+	mcp.WithBoolean("include_text",
+		mcp.Description("Include capsule_text in response (default: false for summary)"),
+	),
+	mcp.WithBoolean("include_deleted",
+		mcp.Description("Include soft-deleted capsules in lookup (default: config default_include_deleted, normally false)"),
+	),
+	mcp.WithString("by",
+		mcp.Description("Which timestamp defines first: 'updated' (default) or 'created' (earliest created)"),
+		mcp.Enum("updated", "created"),
+	),
+	mcp.WithBoolean("prefer_named",
+		mcp.Description("Return the oldest named capsule even if an older unnamed one exists; unnamed capsules are returned only when none is named (default: false)"),
 	),
 )
 
@@ -185,6 +318,9 @@ var listToolDef = mcp.NewTool("capsule_list",
 	mcp.WithString("role",
 		mcp.Description("Filter by agent role"),
 	),
+	mcp.WithBoolean("named",
+		mcp.Description("true: only named capsules; false: only unnamed (auto-ID) capsules; omit for both"),
+	),
 	mcp.WithNumber("limit",
 		mcp.Description("Max items to return (default: 20, max: 100)"),
 	),
@@ -192,7 +328,10 @@ var listToolDef = mcp.NewTool("capsule_list",
 		mcp.Description("Skip first N items for pagination"),
 	),
 	mcp.WithBoolean("include_deleted",
-		mcp.Description("Include soft-deleted capsules"),
+		mcp.Description("Include soft-deleted capsules (default: config default_include_deleted, normally false)"),
+	),
+	mcp.WithBoolean("include_text",
+		mcp.Description("Return full capsule_text with each item (limit max 50; pages are also capped at 1 MiB of text; a cut page sets truncated and next_offset)"),
 	),
 )
 
@@ -218,6 +357,12 @@ var inventoryToolDef = mcp.NewTool("capsule_inventory",
 	mcp.WithString("role",
 		mcp.Description("Filter by agent role"),
 	),
+	mcp.WithString("source_type",
+		mcp.Description("Filter by source type ('agent', 'human', 'import')"),
+	),
+	mcp.WithBoolean("named",
+		mcp.Description("true: only named capsules; false: only unnamed (auto-ID) capsules; omit for both"),
+	),
 	mcp.WithNumber("limit",
 		mcp.Description("Max items to return (default: 100, max: 500)"),
 	),
@@ -225,40 +370,88 @@ var inventoryToolDef = mcp.NewTool("capsule_inventory",
 		mcp.Description("Skip first N items for pagination"),
 	),
 	mcp.WithBoolean("include_deleted",
-		mcp.Description("Include soft-deleted capsules"),
+		mcp.Description("Include soft-deleted capsules (default: config default_include_deleted, normally false)"),
+	),
+	mcp.WithBoolean("include_text",
+		mcp.Description("Return full capsule_text with each item (limit default/max 50; pages are also capped at 1 MiB of text; a cut page sets truncated and next_offset)"),
 	),
 )
 
 var exportToolDef = mcp.NewTool("capsule_export",
-	mcp.WithDescription("Export capsules to a JSONL file for backup or migration."),
+	mcp.WithDescription("Export capsules to a JSONL file (or a single JSON document) for backup or migration, or to a metadata-only CSV for spreadsheets."),
 	mcp.WithReadOnlyHintAnnotation(false), // Writes files to disk
 	mcp.WithDestructiveHintAnnotation(false),
 	mcp.WithString("path",
-		mcp.Description("Export file path. Default: ~/.moss/exports/<workspace>-<timestamp>.jsonl"),
+		mcp.Description("Export file path. Default: ~/.moss/exports/<workspace>-<timestamp>.jsonl (.json / .csv for those formats)"),
 	),
 	mcp.WithString("workspace",
 		mcp.Description("Filter by workspace. Omit to export all."),
 	),
+	mcp.WithArray("ids",
+		mcp.Description("Export only these capsule IDs (max 500; combines with workspace and include_deleted). Unknown IDs are skipped."),
+		mcp.WithStringItems(),
+	),
 	mcp.WithBoolean("include_deleted",
 		mcp.Description("Include soft-deleted capsules"),
 	),
+	mcp.WithString("format",
+		mcp.Description("File layout: 'jsonl' (default, one record per line), 'json' (single {header, capsules} document), or 'csv' (metadata only, no capsule_text; not importable)"),
+		mcp.Enum("jsonl", "json", "csv"),
+	),
+	mcp.WithString("order_by",
+		mcp.Description("Record order: 'created' (default, created_at then id) or 'workspace' (workspace, then name with unnamed last, then id; stable across reorganization for diffable backups)"),
+		mcp.Enum("created", "workspace"),
+	),
+	mcp.WithBoolean("compact",
+		mcp.Description("Omit fields import recomputes (workspace_norm, name_norm, capsule_chars, tokens_estimate) for smaller backups. jsonl/json only (default: false)"),
+	),
+	mcp.WithString("resume_from",
+		mcp.Description("Export only capsules after this ID in created_at, id order, e.g. the resume_cursor of an earlier export. Requires order_by 'created'."),
+	),
+	mcp.WithBoolean("append",
+		mcp.Description("Write records straight into path, appending to an existing file (header only if new or empty), so an interrupted export keeps what it wrote. Requires path; jsonl/csv only (default: false)"),
+	),
 )
 
 var importToolDef = mcp.NewTool("capsule_import",
-	mcp.WithDescription("Import capsules from a JSONL export file."),
+	mcp.WithDescription("Import capsules from an export file (JSONL or JSON document, auto-detected)."),
 	mcp.WithDestructiveHintAnnotation(false),
 	mcp.WithString("path",
 		mcp.Required(),
-		mcp.Description("Path to JSONL export file"),
+		mcp.Description("Path to export file (.jsonl or .json)"),
 	),
 	mcp.WithString("mode",
-		mcp.Description("Collision handling: 'error' (default, atomic), 'replace' (overwrite), 'rename' (auto-suffix)"),
-		mcp.Enum("error", "replace", "rename"),
+		mcp.Description("Collision handling: 'error' (default, atomic), 'replace' (overwrite), 'rename' (auto-suffix), 'skip' (import only non-colliding records), 'merge' (keep the newer of the two by updated_at, union tags)"),
+		mcp.Enum("error", "replace", "rename", "skip", "merge"),
+	),
+)
+
+var exportStringToolDef = mcp.NewTool("capsule_export_string",
+	mcp.WithDescription("Export capsules inline as a single gzip+base64 string (JSONL records, same as capsule_export) for clipboard transfer to another machine. Capped at 1 MB; larger exports fail with INVALID_REQUEST."),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithString("workspace",
+		mcp.Description("Filter by workspace. Omit to export all."),
+	),
+	mcp.WithBoolean("include_deleted",
+		mcp.Description("Include soft-deleted capsules"),
+	),
+)
+
+var importStringToolDef = mcp.NewTool("capsule_import_string",
+	mcp.WithDescription("Import capsules from a string produced by capsule_export_string."),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("data",
+		mcp.Required(),
+		mcp.Description("The gzip+base64 export string (whitespace is ignored)"),
+	),
+	mcp.WithString("mode",
+		mcp.Description("Collision handling: 'error' (default, atomic), 'replace' (overwrite), 'rename' (auto-suffix), 'skip' (import only non-colliding records), 'merge' (keep the newer of the two by updated_at, union tags)"),
+		mcp.Enum("error", "replace", "rename", "skip", "merge"),
 	),
 )
 
 var purgeToolDef = mcp.NewTool("capsule_purge",
-	mcp.WithDescription("Permanently delete soft-deleted capsules. Irreversible."),
+	mcp.WithDescription("Permanently delete soft-deleted capsules. Irreversible unless archive_on_purge is enabled, which moves them to the archive (see capsule_list_archived)."),
 	mcp.WithDestructiveHintAnnotation(true),
 	mcp.WithString("workspace",
 		mcp.Description("Filter by workspace. Omit to purge all."),
@@ -268,8 +461,70 @@ var purgeToolDef = mcp.NewTool("capsule_purge",
 	),
 )
 
+var recentlyDeletedToolDef = mcp.NewTool("capsule_recently_deleted",
+	mcp.WithDescription("List soft-deleted capsules, most recently deleted first, to find and restore a mistaken delete (capsule_bulk_restore). Purged capsules are gone and not listed; see capsule_list_archived."),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("workspace",
+		mcp.Description("Filter by workspace"),
+	),
+	mcp.WithNumber("limit",
+		mcp.Description("Max items to return (default: 20, max: 100)"),
+	),
+)
+
+var listArchivedToolDef = mcp.NewTool("capsule_list_archived",
+	mcp.WithDescription("List capsules moved to the archive by capsule_purge (when archive_on_purge is enabled), most recently archived first. Archived capsules are not searchable or fetchable until restored."),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("workspace",
+		mcp.Description("Filter by workspace"),
+	),
+	mcp.WithNumber("limit",
+		mcp.Description("Max items to return (default: 100, max: 500)"),
+	),
+	mcp.WithNumber("offset",
+		mcp.Description("Items to skip (default: 0)"),
+	),
+)
+
+var restoreArchivedToolDef = mcp.NewTool("capsule_restore_archived",
+	mcp.WithDescription("Move an archived capsule back into the live store as an active capsule. Fails if its name has been reused in the workspace. Links removed by the purge are not restored."),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("id",
+		mcp.Required(),
+		mcp.Description("ID of the archived capsule (from capsule_list_archived)"),
+	),
+)
+
+var pruneDuplicatesToolDef = mcp.NewTool("capsule_prune_duplicates",
+	mcp.WithDescription("Soft-delete unnamed capsules whose text is identical to another unnamed capsule in the same workspace, keeping the most recently updated copy. Named capsules are never pruned. Use dry_run to preview the groups."),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithString("workspace",
+		mcp.Description("Only prune within this workspace. Omit to scan all."),
+	),
+	mcp.WithBoolean("dry_run",
+		mcp.Description("Report duplicate groups without deleting (default: false)"),
+	),
+)
+
+var reindexToolDef = mcp.NewTool("capsule_reindex",
+	mcp.WithDescription("Check the full-text search index against stored capsules and rebuild it if out of sync. "+
+		"Use when capsule_search misses capsules that capsule_fetch can find."),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithBoolean("check_only",
+		mcp.Description("Only report consistency; never rebuild"),
+	),
+	mcp.WithBoolean("force",
+		mcp.Description("Rebuild even if the index looks consistent (also refreshes stale indexed text)"),
+	),
+)
+
 var bulkDeleteToolDef = mcp.NewTool("capsule_bulk_delete",
-	mcp.WithDescription("Soft-delete multiple capsules matching filters. Requires at least one filter. Only targets active capsules."),
+	mcp.WithDescription("Soft-delete multiple capsules matching filters. Requires at least one filter. Only targets active capsules. Use dry_run to preview the count."),
 	mcp.WithReadOnlyHintAnnotation(false),
 	mcp.WithDestructiveHintAnnotation(true),
 	mcp.WithString("workspace",
@@ -290,10 +545,82 @@ var bulkDeleteToolDef = mcp.NewTool("capsule_bulk_delete",
 	mcp.WithString("role",
 		mcp.Description("Filter by agent role"),
 	),
+	mcp.WithBoolean("dry_run",
+		mcp.Description("Return would_affect (the number of matching capsules) without deleting (default: false)"),
+	),
+)
+
+var bulkRestoreToolDef = mcp.NewTool("capsule_bulk_restore",
+	mcp.WithDescription("Restore soft-deleted capsules matching filters. Requires at least one filter. Capsules whose name is now used by an active capsule are skipped and counted."),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("workspace",
+		mcp.Description("Filter by workspace"),
+	),
+	mcp.WithString("tag",
+		mcp.Description("Filter by tag"),
+	),
+	mcp.WithString("name_prefix",
+		mcp.Description("Filter by name prefix (normalized)"),
+	),
+	mcp.WithString("run_id",
+		mcp.Description("Filter by orchestration run ID"),
+	),
+	mcp.WithString("phase",
+		mcp.Description("Filter by workflow phase"),
+	),
+	mcp.WithString("role",
+		mcp.Description("Filter by agent role"),
+	),
+)
+
+var bulkMoveToolDef = mcp.NewTool("capsule_bulk_move",
+	mcp.WithDescription("Move active capsules matching filters into another workspace. Requires at least one filter. If any name would collide in the destination, nothing is moved."),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("to_workspace",
+		mcp.Required(),
+		mcp.Description("Destination workspace"),
+	),
+	mcp.WithString("workspace",
+		mcp.Description("Filter by workspace"),
+	),
+	mcp.WithString("tag",
+		mcp.Description("Filter by tag"),
+	),
+	mcp.WithString("name_prefix",
+		mcp.Description("Filter by name prefix (normalized)"),
+	),
+	mcp.WithString("run_id",
+		mcp.Description("Filter by orchestration run ID"),
+	),
+	mcp.WithString("phase",
+		mcp.Description("Filter by workflow phase"),
+	),
+	mcp.WithString("role",
+		mcp.Description("Filter by agent role"),
+	),
+)
+
+var renameTagToolDef = mcp.NewTool("capsule_rename_tag",
+	mcp.WithDescription("Rename a tag on all active capsules. If a capsule already has new_tag, the two are merged. Optionally scoped to one workspace."),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("old_tag",
+		mcp.Required(),
+		mcp.Description("Tag to replace"),
+	),
+	mcp.WithString("new_tag",
+		mcp.Required(),
+		mcp.Description("Replacement tag (merged if already present)"),
+	),
+	mcp.WithString("workspace",
+		mcp.Description("Limit the rename to this workspace"),
+	),
 )
 
 var bulkUpdateToolDef = mcp.NewTool("capsule_bulk_update",
-	mcp.WithDescription("Update metadata on multiple capsules matching filters. Requires at least one filter and one update field. Only targets active capsules."),
+	mcp.WithDescription("Update metadata on multiple capsules matching filters. Requires at least one filter and one update field. Only targets active capsules. Use dry_run to preview the count."),
 	mcp.WithReadOnlyHintAnnotation(false),
 	mcp.WithDestructiveHintAnnotation(true),
 	// Filter params
@@ -326,6 +653,75 @@ var bulkUpdateToolDef = mcp.NewTool("capsule_bulk_update",
 		mcp.Description("New tags (replaces existing; empty array clears tags)"),
 		mcp.WithStringItems(),
 	),
+	mcp.WithString("set_source",
+		mcp.Description("New source (empty string clears the field)"),
+	),
+	mcp.WithString("set_title",
+		mcp.Description("New title (empty string clears the field)"),
+	),
+	mcp.WithBoolean("dry_run",
+		mcp.Description("Return would_affect (the number of matching capsules) without updating (default: false)"),
+	),
+)
+
+var bulkAddTagsToolDef = mcp.NewTool("capsule_bulk_add_tags",
+	mcp.WithDescription("Add tags to multiple capsules matching filters, keeping their existing tags. Requires at least one filter. Only targets active capsules; those already carrying every tag are not counted."),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(false),
+	// Filter params
+	mcp.WithString("workspace",
+		mcp.Description("Filter by workspace"),
+	),
+	mcp.WithString("tag",
+		mcp.Description("Filter by tag"),
+	),
+	mcp.WithString("name_prefix",
+		mcp.Description("Filter by name prefix (normalized)"),
+	),
+	mcp.WithString("run_id",
+		mcp.Description("Filter by orchestration run ID"),
+	),
+	mcp.WithString("phase",
+		mcp.Description("Filter by workflow phase"),
+	),
+	mcp.WithString("role",
+		mcp.Description("Filter by agent role"),
+	),
+	mcp.WithArray("tags",
+		mcp.Required(),
+		mcp.Description("Tags to add (max 20; trimmed and deduped)"),
+		mcp.WithStringItems(),
+	),
+)
+
+var bulkRemoveTagsToolDef = mcp.NewTool("capsule_bulk_remove_tags",
+	mcp.WithDescription("Remove tags from multiple capsules matching filters, keeping their other tags. Requires at least one filter. Only targets active capsules; those carrying none of the tags are not counted."),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(true),
+	// Filter params
+	mcp.WithString("workspace",
+		mcp.Description("Filter by workspace"),
+	),
+	mcp.WithString("tag",
+		mcp.Description("Filter by tag"),
+	),
+	mcp.WithString("name_prefix",
+		mcp.Description("Filter by name prefix (normalized)"),
+	),
+	mcp.WithString("run_id",
+		mcp.Description("Filter by orchestration run ID"),
+	),
+	mcp.WithString("phase",
+		mcp.Description("Filter by workflow phase"),
+	),
+	mcp.WithString("role",
+		mcp.Description("Filter by agent role"),
+	),
+	mcp.WithArray("tags",
+		mcp.Required(),
+		mcp.Description("Tags to remove (max 20; trimmed and deduped)"),
+		mcp.WithStringItems(),
+	),
 )
 
 var searchToolDef = mcp.NewTool("capsule_search",
@@ -334,14 +730,35 @@ var searchToolDef = mcp.NewTool("capsule_search",
 	mcp.WithDestructiveHintAnnotation(false),
 	mcp.WithString("query",
 		mcp.Required(),
-		mcp.Description("Search query. Supports phrases (\"exact match\"), prefix (auth*), boolean (A OR B, A AND B, NOT A)."),
+		mcp.Description("Search query. Supports phrases (\"exact match\"), prefix (auth*), boolean (A OR B, A AND B, NOT A). Empty lists all capsules matching the filters, newest first, when config empty_query_lists_all is on."),
 	),
 	mcp.WithString("workspace",
 		mcp.Description("Filter by workspace"),
 	),
+	mcp.WithArray("workspaces",
+		mcp.Description("Filter to any of these workspaces (max 20; combined with workspace)"),
+		mcp.Items(map[string]any{"type": "string"}),
+	),
 	mcp.WithString("tag",
 		mcp.Description("Filter by tag"),
 	),
+	mcp.WithArray("tags",
+		mcp.Description("Filter by several tags (max 20), matched per tag_match; combined with tag"),
+		mcp.Items(map[string]any{"type": "string"}),
+	),
+	mcp.WithString("tag_match",
+		mcp.Description("How tags match: 'any' (default, at least one) or 'all' (every tag)"),
+		mcp.Enum("any", "all"),
+	),
+	mcp.WithString("name_prefix",
+		mcp.Description("Filter by capsule name prefix"),
+	),
+	mcp.WithNumber("updated_after",
+		mcp.Description("Only capsules updated at or after this Unix timestamp (seconds)"),
+	),
+	mcp.WithNumber("updated_before",
+		mcp.Description("Only capsules updated before this Unix timestamp (seconds)"),
+	),
 	mcp.WithString("run_id",
 		mcp.Description("Filter by orchestration run ID"),
 	),
@@ -351,14 +768,56 @@ var searchToolDef = mcp.NewTool("capsule_search",
 	mcp.WithString("role",
 		mcp.Description("Filter by agent role"),
 	),
+	mcp.WithString("source_type",
+		mcp.Description("Filter by source type ('agent', 'human', 'import')"),
+	),
 	mcp.WithNumber("limit",
 		mcp.Description("Max items to return (default: 20, max: 100)"),
 	),
 	mcp.WithNumber("offset",
 		mcp.Description("Skip first N items for pagination"),
 	),
+	mcp.WithString("sort",
+		mcp.Description("Result order: 'relevance' (default, BM25) or 'updated_at' (newest first; enables cursor paging)"),
+		mcp.Enum("relevance", "updated_at"),
+	),
+	mcp.WithString("cursor",
+		mcp.Description("next_cursor from the previous page. Requires sort:'updated_at'; stable under concurrent writes, unlike offset"),
+	),
 	mcp.WithBoolean("include_deleted",
-		mcp.Description("Include soft-deleted capsules"),
+		mcp.Description("Include soft-deleted capsules (default: config default_include_deleted, normally false)"),
+	),
+	mcp.WithBoolean("with_snippet",
+		mcp.Description("Compute match snippets (default: true). Set false for a faster ID/title-only result list; ranking is unchanged"),
+	),
+	mcp.WithNumber("snippet_tokens",
+		mcp.Description("Tokens of context around each match (default: 64, range: 8-128). Snippets are still capped at ~300 chars"),
+	),
+	mcp.WithArray("snippet_formats",
+		mcp.Description("Also return each snippet in these formats under 'snippets' (html, plain, markdown), all cut from the same match text. 'snippet' stays HTML"),
+		mcp.Items(map[string]any{"type": "string", "enum": []string{"html", "plain", "markdown"}}),
+	),
+	mcp.WithBoolean("facets",
+		mcp.Description("Also return hit counts per workspace, phase, and tag over all matches (not just this page)"),
+	),
+	mcp.WithBoolean("group_by_workspace",
+		mcp.Description("Also return this page's items bucketed by workspace in 'groups' (ordered by best hit)"),
+	),
+)
+
+var changesToolDef = mcp.NewTool("capsule_changes",
+	mcp.WithDescription("List capsules created, updated, or soft-deleted after a timestamp, oldest change first, as full export records (deleted ones carry deleted_at). Use to keep a local mirror in sync; purged capsules are not reported."),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithNumber("since",
+		mcp.Required(),
+		mcp.Description("Unix timestamp (seconds); only capsules with updated_at after this are returned. Use 0 for a full sync"),
+	),
+	mcp.WithNumber("limit",
+		mcp.Description("Max items to return (default: 50, max: 200)"),
+	),
+	mcp.WithString("cursor",
+		mcp.Description("next_cursor from the previous call. Continue while has_more; keep the last one to pick up later changes"),
 	),
 )
 
@@ -387,6 +846,38 @@ var appendToolDef = mcp.NewTool("capsule_append",
 	),
 )
 
+var patchToolDef = mcp.NewTool("capsule_patch",
+	mcp.WithDescription("Apply several small edits to one capsule atomically (max 50): set-title, add-tag, remove-tag, replace-section, append-section. "+
+		"Ops run in order; the final text is linted like capsule_update. If any op or the lint fails, nothing is changed; op errors name the failing op as ops[i]."),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("id",
+		mcp.Description("Capsule ID (ULID). Mutually exclusive with workspace+name."),
+	),
+	mcp.WithString("workspace",
+		mcp.Description("Workspace namespace (default: 'default')"),
+	),
+	mcp.WithString("name",
+		mcp.Description("Capsule name within workspace."),
+	),
+	mcp.WithArray("ops",
+		mcp.Required(),
+		mcp.Description("Edits in order. value is the new title (empty clears it), the tag, or the section content; section ops match the header exactly (case-insensitive), as capsule_append."),
+		mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"op":      map[string]any{"type": "string", "enum": []string{"set-title", "add-tag", "remove-tag", "replace-section", "append-section"}},
+				"section": map[string]any{"type": "string", "description": "Target section header (replace-section/append-section)"},
+				"value":   map[string]any{"type": "string"},
+			},
+			"required": []string{"op"},
+		}),
+	),
+	mcp.WithBoolean("allow_thin",
+		mcp.Description("Skip the required-sections lint on the final text (size limits still apply)"),
+	),
+)
+
 var composeToolDef = mcp.NewTool("capsule_compose",
 	mcp.WithDescription("Assemble multiple capsules into a single bundle. Optionally filter to specific sections. All-or-nothing: fails if any capsule is missing."),
 	mcp.WithReadOnlyHintAnnotation(false), // May write if store_as provided
@@ -411,12 +902,200 @@ var composeToolDef = mcp.NewTool("capsule_compose",
 		mcp.Description("Only include these sections from each capsule (exact match, case-insensitive). Omit for all sections."),
 		mcp.WithStringItems(),
 	),
+	mcp.WithString("header_template",
+		mcp.Description("Markdown only: Go text/template for each part header. Fields: {{.Title}} {{.Name}} {{.ID}} {{.Workspace}} {{.DisplayName}} {{.Index}} (1-based). Default: '## {{.DisplayName}}'"),
+	),
+	mcp.WithString("intra_part_separator",
+		mcp.Description("Markdown only, with sections: text placed between a capsule's included sections (max 100 chars), e.g. '\n\n* * *\n\n'. Default: a blank line"),
+	),
+	mcp.WithBoolean("trailing_newline",
+		mcp.Description("true: bundle_text ends with exactly one newline; false: with none. Default: as assembled. Applies to the stored bundle too"),
+	),
 	mcp.WithObject("store_as",
 		mcp.Description("Optional: persist the composed bundle as a new capsule. Requires format:'markdown' (JSON lacks section headers for lint)."),
 		mcp.Properties(map[string]any{
-			"workspace": map[string]any{"type": "string", "description": "Target workspace (default: 'default')"},
-			"name":      map[string]any{"type": "string", "description": "Capsule name (required)"},
-			"mode":      map[string]any{"type": "string", "enum": []string{"error", "replace"}, "description": "Collision behavior: 'error' (default) or 'replace'"},
+			"workspace":    map[string]any{"type": "string", "description": "Target workspace (default: 'default')"},
+			"name":         map[string]any{"type": "string", "description": "Capsule name (required)"},
+			"mode":         map[string]any{"type": "string", "enum": []string{"error", "replace"}, "description": "Collision behavior: 'error' (default) or 'replace'"},
+			"title_prefix": map[string]any{"type": "string", "description": "Prepended verbatim to the stored title (which defaults to name), e.g. '[bundle] '"},
+			"title_suffix": map[string]any{"type": "string", "description": "Appended verbatim to the stored title, e.g. ' (composite)'"},
+		}),
+	),
+	mcp.WithBoolean("dry_run",
+		mcp.Description("Only report the projected bundle_chars, tokens, and whether it exceeds the size limit; bundle_text is empty and nothing is stored"),
+	),
+	mcp.WithString("order_by",
+		mcp.Description("Part order: 'caller' (default, items order), 'created' or 'updated' (oldest first), 'name' (case-insensitive, unnamed last)"),
+		mcp.Enum("caller", "created", "updated", "name"),
+	),
+)
+
+// linkRefSchema describes a link endpoint (id OR workspace+name).
+var linkRefSchema = map[string]any{
+	"id":        map[string]any{"type": "string", "description": "Capsule ID (ULID)"},
+	"workspace": map[string]any{"type": "string", "description": "Workspace namespace"},
+	"name":      map[string]any{"type": "string", "description": "Capsule name"},
+}
+
+var linkToolDef = mcp.NewTool("capsule_link",
+	mcp.WithDescription("Create a directed, typed link between two capsules (e.g., a review that covers a design). Idempotent."),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithObject("from",
+		mcp.Required(),
+		mcp.Description("Source capsule. Use id OR (workspace+name)."),
+		mcp.Properties(linkRefSchema),
+	),
+	mcp.WithObject("to",
+		mcp.Required(),
+		mcp.Description("Target capsule. Use id OR (workspace+name)."),
+		mcp.Properties(linkRefSchema),
+	),
+	mcp.WithString("relation",
+		mcp.Required(),
+		mcp.Description("Relationship type (e.g., 'reviews', 'supersedes', 'depends-on'). Normalized."),
+	),
+)
+
+var unlinkToolDef = mcp.NewTool("capsule_unlink",
+	mcp.WithDescription("Remove a link between two capsules."),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithObject("from",
+		mcp.Required(),
+		mcp.Description("Source capsule. Use id OR (workspace+name)."),
+		mcp.Properties(linkRefSchema),
+	),
+	mcp.WithObject("to",
+		mcp.Required(),
+		mcp.Description("Target capsule. Use id OR (workspace+name)."),
+		mcp.Properties(linkRefSchema),
+	),
+	mcp.WithString("relation",
+		mcp.Required(),
+		mcp.Description("Relationship type to remove"),
+	),
+)
+
+var linksToolDef = mcp.NewTool("capsule_links",
+	mcp.WithDescription("List a capsule's outgoing and incoming links with summaries of the linked capsules."),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("id",
+		mcp.Description("Capsule ID (ULID). Mutually exclusive with workspace+name."),
+	),
+	mcp.WithString("workspace",
+		mcp.Description("Workspace namespace (default: 'default')"),
+	),
+	mcp.WithString("name",
+		mcp.Description("Capsule name within workspace."),
+	),
+	mcp.WithBoolean("include_deleted",
+		mcp.Description("Include soft-deleted capsules (both the addressed capsule and linked ones)"),
+	),
+)
+
+var bundlesToolDef = mcp.NewTool("capsule_bundles",
+	mcp.WithDescription("List the stored bundles (capsule_compose with store_as) that were composed from a capsule, most recently updated first."),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("id",
+		mcp.Description("Capsule ID (ULID). Mutually exclusive with workspace+name."),
+	),
+	mcp.WithString("workspace",
+		mcp.Description("Workspace namespace (default: 'default')"),
+	),
+	mcp.WithString("name",
+		mcp.Description("Capsule name within workspace."),
+	),
+	mcp.WithBoolean("include_deleted",
+		mcp.Description("Include soft-deleted capsules (both the addressed capsule and bundles)"),
+	),
+)
+
+var relatedToolDef = mcp.NewTool("capsule_related",
+	mcp.WithDescription("Find capsules covering similar topics to a given one. Uses the capsule's most significant title/body terms as an OR full-text query; the source is excluded."),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("id",
+		mcp.Description("Capsule ID (ULID). Mutually exclusive with workspace+name."),
+	),
+	mcp.WithString("workspace",
+		mcp.Description("Workspace namespace (default: 'default')"),
+	),
+	mcp.WithString("name",
+		mcp.Description("Capsule name within workspace."),
+	),
+	mcp.WithNumber("limit",
+		mcp.Description("Max items to return (default: 10, max: 50)"),
+	),
+	mcp.WithBoolean("same_workspace",
+		mcp.Description("Only consider capsules in the source capsule's workspace"),
+	),
+)
+
+var topTermsToolDef = mcp.NewTool("capsule_top_terms",
+	mcp.WithDescription("Most common meaningful terms in a workspace's active capsules, ranked by how many capsules contain each. Skips stopwords, section headings, numbers, and terms under 3 characters. Useful for a quick sense of what a workspace is about."),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("workspace",
+		mcp.Description("Workspace namespace (default: 'default')"),
+	),
+	mcp.WithNumber("limit",
+		mcp.Description("Max terms to return (default: 20, max: 100)"),
+	),
+)
+
+var batchToolDef = mcp.NewTool("capsule_batch",
+	mcp.WithDescription("Run store/update/delete/move operations atomically in one transaction (max 50). If any op fails, none are applied; the error names the failing op as ops[i]."),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithArray("ops",
+		mcp.Required(),
+		mcp.Description("Operations in order. Each has an op type plus that operation's fields: store/update use the capsule_store/capsule_update fields, delete uses id OR (workspace+name), move uses the address plus to_workspace and/or to_name."),
+		mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"op":           map[string]any{"type": "string", "enum": []string{"store", "update", "delete", "move"}},
+				"id":           map[string]any{"type": "string", "description": "Capsule ID (update/delete/move)"},
+				"workspace":    map[string]any{"type": "string", "description": "Workspace namespace"},
+				"name":         map[string]any{"type": "string", "description": "Capsule name"},
+				"title":        map[string]any{"type": "string"},
+				"capsule_text": map[string]any{"type": "string"},
+				"tags":         map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				"source":       map[string]any{"type": "string"},
+				"run_id":       map[string]any{"type": "string"},
+				"phase":        map[string]any{"type": "string"},
+				"role":         map[string]any{"type": "string"},
+				"source_type":  map[string]any{"type": "string", "enum": []string{"agent", "human", "import"}},
+				"source_ref":   map[string]any{"type": "string"},
+				"note":         map[string]any{"type": "string"},
+				"expires_at":   map[string]any{"type": "number", "description": "Expiry Unix timestamp in seconds (store/update; 0 = none)"},
+				"mode":         map[string]any{"type": "string", "enum": []string{"error", "replace", "rename"}, "description": "Store collision mode"},
+				"allow_thin":   map[string]any{"type": "boolean"},
+				"to_workspace": map[string]any{"type": "string", "description": "Move destination workspace"},
+				"to_name":      map[string]any{"type": "string", "description": "Move destination name"},
+			},
+			"required": []string{"op"},
 		}),
 	),
 )
+
+var getConfigToolDef = mcp.NewTool("capsule_get_config",
+	mcp.WithDescription("Show the effective Moss configuration (defaults, global, and repo config merged), the keys capsule_set_config can change, and the enabled tools."),
+	mcp.WithReadOnlyHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+)
+
+var setConfigToolDef = mcp.NewTool("capsule_set_config",
+	mcp.WithDescription("Change one config setting at runtime and save it to the global config.json. "+
+		"Only archive_on_purge, capsule_max_bytes, capsule_max_chars, default_include_deleted, empty_query_lists_all, max_capsules_per_workspace, max_response_bytes, max_sections, min_search_term_len, normalize_on_store, and normalize_tags can be set; "+
+		"other keys (tools, paths, database, locale, web UI) require editing config.json and restarting."),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithString("key",
+		mcp.Required(),
+		mcp.Description("Config key, as named in config.json (e.g. capsule_max_chars)"),
+	),
+	mcp.WithAny("value",
+		mcp.Required(),
+		mcp.Description("New value: an integer for limits, a boolean for archive_on_purge, default_include_deleted, empty_query_lists_all, and normalize_* keys"),
+	),
+)
@@ -0,0 +1,72 @@
+package ops
+
+import (
+	"context"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+)
+
+// ActivityAction describes a capsule's most recent change.
+type ActivityAction string
+
+const (
+	ActivityCreated ActivityAction = "created"
+	ActivityUpdated ActivityAction = "updated"
+	ActivityDeleted ActivityAction = "deleted"
+)
+
+// ActivityInput contains parameters for the Activity operation.
+type ActivityInput struct {
+	Limit          int // default: 20, max: 100
+	IncludeDeleted bool
+}
+
+// ActivityItem is a capsule summary annotated with its most recent change.
+type ActivityItem struct {
+	SummaryItem
+	Action ActivityAction `json:"action"`
+}
+
+// ActivityOutput contains the result of the Activity operation.
+type ActivityOutput struct {
+	Items []ActivityItem `json:"items"`
+}
+
+// Activity returns the most recently changed capsules across all workspaces.
+func Activity(ctx context.Context, database db.Querier, input ActivityInput) (*ActivityOutput, error) {
+	// Apply limit defaults and bounds
+	limit := input.Limit
+	if limit <= 0 {
+		limit = DefaultActivityLimit
+	}
+	if limit > MaxActivityLimit {
+		limit = MaxActivityLimit
+	}
+
+	summaries, err := db.RecentActivity(ctx, database, limit, input.IncludeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]ActivityItem, len(summaries))
+	for i, s := range summaries {
+		items[i] = ActivityItem{
+			SummaryItem: SummaryToItem(s),
+			Action:      activityAction(s),
+		}
+	}
+
+	return &ActivityOutput{Items: items}, nil
+}
+
+// activityAction derives the most recent change from a summary's timestamps.
+func activityAction(s capsule.CapsuleSummary) ActivityAction {
+	if s.DeletedAt != nil && *s.DeletedAt >= s.UpdatedAt {
+		return ActivityDeleted
+	}
+	if s.CreatedAt == s.UpdatedAt {
+		return ActivityCreated
+	}
+	return ActivityUpdated
+}
@@ -0,0 +1,104 @@
+package ops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+)
+
+func TestActivity_Timeline(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+
+	// created at 100, never touched
+	created := newTestCapsuleForExport("01ACT001", "alpha", "Created")
+	created.CreatedAt, created.UpdatedAt = 100, 100
+
+	// created at 100, edited at 300
+	updated := newTestCapsuleForExport("01ACT002", "beta", "Updated")
+	updated.CreatedAt, updated.UpdatedAt = 100, 300
+
+	// created at 100, deleted at 400 (deleted_at set below; Insert ignores it)
+	deleted := newTestCapsuleForExport("01ACT003", "alpha", "Deleted")
+	deleted.CreatedAt, deleted.UpdatedAt = 100, 400
+
+	// same updated_at as updated: id DESC breaks the tie
+	tied := newTestCapsuleForExport("01ACT004", "gamma", "Tied")
+	tied.CreatedAt, tied.UpdatedAt = 300, 300
+
+	for _, c := range []*capsule.Capsule{created, updated, deleted, tied} {
+		if err := db.Insert(ctx, database, c); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	if _, err := database.Exec("UPDATE capsules SET deleted_at = 400 WHERE id = ?", deleted.ID); err != nil {
+		t.Fatalf("soft delete failed: %v", err)
+	}
+
+	output, err := Activity(ctx, database, ActivityInput{IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Activity failed: %v", err)
+	}
+
+	want := []struct {
+		id     string
+		action ActivityAction
+	}{
+		{"01ACT003", ActivityDeleted},
+		{"01ACT004", ActivityCreated},
+		{"01ACT002", ActivityUpdated},
+		{"01ACT001", ActivityCreated},
+	}
+	if len(output.Items) != len(want) {
+		t.Fatalf("items = %d, want %d", len(output.Items), len(want))
+	}
+	for i, w := range want {
+		got := output.Items[i]
+		if got.ID != w.id || got.Action != w.action {
+			t.Errorf("items[%d] = {%s, %s}, want {%s, %s}", i, got.ID, got.Action, w.id, w.action)
+		}
+	}
+
+	// Deleted capsules are hidden by default
+	output, err = Activity(ctx, database, ActivityInput{})
+	if err != nil {
+		t.Fatalf("Activity failed: %v", err)
+	}
+	if len(output.Items) != 3 || output.Items[0].ID != "01ACT004" {
+		t.Errorf("items without deleted = %+v, want 3 starting with 01ACT004", output.Items)
+	}
+
+	// Limit applies across workspaces
+	output, err = Activity(ctx, database, ActivityInput{Limit: 2, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Activity failed: %v", err)
+	}
+	if len(output.Items) != 2 {
+		t.Errorf("items with limit 2 = %d, want 2", len(output.Items))
+	}
+}
+
+func TestActivity_Empty(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	output, err := Activity(context.Background(), database, ActivityInput{})
+	if err != nil {
+		t.Fatalf("Activity failed: %v", err)
+	}
+	if output.Items == nil || len(output.Items) != 0 {
+		t.Errorf("Items = %v, want empty non-nil slice", output.Items)
+	}
+}
@@ -2,7 +2,6 @@ package ops
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"strings"
 
@@ -35,7 +34,9 @@ type AppendOutput struct {
 // Append adds content to a specific section of a capsule.
 // It finds the section by exact header name (case-insensitive)
 // and either replaces placeholder content or appends after existing content.
-func Append(ctx context.Context, database *sql.DB, cfg *config.Config, input AppendInput) (*AppendOutput, error) {
+func Append(ctx context.Context, database db.Querier, cfg *config.Config, input AppendInput) (*AppendOutput, error) {
+	defer invalidateSearchCache()
+
 	// Validate address
 	addr, err := ValidateAddress(input.ID, input.Workspace, input.Name)
 	if err != nil {
@@ -85,11 +86,13 @@ func Append(ctx context.Context, database *sql.DB, cfg *config.Config, input App
 	if cfg.CapsuleMaxChars > 0 && newChars > cfg.CapsuleMaxChars {
 		return nil, errors.NewCapsuleTooLarge(cfg.CapsuleMaxChars, newChars)
 	}
+	if err := checkCapsuleBytes(cfg, newText); err != nil {
+		return nil, err
+	}
 
 	// Update capsule fields
 	c.CapsuleText = newText
-	c.CapsuleChars = newChars
-	c.TokensEstimate = capsule.EstimateTokens(newText)
+	c.RecomputeMetrics()
 
 	// Persist update
 	if err := db.UpdateByID(ctx, database, c); err != nil {
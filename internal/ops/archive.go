@@ -0,0 +1,108 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// ListArchivedInput contains parameters for the ListArchived operation.
+type ListArchivedInput struct {
+	Workspace *string // optional filter by workspace
+	Limit     int     // default: 100, max: 500
+	Offset    int     // default: 0
+}
+
+// ListArchivedOutput contains the result of the ListArchived operation.
+type ListArchivedOutput struct {
+	Items      []db.ArchivedSummary `json:"items"`
+	Pagination Pagination           `json:"pagination"`
+	Sort       string               `json:"sort"`
+}
+
+// RestoreFromArchiveInput contains parameters for the RestoreFromArchive operation.
+type RestoreFromArchiveInput struct {
+	ID string // required, ID of an archived capsule
+}
+
+// RestoreFromArchiveOutput contains the result of the RestoreFromArchive operation.
+type RestoreFromArchiveOutput struct {
+	ID        string   `json:"id"`
+	FetchKey  FetchKey `json:"fetch_key"`
+	Workspace string   `json:"workspace"`
+	Name      *string  `json:"name,omitempty"`
+	Message   string   `json:"message"`
+}
+
+// ListArchived lists capsules moved to the archive by purge (with
+// config.ArchiveOnPurge), most recently archived first.
+func ListArchived(ctx context.Context, database db.Querier, input ListArchivedInput) (*ListArchivedOutput, error) {
+	var workspaceNorm *string
+	if input.Workspace != nil {
+		ws := capsule.Normalize(*input.Workspace)
+		if ws != "" {
+			workspaceNorm = &ws
+		}
+	}
+
+	limit := input.Limit
+	if limit <= 0 {
+		limit = DefaultInventoryLimit
+	}
+	if limit > MaxInventoryLimit {
+		limit = MaxInventoryLimit
+	}
+	offset := max(input.Offset, 0)
+
+	items, total, err := db.ListArchived(ctx, database, workspaceNorm, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []db.ArchivedSummary{}
+	}
+
+	return &ListArchivedOutput{
+		Items: items,
+		Pagination: Pagination{
+			Limit:   limit,
+			Offset:  offset,
+			HasMore: offset+len(items) < total,
+			Total:   total,
+		},
+		Sort: "archived_at_desc",
+	}, nil
+}
+
+// RestoreFromArchive moves an archived capsule back into the live store as an
+// active capsule. Links removed by the purge are not restored.
+func RestoreFromArchive(ctx context.Context, database *sql.DB, input RestoreFromArchiveInput) (*RestoreFromArchiveOutput, error) {
+	defer invalidateSearchCache()
+
+	id := strings.TrimSpace(input.ID)
+	if id == "" {
+		return nil, errors.NewInvalidRequest("id is required")
+	}
+
+	c, err := db.RestoreFromArchive(ctx, database, id)
+	if err != nil {
+		return nil, err
+	}
+
+	name := ""
+	if c.NameRaw != nil {
+		name = *c.NameRaw
+	}
+	return &RestoreFromArchiveOutput{
+		ID:        c.ID,
+		FetchKey:  BuildFetchKey(c.WorkspaceRaw, name, c.ID),
+		Workspace: c.WorkspaceRaw,
+		Name:      c.NameRaw,
+		Message:   fmt.Sprintf("Restored capsule %s from the archive", c.ID),
+	}, nil
+}
@@ -0,0 +1,136 @@
+package ops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestPurge_ArchiveAndRestore(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	var ids []string
+	for _, name := range []string{"keep", "gone"} {
+		out, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", Name: stringPtr(name), CapsuleText: validCapsuleText})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		ids = append(ids, out.ID)
+		if _, err := Delete(ctx, database, DeleteInput{ID: out.ID}); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+	}
+
+	output, err := Purge(ctx, database, PurgeInput{Archive: true})
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if output.Purged != 2 || !output.Archived {
+		t.Errorf("Purged = %d, Archived = %v; want 2, true", output.Purged, output.Archived)
+	}
+
+	// Gone from the live store, including search
+	if _, err := Fetch(ctx, database, FetchInput{ID: ids[0], IncludeDeleted: true}); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("purged capsule should be gone from capsules, got: %v", err)
+	}
+	results, err := Search(ctx, database, SearchInput{Query: "objective", IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results.Items) != 0 {
+		t.Errorf("search found %d archived capsules, want 0", len(results.Items))
+	}
+
+	archived, err := ListArchived(ctx, database, ListArchivedInput{Workspace: stringPtr("Proj")})
+	if err != nil {
+		t.Fatalf("ListArchived failed: %v", err)
+	}
+	if archived.Pagination.Total != 2 || len(archived.Items) != 2 {
+		t.Fatalf("archived total = %d, items = %d; want 2, 2", archived.Pagination.Total, len(archived.Items))
+	}
+	if archived.Items[0].ArchivedAt == 0 || archived.Items[0].DeletedAt == nil {
+		t.Errorf("archived item = %+v, want archived_at and deleted_at set", archived.Items[0])
+	}
+
+	restored, err := RestoreFromArchive(ctx, database, RestoreFromArchiveInput{ID: ids[0]})
+	if err != nil {
+		t.Fatalf("RestoreFromArchive failed: %v", err)
+	}
+	if restored.ID != ids[0] || restored.Name == nil || *restored.Name != "keep" {
+		t.Errorf("restored = %+v, want capsule keep", restored)
+	}
+
+	// Active again, searchable, and no longer archived
+	fetched, err := Fetch(ctx, database, FetchInput{Workspace: "proj", Name: "keep"})
+	if err != nil {
+		t.Fatalf("restored capsule should be fetchable: %v", err)
+	}
+	if fetched.CapsuleText != validCapsuleText {
+		t.Error("restored capsule text does not match the original")
+	}
+	results, err = Search(ctx, database, SearchInput{Query: "objective"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results.Items) != 1 || results.Items[0].ID != ids[0] {
+		t.Errorf("search after restore = %+v, want only the restored capsule", results.Items)
+	}
+	archived, err = ListArchived(ctx, database, ListArchivedInput{})
+	if err != nil {
+		t.Fatalf("ListArchived failed: %v", err)
+	}
+	if len(archived.Items) != 1 || archived.Items[0].ID != ids[1] {
+		t.Errorf("archive after restore = %+v, want only %s", archived.Items, ids[1])
+	}
+
+	// Restoring twice, or over a name that's taken again, fails
+	if _, err := RestoreFromArchive(ctx, database, RestoreFromArchiveInput{ID: ids[0]}); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("second restore error = %v, want NOT_FOUND", err)
+	}
+	if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", Name: stringPtr("gone"), CapsuleText: validCapsuleText}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := RestoreFromArchive(ctx, database, RestoreFromArchiveInput{ID: ids[1]}); !errors.Is(err, errors.ErrNameAlreadyExists) {
+		t.Errorf("restore over taken name error = %v, want NAME_ALREADY_EXISTS", err)
+	}
+}
+
+func TestPurge_WithoutArchiveDiscards(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	out, err := Store(ctx, database, config.DefaultConfig(), StoreInput{Workspace: "proj", CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := Delete(ctx, database, DeleteInput{ID: out.ID}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := Purge(ctx, database, PurgeInput{}); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+
+	archived, err := ListArchived(ctx, database, ListArchivedInput{})
+	if err != nil {
+		t.Fatalf("ListArchived failed: %v", err)
+	}
+	if len(archived.Items) != 0 {
+		t.Errorf("archive has %d items, want 0 without archive_on_purge", len(archived.Items))
+	}
+}
@@ -0,0 +1,230 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// MaxBatchOps is the maximum number of operations in a single batch.
+const MaxBatchOps = 50
+
+// BatchOpType identifies which operation a BatchOp carries.
+type BatchOpType string
+
+const (
+	BatchOpStore  BatchOpType = "store"
+	BatchOpUpdate BatchOpType = "update"
+	BatchOpDelete BatchOpType = "delete"
+	BatchOpMove   BatchOpType = "move"
+)
+
+// BatchOp is a tagged union: Op selects which of the payload fields is used.
+// Exactly the payload matching Op must be set.
+type BatchOp struct {
+	Op     BatchOpType
+	Store  *StoreInput
+	Update *UpdateInput
+	Delete *DeleteInput
+	Move   *MoveInput
+}
+
+// MoveInput contains parameters for a batch move: relocate a capsule to another
+// workspace and/or give it a new name. The capsule keeps its ID and links.
+type MoveInput struct {
+	// Addressing
+	ID        string
+	Workspace string
+	Name      string
+
+	// Destination (at least one required)
+	ToWorkspace *string // nil = keep current workspace
+	ToName      *string // nil = keep current name
+}
+
+// BatchOpResult is the outcome of one operation in a committed batch.
+type BatchOpResult struct {
+	Op       BatchOpType `json:"op"`
+	ID       string      `json:"id"`
+	FetchKey *FetchKey   `json:"fetch_key,omitempty"` // absent for delete
+}
+
+// BatchOutput contains the result of the Batch operation.
+type BatchOutput struct {
+	Results []BatchOpResult `json:"results"`
+}
+
+// Batch executes store/update/delete/move operations in a single transaction.
+// All-or-nothing: the first failing op rolls back every earlier op, and the
+// returned error is prefixed with its position ("ops[1]: ...").
+func Batch(ctx context.Context, database *sql.DB, cfg *config.Config, batch []BatchOp) (*BatchOutput, error) {
+	defer invalidateSearchCache()
+
+	if len(batch) == 0 {
+		return nil, errors.NewInvalidRequest("ops must not be empty")
+	}
+	if len(batch) > MaxBatchOps {
+		return nil, errors.NewInvalidRequest(
+			fmt.Sprintf("too many ops: %d (max %d)", len(batch), MaxBatchOps))
+	}
+
+	// Validate the union shape up front so a malformed op fails before any writes
+	for i, op := range batch {
+		if err := validateBatchOp(op); err != nil {
+			return nil, fmt.Errorf("ops[%d]: %w", i, err)
+		}
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("batch")
+		}
+		return nil, errors.NewInternal(err)
+	}
+	defer db.Rollback(tx) //nolint:errcheck
+
+	results := make([]BatchOpResult, 0, len(batch))
+	for i, op := range batch {
+		select {
+		case <-ctx.Done():
+			return nil, errors.NewCancelled("batch")
+		default:
+		}
+
+		result, err := runBatchOp(ctx, tx, cfg, op)
+		if err != nil {
+			return nil, fmt.Errorf("ops[%d]: %w", i, err)
+		}
+		results = append(results, *result)
+	}
+
+	if err := db.Commit(tx); err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("batch")
+		}
+		return nil, errors.NewInternal(err)
+	}
+
+	return &BatchOutput{Results: results}, nil
+}
+
+// validateBatchOp checks that op carries exactly the payload named by its type.
+func validateBatchOp(op BatchOp) error {
+	set := 0
+	for _, present := range []bool{op.Store != nil, op.Update != nil, op.Delete != nil, op.Move != nil} {
+		if present {
+			set++
+		}
+	}
+
+	var matches bool
+	switch op.Op {
+	case BatchOpStore:
+		matches = op.Store != nil
+	case BatchOpUpdate:
+		matches = op.Update != nil
+	case BatchOpDelete:
+		matches = op.Delete != nil
+	case BatchOpMove:
+		matches = op.Move != nil
+	default:
+		return errors.NewInvalidRequest("op must be one of: store, update, delete, move")
+	}
+	if !matches || set != 1 {
+		return errors.NewInvalidRequest(fmt.Sprintf("op %q must carry exactly its own payload", op.Op))
+	}
+	return nil
+}
+
+// runBatchOp dispatches a validated op to its Querier-based implementation.
+func runBatchOp(ctx context.Context, q db.Querier, cfg *config.Config, op BatchOp) (*BatchOpResult, error) {
+	switch op.Op {
+	case BatchOpStore:
+		out, err := store(ctx, q, cfg, *op.Store)
+		if err != nil {
+			return nil, err
+		}
+		return &BatchOpResult{Op: op.Op, ID: out.ID, FetchKey: &out.FetchKey}, nil
+	case BatchOpUpdate:
+		out, err := update(ctx, q, cfg, *op.Update)
+		if err != nil {
+			return nil, err
+		}
+		return &BatchOpResult{Op: op.Op, ID: out.ID, FetchKey: &out.FetchKey}, nil
+	case BatchOpDelete:
+		out, err := deleteCapsule(ctx, q, *op.Delete)
+		if err != nil {
+			return nil, err
+		}
+		return &BatchOpResult{Op: op.Op, ID: out.ID}, nil
+	default: // BatchOpMove
+		c, err := moveCapsule(ctx, q, cfg, *op.Move)
+		if err != nil {
+			return nil, err
+		}
+		name := ""
+		if c.NameRaw != nil {
+			name = *c.NameRaw
+		}
+		fetchKey := BuildFetchKey(c.WorkspaceRaw, name, c.ID)
+		return &BatchOpResult{Op: op.Op, ID: c.ID, FetchKey: &fetchKey}, nil
+	}
+}
+
+// moveCapsule changes an active capsule's workspace and/or name in place.
+// Name collisions at the destination return NAME_ALREADY_EXISTS.
+func moveCapsule(ctx context.Context, q db.Querier, cfg *config.Config, input MoveInput) (*capsule.Capsule, error) {
+	addr, err := ValidateAddress(input.ID, input.Workspace, input.Name)
+	if err != nil {
+		return nil, err
+	}
+	if input.ToWorkspace == nil && input.ToName == nil {
+		return nil, errors.NewInvalidRequest("move requires to_workspace or to_name")
+	}
+
+	var c *capsule.Capsule
+	if addr.ByID {
+		c, err = db.GetByID(ctx, q, addr.ID, false)
+	} else {
+		c, err = db.GetByName(ctx, q, addr.Workspace, addr.Name, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if input.ToWorkspace != nil {
+		workspaceNorm := capsule.Normalize(*input.ToWorkspace)
+		if workspaceNorm == "" {
+			return nil, errors.NewInvalidRequest("to_workspace must not be empty")
+		}
+		if workspaceNorm != c.WorkspaceNorm && cfg.MaxCapsulesPerWorkspace > 0 {
+			if err := checkWorkspaceQuota(ctx, q, cfg.MaxCapsulesPerWorkspace, *input.ToWorkspace, workspaceNorm); err != nil {
+				return nil, err
+			}
+		}
+		c.WorkspaceRaw = *input.ToWorkspace
+		c.WorkspaceNorm = workspaceNorm
+	}
+
+	if input.ToName != nil {
+		nameNorm := capsule.Normalize(*input.ToName)
+		if nameNorm == "" {
+			return nil, errors.NewInvalidRequest("to_name must not be empty")
+		}
+		c.NameRaw = input.ToName
+		c.NameNorm = &nameNorm
+	}
+
+	c.UpdatedAt = time.Now().Unix()
+	if err := db.UpdateFull(ctx, q, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
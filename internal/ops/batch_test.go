@@ -0,0 +1,184 @@
+package ops
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestBatch_DeleteAndStoreCommits(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	old, err := Store(context.Background(), database, cfg, StoreInput{
+		Name:        stringPtr("plan-v1"),
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := Batch(context.Background(), database, cfg, []BatchOp{
+		{Op: BatchOpDelete, Delete: &DeleteInput{ID: old.ID}},
+		{Op: BatchOpStore, Store: &StoreInput{Name: stringPtr("plan-v2"), CapsuleText: validCapsuleText}},
+	})
+	if err != nil {
+		t.Fatalf("Batch failed: %v", err)
+	}
+
+	if len(output.Results) != 2 {
+		t.Fatalf("len(Results) = %d, want 2", len(output.Results))
+	}
+	if output.Results[0].Op != BatchOpDelete || output.Results[0].ID != old.ID {
+		t.Errorf("Results[0] = %+v, want delete of %s", output.Results[0], old.ID)
+	}
+	if output.Results[0].FetchKey != nil {
+		t.Error("delete result should not carry a fetch_key")
+	}
+	if output.Results[1].Op != BatchOpStore || output.Results[1].FetchKey == nil ||
+		output.Results[1].FetchKey.MossCapsule != "plan-v2" {
+		t.Errorf("Results[1] = %+v, want store of plan-v2", output.Results[1])
+	}
+
+	if _, err := Fetch(context.Background(), database, FetchInput{ID: old.ID}); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("old capsule should be deleted, got: %v", err)
+	}
+	if _, err := Fetch(context.Background(), database, FetchInput{Workspace: "default", Name: "plan-v2"}); err != nil {
+		t.Errorf("new capsule should exist: %v", err)
+	}
+}
+
+func TestBatch_FailureRollsBackEarlierOps(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	existing, err := Store(context.Background(), database, cfg, StoreInput{
+		Name:        stringPtr("keep"),
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	// Op 0 deletes, op 1 collides with an existing name
+	if _, err := Store(context.Background(), database, cfg, StoreInput{
+		Name:        stringPtr("taken"),
+		CapsuleText: validCapsuleText,
+	}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	_, err = Batch(context.Background(), database, cfg, []BatchOp{
+		{Op: BatchOpDelete, Delete: &DeleteInput{ID: existing.ID}},
+		{Op: BatchOpStore, Store: &StoreInput{Name: stringPtr("taken"), CapsuleText: validCapsuleText}},
+	})
+	if !errors.Is(err, errors.ErrNameAlreadyExists) {
+		t.Fatalf("Batch error = %v, want NAME_ALREADY_EXISTS", err)
+	}
+	if !strings.HasPrefix(err.Error(), "ops[1]: ") {
+		t.Errorf("error %q should identify the failing op", err.Error())
+	}
+
+	// Op 0's delete was rolled back
+	if _, err := Fetch(context.Background(), database, FetchInput{ID: existing.ID}); err != nil {
+		t.Errorf("capsule deleted by rolled-back op should still be active: %v", err)
+	}
+}
+
+func TestBatch_Move(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	stored, err := Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "drafts",
+		Name:        stringPtr("auth"),
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := Batch(context.Background(), database, cfg, []BatchOp{
+		{Op: BatchOpMove, Move: &MoveInput{
+			Workspace:   "drafts",
+			Name:        "auth",
+			ToWorkspace: stringPtr("final"),
+			ToName:      stringPtr("auth-design"),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Batch failed: %v", err)
+	}
+	if output.Results[0].ID != stored.ID {
+		t.Errorf("move should keep the ID: got %s, want %s", output.Results[0].ID, stored.ID)
+	}
+
+	moved, err := Fetch(context.Background(), database, FetchInput{Workspace: "final", Name: "auth-design"})
+	if err != nil {
+		t.Fatalf("moved capsule not found at destination: %v", err)
+	}
+	if moved.ID != stored.ID {
+		t.Errorf("ID = %s, want %s", moved.ID, stored.ID)
+	}
+	if _, err := Fetch(context.Background(), database, FetchInput{Workspace: "drafts", Name: "auth"}); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("old address should be gone, got: %v", err)
+	}
+}
+
+func TestBatch_Validation(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	tooMany := make([]BatchOp, MaxBatchOps+1)
+	for i := range tooMany {
+		tooMany[i] = BatchOp{Op: BatchOpDelete, Delete: &DeleteInput{ID: "x"}}
+	}
+
+	tests := []struct {
+		name  string
+		batch []BatchOp
+	}{
+		{"empty", nil},
+		{"too many", tooMany},
+		{"unknown op", []BatchOp{{Op: "rename"}}},
+		{"missing payload", []BatchOp{{Op: BatchOpStore}}},
+		{"mismatched payload", []BatchOp{{Op: BatchOpStore, Delete: &DeleteInput{ID: "x"}}}},
+		{"move without destination", []BatchOp{{Op: BatchOpMove, Move: &MoveInput{ID: "x"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Batch(context.Background(), database, cfg, tt.batch)
+			if !errors.Is(err, errors.ErrInvalidRequest) {
+				t.Errorf("Batch error = %v, want INVALID_REQUEST", err)
+			}
+		})
+	}
+}
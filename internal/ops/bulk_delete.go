@@ -2,7 +2,6 @@ package ops
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"strings"
 
@@ -13,23 +12,29 @@ import (
 
 // BulkDeleteInput contains parameters for the BulkDelete operation.
 type BulkDeleteInput struct {
-	Workspace  *string
-	Tag        *string
-	NamePrefix *string
-	RunID      *string
-	Phase      *string
-	Role       *string
+	Workspace     *string
+	Tag           *string
+	NamePrefix    *string
+	RunID         *string
+	Phase         *string
+	Role          *string
+	NormalizeTags bool // from config.NormalizeTags; normalizes the tag filter
+	DryRun        bool // count the matching capsules without deleting them
 }
 
 // BulkDeleteOutput contains the result of the BulkDelete operation.
 type BulkDeleteOutput struct {
-	Deleted int    `json:"deleted"`
-	Message string `json:"message"`
+	Deleted     int    `json:"deleted"`
+	DryRun      bool   `json:"dry_run,omitempty"`
+	WouldAffect *int   `json:"would_affect,omitempty"` // dry-run only
+	Message     string `json:"message"`
 }
 
 // BulkDelete soft-deletes all active capsules matching the given filters.
 // At least one filter must be provided (safety guard).
-func BulkDelete(ctx context.Context, database *sql.DB, input BulkDeleteInput) (*BulkDeleteOutput, error) {
+func BulkDelete(ctx context.Context, database db.Querier, input BulkDeleteInput) (*BulkDeleteOutput, error) {
+	defer invalidateSearchCache()
+
 	// Phase 1: at least one filter must be non-nil
 	if !hasAnyFilter(input) {
 		return nil, errors.NewInvalidRequest("at least one filter is required")
@@ -43,12 +48,7 @@ func BulkDelete(ctx context.Context, database *sql.DB, input BulkDeleteInput) (*
 			filters.Workspace = &workspace
 		}
 	}
-	if input.Tag != nil {
-		tag := strings.TrimSpace(*input.Tag)
-		if tag != "" {
-			filters.Tag = &tag
-		}
-	}
+	filters.Tag = cleanTagFilter(input.Tag, input.NormalizeTags)
 	if input.NamePrefix != nil {
 		prefix := capsule.Normalize(*input.NamePrefix)
 		if prefix != "" {
@@ -64,6 +64,18 @@ func BulkDelete(ctx context.Context, database *sql.DB, input BulkDeleteInput) (*
 		return nil, errors.NewInvalidRequest("at least one filter must be non-empty after normalization")
 	}
 
+	if input.DryRun {
+		n, err := db.CountBulkActive(ctx, database, filters)
+		if err != nil {
+			return nil, err
+		}
+		return &BulkDeleteOutput{
+			DryRun:      true,
+			WouldAffect: &n,
+			Message:     formatBulkDryRunMessage("soft-delete", n, filters),
+		}, nil
+	}
+
 	count, err := db.BulkSoftDelete(ctx, database, filters)
 	if err != nil {
 		return nil, err
@@ -107,7 +119,34 @@ func formatBulkDeleteMessage(count int, filters db.InventoryFilters) string {
 	}
 
 	msg := fmt.Sprintf("Soft-deleted %d %s", count, capsuleWord)
+	if desc := describeBulkFilters(filters); desc != "" {
+		msg += " matching " + desc
+	}
+
+	return msg
+}
+
+// formatBulkDryRunMessage describes what a dry-run bulk operation would do.
+func formatBulkDryRunMessage(verb string, count int, filters db.InventoryFilters) string {
+	if count == 0 {
+		return "No active capsules matched the filters"
+	}
 
+	capsuleWord := "capsule"
+	if count > 1 {
+		capsuleWord = "capsules"
+	}
+
+	msg := fmt.Sprintf("Would %s %d %s", verb, count, capsuleWord)
+	if desc := describeBulkFilters(filters); desc != "" {
+		msg += " matching " + desc
+	}
+
+	return msg
+}
+
+// describeBulkFilters lists the effective filters as key=value pairs.
+func describeBulkFilters(filters db.InventoryFilters) string {
 	var parts []string
 	if filters.Workspace != nil {
 		parts = append(parts, fmt.Sprintf("workspace=%q", *filters.Workspace))
@@ -128,9 +167,5 @@ func formatBulkDeleteMessage(count int, filters db.InventoryFilters) string {
 		parts = append(parts, fmt.Sprintf("role=%q", *filters.Role))
 	}
 
-	if len(parts) > 0 {
-		msg += " matching " + strings.Join(parts, ", ")
-	}
-
-	return msg
+	return strings.Join(parts, ", ")
 }
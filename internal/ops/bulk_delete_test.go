@@ -457,3 +457,82 @@ func TestBulkDelete_NoMatchesReturnsZero(t *testing.T) {
 		t.Errorf("Message = %q, want 'No active capsules matched the filters'", output.Message)
 	}
 }
+
+func TestBulkDelete_DryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	// Two active in ws1, one already deleted in ws1, one active in ws2
+	var ws1IDs []string
+	for i := 0; i < 2; i++ {
+		out, err := Store(context.Background(), database, cfg, StoreInput{Workspace: "ws1", CapsuleText: validCapsuleText})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		ws1IDs = append(ws1IDs, out.ID)
+	}
+	deleted, err := Store(context.Background(), database, cfg, StoreInput{Workspace: "ws1", CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := Delete(context.Background(), database, DeleteInput{ID: deleted.ID}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := Store(context.Background(), database, cfg, StoreInput{Workspace: "ws2", CapsuleText: validCapsuleText}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	ws := "ws1"
+	output, err := BulkDelete(context.Background(), database, BulkDeleteInput{Workspace: &ws, DryRun: true})
+	if err != nil {
+		t.Fatalf("BulkDelete dry run failed: %v", err)
+	}
+	if !output.DryRun {
+		t.Error("DryRun = false, want true")
+	}
+	if output.WouldAffect == nil || *output.WouldAffect != 2 {
+		t.Errorf("WouldAffect = %v, want 2", output.WouldAffect)
+	}
+	if output.Deleted != 0 {
+		t.Errorf("Deleted = %d, want 0", output.Deleted)
+	}
+
+	// Nothing was deleted
+	for _, id := range ws1IDs {
+		if _, err := db.GetByID(context.Background(), database, id, false); err != nil {
+			t.Errorf("capsule %s should still be active after dry run: %v", id, err)
+		}
+	}
+
+	// The real run affects exactly the previewed count
+	output, err = BulkDelete(context.Background(), database, BulkDeleteInput{Workspace: &ws})
+	if err != nil {
+		t.Fatalf("BulkDelete failed: %v", err)
+	}
+	if output.Deleted != 2 {
+		t.Errorf("Deleted = %d, want 2", output.Deleted)
+	}
+	if output.WouldAffect != nil {
+		t.Errorf("WouldAffect = %v, want nil outside dry run", *output.WouldAffect)
+	}
+}
+
+func TestBulkDelete_DryRunNoFiltersError(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	_, err = BulkDelete(context.Background(), database, BulkDeleteInput{DryRun: true})
+	if err == nil {
+		t.Fatal("expected error for dry run without filters")
+	}
+}
@@ -0,0 +1,139 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// BulkMoveInput contains parameters for the BulkMove operation.
+type BulkMoveInput struct {
+	// Filters
+	Workspace  *string
+	Tag        *string
+	NamePrefix *string
+	RunID      *string
+	Phase      *string
+	Role       *string
+	// Destination workspace (required)
+	ToWorkspace     string
+	NormalizeTags   bool // from config.NormalizeTags; normalizes the tag filter
+	MaxPerWorkspace int  // from config.MaxCapsulesPerWorkspace; 0 = unlimited
+}
+
+// BulkMoveOutput contains the result of the BulkMove operation.
+type BulkMoveOutput struct {
+	Moved     int    `json:"moved"`
+	Workspace string `json:"workspace"` // destination
+	Message   string `json:"message"`
+}
+
+// BulkMove moves all active capsules matching the given filters into another
+// workspace. At least one filter must be provided (safety guard). If any named
+// capsule would collide with an active capsule in the destination, nothing is
+// moved and NAME_ALREADY_EXISTS reports the first conflict. If the move would
+// take the destination past MaxPerWorkspace active capsules, nothing is moved
+// and QUOTA_EXCEEDED is returned.
+func BulkMove(ctx context.Context, database *sql.DB, input BulkMoveInput) (*BulkMoveOutput, error) {
+	defer invalidateSearchCache()
+
+	// Phase 1: at least one filter must be non-nil
+	if !hasAnyBulkMoveFilter(input) {
+		return nil, errors.NewInvalidRequest("at least one filter is required")
+	}
+
+	toWorkspace := strings.TrimSpace(input.ToWorkspace)
+	if capsule.Normalize(toWorkspace) == "" {
+		return nil, errors.NewInvalidRequest("to_workspace is required")
+	}
+
+	// Normalize filters
+	var filters db.InventoryFilters
+	if input.Workspace != nil {
+		workspace := capsule.Normalize(*input.Workspace)
+		if workspace != "" {
+			filters.Workspace = &workspace
+		}
+	}
+	filters.Tag = cleanTagFilter(input.Tag, input.NormalizeTags)
+	if input.NamePrefix != nil {
+		prefix := capsule.Normalize(*input.NamePrefix)
+		if prefix != "" {
+			filters.NamePrefix = &prefix
+		}
+	}
+	filters.RunID = cleanOptionalString(input.RunID)
+	filters.Phase = cleanOptionalString(input.Phase)
+	filters.Role = cleanOptionalString(input.Role)
+
+	// Phase 2: at least one filter must be non-empty after normalization
+	if !hasAnyEffectiveFilter(filters) {
+		return nil, errors.NewInvalidRequest("at least one filter must be non-empty after normalization")
+	}
+
+	count, err := db.BulkMove(ctx, database, filters, toWorkspace, input.MaxPerWorkspace)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BulkMoveOutput{
+		Moved:     count,
+		Workspace: toWorkspace,
+		Message:   formatBulkMoveMessage(count, filters, toWorkspace),
+	}, nil
+}
+
+// hasAnyBulkMoveFilter checks if any filter field is non-nil.
+func hasAnyBulkMoveFilter(input BulkMoveInput) bool {
+	return input.Workspace != nil ||
+		input.Tag != nil ||
+		input.NamePrefix != nil ||
+		input.RunID != nil ||
+		input.Phase != nil ||
+		input.Role != nil
+}
+
+// formatBulkMoveMessage creates a human-readable message for the bulk move result.
+func formatBulkMoveMessage(count int, filters db.InventoryFilters, toWorkspace string) string {
+	if count == 0 {
+		return "No active capsules matched the filters"
+	}
+
+	capsuleWord := "capsule"
+	if count > 1 {
+		capsuleWord = "capsules"
+	}
+
+	msg := fmt.Sprintf("Moved %d %s to workspace %q", count, capsuleWord, toWorkspace)
+
+	var parts []string
+	if filters.Workspace != nil {
+		parts = append(parts, fmt.Sprintf("workspace=%q", *filters.Workspace))
+	}
+	if filters.Tag != nil {
+		parts = append(parts, fmt.Sprintf("tag=%q", *filters.Tag))
+	}
+	if filters.NamePrefix != nil {
+		parts = append(parts, fmt.Sprintf("name_prefix=%q", *filters.NamePrefix))
+	}
+	if filters.RunID != nil {
+		parts = append(parts, fmt.Sprintf("run_id=%q", *filters.RunID))
+	}
+	if filters.Phase != nil {
+		parts = append(parts, fmt.Sprintf("phase=%q", *filters.Phase))
+	}
+	if filters.Role != nil {
+		parts = append(parts, fmt.Sprintf("role=%q", *filters.Role))
+	}
+
+	if len(parts) > 0 {
+		msg += " matching " + strings.Join(parts, ", ")
+	}
+
+	return msg
+}
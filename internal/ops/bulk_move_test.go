@@ -0,0 +1,190 @@
+package ops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestBulkMove_ByTag(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	plan, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws1", Name: stringPtr("plan"), Tags: []string{"auth"}, CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	unnamed, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws2", Tags: []string{"auth"}, CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	untagged, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws1", Name: stringPtr("notes"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := BulkMove(ctx, database, BulkMoveInput{Tag: stringPtr("auth"), ToWorkspace: "  Security  "})
+	if err != nil {
+		t.Fatalf("BulkMove failed: %v", err)
+	}
+	if output.Moved != 2 {
+		t.Errorf("Moved = %d, want 2", output.Moved)
+	}
+	if output.Workspace != "Security" {
+		t.Errorf("Workspace = %q, want %q", output.Workspace, "Security")
+	}
+
+	// Named capsule is now addressable in the destination
+	moved, err := Fetch(ctx, database, FetchInput{Workspace: "security", Name: "plan"})
+	if err != nil {
+		t.Fatalf("Fetch moved capsule failed: %v", err)
+	}
+	if moved.ID != plan.ID || moved.Workspace != "Security" || moved.WorkspaceNorm != "security" {
+		t.Errorf("moved = (%s, %q, %q), want (%s, Security, security)", moved.ID, moved.Workspace, moved.WorkspaceNorm, plan.ID)
+	}
+	if _, err := Fetch(ctx, database, FetchInput{Workspace: "ws1", Name: "plan"}); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("old address should be gone, got: %v", err)
+	}
+
+	c, err := db.GetByID(ctx, database, unnamed.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if c.WorkspaceNorm != "security" {
+		t.Errorf("unnamed capsule workspace = %q, want security", c.WorkspaceNorm)
+	}
+
+	// Capsules outside the filter stay put
+	c, err = db.GetByID(ctx, database, untagged.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if c.WorkspaceNorm != "ws1" {
+		t.Errorf("untagged capsule workspace = %q, want ws1", c.WorkspaceNorm)
+	}
+}
+
+func TestBulkMove_CollisionMovesNothing(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	var ids []string
+	for _, name := range []string{"alpha", "plan", "zeta"} {
+		out, err := Store(ctx, database, cfg, StoreInput{Workspace: "src", Name: stringPtr(name), CapsuleText: validCapsuleText})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		ids = append(ids, out.ID)
+	}
+	holder, err := Store(ctx, database, cfg, StoreInput{Workspace: "dst", Name: stringPtr("Plan"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	_, err = BulkMove(ctx, database, BulkMoveInput{Workspace: stringPtr("src"), ToWorkspace: "dst"})
+	if !errors.Is(err, errors.ErrNameAlreadyExists) {
+		t.Fatalf("expected NAME_ALREADY_EXISTS, got: %v", err)
+	}
+	mossErr, ok := err.(*errors.MossError)
+	if !ok {
+		t.Fatalf("expected *MossError, got %T", err)
+	}
+	if mossErr.Details["name"] != "plan" {
+		t.Errorf("conflict name = %v, want plan", mossErr.Details["name"])
+	}
+	if mossErr.Details["id"] != holder.ID {
+		t.Errorf("conflict id = %v, want %s", mossErr.Details["id"], holder.ID)
+	}
+
+	// The transaction rolled back: even capsules checked before the conflict stay put
+	for _, id := range ids {
+		c, err := db.GetByID(ctx, database, id, false)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if c.WorkspaceNorm != "src" {
+			t.Errorf("capsule %s workspace = %q, want src", id, c.WorkspaceNorm)
+		}
+	}
+}
+
+func TestBulkMove_RespectsWorkspaceQuota(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	for _, ws := range []string{"src", "src", "dst"} {
+		if _, err := Store(ctx, database, cfg, StoreInput{Workspace: ws, CapsuleText: validCapsuleText}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	// dst holds 1; moving 2 more would make 3 > 2
+	_, err = BulkMove(ctx, database, BulkMoveInput{Workspace: stringPtr("src"), ToWorkspace: "dst", MaxPerWorkspace: 2})
+	if !errors.Is(err, errors.ErrQuotaExceeded) {
+		t.Fatalf("expected QUOTA_EXCEEDED, got: %v", err)
+	}
+	if n, err := db.CountActiveInWorkspace(ctx, database, "src"); err != nil || n != 2 {
+		t.Errorf("src count = %d (%v), want 2 (nothing moved)", n, err)
+	}
+
+	// Exactly reaching the limit is allowed
+	out, err := BulkMove(ctx, database, BulkMoveInput{Workspace: stringPtr("src"), ToWorkspace: "dst", MaxPerWorkspace: 3})
+	if err != nil {
+		t.Fatalf("BulkMove failed: %v", err)
+	}
+	if out.Moved != 2 {
+		t.Errorf("Moved = %d, want 2", out.Moved)
+	}
+}
+
+func TestBulkMove_Validation(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		input BulkMoveInput
+	}{
+		{"no filter", BulkMoveInput{ToWorkspace: "dst"}},
+		{"empty filter", BulkMoveInput{Workspace: stringPtr("  "), ToWorkspace: "dst"}},
+		{"no destination", BulkMoveInput{Workspace: stringPtr("src")}},
+		{"blank destination", BulkMoveInput{Workspace: stringPtr("src"), ToWorkspace: "   "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BulkMove(ctx, database, tt.input); !errors.Is(err, errors.ErrInvalidRequest) {
+				t.Errorf("expected INVALID_REQUEST, got: %v", err)
+			}
+		})
+	}
+}
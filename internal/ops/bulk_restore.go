@@ -0,0 +1,130 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// BulkRestoreInput contains parameters for the BulkRestore operation.
+type BulkRestoreInput struct {
+	Workspace     *string
+	Tag           *string
+	NamePrefix    *string
+	RunID         *string
+	Phase         *string
+	Role          *string
+	NormalizeTags bool // from config.NormalizeTags; normalizes the tag filter
+}
+
+// BulkRestoreOutput contains the result of the BulkRestore operation.
+type BulkRestoreOutput struct {
+	Restored int    `json:"restored"`
+	Skipped  int    `json:"skipped"` // name now held by an active capsule
+	Message  string `json:"message"`
+}
+
+// BulkRestore un-deletes all soft-deleted capsules matching the given filters.
+// At least one filter must be provided (safety guard). Capsules whose name
+// collides with an active capsule are skipped and counted, not restored.
+func BulkRestore(ctx context.Context, database *sql.DB, input BulkRestoreInput) (*BulkRestoreOutput, error) {
+	defer invalidateSearchCache()
+
+	// Phase 1: at least one filter must be non-nil
+	if !hasAnyBulkRestoreFilter(input) {
+		return nil, errors.NewInvalidRequest("at least one filter is required")
+	}
+
+	// Normalize filters
+	var filters db.InventoryFilters
+	if input.Workspace != nil {
+		workspace := capsule.Normalize(*input.Workspace)
+		if workspace != "" {
+			filters.Workspace = &workspace
+		}
+	}
+	filters.Tag = cleanTagFilter(input.Tag, input.NormalizeTags)
+	if input.NamePrefix != nil {
+		prefix := capsule.Normalize(*input.NamePrefix)
+		if prefix != "" {
+			filters.NamePrefix = &prefix
+		}
+	}
+	filters.RunID = cleanOptionalString(input.RunID)
+	filters.Phase = cleanOptionalString(input.Phase)
+	filters.Role = cleanOptionalString(input.Role)
+
+	// Phase 2: at least one filter must be non-empty after normalization
+	if !hasAnyEffectiveFilter(filters) {
+		return nil, errors.NewInvalidRequest("at least one filter must be non-empty after normalization")
+	}
+
+	restored, skipped, err := db.BulkRestore(ctx, database, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BulkRestoreOutput{
+		Restored: restored,
+		Skipped:  skipped,
+		Message:  formatBulkRestoreMessage(restored, skipped, filters),
+	}, nil
+}
+
+// hasAnyBulkRestoreFilter checks if any filter field is non-nil.
+func hasAnyBulkRestoreFilter(input BulkRestoreInput) bool {
+	return input.Workspace != nil ||
+		input.Tag != nil ||
+		input.NamePrefix != nil ||
+		input.RunID != nil ||
+		input.Phase != nil ||
+		input.Role != nil
+}
+
+// formatBulkRestoreMessage creates a human-readable message for the bulk restore result.
+func formatBulkRestoreMessage(restored, skipped int, filters db.InventoryFilters) string {
+	if restored == 0 && skipped == 0 {
+		return "No deleted capsules matched the filters"
+	}
+
+	capsuleWord := "capsule"
+	if restored != 1 {
+		capsuleWord = "capsules"
+	}
+
+	msg := fmt.Sprintf("Restored %d %s", restored, capsuleWord)
+
+	var parts []string
+	if filters.Workspace != nil {
+		parts = append(parts, fmt.Sprintf("workspace=%q", *filters.Workspace))
+	}
+	if filters.Tag != nil {
+		parts = append(parts, fmt.Sprintf("tag=%q", *filters.Tag))
+	}
+	if filters.NamePrefix != nil {
+		parts = append(parts, fmt.Sprintf("name_prefix=%q", *filters.NamePrefix))
+	}
+	if filters.RunID != nil {
+		parts = append(parts, fmt.Sprintf("run_id=%q", *filters.RunID))
+	}
+	if filters.Phase != nil {
+		parts = append(parts, fmt.Sprintf("phase=%q", *filters.Phase))
+	}
+	if filters.Role != nil {
+		parts = append(parts, fmt.Sprintf("role=%q", *filters.Role))
+	}
+
+	if len(parts) > 0 {
+		msg += " matching " + strings.Join(parts, ", ")
+	}
+	if skipped > 0 {
+		msg += fmt.Sprintf("; skipped %d (name in use by an active capsule)", skipped)
+	}
+
+	return msg
+}
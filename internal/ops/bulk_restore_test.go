@@ -0,0 +1,144 @@
+package ops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestBulkRestore_WorkspaceWithNameCollision(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// ws1: two named capsules and one unnamed; ws2: one capsule that must stay deleted
+	plan, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws1", Name: stringPtr("plan"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	notes, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws1", Name: stringPtr("notes"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	unnamed, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws1", CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	other, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws2", Name: stringPtr("plan"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	for _, ws := range []string{"ws1", "ws2"} {
+		if _, err := BulkDelete(ctx, database, BulkDeleteInput{Workspace: stringPtr(ws)}); err != nil {
+			t.Fatalf("BulkDelete(%s) failed: %v", ws, err)
+		}
+	}
+
+	// A new active "notes" takes the name while the old one is deleted
+	replacement, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws1", Name: stringPtr("notes"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store replacement failed: %v", err)
+	}
+
+	output, err := BulkRestore(ctx, database, BulkRestoreInput{Workspace: stringPtr("WS1")})
+	if err != nil {
+		t.Fatalf("BulkRestore failed: %v", err)
+	}
+	if output.Restored != 2 {
+		t.Errorf("Restored = %d, want 2", output.Restored)
+	}
+	if output.Skipped != 1 {
+		t.Errorf("Skipped = %d, want 1", output.Skipped)
+	}
+
+	// Restored capsules are active again
+	for _, id := range []string{plan.ID, unnamed.ID} {
+		if _, err := db.GetByID(ctx, database, id, false); err != nil {
+			t.Errorf("capsule %s should be active after restore: %v", id, err)
+		}
+	}
+
+	// The colliding capsule stays deleted; the live one is untouched
+	if _, err := db.GetByID(ctx, database, notes.ID, false); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("colliding capsule should stay deleted, got: %v", err)
+	}
+	if _, err := db.GetByID(ctx, database, replacement.ID, false); err != nil {
+		t.Errorf("live capsule should be untouched: %v", err)
+	}
+
+	// Other workspaces are not affected
+	if _, err := db.GetByID(ctx, database, other.ID, false); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("ws2 capsule should stay deleted, got: %v", err)
+	}
+}
+
+func TestBulkRestore_DuplicateDeletedNames(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// Two generations of "plan", both deleted
+	older, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws1", Name: stringPtr("plan"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := Delete(ctx, database, DeleteInput{ID: older.ID}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	newer, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws1", Name: stringPtr("plan"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := Delete(ctx, database, DeleteInput{ID: newer.ID}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	// Same-second deletes tie on deleted_at; make the order explicit
+	if _, err := database.Exec("UPDATE capsules SET deleted_at = deleted_at - 10 WHERE id = ?", older.ID); err != nil {
+		t.Fatalf("adjust deleted_at failed: %v", err)
+	}
+
+	output, err := BulkRestore(ctx, database, BulkRestoreInput{NamePrefix: stringPtr("plan")})
+	if err != nil {
+		t.Fatalf("BulkRestore failed: %v", err)
+	}
+	if output.Restored != 1 || output.Skipped != 1 {
+		t.Errorf("Restored/Skipped = %d/%d, want 1/1", output.Restored, output.Skipped)
+	}
+
+	// The most recently deleted generation wins
+	if _, err := db.GetByID(ctx, database, newer.ID, false); err != nil {
+		t.Errorf("newer capsule should be restored: %v", err)
+	}
+}
+
+func TestBulkRestore_RequiresFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	if _, err := BulkRestore(context.Background(), database, BulkRestoreInput{}); !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest for no filters, got: %v", err)
+	}
+	if _, err := BulkRestore(context.Background(), database, BulkRestoreInput{Workspace: stringPtr("  ")}); !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest for blank filter, got: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// MaxBulkTags is the maximum number of tags added or removed in one call.
+const MaxBulkTags = 20
+
+// BulkTagsInput contains parameters for the BulkAddTags and BulkRemoveTags operations.
+type BulkTagsInput struct {
+	// Filters
+	Workspace  *string
+	Tag        *string
+	NamePrefix *string
+	RunID      *string
+	Phase      *string
+	Role       *string
+	// Tags to add or remove (required)
+	Tags          []string
+	NormalizeTags bool // from config.NormalizeTags; normalizes the tag filter and Tags
+}
+
+// BulkTagsOutput contains the result of the BulkAddTags and BulkRemoveTags operations.
+type BulkTagsOutput struct {
+	Updated int      `json:"updated"`
+	Tags    []string `json:"tags"` // as applied, after trimming and dedupe
+	Message string   `json:"message"`
+}
+
+// BulkAddTags adds tags to all active capsules matching the given filters,
+// keeping their existing tags. At least one filter must be provided (safety
+// guard). Capsules that already carry every tag are left untouched and not counted.
+func BulkAddTags(ctx context.Context, database *sql.DB, input BulkTagsInput) (*BulkTagsOutput, error) {
+	defer invalidateSearchCache()
+
+	filters, tags, err := planBulkTags(input)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := db.BulkAddTags(ctx, database, filters, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BulkTagsOutput{
+		Updated: count,
+		Tags:    tags,
+		Message: formatBulkTagsMessage("Added", count, tags, filters),
+	}, nil
+}
+
+// BulkRemoveTags removes tags from all active capsules matching the given
+// filters. At least one filter must be provided (safety guard). Capsules
+// carrying none of the tags are left untouched and not counted.
+func BulkRemoveTags(ctx context.Context, database *sql.DB, input BulkTagsInput) (*BulkTagsOutput, error) {
+	defer invalidateSearchCache()
+
+	filters, tags, err := planBulkTags(input)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := db.BulkRemoveTags(ctx, database, filters, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BulkTagsOutput{
+		Updated: count,
+		Tags:    tags,
+		Message: formatBulkTagsMessage("Removed", count, tags, filters),
+	}, nil
+}
+
+// planBulkTags validates and normalizes the filters and tags of input.
+func planBulkTags(input BulkTagsInput) (db.InventoryFilters, []string, error) {
+	var filters db.InventoryFilters
+
+	// Phase 1: at least one filter must be non-nil
+	if input.Workspace == nil && input.Tag == nil && input.NamePrefix == nil &&
+		input.RunID == nil && input.Phase == nil && input.Role == nil {
+		return filters, nil, errors.NewInvalidRequest("at least one filter is required")
+	}
+
+	var tags []string
+	for _, tag := range input.Tags {
+		tag = strings.TrimSpace(tag)
+		if input.NormalizeTags {
+			tag = capsule.Normalize(tag)
+		}
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return filters, nil, errors.NewInvalidRequest("tags must contain at least one non-empty tag")
+	}
+	if len(tags) > MaxBulkTags {
+		return filters, nil, errors.NewInvalidRequest(
+			fmt.Sprintf("too many tags: %d (max %d)", len(tags), MaxBulkTags))
+	}
+
+	// Normalize filters
+	if input.Workspace != nil {
+		workspace := capsule.Normalize(*input.Workspace)
+		if workspace != "" {
+			filters.Workspace = &workspace
+		}
+	}
+	filters.Tag = cleanTagFilter(input.Tag, input.NormalizeTags)
+	if input.NamePrefix != nil {
+		prefix := capsule.Normalize(*input.NamePrefix)
+		if prefix != "" {
+			filters.NamePrefix = &prefix
+		}
+	}
+	filters.RunID = cleanOptionalString(input.RunID)
+	filters.Phase = cleanOptionalString(input.Phase)
+	filters.Role = cleanOptionalString(input.Role)
+
+	// Phase 2: at least one filter must be non-empty after normalization
+	if !hasAnyEffectiveFilter(filters) {
+		return filters, nil, errors.NewInvalidRequest("at least one filter must be non-empty after normalization")
+	}
+
+	return filters, tags, nil
+}
+
+// formatBulkTagsMessage creates a human-readable message for a bulk tag edit.
+func formatBulkTagsMessage(verb string, count int, tags []string, filters db.InventoryFilters) string {
+	if count == 0 {
+		return "No active capsules needed changes"
+	}
+
+	capsuleWord := "capsule"
+	if count > 1 {
+		capsuleWord = "capsules"
+	}
+
+	msg := fmt.Sprintf("%s tags %v on %d %s", verb, tags, count, capsuleWord)
+
+	var parts []string
+	if filters.Workspace != nil {
+		parts = append(parts, fmt.Sprintf("workspace=%q", *filters.Workspace))
+	}
+	if filters.Tag != nil {
+		parts = append(parts, fmt.Sprintf("tag=%q", *filters.Tag))
+	}
+	if filters.NamePrefix != nil {
+		parts = append(parts, fmt.Sprintf("name_prefix=%q", *filters.NamePrefix))
+	}
+	if filters.RunID != nil {
+		parts = append(parts, fmt.Sprintf("run_id=%q", *filters.RunID))
+	}
+	if filters.Phase != nil {
+		parts = append(parts, fmt.Sprintf("phase=%q", *filters.Phase))
+	}
+	if filters.Role != nil {
+		parts = append(parts, fmt.Sprintf("role=%q", *filters.Role))
+	}
+	if len(parts) > 0 {
+		msg += " matching " + strings.Join(parts, ", ")
+	}
+
+	return msg
+}
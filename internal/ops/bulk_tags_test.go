@@ -0,0 +1,134 @@
+package ops
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestBulkAddRemoveTags_ByWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	seed := map[string][]string{
+		"auth":  {"backend", "security"},
+		"ui":    {"frontend"},
+		"plain": nil,
+	}
+	ids := map[string]string{}
+	for name, tags := range seed {
+		stored, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", Name: stringPtr(name), CapsuleText: validCapsuleText, Tags: tags})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		ids[name] = stored.ID
+	}
+	other, err := Store(ctx, database, cfg, StoreInput{Workspace: "other", CapsuleText: validCapsuleText, Tags: []string{"backend"}})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	tagsOf := func(id string) []string {
+		t.Helper()
+		c, err := db.GetByID(ctx, database, id, false)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		return c.Tags
+	}
+
+	ws := "proj"
+	added, err := BulkAddTags(ctx, database, BulkTagsInput{Workspace: &ws, Tags: []string{" q3 ", "backend", "q3"}})
+	if err != nil {
+		t.Fatalf("BulkAddTags failed: %v", err)
+	}
+	if added.Updated != 3 || !slices.Equal(added.Tags, []string{"q3", "backend"}) {
+		t.Errorf("output = %+v, want 3 updated with [q3 backend]", added)
+	}
+	want := map[string][]string{
+		"auth":  {"backend", "security", "q3"},
+		"ui":    {"frontend", "q3", "backend"},
+		"plain": {"q3", "backend"},
+	}
+	for name, tags := range want {
+		if got := tagsOf(ids[name]); !slices.Equal(got, tags) {
+			t.Errorf("%s tags = %v, want %v", name, got, tags)
+		}
+	}
+	if got := tagsOf(other.ID); !slices.Equal(got, []string{"backend"}) {
+		t.Errorf("other workspace tags = %v, want unchanged [backend]", got)
+	}
+
+	// Adding again changes nothing
+	again, err := BulkAddTags(ctx, database, BulkTagsInput{Workspace: &ws, Tags: []string{"q3"}})
+	if err != nil {
+		t.Fatalf("BulkAddTags failed: %v", err)
+	}
+	if again.Updated != 0 {
+		t.Errorf("Updated = %d, want 0 when every capsule has the tag", again.Updated)
+	}
+
+	removed, err := BulkRemoveTags(ctx, database, BulkTagsInput{Workspace: &ws, Tags: []string{"backend", "q3"}})
+	if err != nil {
+		t.Fatalf("BulkRemoveTags failed: %v", err)
+	}
+	if removed.Updated != 3 {
+		t.Errorf("Updated = %d, want 3", removed.Updated)
+	}
+	want = map[string][]string{
+		"auth":  {"security"},
+		"ui":    {"frontend"},
+		"plain": nil,
+	}
+	for name, tags := range want {
+		if got := tagsOf(ids[name]); !slices.Equal(got, tags) {
+			t.Errorf("%s tags = %v, want %v", name, got, tags)
+		}
+	}
+}
+
+func TestBulkTags_Validation(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ws, blank := "proj", "  "
+	tests := []struct {
+		name  string
+		input BulkTagsInput
+	}{
+		{"no filter", BulkTagsInput{Tags: []string{"x"}}},
+		{"blank filter", BulkTagsInput{Workspace: &blank, Tags: []string{"x"}}},
+		{"no tags", BulkTagsInput{Workspace: &ws}},
+		{"blank tags", BulkTagsInput{Workspace: &ws, Tags: []string{" ", ""}}},
+		{"too many tags", BulkTagsInput{Workspace: &ws, Tags: make([]string, MaxBulkTags+1)}},
+	}
+	for i := range tests[4].input.Tags {
+		tests[4].input.Tags[i] = string(rune('a' + i))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BulkAddTags(context.Background(), database, tt.input); !errors.Is(err, errors.ErrInvalidRequest) {
+				t.Errorf("BulkAddTags error = %v, want INVALID_REQUEST", err)
+			}
+			if _, err := BulkRemoveTags(context.Background(), database, tt.input); !errors.Is(err, errors.ErrInvalidRequest) {
+				t.Errorf("BulkRemoveTags error = %v, want INVALID_REQUEST", err)
+			}
+		})
+	}
+}
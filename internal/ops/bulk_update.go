@@ -2,7 +2,6 @@ package ops
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"strings"
 
@@ -21,20 +20,30 @@ type BulkUpdateInput struct {
 	Phase      *string
 	Role       *string
 	// Updates (set_ prefix to distinguish from filters)
-	SetPhase *string
-	SetRole  *string
-	SetTags  *[]string
+	SetPhase  *string
+	SetRole   *string
+	SetTags   *[]string
+	SetSource *string
+	SetTitle  *string
+	// From config.NormalizeTags: normalizes the tag filter and SetTags
+	NormalizeTags bool
+	// Count the matching capsules without updating them
+	DryRun bool
 }
 
 // BulkUpdateOutput contains the result of the BulkUpdate operation.
 type BulkUpdateOutput struct {
-	Updated int    `json:"updated"`
-	Message string `json:"message"`
+	Updated     int    `json:"updated"`
+	DryRun      bool   `json:"dry_run,omitempty"`
+	WouldAffect *int   `json:"would_affect,omitempty"` // dry-run only
+	Message     string `json:"message"`
 }
 
 // BulkUpdate updates metadata on all active capsules matching the given filters.
 // At least one filter and at least one update field must be provided (safety guard).
-func BulkUpdate(ctx context.Context, database *sql.DB, input BulkUpdateInput) (*BulkUpdateOutput, error) {
+func BulkUpdate(ctx context.Context, database db.Querier, input BulkUpdateInput) (*BulkUpdateOutput, error) {
+	defer invalidateSearchCache()
+
 	// Phase 1: at least one filter must be non-nil
 	if !hasAnyBulkUpdateFilter(input) {
 		return nil, errors.NewInvalidRequest("at least one filter is required")
@@ -53,12 +62,7 @@ func BulkUpdate(ctx context.Context, database *sql.DB, input BulkUpdateInput) (*
 			filters.Workspace = &workspace
 		}
 	}
-	if input.Tag != nil {
-		tag := strings.TrimSpace(*input.Tag)
-		if tag != "" {
-			filters.Tag = &tag
-		}
-	}
+	filters.Tag = cleanTagFilter(input.Tag, input.NormalizeTags)
 	if input.NamePrefix != nil {
 		prefix := capsule.Normalize(*input.NamePrefix)
 		if prefix != "" {
@@ -86,6 +90,30 @@ func BulkUpdate(ctx context.Context, database *sql.DB, input BulkUpdateInput) (*
 	}
 	if input.SetTags != nil {
 		fields.Tags = input.SetTags
+		if input.NormalizeTags {
+			tags := capsule.NormalizeTags(*input.SetTags)
+			fields.Tags = &tags
+		}
+	}
+	if input.SetSource != nil {
+		v := strings.TrimSpace(*input.SetSource)
+		fields.Source = &v
+	}
+	if input.SetTitle != nil {
+		v := strings.TrimSpace(*input.SetTitle)
+		fields.Title = &v
+	}
+
+	if input.DryRun {
+		n, err := db.CountBulkActive(ctx, database, filters)
+		if err != nil {
+			return nil, err
+		}
+		return &BulkUpdateOutput{
+			DryRun:      true,
+			WouldAffect: &n,
+			Message:     formatBulkDryRunMessage("update", n, filters),
+		}, nil
 	}
 
 	count, err := db.BulkUpdate(ctx, database, filters, fields)
@@ -113,7 +141,9 @@ func hasAnyBulkUpdateFilter(input BulkUpdateInput) bool {
 func hasAnyUpdateField(input BulkUpdateInput) bool {
 	return input.SetPhase != nil ||
 		input.SetRole != nil ||
-		input.SetTags != nil
+		input.SetTags != nil ||
+		input.SetSource != nil ||
+		input.SetTitle != nil
 }
 
 // formatBulkUpdateMessage creates a human-readable message for the bulk update result.
@@ -177,6 +207,20 @@ func formatBulkUpdateMessage(count int, filters db.InventoryFilters, fields db.B
 			updateParts = append(updateParts, fmt.Sprintf("tags=%v", *fields.Tags))
 		}
 	}
+	if fields.Source != nil {
+		if *fields.Source == "" {
+			updateParts = append(updateParts, "source=null")
+		} else {
+			updateParts = append(updateParts, fmt.Sprintf("source=%q", *fields.Source))
+		}
+	}
+	if fields.Title != nil {
+		if *fields.Title == "" {
+			updateParts = append(updateParts, "title=null")
+		} else {
+			updateParts = append(updateParts, fmt.Sprintf("title=%q", *fields.Title))
+		}
+	}
 
 	if len(updateParts) > 0 {
 		msg += "; set " + strings.Join(updateParts, ", ")
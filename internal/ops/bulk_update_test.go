@@ -2,6 +2,7 @@ package ops
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/hpungsan/moss/internal/config"
@@ -479,3 +480,184 @@ func TestBulkUpdate_ClearsFieldWithEmptyString(t *testing.T) {
 		t.Errorf("Phase = %v, want nil (cleared)", c.Phase)
 	}
 }
+
+func TestBulkUpdate_SetAndClearSourceAndTitle(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	var ids []string
+	for _, name := range []string{"a", "b"} {
+		stored, err := Store(context.Background(), database, cfg, StoreInput{
+			Workspace:   "imported",
+			Name:        stringPtr(name),
+			CapsuleText: validCapsuleText,
+		})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		ids = append(ids, stored.ID)
+	}
+	other, err := Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "native",
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	// Set source and title across the workspace
+	ws := "imported"
+	output, err := BulkUpdate(context.Background(), database, BulkUpdateInput{
+		Workspace: &ws,
+		SetSource: stringPtr(" legacy "),
+		SetTitle:  stringPtr("Migrated"),
+	})
+	if err != nil {
+		t.Fatalf("BulkUpdate failed: %v", err)
+	}
+	if output.Updated != 2 {
+		t.Errorf("Updated = %d, want 2", output.Updated)
+	}
+	if !strings.Contains(output.Message, `source="legacy"`) || !strings.Contains(output.Message, `title="Migrated"`) {
+		t.Errorf("Message = %q, want source and title listed", output.Message)
+	}
+	for _, id := range ids {
+		c, err := db.GetByID(context.Background(), database, id, false)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if c.Source == nil || *c.Source != "legacy" {
+			t.Errorf("Source = %v, want legacy", c.Source)
+		}
+		if c.Title == nil || *c.Title != "Migrated" {
+			t.Errorf("Title = %v, want Migrated", c.Title)
+		}
+	}
+	c, err := db.GetByID(context.Background(), database, other.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if c.Source != nil {
+		t.Errorf("capsule outside the filter got Source = %v", *c.Source)
+	}
+
+	// Empty string clears
+	output, err = BulkUpdate(context.Background(), database, BulkUpdateInput{
+		Workspace: &ws,
+		SetSource: stringPtr(""),
+		SetTitle:  stringPtr(""),
+	})
+	if err != nil {
+		t.Fatalf("BulkUpdate failed: %v", err)
+	}
+	if !strings.Contains(output.Message, "source=null") || !strings.Contains(output.Message, "title=null") {
+		t.Errorf("Message = %q, want source=null and title=null", output.Message)
+	}
+	for _, id := range ids {
+		c, err := db.GetByID(context.Background(), database, id, false)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if c.Source != nil || c.Title != nil {
+			t.Errorf("Source = %v, Title = %v, want both cleared", c.Source, c.Title)
+		}
+	}
+}
+
+func TestBulkUpdate_DryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	// Two tagged "x" (one deleted), one tagged "y"
+	target, err := Store(context.Background(), database, cfg, StoreInput{CapsuleText: validCapsuleText, Tags: []string{"x"}})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	deleted, err := Store(context.Background(), database, cfg, StoreInput{CapsuleText: validCapsuleText, Tags: []string{"x"}})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := Delete(context.Background(), database, DeleteInput{ID: deleted.ID}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := Store(context.Background(), database, cfg, StoreInput{CapsuleText: validCapsuleText, Tags: []string{"y"}}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	// Backdate so an accidental write would be visible in updated_at
+	if _, err := database.Exec("UPDATE capsules SET updated_at = 1000"); err != nil {
+		t.Fatalf("backdate failed: %v", err)
+	}
+
+	tag := "x"
+	newPhase := "done"
+	output, err := BulkUpdate(context.Background(), database, BulkUpdateInput{
+		Tag:      &tag,
+		SetPhase: &newPhase,
+		DryRun:   true,
+	})
+	if err != nil {
+		t.Fatalf("BulkUpdate dry run failed: %v", err)
+	}
+	if !output.DryRun {
+		t.Error("DryRun = false, want true")
+	}
+	if output.WouldAffect == nil || *output.WouldAffect != 1 {
+		t.Errorf("WouldAffect = %v, want 1", output.WouldAffect)
+	}
+	if output.Updated != 0 {
+		t.Errorf("Updated = %d, want 0", output.Updated)
+	}
+
+	// No row changed
+	var changed int
+	if err := database.QueryRow("SELECT COUNT(*) FROM capsules WHERE updated_at != 1000 OR phase IS NOT NULL").Scan(&changed); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if changed != 0 {
+		t.Errorf("%d rows changed during dry run, want 0", changed)
+	}
+
+	// The real run affects exactly the previewed count
+	output, err = BulkUpdate(context.Background(), database, BulkUpdateInput{Tag: &tag, SetPhase: &newPhase})
+	if err != nil {
+		t.Fatalf("BulkUpdate failed: %v", err)
+	}
+	if output.Updated != 1 {
+		t.Errorf("Updated = %d, want 1", output.Updated)
+	}
+	c, err := db.GetByID(context.Background(), database, target.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if c.Phase == nil || *c.Phase != "done" {
+		t.Errorf("Phase = %v, want 'done'", c.Phase)
+	}
+}
+
+func TestBulkUpdate_DryRunStillRequiresUpdateField(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ws := "ws1"
+	_, err = BulkUpdate(context.Background(), database, BulkUpdateInput{Workspace: &ws, DryRun: true})
+	if err == nil {
+		t.Fatal("expected error for dry run without update fields")
+	}
+}
@@ -0,0 +1,60 @@
+package ops
+
+import (
+	"context"
+
+	"github.com/hpungsan/moss/internal/db"
+)
+
+// BundlesInput contains parameters for the Bundles operation.
+type BundlesInput struct {
+	ID             string
+	Workspace      string
+	Name           string
+	IncludeDeleted bool
+}
+
+// BundlesOutput contains the result of the Bundles operation.
+type BundlesOutput struct {
+	ID      string        `json:"id"`
+	Bundles []SummaryItem `json:"bundles"`
+}
+
+// Bundles lists the stored bundles (compose with store_as) that were composed
+// from a capsule, most recently updated first.
+func Bundles(ctx context.Context, database db.Querier, input BundlesInput) (*BundlesOutput, error) {
+	addr, err := ValidateAddress(input.ID, input.Workspace, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := resolveAddress(ctx, database, addr, input.IncludeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	bundles, err := loadBundles(ctx, database, c.ID, input.IncludeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BundlesOutput{
+		ID:      c.ID,
+		Bundles: bundles,
+	}, nil
+}
+
+// loadBundles fetches summaries of the bundles composed from a capsule.
+// Always returns a non-nil slice so JSON output uses [] rather than null.
+func loadBundles(ctx context.Context, q db.Querier, id string, includeDeleted bool) ([]SummaryItem, error) {
+	summaries, err := db.ListBundlesContaining(ctx, q, id, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	bundles := make([]SummaryItem, 0, len(summaries))
+	for _, s := range summaries {
+		bundles = append(bundles, SummaryToItem(s))
+	}
+	return bundles, nil
+}
@@ -0,0 +1,125 @@
+package ops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestBundles_ComposeStoreAs(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	cfg := config.DefaultConfig()
+
+	ids := map[string]string{}
+	for _, name := range []string{"cap1", "cap2", "cap3"} {
+		out, err := Store(ctx, database, cfg, StoreInput{Workspace: "default", Name: stringPtr(name), CapsuleText: validCapsuleText})
+		if err != nil {
+			t.Fatalf("Store %s failed: %v", name, err)
+		}
+		ids[name] = out.ID
+	}
+
+	composed, err := Compose(ctx, database, cfg, ComposeInput{
+		Items:   []ComposeRef{{Workspace: "default", Name: "cap1"}, {ID: ids["cap2"]}},
+		StoreAs: &ComposeStoreAs{Workspace: "composed", Name: "bundle"},
+	})
+	if err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+	bundleID := composed.Stored.ID
+
+	// Each source reports the bundle, however it is addressed
+	for _, input := range []BundlesInput{
+		{Workspace: "default", Name: "cap1"},
+		{ID: ids["cap2"]},
+	} {
+		out, err := Bundles(ctx, database, input)
+		if err != nil {
+			t.Fatalf("Bundles(%+v) failed: %v", input, err)
+		}
+		if len(out.Bundles) != 1 || out.Bundles[0].ID != bundleID {
+			t.Fatalf("Bundles(%+v) = %+v, want [%s]", input, out.Bundles, bundleID)
+		}
+		if out.Bundles[0].FetchKey.MossCapsule != "bundle" || out.Bundles[0].FetchKey.MossWorkspace != "composed" {
+			t.Errorf("bundle fetch_key = %+v, want composed/bundle", out.Bundles[0].FetchKey)
+		}
+	}
+
+	// A capsule that wasn't composed has none, as an empty list rather than null
+	out, err := Bundles(ctx, database, BundlesInput{ID: ids["cap3"]})
+	if err != nil {
+		t.Fatalf("Bundles failed: %v", err)
+	}
+	if out.Bundles == nil || len(out.Bundles) != 0 {
+		t.Errorf("Bundles(cap3) = %#v, want empty", out.Bundles)
+	}
+
+	// Fetch can include them too
+	fetched, err := Fetch(ctx, database, FetchInput{ID: ids["cap1"], WithBundles: true})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(fetched.Bundles) != 1 || fetched.Bundles[0].ID != bundleID {
+		t.Errorf("Fetch bundles = %+v, want [%s]", fetched.Bundles, bundleID)
+	}
+
+	// Replacing the bundle replaces its sources
+	if _, err := Compose(ctx, database, cfg, ComposeInput{
+		Items:   []ComposeRef{{ID: ids["cap2"]}, {ID: ids["cap3"]}},
+		StoreAs: &ComposeStoreAs{Workspace: "composed", Name: "bundle", Mode: StoreModeReplace},
+	}); err != nil {
+		t.Fatalf("Compose replace failed: %v", err)
+	}
+	for name, want := range map[string]int{"cap1": 0, "cap2": 1, "cap3": 1} {
+		out, err := Bundles(ctx, database, BundlesInput{ID: ids[name]})
+		if err != nil {
+			t.Fatalf("Bundles(%s) failed: %v", name, err)
+		}
+		if len(out.Bundles) != want {
+			t.Errorf("Bundles(%s) after replace = %d, want %d", name, len(out.Bundles), want)
+		}
+	}
+
+	// Soft-deleted bundles are hidden unless include_deleted
+	if _, err := Delete(ctx, database, DeleteInput{ID: bundleID}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	out, err = Bundles(ctx, database, BundlesInput{ID: ids["cap2"]})
+	if err != nil {
+		t.Fatalf("Bundles failed: %v", err)
+	}
+	if len(out.Bundles) != 0 {
+		t.Errorf("Bundles after delete = %d, want 0", len(out.Bundles))
+	}
+	out, err = Bundles(ctx, database, BundlesInput{ID: ids["cap2"], IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Bundles failed: %v", err)
+	}
+	if len(out.Bundles) != 1 {
+		t.Errorf("Bundles with include_deleted = %d, want 1", len(out.Bundles))
+	}
+}
+
+func TestBundles_NotFound(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	_, err = Bundles(context.Background(), database, BundlesInput{ID: "01NOPE"})
+	if !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("expected NOT_FOUND, got: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package ops
+
+import (
+	"context"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// Changes limits
+const (
+	DefaultChangesLimit = 50
+	MaxChangesLimit     = 200
+)
+
+// ChangesInput contains parameters for the Changes operation.
+type ChangesInput struct {
+	Since  int64   // Unix seconds; only capsules updated after this are returned
+	Limit  int     // default: 50, max: 200
+	Cursor *string // next_cursor from a previous call
+}
+
+// ChangesOutput contains the result of the Changes operation.
+type ChangesOutput struct {
+	Items      []capsule.ExportRecord `json:"items"` // oldest change first; deleted capsules carry deleted_at
+	HasMore    bool                   `json:"has_more"`
+	NextCursor string                 `json:"next_cursor,omitempty"` // resume point; store it to sync again later
+}
+
+// Changes returns capsules created, updated, or soft-deleted after Since, as
+// export records ordered by updated_at, so a client can keep a local mirror in
+// sync. Each record is the capsule's current state; a soft-delete shows up as
+// a record with deleted_at set. Purged capsules are not reported.
+func Changes(ctx context.Context, database db.Querier, input ChangesInput) (*ChangesOutput, error) {
+	if input.Since < 0 {
+		return nil, errors.NewInvalidRequest("since must be a non-negative Unix timestamp")
+	}
+
+	limit := input.Limit
+	if limit <= 0 {
+		limit = DefaultChangesLimit
+	}
+	if limit > MaxChangesLimit {
+		limit = MaxChangesLimit
+	}
+
+	var after *db.SearchCursor
+	if input.Cursor != nil {
+		var err error
+		if after, err = decodeSearchCursor(*input.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fetch one extra row to tell whether more changes follow
+	capsules, err := db.ChangesSince(ctx, database, input.Since, limit+1, after)
+	if err != nil {
+		return nil, err
+	}
+	hasMore := len(capsules) > limit
+	capsules = capsules[:min(len(capsules), limit)]
+
+	output := &ChangesOutput{
+		Items:   make([]capsule.ExportRecord, len(capsules)),
+		HasMore: hasMore,
+	}
+	for i := range capsules {
+		output.Items[i] = *capsule.CapsuleToExportRecord(&capsules[i])
+	}
+
+	// With nothing new, hand the caller's cursor back so it can poll from the same spot
+	if len(capsules) > 0 {
+		last := capsules[len(capsules)-1]
+		output.NextCursor = encodeSearchCursor(db.SearchCursor{UpdatedAt: last.UpdatedAt, ID: last.ID})
+	} else if after != nil {
+		output.NextCursor = encodeSearchCursor(*after)
+	}
+
+	return output, nil
+}
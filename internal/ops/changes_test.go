@@ -0,0 +1,114 @@
+package ops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestChanges_ReturnsChangesAfterSince(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// Timeline: old (100), edited (200), removed (deleted at 300), fresh (400)
+	ids := make(map[string]string)
+	for _, name := range []string{"old", "edited", "removed", "fresh"} {
+		out, err := Store(ctx, database, cfg, StoreInput{Name: stringPtr(name), CapsuleText: "## Status\n" + name, AllowThin: true})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		ids[name] = out.ID
+	}
+	if _, err := Delete(ctx, database, DeleteInput{ID: ids["removed"]}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	for name, ts := range map[string]int64{"old": 100, "edited": 200, "removed": 300, "fresh": 400} {
+		if _, err := database.Exec("UPDATE capsules SET updated_at = ? WHERE id = ?", ts, ids[name]); err != nil {
+			t.Fatalf("set updated_at: %v", err)
+		}
+	}
+
+	first, err := Changes(ctx, database, ChangesInput{Since: 150, Limit: 2})
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(first.Items) != 2 || first.Items[0].ID != ids["edited"] || first.Items[1].ID != ids["removed"] {
+		t.Fatalf("first page = %+v, want edited then removed", first.Items)
+	}
+	if first.Items[1].DeletedAt == nil {
+		t.Error("removed capsule should carry deleted_at")
+	}
+	if first.Items[0].CapsuleText != "## Status\nedited" {
+		t.Errorf("CapsuleText = %q, want the full text", first.Items[0].CapsuleText)
+	}
+	if !first.HasMore || first.NextCursor == "" {
+		t.Fatalf("first page HasMore = %v, NextCursor = %q; want more with a cursor", first.HasMore, first.NextCursor)
+	}
+
+	second, err := Changes(ctx, database, ChangesInput{Since: 150, Limit: 2, Cursor: &first.NextCursor})
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(second.Items) != 1 || second.Items[0].ID != ids["fresh"] || second.HasMore {
+		t.Fatalf("second page = %+v (has_more %v), want only fresh", second.Items, second.HasMore)
+	}
+
+	// Nothing new: the cursor is handed back for the next poll
+	idle, err := Changes(ctx, database, ChangesInput{Since: 150, Cursor: &second.NextCursor})
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(idle.Items) != 0 || idle.NextCursor != second.NextCursor {
+		t.Errorf("idle poll = %+v, want no items and the same cursor", idle)
+	}
+
+	// An update after the cursor shows up on the next poll
+	newText := "## Status\nold, revised"
+	if _, err := Update(ctx, database, cfg, UpdateInput{ID: ids["old"], CapsuleText: &newText, AllowThin: true}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	next, err := Changes(ctx, database, ChangesInput{Since: 150, Cursor: &idle.NextCursor})
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(next.Items) != 1 || next.Items[0].ID != ids["old"] || next.Items[0].CapsuleText != newText {
+		t.Errorf("after update = %+v, want the revised old capsule", next.Items)
+	}
+
+	// since 0 is a full sync
+	all, err := Changes(ctx, database, ChangesInput{})
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(all.Items) != 4 || all.HasMore {
+		t.Errorf("full sync = %d items (has_more %v), want 4", len(all.Items), all.HasMore)
+	}
+}
+
+func TestChanges_Validation(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	if _, err := Changes(ctx, database, ChangesInput{Since: -1}); !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("negative since: expected ErrInvalidRequest, got %v", err)
+	}
+	bad := "not-a-cursor"
+	if _, err := Changes(ctx, database, ChangesInput{Cursor: &bad}); !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("bad cursor: expected ErrInvalidRequest, got %v", err)
+	}
+}
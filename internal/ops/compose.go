@@ -1,11 +1,16 @@
 package ops
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/config"
@@ -15,10 +20,56 @@ import (
 
 // ComposeInput contains parameters for the Compose operation.
 type ComposeInput struct {
-	Items    []ComposeRef    // required, 1-50 items
-	Format   string          // "markdown" (default) or "json"
-	Sections []string        // only include these sections (exact match, case-insensitive)
-	StoreAs  *ComposeStoreAs // optional: persist result
+	Items          []ComposeRef    // required, 1-50 items
+	Format         string          // "markdown" (default) or "json"
+	Sections       []string        // only include these sections (exact match, case-insensitive)
+	StoreAs        *ComposeStoreAs // optional: persist result
+	HeaderTemplate *string         // optional: text/template over ComposeHeaderData (markdown only)
+	DryRun         bool            // project the bundle size without assembling or storing it
+	OrderBy        ComposeOrder    // part order; empty means ComposeOrderCaller
+
+	// IntraPartSeparator goes between a capsule's included sections when Sections
+	// is set (markdown only). Sections' trailing blank lines are trimmed so it is
+	// the only thing between them. nil keeps the default blank line.
+	IntraPartSeparator *string
+
+	// TrailingNewline sets how BundleText ends: true with exactly one "\n",
+	// false with none. nil leaves it as assembled (whatever the last part ends
+	// with for markdown, no newline for json). Applies to the stored bundle too.
+	TrailingNewline *bool
+}
+
+// ComposeOrder selects how parts are ordered in the bundle.
+type ComposeOrder string
+
+const (
+	ComposeOrderCaller  ComposeOrder = "caller"  // order of Items (default)
+	ComposeOrderCreated ComposeOrder = "created" // oldest created first
+	ComposeOrderUpdated ComposeOrder = "updated" // least recently updated first
+	ComposeOrderName    ComposeOrder = "name"    // by name, case-insensitive; unnamed last
+)
+
+// Limits on custom compose headers. The output cap also bounds runaway templates
+// (e.g. {{range 1000000000}}) before they can allocate unbounded memory.
+const (
+	maxHeaderTemplateChars = 500
+	maxHeaderOutputChars   = 1000
+)
+
+// maxIntraPartSeparatorChars caps the separator between a part's sections.
+const maxIntraPartSeparatorChars = 100
+
+// defaultComposeHeader reproduces the built-in "## {display name}" part header.
+const defaultComposeHeader = "## {{.DisplayName}}"
+
+// ComposeHeaderData is the data passed to a compose header template.
+type ComposeHeaderData struct {
+	Title       string // capsule title ("" if none)
+	Name        string // capsule name ("" if unnamed)
+	ID          string
+	Workspace   string
+	DisplayName string // title > name > id
+	Index       int    // 1-based position in the bundle
 }
 
 // ComposeRef identifies a capsule by ID or by workspace+name.
@@ -33,14 +84,30 @@ type ComposeStoreAs struct {
 	Workspace string    // default: "default"
 	Name      string    // required
 	Mode      StoreMode // default: StoreModeError
+
+	// TitlePrefix and TitleSuffix decorate the stored bundle's title, which
+	// otherwise defaults to Name (e.g. "[bundle] " marks composites in listings).
+	// Applied verbatim, so include any separating space.
+	TitlePrefix *string
+	TitleSuffix *string
 }
 
 // ComposeOutput contains the result of the Compose operation.
 type ComposeOutput struct {
-	BundleText  string       `json:"bundle_text"`
-	BundleChars int          `json:"bundle_chars"`
-	PartsCount  int          `json:"parts_count"`
-	Stored      *StoreOutput `json:"stored,omitempty"` // only if store_as
+	BundleText  string         `json:"bundle_text"`
+	BundleChars int            `json:"bundle_chars"`
+	PartsCount  int            `json:"parts_count"`
+	Stored      *StoreOutput   `json:"stored,omitempty"`  // only if store_as
+	DryRun      *ComposeDryRun `json:"dry_run,omitempty"` // only if dry_run
+}
+
+// ComposeDryRun reports the projected bundle budget. BundleChars in the
+// enclosing output holds the projected size; BundleText is left empty.
+type ComposeDryRun struct {
+	TokensEstimate int  `json:"tokens_estimate"`
+	MaxChars       int  `json:"max_chars"`
+	MaxBytes       int  `json:"max_bytes,omitempty"` // capsule_max_bytes, when set
+	TooLarge       bool `json:"too_large"`           // compose would fail with COMPOSE_TOO_LARGE
 }
 
 // ComposePart represents a single capsule in the composed bundle.
@@ -51,6 +118,11 @@ type ComposePart struct {
 	DisplayName string `json:"display_name"` // computed: title > name > id
 	Text        string `json:"text"`
 	Chars       int    `json:"chars"`
+
+	title     string // raw title for header templates (not serialized)
+	nameNorm  string // sort key for ComposeOrderName
+	createdAt int64
+	updatedAt int64
 }
 
 // ComposeBundle is the JSON format output structure.
@@ -89,6 +161,48 @@ func Compose(ctx context.Context, database *sql.DB, cfg *config.Config, input Co
 		}
 	}
 
+	// Validate order_by (default: caller)
+	order := input.OrderBy
+	if order == "" {
+		order = ComposeOrderCaller
+	}
+	switch order {
+	case ComposeOrderCaller, ComposeOrderCreated, ComposeOrderUpdated, ComposeOrderName:
+	default:
+		return nil, errors.NewInvalidRequest("order_by must be one of: caller, created, updated, name")
+	}
+
+	// Validate header template (markdown only)
+	headerSrc := defaultComposeHeader
+	if input.HeaderTemplate != nil {
+		if format != "markdown" {
+			return nil, errors.NewInvalidRequest("header_template is only supported with format:\"markdown\"")
+		}
+		headerSrc = *input.HeaderTemplate
+		if strings.TrimSpace(headerSrc) == "" {
+			return nil, errors.NewInvalidRequest("header_template must not be empty")
+		}
+		if len(headerSrc) > maxHeaderTemplateChars {
+			return nil, errors.NewInvalidRequest(
+				fmt.Sprintf("header_template too long: %d chars (max %d)", len(headerSrc), maxHeaderTemplateChars))
+		}
+	}
+	// Validate intra-part separator (markdown only)
+	if input.IntraPartSeparator != nil {
+		if format != "markdown" {
+			return nil, errors.NewInvalidRequest("intra_part_separator is only supported with format:\"markdown\"")
+		}
+		if n := utf8.RuneCountInString(*input.IntraPartSeparator); n > maxIntraPartSeparatorChars {
+			return nil, errors.NewInvalidRequest(
+				fmt.Sprintf("intra_part_separator too long: %d chars (max %d)", n, maxIntraPartSeparatorChars))
+		}
+	}
+
+	header, err := template.New("header").Parse(headerSrc)
+	if err != nil {
+		return nil, errors.NewInvalidRequest(fmt.Sprintf("invalid header_template: %v", err))
+	}
+
 	// Reject JSON format with store_as (JSON output lacks section headers, so lint would fail)
 	if format == "json" && input.StoreAs != nil {
 		return nil, errors.NewInvalidRequest("cannot use format:\"json\" with store_as; JSON output is not a valid capsule structure")
@@ -106,7 +220,16 @@ func Compose(ctx context.Context, database *sql.DB, cfg *config.Config, input Co
 
 	// Fetch all capsules (all-or-nothing)
 	parts := make([]ComposePart, 0, len(input.Items))
-	estimatedChars := 0
+	projection := newBundleProjection(format, header, input.TrailingNewline)
+	project := func(part ComposePart) error {
+		if err := projection.addPart(part); err != nil {
+			return err
+		}
+		if input.DryRun {
+			return nil
+		}
+		return projection.measured().checkLimits(cfg)
+	}
 	for i, ref := range input.Items {
 		select {
 		case <-ctx.Done():
@@ -134,17 +257,10 @@ func Compose(ctx context.Context, database *sql.DB, cfg *config.Config, input Co
 		partText := c.CapsuleText
 		partChars := c.CapsuleChars
 		if len(input.Sections) > 0 {
-			partText = filterSections(partText, input.Sections)
+			partText = filterSections(partText, input.Sections, input.IntraPartSeparator)
 			partChars = capsule.CountChars(partText)
 		}
 
-		// Early size check (conservative estimate without formatting overhead).
-		// When sections filtering is enabled, estimate based on filtered text to avoid false positives.
-		estimatedChars += partChars
-		if estimatedChars > cfg.CapsuleMaxChars {
-			return nil, errors.NewComposeTooLarge(cfg.CapsuleMaxChars, estimatedChars)
-		}
-
 		// Build part with display name priority: title > name > id
 		displayName := c.ID
 		if c.NameRaw != nil {
@@ -171,31 +287,81 @@ func Compose(ctx context.Context, database *sql.DB, cfg *config.Config, input Co
 			DisplayName: displayName,
 			Text:        partText,
 			Chars:       partChars,
+			title:       derefString(c.Title),
+			nameNorm:    derefString(c.NameNorm),
+			createdAt:   c.CreatedAt,
+			updatedAt:   c.UpdatedAt,
 		})
+
+		// Early size check: the projection counts this part plus its header and
+		// separator exactly, so we stop fetching as soon as the bundle is known to
+		// be too large instead of assembling it first. Headers depend on position,
+		// so sorted bundles are projected once their order is known.
+		if order == ComposeOrderCaller {
+			if err := project(parts[len(parts)-1]); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("compose")
+		}
 		return nil, errors.NewInternal(err)
 	}
 
+	if order != ComposeOrderCaller {
+		sortComposeParts(parts, order)
+		for _, part := range parts {
+			if ctx.Err() != nil {
+				return nil, errors.NewCancelled("compose")
+			}
+			if err := project(part); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	projection.finish()
+	size := projection.measured()
+	if input.DryRun {
+		return &ComposeOutput{
+			BundleChars: size.chars,
+			PartsCount:  len(parts),
+			DryRun: &ComposeDryRun{
+				TokensEstimate: capsule.EstimateTokensFromWords(size.words),
+				MaxChars:       cfg.CapsuleMaxChars,
+				MaxBytes:       cfg.CapsuleMaxBytes,
+				TooLarge:       size.checkLimits(cfg) != nil,
+			},
+		}, nil
+	}
+	if err := size.checkLimits(cfg); err != nil {
+		return nil, err
+	}
+
 	// Assemble bundle based on format
 	var bundleText string
 	if format == "markdown" {
-		bundleText = assembleMarkdown(parts)
+		bundleText, err = assembleMarkdown(ctx, parts, header)
 	} else {
-		var err error
 		bundleText, err = assembleJSON(parts)
-		if err != nil {
-			return nil, err
-		}
 	}
+	if err != nil {
+		return nil, err
+	}
+	bundleText = applyTrailingNewline(bundleText, input.TrailingNewline)
 
 	bundleChars := capsule.CountChars(bundleText)
 
-	// Check size limit
+	// Exact check on the assembled bundle (the projection should already agree)
 	if bundleChars > cfg.CapsuleMaxChars {
 		return nil, errors.NewComposeTooLarge(cfg.CapsuleMaxChars, bundleChars)
 	}
+	if cfg.CapsuleMaxBytes > 0 && len(bundleText) > cfg.CapsuleMaxBytes {
+		return nil, errors.NewComposeTooLargeBytes(cfg.CapsuleMaxBytes, len(bundleText))
+	}
 
 	output := &ComposeOutput{
 		BundleText:  bundleText,
@@ -212,9 +378,25 @@ func Compose(ctx context.Context, database *sql.DB, cfg *config.Config, input Co
 			return nil, errors.NewInvalidRequest("cannot store empty bundle (sections filter matched no content)")
 		}
 
-		storeResult, err := Store(ctx, database, cfg, StoreInput{
+		var title *string
+		if input.StoreAs.TitlePrefix != nil || input.StoreAs.TitleSuffix != nil {
+			decorated := derefString(input.StoreAs.TitlePrefix) + input.StoreAs.Name + derefString(input.StoreAs.TitleSuffix)
+			title = &decorated
+		}
+
+		defer invalidateSearchCache()
+
+		// The bundle and the record of its sources are stored together
+		tx, err := database.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		defer db.Rollback(tx) //nolint:errcheck
+
+		storeResult, err := store(ctx, tx, cfg, StoreInput{
 			Workspace:   input.StoreAs.Workspace,
 			Name:        &input.StoreAs.Name,
+			Title:       title,
 			CapsuleText: bundleText,
 			Mode:        input.StoreAs.Mode,
 			AllowThin:   len(input.Sections) > 0,
@@ -222,25 +404,238 @@ func Compose(ctx context.Context, database *sql.DB, cfg *config.Config, input Co
 		if err != nil {
 			return nil, err
 		}
+
+		sourceIDs := make([]string, len(parts))
+		for i, p := range parts {
+			sourceIDs[i] = p.ID
+		}
+		if err := db.SetComposeSources(ctx, tx, storeResult.ID, sourceIDs); err != nil {
+			return nil, err
+		}
+
+		if err := db.Commit(tx); err != nil {
+			return nil, errors.NewInternal(err)
+		}
 		output.Stored = storeResult
 	}
 
 	return output, nil
 }
 
-// assembleMarkdown creates markdown format: ## heading\n\ntext\n\n---\n\n...
-func assembleMarkdown(parts []ComposePart) string {
+// sortComposeParts orders parts by the given field. The sort is stable, so
+// ties (equal timestamps, repeated or missing names) keep caller order.
+func sortComposeParts(parts []ComposePart, order ComposeOrder) {
+	slices.SortStableFunc(parts, func(a, b ComposePart) int {
+		switch order {
+		case ComposeOrderCreated:
+			return cmp.Compare(a.createdAt, b.createdAt)
+		case ComposeOrderUpdated:
+			return cmp.Compare(a.updatedAt, b.updatedAt)
+		default: // ComposeOrderName
+			if (a.nameNorm == "") != (b.nameNorm == "") {
+				if a.nameNorm == "" {
+					return 1
+				}
+				return -1
+			}
+			return cmp.Compare(a.nameNorm, b.nameNorm)
+		}
+	})
+}
+
+// assembleMarkdown creates markdown format: <header>\n\ntext\n\n---\n\n...
+// The header defaults to "## {display name}".
+func assembleMarkdown(ctx context.Context, parts []ComposePart, header *template.Template) (string, error) {
 	var sb strings.Builder
 	for i, part := range parts {
+		select {
+		case <-ctx.Done():
+			return "", errors.NewCancelled("compose")
+		default:
+		}
+
 		if i > 0 {
 			sb.WriteString("\n\n---\n\n")
 		}
-		sb.WriteString("## ")
-		sb.WriteString(part.DisplayName)
+		h, err := renderHeader(header, part, i)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(h)
 		sb.WriteString("\n\n")
 		sb.WriteString(part.Text)
 	}
-	return sb.String()
+	return sb.String(), nil
+}
+
+// applyTrailingNewline makes text end with exactly one "\n" (policy true) or
+// none (false). A nil policy or an empty bundle leaves text unchanged.
+func applyTrailingNewline(text string, policy *bool) string {
+	if policy == nil || text == "" {
+		return text
+	}
+	text = strings.TrimRight(text, "\n")
+	if *policy {
+		text += "\n"
+	}
+	return text
+}
+
+// renderHeader executes the header template for the part at position i (0-based).
+func renderHeader(header *template.Template, part ComposePart, i int) (string, error) {
+	data := ComposeHeaderData{
+		Title:       part.title,
+		Name:        part.Name,
+		ID:          part.ID,
+		Workspace:   part.Workspace,
+		DisplayName: part.DisplayName,
+		Index:       i + 1,
+	}
+	w := &limitedWriter{limit: maxHeaderOutputChars}
+	if err := header.Execute(w, data); err != nil {
+		return "", errors.NewInvalidRequest(fmt.Sprintf("header_template: %v", err))
+	}
+	return w.sb.String(), nil
+}
+
+// bundleSize accumulates the size of a bundle piece by piece, tracking whether
+// the last piece ended mid-word so a word split across pieces counts once.
+type bundleSize struct {
+	chars            int
+	bytes            int
+	words            int
+	endsInWord       bool
+	trailingNewlines int // "\n"s at the end of the bundle so far
+}
+
+func (s *bundleSize) add(piece string) {
+	if piece == "" {
+		return
+	}
+	s.chars += capsule.CountChars(piece)
+	s.bytes += len(piece)
+	s.words += len(strings.Fields(piece))
+	if first, _ := utf8.DecodeRuneInString(piece); s.endsInWord && !unicode.IsSpace(first) {
+		s.words--
+	}
+	last, _ := utf8.DecodeLastRuneInString(piece)
+	s.endsInWord = !unicode.IsSpace(last)
+	if trimmed := strings.TrimRight(piece, "\n"); trimmed == "" {
+		s.trailingNewlines += len(piece)
+	} else {
+		s.trailingNewlines = len(piece) - len(trimmed)
+	}
+}
+
+// applyTrailingNewline adjusts the size the way applyTrailingNewline adjusts
+// the bundle text.
+func (s *bundleSize) applyTrailingNewline(policy *bool) {
+	if policy == nil || s.bytes == 0 {
+		return
+	}
+	s.chars -= s.trailingNewlines
+	s.bytes -= s.trailingNewlines
+	s.trailingNewlines = 0
+	if *policy {
+		s.add("\n")
+	}
+}
+
+// checkLimits returns COMPOSE_TOO_LARGE if the bundle so far is over
+// capsule_max_chars or, when set, capsule_max_bytes.
+func (s *bundleSize) checkLimits(cfg *config.Config) error {
+	if s.chars > cfg.CapsuleMaxChars {
+		return errors.NewComposeTooLarge(cfg.CapsuleMaxChars, s.chars)
+	}
+	if cfg.CapsuleMaxBytes > 0 && s.bytes > cfg.CapsuleMaxBytes {
+		return errors.NewComposeTooLargeBytes(cfg.CapsuleMaxBytes, s.bytes)
+	}
+	return nil
+}
+
+// bundleProjection measures what assembleMarkdown/assembleJSON would produce,
+// one part at a time, without building the bundle.
+type bundleProjection struct {
+	format          string
+	header          *template.Template
+	trailingNewline *bool
+	size            bundleSize
+	parts           int
+}
+
+func newBundleProjection(format string, header *template.Template, trailingNewline *bool) *bundleProjection {
+	p := &bundleProjection{format: format, header: header, trailingNewline: trailingNewline}
+	if format == "json" {
+		p.size.add("{\n  \"parts\": [")
+	}
+	return p
+}
+
+// addPart adds the next part with its header (markdown) or serialized object (json).
+func (p *bundleProjection) addPart(part ComposePart) error {
+	defer func() { p.parts++ }()
+
+	if p.format == "json" {
+		// Serialize with the indentation the part has inside the bundle
+		data, err := json.MarshalIndent(part, "    ", "  ")
+		if err != nil {
+			return errors.NewInternal(err)
+		}
+		if p.parts > 0 {
+			p.size.add(",")
+		}
+		p.size.add("\n    ")
+		p.size.add(string(data))
+		return nil
+	}
+
+	if p.parts > 0 {
+		p.size.add("\n\n---\n\n")
+	}
+	h, err := renderHeader(p.header, part, p.parts)
+	if err != nil {
+		return err
+	}
+	p.size.add(h)
+	p.size.add("\n\n")
+	p.size.add(part.Text)
+	return nil
+}
+
+// finish adds the closing framing once all parts are added.
+func (p *bundleProjection) finish() {
+	if p.format != "json" {
+		return
+	}
+	if p.parts > 0 {
+		p.size.add("\n  ")
+	}
+	p.size.add("]\n}")
+}
+
+// measured returns the size of the bundle so far with the trailing newline
+// policy applied. Adding parts never shrinks it, so a prefix over the limits
+// means the whole bundle is too.
+func (p *bundleProjection) measured() *bundleSize {
+	s := p.size
+	s.applyTrailingNewline(p.trailingNewline)
+	return &s
+}
+
+// errHeaderTooLong aborts header template execution once output exceeds the cap.
+var errHeaderTooLong = fmt.Errorf("output exceeds %d chars", maxHeaderOutputChars)
+
+// limitedWriter buffers template output, failing once it exceeds limit bytes.
+type limitedWriter struct {
+	sb    strings.Builder
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.sb.Len()+len(p) > w.limit {
+		return 0, errHeaderTooLong
+	}
+	return w.sb.Write(p)
 }
 
 // assembleJSON creates JSON format: {"parts": [...]}
@@ -257,7 +652,9 @@ func assembleJSON(parts []ComposePart) (string, error) {
 // Sections are matched by exact name (case-insensitive), in the order specified
 // by the caller. Placeholder sections are skipped. If no sections are found
 // (e.g., thin capsule without markdown headers), the original text is returned.
-func filterSections(text string, sections []string) string {
+// A non-nil separator replaces the default blank line between sections; each
+// section's trailing whitespace is trimmed so the separator alone divides them.
+func filterSections(text string, sections []string, separator *string) string {
 	parsed := capsule.ParseSections(text)
 	if len(parsed) == 0 {
 		return text // thin capsule, no markdown headers — pass through unchanged
@@ -270,10 +667,18 @@ func filterSections(text string, sections []string) string {
 		if sec == nil || sec.IsPlaceholder {
 			continue
 		}
+		section := text[sec.HeaderStart:sec.ContentEnd]
+		if separator != nil {
+			section = strings.TrimRight(section, " \t\n")
+		}
 		if found {
-			sb.WriteString("\n")
+			if separator != nil {
+				sb.WriteString(*separator)
+			} else {
+				sb.WriteString("\n")
+			}
 		}
-		sb.WriteString(text[sec.HeaderStart:sec.ContentEnd])
+		sb.WriteString(section)
 		found = true
 	}
 
@@ -2,9 +2,11 @@ package ops
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/config"
 	"github.com/hpungsan/moss/internal/db"
 	"github.com/hpungsan/moss/internal/errors"
@@ -472,6 +474,61 @@ func TestCompose_StoreAs_ModeReplace(t *testing.T) {
 	}
 }
 
+func TestCompose_StoreAs_TitlePrefixSuffix(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	if _, err := Store(ctx, database, cfg, StoreInput{Name: stringPtr("cap1"), CapsuleText: validCapsuleText}); err != nil {
+		t.Fatalf("Store cap1 failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		prefix *string
+		suffix *string
+		want   string
+	}{
+		{"prefix", stringPtr("[bundle] "), nil, "[bundle] sprint"},
+		{"suffix", nil, stringPtr(" (composite)"), "sprint (composite)"},
+		{"both", stringPtr("[bundle] "), stringPtr(" v2"), "[bundle] sprint v2"},
+		{"neither", nil, nil, "sprint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := Compose(ctx, database, cfg, ComposeInput{
+				Items: []ComposeRef{{Name: "cap1"}},
+				StoreAs: &ComposeStoreAs{
+					Workspace:   tt.name,
+					Name:        "sprint",
+					TitlePrefix: tt.prefix,
+					TitleSuffix: tt.suffix,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Compose failed: %v", err)
+			}
+
+			fetched, err := Fetch(ctx, database, FetchInput{ID: output.Stored.ID})
+			if err != nil {
+				t.Fatalf("Fetch failed: %v", err)
+			}
+			if fetched.Title == nil || *fetched.Title != tt.want {
+				t.Errorf("Title = %v, want %q", fetched.Title, tt.want)
+			}
+			if fetched.Name == nil || *fetched.Name != "sprint" {
+				t.Errorf("Name = %v, want sprint (undecorated)", fetched.Name)
+			}
+		})
+	}
+}
+
 func TestCompose_StoreAs_NameCollision_ModeError(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -1350,6 +1407,64 @@ func TestCompose_Sections_PreservesOrder(t *testing.T) {
 	}
 }
 
+func TestCompose_Sections_IntraPartSeparator(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	_, err = Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "default",
+		Name:        stringPtr("cap1"),
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	sep := "\n\n* * *\n\n"
+	output, err := Compose(context.Background(), database, cfg, ComposeInput{
+		Items:              []ComposeRef{{Workspace: "default", Name: "cap1"}},
+		Sections:           []string{"Decisions", "Open questions"},
+		IntraPartSeparator: &sep,
+	})
+	if err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+
+	if n := strings.Count(output.BundleText, sep); n != 1 {
+		t.Fatalf("separator appears %d times, want 1:\n%s", n, output.BundleText)
+	}
+	before, after, _ := strings.Cut(output.BundleText, sep)
+	if !strings.Contains(before, "## Decisions") || !strings.HasPrefix(after, "## Open questions") {
+		t.Errorf("separator should sit between Decisions and Open questions:\n%s", output.BundleText)
+	}
+	if strings.HasSuffix(before, "\n") {
+		t.Errorf("Decisions' trailing newlines should be trimmed before the separator: %q", before)
+	}
+	if strings.HasSuffix(strings.TrimRight(output.BundleText, " \t\n"), strings.TrimSpace(sep)) {
+		t.Error("separator should not follow the last section")
+	}
+	if output.BundleChars != capsule.CountChars(output.BundleText) {
+		t.Errorf("BundleChars = %d, want %d", output.BundleChars, capsule.CountChars(output.BundleText))
+	}
+
+	// JSON format rejects the separator
+	_, err = Compose(context.Background(), database, cfg, ComposeInput{
+		Items:              []ComposeRef{{Workspace: "default", Name: "cap1"}},
+		Format:             "json",
+		Sections:           []string{"Decisions"},
+		IntraPartSeparator: &sep,
+	})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest for json format, got %v", err)
+	}
+}
+
 func TestCompose_Sections_AllMissing_SkipsPart(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -1541,3 +1656,527 @@ func TestCompose_Sections_EmptyBundle_StoreAs_Rejected(t *testing.T) {
 		t.Errorf("error should mention empty bundle, got: %v", err)
 	}
 }
+
+func TestCompose_HeaderTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	_, err = Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "alpha",
+		Name:        stringPtr("cap1"),
+		Title:       stringPtr("Capsule One"),
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store cap1 failed: %v", err)
+	}
+	_, err = Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "beta",
+		Name:        stringPtr("cap2"),
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store cap2 failed: %v", err)
+	}
+
+	output, err := Compose(context.Background(), database, cfg, ComposeInput{
+		Items: []ComposeRef{
+			{Workspace: "alpha", Name: "cap1"},
+			{Workspace: "beta", Name: "cap2"},
+		},
+		HeaderTemplate: stringPtr("## {{.Index}}. {{.Workspace}}/{{.Name}}{{if .Title}} — {{.Title}}{{end}}"),
+	})
+	if err != nil {
+		t.Fatalf("Compose failed: %v", err)
+	}
+
+	if !strings.HasPrefix(output.BundleText, "## 1. alpha/cap1 — Capsule One\n\n## Objective") {
+		t.Errorf("first header not rendered from template, got:\n%.80s", output.BundleText)
+	}
+	// Title defaults to the name on store
+	if !strings.Contains(output.BundleText, "\n\n---\n\n## 2. beta/cap2 — cap2\n\n") {
+		t.Error("second header should use index 2")
+	}
+	if strings.Contains(output.BundleText, "## Capsule One\n") {
+		t.Error("default header should not be used with a custom template")
+	}
+}
+
+func TestCompose_HeaderTemplate_Invalid(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	_, err = Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "default",
+		Name:        stringPtr("cap1"),
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		template string
+	}{
+		{"malformed", "markdown", "## {{.Name"},
+		{"unknown field", "markdown", "## {{.Author}}"},
+		{"runaway output", "markdown", "{{range 100000}}x{{end}}"},
+		{"empty", "markdown", "   "},
+		{"json format", "json", "## {{.Name}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compose(context.Background(), database, cfg, ComposeInput{
+				Items:          []ComposeRef{{Workspace: "default", Name: "cap1"}},
+				Format:         tt.format,
+				HeaderTemplate: stringPtr(tt.template),
+			})
+			if !errors.Is(err, errors.ErrInvalidRequest) {
+				t.Errorf("expected ErrInvalidRequest, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestCompose_DryRun_MatchesComposedSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	_, err = Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "default",
+		Name:        stringPtr("cap1"),
+		Title:       stringPtr("Capsule \"One\" <é>"),
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store cap1 failed: %v", err)
+	}
+	_, err = Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "default",
+		Name:        stringPtr("cap2"),
+		CapsuleText: validCapsuleText + "\nnaïve café — ünïcode",
+	})
+	if err != nil {
+		t.Fatalf("Store cap2 failed: %v", err)
+	}
+
+	items := []ComposeRef{
+		{Workspace: "default", Name: "cap1"},
+		{Workspace: "default", Name: "cap2"},
+	}
+	tests := []struct {
+		name  string
+		input ComposeInput
+	}{
+		{"markdown", ComposeInput{Items: items}},
+		{"json", ComposeInput{Items: items, Format: "json"}},
+		{"sections", ComposeInput{Items: items, Sections: []string{"Decisions", "Objective"}}},
+		{"header template", ComposeInput{Items: items, HeaderTemplate: stringPtr("### {{.Index}} {{.Name}}")}},
+		{"sections json", ComposeInput{Items: items, Format: "json", Sections: []string{"Status"}}},
+		{"no matching sections", ComposeInput{Items: items, Format: "json", Sections: []string{"Nope"}}},
+		{"trailing newline", ComposeInput{Items: items, TrailingNewline: boolPtr(true)}},
+		{"no trailing newline", ComposeInput{Items: items, Sections: []string{"Status"}, TrailingNewline: boolPtr(false)}},
+		{"json trailing newline", ComposeInput{Items: items, Format: "json", TrailingNewline: boolPtr(true)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := Compose(context.Background(), database, cfg, tt.input)
+			if err != nil {
+				t.Fatalf("Compose failed: %v", err)
+			}
+
+			dry := tt.input
+			dry.DryRun = true
+			projected, err := Compose(context.Background(), database, cfg, dry)
+			if err != nil {
+				t.Fatalf("Compose dry run failed: %v", err)
+			}
+
+			if projected.BundleText != "" {
+				t.Error("dry run should not assemble BundleText")
+			}
+			if projected.BundleChars != actual.BundleChars {
+				t.Errorf("projected BundleChars = %d, actual %d", projected.BundleChars, actual.BundleChars)
+			}
+			if projected.PartsCount != actual.PartsCount {
+				t.Errorf("projected PartsCount = %d, actual %d", projected.PartsCount, actual.PartsCount)
+			}
+			if projected.DryRun == nil {
+				t.Fatal("DryRun should be set")
+			}
+			if want := capsule.EstimateTokens(actual.BundleText); projected.DryRun.TokensEstimate != want {
+				t.Errorf("projected TokensEstimate = %d, actual %d", projected.DryRun.TokensEstimate, want)
+			}
+			if projected.DryRun.TooLarge {
+				t.Error("TooLarge should be false within the default limit")
+			}
+		})
+	}
+}
+
+func TestCompose_TrailingNewline(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	cfg := config.DefaultConfig()
+
+	// The last part ends with several newlines, so the default keeps them all
+	for name, text := range map[string]string{"cap1": validCapsuleText, "cap2": validCapsuleText + "\n\n\n"} {
+		if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "default", Name: stringPtr(name), CapsuleText: text}); err != nil {
+			t.Fatalf("Store %s failed: %v", name, err)
+		}
+	}
+	items := []ComposeRef{{Workspace: "default", Name: "cap1"}, {Workspace: "default", Name: "cap2"}}
+
+	tests := []struct {
+		name   string
+		format string
+		policy *bool
+		suffix string // how bundle_text must end
+		not    string // and must not end
+	}{
+		{"markdown default", "markdown", nil, "\n\n\n", ""},
+		{"markdown true", "markdown", boolPtr(true), "\n", "\n\n"},
+		{"markdown false", "markdown", boolPtr(false), "", "\n"},
+		{"json default", "json", nil, "}", ""},
+		{"json true", "json", boolPtr(true), "}\n", ""},
+		{"json false", "json", boolPtr(false), "}", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Compose(ctx, database, cfg, ComposeInput{Items: items, Format: tt.format, TrailingNewline: tt.policy})
+			if err != nil {
+				t.Fatalf("Compose failed: %v", err)
+			}
+			if !strings.HasSuffix(out.BundleText, tt.suffix) {
+				t.Errorf("bundle ends with %q, want suffix %q", out.BundleText[len(out.BundleText)-5:], tt.suffix)
+			}
+			if tt.not != "" && strings.HasSuffix(out.BundleText, tt.not) {
+				t.Errorf("bundle ends with %q, must not end with %q", out.BundleText[len(out.BundleText)-5:], tt.not)
+			}
+			if out.BundleChars != capsule.CountChars(out.BundleText) {
+				t.Errorf("BundleChars = %d, want %d", out.BundleChars, capsule.CountChars(out.BundleText))
+			}
+		})
+	}
+
+	// The stored bundle gets the same ending as the response
+	out, err := Compose(ctx, database, cfg, ComposeInput{
+		Items:           items,
+		TrailingNewline: boolPtr(true),
+		StoreAs:         &ComposeStoreAs{Workspace: "default", Name: "bundle"},
+	})
+	if err != nil {
+		t.Fatalf("Compose store_as failed: %v", err)
+	}
+	stored, err := Fetch(ctx, database, FetchInput{ID: out.Stored.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if stored.CapsuleText != out.BundleText {
+		t.Error("stored text differs from bundle_text")
+	}
+	if !strings.HasSuffix(stored.CapsuleText, "\n") || strings.HasSuffix(stored.CapsuleText, "\n\n") {
+		t.Errorf("stored bundle should end with exactly one newline, ends %q", stored.CapsuleText[len(stored.CapsuleText)-5:])
+	}
+}
+
+func TestCompose_DryRun_TooLargeIsMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	storeCfg := config.DefaultConfig()
+	_, err = Store(context.Background(), database, storeCfg, StoreInput{
+		Workspace:   "default",
+		Name:        stringPtr("cap1"),
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store cap1 failed: %v", err)
+	}
+
+	composeCfg := &config.Config{CapsuleMaxChars: 100}
+	output, err := Compose(context.Background(), database, composeCfg, ComposeInput{
+		Items:   []ComposeRef{{Workspace: "default", Name: "cap1"}},
+		StoreAs: &ComposeStoreAs{Name: "bundle"},
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatalf("Compose dry run should not fail when too large: %v", err)
+	}
+	if !output.DryRun.TooLarge || output.DryRun.MaxChars != 100 {
+		t.Errorf("DryRun = %+v, want too_large with max_chars 100", output.DryRun)
+	}
+	if output.BundleChars <= 100 {
+		t.Errorf("BundleChars = %d, want > 100", output.BundleChars)
+	}
+
+	// Nothing was stored
+	if _, err := db.GetByName(context.Background(), database, "default", "bundle", false); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("dry run should not store, got: %v", err)
+	}
+}
+
+func TestCompose_SizeLimitExceeded_BailsEarly(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	storeCfg := &config.Config{CapsuleMaxChars: 50000}
+
+	// 40 capsules of ~5k chars each (~200k total) against a 12k limit
+	const numCapsules = 40
+	bigText := validCapsuleText + "\n" + strings.Repeat("lorem ipsum ", 400)
+	partChars := len(bigText)
+	items := make([]ComposeRef, 0, numCapsules)
+	for i := range numCapsules {
+		out, err := Store(context.Background(), database, storeCfg, StoreInput{CapsuleText: bigText})
+		if err != nil {
+			t.Fatalf("Store %d failed: %v", i, err)
+		}
+		items = append(items, ComposeRef{ID: out.ID})
+	}
+
+	cfg := config.DefaultConfig()
+	for _, input := range []ComposeInput{
+		{Items: items},
+		{Items: items, StoreAs: &ComposeStoreAs{Name: "bundle"}},
+		{Items: items, Format: "json"},
+	} {
+		_, err = Compose(context.Background(), database, cfg, input)
+		if !errors.Is(err, errors.ErrComposeTooLarge) {
+			t.Fatalf("error = %v, want ErrComposeTooLarge", err)
+		}
+
+		// The reported size is where processing stopped: just past the limit,
+		// within one part (plus framing) of it — not the full ~200k bundle.
+		mossErr, ok := err.(*errors.MossError)
+		if !ok {
+			t.Fatalf("expected *MossError, got %T", err)
+		}
+		actual := mossErr.Details["actual_chars"].(int)
+		if actual <= cfg.CapsuleMaxChars {
+			t.Errorf("actual_chars = %d, want > %d", actual, cfg.CapsuleMaxChars)
+		}
+		if limit := cfg.CapsuleMaxChars + 2*partChars; actual > limit {
+			t.Errorf("actual_chars = %d, want <= %d (stopped within one part of the limit)", actual, limit)
+		}
+	}
+
+	// Nothing was stored
+	if _, err := db.GetByName(context.Background(), database, "default", "bundle", false); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("store_as should not store an oversized bundle, got: %v", err)
+	}
+}
+
+func TestCompose_OrderBy(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	// Each ordering disagrees with the others and with caller order
+	capsules := []struct {
+		key                  string
+		name                 *string
+		createdAt, updatedAt int64
+	}{
+		{"A", stringPtr("charlie"), 100, 600},
+		{"B", stringPtr("alpha"), 300, 400},
+		{"C", stringPtr("Bravo"), 200, 500},
+		{"D", nil, 400, 100},
+	}
+	ids := map[string]string{}
+	for _, c := range capsules {
+		out, err := Store(context.Background(), database, cfg, StoreInput{Name: c.name, CapsuleText: validCapsuleText})
+		if err != nil {
+			t.Fatalf("Store %s failed: %v", c.key, err)
+		}
+		if _, err := database.Exec("UPDATE capsules SET created_at = ?, updated_at = ? WHERE id = ?", c.createdAt, c.updatedAt, out.ID); err != nil {
+			t.Fatalf("set timestamps: %v", err)
+		}
+		ids[c.key] = out.ID
+	}
+	items := []ComposeRef{{ID: ids["B"]}, {ID: ids["D"]}, {ID: ids["A"]}, {ID: ids["C"]}}
+
+	tests := []struct {
+		order ComposeOrder
+		want  string
+	}{
+		{"", "BDAC"},
+		{ComposeOrderCaller, "BDAC"},
+		{ComposeOrderCreated, "ACBD"},
+		{ComposeOrderUpdated, "DBCA"},
+		{ComposeOrderName, "BCAD"}, // case-insensitive, unnamed last
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			header := "## {{.Index}} {{.ID}}"
+			output, err := Compose(context.Background(), database, cfg, ComposeInput{
+				Items:          items,
+				OrderBy:        tt.order,
+				HeaderTemplate: &header,
+			})
+			if err != nil {
+				t.Fatalf("Compose failed: %v", err)
+			}
+			for i, key := range tt.want {
+				want := fmt.Sprintf("## %d %s", i+1, ids[string(key)])
+				if !strings.Contains(output.BundleText, want) {
+					t.Errorf("bundle missing header %q (want order %s)", want, tt.want)
+				}
+			}
+
+			dry, err := Compose(context.Background(), database, cfg, ComposeInput{
+				Items:          items,
+				OrderBy:        tt.order,
+				HeaderTemplate: &header,
+				DryRun:         true,
+			})
+			if err != nil {
+				t.Fatalf("Compose(dry run) failed: %v", err)
+			}
+			if dry.BundleChars != output.BundleChars {
+				t.Errorf("dry run BundleChars = %d, want %d", dry.BundleChars, output.BundleChars)
+			}
+		})
+	}
+}
+
+func TestCompose_OrderBy_Invalid(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	_, err = Compose(context.Background(), database, config.DefaultConfig(), ComposeInput{
+		Items:   []ComposeRef{{Workspace: "default", Name: "cap1"}},
+		OrderBy: "size",
+	})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("Compose error = %v, want INVALID_REQUEST", err)
+	}
+}
+
+func TestCompose_IgnoresNote(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	_, err = Store(ctx, database, cfg, StoreInput{
+		Workspace:   "default",
+		Name:        stringPtr("noted"),
+		CapsuleText: validCapsuleText,
+		Note:        stringPtr("private reviewer aside"),
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	for _, format := range []string{"markdown", "json"} {
+		output, err := Compose(ctx, database, cfg, ComposeInput{
+			Items:  []ComposeRef{{Workspace: "default", Name: "noted"}},
+			Format: format,
+		})
+		if err != nil {
+			t.Fatalf("Compose(%s) failed: %v", format, err)
+		}
+		if strings.Contains(output.BundleText, "reviewer aside") {
+			t.Errorf("Compose(%s) bundle contains the note", format)
+		}
+	}
+}
+
+func TestCompose_SizeLimitExceeded_Bytes(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	storeCfg := config.DefaultConfig()
+
+	// Each capsule is ~500 chars but ~1,400 bytes
+	text := validCapsuleText + "\n" + strings.Repeat("é漢", 200)
+	var items []ComposeRef
+	for i := range 2 {
+		out, err := Store(ctx, database, storeCfg, StoreInput{Name: stringPtr(fmt.Sprintf("mb-%d", i)), CapsuleText: text})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		items = append(items, ComposeRef{ID: out.ID})
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.CapsuleMaxBytes = 2000
+	_, err = Compose(ctx, database, cfg, ComposeInput{Items: items})
+	if !errors.Is(err, errors.ErrComposeTooLarge) {
+		t.Fatalf("error = %v, want ErrComposeTooLarge", err)
+	}
+	mossErr, ok := err.(*errors.MossError)
+	if !ok || mossErr.Details["max_bytes"] != 2000 {
+		t.Errorf("error = %v, want the byte limit error", err)
+	}
+
+	dry, err := Compose(ctx, database, cfg, ComposeInput{Items: items, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if !dry.DryRun.TooLarge || dry.DryRun.MaxBytes != 2000 {
+		t.Errorf("DryRun = %+v, want too_large with max_bytes 2000", dry.DryRun)
+	}
+
+	// Within both limits
+	cfg.CapsuleMaxBytes = 10000
+	if _, err := Compose(ctx, database, cfg, ComposeInput{Items: items}); err != nil {
+		t.Errorf("Compose under the byte limit failed: %v", err)
+	}
+}
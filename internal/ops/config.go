@@ -0,0 +1,59 @@
+package ops
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// SetConfigInput contains parameters for the SetConfig operation.
+type SetConfigInput struct {
+	Key   string          // required, one of config.RuntimeKeys
+	Value json.RawMessage // required, JSON value of the key's type
+}
+
+// SetConfigOutput contains the result of the SetConfig operation.
+type SetConfigOutput struct {
+	Key     string          `json:"key"`
+	Value   json.RawMessage `json:"value"`
+	Path    string          `json:"path"` // config file the value was persisted to
+	Message string          `json:"message"`
+}
+
+// SetConfig validates a runtime config change, persists it to dir/config.json,
+// and returns the updated config for the caller to swap in. cfg is not modified.
+// Keys outside config.RuntimeKeys fail with INVALID_REQUEST.
+func SetConfig(cfg *config.Config, dir string, input SetConfigInput) (*config.Config, *SetConfigOutput, error) {
+	key := strings.TrimSpace(input.Key)
+	if key == "" {
+		return nil, nil, errors.NewInvalidRequest("key is required")
+	}
+	if len(bytes.TrimSpace(input.Value)) == 0 {
+		return nil, nil, errors.NewInvalidRequest("value is required")
+	}
+	if dir == "" {
+		return nil, nil, errors.NewInvalidRequest("config changes can't be saved: no config directory")
+	}
+
+	next, err := cfg.WithValue(key, input.Value)
+	if err != nil {
+		return nil, nil, errors.NewInvalidRequest(err.Error())
+	}
+
+	// Persist before returning the new config so memory never runs ahead of disk
+	path, err := config.SaveValue(dir, key, input.Value)
+	if err != nil {
+		return nil, nil, errors.NewInternal(fmt.Errorf("failed to save config: %w", err))
+	}
+
+	return next, &SetConfigOutput{
+		Key:     key,
+		Value:   input.Value,
+		Path:    path,
+		Message: fmt.Sprintf("Set %s; saved to %s", key, path),
+	}, nil
+}
@@ -2,7 +2,6 @@ package ops
 
 import (
 	"context"
-	"database/sql"
 
 	"github.com/hpungsan/moss/internal/db"
 )
@@ -21,7 +20,13 @@ type DeleteOutput struct {
 }
 
 // Delete soft-deletes a capsule.
-func Delete(ctx context.Context, database *sql.DB, input DeleteInput) (*DeleteOutput, error) {
+func Delete(ctx context.Context, database db.Querier, input DeleteInput) (*DeleteOutput, error) {
+	defer invalidateSearchCache()
+	return deleteCapsule(ctx, database, input)
+}
+
+// deleteCapsule is Delete without the search-cache bump; Batch bumps once after its transaction commits.
+func deleteCapsule(ctx context.Context, q db.Querier, input DeleteInput) (*DeleteOutput, error) {
 	// Validate address
 	addr, err := ValidateAddress(input.ID, input.Workspace, input.Name)
 	if err != nil {
@@ -33,12 +38,12 @@ func Delete(ctx context.Context, database *sql.DB, input DeleteInput) (*DeleteOu
 	if addr.ByID {
 		capsuleID = addr.ID
 		// Verify it exists (GetByID will return ErrNotFound if not)
-		_, err = db.GetByID(ctx, database, addr.ID, false)
+		_, err = db.GetByID(ctx, q, addr.ID, false)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		c, err := db.GetByName(ctx, database, addr.Workspace, addr.Name, false)
+		c, err := db.GetByName(ctx, q, addr.Workspace, addr.Name, false)
 		if err != nil {
 			return nil, err
 		}
@@ -46,7 +51,7 @@ func Delete(ctx context.Context, database *sql.DB, input DeleteInput) (*DeleteOu
 	}
 
 	// Soft delete
-	if err := db.SoftDelete(ctx, database, capsuleID); err != nil {
+	if err := db.SoftDelete(ctx, q, capsuleID); err != nil {
 		return nil, err
 	}
 
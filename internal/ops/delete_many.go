@@ -0,0 +1,110 @@
+package ops
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// DeleteManyAlreadyDeleted is the per-ID error code for capsules that were already soft-deleted.
+const DeleteManyAlreadyDeleted = "ALREADY_DELETED"
+
+// DeleteManyOutput contains the result of the DeleteMany operation.
+type DeleteManyOutput struct {
+	Items   []DeleteManyItem `json:"items"`
+	Deleted int              `json:"deleted"`
+}
+
+// DeleteManyItem is the outcome for a single ID, in request order.
+type DeleteManyItem struct {
+	ID      string           `json:"id"`
+	Deleted bool             `json:"deleted"`
+	Error   *DeleteManyError `json:"error,omitempty"`
+}
+
+// DeleteManyError explains why an ID was not deleted.
+type DeleteManyError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// DeleteMany soft-deletes an explicit list of capsules by ID.
+// Unlike BulkDelete no filter guard is needed: every target is named.
+// Missing and already-deleted IDs are reported per item rather than aborting the batch.
+func DeleteMany(ctx context.Context, database db.Querier, ids []string) (*DeleteManyOutput, error) {
+	defer invalidateSearchCache()
+
+	if len(ids) == 0 {
+		return nil, errors.NewInvalidRequest("ids is required")
+	}
+	if len(ids) > MaxFetchManyItems {
+		return nil, errors.NewInvalidRequest(
+			fmt.Sprintf("too many ids: %d (max %d)", len(ids), MaxFetchManyItems))
+	}
+
+	items := make([]DeleteManyItem, 0, len(ids))
+	deleted := 0
+
+	for _, rawID := range ids {
+		select {
+		case <-ctx.Done():
+			return nil, errors.NewCancelled("delete_many")
+		default:
+		}
+
+		id := strings.TrimSpace(rawID)
+		item := DeleteManyItem{ID: id}
+		if err := deleteOne(ctx, database, id); err != nil {
+			if ctx.Err() != nil {
+				return nil, errors.NewCancelled("delete_many")
+			}
+			item.Error = err
+		} else {
+			item.Deleted = true
+			deleted++
+		}
+		items = append(items, item)
+	}
+
+	return &DeleteManyOutput{
+		Items:   items,
+		Deleted: deleted,
+	}, nil
+}
+
+// deleteOne soft-deletes a single active capsule, distinguishing missing from already-deleted.
+func deleteOne(ctx context.Context, q db.Querier, id string) *DeleteManyError {
+	if id == "" {
+		return toDeleteManyError(errors.NewInvalidRequest("id must not be empty"))
+	}
+
+	c, err := db.GetByID(ctx, q, id, true)
+	if err != nil {
+		return toDeleteManyError(err)
+	}
+	if c.DeletedAt != nil {
+		return &DeleteManyError{
+			Code:    DeleteManyAlreadyDeleted,
+			Message: fmt.Sprintf("capsule already deleted: %s", id),
+		}
+	}
+
+	if err := db.SoftDelete(ctx, q, id); err != nil {
+		return toDeleteManyError(err)
+	}
+	return nil
+}
+
+// toDeleteManyError converts a delete error to a DeleteManyError.
+func toDeleteManyError(err error) *DeleteManyError {
+	var mossErr *errors.MossError
+	if stderrors.As(err, &mossErr) {
+		return &DeleteManyError{Code: string(mossErr.Code), Message: mossErr.Message}
+	}
+
+	return &DeleteManyError{Code: string(errors.ErrInternal), Message: err.Error()}
+}
@@ -0,0 +1,95 @@
+package ops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestDeleteMany_MixedIDs(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	active, err := Store(ctx, database, cfg, StoreInput{CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	gone, err := Store(ctx, database, cfg, StoreInput{CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := Delete(ctx, database, DeleteInput{ID: gone.ID}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	output, err := DeleteMany(ctx, database, []string{active.ID, "01MISSING000000000000000000", gone.ID, " ", active.ID})
+	if err != nil {
+		t.Fatalf("DeleteMany failed: %v", err)
+	}
+
+	if output.Deleted != 1 {
+		t.Errorf("Deleted = %d, want 1", output.Deleted)
+	}
+	want := []struct {
+		deleted bool
+		code    string
+	}{
+		{true, ""},
+		{false, string(errors.ErrNotFound)},
+		{false, DeleteManyAlreadyDeleted},
+		{false, string(errors.ErrInvalidRequest)},
+		{false, DeleteManyAlreadyDeleted}, // duplicate of the first ID
+	}
+	if len(output.Items) != len(want) {
+		t.Fatalf("len(Items) = %d, want %d", len(output.Items), len(want))
+	}
+	for i, w := range want {
+		item := output.Items[i]
+		if item.Deleted != w.deleted {
+			t.Errorf("Items[%d].Deleted = %v, want %v", i, item.Deleted, w.deleted)
+		}
+		code := ""
+		if item.Error != nil {
+			code = item.Error.Code
+		}
+		if code != w.code {
+			t.Errorf("Items[%d] code = %q, want %q", i, code, w.code)
+		}
+	}
+
+	// The active capsule is now soft-deleted
+	if _, err := db.GetByID(ctx, database, active.ID, false); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("capsule should be deleted, got: %v", err)
+	}
+}
+
+func TestDeleteMany_Limits(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	if _, err := DeleteMany(context.Background(), database, nil); !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("empty ids: expected ErrInvalidRequest, got: %v", err)
+	}
+
+	ids := make([]string, MaxFetchManyItems+1)
+	for i := range ids {
+		ids[i] = "01TOOMANY"
+	}
+	if _, err := DeleteMany(context.Background(), database, ids); !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("too many ids: expected ErrInvalidRequest, got: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package ops
+
+import (
+	"context"
+
+	"github.com/hpungsan/moss/internal/db"
+)
+
+// ExistsInput contains parameters for the Exists operation.
+type ExistsInput struct {
+	ID             string
+	Workspace      string
+	Name           string
+	IncludeDeleted bool
+}
+
+// ExistsOutput contains the result of the Exists operation.
+type ExistsOutput struct {
+	Exists bool   `json:"exists"`
+	ID     string `json:"id,omitempty"` // set when Exists
+}
+
+// Exists reports whether a capsule is addressable by ID or name without
+// loading it. Absence is a normal result, not NOT_FOUND; only bad addressing
+// is an error. Soft-deleted and expired capsules count only with IncludeDeleted.
+func Exists(ctx context.Context, database db.Querier, input ExistsInput) (*ExistsOutput, error) {
+	addr, err := ValidateAddress(input.ID, input.Workspace, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if addr.ByID {
+		found, err := db.ExistsByID(ctx, database, addr.ID, input.IncludeDeleted)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return &ExistsOutput{}, nil
+		}
+		return &ExistsOutput{Exists: true, ID: addr.ID}, nil
+	}
+
+	id, err := db.IDByName(ctx, database, addr.Workspace, addr.Name, input.IncludeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	return &ExistsOutput{Exists: id != "", ID: id}, nil
+}
@@ -0,0 +1,67 @@
+package ops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestExists(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	live, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", Name: stringPtr("auth"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	gone, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", Name: stringPtr("old"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := Delete(ctx, database, DeleteInput{ID: gone.ID}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		input  ExistsInput
+		wantID string // "" means not exists
+	}{
+		{"existing by id", ExistsInput{ID: live.ID}, live.ID},
+		{"existing by name", ExistsInput{Workspace: "Proj", Name: "AUTH"}, live.ID},
+		{"missing by id", ExistsInput{ID: "01NOTAREALCAPSULEID0000000"}, ""},
+		{"missing by name", ExistsInput{Workspace: "proj", Name: "nope"}, ""},
+		{"deleted by id", ExistsInput{ID: gone.ID}, ""},
+		{"deleted by name", ExistsInput{Workspace: "proj", Name: "old"}, ""},
+		{"deleted by id with include_deleted", ExistsInput{ID: gone.ID, IncludeDeleted: true}, gone.ID},
+		{"deleted by name with include_deleted", ExistsInput{Workspace: "proj", Name: "old", IncludeDeleted: true}, gone.ID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := Exists(ctx, database, tt.input)
+			if err != nil {
+				t.Fatalf("Exists failed: %v", err)
+			}
+			if output.Exists != (tt.wantID != "") || output.ID != tt.wantID {
+				t.Errorf("Exists = %+v, want exists=%v id=%q", output, tt.wantID != "", tt.wantID)
+			}
+		})
+	}
+
+	if _, err := Exists(ctx, database, ExistsInput{ID: live.ID, Name: "auth"}); !errors.Is(err, errors.ErrAmbiguousAddressing) {
+		t.Errorf("id+name error = %v, want AMBIGUOUS_ADDRESSING", err)
+	}
+	if _, err := Exists(ctx, database, ExistsInput{}); !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("no address error = %v, want INVALID_REQUEST", err)
+	}
+}
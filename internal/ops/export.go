@@ -3,13 +3,15 @@ package ops
 import (
 	"context"
 	"crypto/rand"
-	"database/sql"
+	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hpungsan/moss/internal/capsule"
@@ -18,29 +20,129 @@ import (
 	"github.com/hpungsan/moss/internal/errors"
 )
 
+// ExportFormat controls the layout of the export file.
+type ExportFormat string
+
+const (
+	ExportFormatJSONL ExportFormat = "jsonl" // header line + one record per line (streaming)
+	ExportFormatJSON  ExportFormat = "json"  // single document: {"header": {...}, "capsules": [...]}
+	ExportFormatCSV   ExportFormat = "csv"   // metadata-only spreadsheet rows (export only, not importable)
+)
+
+// ExportOrder controls the record order of the export file.
+type ExportOrder string
+
+const (
+	ExportOrderCreated   ExportOrder = "created"   // created_at, id (default)
+	ExportOrderWorkspace ExportOrder = "workspace" // workspace, name (unnamed last), id: diff-friendly
+)
+
+// MaxExportIDs caps ExportInput.IDs so the IN list stays well under SQLite's
+// bound-parameter limit.
+const MaxExportIDs = 500
+
+// csvExportColumns is the header row of a CSV export. capsule_text is deliberately
+// omitted: it bloats rows and is an escaping hazard for spreadsheet tools.
+var csvExportColumns = []string{
+	"id", "workspace", "name", "title", "tags", "phase", "role",
+	"chars", "tokens", "created_at", "updated_at", "deleted_at",
+}
+
 // ExportInput contains parameters for the Export operation.
 type ExportInput struct {
-	Path           string  // optional, default: ~/.moss/exports/<workspace>-<timestamp>.jsonl
-	Workspace      *string // optional filter by workspace
+	Path           string   // optional, default: ~/.moss/exports/<workspace>-<timestamp>.jsonl (.json / .csv for those formats)
+	Workspace      *string  // optional filter by workspace
+	IDs            []string // optional: export only these capsules (max 500)
 	IncludeDeleted bool
+	Format         ExportFormat // default: jsonl
+	OrderBy        ExportOrder  // default: created
+	Compact        bool         // omit fields import recomputes (jsonl/json only)
+
+	// Resumable exports (order created only)
+	ResumeFrom *string // export only capsules after this ID (a previous ResumeCursor)
+	Append     bool    // write straight into Path, appending; the header is written only if the file is new or empty (jsonl/csv only)
 }
 
 // ExportOutput contains the result of the Export operation.
 type ExportOutput struct {
-	Path       string `json:"path"`
-	Count      int    `json:"count"`
-	ExportedAt int64  `json:"exported_at"`
+	Path       string       `json:"path"`
+	Count      int          `json:"count"`
+	ExportedAt int64        `json:"exported_at"`
+	Format     ExportFormat `json:"format"`
+
+	// ResumeCursor is the ID of the last capsule written; pass it as
+	// ResumeFrom (with Append) to continue. Empty when nothing was written.
+	ResumeCursor string `json:"resume_cursor,omitempty"`
 }
 
-// ExportHeader represents the header line in a JSONL export file.
+// ExportHeader represents the header line in a JSONL export file
+// (or the "header" field of a JSON export document).
 type ExportHeader struct {
 	MossExport    bool   `json:"_moss_export"`
 	SchemaVersion string `json:"schema_version"`
 	ExportedAt    int64  `json:"exported_at"`
+	Compact       bool   `json:"compact,omitempty"`
 }
 
-// Export exports capsules to a JSONL file.
-func Export(ctx context.Context, database *sql.DB, cfg *config.Config, input ExportInput) (*ExportOutput, error) {
+// Export exports capsules to a JSONL file, a single JSON document with format "json",
+// or a metadata-only CSV file with format "csv".
+//
+// By default the file is written to a temp file and renamed into place, so a
+// failed export leaves nothing behind. With Append, records are written
+// directly into Path as they stream: an interrupted export keeps every
+// complete record, and the ID on the last complete line can be passed back as
+// ResumeFrom to continue.
+func Export(ctx context.Context, database db.Querier, cfg *config.Config, input ExportInput) (*ExportOutput, error) {
+	if input.Format == "" {
+		input.Format = ExportFormatJSONL
+	}
+	if input.Format != ExportFormatJSONL && input.Format != ExportFormatJSON && input.Format != ExportFormatCSV {
+		return nil, errors.NewInvalidRequest("format must be one of: jsonl, json, csv")
+	}
+	if input.OrderBy == "" {
+		input.OrderBy = ExportOrderCreated
+	}
+	if input.OrderBy != ExportOrderCreated && input.OrderBy != ExportOrderWorkspace {
+		return nil, errors.NewInvalidRequest("order_by must be one of: created, workspace")
+	}
+	if input.Compact && input.Format == ExportFormatCSV {
+		return nil, errors.NewInvalidRequest("compact is only supported with format jsonl or json")
+	}
+	ids, err := cleanExportIDs(input.IDs)
+	if err != nil {
+		return nil, err
+	}
+	if input.Append {
+		if input.Path == "" {
+			return nil, errors.NewInvalidRequest("append requires path")
+		}
+		if input.Format == ExportFormatJSON {
+			return nil, errors.NewInvalidRequest("append is only supported with format jsonl or csv")
+		}
+	}
+	resumeFrom := cleanOptionalString(input.ResumeFrom)
+	if resumeFrom != nil {
+		if input.OrderBy != ExportOrderCreated {
+			return nil, errors.NewInvalidRequest("resume_from requires order_by created")
+		}
+		exists, err := db.ExistsByID(ctx, database, *resumeFrom, true)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.NewInvalidRequest(fmt.Sprintf("resume_from capsule not found: %s", *resumeFrom))
+		}
+	}
+
+	// The extension follows the format; import accepts .jsonl and .json
+	ext := ".jsonl"
+	switch input.Format {
+	case ExportFormatJSON:
+		ext = ".json"
+	case ExportFormatCSV:
+		ext = ".csv"
+	}
+
 	now := time.Now()
 	exportedAt := now.Unix()
 
@@ -48,7 +150,7 @@ func Export(ctx context.Context, database *sql.DB, cfg *config.Config, input Exp
 	exportPath := input.Path
 	if exportPath == "" {
 		var err error
-		exportPath, err = defaultExportPath(input.Workspace, now)
+		exportPath, err = defaultExportPath(cfg, input.Workspace, now, ext)
 		if err != nil {
 			return nil, err
 		}
@@ -56,7 +158,7 @@ func Export(ctx context.Context, database *sql.DB, cfg *config.Config, input Exp
 
 	// Validate ALL paths (both user-provided and default) for security
 	// This catches workspace injection attacks in default paths
-	if err := ValidatePath(exportPath, PathCheckWrite, cfg); err != nil {
+	if err := ValidatePathExt(exportPath, ext, PathCheckWrite, cfg); err != nil {
 		return nil, err
 	}
 
@@ -66,16 +168,42 @@ func Export(ctx context.Context, database *sql.DB, cfg *config.Config, input Exp
 		return nil, errors.NewInternal(fmt.Errorf("failed to create export directory: %w", err))
 	}
 
-	// Write to temp file first, then atomic rename to preserve existing file on failure
-	randBytes := make([]byte, 8)
-	if _, err := rand.Read(randBytes); err != nil {
-		return nil, errors.NewInternal(fmt.Errorf("failed to generate temp file name: %w", err))
+	// Write to temp file first, then atomic rename to preserve existing file on failure.
+	// Append writes in place instead, keeping whatever was written before a failure.
+	writePath := exportPath
+	flag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !input.Append {
+		randBytes := make([]byte, 8)
+		if _, err := rand.Read(randBytes); err != nil {
+			return nil, errors.NewInternal(fmt.Errorf("failed to generate temp file name: %w", err))
+		}
+		writePath = exportPath + "." + hex.EncodeToString(randBytes) + ".tmp"
+		flag = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	}
+	fileMode := os.FileMode(cfg.ExportFileMode)
+	if fileMode == 0 {
+		fileMode = 0600
 	}
-	tempPath := exportPath + "." + hex.EncodeToString(randBytes) + ".tmp"
-	file, err := openFileNoFollow(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	file, err := openFileNoFollow(writePath, flag, fileMode)
 	if err != nil {
 		return nil, errors.NewInternal(fmt.Errorf("failed to create export file: %w", err))
 	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, errors.NewInternal(err)
+	}
+	writeHeader := info.Size() == 0
+	// The create mode is masked by umask; set it explicitly so the configured mode is exact
+	if writeHeader {
+		if err := file.Chmod(fileMode); err != nil {
+			file.Close()
+			if !input.Append {
+				os.Remove(writePath)
+			}
+			return nil, errors.NewInternal(fmt.Errorf("failed to set export file mode: %w", err))
+		}
+	}
 
 	// Clean up temp file on failure (original file is preserved)
 	success := false
@@ -83,36 +211,56 @@ func Export(ctx context.Context, database *sql.DB, cfg *config.Config, input Exp
 		if file != nil {
 			file.Close()
 		}
-		if !success {
-			os.Remove(tempPath)
+		if !success && !input.Append {
+			os.Remove(writePath)
 		}
 	}()
 
-	// Write header line
-	header := ExportHeader{
-		MossExport:    true,
-		SchemaVersion: "1.0",
-		ExportedAt:    exportedAt,
-	}
-	headerJSON, err := json.Marshal(header)
-	if err != nil {
-		return nil, errors.NewInternal(err)
-	}
-	if _, err := file.Write(headerJSON); err != nil {
-		return nil, errors.NewInternal(err)
-	}
-	if _, err := file.Write([]byte("\n")); err != nil {
-		return nil, errors.NewInternal(err)
+	// Write header
+	var csvWriter *csv.Writer
+	closing := ""
+	if input.Format == ExportFormatCSV {
+		csvWriter = csv.NewWriter(file)
+		if writeHeader {
+			if err := csvWriter.Write(csvExportColumns); err != nil {
+				return nil, errors.NewInternal(err)
+			}
+		}
+	} else if writeHeader {
+		header := ExportHeader{
+			MossExport:    true,
+			SchemaVersion: "1.0",
+			ExportedAt:    exportedAt,
+			Compact:       input.Compact,
+		}
+		headerJSON, err := json.Marshal(header)
+		if err != nil {
+			return nil, errors.NewInternal(err)
+		}
+
+		// JSONL: header line, then one record per line.
+		// JSON: one document, but records are still streamed (one per line inside "capsules").
+		opening := string(headerJSON) + "\n"
+		if input.Format == ExportFormatJSON {
+			opening, closing = `{"header":`+string(headerJSON)+`,"capsules":[`, "\n]}\n"
+		}
+		if _, err := file.Write([]byte(opening)); err != nil {
+			return nil, errors.NewInternal(err)
+		}
 	}
 
 	// Stream capsules and write to file
-	rows, err := db.StreamForExport(ctx, database, input.Workspace, input.IncludeDeleted)
+	rows, err := db.StreamForExport(ctx, database, input.Workspace, ids, resumeFrom, input.IncludeDeleted, input.OrderBy == ExportOrderWorkspace)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("export")
+		}
 		return nil, err
 	}
 	defer rows.Close()
 
 	count := 0
+	lastID := ""
 	for rows.Next() {
 		select {
 		case <-ctx.Done():
@@ -125,23 +273,65 @@ func Export(ctx context.Context, database *sql.DB, cfg *config.Config, input Exp
 			return nil, errors.NewInternal(err)
 		}
 
+		if csvWriter != nil {
+			if err := csvWriter.Write(capsuleToCSVRow(c)); err != nil {
+				return nil, errors.NewInternal(err)
+			}
+			count++
+			lastID = c.ID
+			continue
+		}
+
 		record := capsule.CapsuleToExportRecord(c)
-		recordJSON, err := json.Marshal(record)
+		var recordJSON []byte
+		if input.Compact {
+			recordJSON, err = json.Marshal(capsule.CompactExportRecord{ExportRecord: record})
+		} else {
+			recordJSON, err = json.Marshal(record)
+		}
 		if err != nil {
 			return nil, errors.NewInternal(err)
 		}
 
-		if _, err := file.Write(recordJSON); err != nil {
-			return nil, errors.NewInternal(err)
-		}
-		if _, err := file.Write([]byte("\n")); err != nil {
-			return nil, errors.NewInternal(err)
+		if input.Format == ExportFormatJSON {
+			// Separator precedes each record so the array has no trailing comma
+			lead := "\n"
+			if count > 0 {
+				lead = ",\n"
+			}
+			if _, err := file.Write([]byte(lead)); err != nil {
+				return nil, errors.NewInternal(err)
+			}
+			if _, err := file.Write(recordJSON); err != nil {
+				return nil, errors.NewInternal(err)
+			}
+		} else {
+			if _, err := file.Write(recordJSON); err != nil {
+				return nil, errors.NewInternal(err)
+			}
+			if _, err := file.Write([]byte("\n")); err != nil {
+				return nil, errors.NewInternal(err)
+			}
 		}
 
 		count++
+		lastID = c.ID
 	}
 
 	if err := rows.Err(); err != nil {
+		// The driver stops iteration when the context is cancelled mid-stream
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("export")
+		}
+		return nil, errors.NewInternal(err)
+	}
+
+	if csvWriter != nil {
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return nil, errors.NewInternal(err)
+		}
+	} else if _, err := file.Write([]byte(closing)); err != nil {
 		return nil, errors.NewInternal(err)
 	}
 
@@ -156,6 +346,17 @@ func Export(ctx context.Context, database *sql.DB, cfg *config.Config, input Exp
 	}
 	file = nil
 
+	if input.Append {
+		success = true
+		return &ExportOutput{
+			Path:         exportPath,
+			Count:        count,
+			ExportedAt:   exportedAt,
+			Format:       input.Format,
+			ResumeCursor: lastID,
+		}, nil
+	}
+
 	// Check if destination is a symlink (os.Rename would follow it)
 	if info, err := os.Lstat(exportPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
 		return nil, errors.NewInternal(fmt.Errorf("export path is a symlink"))
@@ -166,7 +367,7 @@ func Export(ctx context.Context, database *sql.DB, cfg *config.Config, input Exp
 	// Note: On Windows, os.Rename fails if the destination exists. We intentionally
 	// fail safely (preserving the existing file) instead of doing a non-atomic
 	// delete+rename that could lose the original if rename fails.
-	if err := os.Rename(tempPath, exportPath); err != nil {
+	if err := os.Rename(writePath, exportPath); err != nil {
 		if runtime.GOOS == "windows" {
 			if _, statErr := os.Stat(exportPath); statErr == nil {
 				return nil, errors.NewInvalidRequest("export destination already exists; overwriting is not supported on Windows yet (choose a new path or delete the existing file)")
@@ -177,18 +378,20 @@ func Export(ctx context.Context, database *sql.DB, cfg *config.Config, input Exp
 
 	success = true
 	return &ExportOutput{
-		Path:       exportPath,
-		Count:      count,
-		ExportedAt: exportedAt,
+		Path:         exportPath,
+		Count:        count,
+		ExportedAt:   exportedAt,
+		Format:       input.Format,
+		ResumeCursor: lastID,
 	}, nil
 }
 
 // defaultExportPath generates the default export path.
-// Format: ~/.moss/exports/<workspace>-<timestamp>.jsonl or all-<timestamp>.jsonl
-func defaultExportPath(workspace *string, now time.Time) (string, error) {
-	homeDir, err := os.UserHomeDir()
+// Format: <data dir>/exports/<workspace>-<timestamp><ext> or all-<timestamp><ext>
+func defaultExportPath(cfg *config.Config, workspace *string, now time.Time, ext string) (string, error) {
+	exportsDir, err := DefaultExportsDir(cfg)
 	if err != nil {
-		return "", errors.NewInternal(fmt.Errorf("failed to get home directory: %w", err))
+		return "", err
 	}
 
 	timestamp := now.Format("2006-01-02T150405")
@@ -199,6 +402,71 @@ func defaultExportPath(workspace *string, now time.Time) (string, error) {
 		name = SanitizeForFilename(capsule.Normalize(*workspace))
 	}
 
-	filename := fmt.Sprintf("%s-%s.jsonl", name, timestamp)
-	return filepath.Join(homeDir, ".moss", "exports", filename), nil
+	filename := fmt.Sprintf("%s-%s%s", name, timestamp, ext)
+	return filepath.Join(exportsDir, filename), nil
+}
+
+// capsuleToCSVRow converts a capsule to a CSV row matching csvExportColumns.
+// Tags are semicolon-joined; timestamps are RFC 3339 UTC (deleted_at empty if active).
+// Text cells go through csvCell so spreadsheets don't evaluate them as formulas.
+func capsuleToCSVRow(c *capsule.Capsule) []string {
+	formatTime := func(unix int64) string {
+		return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+	}
+	deletedAt := ""
+	if c.DeletedAt != nil {
+		deletedAt = formatTime(*c.DeletedAt)
+	}
+	return []string{
+		csvCell(c.ID),
+		csvCell(c.WorkspaceRaw),
+		csvCell(derefString(c.NameRaw)),
+		csvCell(derefString(c.Title)),
+		csvCell(strings.Join(c.Tags, ";")),
+		csvCell(derefString(c.Phase)),
+		csvCell(derefString(c.Role)),
+		strconv.Itoa(c.CapsuleChars),
+		strconv.Itoa(c.TokensEstimate),
+		formatTime(c.CreatedAt),
+		formatTime(c.UpdatedAt),
+		deletedAt,
+	}
+}
+
+// csvCell prefixes s with a single quote if it starts with a character that
+// spreadsheet tools treat as the start of a formula (=, +, -, @, tab, CR), so
+// a title like "=HYPERLINK(...)" shows as text instead of running.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// cleanExportIDs trims ids and drops blanks and duplicates. Returns nil when
+// no ids were given; ids that are all blank are an error rather than
+// silently exporting everything.
+func cleanExportIDs(ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if len(ids) > MaxExportIDs {
+		return nil, errors.NewInvalidRequest(
+			fmt.Sprintf("too many ids: %d (max %d)", len(ids), MaxExportIDs))
+	}
+
+	cleaned := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		cleaned = append(cleaned, id)
+	}
+	if len(cleaned) == 0 {
+		return nil, errors.NewInvalidRequest("ids must contain at least one non-empty id")
+	}
+	return cleaned, nil
 }
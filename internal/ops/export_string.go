@@ -0,0 +1,173 @@
+package ops
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// MaxExportStringSize caps the encoded length of an inline export string, so a
+// large database can't produce a giant tool response. Bigger exports should use
+// a file export.
+const MaxExportStringSize = 1024 * 1024 // 1MB
+
+// ExportStringInput contains parameters for the ExportString operation.
+type ExportStringInput struct {
+	Workspace      *string // optional filter by workspace
+	IncludeDeleted bool
+}
+
+// ExportStringOutput contains the result of the ExportString operation.
+type ExportStringOutput struct {
+	Data       string `json:"data"` // base64 of the gzipped JSONL export
+	Count      int    `json:"count"`
+	Bytes      int    `json:"bytes"` // len(Data)
+	ExportedAt int64  `json:"exported_at"`
+}
+
+// ImportStringInput contains parameters for the ImportString operation.
+type ImportStringInput struct {
+	Data string     // required, as produced by ExportString
+	Mode ImportMode // default: error
+}
+
+// ExportString produces a JSONL export (same records as a jsonl file export),
+// gzips it, and base64-encodes it into a single string for clipboard transfer.
+// Fails with INVALID_REQUEST if the encoded string would exceed MaxExportStringSize.
+func ExportString(ctx context.Context, database db.Querier, input ExportStringInput) (*ExportStringOutput, error) {
+	exportedAt := time.Now().Unix()
+	// Compressed bytes that still fit once base64-encoded
+	maxCompressed := base64.StdEncoding.DecodedLen(MaxExportStringSize)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+
+	header, err := json.Marshal(ExportHeader{
+		MossExport:    true,
+		SchemaVersion: "1.0",
+		ExportedAt:    exportedAt,
+	})
+	if err != nil {
+		return nil, errors.NewInternal(err)
+	}
+	if _, err := gz.Write(append(header, '\n')); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	rows, err := db.StreamForExport(ctx, database, input.Workspace, nil, nil, input.IncludeDeleted, false)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("export")
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		select {
+		case <-ctx.Done():
+			return nil, errors.NewCancelled("export")
+		default:
+		}
+
+		c, err := db.ScanCapsuleFromRows(rows)
+		if err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		recordJSON, err := json.Marshal(capsule.CapsuleToExportRecord(c))
+		if err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		if _, err := gz.Write(append(recordJSON, '\n')); err != nil {
+			return nil, errors.NewInternal(err)
+		}
+		count++
+
+		// gzip flushes as its window fills, so this stops runaway exports early;
+		// the exact check is on the final encoded length below.
+		if buf.Len() > maxCompressed {
+			return nil, exportStringTooLarge()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("export")
+		}
+		return nil, errors.NewInternal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+	if buf.Len() > maxCompressed {
+		return nil, exportStringTooLarge()
+	}
+
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+	return &ExportStringOutput{
+		Data:       data,
+		Count:      count,
+		Bytes:      len(data),
+		ExportedAt: exportedAt,
+	}, nil
+}
+
+// ImportString imports capsules from a string produced by ExportString.
+// Collision handling matches Import.
+func ImportString(ctx context.Context, database *sql.DB, input ImportStringInput) (*ImportOutput, error) {
+	defer invalidateSearchCache()
+
+	// Clipboards and chat UIs may wrap long lines; strip whitespace before decoding
+	data := strings.Join(strings.Fields(input.Data), "")
+	if data == "" {
+		return nil, errors.NewInvalidRequest("data is required")
+	}
+	if input.Mode == "" {
+		input.Mode = ImportModeError
+	}
+	if input.Mode != ImportModeError && input.Mode != ImportModeReplace && input.Mode != ImportModeRename && input.Mode != ImportModeSkip && input.Mode != ImportModeMerge {
+		return nil, errors.NewInvalidRequest("mode must be one of: error, replace, rename, skip, merge")
+	}
+	if len(data) > MaxExportStringSize {
+		return nil, errors.NewInvalidRequest(fmt.Sprintf("data exceeds maximum size: %d bytes (max %d)", len(data), MaxExportStringSize))
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, errors.NewInvalidRequest("data is not valid base64")
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, errors.NewInvalidRequest("data is not a gzip export string")
+	}
+	defer gz.Close()
+
+	// Bound decompression like an import file, so a crafted string can't expand without limit
+	jsonl, err := io.ReadAll(io.LimitReader(gz, MaxImportFileSize+1))
+	if err != nil {
+		return nil, errors.NewInvalidRequest(fmt.Sprintf("data is not a gzip export string: %v", err))
+	}
+	if int64(len(jsonl)) > MaxImportFileSize {
+		return nil, errors.NewFileTooLarge(MaxImportFileSize, int64(len(jsonl)))
+	}
+
+	records, parseErrors := parseExportFile(bytes.NewReader(jsonl))
+	return importRecords(ctx, database, input.Mode, records, parseErrors)
+}
+
+// exportStringTooLarge reports an export that does not fit inline.
+func exportStringTooLarge() error {
+	return errors.NewInvalidRequest(fmt.Sprintf("export exceeds the %d byte inline limit; filter by workspace or use capsule_export with a file path", MaxExportStringSize))
+}
@@ -0,0 +1,116 @@
+package ops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestExportString_RoundTrip(t *testing.T) {
+	src, err := db.Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer src.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	var ids []string
+	for _, in := range []StoreInput{
+		{Workspace: "alpha", Name: stringPtr("auth"), Title: stringPtr("Auth"), CapsuleText: validCapsuleText, Tags: []string{"x"}},
+		{Workspace: "alpha", CapsuleText: validCapsuleText + "\nunnamed"},
+		{Workspace: "beta", Name: stringPtr("other"), CapsuleText: validCapsuleText},
+	} {
+		out, err := Store(ctx, src, cfg, in)
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		ids = append(ids, out.ID)
+	}
+
+	exported, err := ExportString(ctx, src, ExportStringInput{Workspace: stringPtr("alpha")})
+	if err != nil {
+		t.Fatalf("ExportString failed: %v", err)
+	}
+	if exported.Count != 2 {
+		t.Errorf("Count = %d, want 2", exported.Count)
+	}
+	if exported.Bytes != len(exported.Data) || exported.Data == "" {
+		t.Errorf("Bytes = %d, len(Data) = %d", exported.Bytes, len(exported.Data))
+	}
+
+	dst, err := db.Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer dst.Close()
+
+	imported, err := ImportString(ctx, dst, ImportStringInput{Data: exported.Data})
+	if err != nil {
+		t.Fatalf("ImportString failed: %v", err)
+	}
+	if imported.Imported != 2 || len(imported.Errors) != 0 {
+		t.Fatalf("Imported = %d, Errors = %v; want 2, none", imported.Imported, imported.Errors)
+	}
+
+	for _, id := range ids[:2] {
+		want, err := db.GetByID(ctx, src, id, false)
+		if err != nil {
+			t.Fatalf("GetByID(src) failed: %v", err)
+		}
+		got, err := db.GetByID(ctx, dst, id, false)
+		if err != nil {
+			t.Fatalf("GetByID(dst) failed: %v", err)
+		}
+		if got.CapsuleText != want.CapsuleText || got.WorkspaceRaw != want.WorkspaceRaw ||
+			derefString(got.NameRaw) != derefString(want.NameRaw) || derefString(got.Title) != derefString(want.Title) ||
+			len(got.Tags) != len(want.Tags) || got.CreatedAt != want.CreatedAt || got.UpdatedAt != want.UpdatedAt {
+			t.Errorf("capsule %s mismatch after round trip:\ngot  %+v\nwant %+v", id, got, want)
+		}
+	}
+	if _, err := db.GetByID(ctx, dst, ids[2], false); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("capsule outside the exported workspace should not be imported, got: %v", err)
+	}
+
+	// Importing again collides in the default error mode; skip mode tolerates it
+	again, err := ImportString(ctx, dst, ImportStringInput{Data: exported.Data})
+	if err != nil {
+		t.Fatalf("ImportString failed: %v", err)
+	}
+	if again.Imported != 0 || len(again.Errors) == 0 {
+		t.Errorf("re-import = %+v, want collision error", again)
+	}
+	skipped, err := ImportString(ctx, dst, ImportStringInput{Data: exported.Data, Mode: ImportModeSkip})
+	if err != nil {
+		t.Fatalf("ImportString(skip) failed: %v", err)
+	}
+	if skipped.Skipped != 2 {
+		t.Errorf("Skipped = %d, want 2", skipped.Skipped)
+	}
+}
+
+func TestImportString_InvalidData(t *testing.T) {
+	database, err := db.Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	for name, data := range map[string]string{
+		"empty":      "  \n",
+		"not base64": "!!!not-base64!!!",
+		"not gzip":   "aGVsbG8gd29ybGQ=",
+	} {
+		if _, err := ImportString(ctx, database, ImportStringInput{Data: data}); !errors.Is(err, errors.ErrInvalidRequest) {
+			t.Errorf("%s: expected INVALID_REQUEST, got: %v", name, err)
+		}
+	}
+	if _, err := ImportString(ctx, database, ImportStringInput{Data: "aGVsbG8=", Mode: "bogus"}); !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("bad mode: expected INVALID_REQUEST, got: %v", err)
+	}
+}
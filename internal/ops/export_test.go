@@ -2,10 +2,15 @@ package ops
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -14,6 +19,7 @@ import (
 	"github.com/hpungsan/moss/internal/config"
 	"github.com/hpungsan/moss/internal/db"
 	"github.com/hpungsan/moss/internal/errors"
+	"github.com/hpungsan/moss/internal/testutil"
 )
 
 // testConfigUnsafe returns a config that allows any path (for testing with temp dirs).
@@ -191,6 +197,135 @@ func TestExport_JSONLFormat(t *testing.T) {
 	}
 }
 
+func TestExport_CSVEscapesFormulas(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	c := newTestCapsuleForExport("01EXPCSVF", "@team", "Content")
+	c.NameRaw = stringPtr("-draft")
+	c.NameNorm = stringPtr("-draft")
+	c.Title = stringPtr(`=HYPERLINK("http://example.com","x")`)
+	c.Tags = []string{"+1", "ok"}
+	c.Phase = stringPtr("plan-b")
+	if err := db.Insert(context.Background(), database, c); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	exportPath := filepath.Join(tmpDir, "export.csv")
+	if _, err := Export(context.Background(), database, testConfigUnsafe(), ExportInput{Path: exportPath, Format: ExportFormatCSV}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %d, want 2", len(rows))
+	}
+
+	// Cells starting with a formula character are quoted; others are untouched
+	want := map[int]string{
+		1: "'@team",
+		2: "'-draft",
+		3: `'=HYPERLINK("http://example.com","x")`,
+		4: "'+1;ok",
+		5: "plan-b",
+	}
+	for col, w := range want {
+		if got := rows[1][col]; got != w {
+			t.Errorf("%s = %q, want %q", rows[0][col], got, w)
+		}
+	}
+}
+
+func TestExport_CSVFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	c := newTestCapsuleForExport("01EXPCSV1", "Team A", "Body, with \"quotes\"\nand newlines")
+	c.NameRaw = stringPtr("auth-plan")
+	c.NameNorm = stringPtr("auth-plan")
+	c.Title = stringPtr("Auth, sessions and tokens")
+	c.Tags = []string{"auth", "backend"}
+	c.Phase = stringPtr("design")
+	c.CreatedAt = 1700000000
+	c.UpdatedAt = 1700000060
+	other := newTestCapsuleForExport("01EXPCSV2", "other", "Other content")
+
+	for _, rec := range []*capsule.Capsule{c, other} {
+		if err := db.Insert(context.Background(), database, rec); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	// .jsonl is rejected for CSV exports
+	_, err = Export(context.Background(), database, testConfigUnsafe(), ExportInput{
+		Path:   filepath.Join(tmpDir, "export.jsonl"),
+		Format: ExportFormatCSV,
+	})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("Expected ErrInvalidRequest for .jsonl CSV path, got: %v", err)
+	}
+
+	exportPath := filepath.Join(tmpDir, "export.csv")
+	output, err := Export(context.Background(), database, testConfigUnsafe(), ExportInput{
+		Path:      exportPath,
+		Workspace: stringPtr("team a"),
+		Format:    ExportFormatCSV,
+	})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if output.Count != 1 || output.Format != ExportFormatCSV {
+		t.Errorf("output = %+v, want 1 csv record", output)
+	}
+
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !strings.Contains(string(data), `,"Auth, sessions and tokens",`) {
+		t.Errorf("title with comma should be quoted, got:\n%s", data)
+	}
+	if strings.Contains(string(data), "Body") {
+		t.Error("capsule_text must not be exported to CSV")
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %d, want 2 (header + 1 capsule)", len(rows))
+	}
+	wantHeader := "id,workspace,name,title,tags,phase,role,chars,tokens,created_at,updated_at,deleted_at"
+	if got := strings.Join(rows[0], ","); got != wantHeader {
+		t.Errorf("header = %q, want %q", got, wantHeader)
+	}
+	want := []string{
+		"01EXPCSV1", "Team A", "auth-plan", "Auth, sessions and tokens", "auth;backend", "design", "",
+		strconv.Itoa(c.CapsuleChars), strconv.Itoa(c.TokensEstimate),
+		"2023-11-14T22:13:20Z", "2023-11-14T22:14:20Z", "",
+	}
+	for i, col := range rows[0] {
+		if rows[1][i] != want[i] {
+			t.Errorf("%s = %q, want %q", col, rows[1][i], want[i])
+		}
+	}
+}
+
 func TestExport_WorkspaceFilter(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -223,6 +358,96 @@ func TestExport_WorkspaceFilter(t *testing.T) {
 	}
 }
 
+func TestExport_IDs(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	var capsules []*capsule.Capsule
+	for i, id := range []string{"01EXPID1", "01EXPID2", "01EXPID3", "01EXPID4"} {
+		c := newTestCapsuleForExport(id, "default", "Capsule "+id)
+		c.CreatedAt = int64(1000 * (i + 1))
+		capsules = append(capsules, c)
+		if err := db.Insert(context.Background(), database, c); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	// Requested out of created_at order, with a duplicate and an unknown ID
+	exportPath := filepath.Join(tmpDir, "export.jsonl")
+	output, err := Export(context.Background(), database, testConfigUnsafe(), ExportInput{
+		Path: exportPath,
+		IDs:  []string{capsules[3].ID, " " + capsules[1].ID, capsules[3].ID, "01MISSING"},
+	})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if output.Count != 2 {
+		t.Errorf("Count = %d, want 2", output.Count)
+	}
+
+	file, err := os.Open(exportPath)
+	if err != nil {
+		t.Fatalf("Failed to open export file: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // Skip header
+
+	var ids []string
+	for scanner.Scan() {
+		var record capsule.ExportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Failed to parse capsule: %v", err)
+		}
+		ids = append(ids, record.ID)
+	}
+	if len(ids) != 2 || ids[0] != "01EXPID2" || ids[1] != "01EXPID4" {
+		t.Errorf("IDs = %v, want [01EXPID2 01EXPID4] (created_at order)", ids)
+	}
+
+	// Deleted capsules still need include_deleted
+	if err := db.SoftDelete(context.Background(), database, capsules[1].ID); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+	for _, includeDeleted := range []bool{false, true} {
+		output, err := Export(context.Background(), database, testConfigUnsafe(), ExportInput{
+			Path:           filepath.Join(tmpDir, "deleted.jsonl"),
+			IDs:            []string{capsules[1].ID},
+			IncludeDeleted: includeDeleted,
+		})
+		if err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		want := 0
+		if includeDeleted {
+			want = 1
+		}
+		if output.Count != want {
+			t.Errorf("include_deleted=%v: Count = %d, want %d", includeDeleted, output.Count, want)
+		}
+	}
+
+	// Blank-only and oversized lists are rejected
+	tooMany := make([]string, MaxExportIDs+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("01ID%04d", i)
+	}
+	for _, ids := range [][]string{{" ", ""}, tooMany} {
+		_, err := Export(context.Background(), database, testConfigUnsafe(), ExportInput{
+			Path: filepath.Join(tmpDir, "bad.jsonl"),
+			IDs:  ids,
+		})
+		if !errors.Is(err, errors.ErrInvalidRequest) {
+			t.Errorf("len(ids)=%d: err = %v, want INVALID_REQUEST", len(ids), err)
+		}
+	}
+}
+
 func TestExport_IncludeDeleted(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -330,6 +555,36 @@ func TestExport_FilePermissions(t *testing.T) {
 	}
 }
 
+func TestExport_ConfiguredFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permission bits don't apply on Windows")
+	}
+
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	// 0664 has group write, which the usual 022 umask would strip
+	cfg := testConfigUnsafe()
+	cfg.ExportFileMode = 0o664
+
+	exportPath := filepath.Join(tmpDir, "export.jsonl")
+	if _, err := Export(context.Background(), database, cfg, ExportInput{Path: exportPath}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	info, err := os.Stat(exportPath)
+	if err != nil {
+		t.Fatalf("Failed to stat export file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o664 {
+		t.Errorf("file permissions = %o, want 664", perm)
+	}
+}
+
 func TestExport_DefaultPath(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -465,6 +720,106 @@ func TestExport_PreservesOrder(t *testing.T) {
 	}
 }
 
+func TestExport_OrderByWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	named := func(id, ws, name string, createdAt int64) *capsule.Capsule {
+		c := newTestCapsuleForExport(id, ws, "text "+id)
+		c.CreatedAt = createdAt
+		if name != "" {
+			norm := capsule.Normalize(name)
+			c.NameRaw = &name
+			c.NameNorm = &norm
+		}
+		return c
+	}
+
+	// created_at order deliberately disagrees with workspace/name order
+	for _, c := range []*capsule.Capsule{
+		named("01ORD001", "beta", "zeta", 1000),
+		named("01ORD002", "alpha", "", 2000),
+		named("01ORD003", "Beta", "alpha", 3000),
+		named("01ORD004", "alpha", "mid", 4000),
+		named("01ORD005", "alpha", "", 5000),
+		named("01ORD006", "alpha", "Early", 6000),
+	} {
+		if err := db.Insert(context.Background(), database, c); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	exportPath := filepath.Join(tmpDir, "export.jsonl")
+	if _, err := Export(context.Background(), database, testConfigUnsafe(), ExportInput{
+		Path:    exportPath,
+		OrderBy: ExportOrderWorkspace,
+	}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("Failed to read export file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")[1:] // skip header
+
+	var got []string
+	for _, line := range lines {
+		var record capsule.ExportRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to parse capsule: %v", err)
+		}
+		got = append(got, record.ID)
+	}
+
+	// alpha: early, mid, then unnamed by id; beta: alpha, zeta
+	want := []string{"01ORD006", "01ORD004", "01ORD002", "01ORD005", "01ORD003", "01ORD001"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("IDs = %v, want %v", got, want)
+	}
+
+	_, err = Export(context.Background(), database, testConfigUnsafe(), ExportInput{Path: exportPath, OrderBy: "name"})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("Expected ErrInvalidRequest for unknown order_by, got: %v", err)
+	}
+}
+
+func TestExport_CancelledMidStream(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	for i := 0; i < 200; i++ {
+		c := newTestCapsuleForExport(fmt.Sprintf("01CANCEL%04d", i), "default", "Record")
+		if err := db.Insert(context.Background(), database, c); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	exportDir := filepath.Join(tmpDir, "exports")
+	exportPath := filepath.Join(exportDir, "export.jsonl")
+	_, err = Export(testutil.CancelAfterDoneCalls(context.Background(), 50), database, testConfigUnsafe(), ExportInput{Path: exportPath})
+	if !errors.Is(err, errors.ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got: %v", err)
+	}
+
+	// The partially written temp file is removed and nothing lands at the destination
+	entries, err := os.ReadDir(exportDir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	for _, e := range entries {
+		t.Errorf("unexpected file left behind: %s", e.Name())
+	}
+}
+
 func TestExport_PathTraversalRejected(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -512,6 +867,23 @@ func TestExport_RequiresJSONLExtension(t *testing.T) {
 	}
 }
 
+func TestExport_InvalidFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	_, err = Export(context.Background(), database, testConfigUnsafe(), ExportInput{
+		Path:   filepath.Join(tmpDir, "export.jsonl"),
+		Format: "xml",
+	})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("Expected ErrInvalidRequest for unknown format, got: %v", err)
+	}
+}
+
 func TestExport_WorkspaceInjectionBlocked(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -600,3 +972,308 @@ func TestExport_SymlinkFileRejected(t *testing.T) {
 		t.Error("Expected error when exporting to symlink, got nil")
 	}
 }
+
+func TestExport_Compact(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	c := newTestCapsuleForExport("01EXPCMP1", "My Project", "## Objective\nCompact export round trip")
+	c.NameRaw = stringPtr("Auth Flow")
+	c.NameNorm = stringPtr("auth flow")
+	c.Tags = []string{"tag1"}
+	if err := db.Insert(context.Background(), database, c); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	fullPath := filepath.Join(tmpDir, "full.jsonl")
+	if _, err := Export(context.Background(), database, testConfigUnsafe(), ExportInput{Path: fullPath}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	for _, format := range []ExportFormat{ExportFormatJSONL, ExportFormatJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			compactPath := filepath.Join(tmpDir, "compact."+string(format))
+			if _, err := Export(context.Background(), database, testConfigUnsafe(), ExportInput{
+				Path:    compactPath,
+				Format:  format,
+				Compact: true,
+			}); err != nil {
+				t.Fatalf("Export failed: %v", err)
+			}
+
+			full, _ := os.ReadFile(fullPath)
+			compact, _ := os.ReadFile(compactPath)
+			if format == ExportFormatJSONL && len(compact) >= len(full) {
+				t.Errorf("compact size = %d, want < %d", len(compact), len(full))
+			}
+			for _, key := range []string{"workspace_norm", "name_norm", "capsule_chars", "tokens_estimate"} {
+				if bytes.Contains(compact, []byte(`"`+key+`"`)) {
+					t.Errorf("compact export should omit %s", key)
+				}
+			}
+			if !bytes.Contains(compact, []byte(`"compact":true`)) {
+				t.Error("compact export header should set compact")
+			}
+
+			// Import into a fresh DB and verify derived fields are recomputed
+			importDB, err := db.Init(t.TempDir())
+			if err != nil {
+				t.Fatalf("db.Init failed: %v", err)
+			}
+			defer importDB.Close()
+
+			if _, err := Import(context.Background(), importDB, testConfigUnsafe(), ImportInput{Path: compactPath}); err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			got, err := db.GetByID(context.Background(), importDB, c.ID, false)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if got.WorkspaceNorm != c.WorkspaceNorm {
+				t.Errorf("WorkspaceNorm = %q, want %q", got.WorkspaceNorm, c.WorkspaceNorm)
+			}
+			if got.NameNorm == nil || *got.NameNorm != *c.NameNorm {
+				t.Errorf("NameNorm = %v, want %q", got.NameNorm, *c.NameNorm)
+			}
+			if got.CapsuleChars != c.CapsuleChars {
+				t.Errorf("CapsuleChars = %d, want %d", got.CapsuleChars, c.CapsuleChars)
+			}
+			if got.TokensEstimate != c.TokensEstimate {
+				t.Errorf("TokensEstimate = %d, want %d", got.TokensEstimate, c.TokensEstimate)
+			}
+			if got.CapsuleText != c.CapsuleText || len(got.Tags) != 1 {
+				t.Errorf("round trip changed capsule: %+v", got)
+			}
+		})
+	}
+}
+
+func TestExport_CompactRejectsCSV(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	_, err = Export(context.Background(), database, testConfigUnsafe(), ExportInput{
+		Path:    filepath.Join(tmpDir, "export.csv"),
+		Format:  ExportFormatCSV,
+		Compact: true,
+	})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("Expected ErrInvalidRequest for compact CSV, got: %v", err)
+	}
+}
+
+func TestExport_ResumeAppend(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := testConfigUnsafe()
+	ctx := context.Background()
+
+	insert := func(id string, createdAt int64) {
+		t.Helper()
+		c := newTestCapsuleForExport(id, "default", "Content "+id)
+		c.CreatedAt = createdAt
+		if err := db.Insert(ctx, database, c); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+	insert("01RES001", 1000)
+	insert("01RES002", 2000)
+	insert("01RES003", 3000)
+
+	// First pass: everything so far, written in place
+	resumedPath := filepath.Join(tmpDir, "resumed.jsonl")
+	first, err := Export(ctx, database, cfg, ExportInput{Path: resumedPath, Append: true})
+	if err != nil {
+		t.Fatalf("first pass failed: %v", err)
+	}
+	if first.Count != 3 || first.ResumeCursor != "01RES003" {
+		t.Fatalf("first pass = %d records, cursor %q; want 3, 01RES003", first.Count, first.ResumeCursor)
+	}
+
+	// More capsules arrive. 01RES000 sorts before the cursor by ID but was
+	// created later, so an id-only cursor would skip it.
+	insert("01RES004", 4000)
+	insert("01RES000", 5000)
+
+	second, err := Export(ctx, database, cfg, ExportInput{Path: resumedPath, Append: true, ResumeFrom: &first.ResumeCursor})
+	if err != nil {
+		t.Fatalf("second pass failed: %v", err)
+	}
+	if second.Count != 2 || second.ResumeCursor != "01RES000" {
+		t.Errorf("second pass = %d records, cursor %q; want 2, 01RES000", second.Count, second.ResumeCursor)
+	}
+
+	fullPath := filepath.Join(tmpDir, "full.jsonl")
+	if _, err := Export(ctx, database, cfg, ExportInput{Path: fullPath}); err != nil {
+		t.Fatalf("single pass failed: %v", err)
+	}
+
+	// Records (everything after the header line) match the single-pass export
+	records := func(path string) []string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if !strings.Contains(lines[0], `"_moss_export":true`) {
+			t.Fatalf("%s: first line is not a header: %s", path, lines[0])
+		}
+		for _, line := range lines[1:] {
+			if strings.Contains(line, `"_moss_export"`) {
+				t.Fatalf("%s: header repeated on append", path)
+			}
+		}
+		return lines[1:]
+	}
+	resumed, full := records(resumedPath), records(fullPath)
+	if strings.Join(resumed, "\n") != strings.Join(full, "\n") {
+		t.Errorf("resumed export differs from single pass:\n%s\n---\n%s", strings.Join(resumed, "\n"), strings.Join(full, "\n"))
+	}
+
+	// The resumed file imports like any other export
+	importDB, err := db.Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer importDB.Close()
+	imported, err := Import(ctx, importDB, cfg, ImportInput{Path: resumedPath})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if imported.Imported != 5 {
+		t.Errorf("Imported = %d, want 5", imported.Imported)
+	}
+}
+
+func TestExport_ResumeValidation(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := testConfigUnsafe()
+	path := filepath.Join(tmpDir, "out.jsonl")
+	missing := "01MISSING"
+	tests := []struct {
+		name  string
+		input ExportInput
+	}{
+		{"append without path", ExportInput{Append: true}},
+		{"append json", ExportInput{Path: path, Append: true, Format: ExportFormatJSON}},
+		{"resume with workspace order", ExportInput{Path: path, ResumeFrom: &missing, OrderBy: ExportOrderWorkspace}},
+		{"unknown cursor", ExportInput{Path: path, ResumeFrom: &missing}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Export(context.Background(), database, cfg, tt.input)
+			if !errors.Is(err, errors.ErrInvalidRequest) {
+				t.Errorf("err = %v, want INVALID_REQUEST", err)
+			}
+		})
+	}
+}
+
+func TestExport_DefaultPathFollowsDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+	database, err := db.Init(dataDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.DataDir = dataDir
+	ctx := context.Background()
+
+	if err := db.Insert(ctx, database, newTestCapsuleForExport("01DATADIR01", "default", "Content")); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	// No path: the export lands in <data dir>/exports, which is also the
+	// directory import accepts without allowed_paths
+	out, err := Export(ctx, database, cfg, ExportInput{})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if got := filepath.Dir(out.Path); got != filepath.Join(dataDir, "exports") {
+		t.Errorf("export dir = %s, want %s", got, filepath.Join(dataDir, "exports"))
+	}
+	if err := ValidatePath(out.Path, PathCheckRead, cfg); err != nil {
+		t.Errorf("import validation rejected the default export: %v", err)
+	}
+
+	// The home-directory default is no longer implied
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory: %v", err)
+	}
+	homeExport := filepath.Join(home, ".moss", "exports", "backup.jsonl")
+	if err := ValidatePath(homeExport, PathCheckWrite, cfg); err == nil {
+		t.Error("~/.moss/exports should not be allowed when the data dir is elsewhere")
+	}
+}
+
+func TestExport_JSONFormatUsesJSONExtension(t *testing.T) {
+	dataDir := t.TempDir()
+	database, err := db.Init(dataDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.DataDir = dataDir
+	ctx := context.Background()
+
+	if err := db.Insert(ctx, database, newTestCapsuleForExport("01JSONEXT01", "default", "Content")); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	// Default path gets .json
+	out, err := Export(ctx, database, cfg, ExportInput{Format: ExportFormatJSON})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if ext := filepath.Ext(out.Path); ext != ".json" {
+		t.Errorf("default json export extension = %q, want .json", ext)
+	}
+
+	// A .jsonl path no longer fits a json export
+	_, err = Export(ctx, database, cfg, ExportInput{
+		Path:   filepath.Join(dataDir, "exports", "backup.jsonl"),
+		Format: ExportFormatJSON,
+	})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("json export to .jsonl path: expected ErrInvalidRequest, got %v", err)
+	}
+
+	// The .json file imports back
+	importDB, err := db.Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer importDB.Close()
+	imp, err := Import(ctx, importDB, cfg, ImportInput{Path: out.Path})
+	if err != nil {
+		t.Fatalf("Import of .json export failed: %v", err)
+	}
+	if imp.Imported != 1 {
+		t.Errorf("Imported = %d, want 1", imp.Imported)
+	}
+}
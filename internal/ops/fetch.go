@@ -2,10 +2,15 @@ package ops
 
 import (
 	"context"
-	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
 )
 
 // FetchInput contains parameters for the Fetch operation.
@@ -14,45 +19,141 @@ type FetchInput struct {
 	Workspace      string
 	Name           string
 	IncludeDeleted bool
-	IncludeText    *bool // default: true (nil means default)
+	IncludeText    *bool    // default: true (nil means default)
+	WithLinks      bool     // include summaries of linked capsules
+	WithBundles    bool     // include summaries of stored bundles composed from this capsule
+	Prefix         bool     // treat ID as a prefix (min 4 chars); must match exactly one capsule
+	KnownSHA       *string  // client's cached content_sha256; if it matches, text is omitted and Unchanged is set
+	Fields         []string // JSON field names to return (id is always included); empty returns all
+
+	// FallbackWorkspaces are tried in order when a name-addressed capsule isn't
+	// in Workspace (e.g. a shared "global" workspace). Not allowed with ID.
+	FallbackWorkspaces []string
 }
 
+// minIDPrefixLen is the shortest ID prefix accepted by prefix fetch.
+// Shorter prefixes share the ULID timestamp and would almost always be ambiguous.
+const minIDPrefixLen = 4
+
 // FetchOutput contains the result of the Fetch operation.
 type FetchOutput struct {
-	ID             string   `json:"id"`
-	Workspace      string   `json:"workspace"`
-	WorkspaceNorm  string   `json:"workspace_norm"`
-	Name           *string  `json:"name,omitempty"`
-	NameNorm       *string  `json:"name_norm,omitempty"`
-	Title          *string  `json:"title,omitempty"`
-	CapsuleText    string   `json:"capsule_text,omitempty"`
-	CapsuleChars   int      `json:"capsule_chars"`
-	TokensEstimate int      `json:"tokens_estimate"`
-	Tags           []string `json:"tags,omitempty"`
-	Source         *string  `json:"source,omitempty"`
-	RunID          *string  `json:"run_id,omitempty"`
-	Phase          *string  `json:"phase,omitempty"`
-	Role           *string  `json:"role,omitempty"`
-	CreatedAt      int64    `json:"created_at"`
-	UpdatedAt      int64    `json:"updated_at"`
-	DeletedAt      *int64   `json:"deleted_at,omitempty"`
-	FetchKey       FetchKey `json:"fetch_key"`
+	ID             string        `json:"id"`
+	Workspace      string        `json:"workspace"`
+	WorkspaceNorm  string        `json:"workspace_norm"`
+	Name           *string       `json:"name,omitempty"`
+	NameNorm       *string       `json:"name_norm,omitempty"`
+	Title          *string       `json:"title,omitempty"`
+	CapsuleText    string        `json:"capsule_text,omitempty"`
+	CapsuleChars   int           `json:"capsule_chars"`
+	TokensEstimate int           `json:"tokens_estimate"`
+	ContentSHA256  string        `json:"content_sha256"`
+	LineCount      int           `json:"line_count"`
+	WordCount      int           `json:"word_count"`
+	ReadingMinutes int           `json:"reading_minutes"` // capsule.ReadingTime estimate
+	SectionCount   int           `json:"section_count"`
+	Unchanged      bool          `json:"unchanged,omitempty"` // known_sha matched; capsule_text omitted
+	Tags           []string      `json:"tags,omitempty"`
+	Source         *string       `json:"source,omitempty"`
+	RunID          *string       `json:"run_id,omitempty"`
+	Phase          *string       `json:"phase,omitempty"`
+	Role           *string       `json:"role,omitempty"`
+	SourceType     *string       `json:"source_type,omitempty"`
+	SourceRef      *string       `json:"source_ref,omitempty"`
+	Note           *string       `json:"note,omitempty"`
+	CreatedAt      int64         `json:"created_at"`
+	IDCreatedAt    *int64        `json:"id_created_at,omitempty"` // time embedded in the ULID id; nil for non-ULID ids
+	UpdatedAt      int64         `json:"updated_at"`
+	DeletedAt      *int64        `json:"deleted_at,omitempty"`
+	ExpiresAt      *int64        `json:"expires_at,omitempty"`
+	FetchKey       FetchKey      `json:"fetch_key"`
+	Links          *CapsuleLinks `json:"links,omitempty"`   // only if with_links
+	Bundles        []SummaryItem `json:"bundles,omitempty"` // only if with_bundles; omitted when none
+
+	// MatchedWorkspace is the normalized workspace the name was found in; set
+	// only when FallbackWorkspaces were given.
+	MatchedWorkspace string `json:"matched_workspace,omitempty"`
+
+	fields []string // projection applied by MarshalJSON; nil means all fields
+}
+
+// fetchFields are the FetchOutput JSON field names a Fields projection may select.
+var fetchFields = map[string]bool{
+	"id": true, "workspace": true, "workspace_norm": true, "name": true, "name_norm": true,
+	"title": true, "capsule_text": true, "capsule_chars": true, "tokens_estimate": true,
+	"content_sha256": true, "line_count": true, "word_count": true, "reading_minutes": true, "section_count": true,
+	"unchanged": true, "tags": true, "source": true, "run_id": true, "phase": true, "role": true,
+	"source_type": true, "source_ref": true, "note": true, "created_at": true, "id_created_at": true, "updated_at": true,
+	"deleted_at": true, "expires_at": true, "fetch_key": true, "links": true, "bundles": true, "matched_workspace": true,
+}
+
+// MarshalJSON encodes only the projected fields when FetchInput.Fields was set.
+// Projected fields that are empty and omitempty stay omitted.
+func (o FetchOutput) MarshalJSON() ([]byte, error) {
+	type plain FetchOutput
+	data, err := json.Marshal(plain(o))
+	if err != nil || o.fields == nil {
+		return data, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	projected := make(map[string]json.RawMessage, len(o.fields))
+	for _, f := range o.fields {
+		if v, ok := all[f]; ok {
+			projected[f] = v
+		}
+	}
+	return json.Marshal(projected)
+}
+
+// cleanFetchFields validates a projection and returns it deduped with id first.
+// Returns nil when no fields are given.
+func cleanFetchFields(fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	result := []string{"id"}
+	for _, f := range fields {
+		f = strings.TrimSpace(f)
+		if !fetchFields[f] {
+			return nil, errors.NewInvalidRequest(fmt.Sprintf("unknown field %q; valid fields: %s",
+				f, strings.Join(slices.Sorted(maps.Keys(fetchFields)), ", ")))
+		}
+		if !slices.Contains(result, f) {
+			result = append(result, f)
+		}
+	}
+	return result, nil
 }
 
 // Fetch retrieves a capsule by ID or name.
-func Fetch(ctx context.Context, database *sql.DB, input FetchInput) (*FetchOutput, error) {
+func Fetch(ctx context.Context, database db.Querier, input FetchInput) (*FetchOutput, error) {
 	// Validate address
 	addr, err := ValidateAddress(input.ID, input.Workspace, input.Name)
 	if err != nil {
 		return nil, err
 	}
+	fields, err := cleanFetchFields(input.Fields)
+	if err != nil {
+		return nil, err
+	}
+	if addr.ByID && len(input.FallbackWorkspaces) > 0 {
+		return nil, errors.NewInvalidRequest("fallback_workspaces only applies to name-addressed fetches")
+	}
 
 	// Fetch capsule
 	var c *capsule.Capsule
-	if addr.ByID {
+	if addr.ByID && input.Prefix {
+		if len(addr.ID) < minIDPrefixLen {
+			return nil, errors.NewInvalidRequest(fmt.Sprintf("id prefix must be at least %d characters", minIDPrefixLen))
+		}
+		c, err = db.GetByIDPrefix(ctx, database, addr.ID, input.IncludeDeleted)
+	} else if addr.ByID {
 		c, err = db.GetByID(ctx, database, addr.ID, input.IncludeDeleted)
 	} else {
-		c, err = db.GetByName(ctx, database, addr.Workspace, addr.Name, input.IncludeDeleted)
+		c, err = getByNameWithFallback(ctx, database, addr, input.FallbackWorkspaces, input.IncludeDeleted)
 	}
 	if err != nil {
 		return nil, err
@@ -80,15 +181,42 @@ func Fetch(ctx context.Context, database *sql.DB, input FetchInput) (*FetchOutpu
 		Title:          c.Title,
 		CapsuleChars:   c.CapsuleChars,
 		TokensEstimate: c.TokensEstimate,
+		ContentSHA256:  c.ContentSHA256,
+		LineCount:      c.LineCount,
+		WordCount:      c.WordCount,
+		ReadingMinutes: capsule.ReadingTimeFromWords(c.WordCount),
+		SectionCount:   c.SectionCount,
 		Tags:           c.Tags,
 		Source:         c.Source,
 		RunID:          c.RunID,
 		Phase:          c.Phase,
 		Role:           c.Role,
+		SourceType:     c.SourceType,
+		SourceRef:      c.SourceRef,
+		Note:           c.Note,
 		CreatedAt:      c.CreatedAt,
 		UpdatedAt:      c.UpdatedAt,
 		DeletedAt:      c.DeletedAt,
+		ExpiresAt:      c.ExpiresAt,
 		FetchKey:       BuildFetchKey(c.WorkspaceRaw, name, c.ID),
+		fields:         fields,
+	}
+	if ts, err := capsule.TimestampFromID(c.ID); err == nil {
+		output.IDCreatedAt = &ts
+	}
+	if len(input.FallbackWorkspaces) > 0 {
+		output.MatchedWorkspace = c.WorkspaceNorm
+	}
+
+	// A projection without capsule_text never needs the text
+	if fields != nil && !slices.Contains(fields, "capsule_text") {
+		includeText = false
+	}
+
+	// A matching known_sha means the client's cached text is current
+	if input.KnownSHA != nil && strings.EqualFold(strings.TrimSpace(*input.KnownSHA), c.ContentSHA256) {
+		output.Unchanged = true
+		includeText = false
 	}
 
 	// Only include text if requested (omitempty handles the rest)
@@ -96,5 +224,50 @@ func Fetch(ctx context.Context, database *sql.DB, input FetchInput) (*FetchOutpu
 		output.CapsuleText = c.CapsuleText
 	}
 
+	if input.WithLinks {
+		links, err := loadCapsuleLinks(ctx, database, c.ID, input.IncludeDeleted)
+		if err != nil {
+			return nil, err
+		}
+		output.Links = links
+	}
+
+	if input.WithBundles {
+		bundles, err := loadBundles(ctx, database, c.ID, input.IncludeDeleted)
+		if err != nil {
+			return nil, err
+		}
+		output.Bundles = bundles
+	}
+
 	return output, nil
 }
+
+// getByNameWithFallback looks the name up in addr.Workspace, then in each
+// fallback workspace in order, returning the first hit. If none has it, the
+// primary workspace's NOT_FOUND is returned.
+func getByNameWithFallback(ctx context.Context, database db.Querier, addr *ParsedAddress, fallbacks []string, includeDeleted bool) (*capsule.Capsule, error) {
+	c, err := db.GetByName(ctx, database, addr.Workspace, addr.Name, includeDeleted)
+	if err == nil || !errors.Is(err, errors.ErrNotFound) {
+		return c, err
+	}
+	notFound := err
+
+	tried := map[string]bool{addr.Workspace: true}
+	for _, ws := range fallbacks {
+		ws = capsule.Normalize(ws)
+		if ws == "" || tried[ws] {
+			continue
+		}
+		tried[ws] = true
+
+		c, err := db.GetByName(ctx, database, ws, addr.Name, includeDeleted)
+		if err == nil {
+			return c, nil
+		}
+		if !errors.Is(err, errors.ErrNotFound) {
+			return nil, err
+		}
+	}
+	return nil, notFound
+}
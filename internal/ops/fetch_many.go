@@ -42,14 +42,22 @@ type FetchManyItem struct {
 	CapsuleText    string   `json:"capsule_text,omitempty"` // omitempty - only when include_text=true
 	CapsuleChars   int      `json:"capsule_chars"`
 	TokensEstimate int      `json:"tokens_estimate"`
+	ContentSHA256  string   `json:"content_sha256"`
+	LineCount      int      `json:"line_count"`
+	WordCount      int      `json:"word_count"`
+	SectionCount   int      `json:"section_count"`
 	Tags           []string `json:"tags,omitempty"`
 	Source         *string  `json:"source,omitempty"`
 	RunID          *string  `json:"run_id,omitempty"`
 	Phase          *string  `json:"phase,omitempty"`
 	Role           *string  `json:"role,omitempty"`
+	SourceType     *string  `json:"source_type,omitempty"`
+	SourceRef      *string  `json:"source_ref,omitempty"`
+	Note           *string  `json:"note,omitempty"`
 	CreatedAt      int64    `json:"created_at"`
 	UpdatedAt      int64    `json:"updated_at"`
 	DeletedAt      *int64   `json:"deleted_at,omitempty"`
+	ExpiresAt      *int64   `json:"expires_at,omitempty"`
 	FetchKey       FetchKey `json:"fetch_key"`
 }
 
@@ -180,14 +188,22 @@ func capsuleToItem(c *capsule.Capsule, includeText bool) FetchManyItem {
 		CapsuleText:    text,
 		CapsuleChars:   c.CapsuleChars,
 		TokensEstimate: c.TokensEstimate,
+		ContentSHA256:  c.ContentSHA256,
+		LineCount:      c.LineCount,
+		WordCount:      c.WordCount,
+		SectionCount:   c.SectionCount,
 		Tags:           c.Tags,
 		Source:         c.Source,
 		RunID:          c.RunID,
 		Phase:          c.Phase,
 		Role:           c.Role,
+		SourceType:     c.SourceType,
+		SourceRef:      c.SourceRef,
+		Note:           c.Note,
 		CreatedAt:      c.CreatedAt,
 		UpdatedAt:      c.UpdatedAt,
 		DeletedAt:      c.DeletedAt,
+		ExpiresAt:      c.ExpiresAt,
 		FetchKey:       BuildFetchKey(c.WorkspaceRaw, name, c.ID),
 	}
 }
@@ -0,0 +1,38 @@
+package ops
+
+import (
+	"context"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+)
+
+// FetchRecordInput contains parameters for the FetchRecord operation.
+type FetchRecordInput struct {
+	ID             string
+	Workspace      string
+	Name           string
+	IncludeDeleted bool
+}
+
+// FetchRecord retrieves a capsule as its canonical ExportRecord, the same
+// struct export writes per line. Marshaling the result with encoding/json
+// reproduces the capsule's export line byte for byte (for hashing/signing).
+func FetchRecord(ctx context.Context, database db.Querier, input FetchRecordInput) (*capsule.ExportRecord, error) {
+	addr, err := ValidateAddress(input.ID, input.Workspace, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var c *capsule.Capsule
+	if addr.ByID {
+		c, err = db.GetByID(ctx, database, addr.ID, input.IncludeDeleted)
+	} else {
+		c, err = db.GetByName(ctx, database, addr.Workspace, addr.Name, input.IncludeDeleted)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return capsule.CapsuleToExportRecord(c), nil
+}
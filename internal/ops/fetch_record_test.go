@@ -0,0 +1,99 @@
+package ops
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestFetchRecord_MatchesExportLine(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	stored, err := Store(ctx, database, cfg, StoreInput{
+		Workspace:   "Records",
+		Name:        stringPtr("Signed Plan"),
+		CapsuleText: validCapsuleText + "\nUnicode: café — <tag> & \"quotes\"",
+		Tags:        []string{"b", "a"},
+		RunID:       stringPtr("run-1"),
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	exportPath := filepath.Join(tmpDir, "export.jsonl")
+	if _, err := Export(ctx, database, testConfigUnsafe(), ExportInput{Path: exportPath}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("Failed to read export file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("export lines = %d, want header + 1 record", len(lines))
+	}
+
+	for _, input := range []FetchRecordInput{
+		{ID: stored.ID},
+		{Workspace: "records", Name: "signed plan"},
+	} {
+		record, err := FetchRecord(ctx, database, input)
+		if err != nil {
+			t.Fatalf("FetchRecord(%+v) failed: %v", input, err)
+		}
+		got, err := json.Marshal(record)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if string(got) != lines[1] {
+			t.Errorf("FetchRecord(%+v) =\n%s\nwant export line\n%s", input, got, lines[1])
+		}
+	}
+}
+
+func TestFetchRecord_Deleted(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	stored, err := Store(ctx, database, cfg, StoreInput{Workspace: "default", CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := Delete(ctx, database, DeleteInput{ID: stored.ID}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if _, err := FetchRecord(ctx, database, FetchRecordInput{ID: stored.ID}); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	record, err := FetchRecord(ctx, database, FetchRecordInput{ID: stored.ID, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("FetchRecord with include_deleted failed: %v", err)
+	}
+	if record.DeletedAt == nil {
+		t.Error("DeletedAt should be set")
+	}
+}
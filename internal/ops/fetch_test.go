@@ -2,8 +2,13 @@ package ops
 
 import (
 	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/config"
 	"github.com/hpungsan/moss/internal/db"
 	"github.com/hpungsan/moss/internal/errors"
@@ -51,6 +56,48 @@ func TestFetch_ByID(t *testing.T) {
 	}
 }
 
+func TestFetch_IDCreatedAt(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+	ctx := context.Background()
+
+	storeOutput, err := Store(ctx, database, config.DefaultConfig(), StoreInput{
+		Workspace:   "default",
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := Fetch(ctx, database, FetchInput{ID: storeOutput.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if output.IDCreatedAt == nil {
+		t.Fatal("IDCreatedAt should be set for a ULID id")
+	}
+	// The ID and created_at are stamped separately, possibly across a second boundary
+	if diff := output.CreatedAt - *output.IDCreatedAt; diff < 0 || diff > 1 {
+		t.Errorf("IDCreatedAt = %d, CreatedAt = %d", *output.IDCreatedAt, output.CreatedAt)
+	}
+
+	// Imported IDs needn't be ULIDs
+	if err := db.Insert(ctx, database, newTestCapsuleForExport("legacy-1", "default", "Legacy content")); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	output, err = Fetch(ctx, database, FetchInput{ID: "legacy-1"})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if output.IDCreatedAt != nil {
+		t.Errorf("IDCreatedAt = %d, want nil for a non-ULID id", *output.IDCreatedAt)
+	}
+}
+
 func TestFetch_ByName(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -90,6 +137,52 @@ func TestFetch_ByName(t *testing.T) {
 	}
 }
 
+func TestFetch_ByName_NonASCIICaseVariants(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	stored, err := Store(ctx, database, cfg, StoreInput{
+		Workspace:   "Großprojekt",
+		Name:        stringPtr("Straße"),
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	for _, addr := range [][2]string{
+		{"Großprojekt", "Straße"},
+		{"GROSSPROJEKT", "STRASSE"},
+		{"grossprojekt", "strasse"},
+	} {
+		output, err := Fetch(ctx, database, FetchInput{Workspace: addr[0], Name: addr[1]})
+		if err != nil {
+			t.Errorf("Fetch(%s/%s) failed: %v", addr[0], addr[1], err)
+			continue
+		}
+		if output.ID != stored.ID {
+			t.Errorf("Fetch(%s/%s) = %s, want %s", addr[0], addr[1], output.ID, stored.ID)
+		}
+	}
+
+	// A fold-equivalent name collides with the stored one
+	_, err = Store(ctx, database, cfg, StoreInput{
+		Workspace:   "grossprojekt",
+		Name:        stringPtr("STRASSE"),
+		CapsuleText: validCapsuleText,
+	})
+	if !errors.Is(err, errors.ErrNameAlreadyExists) {
+		t.Errorf("expected NAME_ALREADY_EXISTS for fold-equivalent name, got: %v", err)
+	}
+}
+
 func TestFetch_ByName_DefaultWorkspace(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -330,3 +423,280 @@ func TestFetch_DefaultsToIncludeText(t *testing.T) {
 		t.Error("CapsuleText should not be empty (default include_text=true)")
 	}
 }
+
+func TestFetch_IDPrefix(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	for _, id := range []string{"01HXAAAA0001", "01HXAAAA0002", "01HXBBBB0003"} {
+		c := newTestCapsuleForPurge(id, "default", "Prefix "+id)
+		if err := db.Insert(context.Background(), database, c); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	// Unique prefix (case-insensitive)
+	output, err := Fetch(context.Background(), database, FetchInput{ID: "01hxbb", Prefix: true})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if output.ID != "01HXBBBB0003" {
+		t.Errorf("ID = %q, want 01HXBBBB0003", output.ID)
+	}
+
+	// Ambiguous prefix
+	_, err = Fetch(context.Background(), database, FetchInput{ID: "01HXAAAA", Prefix: true})
+	if !errors.Is(err, errors.ErrAmbiguousAddressing) {
+		t.Errorf("Fetch should return ErrAmbiguousAddressing for ambiguous prefix, got: %v", err)
+	}
+
+	// No match
+	_, err = Fetch(context.Background(), database, FetchInput{ID: "01ZZ", Prefix: true})
+	if !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("Fetch should return ErrNotFound for unmatched prefix, got: %v", err)
+	}
+
+	// Too short
+	_, err = Fetch(context.Background(), database, FetchInput{ID: "01H", Prefix: true})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("Fetch should return ErrInvalidRequest for short prefix, got: %v", err)
+	}
+
+	// Soft-deleted capsules drop out of prefix matching, making the other unique
+	if err := db.SoftDelete(context.Background(), database, "01HXAAAA0001"); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+	output, err = Fetch(context.Background(), database, FetchInput{ID: "01HXAAAA", Prefix: true})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if output.ID != "01HXAAAA0002" {
+		t.Errorf("ID = %q, want 01HXAAAA0002", output.ID)
+	}
+
+	// Without prefix mode, a partial ID is an exact lookup
+	_, err = Fetch(context.Background(), database, FetchInput{ID: "01HXBB"})
+	if !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("Fetch without prefix should return ErrNotFound, got: %v", err)
+	}
+}
+
+func TestFetch_KnownSHA(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	storeOutput, err := Store(context.Background(), database, cfg, StoreInput{
+		Name:        stringPtr("cached"),
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	first, err := Fetch(context.Background(), database, FetchInput{ID: storeOutput.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if first.ContentSHA256 != capsule.ContentSHA256(validCapsuleText) {
+		t.Fatalf("ContentSHA256 = %q, want hash of capsule_text", first.ContentSHA256)
+	}
+	if first.Unchanged {
+		t.Error("Unchanged should be false without known_sha")
+	}
+
+	// Unchanged: matching hash (case-insensitive) omits text
+	known := strings.ToUpper(first.ContentSHA256)
+	same, err := Fetch(context.Background(), database, FetchInput{ID: storeOutput.ID, KnownSHA: &known})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if !same.Unchanged {
+		t.Error("Unchanged should be true when known_sha matches")
+	}
+	if same.CapsuleText != "" {
+		t.Error("CapsuleText should be omitted when unchanged")
+	}
+	if same.ContentSHA256 != first.ContentSHA256 {
+		t.Errorf("ContentSHA256 = %q, want %q", same.ContentSHA256, first.ContentSHA256)
+	}
+
+	// Changed: an update produces a new hash and returns the text
+	newText := validCapsuleText + "\nExtra line."
+	if _, err := Update(context.Background(), database, cfg, UpdateInput{
+		ID:          storeOutput.ID,
+		CapsuleText: &newText,
+	}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	known = first.ContentSHA256
+	changed, err := Fetch(context.Background(), database, FetchInput{ID: storeOutput.ID, KnownSHA: &known})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if changed.Unchanged {
+		t.Error("Unchanged should be false after the text changed")
+	}
+	if changed.CapsuleText != newText {
+		t.Error("CapsuleText should be returned when the hash differs")
+	}
+	if changed.ContentSHA256 != capsule.ContentSHA256(newText) {
+		t.Errorf("ContentSHA256 = %q, want hash of updated text", changed.ContentSHA256)
+	}
+}
+
+func TestFetch_Fields(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	storeOutput, err := Store(context.Background(), database, cfg, StoreInput{
+		Name:        stringPtr("projected"),
+		Title:       stringPtr("Projected Capsule"),
+		Tags:        []string{"alpha", "beta"},
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := Fetch(context.Background(), database, FetchInput{
+		ID:     storeOutput.ID,
+		Fields: []string{"title", " tags ", "title"},
+	})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	keys := slices.Sorted(maps.Keys(got))
+	if want := []string{"id", "tags", "title"}; !slices.Equal(keys, want) {
+		t.Errorf("fields = %v, want %v", keys, want)
+	}
+	if got["id"] != storeOutput.ID || got["title"] != "Projected Capsule" {
+		t.Errorf("id/title = %v/%v, want %s/Projected Capsule", got["id"], got["title"], storeOutput.ID)
+	}
+	if output.CapsuleText != "" {
+		t.Error("CapsuleText should not be loaded when not projected")
+	}
+
+	// Without a projection every field is returned
+	full, err := Fetch(context.Background(), database, FetchInput{ID: storeOutput.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	data, err = json.Marshal(full)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"capsule_text"`) || !strings.Contains(string(data), `"fetch_key"`) {
+		t.Errorf("unprojected output missing fields: %s", data)
+	}
+
+	// Unknown field names are rejected
+	_, err = Fetch(context.Background(), database, FetchInput{ID: storeOutput.ID, Fields: []string{"title", "body"}})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest for unknown field, got %v", err)
+	}
+}
+
+func TestFetch_FallbackWorkspaces(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	ids := map[string]string{}
+	for _, c := range []struct{ workspace, name string }{
+		{"proj", "auth"},
+		{"global", "auth"},
+		{"global", "style-guide"},
+	} {
+		out, err := Store(ctx, database, cfg, StoreInput{Workspace: c.workspace, Name: stringPtr(c.name), CapsuleText: validCapsuleText})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		ids[c.workspace+"/"+c.name] = out.ID
+	}
+
+	tests := []struct {
+		name        string
+		capsuleName string
+		wantID      string
+		wantMatched string
+	}{
+		{"found in primary", "auth", ids["proj/auth"], "proj"},
+		{"found in fallback", "style-guide", ids["global/style-guide"], "global"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := Fetch(ctx, database, FetchInput{
+				Workspace:          "proj",
+				Name:               tt.capsuleName,
+				FallbackWorkspaces: []string{"team", "Global"},
+			})
+			if err != nil {
+				t.Fatalf("Fetch failed: %v", err)
+			}
+			if output.ID != tt.wantID {
+				t.Errorf("ID = %q, want %q", output.ID, tt.wantID)
+			}
+			if output.MatchedWorkspace != tt.wantMatched {
+				t.Errorf("MatchedWorkspace = %q, want %q", output.MatchedWorkspace, tt.wantMatched)
+			}
+		})
+	}
+
+	t.Run("found in neither", func(t *testing.T) {
+		_, err := Fetch(ctx, database, FetchInput{
+			Workspace:          "proj",
+			Name:               "missing",
+			FallbackWorkspaces: []string{"global"},
+		})
+		if !errors.Is(err, errors.ErrNotFound) {
+			t.Errorf("error = %v, want NOT_FOUND", err)
+		}
+	})
+
+	t.Run("not allowed with id", func(t *testing.T) {
+		_, err := Fetch(ctx, database, FetchInput{ID: ids["proj/auth"], FallbackWorkspaces: []string{"global"}})
+		if !errors.Is(err, errors.ErrInvalidRequest) {
+			t.Errorf("error = %v, want INVALID_REQUEST", err)
+		}
+	})
+
+	t.Run("no fallbacks leaves matched_workspace unset", func(t *testing.T) {
+		output, err := Fetch(ctx, database, FetchInput{Workspace: "proj", Name: "auth"})
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if output.MatchedWorkspace != "" {
+			t.Errorf("MatchedWorkspace = %q, want empty", output.MatchedWorkspace)
+		}
+	})
+}
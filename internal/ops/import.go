@@ -2,12 +2,17 @@ package ops
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/rand"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/oklog/ulid/v2"
@@ -18,6 +23,9 @@ import (
 	"github.com/hpungsan/moss/internal/errors"
 )
 
+// importFileExts are the extensions accepted by Import: JSONL and single-document JSON exports.
+var importFileExts = []string{".jsonl", ".json"}
+
 // ImportMode controls collision behavior during import.
 type ImportMode string
 
@@ -25,6 +33,8 @@ const (
 	ImportModeError   ImportMode = "error"   // fail on collision (atomic)
 	ImportModeReplace ImportMode = "replace" // overwrite on collision
 	ImportModeRename  ImportMode = "rename"  // auto-suffix name on collision
+	ImportModeSkip    ImportMode = "skip"    // skip colliding records, import the rest
+	ImportModeMerge   ImportMode = "merge"   // keep the newer of the two, union tags
 
 	// MaxImportFileSize is the maximum allowed import file size (prevents OOM).
 	MaxImportFileSize int64 = 25 * 1024 * 1024 // 25MB
@@ -44,10 +54,12 @@ type ImportInput struct {
 type ImportOutput struct {
 	Imported int           `json:"imported"`
 	Skipped  int           `json:"skipped"`
+	Merged   int           `json:"merged,omitempty"` // mode:merge collisions, counted apart from imported
 	Errors   []ImportError `json:"errors"`
 }
 
 // ImportError represents an error that occurred during import.
+// For JSON document imports, Line is the 1-based position of the record in the capsules array.
 type ImportError struct {
 	Line    int    `json:"line"`
 	ID      string `json:"id,omitempty"`
@@ -56,8 +68,18 @@ type ImportError struct {
 	Message string `json:"message"`
 }
 
-// Import imports capsules from a JSONL export file.
+// importRecord is a parsed export record with its 1-based line (JSONL) or
+// array position (JSON document) in the import file.
+type importRecord struct {
+	capsule.ExportRecord
+	Line int
+}
+
+// Import imports capsules from an export file.
+// The format (JSONL or JSON document) is detected from the file contents.
 func Import(ctx context.Context, database *sql.DB, cfg *config.Config, input ImportInput) (*ImportOutput, error) {
+	defer invalidateSearchCache()
+
 	// Validate input
 	if input.Path == "" {
 		return nil, errors.NewInvalidRequest("path is required")
@@ -65,12 +87,18 @@ func Import(ctx context.Context, database *sql.DB, cfg *config.Config, input Imp
 	if input.Mode == "" {
 		input.Mode = ImportModeError
 	}
-	if input.Mode != ImportModeError && input.Mode != ImportModeReplace && input.Mode != ImportModeRename {
-		return nil, errors.NewInvalidRequest("mode must be one of: error, replace, rename")
+	if input.Mode != ImportModeError && input.Mode != ImportModeReplace && input.Mode != ImportModeRename && input.Mode != ImportModeSkip && input.Mode != ImportModeMerge {
+		return nil, errors.NewInvalidRequest("mode must be one of: error, replace, rename, skip, merge")
 	}
 
-	// Validate path (includes security checks: traversal, extension, directory restrictions, symlinks)
-	if err := ValidatePath(input.Path, PathCheckRead, cfg); err != nil {
+	ext := filepath.Ext(filepath.Clean(input.Path))
+	if !slices.Contains(importFileExts, ext) {
+		return nil, errors.NewInvalidRequest(
+			fmt.Sprintf("path must have one of these extensions: %s", strings.Join(importFileExts, ", ")))
+	}
+
+	// Validate path (includes security checks: traversal, directory restrictions, symlinks)
+	if err := ValidatePathExt(input.Path, ext, PathCheckRead, cfg); err != nil {
 		return nil, err
 	}
 
@@ -95,10 +123,25 @@ func Import(ctx context.Context, database *sql.DB, cfg *config.Config, input Imp
 	}
 
 	// Parse all records first
-	records, parseErrors := parseExportFile(file)
+	isDocument, err := isJSONDocumentExport(file)
+	if err != nil {
+		return nil, errors.NewInternal(fmt.Errorf("failed to read import file: %w", err))
+	}
+	var records []importRecord
+	var parseErrors []ImportError
+	if isDocument {
+		records, parseErrors = parseExportDocument(file)
+	} else {
+		records, parseErrors = parseExportFile(file)
+	}
+
+	return importRecords(ctx, database, input.Mode, records, parseErrors)
+}
 
+// importRecords applies parsed records using the collision mode.
+func importRecords(ctx context.Context, database *sql.DB, mode ImportMode, records []importRecord, parseErrors []ImportError) (*ImportOutput, error) {
 	// For mode:error, fail on any parse errors
-	if input.Mode == ImportModeError && len(parseErrors) > 0 {
+	if mode == ImportModeError && len(parseErrors) > 0 {
 		return &ImportOutput{
 			Imported: 0,
 			Skipped:  0,
@@ -106,97 +149,248 @@ func Import(ctx context.Context, database *sql.DB, cfg *config.Config, input Imp
 		}, nil
 	}
 
-	// Process records based on mode
-	switch input.Mode {
+	var out *ImportOutput
+	var err error
+	switch mode {
 	case ImportModeError:
-		return importModeError(ctx, database, records)
+		out, err = importModeError(ctx, database, records)
 	case ImportModeReplace:
-		return importModeReplace(ctx, database, records, parseErrors)
+		out, err = importModeReplace(ctx, database, records, parseErrors)
 	case ImportModeRename:
-		return importModeRename(ctx, database, records, parseErrors)
+		out, err = importModeRename(ctx, database, records, parseErrors)
+	case ImportModeSkip:
+		out, err = importModeSkip(ctx, database, records, parseErrors)
+	case ImportModeMerge:
+		out, err = importModeMerge(ctx, database, records, parseErrors)
 	default:
 		return nil, errors.NewInvalidRequest("invalid mode")
 	}
+	// Cancellation can interrupt a query mid-flight; report it as such rather
+	// than as the driver's internal error.
+	if err != nil && ctx.Err() != nil {
+		return nil, errors.NewCancelled("import")
+	}
+	return out, err
 }
 
-// parseExportFile parses a JSONL export file into records.
-func parseExportFile(file *os.File) ([]capsule.ExportRecord, []ImportError) {
-	var records []capsule.ExportRecord
+// parseExportFile parses a JSONL export stream into records.
+func parseExportFile(r io.Reader) ([]importRecord, []ImportError) {
+	var records []importRecord
 	var parseErrors []ImportError
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	scanner.Buffer(make([]byte, 0, MaxImportLineSize), MaxImportLineSize)
 	lineNum := 0
 
 	for scanner.Scan() {
 		lineNum++
-		line := scanner.Bytes()
-
-		var record capsule.ExportRecord
-		if err := json.Unmarshal(line, &record); err != nil {
-			parseErrors = append(parseErrors, ImportError{
-				Line:    lineNum,
-				Code:    "PARSE_ERROR",
-				Message: fmt.Sprintf("invalid JSON: %v", err),
-			})
+
+		record, parseErr := parseExportRecord(lineNum, scanner.Bytes())
+		if parseErr != nil {
+			parseErrors = append(parseErrors, *parseErr)
 			continue
 		}
+		if record != nil {
+			records = append(records, importRecord{ExportRecord: *record, Line: lineNum})
+		}
+	}
 
-		// Skip header line
-		if record.MossExport {
-			continue
+	if err := scanner.Err(); err != nil {
+		parseErrors = append(parseErrors, ImportError{
+			Line:    lineNum,
+			Code:    "READ_ERROR",
+			Message: fmt.Sprintf("failed to read file: %v", err),
+		})
+	}
+
+	return records, parseErrors
+}
+
+// isJSONDocumentExport reports whether the file holds a single JSON document
+// (a bare array of records, or an object with a "header" field) rather than JSONL.
+// The JSONL header line also starts with '{', so objects are decoded to check for "header".
+// Rewinds the file to the start before returning.
+func isJSONDocumentExport(file *os.File) (bool, error) {
+	isDocument, err := sniffJSONDocument(bufio.NewReader(file))
+	if err != nil {
+		return false, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	return isDocument, nil
+}
+
+// sniffJSONDocument inspects the first non-whitespace byte of r.
+func sniffJSONDocument(r *bufio.Reader) (bool, error) {
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
 		}
 
-		// Skip lines with no ID (invalid)
-		if record.ID == "" {
-			parseErrors = append(parseErrors, ImportError{
-				Line:    lineNum,
-				Code:    "INVALID_RECORD",
-				Message: "missing id field",
-			})
+		switch b {
+		case ' ', '\t', '\r', '\n':
 			continue
+		case '[':
+			return true, nil
+		case '{':
+			if err := r.UnreadByte(); err != nil {
+				return false, err
+			}
+			var first map[string]json.RawMessage
+			if err := json.NewDecoder(r).Decode(&first); err != nil {
+				// Not valid JSON: let the JSONL parser report per-line errors
+				return false, nil
+			}
+			_, hasHeader := first["header"]
+			return hasHeader, nil
+		default:
+			return false, nil
 		}
+	}
+}
 
-		// Skip lines with no WorkspaceRaw (invalid)
-		if record.WorkspaceRaw == "" {
-			parseErrors = append(parseErrors, ImportError{
-				Line:    lineNum,
-				ID:      record.ID,
-				Code:    "INVALID_RECORD",
-				Message: "missing workspace_raw field",
-			})
-			continue
+// parseExportDocument parses a JSON document export into records.
+// Accepts either {"header": {...}, "capsules": [...]} or a bare array of records.
+// The document is buffered in memory (bounded by MaxImportFileSize).
+func parseExportDocument(file *os.File) ([]importRecord, []ImportError) {
+	var items []json.RawMessage
+	var doc struct {
+		Capsules *[]json.RawMessage `json:"capsules"`
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, []ImportError{{Code: "READ_ERROR", Message: fmt.Sprintf("failed to read file: %v", err)}}
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &items)
+	} else if err = json.Unmarshal(trimmed, &doc); err == nil {
+		if doc.Capsules == nil {
+			return nil, []ImportError{{Code: "PARSE_ERROR", Message: "missing capsules field"}}
 		}
+		items = *doc.Capsules
+	}
+	if err != nil {
+		return nil, []ImportError{{Code: "PARSE_ERROR", Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
 
-		// Skip lines with empty CapsuleText (invalid - breaks API contract)
-		// When fetched with include_text=true, empty string is omitted due to omitempty,
-		// making it indistinguishable from include_text=false.
-		if record.CapsuleText == "" {
-			parseErrors = append(parseErrors, ImportError{
-				Line:    lineNum,
-				ID:      record.ID,
-				Code:    "INVALID_RECORD",
-				Message: "missing or empty capsule_text field",
-			})
+	var records []importRecord
+	var parseErrors []ImportError
+	for i, item := range items {
+		record, parseErr := parseExportRecord(i+1, item)
+		if parseErr != nil {
+			parseErrors = append(parseErrors, *parseErr)
 			continue
 		}
+		if record != nil {
+			records = append(records, importRecord{ExportRecord: *record, Line: i + 1})
+		}
+	}
+
+	return records, parseErrors
+}
+
+// parseExportRecord decodes and validates a single export record.
+// Returns (nil, nil) for the header record, which callers skip.
+func parseExportRecord(lineNum int, data []byte) (*capsule.ExportRecord, *ImportError) {
+	var record capsule.ExportRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, &ImportError{
+			Line:    lineNum,
+			Code:    "PARSE_ERROR",
+			Message: fmt.Sprintf("invalid JSON: %v", err),
+		}
+	}
 
-		records = append(records, record)
+	// Skip header line
+	if record.MossExport {
+		return nil, nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		parseErrors = append(parseErrors, ImportError{
+	// Skip lines with no ID (invalid)
+	if record.ID == "" {
+		return nil, &ImportError{
 			Line:    lineNum,
-			Code:    "READ_ERROR",
-			Message: fmt.Sprintf("failed to read file: %v", err),
-		})
+			Code:    "INVALID_RECORD",
+			Message: "missing id field",
+		}
 	}
 
-	return records, parseErrors
+	// Skip lines with no WorkspaceRaw (invalid)
+	if record.WorkspaceRaw == "" {
+		return nil, &ImportError{
+			Line:    lineNum,
+			ID:      record.ID,
+			Code:    "INVALID_RECORD",
+			Message: "missing workspace_raw field",
+		}
+	}
+
+	// Skip lines with empty CapsuleText (invalid - breaks API contract)
+	// When fetched with include_text=true, empty string is omitted due to omitempty,
+	// making it indistinguishable from include_text=false.
+	if record.CapsuleText == "" {
+		return nil, &ImportError{
+			Line:    lineNum,
+			ID:      record.ID,
+			Code:    "INVALID_RECORD",
+			Message: "missing or empty capsule_text field",
+		}
+	}
+
+	return &record, nil
+}
+
+// duplicateNamesInFile finds active records whose (workspace, name) handle was
+// already used by an earlier active record in the same file, keyed by record
+// index. Soft-deleted records don't hold a name, so they never collide.
+func duplicateNamesInFile(records []importRecord) map[int]ImportError {
+	firstLine := make(map[[2]string]int)
+	duplicates := make(map[int]ImportError)
+	for i, record := range records {
+		if record.NameRaw == nil || record.DeletedAt != nil {
+			continue
+		}
+		key := [2]string{capsule.Normalize(record.WorkspaceRaw), capsule.Normalize(*record.NameRaw)}
+		if line, seen := firstLine[key]; seen {
+			duplicates[i] = ImportError{
+				Line:    record.Line,
+				ID:      record.ID,
+				Name:    *record.NameRaw,
+				Code:    "DUPLICATE_IN_FILE",
+				Message: fmt.Sprintf("name %q in workspace %q is already used by line %d of this file", *record.NameRaw, record.WorkspaceRaw, line),
+			}
+			continue
+		}
+		firstLine[key] = record.Line
+	}
+	return duplicates
 }
 
 // importModeError imports all records atomically, rolling back on any collision.
-func importModeError(ctx context.Context, database *sql.DB, records []capsule.ExportRecord) (*ImportOutput, error) {
+func importModeError(ctx context.Context, database *sql.DB, records []importRecord) (*ImportOutput, error) {
+	// Reject names repeated within the file up front, before touching the database
+	if duplicates := duplicateNamesInFile(records); len(duplicates) > 0 {
+		var importErrors []ImportError
+		for i := range records {
+			if dup, ok := duplicates[i]; ok {
+				importErrors = append(importErrors, dup)
+			}
+		}
+		return &ImportOutput{
+			Imported: 0,
+			Skipped:  0,
+			Errors:   importErrors,
+		}, nil
+	}
+
 	tx, err := database.BeginTx(ctx, nil)
 	if err != nil {
 		if ctx.Err() != nil {
@@ -204,7 +398,7 @@ func importModeError(ctx context.Context, database *sql.DB, records []capsule.Ex
 		}
 		return nil, errors.NewInternal(err)
 	}
-	defer tx.Rollback() //nolint:errcheck
+	defer db.Rollback(tx) //nolint:errcheck
 
 	imported := 0
 	var importErrors []ImportError
@@ -269,7 +463,7 @@ func importModeError(ctx context.Context, database *sql.DB, records []capsule.Ex
 		imported++
 	}
 
-	if err := tx.Commit(); err != nil {
+	if err := db.Commit(tx); err != nil {
 		return nil, errors.NewInternal(err)
 	}
 
@@ -288,7 +482,7 @@ func importModeError(ctx context.Context, database *sql.DB, records []capsule.Ex
 //   - If any such errors exist, the transaction is rolled back and errors are returned
 //   - Database errors (unexpected failures) short-circuit immediately with a top-level error
 //     (these indicate systemic issues, not user-fixable problems)
-func importModeReplace(ctx context.Context, database *sql.DB, records []capsule.ExportRecord, parseErrors []ImportError) (*ImportOutput, error) {
+func importModeReplace(ctx context.Context, database *sql.DB, records []importRecord, parseErrors []ImportError) (*ImportOutput, error) {
 	tx, err := database.BeginTx(ctx, nil)
 	if err != nil {
 		if ctx.Err() != nil {
@@ -296,7 +490,7 @@ func importModeReplace(ctx context.Context, database *sql.DB, records []capsule.
 		}
 		return nil, errors.NewInternal(err)
 	}
-	defer tx.Rollback() //nolint:errcheck
+	defer db.Rollback(tx) //nolint:errcheck
 
 	imported := 0
 	var importErrors []ImportError
@@ -304,13 +498,21 @@ func importModeReplace(ctx context.Context, database *sql.DB, records []capsule.
 	// Include parse errors (will cause rollback at end)
 	importErrors = append(importErrors, parseErrors...)
 
-	for _, record := range records {
+	// A name repeated within the file would silently replace its earlier line
+	duplicates := duplicateNamesInFile(records)
+
+	for i, record := range records {
 		select {
 		case <-ctx.Done():
 			return nil, errors.NewCancelled("import")
 		default:
 		}
 
+		if dup, ok := duplicates[i]; ok {
+			importErrors = append(importErrors, dup)
+			continue
+		}
+
 		c := record.ToCapsule()
 
 		// Check for ID collision
@@ -378,7 +580,7 @@ func importModeReplace(ctx context.Context, database *sql.DB, records []capsule.
 		}, nil
 	}
 
-	if err := tx.Commit(); err != nil {
+	if err := db.Commit(tx); err != nil {
 		return nil, errors.NewInternal(err)
 	}
 
@@ -389,11 +591,14 @@ func importModeReplace(ctx context.Context, database *sql.DB, records []capsule.
 	}, nil
 }
 
-// importModeRename imports records, auto-renaming on collision.
-// Atomic: all records succeed or none. If any errors occur (parse errors,
-// rename failures, or insert failures), the entire transaction is rolled back
-// and all errors are returned so the user can fix their export file and retry.
-func importModeRename(ctx context.Context, database *sql.DB, records []capsule.ExportRecord, parseErrors []ImportError) (*ImportOutput, error) {
+// importModeSkip imports only records that collide with nothing, for topping up
+// a database with new capsules. A record whose ID exists (including soft-deleted)
+// or whose name is held by an active capsule is skipped and counted, not
+// reported as an error. A name repeated within the file is skipped too, but
+// also reported as DUPLICATE_IN_FILE since the file itself is inconsistent.
+// Not atomic: parse and insert errors are returned alongside the records that
+// did import.
+func importModeSkip(ctx context.Context, database *sql.DB, records []importRecord, parseErrors []ImportError) (*ImportOutput, error) {
 	tx, err := database.BeginTx(ctx, nil)
 	if err != nil {
 		if ctx.Err() != nil {
@@ -401,21 +606,30 @@ func importModeRename(ctx context.Context, database *sql.DB, records []capsule.E
 		}
 		return nil, errors.NewInternal(err)
 	}
-	defer tx.Rollback() //nolint:errcheck
+	defer db.Rollback(tx) //nolint:errcheck
 
 	imported := 0
+	skipped := 0
 	var importErrors []ImportError
 
-	// Include parse errors (will cause rollback at end)
+	// Include parse errors (reported, but don't block the rest)
 	importErrors = append(importErrors, parseErrors...)
 
-	for _, record := range records {
+	duplicates := duplicateNamesInFile(records)
+
+	for i, record := range records {
 		select {
 		case <-ctx.Done():
 			return nil, errors.NewCancelled("import")
 		default:
 		}
 
+		if dup, ok := duplicates[i]; ok {
+			importErrors = append(importErrors, dup)
+			skipped++
+			continue
+		}
+
 		c := record.ToCapsule()
 
 		// Check for ID collision
@@ -423,14 +637,9 @@ func importModeRename(ctx context.Context, database *sql.DB, records []capsule.E
 		if err != nil && !errors.Is(err, errors.ErrNotFound) {
 			return nil, err
 		}
-
-		// If ID collision, generate new ULID
 		if existingByID != nil {
-			newID, err := generateNewULID()
-			if err != nil {
-				return nil, errors.NewInternal(fmt.Errorf("failed to generate ULID: %w", err))
-			}
-			c.ID = newID
+			skipped++
+			continue
 		}
 
 		// Check for name collision (if named)
@@ -440,31 +649,16 @@ func importModeRename(ctx context.Context, database *sql.DB, records []capsule.E
 				return nil, err
 			}
 			if exists {
-				// Find unique name
-				baseName := *c.NameNorm
-				newName, err := db.FindUniqueName(ctx, tx, c.WorkspaceNorm, baseName)
-				if err != nil {
-					name := ""
-					if record.NameRaw != nil {
-						name = *record.NameRaw
-					}
-					importErrors = append(importErrors, ImportError{
-						ID:      record.ID,
-						Name:    name,
-						Code:    "RENAME_FAILED",
-						Message: fmt.Sprintf("failed to find unique name: %v", err),
-					})
-					// Continue processing to collect all errors
-					continue
-				}
-				// Apply new name to both raw and norm
-				c.NameRaw = &newName
-				c.NameNorm = &newName
+				skipped++
+				continue
 			}
 		}
 
 		// Insert capsule
 		if err := db.Insert(ctx, tx, c); err != nil {
+			if ctx.Err() != nil {
+				return nil, errors.NewCancelled("import")
+			}
 			name := ""
 			if c.NameRaw != nil {
 				name = *c.NameRaw
@@ -475,6 +669,85 @@ func importModeRename(ctx context.Context, database *sql.DB, records []capsule.E
 				Code:    "INSERT_FAILED",
 				Message: fmt.Sprintf("failed to insert: %v", err),
 			})
+			continue
+		}
+		imported++
+	}
+
+	if err := db.Commit(tx); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	return &ImportOutput{
+		Imported: imported,
+		Skipped:  skipped,
+		Errors:   importErrors,
+	}, nil
+}
+
+// importModeRename imports records, auto-renaming on collision.
+// A name repeated within the file is renamed like any other collision, since
+// the earlier record is already inserted when the later one is reached.
+// Atomic: all records succeed or none. If any errors occur (parse errors,
+// rename failures, or insert failures), the entire transaction is rolled back
+// and all errors are returned so the user can fix their export file and retry.
+func importModeRename(ctx context.Context, database *sql.DB, records []importRecord, parseErrors []ImportError) (*ImportOutput, error) {
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("import")
+		}
+		return nil, errors.NewInternal(err)
+	}
+	defer db.Rollback(tx) //nolint:errcheck
+
+	imported := 0
+	var importErrors []ImportError
+
+	// Include parse errors (will cause rollback at end)
+	importErrors = append(importErrors, parseErrors...)
+
+	for _, record := range records {
+		select {
+		case <-ctx.Done():
+			return nil, errors.NewCancelled("import")
+		default:
+		}
+
+		c := record.ToCapsule()
+
+		// Check for ID collision
+		existingByID, err := db.GetByID(ctx, tx, record.ID, true)
+		if err != nil && !errors.Is(err, errors.ErrNotFound) {
+			return nil, err
+		}
+
+		// If ID collision, generate new ULID
+		if existingByID != nil {
+			newID, err := generateNewULID()
+			if err != nil {
+				return nil, errors.NewInternal(fmt.Errorf("failed to generate ULID: %w", err))
+			}
+			c.ID = newID
+		}
+
+		// Insert, renaming with a -N suffix on name collision. The retry
+		// inside InsertWithUniqueName covers names claimed by concurrent writers.
+		if err := db.InsertWithUniqueName(ctx, tx, c); err != nil {
+			name := ""
+			if record.NameRaw != nil {
+				name = *record.NameRaw
+			}
+			code, message := "INSERT_FAILED", fmt.Sprintf("failed to insert: %v", err)
+			if errors.Is(err, errors.ErrConflict) || errors.Is(err, errors.ErrCancelled) {
+				code, message = "RENAME_FAILED", fmt.Sprintf("failed to find unique name: %v", err)
+			}
+			importErrors = append(importErrors, ImportError{
+				ID:      c.ID,
+				Name:    name,
+				Code:    code,
+				Message: message,
+			})
 			// Continue processing to collect all errors
 			continue
 		}
@@ -491,17 +764,133 @@ func importModeRename(ctx context.Context, database *sql.DB, records []capsule.E
 		}, nil
 	}
 
-	if err := tx.Commit(); err != nil {
+	if err := db.Commit(tx); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	return &ImportOutput{
+		Imported: imported,
+		Skipped:  0,
+		Errors:   importErrors,
+	}, nil
+}
+
+// importModeMerge imports records, merging each collision into the existing
+// capsule: whichever of the two has the newer updated_at keeps its content and
+// metadata (the existing one on a tie), and the result carries the union of
+// both tag lists. Named records collide by ID or by active name; unnamed
+// records only by ID, so they are otherwise always inserted. A name repeated
+// within the file merges into its earlier line like any other collision.
+// Atomic: all records succeed or none.
+func importModeMerge(ctx context.Context, database *sql.DB, records []importRecord, parseErrors []ImportError) (*ImportOutput, error) {
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("import")
+		}
+		return nil, errors.NewInternal(err)
+	}
+	defer db.Rollback(tx) //nolint:errcheck
+
+	imported := 0
+	merged := 0
+	var importErrors []ImportError
+
+	// Include parse errors (will cause rollback at end)
+	importErrors = append(importErrors, parseErrors...)
+
+	for _, record := range records {
+		select {
+		case <-ctx.Done():
+			return nil, errors.NewCancelled("import")
+		default:
+		}
+
+		c := record.ToCapsule()
+
+		existing, err := db.GetByID(ctx, tx, record.ID, true)
+		if err != nil && !errors.Is(err, errors.ErrNotFound) {
+			return nil, err
+		}
+		if c.NameNorm != nil {
+			existingByName, err := db.GetByName(ctx, tx, c.WorkspaceNorm, *c.NameNorm, false)
+			if err != nil && !errors.Is(err, errors.ErrNotFound) {
+				return nil, err
+			}
+			if existing != nil && existingByName != nil && existing.ID != existingByName.ID {
+				name := ""
+				if record.NameRaw != nil {
+					name = *record.NameRaw
+				}
+				importErrors = append(importErrors, ImportError{
+					ID:      record.ID,
+					Name:    name,
+					Code:    "AMBIGUOUS_COLLISION",
+					Message: fmt.Sprintf("id %q matches existing capsule but name %q matches different capsule", record.ID, name),
+				})
+				continue
+			}
+			if existing == nil {
+				existing = existingByName
+			}
+		}
+
+		if existing == nil {
+			if err := db.Insert(ctx, tx, c); err != nil {
+				return nil, err
+			}
+			imported++
+			continue
+		}
+
+		winner := existing
+		if c.UpdatedAt > existing.UpdatedAt {
+			c.ID = existing.ID
+			winner = c
+		}
+		tags := unionTags(existing.Tags, c.Tags)
+		if winner != existing || len(tags) != len(existing.Tags) {
+			winner.Tags = tags
+			if err := db.UpdateFull(ctx, tx, winner); err != nil {
+				return nil, err
+			}
+		}
+		merged++
+	}
+
+	// Atomic: only commit if zero errors
+	if len(importErrors) > 0 {
+		// Transaction will be rolled back by deferred Rollback()
+		return &ImportOutput{
+			Imported: 0,
+			Skipped:  0,
+			Errors:   importErrors,
+		}, nil
+	}
+
+	if err := db.Commit(tx); err != nil {
 		return nil, errors.NewInternal(err)
 	}
 
 	return &ImportOutput{
 		Imported: imported,
 		Skipped:  0,
+		Merged:   merged,
 		Errors:   importErrors,
 	}, nil
 }
 
+// unionTags returns a's tags followed by those of b not already present.
+func unionTags(a, b []string) []string {
+	union := slices.Clone(a)
+	for _, tag := range b {
+		if !slices.Contains(union, tag) {
+			union = append(union, tag)
+		}
+	}
+	return union
+}
+
 // generateNewULID generates a new ULID.
 func generateNewULID() (string, error) {
 	entropy := ulid.Monotonic(rand.Reader, 0)
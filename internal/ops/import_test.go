@@ -3,8 +3,10 @@ package ops
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -12,6 +14,7 @@ import (
 	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/db"
 	"github.com/hpungsan/moss/internal/errors"
+	"github.com/hpungsan/moss/internal/testutil"
 )
 
 func newTestCapsuleForImport(id, workspaceRaw, text string) *capsule.Capsule {
@@ -837,6 +840,292 @@ func TestImport_ModeRename_GeneratesNewIDOnCollision(t *testing.T) {
 	}
 }
 
+func TestImport_ModeSkip_ImportsOnlyNonColliding(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	// Existing: one by ID (soft-deleted still collides), one by name
+	byID := newTestCapsuleForImport("01IMPSKIP01", "default", "Existing by ID")
+	if err := db.Insert(context.Background(), database, byID); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := db.SoftDelete(context.Background(), database, byID.ID); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+	byName := newTestCapsuleForImport("01IMPSKIP02", "default", "Existing by name")
+	byName.NameRaw = stringPtr("auth")
+	byName.NameNorm = stringPtr("auth")
+	if err := db.Insert(context.Background(), database, byName); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	records := []capsule.ExportRecord{
+		{ID: "01IMPSKIP01", WorkspaceRaw: "default", CapsuleText: "ID collision", CreatedAt: 1000, UpdatedAt: 1000},
+		{ID: "01IMPSKIP10", WorkspaceRaw: "default", NameRaw: stringPtr("Auth"), CapsuleText: "Name collision", CreatedAt: 1000, UpdatedAt: 1000},
+		{ID: "01IMPSKIP11", WorkspaceRaw: "default", NameRaw: stringPtr("fresh"), CapsuleText: "New named", CreatedAt: 1000, UpdatedAt: 1000},
+		{ID: "01IMPSKIP12", WorkspaceRaw: "other", NameRaw: stringPtr("auth"), CapsuleText: "Same name, other workspace", CreatedAt: 1000, UpdatedAt: 1000},
+	}
+	exportPath := filepath.Join(tmpDir, "export.jsonl")
+	writeExportFile(t, exportPath, records)
+
+	output, err := Import(context.Background(), database, testConfigUnsafe(), ImportInput{
+		Path: exportPath,
+		Mode: ImportModeSkip,
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if output.Imported != 2 {
+		t.Errorf("Imported = %d, want 2", output.Imported)
+	}
+	if output.Skipped != 2 {
+		t.Errorf("Skipped = %d, want 2", output.Skipped)
+	}
+	if len(output.Errors) != 0 {
+		t.Errorf("Errors = %v, want none", output.Errors)
+	}
+
+	// Non-colliding records imported
+	for _, id := range []string{"01IMPSKIP11", "01IMPSKIP12"} {
+		if _, err := db.GetByID(context.Background(), database, id, false); err != nil {
+			t.Errorf("%s should be imported: %v", id, err)
+		}
+	}
+
+	// Colliding targets untouched
+	c, err := db.GetByID(context.Background(), database, byID.ID, true)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if c.CapsuleText != "Existing by ID" || c.DeletedAt == nil {
+		t.Errorf("ID-collision target changed: text=%q deleted=%v", c.CapsuleText, c.DeletedAt)
+	}
+	c, err = db.GetByName(context.Background(), database, "default", "auth", false)
+	if err != nil {
+		t.Fatalf("GetByName failed: %v", err)
+	}
+	if c.ID != byName.ID || c.CapsuleText != "Existing by name" {
+		t.Errorf("name-collision target changed: id=%s text=%q", c.ID, c.CapsuleText)
+	}
+
+	// Re-running is a no-op: everything now collides
+	output, err = Import(context.Background(), database, testConfigUnsafe(), ImportInput{
+		Path: exportPath,
+		Mode: ImportModeSkip,
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if output.Imported != 0 || output.Skipped != 4 {
+		t.Errorf("re-import: Imported = %d, Skipped = %d, want 0 and 4", output.Imported, output.Skipped)
+	}
+}
+
+func TestImport_DuplicateNameInFile(t *testing.T) {
+	deletedAt := int64(1500)
+	records := []capsule.ExportRecord{
+		{ID: "01IMPDUP01", WorkspaceRaw: "proj", NameRaw: stringPtr("Plan"), CapsuleText: "First plan", CreatedAt: 1000, UpdatedAt: 1000},
+		{ID: "01IMPDUP02", WorkspaceRaw: "Proj", NameRaw: stringPtr("plan"), CapsuleText: "Second plan", CreatedAt: 1000, UpdatedAt: 1000},
+		{ID: "01IMPDUP03", WorkspaceRaw: "proj", NameRaw: stringPtr("other"), CapsuleText: "Unrelated", CreatedAt: 1000, UpdatedAt: 1000},
+		// Soft-deleted records don't hold their name, so this one is not a duplicate
+		{ID: "01IMPDUP04", WorkspaceRaw: "proj", NameRaw: stringPtr("plan"), CapsuleText: "Old plan", CreatedAt: 1000, UpdatedAt: 1000, DeletedAt: &deletedAt},
+	}
+
+	tests := []struct {
+		mode         ImportMode
+		wantImported int
+		wantSkipped  int
+		wantDupError bool
+	}{
+		{ImportModeError, 0, 0, true},
+		{ImportModeReplace, 0, 0, true},
+		{ImportModeSkip, 2, 2, true}, // the deleted record is skipped as a name collision with the imported first line
+		{ImportModeRename, 4, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			tmpDir := t.TempDir()
+			database, err := db.Init(tmpDir)
+			if err != nil {
+				t.Fatalf("db.Init failed: %v", err)
+			}
+			defer database.Close()
+
+			exportPath := filepath.Join(tmpDir, "export.jsonl")
+			writeExportFile(t, exportPath, records)
+
+			output, err := Import(context.Background(), database, testConfigUnsafe(), ImportInput{
+				Path: exportPath,
+				Mode: tt.mode,
+			})
+			if err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			if output.Imported != tt.wantImported || output.Skipped != tt.wantSkipped {
+				t.Errorf("Imported = %d, Skipped = %d; want %d, %d", output.Imported, output.Skipped, tt.wantImported, tt.wantSkipped)
+			}
+
+			if tt.wantDupError {
+				if len(output.Errors) != 1 {
+					t.Fatalf("Errors = %v, want one DUPLICATE_IN_FILE", output.Errors)
+				}
+				e := output.Errors[0]
+				// Line 1 is the header, so the second record is line 3
+				if e.Code != "DUPLICATE_IN_FILE" || e.Line != 3 || e.ID != "01IMPDUP02" {
+					t.Errorf("error = %+v, want DUPLICATE_IN_FILE for 01IMPDUP02 on line 3", e)
+				}
+				if !strings.Contains(e.Message, "line 2") {
+					t.Errorf("message = %q, want it to name the first line", e.Message)
+				}
+			} else if len(output.Errors) != 0 {
+				t.Errorf("Errors = %v, want none", output.Errors)
+			}
+
+			first, firstErr := db.GetByID(context.Background(), database, "01IMPDUP01", false)
+			second, secondErr := db.GetByID(context.Background(), database, "01IMPDUP02", false)
+			switch tt.mode {
+			case ImportModeError, ImportModeReplace:
+				// Atomic modes roll back everything
+				if firstErr == nil || secondErr == nil {
+					t.Error("no records should be imported")
+				}
+			case ImportModeSkip:
+				if firstErr != nil || first.CapsuleText != "First plan" {
+					t.Errorf("first occurrence should be imported unchanged: %v", firstErr)
+				}
+				if secondErr == nil {
+					t.Error("second occurrence should be skipped")
+				}
+			case ImportModeRename:
+				if firstErr != nil || *first.NameNorm != "plan" {
+					t.Errorf("first occurrence should keep its name: %v", firstErr)
+				}
+				if secondErr != nil || second.NameNorm == nil || *second.NameNorm == "plan" {
+					t.Errorf("second occurrence should be imported under a new name: %v", secondErr)
+				}
+			}
+		})
+	}
+}
+
+func TestImport_ModeMerge(t *testing.T) {
+	tests := []struct {
+		name            string
+		importUpdatedAt int64
+		wantText        string
+	}{
+		{"imported newer wins", 3000, "Imported content"},
+		{"existing newer kept", 1000, "Existing content"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			database, err := db.Init(tmpDir)
+			if err != nil {
+				t.Fatalf("db.Init failed: %v", err)
+			}
+			defer database.Close()
+
+			existing := newTestCapsuleForImport("01MERGE01", "default", "Existing content")
+			existing.NameRaw = stringPtr("notes")
+			existing.NameNorm = stringPtr("notes")
+			existing.Tags = []string{"auth", "draft"}
+			existing.UpdatedAt = 2000
+			if err := db.Insert(context.Background(), database, existing); err != nil {
+				t.Fatalf("Insert failed: %v", err)
+			}
+
+			records := []capsule.ExportRecord{
+				{
+					ID:           "01MERGE02", // different ID, same name
+					WorkspaceRaw: "default",
+					NameRaw:      stringPtr("notes"),
+					CapsuleText:  "Imported content",
+					Tags:         []string{"auth", "reviewed"},
+					CreatedAt:    1000,
+					UpdatedAt:    tt.importUpdatedAt,
+				},
+				{
+					ID:           "01MERGE03", // unnamed, always inserted
+					WorkspaceRaw: "default",
+					CapsuleText:  "Unnamed content",
+					CreatedAt:    1000,
+					UpdatedAt:    1000,
+				},
+			}
+			exportPath := filepath.Join(tmpDir, "export.jsonl")
+			writeExportFile(t, exportPath, records)
+
+			output, err := Import(context.Background(), database, testConfigUnsafe(), ImportInput{
+				Path: exportPath,
+				Mode: ImportModeMerge,
+			})
+			if err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			if output.Imported != 1 || output.Merged != 1 || len(output.Errors) != 0 {
+				t.Errorf("Imported = %d, Merged = %d, Errors = %v; want 1, 1, none", output.Imported, output.Merged, output.Errors)
+			}
+
+			c, err := db.GetByID(context.Background(), database, "01MERGE01", false)
+			if err != nil {
+				t.Fatalf("existing ID should be kept: %v", err)
+			}
+			if c.CapsuleText != tt.wantText {
+				t.Errorf("CapsuleText = %q, want %q", c.CapsuleText, tt.wantText)
+			}
+			if !slices.Equal(c.Tags, []string{"auth", "draft", "reviewed"}) {
+				t.Errorf("Tags = %v, want union [auth draft reviewed]", c.Tags)
+			}
+			if _, err := db.GetByID(context.Background(), database, "01MERGE02", true); !errors.Is(err, errors.ErrNotFound) {
+				t.Errorf("imported ID should not be inserted, got: %v", err)
+			}
+			if _, err := db.GetByID(context.Background(), database, "01MERGE03", false); err != nil {
+				t.Errorf("unnamed record should be inserted: %v", err)
+			}
+		})
+	}
+}
+
+func TestImport_ModeSkip_KeepsPartialSuccessOnParseErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	exportPath := filepath.Join(tmpDir, "export.jsonl")
+	content := `{"_moss_export":true,"schema_version":"1.0","exported_at":1000}
+{"id":"01IMPSKIP20","workspace_raw":"default","capsule_text":"Good","created_at":1000,"updated_at":1000}
+not json
+`
+	if err := os.WriteFile(exportPath, []byte(content), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	output, err := Import(context.Background(), database, testConfigUnsafe(), ImportInput{
+		Path: exportPath,
+		Mode: ImportModeSkip,
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if output.Imported != 1 {
+		t.Errorf("Imported = %d, want 1 (no rollback in skip mode)", output.Imported)
+	}
+	if len(output.Errors) != 1 || output.Errors[0].Line != 3 {
+		t.Errorf("Errors = %v, want one error on line 3", output.Errors)
+	}
+}
+
 func TestImport_FileNotFound(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -1033,7 +1322,7 @@ func TestImport_RoundTrip(t *testing.T) {
 	}
 
 	// Purge to remove completely
-	_, err = db.PurgeDeleted(context.Background(), database, nil, nil)
+	_, err = db.PurgeDeleted(context.Background(), database, nil, nil, false)
 	if err != nil {
 		t.Fatalf("PurgeDeleted failed: %v", err)
 	}
@@ -1087,6 +1376,180 @@ func TestImport_RoundTrip(t *testing.T) {
 	}
 }
 
+func TestImport_RoundTrip_JSONFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	c1 := newTestCapsuleForImport("01JSONRT1", "default", "Content 1")
+	c1.NameRaw = stringPtr("cap1")
+	c1.NameNorm = stringPtr("cap1")
+	c1.Tags = []string{"tag1"}
+	c2 := newTestCapsuleForImport("01JSONRT2", "other", "Content 2")
+
+	for _, c := range []*capsule.Capsule{c1, c2} {
+		if err := db.Insert(context.Background(), database, c); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	// Export as a single JSON document
+	exportPath := filepath.Join(tmpDir, "export.json")
+	exportOut, err := Export(context.Background(), database, testConfigUnsafe(), ExportInput{
+		Path:   exportPath,
+		Format: ExportFormatJSON,
+	})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if exportOut.Format != ExportFormatJSON {
+		t.Errorf("Format = %q, want json", exportOut.Format)
+	}
+
+	// File must be one valid JSON document with header + capsules
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	var doc struct {
+		Header   ExportHeader           `json:"header"`
+		Capsules []capsule.ExportRecord `json:"capsules"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("export is not a valid JSON document: %v", err)
+	}
+	if !doc.Header.MossExport {
+		t.Error("header._moss_export should be true")
+	}
+	if len(doc.Capsules) != 2 {
+		t.Fatalf("len(capsules) = %d, want 2", len(doc.Capsules))
+	}
+
+	// Purge and re-import
+	for _, id := range []string{c1.ID, c2.ID} {
+		if err := db.SoftDelete(context.Background(), database, id); err != nil {
+			t.Fatalf("SoftDelete failed: %v", err)
+		}
+	}
+	if _, err := db.PurgeDeleted(context.Background(), database, nil, nil, false); err != nil {
+		t.Fatalf("PurgeDeleted failed: %v", err)
+	}
+
+	importOut, err := Import(context.Background(), database, testConfigUnsafe(), ImportInput{Path: exportPath})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if importOut.Imported != 2 {
+		t.Errorf("Imported = %d, want 2 (errors: %v)", importOut.Imported, importOut.Errors)
+	}
+
+	restored, err := db.GetByID(context.Background(), database, c1.ID, false)
+	if err != nil {
+		t.Fatalf("Capsule 1 should be restored: %v", err)
+	}
+	if restored.CapsuleText != "Content 1" || restored.NameRaw == nil || *restored.NameRaw != "cap1" {
+		t.Errorf("restored = %+v, want original content and name", restored)
+	}
+}
+
+func TestImport_DetectsFormat(t *testing.T) {
+	record := func(id string) string {
+		b, _ := json.Marshal(capsule.ExportRecord{ID: id, WorkspaceRaw: "default", CapsuleText: "Content " + id})
+		return string(b)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantIDs []string
+	}{
+		{
+			name:    "jsonl with header",
+			content: `{"_moss_export":true,"schema_version":"1.0","exported_at":1}` + "\n" + record("01DETECT1") + "\n",
+			wantIDs: []string{"01DETECT1"},
+		},
+		{
+			name:    "jsonl without header",
+			content: record("01DETECT2") + "\n" + record("01DETECT3") + "\n",
+			wantIDs: []string{"01DETECT2", "01DETECT3"},
+		},
+		{
+			name:    "json document pretty-printed",
+			content: "  \n{\n  \"header\": {\"_moss_export\": true},\n  \"capsules\": [\n    " + record("01DETECT4") + "\n  ]\n}\n",
+			wantIDs: []string{"01DETECT4"},
+		},
+		{
+			name:    "bare json array",
+			content: "[" + record("01DETECT5") + "," + record("01DETECT6") + "]",
+			wantIDs: []string{"01DETECT5", "01DETECT6"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			database, err := db.Init(tmpDir)
+			if err != nil {
+				t.Fatalf("db.Init failed: %v", err)
+			}
+			defer database.Close()
+
+			path := filepath.Join(tmpDir, "import.jsonl")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+
+			output, err := Import(context.Background(), database, testConfigUnsafe(), ImportInput{Path: path})
+			if err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			if len(output.Errors) != 0 {
+				t.Fatalf("Errors = %v, want none", output.Errors)
+			}
+			if output.Imported != len(tt.wantIDs) {
+				t.Errorf("Imported = %d, want %d", output.Imported, len(tt.wantIDs))
+			}
+			for _, id := range tt.wantIDs {
+				if _, err := db.GetByID(context.Background(), database, id, false); err != nil {
+					t.Errorf("capsule %s not imported: %v", id, err)
+				}
+			}
+		})
+	}
+}
+
+func TestImport_JSONDocument_InvalidRecord(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	// Second record is missing capsule_text; line reports its array position
+	content := `{"header":{"_moss_export":true},"capsules":[` +
+		`{"id":"01BADDOC1","workspace_raw":"default","capsule_text":"ok"},` +
+		`{"id":"01BADDOC2","workspace_raw":"default"}]}`
+	path := filepath.Join(tmpDir, "import.jsonl")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	output, err := Import(context.Background(), database, testConfigUnsafe(), ImportInput{Path: path})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if output.Imported != 0 {
+		t.Errorf("Imported = %d, want 0 (mode:error is atomic)", output.Imported)
+	}
+	if len(output.Errors) != 1 || output.Errors[0].Line != 2 || output.Errors[0].Code != "INVALID_RECORD" {
+		t.Errorf("Errors = %+v, want one INVALID_RECORD at line 2", output.Errors)
+	}
+}
+
 func TestImport_DefaultsToModeError(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -1203,7 +1666,7 @@ func TestImport_RequiresJSONLExtension(t *testing.T) {
 		path string
 	}{
 		{"no extension", "/tmp/backup"},
-		{"wrong extension", "/tmp/backup.json"},
+		{"csv extension", "/tmp/backup.csv"},
 		{"txt extension", "/tmp/backup.txt"},
 	}
 
@@ -1211,7 +1674,7 @@ func TestImport_RequiresJSONLExtension(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			_, err := Import(context.Background(), database, testConfigUnsafe(), ImportInput{Path: tt.path})
 			if !errors.Is(err, errors.ErrInvalidRequest) {
-				t.Errorf("Import(%q) should return ErrInvalidRequest for non-.jsonl, got: %v", tt.path, err)
+				t.Errorf("Import(%q) should return ErrInvalidRequest for non-.jsonl/.json, got: %v", tt.path, err)
 			}
 		})
 	}
@@ -1250,3 +1713,45 @@ func TestImport_SymlinkFileRejected(t *testing.T) {
 		t.Errorf("Expected ErrInvalidRequest for symlink, got: %v", err)
 	}
 }
+
+func TestImport_CancelledRollsBack(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var records []capsule.ExportRecord
+	for i := 0; i < 200; i++ {
+		records = append(records, capsule.ExportRecord{
+			ID:           fmt.Sprintf("01IMPCANCEL%04d", i),
+			WorkspaceRaw: "default",
+			CapsuleText:  "Content",
+			CreatedAt:    1000,
+			UpdatedAt:    1000,
+		})
+	}
+	exportPath := filepath.Join(tmpDir, "export.jsonl")
+	writeExportFile(t, exportPath, records)
+
+	// Each mode gets its own database: database/sql rolls back a cancelled
+	// transaction asynchronously, so a shared database could still hold the
+	// previous mode's write lock and fail the next import with SQLITE_BUSY.
+	for _, mode := range []ImportMode{ImportModeError, ImportModeReplace, ImportModeRename, ImportModeSkip} {
+		database, err := db.Init(filepath.Join(tmpDir, string(mode)))
+		if err != nil {
+			t.Fatalf("db.Init failed: %v", err)
+		}
+
+		_, err = Import(testutil.CancelAfterDoneCalls(context.Background(), 50), database, testConfigUnsafe(), ImportInput{Path: exportPath, Mode: mode})
+		if !errors.Is(err, errors.ErrCancelled) {
+			t.Errorf("mode %s: expected ErrCancelled, got: %v", mode, err)
+		}
+
+		// The transaction is rolled back: nothing was imported
+		_, total, err := db.ListAll(context.Background(), database, db.InventoryFilters{}, 1, 0, true)
+		if err != nil {
+			t.Fatalf("ListAll failed: %v", err)
+		}
+		if total != 0 {
+			t.Errorf("mode %s: total = %d, want 0 after cancelled import", mode, total)
+		}
+		database.Close()
+	}
+}
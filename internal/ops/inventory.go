@@ -2,8 +2,6 @@ package ops
 
 import (
 	"context"
-	"database/sql"
-	"strings"
 
 	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/db"
@@ -17,9 +15,13 @@ type InventoryInput struct {
 	RunID          *string // optional filter
 	Phase          *string // optional filter
 	Role           *string // optional filter
+	SourceType     *string // optional filter
+	Named          *bool   // optional filter: true = named only, false = unnamed only
 	Limit          int     // default: 100, max: 500
 	Offset         int     // default: 0
 	IncludeDeleted bool
+	IncludeText    bool // return capsule_text; limit default/max 50
+	NormalizeTags  bool // from config.NormalizeTags; normalizes the tag filter
 }
 
 // InventoryOutput contains the result of the Inventory operation.
@@ -30,7 +32,7 @@ type InventoryOutput struct {
 }
 
 // Inventory retrieves capsule summaries across all workspaces with optional filters.
-func Inventory(ctx context.Context, database *sql.DB, input InventoryInput) (*InventoryOutput, error) {
+func Inventory(ctx context.Context, database db.Querier, input InventoryInput) (*InventoryOutput, error) {
 	// Normalize filters if present
 	var filters db.InventoryFilters
 	if input.Workspace != nil {
@@ -39,12 +41,7 @@ func Inventory(ctx context.Context, database *sql.DB, input InventoryInput) (*In
 			filters.Workspace = &workspace
 		}
 	}
-	if input.Tag != nil {
-		tag := strings.TrimSpace(*input.Tag)
-		if tag != "" {
-			filters.Tag = &tag
-		}
-	}
+	filters.Tag = cleanTagFilter(input.Tag, input.NormalizeTags)
 	if input.NamePrefix != nil {
 		prefix := capsule.Normalize(*input.NamePrefix)
 		if prefix != "" {
@@ -54,9 +51,17 @@ func Inventory(ctx context.Context, database *sql.DB, input InventoryInput) (*In
 	filters.RunID = cleanOptionalString(input.RunID)
 	filters.Phase = cleanOptionalString(input.Phase)
 	filters.Role = cleanOptionalString(input.Role)
+	filters.SourceType = cleanOptionalString(input.SourceType)
+	filters.Named = input.Named
 
 	// Apply limit defaults and bounds
 	limit := input.Limit
+	if input.IncludeText {
+		var err error
+		if limit, err = includeTextLimit(limit, DefaultInventoryLimit); err != nil {
+			return nil, err
+		}
+	}
 	if limit <= 0 {
 		limit = DefaultInventoryLimit
 	}
@@ -67,6 +72,19 @@ func Inventory(ctx context.Context, database *sql.DB, input InventoryInput) (*In
 	// Ensure offset is non-negative
 	offset := max(input.Offset, 0)
 
+	if input.IncludeText {
+		capsules, total, err := db.ListAllWithText(ctx, database, filters, limit, offset, input.IncludeDeleted)
+		if err != nil {
+			return nil, err
+		}
+		items, truncated := CapsulesToTextItems(capsules)
+		return &InventoryOutput{
+			Items:      items,
+			Pagination: textPagination(limit, offset, len(items), total, truncated),
+			Sort:       "updated_at_desc",
+		}, nil
+	}
+
 	// Query database
 	summaries, total, err := db.ListAll(ctx, database, filters, limit, offset, input.IncludeDeleted)
 	if err != nil {
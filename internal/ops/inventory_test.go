@@ -6,6 +6,7 @@ import (
 
 	"github.com/hpungsan/moss/internal/config"
 	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
 )
 
 func TestInventory_NoFilters(t *testing.T) {
@@ -483,3 +484,152 @@ func TestInventory_WhitespaceOnlyFilters(t *testing.T) {
 		t.Errorf("len(Items) = %d, want 1 (whitespace tag filter should be ignored)", len(output.Items))
 	}
 }
+
+func TestInventory_IncludeText(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	for _, ws := range []string{"ws1", "ws2"} {
+		if _, err := Store(ctx, database, cfg, StoreInput{Workspace: ws, CapsuleText: validCapsuleText}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	// Default limit drops to the include_text cap
+	output, err := Inventory(ctx, database, InventoryInput{IncludeText: true})
+	if err != nil {
+		t.Fatalf("Inventory failed: %v", err)
+	}
+	if output.Pagination.Limit != MaxIncludeTextItems {
+		t.Errorf("Limit = %d, want %d", output.Pagination.Limit, MaxIncludeTextItems)
+	}
+	if len(output.Items) != 2 {
+		t.Fatalf("len(Items) = %d, want 2", len(output.Items))
+	}
+	for _, item := range output.Items {
+		if item.CapsuleText != validCapsuleText {
+			t.Errorf("item %s: capsule_text not returned", item.ID)
+		}
+	}
+
+	_, err = Inventory(ctx, database, InventoryInput{IncludeText: true, Limit: DefaultInventoryLimit})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest for limit over cap, got: %v", err)
+	}
+}
+
+func TestInventory_SourceTypeFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	seed := []struct {
+		workspace  string
+		sourceType *string
+	}{
+		{"ws1", stringPtr(SourceTypeAgent)},
+		{"ws2", stringPtr(SourceTypeAgent)},
+		{"ws1", stringPtr(SourceTypeHuman)},
+		{"ws2", nil},
+	}
+	for _, c := range seed {
+		if _, err := Store(ctx, database, cfg, StoreInput{
+			Workspace:   c.workspace,
+			CapsuleText: validCapsuleText,
+			SourceType:  c.sourceType,
+		}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	output, err := Inventory(ctx, database, InventoryInput{SourceType: stringPtr(SourceTypeAgent)})
+	if err != nil {
+		t.Fatalf("Inventory failed: %v", err)
+	}
+	if output.Pagination.Total != 2 {
+		t.Errorf("Total = %d, want 2", output.Pagination.Total)
+	}
+	for _, item := range output.Items {
+		if item.SourceType == nil || *item.SourceType != SourceTypeAgent {
+			t.Errorf("item %s SourceType = %v, want agent", item.ID, item.SourceType)
+		}
+	}
+
+	// Combines with other filters
+	output, err = Inventory(ctx, database, InventoryInput{Workspace: stringPtr("ws1"), SourceType: stringPtr(SourceTypeHuman)})
+	if err != nil {
+		t.Fatalf("Inventory failed: %v", err)
+	}
+	if output.Pagination.Total != 1 {
+		t.Errorf("Total = %d, want 1", output.Pagination.Total)
+	}
+}
+
+func TestInventory_NamedFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// 2 named + 3 unnamed across two workspaces
+	for _, c := range []struct {
+		workspace string
+		name      *string
+	}{
+		{"ws1", stringPtr("alpha")},
+		{"ws2", stringPtr("beta")},
+		{"ws1", nil},
+		{"ws1", nil},
+		{"ws2", nil},
+	} {
+		if _, err := Store(ctx, database, cfg, StoreInput{Workspace: c.workspace, Name: c.name, CapsuleText: validCapsuleText}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		named *bool
+		want  int
+	}{
+		{"nil", nil, 5},
+		{"named", boolPtr(true), 2},
+		{"unnamed", boolPtr(false), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, includeText := range []bool{false, true} {
+				output, err := Inventory(ctx, database, InventoryInput{Named: tt.named, IncludeText: includeText})
+				if err != nil {
+					t.Fatalf("Inventory failed: %v", err)
+				}
+				if output.Pagination.Total != tt.want || len(output.Items) != tt.want {
+					t.Errorf("include_text=%v: Total = %d, items = %d, want %d", includeText, output.Pagination.Total, len(output.Items), tt.want)
+				}
+				for _, item := range output.Items {
+					if tt.named != nil && (item.Name != nil) != *tt.named {
+						t.Errorf("item %s Name = %v, want named=%v", item.ID, item.Name, *tt.named)
+					}
+				}
+			}
+		})
+	}
+}
@@ -2,10 +2,10 @@ package ops
 
 import (
 	"context"
-	"database/sql"
 
 	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
 )
 
 // LatestInput contains parameters for the Latest operation.
@@ -16,6 +16,8 @@ type LatestInput struct {
 	Role           *string // optional filter
 	IncludeText    *bool   // default: false (summary only)
 	IncludeDeleted bool
+	By             string // "updated" (default) or "created"
+	PreferNamed    bool   // named capsules win over newer (or older, for First) unnamed ones
 }
 
 // LatestOutput contains the result of the Latest operation.
@@ -30,14 +32,41 @@ type LatestItem struct {
 	FetchKey               FetchKey `json:"fetch_key"`
 }
 
-// Latest retrieves the most recent capsule in a workspace.
-func Latest(ctx context.Context, database *sql.DB, input LatestInput) (*LatestOutput, error) {
+// Latest retrieves the most recent capsule in a workspace (by updated_at, or created_at with By "created").
+func Latest(ctx context.Context, database db.Querier, input LatestInput) (*LatestOutput, error) {
+	return latestOrFirst(ctx, database, input, db.GetLatestSummary, db.GetLatestFull)
+}
+
+// First retrieves the oldest capsule in a workspace, the mirror of Latest:
+// same input, filters, and nil-for-empty-workspace output, but ordered by
+// updated_at (or created_at) ascending with the lower ID winning ties.
+func First(ctx context.Context, database db.Querier, input LatestInput) (*LatestOutput, error) {
+	return latestOrFirst(ctx, database, input, db.GetFirstSummary, db.GetFirstFull)
+}
+
+// latestOrFirst implements Latest and First; getSummary and getFull pick the end of the ordering.
+func latestOrFirst(
+	ctx context.Context,
+	database db.Querier,
+	input LatestInput,
+	getSummary func(context.Context, db.Querier, string, db.LatestFilters, bool) (*capsule.CapsuleSummary, error),
+	getFull func(context.Context, db.Querier, string, db.LatestFilters, bool) (*capsule.Capsule, error),
+) (*LatestOutput, error) {
 	// Normalize workspace
 	workspace := capsule.Normalize(input.Workspace)
 	if workspace == "" {
 		workspace = "default"
 	}
 
+	// Validate by (default: updated)
+	by := db.LatestBy(input.By)
+	if by == "" {
+		by = db.LatestByUpdated
+	}
+	if by != db.LatestByUpdated && by != db.LatestByCreated {
+		return nil, errors.NewInvalidRequest("by must be one of: updated, created")
+	}
+
 	// Determine include_text (default: false)
 	includeText := false
 	if input.IncludeText != nil {
@@ -49,12 +78,15 @@ func Latest(ctx context.Context, database *sql.DB, input LatestInput) (*LatestOu
 		RunID: cleanOptionalString(input.RunID),
 		Phase: cleanOptionalString(input.Phase),
 		Role:  cleanOptionalString(input.Role),
+		By:    by,
+
+		PreferNamed: input.PreferNamed,
 	}
 
 	// Query database based on include_text
 	if includeText {
 		// Fetch full capsule with text
-		c, err := db.GetLatestFull(ctx, database, workspace, filters, input.IncludeDeleted)
+		c, err := getFull(ctx, database, workspace, filters, input.IncludeDeleted)
 		if err != nil {
 			return nil, err
 		}
@@ -78,7 +110,7 @@ func Latest(ctx context.Context, database *sql.DB, input LatestInput) (*LatestOu
 	}
 
 	// Fetch summary only (no text)
-	s, err := db.GetLatestSummary(ctx, database, workspace, filters, input.IncludeDeleted)
+	s, err := getSummary(ctx, database, workspace, filters, input.IncludeDeleted)
 	if err != nil {
 		return nil, err
 	}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hpungsan/moss/internal/config"
 	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
 )
 
 func TestLatest_HappyPath(t *testing.T) {
@@ -420,3 +421,240 @@ func TestLatest_IncludeDeleted(t *testing.T) {
 		t.Errorf("ID = %q, want %q (deleted but more recent)", output.Item.ID, newer.ID)
 	}
 }
+
+func TestLatest_By(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// "edited" was created first but updated most recently; "appended" was created last.
+	timestamps := map[string][2]int64{
+		"edited":   {1000, 3000},
+		"appended": {2000, 2000},
+	}
+	ids := make(map[string]string)
+	for name, ts := range timestamps {
+		stored, err := Store(ctx, database, cfg, StoreInput{
+			Workspace:   "log",
+			Name:        stringPtr(name),
+			CapsuleText: validCapsuleText,
+		})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		if _, err := database.Exec("UPDATE capsules SET created_at = ?, updated_at = ? WHERE id = ?", ts[0], ts[1], stored.ID); err != nil {
+			t.Fatalf("failed to set timestamps: %v", err)
+		}
+		ids[name] = stored.ID
+	}
+
+	tests := []struct {
+		by   string
+		want string
+	}{
+		{"", "edited"},
+		{"updated", "edited"},
+		{"created", "appended"},
+	}
+	for _, tt := range tests {
+		for _, includeText := range []bool{false, true} {
+			output, err := Latest(ctx, database, LatestInput{Workspace: "log", By: tt.by, IncludeText: &includeText})
+			if err != nil {
+				t.Fatalf("Latest(by=%q) failed: %v", tt.by, err)
+			}
+			if output.Item == nil || output.Item.ID != ids[tt.want] {
+				t.Errorf("Latest(by=%q, include_text=%v) = %v, want %s", tt.by, includeText, output.Item, tt.want)
+			}
+		}
+	}
+
+	_, err = Latest(ctx, database, LatestInput{Workspace: "log", By: "deleted"})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("invalid by: err = %v, want INVALID_REQUEST", err)
+	}
+}
+
+func TestFirst_HappyPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	ids := make(map[string]string)
+	for i, name := range []string{"start", "middle", "end"} {
+		stored, err := Store(ctx, database, cfg, StoreInput{
+			Workspace:   "run",
+			Name:        stringPtr(name),
+			CapsuleText: validCapsuleText,
+		})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		ts := int64(1000 * (i + 1))
+		if _, err := database.Exec("UPDATE capsules SET created_at = ?, updated_at = ? WHERE id = ?", ts, ts, stored.ID); err != nil {
+			t.Fatalf("failed to set timestamps: %v", err)
+		}
+		ids[name] = stored.ID
+	}
+
+	for _, includeText := range []bool{false, true} {
+		output, err := First(ctx, database, LatestInput{Workspace: "run", IncludeText: &includeText})
+		if err != nil {
+			t.Fatalf("First failed: %v", err)
+		}
+		if output.Item == nil || output.Item.ID != ids["start"] {
+			t.Fatalf("First(include_text=%v) = %v, want start", includeText, output.Item)
+		}
+		if (output.Item.CapsuleText != "") != includeText {
+			t.Errorf("First(include_text=%v): CapsuleText present = %v", includeText, output.Item.CapsuleText != "")
+		}
+		if output.Item.FetchKey.MossCapsule != "start" {
+			t.Errorf("FetchKey = %+v, want name start", output.Item.FetchKey)
+		}
+	}
+}
+
+func TestFirst_EmptyWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	output, err := First(context.Background(), database, LatestInput{
+		Workspace: "empty",
+	})
+	if err != nil {
+		t.Fatalf("First failed: %v", err)
+	}
+
+	if output.Item != nil {
+		t.Errorf("Item = %v, want nil for empty workspace", output.Item)
+	}
+}
+
+func TestFirst_TiebreakerAndBy(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// "b" and "c" share the oldest updated_at; "a" was created first but edited last.
+	timestamps := map[string][2]int64{
+		"a": {500, 3000},
+		"b": {1000, 1000},
+		"c": {2000, 1000},
+	}
+	ids := make(map[string]string)
+	for name, ts := range timestamps {
+		stored, err := Store(ctx, database, cfg, StoreInput{
+			Workspace:   "log",
+			Name:        stringPtr(name),
+			CapsuleText: validCapsuleText,
+		})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		if _, err := database.Exec("UPDATE capsules SET created_at = ?, updated_at = ? WHERE id = ?", ts[0], ts[1], stored.ID); err != nil {
+			t.Fatalf("failed to set timestamps: %v", err)
+		}
+		ids[name] = stored.ID
+	}
+
+	// Tie on updated_at: the lower ID wins
+	wantTie := ids["b"]
+	if ids["c"] < wantTie {
+		wantTie = ids["c"]
+	}
+
+	output, err := First(ctx, database, LatestInput{Workspace: "log"})
+	if err != nil {
+		t.Fatalf("First failed: %v", err)
+	}
+	if output.Item == nil || output.Item.ID != wantTie {
+		t.Errorf("First() = %v, want lower ID %s", output.Item, wantTie)
+	}
+
+	output, err = First(ctx, database, LatestInput{Workspace: "log", By: "created"})
+	if err != nil {
+		t.Fatalf("First failed: %v", err)
+	}
+	if output.Item == nil || output.Item.ID != ids["a"] {
+		t.Errorf("First(by=created) = %v, want a", output.Item)
+	}
+
+	_, err = First(ctx, database, LatestInput{Workspace: "log", By: "deleted"})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("invalid by: err = %v, want INVALID_REQUEST", err)
+	}
+}
+
+func TestLatest_PreferNamed(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// With no named capsule, the unnamed one is still returned
+	unnamed, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	output, err := Latest(ctx, database, LatestInput{Workspace: "proj", PreferNamed: true})
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if output.Item == nil || output.Item.ID != unnamed.ID {
+		t.Fatalf("Item = %+v, want the unnamed capsule when none is named", output.Item)
+	}
+
+	named, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", Name: stringPtr("plan"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	// The unnamed capsule is the newer one
+	if _, err := database.Exec("UPDATE capsules SET updated_at = ? WHERE id = ?", 1000, named.ID); err != nil {
+		t.Fatalf("set updated_at failed: %v", err)
+	}
+	if _, err := database.Exec("UPDATE capsules SET updated_at = ? WHERE id = ?", 2000, unnamed.ID); err != nil {
+		t.Fatalf("set updated_at failed: %v", err)
+	}
+
+	output, err = Latest(ctx, database, LatestInput{Workspace: "proj"})
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if output.Item.ID != unnamed.ID {
+		t.Errorf("ID = %q, want the newer unnamed capsule without prefer_named", output.Item.ID)
+	}
+
+	output, err = Latest(ctx, database, LatestInput{Workspace: "proj", PreferNamed: true})
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if output.Item.ID != named.ID {
+		t.Errorf("ID = %q, want the named capsule with prefer_named", output.Item.ID)
+	}
+}
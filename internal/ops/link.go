@@ -0,0 +1,219 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// LinkRef identifies a link endpoint by ID or by workspace+name.
+type LinkRef struct {
+	ID        string `json:"id,omitempty"`
+	Workspace string `json:"workspace,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// LinkInput contains parameters for the Link and Unlink operations.
+type LinkInput struct {
+	From     LinkRef // required
+	To       LinkRef // required
+	Relation string  // required, normalized (e.g., "reviews", "supersedes")
+}
+
+// LinkOutput contains the result of the Link operation.
+type LinkOutput struct {
+	FromID   string `json:"from_id"`
+	ToID     string `json:"to_id"`
+	Relation string `json:"relation"`
+	Created  bool   `json:"created"` // false if the link already existed
+}
+
+// UnlinkOutput contains the result of the Unlink operation.
+type UnlinkOutput struct {
+	FromID   string `json:"from_id"`
+	ToID     string `json:"to_id"`
+	Relation string `json:"relation"`
+	Removed  bool   `json:"removed"`
+}
+
+// LinksInput contains parameters for the Links operation.
+type LinksInput struct {
+	ID             string
+	Workspace      string
+	Name           string
+	IncludeDeleted bool
+}
+
+// LinkItem is a linked capsule summary with the relation that connects it.
+type LinkItem struct {
+	SummaryItem
+	Relation string `json:"relation"`
+	LinkedAt int64  `json:"linked_at"`
+}
+
+// CapsuleLinks groups a capsule's links by direction.
+type CapsuleLinks struct {
+	Outgoing []LinkItem `json:"outgoing"` // this capsule -> other
+	Incoming []LinkItem `json:"incoming"` // other -> this capsule
+}
+
+// LinksOutput contains the result of the Links operation.
+type LinksOutput struct {
+	ID string `json:"id"`
+	CapsuleLinks
+}
+
+// Link creates a directed, typed link between two active capsules.
+// Linking is idempotent: re-adding an existing link succeeds with created=false.
+func Link(ctx context.Context, database *sql.DB, input LinkInput) (*LinkOutput, error) {
+	relation, err := validateRelation(input.Relation)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("link")
+		}
+		return nil, errors.NewInternal(err)
+	}
+	defer db.Rollback(tx) //nolint:errcheck
+
+	fromID, toID, err := resolveLinkEndpoints(ctx, tx, input.From, input.To)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := db.AddLink(ctx, tx, fromID, toID, relation)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Commit(tx); err != nil {
+		return nil, errors.NewInternal(err)
+	}
+
+	return &LinkOutput{
+		FromID:   fromID,
+		ToID:     toID,
+		Relation: relation,
+		Created:  created,
+	}, nil
+}
+
+// Unlink removes a directed, typed link between two capsules.
+// Returns NOT_FOUND if the link does not exist.
+func Unlink(ctx context.Context, database db.Querier, input LinkInput) (*UnlinkOutput, error) {
+	relation, err := validateRelation(input.Relation)
+	if err != nil {
+		return nil, err
+	}
+
+	fromID, toID, err := resolveLinkEndpoints(ctx, database, input.From, input.To)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.RemoveLink(ctx, database, fromID, toID, relation); err != nil {
+		return nil, err
+	}
+
+	return &UnlinkOutput{
+		FromID:   fromID,
+		ToID:     toID,
+		Relation: relation,
+		Removed:  true,
+	}, nil
+}
+
+// Links lists the outgoing and incoming links of a capsule.
+func Links(ctx context.Context, database db.Querier, input LinksInput) (*LinksOutput, error) {
+	addr, err := ValidateAddress(input.ID, input.Workspace, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := resolveAddress(ctx, database, addr, input.IncludeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := loadCapsuleLinks(ctx, database, c.ID, input.IncludeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LinksOutput{
+		ID:           c.ID,
+		CapsuleLinks: *links,
+	}, nil
+}
+
+// loadCapsuleLinks fetches a capsule's links and groups them by direction.
+// Always returns non-nil slices so JSON output uses [] rather than null.
+func loadCapsuleLinks(ctx context.Context, q db.Querier, id string, includeDeleted bool) (*CapsuleLinks, error) {
+	rows, err := db.ListLinks(ctx, q, id, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	links := &CapsuleLinks{
+		Outgoing: []LinkItem{},
+		Incoming: []LinkItem{},
+	}
+	for _, l := range rows {
+		item := LinkItem{
+			SummaryItem: SummaryToItem(l.Summary),
+			Relation:    l.Relation,
+			LinkedAt:    l.CreatedAt,
+		}
+		if l.Direction == db.LinkOutgoing {
+			links.Outgoing = append(links.Outgoing, item)
+		} else {
+			links.Incoming = append(links.Incoming, item)
+		}
+	}
+
+	return links, nil
+}
+
+// resolveLinkEndpoints validates and resolves both link endpoints to active capsule IDs.
+func resolveLinkEndpoints(ctx context.Context, q db.Querier, from, to LinkRef) (string, string, error) {
+	fromAddr, err := ValidateAddress(from.ID, from.Workspace, from.Name)
+	if err != nil {
+		return "", "", fmt.Errorf("from: %w", err)
+	}
+	toAddr, err := ValidateAddress(to.ID, to.Workspace, to.Name)
+	if err != nil {
+		return "", "", fmt.Errorf("to: %w", err)
+	}
+
+	fromCapsule, err := resolveAddress(ctx, q, fromAddr, false)
+	if err != nil {
+		return "", "", fmt.Errorf("from: %w", err)
+	}
+	toCapsule, err := resolveAddress(ctx, q, toAddr, false)
+	if err != nil {
+		return "", "", fmt.Errorf("to: %w", err)
+	}
+
+	if fromCapsule.ID == toCapsule.ID {
+		return "", "", errors.NewInvalidRequest("cannot link a capsule to itself")
+	}
+
+	return fromCapsule.ID, toCapsule.ID, nil
+}
+
+// validateRelation normalizes a relation and rejects empty values.
+func validateRelation(relation string) (string, error) {
+	norm := capsule.Normalize(relation)
+	if norm == "" {
+		return "", errors.NewInvalidRequest("relation is required")
+	}
+	return norm, nil
+}
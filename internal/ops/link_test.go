@@ -0,0 +1,229 @@
+package ops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestLink_CreateAndFetchWithLinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	design, err := Store(ctx, database, cfg, StoreInput{Name: stringPtr("design"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	review, err := Store(ctx, database, cfg, StoreInput{Name: stringPtr("review"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := Link(ctx, database, LinkInput{
+		From:     LinkRef{Workspace: "default", Name: "review"},
+		To:       LinkRef{ID: design.ID},
+		Relation: "Reviews",
+	})
+	if err != nil {
+		t.Fatalf("Link failed: %v", err)
+	}
+	if output.FromID != review.ID || output.ToID != design.ID {
+		t.Errorf("link = %s -> %s, want %s -> %s", output.FromID, output.ToID, review.ID, design.ID)
+	}
+	if output.Relation != "reviews" {
+		t.Errorf("Relation = %q, want %q", output.Relation, "reviews")
+	}
+	if !output.Created {
+		t.Error("Created should be true for a new link")
+	}
+
+	// Re-linking is idempotent
+	again, err := Link(ctx, database, LinkInput{
+		From:     LinkRef{ID: review.ID},
+		To:       LinkRef{ID: design.ID},
+		Relation: "reviews",
+	})
+	if err != nil {
+		t.Fatalf("Link (repeat) failed: %v", err)
+	}
+	if again.Created {
+		t.Error("Created should be false for an existing link")
+	}
+
+	// Fetch the design with links: the review shows up as incoming
+	fetched, err := Fetch(ctx, database, FetchInput{ID: design.ID, WithLinks: true})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.Links == nil {
+		t.Fatal("Links should be populated when WithLinks is true")
+	}
+	if len(fetched.Links.Outgoing) != 0 {
+		t.Errorf("Outgoing = %d, want 0", len(fetched.Links.Outgoing))
+	}
+	if len(fetched.Links.Incoming) != 1 {
+		t.Fatalf("Incoming = %d, want 1", len(fetched.Links.Incoming))
+	}
+	in := fetched.Links.Incoming[0]
+	if in.ID != review.ID || in.Relation != "reviews" {
+		t.Errorf("Incoming[0] = {%s, %s}, want {%s, reviews}", in.ID, in.Relation, review.ID)
+	}
+
+	// Without WithLinks, no links are attached
+	plain, err := Fetch(ctx, database, FetchInput{ID: design.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if plain.Links != nil {
+		t.Error("Links should be nil when WithLinks is false")
+	}
+
+	// The review sees the same link as outgoing
+	links, err := Links(ctx, database, LinksInput{ID: review.ID})
+	if err != nil {
+		t.Fatalf("Links failed: %v", err)
+	}
+	if len(links.Outgoing) != 1 || links.Outgoing[0].ID != design.ID {
+		t.Errorf("Outgoing = %+v, want link to %s", links.Outgoing, design.ID)
+	}
+}
+
+func TestLink_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	a, err := Store(ctx, database, cfg, StoreInput{Name: stringPtr("a"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		input LinkInput
+		code  errors.ErrorCode
+	}{
+		{
+			name:  "self link",
+			input: LinkInput{From: LinkRef{ID: a.ID}, To: LinkRef{ID: a.ID}, Relation: "relates"},
+			code:  errors.ErrInvalidRequest,
+		},
+		{
+			name:  "missing relation",
+			input: LinkInput{From: LinkRef{ID: a.ID}, To: LinkRef{Name: "b"}, Relation: "  "},
+			code:  errors.ErrInvalidRequest,
+		},
+		{
+			name:  "ambiguous endpoint",
+			input: LinkInput{From: LinkRef{ID: a.ID, Name: "a"}, To: LinkRef{Name: "b"}, Relation: "relates"},
+			code:  errors.ErrAmbiguousAddressing,
+		},
+		{
+			name:  "missing target",
+			input: LinkInput{From: LinkRef{ID: a.ID}, To: LinkRef{Name: "nonexistent"}, Relation: "relates"},
+			code:  errors.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Link(ctx, database, tt.input)
+			if !errors.Is(err, tt.code) {
+				t.Errorf("Link should return %s, got: %v", tt.code, err)
+			}
+		})
+	}
+
+	// Unlinking a link that doesn't exist
+	b, err := Store(ctx, database, cfg, StoreInput{Name: stringPtr("b"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	_, err = Unlink(ctx, database, LinkInput{From: LinkRef{ID: a.ID}, To: LinkRef{ID: b.ID}, Relation: "relates"})
+	if !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("Unlink should return ErrNotFound, got: %v", err)
+	}
+}
+
+func TestLink_UnlinkAndPurgeCleanup(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	a, err := Store(ctx, database, cfg, StoreInput{Name: stringPtr("a"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	b, err := Store(ctx, database, cfg, StoreInput{Name: stringPtr("b"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	for _, rel := range []string{"depends-on", "supersedes"} {
+		if _, err := Link(ctx, database, LinkInput{From: LinkRef{ID: a.ID}, To: LinkRef{ID: b.ID}, Relation: rel}); err != nil {
+			t.Fatalf("Link(%s) failed: %v", rel, err)
+		}
+	}
+
+	// Unlink one relation
+	unlinked, err := Unlink(ctx, database, LinkInput{From: LinkRef{ID: a.ID}, To: LinkRef{ID: b.ID}, Relation: "supersedes"})
+	if err != nil {
+		t.Fatalf("Unlink failed: %v", err)
+	}
+	if !unlinked.Removed {
+		t.Error("Removed should be true")
+	}
+
+	// Soft-deleted capsules are hidden from links unless include_deleted
+	if _, err := Delete(ctx, database, DeleteInput{ID: b.ID}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	links, err := Links(ctx, database, LinksInput{ID: a.ID})
+	if err != nil {
+		t.Fatalf("Links failed: %v", err)
+	}
+	if len(links.Outgoing) != 0 {
+		t.Errorf("Outgoing = %d, want 0 (target soft-deleted)", len(links.Outgoing))
+	}
+	links, err = Links(ctx, database, LinksInput{ID: a.ID, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Links failed: %v", err)
+	}
+	if len(links.Outgoing) != 1 {
+		t.Errorf("Outgoing = %d, want 1 with include_deleted", len(links.Outgoing))
+	}
+
+	// Purging the target removes the link rows
+	if _, err := Purge(ctx, database, PurgeInput{}); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM capsule_links").Scan(&count); err != nil {
+		t.Fatalf("count links failed: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("capsule_links rows after purge = %d, want 0", count)
+	}
+}
@@ -2,7 +2,6 @@ package ops
 
 import (
 	"context"
-	"database/sql"
 
 	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/db"
@@ -14,9 +13,11 @@ type ListInput struct {
 	RunID          *string // optional filter
 	Phase          *string // optional filter
 	Role           *string // optional filter
+	Named          *bool   // optional filter: true = named only, false = unnamed only
 	Limit          int     // default: 20, max: 100
 	Offset         int     // default: 0
 	IncludeDeleted bool
+	IncludeText    bool // return capsule_text; limit max 50
 }
 
 // ListOutput contains the result of the List operation.
@@ -27,7 +28,7 @@ type ListOutput struct {
 }
 
 // List retrieves capsule summaries for a workspace with pagination.
-func List(ctx context.Context, database *sql.DB, input ListInput) (*ListOutput, error) {
+func List(ctx context.Context, database db.Querier, input ListInput) (*ListOutput, error) {
 	// Normalize workspace
 	workspace := capsule.Normalize(input.Workspace)
 	if workspace == "" {
@@ -36,6 +37,12 @@ func List(ctx context.Context, database *sql.DB, input ListInput) (*ListOutput,
 
 	// Apply limit defaults and bounds
 	limit := input.Limit
+	if input.IncludeText {
+		var err error
+		if limit, err = includeTextLimit(limit, DefaultListLimit); err != nil {
+			return nil, err
+		}
+	}
 	if limit <= 0 {
 		limit = DefaultListLimit
 	}
@@ -51,6 +58,20 @@ func List(ctx context.Context, database *sql.DB, input ListInput) (*ListOutput,
 		RunID: cleanOptionalString(input.RunID),
 		Phase: cleanOptionalString(input.Phase),
 		Role:  cleanOptionalString(input.Role),
+		Named: input.Named,
+	}
+
+	if input.IncludeText {
+		capsules, total, err := db.ListByWorkspaceWithText(ctx, database, workspace, filters, limit, offset, input.IncludeDeleted)
+		if err != nil {
+			return nil, err
+		}
+		items, truncated := CapsulesToTextItems(capsules)
+		return &ListOutput{
+			Items:      items,
+			Pagination: textPagination(limit, offset, len(items), total, truncated),
+			Sort:       "updated_at_desc",
+		}, nil
 	}
 
 	// Query database
@@ -2,10 +2,12 @@ package ops
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/hpungsan/moss/internal/config"
 	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
 )
 
 func TestList_HappyPath(t *testing.T) {
@@ -50,6 +52,11 @@ func TestList_HappyPath(t *testing.T) {
 	if output.Sort != "updated_at_desc" {
 		t.Errorf("Sort = %q, want 'updated_at_desc'", output.Sort)
 	}
+	for _, item := range output.Items {
+		if item.Abstract != "Build a user authentication system." {
+			t.Errorf("Abstract = %q, want the Objective line", item.Abstract)
+		}
+	}
 }
 
 func TestList_DefaultWorkspace(t *testing.T) {
@@ -355,3 +362,169 @@ func TestList_NegativeOffset(t *testing.T) {
 		t.Errorf("Offset = %d, want 0", output.Pagination.Offset)
 	}
 }
+
+func TestList_IncludeText(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	for _, name := range []string{"cap1", "cap2", "cap3"} {
+		if _, err := Store(ctx, database, cfg, StoreInput{
+			Workspace:   "default",
+			Name:        stringPtr(name),
+			CapsuleText: validCapsuleText,
+		}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	output, err := List(ctx, database, ListInput{Workspace: "default", Limit: MaxIncludeTextItems, IncludeText: true})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(output.Items) != 3 {
+		t.Fatalf("len(Items) = %d, want 3", len(output.Items))
+	}
+	for _, item := range output.Items {
+		if item.CapsuleText != validCapsuleText {
+			t.Errorf("item %s: capsule_text not returned", item.ID)
+		}
+	}
+	if output.Pagination.Limit != MaxIncludeTextItems || output.Pagination.Total != 3 || output.Pagination.HasMore {
+		t.Errorf("Pagination = %+v, want limit=%d total=3 has_more=false", output.Pagination, MaxIncludeTextItems)
+	}
+
+	// Default stays summary-only
+	output, err = List(ctx, database, ListInput{Workspace: "default"})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if output.Items[0].CapsuleText != "" {
+		t.Error("capsule_text returned without include_text")
+	}
+}
+
+func TestList_IncludeTextByteCapReportsNextOffset(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.CapsuleMaxChars = MaxIncludeTextBytes
+	ctx := context.Background()
+
+	// Two of these exceed the byte cap, so each page holds one
+	big := validCapsuleText + strings.Repeat("x", MaxIncludeTextBytes/2)
+	for _, name := range []string{"cap1", "cap2", "cap3"} {
+		if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "default", Name: stringPtr(name), CapsuleText: big}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	seen := map[string]bool{}
+	offset := 0
+	for page := 0; page < 5; page++ {
+		output, err := List(ctx, database, ListInput{Workspace: "default", Limit: 3, Offset: offset, IncludeText: true})
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		for _, item := range output.Items {
+			seen[item.ID] = true
+		}
+		p := output.Pagination
+		if !p.HasMore {
+			if p.Truncated {
+				t.Errorf("last page: truncated = true, want false")
+			}
+			break
+		}
+		if !p.Truncated || p.NextOffset != offset+len(output.Items) {
+			t.Fatalf("page %d: pagination = %+v, want truncated with next_offset %d", page, p, offset+len(output.Items))
+		}
+		offset = p.NextOffset
+	}
+	if len(seen) != 3 {
+		t.Errorf("paging by next_offset saw %d capsules, want 3", len(seen))
+	}
+}
+
+func TestList_IncludeTextOverCap(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	_, err = List(context.Background(), database, ListInput{Workspace: "default", Limit: MaxIncludeTextItems + 1, IncludeText: true})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest, got: %v", err)
+	}
+
+	// The same limit is fine without include_text
+	if _, err := List(context.Background(), database, ListInput{Workspace: "default", Limit: MaxIncludeTextItems + 1}); err != nil {
+		t.Errorf("List without include_text failed: %v", err)
+	}
+}
+
+func TestList_NamedFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// 1 named + 2 unnamed in the listed workspace, plus noise elsewhere
+	for _, c := range []struct {
+		workspace string
+		name      *string
+	}{
+		{"default", stringPtr("alpha")},
+		{"default", nil},
+		{"default", nil},
+		{"other", stringPtr("beta")},
+		{"other", nil},
+	} {
+		if _, err := Store(ctx, database, cfg, StoreInput{Workspace: c.workspace, Name: c.name, CapsuleText: validCapsuleText}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		named *bool
+		want  int
+	}{
+		{"nil", nil, 3},
+		{"named", boolPtr(true), 1},
+		{"unnamed", boolPtr(false), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Page size 1 so total must come from the count query, not the page
+			output, err := List(ctx, database, ListInput{Workspace: "default", Named: tt.named, Limit: 1})
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if output.Pagination.Total != tt.want {
+				t.Errorf("Total = %d, want %d", output.Pagination.Total, tt.want)
+			}
+			if tt.named != nil && len(output.Items) == 1 && (output.Items[0].Name != nil) != *tt.named {
+				t.Errorf("item Name = %v, want named=%v", output.Items[0].Name, *tt.named)
+			}
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package ops
 
 import (
+	"context"
+	"fmt"
 	"strings"
 
 	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
 	"github.com/hpungsan/moss/internal/errors"
 )
 
@@ -14,14 +17,48 @@ const (
 	DefaultInventoryLimit = 100
 	MaxInventoryLimit     = 500
 	MaxFetchManyItems     = 50
+	DefaultActivityLimit  = 20
+	MaxActivityLimit      = 100
 )
 
+// include_text caps for list/inventory. Full payloads are opt-in and bounded
+// both by item count and by total capsule_text bytes per page.
+const (
+	MaxIncludeTextItems = 50
+	MaxIncludeTextBytes = 1 << 20 // 1 MiB
+)
+
+// Source types for structured provenance (source_type).
+const (
+	SourceTypeAgent  = "agent"
+	SourceTypeHuman  = "human"
+	SourceTypeImport = "import"
+)
+
+// validateSourceType returns INVALID_REQUEST unless s is nil or a known source type.
+// Callers pass the cleaned value (see cleanOptionalString).
+func validateSourceType(s *string) error {
+	if s == nil {
+		return nil
+	}
+	switch *s {
+	case SourceTypeAgent, SourceTypeHuman, SourceTypeImport:
+		return nil
+	}
+	return errors.NewInvalidRequest("source_type must be one of: agent, human, import")
+}
+
 // Pagination contains pagination metadata for list operations.
 type Pagination struct {
 	Limit   int  `json:"limit"`
 	Offset  int  `json:"offset"`
 	HasMore bool `json:"has_more"`
 	Total   int  `json:"total"`
+
+	// Truncated is set when include_text's byte cap returned fewer than limit
+	// items; resume from NextOffset rather than offset+limit.
+	Truncated  bool `json:"truncated,omitempty"`
+	NextOffset int  `json:"next_offset,omitempty"`
 }
 
 // ParsedAddress represents a validated capsule address.
@@ -79,6 +116,26 @@ func ValidateAddress(id, workspace, name string) (*ParsedAddress, error) {
 	}, nil
 }
 
+// resolveAddress loads the capsule identified by a validated address.
+func resolveAddress(ctx context.Context, q db.Querier, addr *ParsedAddress, includeDeleted bool) (*capsule.Capsule, error) {
+	if addr.ByID {
+		return db.GetByID(ctx, q, addr.ID, includeDeleted)
+	}
+	return db.GetByName(ctx, q, addr.Workspace, addr.Name, includeDeleted)
+}
+
+// cleanExpiresAt validates an expires_at Unix timestamp. 0 means no expiry (nil);
+// a timestamp already in the past is allowed and expires the capsule immediately.
+func cleanExpiresAt(ts *int64) (*int64, error) {
+	if ts == nil || *ts == 0 {
+		return nil, nil
+	}
+	if *ts < 0 {
+		return nil, errors.NewInvalidRequest("expires_at must be a Unix timestamp in seconds (0 for no expiry)")
+	}
+	return ts, nil
+}
+
 func cleanOptionalString(s *string) *string {
 	if s == nil {
 		return nil
@@ -90,6 +147,27 @@ func cleanOptionalString(s *string) *string {
 	return &v
 }
 
+// cleanTagFilter trims a tag filter (normalizing it when tag normalization is
+// on, to match how tags were stored). Returns nil if empty.
+func cleanTagFilter(tag *string, normalize bool) *string {
+	if tag == nil || !normalize {
+		return cleanOptionalString(tag)
+	}
+	v := capsule.Normalize(*tag)
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+// derefString returns *s, or "" if s is nil.
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 // FetchKey provides an address for fetching a capsule.
 // Either (MossCapsule + MossWorkspace) or MossID is populated.
 type FetchKey struct {
@@ -116,7 +194,8 @@ func BuildFetchKey(workspace, name, id string) FetchKey {
 // SummaryItem wraps a CapsuleSummary with a FetchKey for list/inventory responses.
 type SummaryItem struct {
 	capsule.CapsuleSummary
-	FetchKey FetchKey `json:"fetch_key"`
+	CapsuleText string   `json:"capsule_text,omitempty"` // only when include_text=true
+	FetchKey    FetchKey `json:"fetch_key"`
 }
 
 // SummaryToItem converts a CapsuleSummary to a SummaryItem with fetch_key.
@@ -139,3 +218,52 @@ func SummariesToItems(summaries []capsule.CapsuleSummary) []SummaryItem {
 	}
 	return items
 }
+
+// includeTextLimit resolves the page size for an include_text request.
+// An explicit limit above MaxIncludeTextItems is rejected rather than clamped,
+// so callers don't silently get fewer full capsules than they asked for.
+func includeTextLimit(limit, defaultLimit int) (int, error) {
+	if limit > MaxIncludeTextItems {
+		return 0, errors.NewInvalidRequest(
+			fmt.Sprintf("limit %d exceeds max %d when include_text is true", limit, MaxIncludeTextItems))
+	}
+	if limit <= 0 {
+		return min(defaultLimit, MaxIncludeTextItems), nil
+	}
+	return limit, nil
+}
+
+// CapsulesToTextItems converts full capsules to SummaryItems carrying capsule_text.
+// Stops before the item that would push the page past MaxIncludeTextBytes (the
+// first item is always included) and reports whether it did.
+func CapsulesToTextItems(capsules []capsule.Capsule) ([]SummaryItem, bool) {
+	items := make([]SummaryItem, 0, len(capsules))
+	total := 0
+	for i := range capsules {
+		c := &capsules[i]
+		total += len(c.CapsuleText)
+		if total > MaxIncludeTextBytes && len(items) > 0 {
+			return items, true
+		}
+		item := SummaryToItem(c.ToSummary())
+		item.CapsuleText = c.CapsuleText
+		items = append(items, item)
+	}
+	return items, false
+}
+
+// textPagination builds the Pagination for an include_text page, pointing
+// NextOffset past the returned items when the byte cap cut it short.
+func textPagination(limit, offset, returned, total int, truncated bool) Pagination {
+	p := Pagination{
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: offset+returned < total,
+		Total:   total,
+	}
+	if truncated {
+		p.Truncated = true
+		p.NextOffset = offset + returned
+	}
+	return p
+}
@@ -1,8 +1,10 @@
 package ops
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/errors"
 )
 
@@ -123,3 +125,26 @@ func TestBuildFetchKey_Unnamed(t *testing.T) {
 		t.Errorf("MossWorkspace = %q, want empty (unnamed capsule)", link.MossWorkspace)
 	}
 }
+
+func TestCapsulesToTextItems_ByteCap(t *testing.T) {
+	big := strings.Repeat("x", MaxIncludeTextBytes/2+1)
+	capsules := []capsule.Capsule{
+		{ID: "a", CapsuleText: big},
+		{ID: "b", CapsuleText: big},
+		{ID: "c", CapsuleText: "small"},
+	}
+
+	items, truncated := CapsulesToTextItems(capsules)
+	if len(items) != 1 || items[0].ID != "a" {
+		t.Fatalf("items = %d (first %q), want only the first", len(items), items[0].ID)
+	}
+	if !truncated {
+		t.Error("truncated = false, want true")
+	}
+
+	// A single oversized capsule is still returned
+	huge := []capsule.Capsule{{ID: "h", CapsuleText: strings.Repeat("x", MaxIncludeTextBytes+1)}}
+	if items, truncated := CapsulesToTextItems(huge); len(items) != 1 || truncated {
+		t.Errorf("len(items) = %d, truncated = %v; want 1, false", len(items), truncated)
+	}
+}
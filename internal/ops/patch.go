@@ -0,0 +1,211 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// MaxPatchOps is the maximum number of operations in a single patch.
+const MaxPatchOps = 50
+
+// PatchOpType identifies which edit a PatchOp makes.
+type PatchOpType string
+
+const (
+	PatchSetTitle       PatchOpType = "set-title"
+	PatchAddTag         PatchOpType = "add-tag"
+	PatchRemoveTag      PatchOpType = "remove-tag"
+	PatchReplaceSection PatchOpType = "replace-section"
+	PatchAppendSection  PatchOpType = "append-section"
+)
+
+// PatchOp is one edit. Section is used only by the section ops; Value is the
+// new title, the tag, or the section content.
+type PatchOp struct {
+	Op      PatchOpType
+	Section string // exact header name, as capsule_append
+	Value   string
+}
+
+// PatchInput contains parameters for the Patch operation.
+type PatchInput struct {
+	// Addressing
+	ID        string
+	Workspace string
+	Name      string
+
+	Ops       []PatchOp
+	AllowThin bool
+}
+
+// PatchOutput contains the result of the Patch operation.
+type PatchOutput struct {
+	ID       string   `json:"id"`
+	FetchKey FetchKey `json:"fetch_key"`
+	Applied  int      `json:"applied"`
+}
+
+// Patch applies a list of edits to one capsule in a single transaction. Ops
+// run in order against the capsule as left by the previous op; the final text
+// is then linted as capsule_update would. Any failing op or lint rolls back
+// the whole patch, and op errors are prefixed with their position ("ops[1]: ...").
+func Patch(ctx context.Context, database *sql.DB, cfg *config.Config, input PatchInput) (*PatchOutput, error) {
+	defer invalidateSearchCache()
+
+	addr, err := ValidateAddress(input.ID, input.Workspace, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(input.Ops) == 0 {
+		return nil, errors.NewInvalidRequest("ops must not be empty")
+	}
+	if len(input.Ops) > MaxPatchOps {
+		return nil, errors.NewInvalidRequest(
+			fmt.Sprintf("too many ops: %d (max %d)", len(input.Ops), MaxPatchOps))
+	}
+	for i, op := range input.Ops {
+		if err := validatePatchOp(op); err != nil {
+			return nil, fmt.Errorf("ops[%d]: %w", i, err)
+		}
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("patch")
+		}
+		return nil, errors.NewInternal(err)
+	}
+	defer db.Rollback(tx) //nolint:errcheck
+
+	// Fetch existing capsule (active only)
+	var c *capsule.Capsule
+	if addr.ByID {
+		c, err = db.GetByID(ctx, tx, addr.ID, false)
+	} else {
+		c, err = db.GetByName(ctx, tx, addr.Workspace, addr.Name, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	text := c.CapsuleText
+	for i, op := range input.Ops {
+		if text, err = applyPatchOp(cfg, c, text, op); err != nil {
+			return nil, fmt.Errorf("ops[%d]: %w", i, err)
+		}
+	}
+
+	if text != c.CapsuleText {
+		if cfg.NormalizeOnStore {
+			text = capsule.NormalizeText(text)
+		}
+
+		lintResult := capsule.Lint(capsule.LintInput{
+			CapsuleText: text,
+			MaxChars:    cfg.CapsuleMaxChars,
+			AllowThin:   input.AllowThin,
+		})
+		if lintResult.TooLarge {
+			return nil, errors.NewCapsuleTooLarge(lintResult.MaxChars, lintResult.ActualChars)
+		}
+		if err := checkCapsuleBytes(cfg, text); err != nil {
+			return nil, err
+		}
+		if len(lintResult.MissingSections) > 0 {
+			return nil, errors.NewCapsuleTooThin(lintResult.MissingSections)
+		}
+		if err := checkSectionCount(cfg, text, input.AllowThin); err != nil {
+			return nil, err
+		}
+
+		c.CapsuleText = text
+		c.RecomputeMetrics()
+	}
+
+	if err := db.UpdateByID(ctx, tx, c); err != nil {
+		return nil, err
+	}
+	if err := db.Commit(tx); err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("patch")
+		}
+		return nil, errors.NewInternal(err)
+	}
+
+	name := ""
+	if c.NameRaw != nil {
+		name = *c.NameRaw
+	}
+
+	return &PatchOutput{
+		ID:       c.ID,
+		FetchKey: BuildFetchKey(c.WorkspaceRaw, name, c.ID),
+		Applied:  len(input.Ops),
+	}, nil
+}
+
+// validatePatchOp checks an op's type and required fields before any reads.
+func validatePatchOp(op PatchOp) error {
+	switch op.Op {
+	case PatchSetTitle:
+		return nil
+	case PatchAddTag, PatchRemoveTag:
+		if strings.TrimSpace(op.Value) == "" {
+			return errors.NewInvalidRequest(fmt.Sprintf("%s requires a value (the tag)", op.Op))
+		}
+	case PatchReplaceSection, PatchAppendSection:
+		if strings.TrimSpace(op.Section) == "" {
+			return errors.NewInvalidRequest(fmt.Sprintf("%s requires a section", op.Op))
+		}
+		if op.Op == PatchAppendSection && strings.TrimSpace(op.Value) == "" {
+			return errors.NewInvalidRequest("append-section requires a value (the content)")
+		}
+	default:
+		return errors.NewInvalidRequest("op must be one of: set-title, add-tag, remove-tag, replace-section, append-section")
+	}
+	return nil
+}
+
+// applyPatchOp applies op to c's metadata or to text, returning the new text.
+// Text edits are kept out of c until the final lint passes.
+func applyPatchOp(cfg *config.Config, c *capsule.Capsule, text string, op PatchOp) (string, error) {
+	switch op.Op {
+	case PatchSetTitle:
+		c.Title = cleanOptionalString(&op.Value)
+	case PatchAddTag, PatchRemoveTag:
+		tag := strings.TrimSpace(op.Value)
+		if cfg.NormalizeTags {
+			tag = capsule.Normalize(tag)
+		}
+		if op.Op == PatchRemoveTag {
+			c.Tags = slices.DeleteFunc(c.Tags, func(t string) bool { return t == tag })
+		} else if !slices.Contains(c.Tags, tag) {
+			c.Tags = append(c.Tags, tag)
+		}
+	case PatchReplaceSection, PatchAppendSection:
+		sections := capsule.ParseSections(text)
+		if len(sections) == 0 {
+			return "", errors.NewInvalidRequest(fmt.Sprintf("%s requires markdown format (no sections found)", op.Op))
+		}
+		section := capsule.FindSectionExact(sections, op.Section)
+		if section == nil {
+			available := capsule.SectionNames(sections)
+			return "", errors.NewInvalidRequest(fmt.Sprintf("section %q not found; available: %v", op.Section, available))
+		}
+		if op.Op == PatchReplaceSection {
+			return capsule.ReplaceContent(text, section, op.Value), nil
+		}
+		return capsule.InsertContent(text, section, op.Value), nil
+	}
+	return text, nil
+}
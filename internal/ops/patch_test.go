@@ -0,0 +1,148 @@
+package ops
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestPatch_MultipleOps(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	tags := []string{"auth", "wip"}
+	if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", Name: stringPtr("auth"), CapsuleText: validCapsuleText, Tags: tags}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := Patch(ctx, database, cfg, PatchInput{
+		Workspace: "proj",
+		Name:      "auth",
+		Ops: []PatchOp{
+			{Op: PatchSetTitle, Value: "Auth rollout"},
+			{Op: PatchAddTag, Value: "backend"},
+			{Op: PatchAddTag, Value: "auth"}, // already present
+			{Op: PatchRemoveTag, Value: "wip"},
+			{Op: PatchReplaceSection, Section: "current status", Value: "Login endpoint shipped."},
+			{Op: PatchAppendSection, Section: "Decisions", Value: "Refresh tokens rotate on use."},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Patch failed: %v", err)
+	}
+	if output.Applied != 6 || output.FetchKey.MossCapsule != "auth" {
+		t.Errorf("output = %+v, want 6 applied for auth", output)
+	}
+
+	fetched, err := Fetch(ctx, database, FetchInput{ID: output.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.Title == nil || *fetched.Title != "Auth rollout" {
+		t.Errorf("Title = %v, want Auth rollout", fetched.Title)
+	}
+	if !slices.Equal(fetched.Tags, []string{"auth", "backend"}) {
+		t.Errorf("Tags = %v, want [auth backend]", fetched.Tags)
+	}
+	if strings.Contains(fetched.CapsuleText, "Database schema is complete.") ||
+		!strings.Contains(fetched.CapsuleText, "## Current status\nLogin endpoint shipped.\n\n## Decisions") {
+		t.Errorf("status section not replaced:\n%s", fetched.CapsuleText)
+	}
+	if !strings.Contains(fetched.CapsuleText, "Using JWT for tokens.\n\nRefresh tokens rotate on use.\n") {
+		t.Errorf("decisions section not appended:\n%s", fetched.CapsuleText)
+	}
+	if fetched.WordCount <= 32 {
+		t.Errorf("WordCount = %d, metrics not recomputed", fetched.WordCount)
+	}
+}
+
+func TestPatch_FailureRollsBack(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.CapsuleMaxChars = len(validCapsuleText) + 10
+	ctx := context.Background()
+
+	stored, err := Store(ctx, database, cfg, StoreInput{Name: stringPtr("auth"), Title: stringPtr("Original"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	// Final text fails lint (too large): the earlier title change is discarded too
+	_, err = Patch(ctx, database, cfg, PatchInput{
+		ID: stored.ID,
+		Ops: []PatchOp{
+			{Op: PatchSetTitle, Value: "Changed"},
+			{Op: PatchAppendSection, Section: "Open questions", Value: strings.Repeat("x", 50)},
+		},
+	})
+	if !errors.Is(err, errors.ErrCapsuleTooLarge) {
+		t.Fatalf("err = %v, want CAPSULE_TOO_LARGE", err)
+	}
+
+	// Missing section fails mid-patch with the op position
+	_, err = Patch(ctx, database, cfg, PatchInput{
+		ID: stored.ID,
+		Ops: []PatchOp{
+			{Op: PatchSetTitle, Value: "Changed"},
+			{Op: PatchReplaceSection, Section: "Risks", Value: "None"},
+		},
+	})
+	if !errors.Is(err, errors.ErrInvalidRequest) || !strings.HasPrefix(err.Error(), "ops[1]:") {
+		t.Fatalf("err = %v, want INVALID_REQUEST for ops[1]", err)
+	}
+
+	fetched, err := Fetch(ctx, database, FetchInput{ID: stored.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if *fetched.Title != "Original" || fetched.CapsuleText != validCapsuleText {
+		t.Errorf("capsule changed after failed patch: title %q\n%s", *fetched.Title, fetched.CapsuleText)
+	}
+}
+
+func TestPatch_Validation(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	tests := []struct {
+		name string
+		ops  []PatchOp
+	}{
+		{"no ops", nil},
+		{"unknown op", []PatchOp{{Op: "move"}}},
+		{"blank tag", []PatchOp{{Op: PatchAddTag, Value: " "}}},
+		{"missing section", []PatchOp{{Op: PatchReplaceSection, Value: "x"}}},
+		{"blank append content", []PatchOp{{Op: PatchAppendSection, Section: "Decisions"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Patch(context.Background(), database, cfg, PatchInput{ID: "01MISSING", Ops: tt.ops})
+			if !errors.Is(err, errors.ErrInvalidRequest) {
+				t.Errorf("err = %v, want INVALID_REQUEST", err)
+			}
+		})
+	}
+}
@@ -29,6 +29,11 @@ const (
 // swap an intermediate directory component with a symlink between validation and open.
 // Combined with O_NOFOLLOW on the final component, this provides complete symlink protection.
 func ValidatePath(path string, mode PathCheckMode, cfg *config.Config) error {
+	return ValidatePathExt(path, ".jsonl", mode, cfg)
+}
+
+// ValidatePathExt is ValidatePath with a caller-chosen required extension (e.g. ".csv").
+func ValidatePathExt(path, ext string, mode PathCheckMode, cfg *config.Config) error {
 	if path == "" {
 		return errors.NewInvalidRequest("path is required")
 	}
@@ -38,10 +43,10 @@ func ValidatePath(path string, mode PathCheckMode, cfg *config.Config) error {
 		return errors.NewInvalidRequest("path must not contain directory traversal (..)")
 	}
 
-	// Require .jsonl extension
+	// Require the expected extension
 	cleaned := filepath.Clean(path)
-	if filepath.Ext(cleaned) != ".jsonl" {
-		return errors.NewInvalidRequest("path must have .jsonl extension")
+	if filepath.Ext(cleaned) != ext {
+		return errors.NewInvalidRequest(fmt.Sprintf("path must have %s extension", ext))
 	}
 
 	absPath, err := filepath.Abs(cleaned)
@@ -110,13 +115,11 @@ func ValidatePath(path string, mode PathCheckMode, cfg *config.Config) error {
 // getAllowedDirs returns the list of allowed directories (absolute, cleaned).
 // If an allowed directory exists, it is resolved to catch symlinked allowed_paths entries.
 func getAllowedDirs(cfg *config.Config) ([]string, error) {
-	homeDir, err := os.UserHomeDir()
+	// Default: <data dir>/exports
+	defaultDir, err := DefaultExportsDir(cfg)
 	if err != nil {
-		return nil, errors.NewInternal(fmt.Errorf("failed to get home directory: %w", err))
+		return nil, err
 	}
-
-	// Default: ~/.moss/exports
-	defaultDir := filepath.Join(homeDir, ".moss", "exports")
 	dirs := []string{defaultDir}
 
 	// Add configured allowed paths (only absolute paths)
@@ -162,8 +165,12 @@ func isDirectlyInAllowedDir(parentDir string, allowedDirs []string) bool {
 	return false
 }
 
-// DefaultExportsDir returns the default exports directory (~/.moss/exports).
-func DefaultExportsDir() (string, error) {
+// DefaultExportsDir returns the default exports directory: exports under
+// cfg.DataDir, or ~/.moss/exports when no data directory was resolved.
+func DefaultExportsDir(cfg *config.Config) (string, error) {
+	if cfg != nil && cfg.DataDir != "" {
+		return filepath.Join(cfg.DataDir, "exports"), nil
+	}
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", errors.NewInternal(fmt.Errorf("failed to get home directory: %w", err))
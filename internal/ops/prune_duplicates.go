@@ -0,0 +1,154 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// PruneDuplicatesInput contains parameters for the PruneDuplicates operation.
+type PruneDuplicatesInput struct {
+	Workspace *string // optional filter by workspace; nil scans all workspaces
+	DryRun    bool    // report the groups without deleting anything
+}
+
+// DuplicateGroup is a set of unnamed capsules in one workspace with identical text.
+type DuplicateGroup struct {
+	Workspace     string   `json:"workspace"`
+	ContentSHA256 string   `json:"content_sha256"`
+	Keep          string   `json:"keep"`  // most recently updated, left active
+	Prune         []string `json:"prune"` // surplus copies, soft-deleted
+}
+
+// PruneDuplicatesOutput contains the result of the PruneDuplicates operation.
+type PruneDuplicatesOutput struct {
+	Pruned  int              `json:"pruned"` // in dry-run, how many would be pruned
+	DryRun  bool             `json:"dry_run,omitempty"`
+	Groups  []DuplicateGroup `json:"groups,omitempty"` // dry-run only
+	Message string           `json:"message"`
+}
+
+// PruneDuplicates soft-deletes surplus unnamed capsules whose text is identical
+// (same content_sha256) to another active unnamed capsule in the same workspace.
+// The most recently updated capsule of each group is kept. Named capsules are
+// never considered, so a deliberately named copy is always left alone.
+func PruneDuplicates(ctx context.Context, database *sql.DB, input PruneDuplicatesInput) (*PruneDuplicatesOutput, error) {
+	defer invalidateSearchCache()
+
+	var workspaceNorm *string
+	if input.Workspace != nil {
+		ws := capsule.Normalize(*input.Workspace)
+		if ws != "" {
+			workspaceNorm = &ws
+		}
+	}
+
+	if input.DryRun {
+		candidates, err := db.ListUnnamedDuplicates(ctx, database, workspaceNorm)
+		if err != nil {
+			return nil, err
+		}
+		groups := groupDuplicates(candidates)
+		pruned := countPrunable(groups)
+		return &PruneDuplicatesOutput{
+			Pruned:  pruned,
+			DryRun:  true,
+			Groups:  groups,
+			Message: formatPruneDuplicatesMessage(pruned, len(groups), true),
+		}, nil
+	}
+
+	// List and delete in one transaction so a concurrent update can't change
+	// which copy is the newest between the two steps.
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("prune_duplicates")
+		}
+		return nil, errors.NewInternal(err)
+	}
+	defer db.Rollback(tx) //nolint:errcheck
+
+	candidates, err := db.ListUnnamedDuplicates(ctx, tx, workspaceNorm)
+	if err != nil {
+		return nil, err
+	}
+	groups := groupDuplicates(candidates)
+
+	pruned := 0
+	for _, g := range groups {
+		for _, id := range g.Prune {
+			if err := db.SoftDelete(ctx, tx, id); err != nil {
+				return nil, err
+			}
+			pruned++
+		}
+	}
+
+	if err := db.Commit(tx); err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewCancelled("prune_duplicates")
+		}
+		return nil, errors.NewInternal(err)
+	}
+
+	return &PruneDuplicatesOutput{
+		Pruned:  pruned,
+		Message: formatPruneDuplicatesMessage(pruned, len(groups), false),
+	}, nil
+}
+
+// groupDuplicates folds candidates (ordered by workspace, checksum, newest first)
+// into groups, keeping the first capsule of each.
+func groupDuplicates(candidates []db.DuplicateCandidate) []DuplicateGroup {
+	var groups []DuplicateGroup
+	for i, c := range candidates {
+		if i == 0 || c.WorkspaceNorm != candidates[i-1].WorkspaceNorm || c.ContentSHA256 != candidates[i-1].ContentSHA256 {
+			groups = append(groups, DuplicateGroup{
+				Workspace:     c.WorkspaceRaw,
+				ContentSHA256: c.ContentSHA256,
+				Keep:          c.ID,
+				Prune:         []string{},
+			})
+			continue
+		}
+		g := &groups[len(groups)-1]
+		g.Prune = append(g.Prune, c.ID)
+	}
+	return groups
+}
+
+// countPrunable returns the number of surplus capsules across groups.
+func countPrunable(groups []DuplicateGroup) int {
+	n := 0
+	for _, g := range groups {
+		n += len(g.Prune)
+	}
+	return n
+}
+
+// formatPruneDuplicatesMessage creates a human-readable message for the prune result.
+func formatPruneDuplicatesMessage(count, groups int, dryRun bool) string {
+	if count == 0 {
+		return "No duplicate capsules found"
+	}
+
+	capsuleWord := "capsule"
+	if count > 1 {
+		capsuleWord = "capsules"
+	}
+	groupWord := "group"
+	if groups > 1 {
+		groupWord = "groups"
+	}
+
+	verb := "Soft-deleted"
+	if dryRun {
+		verb = "Would soft-delete"
+	}
+	return fmt.Sprintf("%s %d duplicate %s across %d %s", verb, count, capsuleWord, groups, groupWord)
+}
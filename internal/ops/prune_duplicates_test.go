@@ -0,0 +1,128 @@
+package ops
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestPruneDuplicates_RemovesOnlySurplusCopies(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	store := func(workspace string, name *string, text string) string {
+		t.Helper()
+		out, err := Store(ctx, database, cfg, StoreInput{Workspace: workspace, Name: name, CapsuleText: text, AllowThin: true})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		return out.ID
+	}
+
+	dup := "## Status\nSame text stored three times."
+	first := store("alpha", nil, dup)
+	second := store("alpha", nil, dup)
+	newest := store("alpha", nil, dup)
+	named := store("alpha", stringPtr("keep-me"), dup)
+	otherWorkspace := store("beta", nil, dup)
+	unique := store("alpha", nil, "## Status\nOnly one of these.")
+
+	// Dry run reports the group without deleting
+	preview, err := PruneDuplicates(ctx, database, PruneDuplicatesInput{DryRun: true})
+	if err != nil {
+		t.Fatalf("PruneDuplicates(dry run) failed: %v", err)
+	}
+	if preview.Pruned != 2 || !preview.DryRun {
+		t.Errorf("dry run Pruned = %d, DryRun = %v; want 2, true", preview.Pruned, preview.DryRun)
+	}
+	if len(preview.Groups) != 1 {
+		t.Fatalf("len(Groups) = %d, want 1", len(preview.Groups))
+	}
+	group := preview.Groups[0]
+	if group.Keep != newest {
+		t.Errorf("Keep = %s, want most recent %s", group.Keep, newest)
+	}
+	if !slices.Contains(group.Prune, first) || !slices.Contains(group.Prune, second) || len(group.Prune) != 2 {
+		t.Errorf("Prune = %v, want [%s %s]", group.Prune, first, second)
+	}
+	if _, err := Fetch(ctx, database, FetchInput{ID: first}); err != nil {
+		t.Errorf("dry run should not delete: %v", err)
+	}
+
+	output, err := PruneDuplicates(ctx, database, PruneDuplicatesInput{})
+	if err != nil {
+		t.Fatalf("PruneDuplicates failed: %v", err)
+	}
+	if output.Pruned != 2 {
+		t.Errorf("Pruned = %d, want 2", output.Pruned)
+	}
+	if output.Groups != nil {
+		t.Error("Groups should only be reported in dry run")
+	}
+
+	for _, id := range []string{first, second} {
+		if _, err := Fetch(ctx, database, FetchInput{ID: id}); !errors.Is(err, errors.ErrNotFound) {
+			t.Errorf("surplus duplicate %s should be deleted, got: %v", id, err)
+		}
+	}
+	for _, id := range []string{newest, named, otherWorkspace, unique} {
+		if _, err := Fetch(ctx, database, FetchInput{ID: id}); err != nil {
+			t.Errorf("capsule %s should be kept: %v", id, err)
+		}
+	}
+
+	// Nothing left to prune
+	again, err := PruneDuplicates(ctx, database, PruneDuplicatesInput{})
+	if err != nil {
+		t.Fatalf("PruneDuplicates failed: %v", err)
+	}
+	if again.Pruned != 0 {
+		t.Errorf("second run Pruned = %d, want 0", again.Pruned)
+	}
+}
+
+func TestPruneDuplicates_WorkspaceFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	text := "## Status\nRepeated."
+	for _, ws := range []string{"alpha", "alpha", "beta", "beta"} {
+		if _, err := Store(ctx, database, cfg, StoreInput{Workspace: ws, CapsuleText: text, AllowThin: true}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	output, err := PruneDuplicates(ctx, database, PruneDuplicatesInput{Workspace: stringPtr("Alpha")})
+	if err != nil {
+		t.Fatalf("PruneDuplicates failed: %v", err)
+	}
+	if output.Pruned != 1 {
+		t.Errorf("Pruned = %d, want 1 (alpha only)", output.Pruned)
+	}
+
+	rest, err := PruneDuplicates(ctx, database, PruneDuplicatesInput{DryRun: true})
+	if err != nil {
+		t.Fatalf("PruneDuplicates(dry run) failed: %v", err)
+	}
+	if rest.Pruned != 1 || len(rest.Groups) != 1 || rest.Groups[0].Workspace != "beta" {
+		t.Errorf("remaining = %+v, want one beta duplicate", rest)
+	}
+}
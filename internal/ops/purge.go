@@ -12,31 +12,37 @@ import (
 type PurgeInput struct {
 	Workspace     *string // optional filter by workspace
 	OlderThanDays *int    // optional, only purge if deleted_at < (now - N days)
+	Archive       bool    // from config.ArchiveOnPurge; move to archived_capsules instead of discarding
 }
 
 // PurgeOutput contains the result of the Purge operation.
 type PurgeOutput struct {
-	Purged  int    `json:"purged"`
-	Message string `json:"message"`
+	Purged   int    `json:"purged"`
+	Archived bool   `json:"archived,omitempty"` // purged capsules were moved to the archive
+	Message  string `json:"message"`
 }
 
-// Purge permanently deletes soft-deleted capsules.
+// Purge permanently deletes soft-deleted capsules from the live store.
+// With Archive, they are kept in archived_capsules (see ListArchived).
 func Purge(ctx context.Context, database *sql.DB, input PurgeInput) (*PurgeOutput, error) {
-	count, err := db.PurgeDeleted(ctx, database, input.Workspace, input.OlderThanDays)
+	defer invalidateSearchCache()
+
+	count, err := db.PurgeDeleted(ctx, database, input.Workspace, input.OlderThanDays, input.Archive)
 	if err != nil {
 		return nil, err
 	}
 
-	message := formatPurgeMessage(count, input.Workspace, input.OlderThanDays)
+	message := formatPurgeMessage(count, input.Workspace, input.OlderThanDays, input.Archive)
 
 	return &PurgeOutput{
-		Purged:  count,
-		Message: message,
+		Purged:   count,
+		Archived: input.Archive && count > 0,
+		Message:  message,
 	}, nil
 }
 
 // formatPurgeMessage creates a human-readable message for the purge result.
-func formatPurgeMessage(count int, workspace *string, olderThanDays *int) string {
+func formatPurgeMessage(count int, workspace *string, olderThanDays *int, archive bool) string {
 	if count == 0 {
 		return "No deleted capsules to purge"
 	}
@@ -47,6 +53,9 @@ func formatPurgeMessage(count int, workspace *string, olderThanDays *int) string
 	}
 
 	msg := fmt.Sprintf("Permanently deleted %d %s", count, capsuleWord)
+	if archive {
+		msg = fmt.Sprintf("Archived %d %s", count, capsuleWord)
+	}
 
 	if workspace != nil {
 		msg += fmt.Sprintf(" from workspace %q", *workspace)
@@ -0,0 +1,57 @@
+package ops
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// capsuleFileExts are the extensions accepted by ReadCapsuleFile.
+var capsuleFileExts = []string{".md", ".txt"}
+
+// ReadCapsuleFile reads capsule_text from a local file (CLI --file).
+// The path goes through the same safe-path checks as import (allowed directories,
+// no traversal, no symlinks) and must end in .md or .txt. Files larger than
+// maxBytes return FILE_TOO_LARGE. Surrounding whitespace is trimmed, as for stdin.
+func ReadCapsuleFile(path string, maxBytes int, cfg *config.Config) (string, error) {
+	ext := filepath.Ext(filepath.Clean(path))
+	if !slices.Contains(capsuleFileExts, ext) {
+		return "", errors.NewInvalidRequest(
+			fmt.Sprintf("file must have one of these extensions: %s", strings.Join(capsuleFileExts, ", ")))
+	}
+	if err := ValidatePathExt(path, ext, PathCheckRead, cfg); err != nil {
+		return "", err
+	}
+
+	file, err := openFileNoFollowRead(path)
+	if err != nil {
+		if _, ok := err.(*errors.MossError); ok {
+			return "", err
+		}
+		return "", errors.NewInternal(fmt.Errorf("failed to open capsule file: %w", err))
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", errors.NewInternal(fmt.Errorf("failed to stat capsule file: %w", err))
+	}
+	if info.Size() > int64(maxBytes) {
+		return "", errors.NewFileTooLarge(int64(maxBytes), info.Size())
+	}
+
+	// Bound the read too, in case the file grew after Stat
+	data, err := io.ReadAll(io.LimitReader(file, int64(maxBytes)+1))
+	if err != nil {
+		return "", errors.NewInternal(fmt.Errorf("failed to read capsule file: %w", err))
+	}
+	if len(data) > maxBytes {
+		return "", errors.NewFileTooLarge(int64(maxBytes), int64(len(data)))
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,66 @@
+package ops
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestReadCapsuleFile(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{AllowedPaths: []string{dir}}
+
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		return path
+	}
+
+	t.Run("reads and trims", func(t *testing.T) {
+		path := write("ok.md", "\n  ## Objective\nShip it.\n\n")
+		text, err := ReadCapsuleFile(path, 1000, cfg)
+		if err != nil {
+			t.Fatalf("ReadCapsuleFile failed: %v", err)
+		}
+		if text != "## Objective\nShip it." {
+			t.Errorf("text = %q", text)
+		}
+	})
+
+	t.Run("wrong extension", func(t *testing.T) {
+		path := write("capsule.json", "{}")
+		if _, err := ReadCapsuleFile(path, 1000, cfg); !errors.Is(err, errors.ErrInvalidRequest) {
+			t.Errorf("error = %v, want INVALID_REQUEST", err)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		path := write("big.txt", strings.Repeat("x", 101))
+		if _, err := ReadCapsuleFile(path, 100, cfg); !errors.Is(err, errors.ErrFileTooLarge) {
+			t.Errorf("error = %v, want FILE_TOO_LARGE", err)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if _, err := ReadCapsuleFile(filepath.Join(dir, "missing.md"), 1000, cfg); !errors.Is(err, errors.ErrNotFound) {
+			t.Errorf("error = %v, want NOT_FOUND", err)
+		}
+	})
+
+	t.Run("outside allowed dirs", func(t *testing.T) {
+		other := filepath.Join(t.TempDir(), "elsewhere.md")
+		if err := os.WriteFile(other, []byte("text"), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		if _, err := ReadCapsuleFile(other, 1000, cfg); !errors.Is(err, errors.ErrInvalidRequest) {
+			t.Errorf("error = %v, want INVALID_REQUEST", err)
+		}
+	})
+}
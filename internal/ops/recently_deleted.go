@@ -0,0 +1,56 @@
+package ops
+
+import (
+	"context"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+)
+
+// RecentlyDeletedInput contains parameters for the RecentlyDeleted operation.
+type RecentlyDeletedInput struct {
+	Workspace *string // optional filter by workspace
+	Limit     int     // default: 20, max: 100
+}
+
+// RecentlyDeletedOutput contains the result of the RecentlyDeleted operation.
+type RecentlyDeletedOutput struct {
+	Items []SummaryItem `json:"items"`
+	Sort  string        `json:"sort"`
+}
+
+// RecentlyDeleted lists soft-deleted capsules, most recently deleted first, so
+// a mistaken delete can be found and restored. Purged capsules are gone and
+// never listed (see ListArchived for those kept by archive_on_purge).
+func RecentlyDeleted(ctx context.Context, database db.Querier, input RecentlyDeletedInput) (*RecentlyDeletedOutput, error) {
+	var workspaceNorm *string
+	if input.Workspace != nil {
+		ws := capsule.Normalize(*input.Workspace)
+		if ws != "" {
+			workspaceNorm = &ws
+		}
+	}
+
+	limit := input.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		limit = MaxListLimit
+	}
+
+	summaries, err := db.ListRecentlyDeleted(ctx, database, workspaceNorm, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]SummaryItem, 0, len(summaries))
+	for _, s := range summaries {
+		items = append(items, SummaryToItem(s))
+	}
+
+	return &RecentlyDeletedOutput{
+		Items: items,
+		Sort:  "deleted_at_desc",
+	}, nil
+}
@@ -0,0 +1,98 @@
+package ops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+)
+
+func TestRecentlyDeleted(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	cfg := config.DefaultConfig()
+
+	// Deleted in this order: a1 (oldest), b1, a2, a3 (newest); "live" stays active
+	ids := map[string]string{}
+	for _, c := range []struct{ workspace, name string }{
+		{"alpha", "a1"}, {"alpha", "a2"}, {"alpha", "a3"}, {"beta", "b1"}, {"alpha", "live"},
+	} {
+		out, err := Store(ctx, database, cfg, StoreInput{Workspace: c.workspace, Name: stringPtr(c.name), CapsuleText: validCapsuleText})
+		if err != nil {
+			t.Fatalf("Store %s failed: %v", c.name, err)
+		}
+		ids[c.name] = out.ID
+	}
+	for i, name := range []string{"a1", "b1", "a2", "a3"} {
+		if _, err := Delete(ctx, database, DeleteInput{ID: ids[name]}); err != nil {
+			t.Fatalf("Delete %s failed: %v", name, err)
+		}
+		if _, err := database.Exec("UPDATE capsules SET deleted_at = ? WHERE id = ?", 1000+i, ids[name]); err != nil {
+			t.Fatalf("set deleted_at failed: %v", err)
+		}
+	}
+
+	names := func(items []SummaryItem) []string {
+		var got []string
+		for _, item := range items {
+			got = append(got, *item.Name)
+		}
+		return got
+	}
+	assertNames := func(t *testing.T, got, want []string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("names = %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("names = %v, want %v", got, want)
+			}
+		}
+	}
+
+	out, err := RecentlyDeleted(ctx, database, RecentlyDeletedInput{})
+	if err != nil {
+		t.Fatalf("RecentlyDeleted failed: %v", err)
+	}
+	assertNames(t, names(out.Items), []string{"a3", "a2", "b1", "a1"})
+	if out.Sort != "deleted_at_desc" {
+		t.Errorf("Sort = %q, want deleted_at_desc", out.Sort)
+	}
+	if out.Items[0].DeletedAt == nil || *out.Items[0].DeletedAt != 1003 {
+		t.Errorf("DeletedAt = %v, want 1003", out.Items[0].DeletedAt)
+	}
+
+	// Scoped by workspace (normalized), and limited
+	out, err = RecentlyDeleted(ctx, database, RecentlyDeletedInput{Workspace: stringPtr(" Alpha "), Limit: 2})
+	if err != nil {
+		t.Fatalf("RecentlyDeleted failed: %v", err)
+	}
+	assertNames(t, names(out.Items), []string{"a3", "a2"})
+
+	// Purged capsules are gone
+	if _, err := Purge(ctx, database, PurgeInput{Workspace: stringPtr("beta")}); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	out, err = RecentlyDeleted(ctx, database, RecentlyDeletedInput{})
+	if err != nil {
+		t.Fatalf("RecentlyDeleted failed: %v", err)
+	}
+	assertNames(t, names(out.Items), []string{"a3", "a2", "a1"})
+
+	// Nothing deleted: an empty list rather than null
+	out, err = RecentlyDeleted(ctx, database, RecentlyDeletedInput{Workspace: stringPtr("beta")})
+	if err != nil {
+		t.Fatalf("RecentlyDeleted failed: %v", err)
+	}
+	if out.Items == nil || len(out.Items) != 0 {
+		t.Errorf("Items = %#v, want empty", out.Items)
+	}
+}
@@ -0,0 +1,102 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// ReindexInput contains parameters for the Reindex operation.
+type ReindexInput struct {
+	CheckOnly bool // report consistency without rebuilding
+	Force     bool // rebuild even if the index looks consistent (fixes stale text)
+}
+
+// FTSCheck is the JSON form of a full-text index consistency report.
+type FTSCheck struct {
+	Consistent     bool    `json:"consistent"`
+	CapsuleRows    int     `json:"capsule_rows"`
+	IndexedRows    int     `json:"indexed_rows"`
+	Missing        int     `json:"missing"`
+	Orphaned       int     `json:"orphaned"`
+	MissingRowIDs  []int64 `json:"missing_rowids,omitempty"`
+	OrphanedRowIDs []int64 `json:"orphaned_rowids,omitempty"`
+}
+
+// ReindexOutput contains the result of the Reindex operation.
+type ReindexOutput struct {
+	Before  FTSCheck  `json:"before"`
+	After   *FTSCheck `json:"after,omitempty"` // set when rebuilt
+	Rebuilt bool      `json:"rebuilt"`
+	Message string    `json:"message"`
+}
+
+// Reindex verifies the full-text search index and rebuilds it when it has
+// drifted from the capsules table (or when forced).
+func Reindex(ctx context.Context, database *sql.DB, input ReindexInput) (*ReindexOutput, error) {
+	defer invalidateSearchCache()
+
+	if input.CheckOnly && input.Force {
+		return nil, errors.NewInvalidRequest("check_only and force cannot be combined")
+	}
+
+	before, err := db.VerifyFTSConsistency(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ReindexOutput{Before: toFTSCheck(before)}
+
+	if input.CheckOnly || (before.Consistent() && !input.Force) {
+		output.Message = formatReindexMessage(before, nil)
+		return output, nil
+	}
+
+	if err := db.RebuildFTS(ctx, database); err != nil {
+		return nil, err
+	}
+
+	after, err := db.VerifyFTSConsistency(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	afterCheck := toFTSCheck(after)
+	output.After = &afterCheck
+	output.Rebuilt = true
+	output.Message = formatReindexMessage(before, after)
+
+	return output, nil
+}
+
+// toFTSCheck converts a db report to its JSON form.
+func toFTSCheck(r *db.FTSReport) FTSCheck {
+	return FTSCheck{
+		Consistent:     r.Consistent(),
+		CapsuleRows:    r.CapsuleRows,
+		IndexedRows:    r.IndexedRows,
+		Missing:        r.Missing,
+		Orphaned:       r.Orphaned,
+		MissingRowIDs:  r.MissingSample,
+		OrphanedRowIDs: r.OrphanedSample,
+	}
+}
+
+// formatReindexMessage creates a human-readable message for the reindex result.
+// after is nil when no rebuild ran.
+func formatReindexMessage(before, after *db.FTSReport) string {
+	if after == nil {
+		if before.Consistent() {
+			return "Search index is consistent"
+		}
+		return fmt.Sprintf("Search index is out of sync (%d missing, %d orphaned)", before.Missing, before.Orphaned)
+	}
+
+	msg := fmt.Sprintf("Rebuilt search index from %d capsules", after.CapsuleRows)
+	if !before.Consistent() {
+		msg += fmt.Sprintf(" (was %d missing, %d orphaned)", before.Missing, before.Orphaned)
+	}
+	return msg
+}
@@ -0,0 +1,124 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// dropFTSRow removes a capsule's document from capsules_fts, simulating a failed trigger.
+func dropFTSRow(t *testing.T, database *sql.DB, id string) {
+	t.Helper()
+	_, err := database.Exec(`
+		INSERT INTO capsules_fts(capsules_fts, rowid, capsule_text, title, name_raw)
+		SELECT 'delete', rowid, capsule_text, title, name_raw FROM capsules WHERE id = ?`, id)
+	if err != nil {
+		t.Fatalf("failed to drop FTS row: %v", err)
+	}
+}
+
+func TestReindex_RecoversDesyncedIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	var lostID string
+	for _, name := range []string{"kept", "lost"} {
+		out, err := Store(ctx, database, cfg, StoreInput{
+			Workspace:   "default",
+			Name:        stringPtr(name),
+			CapsuleText: validCapsuleText + "\nquokka",
+		})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		if name == "lost" {
+			lostID = out.ID
+		}
+	}
+
+	// Healthy index: nothing to do
+	healthy, err := Reindex(ctx, database, ReindexInput{})
+	if err != nil {
+		t.Fatalf("Reindex failed: %v", err)
+	}
+	if !healthy.Before.Consistent || healthy.Rebuilt || healthy.After != nil {
+		t.Fatalf("healthy index: got %+v, want consistent and not rebuilt", healthy)
+	}
+
+	dropFTSRow(t, database, lostID)
+
+	search, err := Search(ctx, database, SearchInput{Query: "quokka"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if search.Pagination.Total != 1 {
+		t.Fatalf("desynced search total = %d, want 1", search.Pagination.Total)
+	}
+
+	// check_only detects without rebuilding
+	check, err := Reindex(ctx, database, ReindexInput{CheckOnly: true})
+	if err != nil {
+		t.Fatalf("Reindex check_only failed: %v", err)
+	}
+	if check.Before.Consistent || check.Rebuilt {
+		t.Fatalf("check_only: got consistent=%v rebuilt=%v, want false/false", check.Before.Consistent, check.Rebuilt)
+	}
+	if check.Before.Missing != 1 || check.Before.CapsuleRows != 2 || check.Before.IndexedRows != 1 {
+		t.Errorf("check_only report = %+v, want 2 rows / 1 indexed / 1 missing", check.Before)
+	}
+	if len(check.Before.MissingRowIDs) != 1 {
+		t.Errorf("MissingRowIDs = %v, want 1 entry", check.Before.MissingRowIDs)
+	}
+
+	// Default mode rebuilds
+	result, err := Reindex(ctx, database, ReindexInput{})
+	if err != nil {
+		t.Fatalf("Reindex failed: %v", err)
+	}
+	if !result.Rebuilt || result.After == nil || !result.After.Consistent {
+		t.Fatalf("rebuild: got %+v, want rebuilt and consistent after", result)
+	}
+
+	search, err = Search(ctx, database, SearchInput{Query: "quokka"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if search.Pagination.Total != 2 {
+		t.Errorf("recovered search total = %d, want 2", search.Pagination.Total)
+	}
+}
+
+func TestReindex_Force(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+
+	result, err := Reindex(ctx, database, ReindexInput{Force: true})
+	if err != nil {
+		t.Fatalf("Reindex failed: %v", err)
+	}
+	if !result.Rebuilt || result.After == nil {
+		t.Errorf("force: got rebuilt=%v after=%v, want rebuilt", result.Rebuilt, result.After)
+	}
+
+	_, err = Reindex(ctx, database, ReindexInput{CheckOnly: true, Force: true})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("check_only+force: err = %v, want INVALID_REQUEST", err)
+	}
+}
@@ -0,0 +1,158 @@
+package ops
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hpungsan/moss/internal/db"
+)
+
+// Related limits
+const (
+	DefaultRelatedLimit = 10
+	MaxRelatedLimit     = 50
+	relatedTermCount    = 12 // significant terms OR'd into the FTS query
+	relatedTitleWeight  = 3  // a title/name occurrence counts as this many body occurrences
+	relatedMinTermChars = 3
+)
+
+// relatedStopwords are common words that carry no topic signal.
+var relatedStopwords = map[string]bool{
+	"about": true, "after": true, "again": true, "all": true, "also": true, "and": true,
+	"any": true, "are": true, "because": true, "been": true, "before": true, "being": true,
+	"but": true, "can": true, "could": true, "did": true, "does": true, "done": true,
+	"each": true, "for": true, "from": true, "had": true, "has": true, "have": true,
+	"how": true, "into": true, "its": true, "just": true, "more": true, "most": true,
+	"near": true, "not": true, "now": true, "only": true, "other": true, "our": true,
+	"out": true, "over": true, "should": true, "some": true, "such": true, "than": true,
+	"that": true, "the": true, "their": true, "them": true, "then": true, "there": true,
+	"these": true, "they": true, "this": true, "those": true, "through": true, "use": true,
+	"used": true, "uses": true, "using": true, "very": true, "was": true, "were": true,
+	"what": true, "when": true, "where": true, "which": true, "while": true, "who": true,
+	"why": true, "will": true, "with": true, "would": true, "yet": true, "you": true,
+	"your": true,
+}
+
+// RelatedInput contains parameters for the Related operation.
+type RelatedInput struct {
+	// Addressing (source capsule)
+	ID        string
+	Workspace string
+	Name      string
+
+	Limit         int  // default: 10, max: 50
+	SameWorkspace bool // only consider capsules in the source's workspace
+}
+
+// RelatedOutput contains the result of the Related operation.
+type RelatedOutput struct {
+	ID    string             `json:"id"`    // source capsule
+	Terms []string           `json:"terms"` // significant terms the match was based on
+	Items []SearchResultItem `json:"items"` // ranked by relevance, source excluded
+}
+
+// Related finds active capsules covering similar topics to the addressed one.
+// It extracts the source's most significant title/body terms, ORs them into an
+// FTS query, and returns ranked summaries excluding the source itself.
+func Related(ctx context.Context, database *sql.DB, input RelatedInput) (*RelatedOutput, error) {
+	addr, err := ValidateAddress(input.ID, input.Workspace, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := resolveAddress(ctx, database, addr, false)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := input.Limit
+	if limit <= 0 {
+		limit = DefaultRelatedLimit
+	}
+	if limit > MaxRelatedLimit {
+		limit = MaxRelatedLimit
+	}
+
+	var heading []string
+	if c.Title != nil {
+		heading = append(heading, *c.Title)
+	}
+	if c.NameRaw != nil {
+		heading = append(heading, *c.NameRaw)
+	}
+	terms := relatedTerms(strings.Join(heading, " "), c.CapsuleText)
+
+	output := &RelatedOutput{ID: c.ID, Terms: terms, Items: []SearchResultItem{}}
+	if len(terms) == 0 {
+		return output, nil
+	}
+
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = `"` + t + `"`
+	}
+	filters := db.SearchFilters{ExcludeID: c.ID}
+	if input.SameWorkspace {
+		filters.Workspaces = []string{c.WorkspaceNorm}
+	}
+
+	results, _, err := db.SearchFullText(ctx, database, strings.Join(quoted, " OR "), filters, limit, 0, false, DefaultSnippetTokens, db.SearchOrderRelevance, nil)
+	if err != nil {
+		return nil, err
+	}
+	output.Items = toSearchResultItems(results, true, nil)
+
+	return output, nil
+}
+
+// relatedTerms returns the top terms by weighted frequency. Markdown heading
+// lines are skipped so the six required section names don't match everything.
+// Ties break alphabetically so the query is deterministic.
+func relatedTerms(heading, body string) []string {
+	scores := make(map[string]int)
+	countTerms(scores, heading, relatedTitleWeight)
+	for line := range strings.Lines(body) {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		countTerms(scores, line, 1)
+	}
+
+	terms := make([]string, 0, len(scores))
+	for t := range scores {
+		terms = append(terms, t)
+	}
+	slices.SortFunc(terms, func(a, b string) int {
+		if c := cmp.Compare(scores[b], scores[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	if len(terms) > relatedTermCount {
+		terms = terms[:relatedTermCount]
+	}
+	return terms
+}
+
+// countTerms adds weight to scores for each significant word in s.
+// Words split on anything but letters and digits, matching the unicode61 tokenizer.
+func countTerms(scores map[string]int, s string, weight int) {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		if utf8.RuneCountInString(w) < relatedMinTermChars || relatedStopwords[w] || isDigits(w) {
+			continue
+		}
+		scores[w] += weight
+	}
+}
+
+func isDigits(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) }) == -1
+}
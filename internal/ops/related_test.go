@@ -0,0 +1,134 @@
+package ops
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestRelated_RanksMostSimilarFirst(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	capsules := []struct{ name, text string }{
+		{"oauth-design", "## Objective\nDesign OAuth token refresh. Refresh tokens rotate on every refresh; access tokens expire after an hour.\n## Decisions\nStore refresh tokens hashed."},
+		{"oauth-impl", "## Objective\nImplement OAuth token refresh endpoint. Rotate refresh tokens and reject reused tokens.\n## Decisions\nHash refresh tokens at rest."},
+		{"session-cookies", "## Objective\nSession cookies for the web app. Access checks happen in middleware."},
+		{"billing", "## Objective\nInvoice generation and payment reconciliation for monthly billing."},
+	}
+	ids := map[string]string{}
+	for _, c := range capsules {
+		out, err := Store(ctx, database, cfg, StoreInput{Name: stringPtr(c.name), CapsuleText: c.text, AllowThin: true})
+		if err != nil {
+			t.Fatalf("Store(%s) failed: %v", c.name, err)
+		}
+		ids[c.name] = out.ID
+	}
+
+	output, err := Related(ctx, database, RelatedInput{Workspace: "default", Name: "oauth-design"})
+	if err != nil {
+		t.Fatalf("Related failed: %v", err)
+	}
+
+	if output.ID != ids["oauth-design"] {
+		t.Errorf("ID = %s, want source %s", output.ID, ids["oauth-design"])
+	}
+	if !slices.Contains(output.Terms, "refresh") || !slices.Contains(output.Terms, "tokens") {
+		t.Errorf("Terms = %v, want refresh and tokens", output.Terms)
+	}
+	if slices.Contains(output.Terms, "objective") || slices.Contains(output.Terms, "the") {
+		t.Errorf("Terms = %v, should skip section headings and stopwords", output.Terms)
+	}
+	if len(output.Items) == 0 {
+		t.Fatal("expected related capsules")
+	}
+	if output.Items[0].ID != ids["oauth-impl"] {
+		t.Errorf("Items[0].ID = %s, want oauth-impl %s", output.Items[0].ID, ids["oauth-impl"])
+	}
+	for _, item := range output.Items {
+		if item.ID == ids["oauth-design"] {
+			t.Error("source capsule should be excluded from results")
+		}
+		if item.ID == ids["billing"] {
+			t.Error("unrelated capsule should not match")
+		}
+	}
+}
+
+func TestRelated_SameWorkspaceAndLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	text := "## Objective\nMigrate the queue workers to the new scheduler."
+	for _, ws := range []string{"alpha", "alpha", "alpha", "beta"} {
+		if _, err := Store(ctx, database, cfg, StoreInput{Workspace: ws, CapsuleText: text, AllowThin: true}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+	source, err := Store(ctx, database, cfg, StoreInput{Workspace: "alpha", Name: stringPtr("src"), CapsuleText: text, AllowThin: true})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	all, err := Related(ctx, database, RelatedInput{ID: source.ID})
+	if err != nil {
+		t.Fatalf("Related failed: %v", err)
+	}
+	if len(all.Items) != 4 {
+		t.Errorf("len(Items) = %d, want 4 across workspaces", len(all.Items))
+	}
+
+	same, err := Related(ctx, database, RelatedInput{ID: source.ID, SameWorkspace: true, Limit: 2})
+	if err != nil {
+		t.Fatalf("Related failed: %v", err)
+	}
+	if len(same.Items) != 2 {
+		t.Fatalf("len(Items) = %d, want 2 (limit)", len(same.Items))
+	}
+	for _, item := range same.Items {
+		if item.Workspace != "alpha" {
+			t.Errorf("item workspace = %q, want alpha", item.Workspace)
+		}
+	}
+}
+
+func TestRelated_NotFound(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	_, err = Related(context.Background(), database, RelatedInput{Workspace: "default", Name: "missing"})
+	if !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("error = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestRelatedTerms(t *testing.T) {
+	terms := relatedTerms("Cache Layer", "## Objective\nThe cache layer uses Redis 7.\nRedis eviction: LRU. Redis cluster.\n# Heading words ignored")
+
+	want := []string{"cache", "layer", "redis", "cluster", "eviction", "lru"}
+	if !slices.Equal(terms, want) {
+		t.Errorf("relatedTerms = %v, want %v", terms, want)
+	}
+}
@@ -0,0 +1,83 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// RenameTagInput contains parameters for the RenameTag operation.
+type RenameTagInput struct {
+	OldTag        string
+	NewTag        string
+	Workspace     *string // optional scope
+	NormalizeTags bool    // from config.NormalizeTags; normalizes NewTag only, so old variants can be folded
+}
+
+// RenameTagOutput contains the result of the RenameTag operation.
+type RenameTagOutput struct {
+	Renamed int    `json:"renamed"`
+	Message string `json:"message"`
+}
+
+// RenameTag replaces OldTag with NewTag on all active capsules carrying it.
+// If a capsule already has NewTag, the tags are merged (no duplicate).
+func RenameTag(ctx context.Context, database *sql.DB, input RenameTagInput) (*RenameTagOutput, error) {
+	defer invalidateSearchCache()
+
+	oldTag := strings.TrimSpace(input.OldTag)
+	newTag := strings.TrimSpace(input.NewTag)
+	if input.NormalizeTags {
+		newTag = capsule.Normalize(newTag)
+	}
+	if oldTag == "" {
+		return nil, errors.NewInvalidRequest("old_tag is required")
+	}
+	if newTag == "" {
+		return nil, errors.NewInvalidRequest("new_tag is required")
+	}
+	if oldTag == newTag {
+		return nil, errors.NewInvalidRequest("old_tag and new_tag must differ")
+	}
+
+	var workspace *string
+	if input.Workspace != nil {
+		ws := capsule.Normalize(*input.Workspace)
+		if ws != "" {
+			workspace = &ws
+		}
+	}
+
+	renamed, err := db.RenameTag(ctx, database, oldTag, newTag, workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RenameTagOutput{
+		Renamed: renamed,
+		Message: formatRenameTagMessage(renamed, oldTag, newTag, workspace),
+	}, nil
+}
+
+// formatRenameTagMessage creates a human-readable message for the rename result.
+func formatRenameTagMessage(count int, oldTag, newTag string, workspace *string) string {
+	if count == 0 {
+		return fmt.Sprintf("No active capsules tagged %q", oldTag)
+	}
+
+	capsuleWord := "capsule"
+	if count > 1 {
+		capsuleWord = "capsules"
+	}
+
+	msg := fmt.Sprintf("Renamed tag %q to %q on %d %s", oldTag, newTag, count, capsuleWord)
+	if workspace != nil {
+		msg += fmt.Sprintf(" in workspace=%q", *workspace)
+	}
+	return msg
+}
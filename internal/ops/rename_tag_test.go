@@ -0,0 +1,161 @@
+package ops
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func TestRenameTag_MergeIntoExisting(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	both, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws", CapsuleText: validCapsuleText, Tags: []string{"auth", "authn", "api"}})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	oldOnly, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws", CapsuleText: validCapsuleText, Tags: []string{"authn"}})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	untouched, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws", CapsuleText: validCapsuleText, Tags: []string{"api"}})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := RenameTag(ctx, database, RenameTagInput{OldTag: " authn ", NewTag: "auth"})
+	if err != nil {
+		t.Fatalf("RenameTag failed: %v", err)
+	}
+	if output.Renamed != 2 {
+		t.Errorf("Renamed = %d, want 2", output.Renamed)
+	}
+
+	cases := []struct {
+		id   string
+		want []string
+	}{
+		{both.ID, []string{"auth", "api"}},
+		{oldOnly.ID, []string{"auth"}},
+		{untouched.ID, []string{"api"}},
+	}
+	for _, tc := range cases {
+		c, err := db.GetByID(ctx, database, tc.id, false)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if !reflect.DeepEqual(c.Tags, tc.want) {
+			t.Errorf("capsule %s tags = %v, want %v", tc.id, c.Tags, tc.want)
+		}
+	}
+}
+
+func TestRenameTag_FreshTagScopedToWorkspace(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	in, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws1", CapsuleText: validCapsuleText, Tags: []string{"draft", "api"}})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	out, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws2", CapsuleText: validCapsuleText, Tags: []string{"draft"}})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := RenameTag(ctx, database, RenameTagInput{OldTag: "draft", NewTag: "wip", Workspace: stringPtr("WS1")})
+	if err != nil {
+		t.Fatalf("RenameTag failed: %v", err)
+	}
+	if output.Renamed != 1 {
+		t.Errorf("Renamed = %d, want 1", output.Renamed)
+	}
+
+	c, err := db.GetByID(ctx, database, in.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if want := []string{"wip", "api"}; !reflect.DeepEqual(c.Tags, want) {
+		t.Errorf("ws1 tags = %v, want %v", c.Tags, want)
+	}
+
+	c, err = db.GetByID(ctx, database, out.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if want := []string{"draft"}; !reflect.DeepEqual(c.Tags, want) {
+		t.Errorf("ws2 tags = %v, want %v (outside scope)", c.Tags, want)
+	}
+}
+
+func TestRenameTag_InvalidInput(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+
+	for _, input := range []RenameTagInput{
+		{OldTag: "", NewTag: "x"},
+		{OldTag: "x", NewTag: "  "},
+		{OldTag: "same", NewTag: " same "},
+	} {
+		if _, err := RenameTag(ctx, database, input); !errors.Is(err, errors.ErrInvalidRequest) {
+			t.Errorf("RenameTag(%+v): expected ErrInvalidRequest, got: %v", input, err)
+		}
+	}
+}
+
+func TestRenameTag_NormalizeTagsFoldsLegacyVariant(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+
+	// Stored before normalize_tags was turned on
+	legacy, err := Store(ctx, database, config.DefaultConfig(), StoreInput{Workspace: "ws", CapsuleText: validCapsuleText, Tags: []string{"Security"}})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := RenameTag(ctx, database, RenameTagInput{OldTag: "Security", NewTag: "SECURITY", NormalizeTags: true})
+	if err != nil {
+		t.Fatalf("RenameTag failed: %v", err)
+	}
+	if output.Renamed != 1 {
+		t.Errorf("Renamed = %d, want 1", output.Renamed)
+	}
+
+	c, err := db.GetByID(ctx, database, legacy.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if !reflect.DeepEqual(c.Tags, []string{"security"}) {
+		t.Errorf("tags = %v, want [security]", c.Tags)
+	}
+}
@@ -3,9 +3,13 @@ package ops
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"fmt"
 	"html"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/hpungsan/moss/internal/capsule"
@@ -15,23 +19,56 @@ import (
 
 // Search limits
 const (
-	DefaultSearchLimit = 20
-	MaxSearchLimit     = 100
-	MaxQueryLength     = db.MaxSearchQueryChars
-	MaxSnippetChars    = 300
+	DefaultSearchLimit  = 20
+	MaxSearchLimit      = 100
+	MaxQueryLength      = db.MaxSearchQueryChars
+	MaxSnippetChars     = 300
+	MaxSearchWorkspaces = 20
+	MaxSearchTags       = 20
+
+	// snippet() token window around each match
+	DefaultSnippetTokens = 64
+	MinSnippetTokens     = 8
+	MaxSnippetTokens     = 128
+)
+
+// SnippetFormat is an encoding of a match snippet.
+type SnippetFormat string
+
+const (
+	SnippetFormatHTML     SnippetFormat = "html"     // escaped, <b> highlights (same as snippet)
+	SnippetFormatPlain    SnippetFormat = "plain"    // unescaped text, no highlights
+	SnippetFormatMarkdown SnippetFormat = "markdown" // unescaped text, **bold** highlights, markdown specials escaped
 )
 
 // SearchInput contains parameters for the Search operation.
 type SearchInput struct {
-	Query          string  // required
-	Workspace      *string // optional filter
-	Tag            *string // optional filter
-	RunID          *string // optional filter
-	Phase          *string // optional filter
-	Role           *string // optional filter
-	Limit          int     // default: 20, max: 100
-	Offset         int     // default: 0
-	IncludeDeleted bool
+	Query              string   // required
+	Workspace          *string  // optional filter
+	Workspaces         []string // optional filter: match any (combined with Workspace), max 20
+	Tag                *string  // optional filter
+	Tags               []string // optional filter matched per TagMatch (combined with Tag), max 20
+	TagMatch           string   // "any" (default) or "all"
+	NamePrefix         *string  // optional filter
+	UpdatedAfter       *int64   // optional filter: updated_at >= this Unix time
+	UpdatedBefore      *int64   // optional filter: updated_at < this Unix time
+	RunID              *string  // optional filter
+	Phase              *string  // optional filter
+	Role               *string  // optional filter
+	SourceType         *string  // optional filter
+	Limit              int      // default: 20, max: 100
+	Offset             int      // default: 0
+	Sort               string   // "relevance" (default) or "updated_at"
+	Cursor             *string  // next_cursor from a previous page; requires Sort "updated_at"
+	IncludeDeleted     bool
+	WithSnippet        *bool    // default: true; false skips snippet() for ID/title pickers
+	SnippetTokens      int      // tokens of context per snippet; 0 = 64, else 8-128
+	SnippetFormats     []string // optional: also return snippets in these formats (html, plain, markdown)
+	Facets             bool     // also return per-workspace/phase/tag hit counts
+	GroupByWorkspace   bool     // also bucket the page's items by workspace
+	MinTermLen         int      // from config.MinSearchTermLen; <= 1 means no restriction
+	NormalizeTags      bool     // from config.NormalizeTags; normalizes the tag filter
+	EmptyQueryListsAll bool     // from config.EmptyQueryListsAll; empty query lists by recency instead of failing
 }
 
 // SearchResultItem wraps a SummaryItem with a match snippet.
@@ -40,99 +77,543 @@ type SearchResultItem struct {
 	// Snippet is HTML-safe: user-controlled content is escaped; only <b>...</b>
 	// highlight tags are present.
 	Snippet string `json:"snippet"` // Match context (~300 chars max, <b> highlights)
+
+	// Snippets holds the same snippet in each requested SnippetFormat, keyed by
+	// format name. Only set when SnippetFormats was given.
+	Snippets map[SnippetFormat]string `json:"snippets,omitempty"`
 }
 
 // SearchOutput contains the result of the Search operation.
 type SearchOutput struct {
 	Items      []SearchResultItem `json:"items"`
 	Pagination Pagination         `json:"pagination"`
-	Sort       string             `json:"sort"` // "relevance"
+	Sort       string             `json:"sort"`                  // "relevance" or "updated_at"
+	NextCursor string             `json:"next_cursor,omitempty"` // updated_at sort only, when has_more
+	Facets     *SearchFacets      `json:"facets,omitempty"`
+	Groups     []SearchGroup      `json:"groups,omitempty"`
+}
+
+// SearchGroup buckets a page's search results by workspace.
+// Groups are ordered by their best-ranked hit; items keep relevance order.
+type SearchGroup struct {
+	Workspace string             `json:"workspace"` // normalized
+	Count     int                `json:"count"`
+	Items     []SearchResultItem `json:"items"`
+}
+
+// SearchFacets contains hit counts over all matching capsules (not just the page).
+type SearchFacets struct {
+	Workspaces map[string]int `json:"workspaces"` // keyed by normalized workspace
+	Phases     map[string]int `json:"phases"`
+	Tags       map[string]int `json:"tags"`
 }
 
 // Search performs full-text search across capsules.
-// Results are ranked by relevance (BM25) with title matches weighted 5x higher.
+// Results are ranked by relevance (BM25) with title matches weighted 5x higher,
+// or ordered by updated_at with Sort "updated_at". The updated_at order supports
+// keyset paging via Cursor, which stays stable while new capsules are written.
+// With EmptyQueryListsAll, an empty query lists every capsule matching the
+// filters instead (see searchListAll).
 func Search(ctx context.Context, database *sql.DB, input SearchInput) (*SearchOutput, error) {
+	plan, err := planSearch(input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply limit defaults and bounds
+	limit := input.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	// Ensure offset is non-negative
+	offset := max(input.Offset, 0)
+
+	if plan.query == "" {
+		return searchListAll(ctx, database, input, plan, limit, offset)
+	}
+
+	var after *db.SearchCursor
+	if input.Cursor != nil {
+		if plan.order != db.SearchOrderUpdated {
+			return nil, errors.NewInvalidRequest("cursor requires sort:\"updated_at\"; relevance order has no stable cursor")
+		}
+		if offset > 0 {
+			return nil, errors.NewInvalidRequest("cursor cannot be combined with offset")
+		}
+		after, err = decodeSearchCursor(*input.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Query database. In updated_at order, fetch one extra row: with a cursor,
+	// offset arithmetic against total no longer says whether more rows follow.
+	fetchLimit := limit
+	if plan.order == db.SearchOrderUpdated {
+		fetchLimit = limit + 1
+	}
+	results, total, err := cachedSearchFullText(ctx, database, searchCacheKey{
+		Query:          plan.query,
+		Filters:        plan.filters,
+		Limit:          fetchLimit,
+		Offset:         offset,
+		IncludeDeleted: input.IncludeDeleted,
+		SnippetTokens:  plan.snippetTokens,
+		Order:          plan.order,
+		After:          after,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate has_more
+	hasMore := offset+len(results) < total
+	if plan.order == db.SearchOrderUpdated {
+		hasMore = len(results) > limit
+		results = results[:min(len(results), limit)]
+	}
+
+	items := toSearchResultItems(results, plan.withSnippet, plan.snippetFormats)
+
+	output := &SearchOutput{
+		Items: items,
+		Pagination: Pagination{
+			Limit:   limit,
+			Offset:  offset,
+			HasMore: hasMore,
+			Total:   total,
+		},
+		Sort: string(plan.order),
+	}
+	if plan.order == db.SearchOrderUpdated && hasMore {
+		last := items[len(items)-1]
+		output.NextCursor = encodeSearchCursor(db.SearchCursor{UpdatedAt: last.UpdatedAt, ID: last.ID})
+	}
+
+	if input.GroupByWorkspace {
+		output.Groups = groupSearchResults(items)
+	}
+
+	if input.Facets {
+		facets, err := db.SearchFacetCounts(ctx, database, plan.query, plan.filters, input.IncludeDeleted)
+		if err != nil {
+			return nil, err
+		}
+		output.Facets = &SearchFacets{
+			Workspaces: facets.Workspaces,
+			Phases:     facets.Phases,
+			Tags:       facets.Tags,
+		}
+	}
+
+	return output, nil
+}
+
+// SearchRun searches one orchestration run's capsules, newest first, for
+// rebuilding chronological context when resuming a run. It is Search with
+// RunID set to runID and Sort "updated_at"; the rest of opts (filters, Limit,
+// Cursor, snippets, ...) applies as usual, and its Query, RunID, and Sort are
+// ignored.
+func SearchRun(ctx context.Context, database *sql.DB, runID, query string, opts SearchInput) (*SearchOutput, error) {
+	runID = strings.TrimSpace(runID)
+	if runID == "" {
+		return nil, errors.NewInvalidRequest("run_id is required")
+	}
+	opts.Query = query
+	opts.RunID = &runID
+	opts.Sort = string(db.SearchOrderUpdated)
+	return Search(ctx, database, opts)
+}
+
+// SearchStream runs the same search as Search but hands each result to emit as
+// it is read, instead of collecting a page, so large result sets never sit in
+// memory. Limit <= 0 streams every match (MaxSearchLimit does not apply), and
+// Offset skips leading matches. Cursor, Facets, and GroupByWorkspace need the
+// whole page and are rejected. An error from emit stops the scan and is returned.
+func SearchStream(ctx context.Context, database *sql.DB, input SearchInput, emit func(SearchResultItem) error) error {
+	if input.Cursor != nil || input.Facets || input.GroupByWorkspace {
+		return errors.NewInvalidRequest("cursor, facets, and group_by_workspace are not supported when streaming")
+	}
+	input.EmptyQueryListsAll = false // streaming always needs a query
+	plan, err := planSearch(input)
+	if err != nil {
+		return err
+	}
+
+	return db.SearchFullTextEach(ctx, database, plan.query, plan.filters, input.Limit, max(input.Offset, 0), input.IncludeDeleted, plan.snippetTokens, plan.order,
+		func(r db.SearchResult) error {
+			return emit(toSearchResultItem(r, plan.withSnippet, plan.snippetFormats))
+		})
+}
+
+// searchPlan is a validated search query shared by Search and SearchStream.
+type searchPlan struct {
+	query          string
+	filters        db.SearchFilters
+	order          db.SearchOrder
+	withSnippet    bool
+	snippetTokens  int             // 0 when withSnippet is false
+	snippetFormats []SnippetFormat // deduped; nil unless requested
+}
+
+// planSearch validates the query, filters, sort, and snippet options of input.
+func planSearch(input SearchInput) (*searchPlan, error) {
 	// Validate query
 	query := strings.TrimSpace(input.Query)
-	if query == "" {
+	if query == "" && !input.EmptyQueryListsAll {
 		return nil, errors.NewInvalidRequest("query is required")
 	}
 	if utf8.RuneCountInString(query) > MaxQueryLength {
 		return nil, errors.NewInvalidRequest(fmt.Sprintf("query exceeds maximum length of %d characters", MaxQueryLength))
 	}
+	if input.MinTermLen > 1 && query != "" {
+		if longest := longestSearchTerm(query); longest < input.MinTermLen {
+			return nil, errors.NewInvalidRequest(fmt.Sprintf(
+				"search terms must be at least %d characters (longest term in query has %d); use a longer word or prefix like \"auth*\"",
+				input.MinTermLen, longest))
+		}
+	}
 
 	// Build filters
 	var filters db.SearchFilters
-	if input.Workspace != nil {
-		workspace := capsule.Normalize(*input.Workspace)
-		if workspace != "" {
-			filters.Workspace = &workspace
-		}
-	}
-	if input.Tag != nil {
-		tag := strings.TrimSpace(*input.Tag)
-		if tag != "" {
-			filters.Tag = &tag
-		}
+	workspaces, err := searchWorkspaces(input.Workspace, input.Workspaces)
+	if err != nil {
+		return nil, err
 	}
+	filters.Workspaces = workspaces
+	filters.Tag = cleanTagFilter(input.Tag, input.NormalizeTags)
 	filters.RunID = cleanOptionalString(input.RunID)
 	filters.Phase = cleanOptionalString(input.Phase)
 	filters.Role = cleanOptionalString(input.Role)
+	filters.SourceType = cleanOptionalString(input.SourceType)
+	if filters.Tags, filters.TagMatch, err = searchTags(input.Tags, input.TagMatch, input.NormalizeTags); err != nil {
+		return nil, err
+	}
+	if input.NamePrefix != nil {
+		if prefix := capsule.Normalize(*input.NamePrefix); prefix != "" {
+			filters.NamePrefix = &prefix
+		}
+	}
+	if input.UpdatedAfter != nil && input.UpdatedBefore != nil && *input.UpdatedAfter >= *input.UpdatedBefore {
+		return nil, errors.NewInvalidRequest("updated_after must be earlier than updated_before")
+	}
+	filters.UpdatedAfter = input.UpdatedAfter
+	filters.UpdatedBefore = input.UpdatedBefore
 
-	// Apply limit defaults and bounds
-	limit := input.Limit
-	if limit <= 0 {
-		limit = DefaultSearchLimit
+	// Validate sort (default: relevance)
+	order := db.SearchOrder(input.Sort)
+	if order == "" {
+		order = db.SearchOrderRelevance
 	}
-	if limit > MaxSearchLimit {
-		limit = MaxSearchLimit
+	if order != db.SearchOrderRelevance && order != db.SearchOrderUpdated {
+		return nil, errors.NewInvalidRequest("sort must be one of: relevance, updated_at")
 	}
 
-	// Ensure offset is non-negative
-	offset := max(input.Offset, 0)
+	// Determine with_snippet (default: true)
+	withSnippet := true
+	if input.WithSnippet != nil {
+		withSnippet = *input.WithSnippet
+	}
+
+	snippetTokens := DefaultSnippetTokens
+	if input.SnippetTokens != 0 {
+		if input.SnippetTokens < MinSnippetTokens || input.SnippetTokens > MaxSnippetTokens {
+			return nil, errors.NewInvalidRequest(fmt.Sprintf(
+				"snippet_tokens must be between %d and %d", MinSnippetTokens, MaxSnippetTokens))
+		}
+		snippetTokens = input.SnippetTokens
+	}
+	if !withSnippet {
+		snippetTokens = 0
+	}
 
-	// Query database
-	results, total, err := db.SearchFullText(ctx, database, query, filters, limit, offset, input.IncludeDeleted)
+	snippetFormats, err := searchSnippetFormats(input.SnippetFormats)
 	if err != nil {
 		return nil, err
 	}
+	if snippetFormats != nil && !withSnippet {
+		return nil, errors.NewInvalidRequest("snippet_formats cannot be combined with with_snippet:false")
+	}
 
-	// Convert to output items
-	items := make([]SearchResultItem, len(results))
-	for i, r := range results {
-		name := ""
-		if r.Summary.Name != nil {
-			name = *r.Summary.Name
-		}
+	return &searchPlan{
+		query:          query,
+		filters:        filters,
+		order:          order,
+		withSnippet:    withSnippet,
+		snippetTokens:  snippetTokens,
+		snippetFormats: snippetFormats,
+	}, nil
+}
 
-		// Process snippet:
-		// 1. Escape user content to prevent XSS; convert internal markers to <b> tags
-		// 2. Truncate to max length (preserves UTF-8 and closes unclosed tags)
-		snippet := escapeSnippetHTML(r.Snippet)
-		snippet = truncateSnippet(snippet, MaxSnippetChars)
+// searchListAll answers an empty query (EmptyQueryListsAll) with db.ListAll:
+// every capsule matching the filters, newest first, with empty snippets and
+// Sort "updated_at". Filters and options ListAll can't apply are rejected
+// rather than silently dropped.
+func searchListAll(ctx context.Context, database *sql.DB, input SearchInput, plan *searchPlan, limit, offset int) (*SearchOutput, error) {
+	f := plan.filters
+	if len(f.Workspaces) > 1 || len(f.Tags) > 0 || f.UpdatedAfter != nil || f.UpdatedBefore != nil {
+		return nil, errors.NewInvalidRequest("an empty query supports only the workspace, tag, name_prefix, run_id, phase, role, and source_type filters")
+	}
+	if input.Cursor != nil || input.Facets {
+		return nil, errors.NewInvalidRequest("cursor and facets require a query")
+	}
 
-		items[i] = SearchResultItem{
-			SummaryItem: SummaryItem{
-				CapsuleSummary: r.Summary,
-				FetchKey:       BuildFetchKey(r.Summary.Workspace, name, r.Summary.ID),
-			},
-			Snippet: snippet,
-		}
+	filters := db.InventoryFilters{
+		Tag:        f.Tag,
+		NamePrefix: f.NamePrefix,
+		RunID:      f.RunID,
+		Phase:      f.Phase,
+		Role:       f.Role,
+		SourceType: f.SourceType,
+	}
+	if len(f.Workspaces) == 1 {
+		filters.Workspace = &f.Workspaces[0]
 	}
 
-	// Calculate has_more
-	hasMore := offset+len(items) < total
+	summaries, total, err := db.ListAll(ctx, database, filters, limit, offset, input.IncludeDeleted)
+	if err != nil {
+		return nil, err
+	}
 
-	return &SearchOutput{
+	items := make([]SearchResultItem, len(summaries))
+	for i, s := range summaries {
+		items[i] = SearchResultItem{SummaryItem: SummaryToItem(s)}
+	}
+
+	output := &SearchOutput{
 		Items: items,
 		Pagination: Pagination{
 			Limit:   limit,
 			Offset:  offset,
-			HasMore: hasMore,
+			HasMore: offset+len(items) < total,
 			Total:   total,
 		},
-		Sort: "relevance",
-	}, nil
+		Sort: string(db.SearchOrderUpdated),
+	}
+	if input.GroupByWorkspace {
+		output.Groups = groupSearchResults(items)
+	}
+	return output, nil
+}
+
+// encodeSearchCursor makes an opaque cursor from the last row of a page.
+func encodeSearchCursor(c db.SearchCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", c.UpdatedAt, c.ID)))
+}
+
+// decodeSearchCursor parses a cursor produced by encodeSearchCursor.
+func decodeSearchCursor(s string) (*db.SearchCursor, error) {
+	invalid := errors.NewInvalidRequest("invalid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, invalid
+	}
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return nil, invalid
+	}
+	updatedAt, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	return &db.SearchCursor{UpdatedAt: updatedAt, ID: id}, nil
+}
+
+// toSearchResultItems converts ranked db results to output items.
+func toSearchResultItems(results []db.SearchResult, withSnippet bool, formats []SnippetFormat) []SearchResultItem {
+	items := make([]SearchResultItem, len(results))
+	for i, r := range results {
+		items[i] = toSearchResultItem(r, withSnippet, formats)
+	}
+	return items
+}
+
+// toSearchResultItem converts one db result to an output item.
+func toSearchResultItem(r db.SearchResult, withSnippet bool, formats []SnippetFormat) SearchResultItem {
+	name := ""
+	if r.Summary.Name != nil {
+		name = *r.Summary.Name
+	}
+
+	// Process snippet:
+	// 1. Escape user content to prevent XSS; convert internal markers to <b> tags
+	// 2. Truncate to max length (preserves UTF-8 and closes unclosed tags)
+	snippet := ""
+	if withSnippet {
+		snippet = escapeSnippetHTML(r.Snippet)
+		snippet = truncateSnippet(snippet, MaxSnippetChars)
+	}
+
+	// Other formats are derived from the final HTML snippet, so every format
+	// carries exactly the same (truncated) text and highlights.
+	var snippets map[SnippetFormat]string
+	if len(formats) > 0 {
+		snippets = make(map[SnippetFormat]string, len(formats))
+		for _, f := range formats {
+			snippets[f] = convertSnippet(snippet, f)
+		}
+	}
+
+	return SearchResultItem{
+		SummaryItem: SummaryItem{
+			CapsuleSummary: r.Summary,
+			FetchKey:       BuildFetchKey(r.Summary.Workspace, name, r.Summary.ID),
+		},
+		Snippet:  snippet,
+		Snippets: snippets,
+	}
+}
+
+// searchSnippetFormats validates and dedupes the requested snippet formats.
+// Returns nil when none were requested.
+func searchSnippetFormats(formats []string) ([]SnippetFormat, error) {
+	var result []SnippetFormat
+	for _, f := range formats {
+		format := SnippetFormat(strings.ToLower(strings.TrimSpace(f)))
+		switch format {
+		case SnippetFormatHTML, SnippetFormatPlain, SnippetFormatMarkdown:
+		default:
+			return nil, errors.NewInvalidRequest("snippet_formats entries must be one of: html, plain, markdown")
+		}
+		if !slices.Contains(result, format) {
+			result = append(result, format)
+		}
+	}
+	return result, nil
+}
+
+// snippetPlainReplacer drops the highlight tags of an HTML snippet.
+var snippetPlainReplacer = strings.NewReplacer("<b>", "", "</b>", "")
+
+// snippetMarkdownReplacer escapes markdown emphasis and code characters in an
+// HTML snippet's text (escaped HTML leaves them as-is) and turns highlight tags into **.
+var snippetMarkdownReplacer = strings.NewReplacer(
+	"<b>", "**", "</b>", "**",
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`",
+)
+
+// convertSnippet re-encodes an HTML snippet (as produced by escapeSnippetHTML
+// and truncateSnippet: only <b>/</b> tags, everything else escaped) in format.
+func convertSnippet(htmlSnippet string, format SnippetFormat) string {
+	switch format {
+	case SnippetFormatPlain:
+		return html.UnescapeString(snippetPlainReplacer.Replace(htmlSnippet))
+	case SnippetFormatMarkdown:
+		return html.UnescapeString(snippetMarkdownReplacer.Replace(htmlSnippet))
+	default:
+		return htmlSnippet
+	}
+}
+
+// searchWorkspaces normalizes and dedupes the workspace filters.
+// A single workspace and a list may be combined; blank entries are ignored.
+func searchWorkspaces(workspace *string, workspaces []string) ([]string, error) {
+	raw := workspaces
+	if workspace != nil {
+		raw = append([]string{*workspace}, workspaces...)
+	}
+	if len(raw) > MaxSearchWorkspaces {
+		return nil, errors.NewInvalidRequest(
+			fmt.Sprintf("too many workspaces: %d (max %d)", len(raw), MaxSearchWorkspaces))
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, ws := range raw {
+		norm := capsule.Normalize(ws)
+		if norm == "" || seen[norm] {
+			continue
+		}
+		seen[norm] = true
+		result = append(result, norm)
+	}
+	return result, nil
+}
+
+// searchTags cleans and dedupes the tags filter and validates tag_match.
+func searchTags(tags []string, match string, normalize bool) ([]string, db.TagMatch, error) {
+	tagMatch := db.TagMatch(match)
+	if tagMatch == "" {
+		tagMatch = db.TagMatchAny
+	}
+	if tagMatch != db.TagMatchAny && tagMatch != db.TagMatchAll {
+		return nil, "", errors.NewInvalidRequest("tag_match must be one of: any, all")
+	}
+	if len(tags) > MaxSearchTags {
+		return nil, "", errors.NewInvalidRequest(
+			fmt.Sprintf("too many tags: %d (max %d)", len(tags), MaxSearchTags))
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		cleaned := cleanTagFilter(&tag, normalize)
+		if cleaned == nil || seen[*cleaned] {
+			continue
+		}
+		seen[*cleaned] = true
+		result = append(result, *cleaned)
+	}
+	return result, tagMatch, nil
+}
+
+// groupSearchResults buckets items by normalized workspace in first-seen order.
+func groupSearchResults(items []SearchResultItem) []SearchGroup {
+	groups := []SearchGroup{}
+	index := make(map[string]int)
+	for _, item := range items {
+		i, ok := index[item.WorkspaceNorm]
+		if !ok {
+			i = len(groups)
+			index[item.WorkspaceNorm] = i
+			groups = append(groups, SearchGroup{Workspace: item.WorkspaceNorm})
+		}
+		groups[i].Items = append(groups[i].Items, item)
+		groups[i].Count++
+	}
+	return groups
+}
+
+// longestSearchTerm returns the rune length of the longest bare term in an FTS5 query.
+// Terms inside "phrases" are measured word by word; outside phrases, the boolean
+// operators (AND, OR, NOT, NEAR), column filters ("title:"), and the prefix "*"
+// are not counted, so auth* measures 4. Words are split like the unicode61
+// tokenizer: any rune that isn't a letter or digit separates terms.
+func longestSearchTerm(query string) int {
+	longest := 0
+	measure := func(s string) {
+		for _, word := range strings.FieldsFunc(s, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		}) {
+			longest = max(longest, utf8.RuneCountInString(word))
+		}
+	}
+
+	// Odd-indexed parts are inside double quotes
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			measure(part)
+			continue
+		}
+		for _, field := range strings.FieldsFunc(part, func(r rune) bool {
+			return unicode.IsSpace(r) || r == '(' || r == ')'
+		}) {
+			switch field {
+			case "AND", "OR", "NOT", "NEAR":
+				continue
+			}
+			if idx := strings.LastIndex(field, ":"); idx != -1 {
+				field = field[idx+1:]
+			}
+			measure(field)
+		}
+	}
+
+	return longest
 }
 
 // truncateSnippet truncates a snippet to approximately maxChars while:
@@ -0,0 +1,140 @@
+package ops
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// searchGeneration counts writes. Every write op bumps it when it returns, so
+// cached search results from an earlier generation are never served. It only
+// sees this process's writes; other processes writing the same DB don't bump it.
+var searchGeneration atomic.Uint64
+
+// activeSearchCache is nil unless ConfigureSearchCache enabled caching.
+var activeSearchCache atomic.Pointer[searchCache]
+
+// ConfigureSearchCache sets up an in-memory LRU of up to size search result
+// pages (config.SearchCacheSize). size <= 0 disables caching. Call once at
+// startup; calling again drops the existing cache.
+func ConfigureSearchCache(size int) {
+	if size <= 0 {
+		activeSearchCache.Store(nil)
+		return
+	}
+	activeSearchCache.Store(newSearchCache(size))
+}
+
+// invalidateSearchCache marks every cached search result stale. Write ops
+// defer it so the bump lands after their transaction commits.
+func invalidateSearchCache() {
+	searchGeneration.Add(1)
+}
+
+// searchCacheKey identifies one page of results from db.SearchFullText.
+type searchCacheKey struct {
+	Query          string           `json:"q"`
+	Filters        db.SearchFilters `json:"f"`
+	Limit          int              `json:"l"`
+	Offset         int              `json:"o"`
+	IncludeDeleted bool             `json:"d"`
+	SnippetTokens  int              `json:"s"`
+	Order          db.SearchOrder   `json:"r"`
+	After          *db.SearchCursor `json:"a"`
+}
+
+// searchCacheEntry is one cached page.
+type searchCacheEntry struct {
+	key        string
+	generation uint64
+	results    []db.SearchResult
+	total      int
+}
+
+// searchCache is a concurrency-safe LRU of search result pages.
+type searchCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element // key → element holding *searchCacheEntry
+	order   *list.List               // front is most recently used
+	hits    int
+	misses  int
+}
+
+func newSearchCache(size int) *searchCache {
+	return &searchCache{size: size, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// get returns the cached page for key if it was stored in generation.
+// A stale entry is dropped.
+func (c *searchCache) get(key string, generation uint64) ([]db.SearchResult, int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, 0, false
+	}
+	entry := el.Value.(*searchCacheEntry)
+	if entry.generation != generation {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		c.misses++
+		return nil, 0, false
+	}
+	c.order.MoveToFront(el)
+	c.hits++
+	return entry.results, entry.total, true
+}
+
+// put stores a page read in generation, evicting the least recently used
+// entry when full.
+func (c *searchCache) put(key string, generation uint64, results []db.SearchResult, total int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = &searchCacheEntry{key: key, generation: generation, results: results, total: total}
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&searchCacheEntry{key: key, generation: generation, results: results, total: total})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*searchCacheEntry).key)
+	}
+}
+
+// cachedSearchFullText runs db.SearchFullText through the search cache, if
+// enabled. The generation is read before querying, so a write that commits
+// mid-query leaves the stored page already stale.
+func cachedSearchFullText(ctx context.Context, database *sql.DB, key searchCacheKey) ([]db.SearchResult, int, error) {
+	cache := activeSearchCache.Load()
+	if cache == nil {
+		return db.SearchFullText(ctx, database, key.Query, key.Filters, key.Limit, key.Offset, key.IncludeDeleted, key.SnippetTokens, key.Order, key.After)
+	}
+
+	encoded, err := json.Marshal(key)
+	if err != nil {
+		return nil, 0, errors.NewInternal(err)
+	}
+	generation := searchGeneration.Load()
+	if results, total, ok := cache.get(string(encoded), generation); ok {
+		return results, total, nil
+	}
+
+	results, total, err := db.SearchFullText(ctx, database, key.Query, key.Filters, key.Limit, key.Offset, key.IncludeDeleted, key.SnippetTokens, key.Order, key.After)
+	if err != nil {
+		return nil, 0, err
+	}
+	cache.put(string(encoded), generation, results, total)
+	return results, total, nil
+}
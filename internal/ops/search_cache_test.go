@@ -0,0 +1,112 @@
+package ops
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+)
+
+func TestSearchCache_HitAndInvalidate(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ConfigureSearchCache(8)
+	t.Cleanup(func() { ConfigureSearchCache(0) })
+	cache := activeSearchCache.Load()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", Name: stringPtr("auth"), CapsuleText: validCapsuleText}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	first, err := Search(ctx, database, SearchInput{Query: "authentication"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	second, err := Search(ctx, database, SearchInput{Query: "authentication"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if cache.hits != 1 || cache.misses != 1 {
+		t.Errorf("hits = %d, misses = %d; want 1, 1", cache.hits, cache.misses)
+	}
+	if len(first.Items) != 1 || len(second.Items) != 1 || first.Items[0].Snippet != second.Items[0].Snippet {
+		t.Errorf("cached result differs: %+v vs %+v", first.Items, second.Items)
+	}
+
+	// A different page is a different entry
+	if _, err := Search(ctx, database, SearchInput{Query: "authentication", Limit: 5}); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if cache.misses != 2 {
+		t.Errorf("misses = %d, want 2 after a new limit", cache.misses)
+	}
+
+	// A write invalidates: the new capsule shows up
+	if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", Name: stringPtr("auth-2"), CapsuleText: validCapsuleText}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	third, err := Search(ctx, database, SearchInput{Query: "authentication"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(third.Items) != 2 {
+		t.Errorf("items after store = %d, want 2 (stale cache served)", len(third.Items))
+	}
+	if cache.hits != 1 {
+		t.Errorf("hits = %d, want still 1 after invalidation", cache.hits)
+	}
+}
+
+func TestSearchCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newSearchCache(2)
+	for i := range 3 {
+		cache.put(fmt.Sprintf("k%d", i), 0, nil, i)
+		if i == 1 {
+			cache.get("k0", 0) // k0 is now more recent than k1
+		}
+	}
+
+	if _, _, ok := cache.get("k1", 0); ok {
+		t.Error("k1 should have been evicted")
+	}
+	for _, key := range []string{"k0", "k2"} {
+		if _, _, ok := cache.get(key, 0); !ok {
+			t.Errorf("%s should still be cached", key)
+		}
+	}
+	if _, _, ok := cache.get("k2", 1); ok {
+		t.Error("entry from an older generation should miss")
+	}
+}
+
+func TestSearchCache_ConcurrentAccess(t *testing.T) {
+	cache := newSearchCache(4)
+	done := make(chan struct{})
+	for g := range 8 {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for i := range 200 {
+				key := fmt.Sprintf("k%d", (g+i)%6)
+				if _, _, ok := cache.get(key, 0); !ok {
+					cache.put(key, 0, nil, i)
+				}
+			}
+		}()
+	}
+	for range 8 {
+		<-done
+	}
+	if cache.order.Len() > 4 || len(cache.entries) != cache.order.Len() {
+		t.Errorf("cache has %d entries, %d in order; want <= 4 and equal", len(cache.entries), cache.order.Len())
+	}
+}
@@ -2,6 +2,8 @@ package ops
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -49,6 +51,54 @@ func TestSearch_BasicMatch(t *testing.T) {
 	}
 }
 
+func TestSearchStream_MatchesSearchOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+	for i := range 5 {
+		if _, err := Store(ctx, database, cfg, StoreInput{Name: stringPtr(fmt.Sprintf("cap-%d", i)), CapsuleText: validCapsuleText}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	page, err := Search(ctx, database, SearchInput{Query: "JWT", Sort: "updated_at"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	var streamed []SearchResultItem
+	err = SearchStream(ctx, database, SearchInput{Query: "JWT", Sort: "updated_at"}, func(item SearchResultItem) error {
+		streamed = append(streamed, item)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SearchStream failed: %v", err)
+	}
+	if !reflect.DeepEqual(streamed, page.Items) {
+		t.Errorf("streamed items differ from Search page:\n got %+v\nwant %+v", streamed, page.Items)
+	}
+
+	// emit errors stop the scan
+	stop := errors.NewInternal(fmt.Errorf("stop"))
+	calls := 0
+	err = SearchStream(ctx, database, SearchInput{Query: "JWT"}, func(SearchResultItem) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("err = %v, calls = %d; want emit's error after 1 call", err, calls)
+	}
+
+	if err := SearchStream(ctx, database, SearchInput{Query: "JWT", Facets: true}, func(SearchResultItem) error { return nil }); !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("Facets error = %v, want INVALID_REQUEST", err)
+	}
+}
+
 func TestSearch_EmptyQuery(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -81,6 +131,64 @@ func TestSearch_WhitespaceOnlyQuery(t *testing.T) {
 	}
 }
 
+func TestSearch_EmptyQueryListsAll(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	for i, name := range []string{"first", "second", "third"} {
+		stored, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", Name: stringPtr(name), CapsuleText: validCapsuleText})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		if _, err := database.Exec("UPDATE capsules SET updated_at = ? WHERE id = ?", 1000+i, stored.ID); err != nil {
+			t.Fatalf("set updated_at: %v", err)
+		}
+	}
+	if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "other", Name: stringPtr("elsewhere"), CapsuleText: validCapsuleText}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := Search(ctx, database, SearchInput{
+		Query:              "  ",
+		Workspace:          stringPtr("proj"),
+		Limit:              2,
+		MinTermLen:         3,
+		EmptyQueryListsAll: true,
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if output.Sort != "updated_at" {
+		t.Errorf("Sort = %q, want updated_at", output.Sort)
+	}
+	if output.Pagination.Total != 3 || !output.Pagination.HasMore {
+		t.Errorf("Pagination = %+v, want total 3 with more", output.Pagination)
+	}
+	var names []string
+	for _, item := range output.Items {
+		names = append(names, *item.Name)
+		if item.Snippet != "" {
+			t.Errorf("%s: Snippet = %q, want empty", *item.Name, item.Snippet)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"third", "second"}) {
+		t.Errorf("names = %v, want newest first [third second]", names)
+	}
+
+	// Filters ListAll can't apply are rejected, not dropped
+	_, err = Search(ctx, database, SearchInput{Tags: []string{"a"}, EmptyQueryListsAll: true})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("tags with empty query: error = %v, want INVALID_REQUEST", err)
+	}
+}
+
 func TestSearch_QueryTooLong(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -145,6 +253,108 @@ func TestSearch_WorkspaceFilter(t *testing.T) {
 	}
 }
 
+func TestSearch_MultiWorkspaceFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	// Two capsules in alpha, one each in beta and gamma
+	for _, ws := range []string{"alpha", "alpha", "beta", "gamma"} {
+		_, err = Store(context.Background(), database, cfg, StoreInput{
+			Workspace:   ws,
+			CapsuleText: validCapsuleText,
+		})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	output, err := Search(context.Background(), database, SearchInput{
+		Query:            "authentication",
+		Workspaces:       []string{"Alpha", " beta ", "alpha", ""},
+		GroupByWorkspace: true,
+		Facets:           true,
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if output.Pagination.Total != 3 {
+		t.Errorf("Total = %d, want 3", output.Pagination.Total)
+	}
+	for _, item := range output.Items {
+		if item.WorkspaceNorm == "gamma" {
+			t.Errorf("gamma should not contribute results: %+v", item)
+		}
+	}
+	if len(output.Facets.Workspaces) != 2 || output.Facets.Workspaces["alpha"] != 2 || output.Facets.Workspaces["beta"] != 1 {
+		t.Errorf("Facets.Workspaces = %v, want alpha:2 beta:1", output.Facets.Workspaces)
+	}
+
+	if len(output.Groups) != 2 {
+		t.Fatalf("len(Groups) = %d, want 2", len(output.Groups))
+	}
+	counts := map[string]int{}
+	for _, g := range output.Groups {
+		counts[g.Workspace] = g.Count
+		if len(g.Items) != g.Count {
+			t.Errorf("group %s: len(Items) = %d, Count = %d", g.Workspace, len(g.Items), g.Count)
+		}
+		for _, item := range g.Items {
+			if item.WorkspaceNorm != g.Workspace {
+				t.Errorf("group %s contains item from %s", g.Workspace, item.WorkspaceNorm)
+			}
+		}
+	}
+	if counts["alpha"] != 2 || counts["beta"] != 1 {
+		t.Errorf("group counts = %v, want alpha:2 beta:1", counts)
+	}
+
+	// Single workspace combined with the list
+	workspace := "gamma"
+	output, err = Search(context.Background(), database, SearchInput{
+		Query:      "authentication",
+		Workspace:  &workspace,
+		Workspaces: []string{"beta"},
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if output.Pagination.Total != 2 {
+		t.Errorf("Total = %d, want 2 (gamma + beta)", output.Pagination.Total)
+	}
+	if output.Groups != nil {
+		t.Error("Groups should be omitted unless requested")
+	}
+}
+
+func TestSearch_TooManyWorkspaces(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	workspaces := make([]string, MaxSearchWorkspaces+1)
+	for i := range workspaces {
+		workspaces[i] = fmt.Sprintf("ws%d", i)
+	}
+
+	_, err = Search(context.Background(), database, SearchInput{
+		Query:      "authentication",
+		Workspaces: workspaces,
+	})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest, got %v", err)
+	}
+}
+
 func TestSearch_TagFilter(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -238,6 +448,113 @@ func TestSearch_PhaseRoleFilters(t *testing.T) {
 	}
 }
 
+func TestSearch_CombinedFilters(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	type seed struct {
+		workspace string
+		name      string
+		phase     string
+		tags      []string
+		text      string
+		updatedAt int64
+	}
+	seeds := []seed{
+		{"proj", "auth-review", "review", []string{"security", "backend"}, "## Status\nThe token rotation plan is approved.", 1000},
+		{"proj", "auth-design", "review", []string{"security", "backend"}, "## Status\nThe token rotation plan is approved.", 900},     // wrong name prefix
+		{"proj", "auth-old", "review", []string{"security", "backend"}, "## Status\nThe token rotation plan is approved.", 100},        // too old
+		{"proj", "auth-thin", "review", []string{"security"}, "## Status\nThe token rotation plan is approved.", 1000},                 // missing backend tag
+		{"proj", "auth-impl", "implement", []string{"security", "backend"}, "## Status\nThe token rotation plan is approved.", 1000},   // wrong phase
+		{"proj", "auth-words", "review", []string{"security", "backend"}, "## Status\nThe plan for token rotation is approved.", 1000}, // phrase not contiguous
+		{"other", "auth-review", "review", []string{"security", "backend"}, "## Status\nThe token rotation plan is approved.", 1000},   // wrong workspace
+	}
+	ids := make(map[string]string)
+	for _, s := range seeds {
+		out, err := Store(ctx, database, cfg, StoreInput{
+			Workspace:   s.workspace,
+			Name:        stringPtr(s.name),
+			CapsuleText: s.text,
+			Tags:        s.tags,
+			Phase:       stringPtr(s.phase),
+			AllowThin:   true,
+		})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		if _, err := database.Exec("UPDATE capsules SET updated_at = ? WHERE id = ?", s.updatedAt, out.ID); err != nil {
+			t.Fatalf("set updated_at: %v", err)
+		}
+		ids[s.workspace+"/"+s.name] = out.ID
+	}
+
+	after, before := int64(500), int64(2000)
+	output, err := Search(ctx, database, SearchInput{
+		Query:         `"token rotation plan"`,
+		Workspace:     stringPtr("proj"),
+		Phase:         stringPtr("review"),
+		Tags:          []string{"security", "backend", "security"},
+		TagMatch:      "all",
+		NamePrefix:    stringPtr("Auth-R"),
+		UpdatedAfter:  &after,
+		UpdatedBefore: &before,
+		Facets:        true,
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(output.Items) != 1 || output.Items[0].ID != ids["proj/auth-review"] {
+		t.Fatalf("Items = %+v, want only proj/auth-review", output.Items)
+	}
+	if output.Pagination.Total != 1 {
+		t.Errorf("Total = %d, want 1 (count must apply every filter)", output.Pagination.Total)
+	}
+	if output.Facets == nil || output.Facets.Workspaces["proj"] != 1 || output.Facets.Phases["review"] != 1 {
+		t.Errorf("Facets = %+v, want one proj/review hit", output.Facets)
+	}
+
+	// tag_match any accepts capsules carrying at least one of the tags
+	output, err = Search(ctx, database, SearchInput{
+		Query:     `"token rotation plan"`,
+		Workspace: stringPtr("proj"),
+		Phase:     stringPtr("review"),
+		Tags:      []string{"backend", "missing"},
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if output.Pagination.Total != 3 {
+		t.Errorf("tag_match any Total = %d, want 3 (review, design, old)", output.Pagination.Total)
+	}
+
+	// Time range alone
+	output, err = Search(ctx, database, SearchInput{Query: "rotation", UpdatedBefore: &after})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(output.Items) != 1 || output.Items[0].ID != ids["proj/auth-old"] {
+		t.Errorf("updated_before Items = %+v, want only proj/auth-old", output.Items)
+	}
+
+	// Validation
+	for name, input := range map[string]SearchInput{
+		"tag_match":  {Query: "rotation", Tags: []string{"security"}, TagMatch: "most"},
+		"time range": {Query: "rotation", UpdatedAfter: &before, UpdatedBefore: &after},
+		"too many":   {Query: "rotation", Tags: make([]string, MaxSearchTags+1)},
+	} {
+		if _, err := Search(ctx, database, input); !errors.Is(err, errors.ErrInvalidRequest) {
+			t.Errorf("%s: expected ErrInvalidRequest, got %v", name, err)
+		}
+	}
+}
+
 func TestSearch_Pagination(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -397,6 +714,58 @@ func TestSearch_IncludeDeleted(t *testing.T) {
 	}
 }
 
+func TestSearch_SoftDeletedStaysIndexedUntilPurge(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	stored, err := Store(ctx, database, cfg, StoreInput{
+		Workspace:   "default",
+		CapsuleText: "## Status\nThe quokka migration is blocked.",
+		AllowThin:   true,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := Delete(ctx, database, DeleteInput{ID: stored.ID}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	search := func(includeDeleted bool) []SearchResultItem {
+		t.Helper()
+		output, err := Search(ctx, database, SearchInput{Query: "quokka", IncludeDeleted: includeDeleted})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		return output.Items
+	}
+
+	if items := search(false); len(items) != 0 {
+		t.Errorf("without include_deleted: %d items, want 0", len(items))
+	}
+	items := search(true)
+	if len(items) != 1 || items[0].ID != stored.ID {
+		t.Fatalf("with include_deleted: items = %+v, want the soft-deleted capsule", items)
+	}
+	if items[0].DeletedAt == nil {
+		t.Error("DeletedAt = nil, want the soft-delete time")
+	}
+
+	// Hard purge removes the index entry too
+	if _, err := Purge(ctx, database, PurgeInput{}); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if items := search(true); len(items) != 0 {
+		t.Errorf("after purge: %d items, want 0", len(items))
+	}
+}
+
 func TestSearch_PhraseQuery(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -537,7 +906,7 @@ func TestSearch_TitleSearch(t *testing.T) {
 	}
 }
 
-func TestSearch_NoResults(t *testing.T) {
+func TestSearch_NameSearch(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
 	if err != nil {
@@ -547,36 +916,54 @@ func TestSearch_NoResults(t *testing.T) {
 
 	cfg := config.DefaultConfig()
 
-	// Store a capsule
-	_, err = Store(context.Background(), database, cfg, StoreInput{
+	// Store with a name token that appears nowhere else
+	stored, err := Store(context.Background(), database, cfg, StoreInput{
 		Workspace:   "default",
-		CapsuleText: validCapsuleText,
+		Name:        stringPtr("quokka-migration"),
+		CapsuleText: validCapsuleText, // Does not contain "quokka"
 	})
 	if err != nil {
 		t.Fatalf("Store failed: %v", err)
 	}
 
-	// Search for non-existent term
 	output, err := Search(context.Background(), database, SearchInput{
-		Query: "nonexistentterm12345",
+		Query: "quokka",
 	})
 	if err != nil {
 		t.Fatalf("Search failed: %v", err)
 	}
+	if len(output.Items) != 1 {
+		t.Fatalf("len(Items) = %d, want 1", len(output.Items))
+	}
+	if output.Items[0].ID != stored.ID {
+		t.Errorf("ID = %q, want %q", output.Items[0].ID, stored.ID)
+	}
+	if !strings.Contains(output.Items[0].Snippet, "<b>quokka</b>") {
+		t.Errorf("Snippet = %q, want highlighted name token", output.Items[0].Snippet)
+	}
 
-	// Should return empty array, not error
-	if len(output.Items) != 0 {
-		t.Errorf("len(Items) = %d, want 0", len(output.Items))
+	// Renaming via store replace keeps the index in sync
+	_, err = Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "default",
+		Name:        stringPtr("quokka-migration"),
+		CapsuleText: validCapsuleText,
+		Mode:        "replace",
+	})
+	if err != nil {
+		t.Fatalf("Store replace failed: %v", err)
 	}
-	if output.Pagination.Total != 0 {
-		t.Errorf("Total = %d, want 0", output.Pagination.Total)
+	output, err = Search(context.Background(), database, SearchInput{
+		Query: "quokka",
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
 	}
-	if output.Items == nil {
-		t.Error("Items should be empty array, not nil")
+	if len(output.Items) != 1 {
+		t.Errorf("After replace: len(Items) = %d, want 1", len(output.Items))
 	}
 }
 
-func TestSearch_FetchKeyIncluded(t *testing.T) {
+func TestSearch_NameAndTitleWeightedAboveBody(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
 	if err != nil {
@@ -586,10 +973,32 @@ func TestSearch_FetchKeyIncluded(t *testing.T) {
 
 	cfg := config.DefaultConfig()
 
-	// Store named capsule
-	_, err = Store(context.Background(), database, cfg, StoreInput{
-		Workspace:   "myworkspace",
-		Name:        stringPtr("myauth"),
+	// Body-only match: "pelican" appears in the capsule text
+	bodyText := strings.Replace(validCapsuleText, "JWT", "pelican JWT", 1)
+	body, err := Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "default",
+		Name:        stringPtr("body-match"),
+		CapsuleText: bodyText,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	// Name-only match
+	named, err := Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "default",
+		Name:        stringPtr("pelican-notes"),
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	// Title-only match
+	titled, err := Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "default",
+		Name:        stringPtr("title-match"),
+		Title:       stringPtr("Pelican rollout"),
 		CapsuleText: validCapsuleText,
 	})
 	if err != nil {
@@ -597,27 +1006,26 @@ func TestSearch_FetchKeyIncluded(t *testing.T) {
 	}
 
 	output, err := Search(context.Background(), database, SearchInput{
-		Query: "authentication",
+		Query: "pelican",
 	})
 	if err != nil {
 		t.Fatalf("Search failed: %v", err)
 	}
-
-	if len(output.Items) != 1 {
-		t.Fatalf("len(Items) = %d, want 1", len(output.Items))
+	if len(output.Items) != 3 {
+		t.Fatalf("len(Items) = %d, want 3", len(output.Items))
 	}
 
-	// Verify fetch_key
-	item := output.Items[0]
-	if item.FetchKey.MossCapsule != "myauth" {
-		t.Errorf("FetchKey.MossCapsule = %q, want 'myauth'", item.FetchKey.MossCapsule)
+	// Body match must rank last; name and title matches ahead of it
+	if output.Items[2].ID != body.ID {
+		t.Errorf("Items[2].ID = %q, want body match %q", output.Items[2].ID, body.ID)
 	}
-	if item.FetchKey.MossWorkspace != "myworkspace" {
-		t.Errorf("FetchKey.MossWorkspace = %q, want 'myworkspace'", item.FetchKey.MossWorkspace)
+	top := map[string]bool{output.Items[0].ID: true, output.Items[1].ID: true}
+	if !top[named.ID] || !top[titled.ID] {
+		t.Errorf("top results = %v, want name match %q and title match %q", top, named.ID, titled.ID)
 	}
 }
 
-func TestSearch_SnippetTruncation(t *testing.T) {
+func TestSearch_NoResults(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
 	if err != nil {
@@ -627,28 +1035,108 @@ func TestSearch_SnippetTruncation(t *testing.T) {
 
 	cfg := config.DefaultConfig()
 
-	// Store with long content
-	longText := "## Objective\n" + strings.Repeat("authentication ", 100) + "\n## Current status\nDone."
+	// Store a capsule
 	_, err = Store(context.Background(), database, cfg, StoreInput{
 		Workspace:   "default",
-		CapsuleText: longText,
-		AllowThin:   true,
+		CapsuleText: validCapsuleText,
 	})
 	if err != nil {
 		t.Fatalf("Store failed: %v", err)
 	}
 
+	// Search for non-existent term
 	output, err := Search(context.Background(), database, SearchInput{
-		Query: "authentication",
+		Query: "nonexistentterm12345",
 	})
 	if err != nil {
 		t.Fatalf("Search failed: %v", err)
 	}
 
-	if len(output.Items) != 1 {
-		t.Fatalf("len(Items) = %d, want 1", len(output.Items))
-	}
-
+	// Should return empty array, not error
+	if len(output.Items) != 0 {
+		t.Errorf("len(Items) = %d, want 0", len(output.Items))
+	}
+	if output.Pagination.Total != 0 {
+		t.Errorf("Total = %d, want 0", output.Pagination.Total)
+	}
+	if output.Items == nil {
+		t.Error("Items should be empty array, not nil")
+	}
+}
+
+func TestSearch_FetchKeyIncluded(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	// Store named capsule
+	_, err = Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "myworkspace",
+		Name:        stringPtr("myauth"),
+		CapsuleText: validCapsuleText,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := Search(context.Background(), database, SearchInput{
+		Query: "authentication",
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if len(output.Items) != 1 {
+		t.Fatalf("len(Items) = %d, want 1", len(output.Items))
+	}
+
+	// Verify fetch_key
+	item := output.Items[0]
+	if item.FetchKey.MossCapsule != "myauth" {
+		t.Errorf("FetchKey.MossCapsule = %q, want 'myauth'", item.FetchKey.MossCapsule)
+	}
+	if item.FetchKey.MossWorkspace != "myworkspace" {
+		t.Errorf("FetchKey.MossWorkspace = %q, want 'myworkspace'", item.FetchKey.MossWorkspace)
+	}
+}
+
+func TestSearch_SnippetTruncation(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	// Store with long content
+	longText := "## Objective\n" + strings.Repeat("authentication ", 100) + "\n## Current status\nDone."
+	_, err = Store(context.Background(), database, cfg, StoreInput{
+		Workspace:   "default",
+		CapsuleText: longText,
+		AllowThin:   true,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := Search(context.Background(), database, SearchInput{
+		Query: "authentication",
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if len(output.Items) != 1 {
+		t.Fatalf("len(Items) = %d, want 1", len(output.Items))
+	}
+
 	// Snippet should be truncated to ~300 chars
 	snippet := output.Items[0].Snippet
 	if len(snippet) > MaxSnippetChars+10 { // Allow for ellipsis and word boundary
@@ -656,6 +1144,288 @@ func TestSearch_SnippetTruncation(t *testing.T) {
 	}
 }
 
+func TestSearch_WithoutSnippet(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// Varying match strength so ranking is meaningful
+	for i, name := range []string{"auth-one", "auth-two", "auth-three", "other"} {
+		text := validCapsuleText + "\n" + strings.Repeat("authentication ", i+1)
+		if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "default", Name: stringPtr(name), CapsuleText: text}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	withSnippets, err := Search(ctx, database, SearchInput{Query: "authentication"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	withSnippet := false
+	without, err := Search(ctx, database, SearchInput{Query: "authentication", WithSnippet: &withSnippet})
+	if err != nil {
+		t.Fatalf("Search without snippet failed: %v", err)
+	}
+
+	if without.Pagination != withSnippets.Pagination {
+		t.Errorf("Pagination = %+v, want %+v", without.Pagination, withSnippets.Pagination)
+	}
+	if len(without.Items) != len(withSnippets.Items) || len(without.Items) == 0 {
+		t.Fatalf("len(Items) = %d, want %d (non-zero)", len(without.Items), len(withSnippets.Items))
+	}
+	for i := range without.Items {
+		if without.Items[i].ID != withSnippets.Items[i].ID {
+			t.Errorf("Items[%d].ID = %s, want %s (same order)", i, without.Items[i].ID, withSnippets.Items[i].ID)
+		}
+		if without.Items[i].Snippet != "" {
+			t.Errorf("Items[%d].Snippet = %q, want empty", i, without.Items[i].Snippet)
+		}
+		if withSnippets.Items[i].Snippet == "" {
+			t.Errorf("Items[%d]: default search should include a snippet", i)
+		}
+	}
+}
+
+func TestSearch_SnippetTokens(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// Short tokens so the default window fits under MaxSnippetChars but 128 does not
+	text := "## Objective\n" + strings.Repeat("ab ", 150) + "needle " + strings.Repeat("cd ", 150)
+	if _, err := Store(ctx, database, cfg, StoreInput{CapsuleText: text, AllowThin: true}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	snippet := func(tokens int) string {
+		t.Helper()
+		output, err := Search(ctx, database, SearchInput{Query: "needle", SnippetTokens: tokens})
+		if err != nil {
+			t.Fatalf("Search(snippet_tokens=%d) failed: %v", tokens, err)
+		}
+		if len(output.Items) != 1 {
+			t.Fatalf("len(Items) = %d, want 1", len(output.Items))
+		}
+		return output.Items[0].Snippet
+	}
+
+	small, def, large := snippet(MinSnippetTokens), snippet(0), snippet(MaxSnippetTokens)
+	if !(len(small) < len(def) && len(def) < len(large)) {
+		t.Errorf("snippet lengths = %d/%d/%d (8/default/128 tokens), want increasing", len(small), len(def), len(large))
+	}
+	if len(large) > MaxSnippetChars+10 {
+		t.Errorf("large snippet length = %d, want <= %d", len(large), MaxSnippetChars+10)
+	}
+	for _, s := range []string{small, def, large} {
+		if !strings.Contains(s, "<b>needle</b>") {
+			t.Errorf("snippet %q should highlight the match", s)
+		}
+	}
+
+	for _, tokens := range []int{MinSnippetTokens - 1, MaxSnippetTokens + 1, -5} {
+		_, err := Search(ctx, database, SearchInput{Query: "needle", SnippetTokens: tokens})
+		if !errors.Is(err, errors.ErrInvalidRequest) {
+			t.Errorf("snippet_tokens=%d: error = %v, want INVALID_REQUEST", tokens, err)
+		}
+	}
+}
+
+func TestSearch_SnippetFormats(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	text := "## Objective\nCompare a < b & use the needle_finder *carefully* near the needle."
+	if _, err := Store(ctx, database, cfg, StoreInput{CapsuleText: text, AllowThin: true}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	output, err := Search(ctx, database, SearchInput{Query: "needle", SnippetFormats: []string{"html", "plain", "html"}})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(output.Items) != 1 {
+		t.Fatalf("len(Items) = %d, want 1", len(output.Items))
+	}
+	item := output.Items[0]
+	if len(item.Snippets) != 2 {
+		t.Fatalf("Snippets = %v, want html and plain only", item.Snippets)
+	}
+
+	htmlSnippet, plain := item.Snippets[SnippetFormatHTML], item.Snippets[SnippetFormatPlain]
+	if htmlSnippet != item.Snippet {
+		t.Errorf("html snippet = %q, want same as Snippet %q", htmlSnippet, item.Snippet)
+	}
+	if !strings.Contains(htmlSnippet, "<b>needle</b>") || !strings.Contains(htmlSnippet, "a &lt; b &amp; use") {
+		t.Errorf("html snippet = %q, want escaped text with highlights", htmlSnippet)
+	}
+	if strings.Contains(plain, "<b>") || !strings.Contains(plain, "a < b & use") || !strings.Contains(plain, "the needle.") {
+		t.Errorf("plain snippet = %q, want unescaped text without highlights", plain)
+	}
+	// Both come from the same markers: stripping the html gives the plain text
+	want := strings.NewReplacer("<b>", "", "</b>", "", "&lt;", "<", "&amp;", "&").Replace(htmlSnippet)
+	if plain != want {
+		t.Errorf("plain snippet = %q, want %q", plain, want)
+	}
+
+	markdown, err := Search(ctx, database, SearchInput{Query: "needle", SnippetFormats: []string{"markdown"}})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if md := markdown.Items[0].Snippets[SnippetFormatMarkdown]; !strings.Contains(md, "the **needle**.") || !strings.Contains(md, `\*carefully\*`) {
+		t.Errorf("markdown snippet = %q, want bold match and escaped emphasis", md)
+	}
+
+	withSnippet := false
+	for name, input := range map[string]SearchInput{
+		"unknown format":  {Query: "needle", SnippetFormats: []string{"rtf"}},
+		"without snippet": {Query: "needle", SnippetFormats: []string{"plain"}, WithSnippet: &withSnippet},
+	} {
+		if _, err := Search(ctx, database, input); !errors.Is(err, errors.ErrInvalidRequest) {
+			t.Errorf("%s: error = %v, want INVALID_REQUEST", name, err)
+		}
+	}
+}
+
+func TestSearch_Facets(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	seed := []struct {
+		workspace string
+		name      string
+		phase     *string
+		tags      []string
+		text      string
+	}{
+		{"alpha", "a1", stringPtr("design"), []string{"auth", "api"}, "zephyr flow"},
+		{"alpha", "a2", stringPtr("design"), []string{"auth"}, "zephyr tokens"},
+		{"alpha", "a3", stringPtr("impl"), nil, "zephyr middleware"},
+		{"Beta", "b1", nil, []string{"api"}, "zephyr gateway"},
+		{"beta", "b2", stringPtr("impl"), []string{"auth"}, "unrelated caching"},
+	}
+	for _, c := range seed {
+		_, err := Store(ctx, database, cfg, StoreInput{
+			Workspace:   c.workspace,
+			Name:        stringPtr(c.name),
+			Phase:       c.phase,
+			Tags:        c.tags,
+			CapsuleText: validCapsuleText + "\n" + c.text,
+		})
+		if err != nil {
+			t.Fatalf("Store(%s) failed: %v", c.name, err)
+		}
+	}
+
+	// Deleted match is excluded by default
+	deleted, err := Store(ctx, database, cfg, StoreInput{
+		Workspace: "alpha", Name: stringPtr("gone"), Phase: stringPtr("design"),
+		Tags: []string{"auth"}, CapsuleText: validCapsuleText + "\nzephyr",
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := Delete(ctx, database, DeleteInput{ID: deleted.ID}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	t.Run("counts cover all matches", func(t *testing.T) {
+		// Limit 1: facets must not be limited to the page
+		result, err := Search(ctx, database, SearchInput{Query: "zephyr", Limit: 1, Facets: true})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(result.Items) != 1 || result.Pagination.Total != 4 {
+			t.Fatalf("page = %d items / total %d, want 1 / 4", len(result.Items), result.Pagination.Total)
+		}
+		if result.Facets == nil {
+			t.Fatal("Facets = nil, want counts")
+		}
+
+		wantWorkspaces := map[string]int{"alpha": 3, "beta": 1}
+		wantPhases := map[string]int{"design": 2, "impl": 1}
+		wantTags := map[string]int{"auth": 2, "api": 2}
+		if !reflect.DeepEqual(result.Facets.Workspaces, wantWorkspaces) {
+			t.Errorf("Workspaces = %v, want %v", result.Facets.Workspaces, wantWorkspaces)
+		}
+		if !reflect.DeepEqual(result.Facets.Phases, wantPhases) {
+			t.Errorf("Phases = %v, want %v", result.Facets.Phases, wantPhases)
+		}
+		if !reflect.DeepEqual(result.Facets.Tags, wantTags) {
+			t.Errorf("Tags = %v, want %v", result.Facets.Tags, wantTags)
+		}
+	})
+
+	t.Run("respects filters", func(t *testing.T) {
+		result, err := Search(ctx, database, SearchInput{Query: "zephyr", Tag: stringPtr("auth"), Facets: true})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if want := map[string]int{"alpha": 2}; !reflect.DeepEqual(result.Facets.Workspaces, want) {
+			t.Errorf("Workspaces = %v, want %v", result.Facets.Workspaces, want)
+		}
+		if want := map[string]int{"auth": 2, "api": 1}; !reflect.DeepEqual(result.Facets.Tags, want) {
+			t.Errorf("Tags = %v, want %v", result.Facets.Tags, want)
+		}
+	})
+
+	t.Run("include_deleted", func(t *testing.T) {
+		result, err := Search(ctx, database, SearchInput{Query: "zephyr", IncludeDeleted: true, Facets: true})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if got := result.Facets.Workspaces["alpha"]; got != 4 {
+			t.Errorf("Workspaces[alpha] = %d, want 4", got)
+		}
+	})
+
+	t.Run("omitted by default", func(t *testing.T) {
+		result, err := Search(ctx, database, SearchInput{Query: "zephyr"})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if result.Facets != nil {
+			t.Errorf("Facets = %+v, want nil", result.Facets)
+		}
+	})
+
+	t.Run("no matches yields empty maps", func(t *testing.T) {
+		result, err := Search(ctx, database, SearchInput{Query: "nonexistentterm", Facets: true})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if result.Facets == nil || len(result.Facets.Workspaces) != 0 || result.Facets.Tags == nil {
+			t.Errorf("Facets = %+v, want empty non-nil maps", result.Facets)
+		}
+	})
+}
+
 func TestSearch_TriggerSync(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -1023,3 +1793,271 @@ func decodeRuneAt(s string, i int) (rune, int) {
 	}
 	return rune(b&0x07)<<18 | rune(s[i+1]&0x3F)<<12 | rune(s[i+2]&0x3F)<<6 | rune(s[i+3]&0x3F), 4
 }
+
+func TestSearch_SourceTypeFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	var importedID string
+	for _, sourceType := range []string{SourceTypeAgent, SourceTypeImport} {
+		out, err := Store(ctx, database, cfg, StoreInput{
+			CapsuleText: validCapsuleText + "\nplatypus",
+			SourceType:  stringPtr(sourceType),
+		})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		if sourceType == SourceTypeImport {
+			importedID = out.ID
+		}
+	}
+
+	result, err := Search(ctx, database, SearchInput{Query: "platypus", SourceType: stringPtr(SourceTypeImport)})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(result.Items) != 1 || result.Items[0].ID != importedID {
+		t.Fatalf("Items = %+v, want only the imported capsule", result.Items)
+	}
+	if result.Items[0].SourceType == nil || *result.Items[0].SourceType != SourceTypeImport {
+		t.Errorf("SourceType = %v, want import", result.Items[0].SourceType)
+	}
+}
+
+func TestSearch_MinTermLen(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	if _, err := Store(ctx, database, cfg, StoreInput{CapsuleText: validCapsuleText}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	tests := []struct {
+		query   string
+		minLen  int
+		wantErr bool
+	}{
+		{"a", 1, false},   // default: no restriction
+		{"a", 0, false},   // unset: no restriction
+		{"ab", 3, true},   // below boundary
+		{"abc", 3, false}, // at boundary
+		{"auth*", 4, false},
+		{"auth*", 5, true}, // prefix operator doesn't count
+		{`"of an"`, 3, true},
+		{`"of authentication"`, 3, false}, // phrase words measured individually
+		{"a AND b OR NOT c", 3, true},     // operators aren't terms
+		{"title:ab", 3, true},             // column filter isn't counted
+		{"ab authentication", 3, false},   // longest term decides
+	}
+	for _, tt := range tests {
+		_, err := Search(ctx, database, SearchInput{Query: tt.query, MinTermLen: tt.minLen})
+		if tt.wantErr {
+			if !errors.Is(err, errors.ErrInvalidRequest) {
+				t.Errorf("Search(%q, min=%d) error = %v, want ErrInvalidRequest", tt.query, tt.minLen, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Search(%q, min=%d) unexpected error: %v", tt.query, tt.minLen, err)
+		}
+	}
+}
+
+func TestSearch_CursorTraversalAcrossInsert(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// Five matches with distinct updated_at; two share a timestamp to exercise the id tiebreak
+	var want []string
+	for i, ts := range []int64{500, 400, 300, 300, 100} {
+		out, err := Store(ctx, database, cfg, StoreInput{CapsuleText: fmt.Sprintf("## Status\nPagination marker %d", i), AllowThin: true})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		if _, err := database.Exec("UPDATE capsules SET updated_at = ? WHERE id = ?", ts, out.ID); err != nil {
+			t.Fatalf("set updated_at: %v", err)
+		}
+		want = append(want, out.ID)
+	}
+
+	page := func(cursor *string) *SearchOutput {
+		t.Helper()
+		output, err := Search(ctx, database, SearchInput{Query: "pagination", Sort: "updated_at", Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if output.Sort != "updated_at" {
+			t.Errorf("Sort = %q, want updated_at", output.Sort)
+		}
+		return output
+	}
+
+	first := page(nil)
+	if !first.Pagination.HasMore || first.NextCursor == "" {
+		t.Fatalf("first page should have more and a next_cursor: %+v", first.Pagination)
+	}
+	var seen []string
+	for _, item := range first.Items {
+		seen = append(seen, item.ID)
+	}
+
+	// A newer match written mid-traversal lands before the cursor and must not shift later pages
+	if _, err := Store(ctx, database, cfg, StoreInput{CapsuleText: "## Status\nPagination marker new", AllowThin: true}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	cursor := first.NextCursor
+	for cursor != "" {
+		next := page(&cursor)
+		for _, item := range next.Items {
+			seen = append(seen, item.ID)
+		}
+		if next.Pagination.HasMore != (next.NextCursor != "") {
+			t.Errorf("HasMore = %v but NextCursor = %q", next.Pagination.HasMore, next.NextCursor)
+		}
+		cursor = next.NextCursor
+	}
+
+	// Same timestamp: the larger id comes first
+	if want[2] < want[3] {
+		want[2], want[3] = want[3], want[2]
+	}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("traversal = %v, want %v (each original match once, newest first)", seen, want)
+	}
+}
+
+func TestSearch_CursorValidation(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	if _, err := Store(ctx, database, config.DefaultConfig(), StoreInput{CapsuleText: validCapsuleText}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	valid := encodeSearchCursor(db.SearchCursor{UpdatedAt: 100, ID: "01ABC"})
+
+	tests := []struct {
+		name  string
+		input SearchInput
+	}{
+		{"unknown sort", SearchInput{Query: "authentication", Sort: "created_at"}},
+		{"cursor with relevance", SearchInput{Query: "authentication", Cursor: &valid}},
+		{"cursor with offset", SearchInput{Query: "authentication", Sort: "updated_at", Cursor: &valid, Offset: 5}},
+		{"malformed cursor", SearchInput{Query: "authentication", Sort: "updated_at", Cursor: stringPtr("not-a-cursor")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Search(ctx, database, tt.input); !errors.Is(err, errors.ErrInvalidRequest) {
+				t.Errorf("Search error = %v, want INVALID_REQUEST", err)
+			}
+		})
+	}
+}
+
+func TestLongestSearchTerm(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"auth*", 4},
+		{"auth AND tokens", 6},
+		{`"of an" OR x`, 2},
+		{"NEAR(ab cd)", 2},
+		{"title:jwt", 3},
+		{"café", 4},
+		{"AND OR", 0},
+	}
+	for _, tt := range tests {
+		if got := longestSearchTerm(tt.query); got != tt.want {
+			t.Errorf("longestSearchTerm(%q) = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// Matches in two runs with distinct updated_at; the target run's are stored out of order
+	var want []string
+	for i, tc := range []struct {
+		runID string
+		ts    int64
+	}{
+		{"run-a", 200},
+		{"run-b", 500},
+		{"run-a", 400},
+		{"run-b", 300},
+		{"run-a", 100},
+	} {
+		out, err := Store(ctx, database, cfg, StoreInput{
+			CapsuleText: fmt.Sprintf("## Status\nResume checkpoint %d", i),
+			RunID:       stringPtr(tc.runID),
+			AllowThin:   true,
+		})
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		if _, err := database.Exec("UPDATE capsules SET updated_at = ? WHERE id = ?", tc.ts, out.ID); err != nil {
+			t.Fatalf("set updated_at: %v", err)
+		}
+		if tc.runID == "run-a" {
+			want = append(want, out.ID)
+		}
+	}
+	// Newest first: ts 400, 200, 100
+	want = []string{want[1], want[0], want[2]}
+
+	// Sort and RunID in opts are overridden
+	output, err := SearchRun(ctx, database, " run-a ", "checkpoint", SearchInput{Sort: "relevance", RunID: stringPtr("run-b")})
+	if err != nil {
+		t.Fatalf("SearchRun failed: %v", err)
+	}
+	if output.Sort != "updated_at" {
+		t.Errorf("Sort = %q, want updated_at", output.Sort)
+	}
+	var got []string
+	for _, item := range output.Items {
+		got = append(got, item.ID)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("items = %v, want %v (run-a only, newest first)", got, want)
+	}
+
+	if _, err := SearchRun(ctx, database, "  ", "checkpoint", SearchInput{}); !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("blank run_id error = %v, want ErrInvalidRequest", err)
+	}
+}
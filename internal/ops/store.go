@@ -3,7 +3,8 @@ package ops
 import (
 	"context"
 	"crypto/rand"
-	"database/sql"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -21,6 +22,7 @@ type StoreMode string
 const (
 	StoreModeError   StoreMode = "error"   // default: fail on name collision
 	StoreModeReplace StoreMode = "replace" // overwrite existing
+	StoreModeRename  StoreMode = "rename"  // store under the next free name-N suffix
 )
 
 // StoreInput contains parameters for the Store operation.
@@ -31,21 +33,44 @@ type StoreInput struct {
 	CapsuleText string  // required
 	Tags        []string
 	Source      *string
+	SourceType  *string   // "agent", "human", or "import"
+	SourceRef   *string   // optional URL/reference for the origin
+	Note        *string   // free-form annotation; not linted, searched, or composed
 	RunID       *string   // orchestration run ID
 	Phase       *string   // workflow phase
 	Role        *string   // agent role
+	ExpiresAt   *int64    // Unix seconds; after this the capsule reads as deleted (nil or 0 = never)
 	Mode        StoreMode // default: StoreModeError
 	AllowThin   bool
+
+	// DedupeWindowSeconds makes an unnamed store return the existing active
+	// unnamed capsule in the workspace with identical text, title, tags, run_id,
+	// phase and role, if one was updated within this many seconds, instead of
+	// inserting (0 = config store_dedupe_window_seconds). Named capsules are
+	// unaffected.
+	DedupeWindowSeconds int
 }
 
 // StoreOutput contains the result of the Store operation.
 type StoreOutput struct {
-	ID       string   `json:"id"`
-	FetchKey FetchKey `json:"fetch_key"`
+	ID           string   `json:"id"`
+	FetchKey     FetchKey `json:"fetch_key"`
+	WasDuplicate bool     `json:"was_duplicate,omitempty"` // an identical unnamed capsule was returned; nothing was stored
 }
 
 // Store creates or replaces a capsule.
-func Store(ctx context.Context, database *sql.DB, cfg *config.Config, input StoreInput) (*StoreOutput, error) {
+func Store(ctx context.Context, database db.Querier, cfg *config.Config, input StoreInput) (*StoreOutput, error) {
+	defer invalidateSearchCache()
+	return store(ctx, database, cfg, input)
+}
+
+// store is Store without the search-cache bump; Batch bumps once after its transaction commits.
+func store(ctx context.Context, q db.Querier, cfg *config.Config, input StoreInput) (*StoreOutput, error) {
+	// Normalize text formatting before validation, lint, and metrics
+	if cfg.NormalizeOnStore {
+		input.CapsuleText = capsule.NormalizeText(input.CapsuleText)
+	}
+
 	// Validate required fields
 	if input.CapsuleText == "" {
 		return nil, errors.NewInvalidRequest("capsule_text is required")
@@ -58,11 +83,28 @@ func Store(ctx context.Context, database *sql.DB, cfg *config.Config, input Stor
 	input.RunID = cleanOptionalString(input.RunID)
 	input.Phase = cleanOptionalString(input.Phase)
 	input.Role = cleanOptionalString(input.Role)
+	input.SourceType = cleanOptionalString(input.SourceType)
+	input.SourceRef = cleanOptionalString(input.SourceRef)
+	input.Note = cleanOptionalString(input.Note)
+	if err := validateSourceType(input.SourceType); err != nil {
+		return nil, err
+	}
+	expiresAt, err := cleanExpiresAt(input.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
 	if input.Mode == "" {
 		input.Mode = StoreModeError
 	}
-	if input.Mode != StoreModeError && input.Mode != StoreModeReplace {
-		return nil, errors.NewInvalidRequest("mode must be one of: error, replace")
+	if input.Mode != StoreModeError && input.Mode != StoreModeReplace && input.Mode != StoreModeRename {
+		return nil, errors.NewInvalidRequest("mode must be one of: error, replace, rename")
+	}
+	if input.DedupeWindowSeconds < 0 {
+		return nil, errors.NewInvalidRequest("dedupe_window_seconds must not be negative")
+	}
+	dedupeWindow := input.DedupeWindowSeconds
+	if dedupeWindow == 0 {
+		dedupeWindow = cfg.StoreDedupeWindowSeconds
 	}
 
 	// Normalize workspace
@@ -98,16 +140,26 @@ func Store(ctx context.Context, database *sql.DB, cfg *config.Config, input Stor
 	if lintResult.TooLarge {
 		return nil, errors.NewCapsuleTooLarge(lintResult.MaxChars, lintResult.ActualChars)
 	}
+	if err := checkCapsuleBytes(cfg, input.CapsuleText); err != nil {
+		return nil, err
+	}
 
 	if len(lintResult.MissingSections) > 0 {
 		return nil, errors.NewCapsuleTooThin(lintResult.MissingSections)
 	}
+	if err := checkSectionCount(cfg, input.CapsuleText, input.AllowThin); err != nil {
+		return nil, err
+	}
 
 	// Compute metrics
-	capsuleChars := capsule.CountChars(input.CapsuleText)
-	tokensEstimate := capsule.EstimateTokens(input.CapsuleText)
+	metrics := capsule.ComputeMetrics(input.CapsuleText)
 	now := time.Now().Unix()
 
+	tags := withWorkspaceDefaultTags(cfg, workspaceNorm, input.Tags)
+	if cfg.NormalizeTags {
+		tags = capsule.NormalizeTags(tags)
+	}
+
 	// Generate ULID for new capsule (may be discarded if upsert updates existing)
 	id, err := generateULID()
 	if err != nil {
@@ -122,15 +174,39 @@ func Store(ctx context.Context, database *sql.DB, cfg *config.Config, input Stor
 		NameNorm:       nameNorm,
 		Title:          title,
 		CapsuleText:    input.CapsuleText,
-		CapsuleChars:   capsuleChars,
-		TokensEstimate: tokensEstimate,
-		Tags:           input.Tags,
+		CapsuleChars:   metrics.Chars,
+		TokensEstimate: metrics.TokensEstimate,
+		LineCount:      metrics.Lines,
+		WordCount:      metrics.Words,
+		SectionCount:   metrics.Sections,
+		Tags:           tags,
 		Source:         input.Source,
 		RunID:          input.RunID,
 		Phase:          input.Phase,
 		Role:           input.Role,
+		SourceType:     input.SourceType,
+		SourceRef:      input.SourceRef,
+		Note:           input.Note,
 		CreatedAt:      now,
 		UpdatedAt:      now,
+		ExpiresAt:      expiresAt,
+	}
+
+	// A retried unnamed store returns the capsule the first attempt created.
+	// Only an exact retry matches: differing title, tags, run_id, phase or
+	// role store a new capsule.
+	if nameNorm == nil && dedupeWindow > 0 {
+		existingID, err := db.RecentUnnamedDuplicateID(ctx, q, c, now-int64(dedupeWindow))
+		if err != nil {
+			return nil, err
+		}
+		if existingID != "" {
+			return &StoreOutput{
+				ID:           existingID,
+				FetchKey:     BuildFetchKey(input.Workspace, "", existingID),
+				WasDuplicate: true,
+			}, nil
+		}
 	}
 
 	// Build name for fetch key
@@ -140,10 +216,26 @@ func Store(ctx context.Context, database *sql.DB, cfg *config.Config, input Stor
 	}
 
 	if input.Mode == StoreModeReplace {
+		// Replacing an existing capsule is net zero; only a new row counts
+		// against the quota.
+		if cfg.MaxCapsulesPerWorkspace > 0 {
+			exists := false
+			if nameNorm != nil {
+				if exists, err = db.CheckNameExists(ctx, q, workspaceNorm, *nameNorm); err != nil {
+					return nil, err
+				}
+			}
+			if !exists {
+				if err := checkWorkspaceQuota(ctx, q, cfg.MaxCapsulesPerWorkspace, input.Workspace, workspaceNorm); err != nil {
+					return nil, err
+				}
+			}
+		}
+
 		// Use atomic UPSERT to avoid race conditions between concurrent callers.
 		// If a capsule with the same (workspace, name) exists, it updates that capsule.
 		// Otherwise, it inserts a new capsule.
-		result, err := db.Upsert(ctx, database, c)
+		result, err := db.Upsert(ctx, q, c)
 		if err != nil {
 			return nil, err
 		}
@@ -154,8 +246,41 @@ func Store(ctx context.Context, database *sql.DB, cfg *config.Config, input Stor
 		}, nil
 	}
 
-	// mode:error - Insert and fail on conflict
-	if err := db.Insert(ctx, database, c); err != nil {
+	// mode:error and mode:rename insert a new row. An expired capsule that
+	// hasn't been swept yet still holds its name; when the name is taken, the
+	// holder is soft-deleted if (and only if) it has expired.
+	nameHeld := false
+	if nameNorm != nil {
+		taken, err := db.CheckNameExists(ctx, q, workspaceNorm, *nameNorm)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			swept, err := db.SweepExpiredName(ctx, q, workspaceNorm, *nameNorm, now)
+			if err != nil {
+				return nil, err
+			}
+			nameHeld = swept == 0
+		}
+	}
+
+	// mode:error onto a held name inserts nothing (Insert reports the
+	// collision), so it is not a quota question.
+	if cfg.MaxCapsulesPerWorkspace > 0 && (input.Mode == StoreModeRename || !nameHeld) {
+		if err := checkWorkspaceQuota(ctx, q, cfg.MaxCapsulesPerWorkspace, input.Workspace, workspaceNorm); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.Mode == StoreModeRename {
+		// Retries on the unique index, so concurrent stores of one name each get their own suffix
+		if err := db.InsertWithUniqueName(ctx, q, c); err != nil {
+			return nil, err
+		}
+		if c.NameRaw != nil {
+			name = *c.NameRaw
+		}
+	} else if err := db.Insert(ctx, q, c); err != nil {
 		return nil, err
 	}
 
@@ -165,6 +290,48 @@ func Store(ctx context.Context, database *sql.DB, cfg *config.Config, input Stor
 	}, nil
 }
 
+// checkWorkspaceQuota returns QUOTA_EXCEEDED if adding a capsule would take a
+// workspace past max. Callers only ask when a new row will be inserted.
+// Best-effort: concurrent stores may overshoot by the number of racing writers.
+func checkWorkspaceQuota(ctx context.Context, q db.Querier, max int, workspace, workspaceNorm string) error {
+	count, err := db.CountActiveInWorkspace(ctx, q, workspaceNorm)
+	if err != nil {
+		return err
+	}
+	if count >= max {
+		return errors.NewQuotaExceeded(workspace, max)
+	}
+	return nil
+}
+
+// checkCapsuleBytes returns CAPSULE_TOO_LARGE if text is over cfg.CapsuleMaxBytes
+// (0 = no byte limit). Callers check the char limit first; both must pass.
+func checkCapsuleBytes(cfg *config.Config, text string) error {
+	if cfg.CapsuleMaxBytes > 0 && len(text) > cfg.CapsuleMaxBytes {
+		return errors.NewCapsuleTooLargeBytes(cfg.CapsuleMaxBytes, len(text))
+	}
+	return nil
+}
+
+// checkSectionCount returns INVALID_REQUEST if text has more "##" sections
+// (outside code fences) than cfg.MaxSections (0 = unlimited). allow_thin skips
+// the check, as it skips lint.
+func checkSectionCount(cfg *config.Config, text string, allowThin bool) error {
+	if cfg.MaxSections <= 0 || allowThin {
+		return nil
+	}
+	count := 0
+	for _, s := range capsule.ParseSections(text) {
+		if len(s.Header)-len(strings.TrimLeft(s.Header, "#")) == 2 {
+			count++
+		}
+	}
+	if count > cfg.MaxSections {
+		return errors.NewTooManySections(cfg.MaxSections, count)
+	}
+	return nil
+}
+
 // generateULID generates a new ULID.
 func generateULID() (string, error) {
 	entropy := ulid.Monotonic(rand.Reader, 0)
@@ -174,3 +341,25 @@ func generateULID() (string, error) {
 	}
 	return id.String(), nil
 }
+
+// withWorkspaceDefaultTags appends the configured default tags for a workspace
+// to the explicit tags, skipping any already present. Config keys are compared
+// after normalization, so "Security" and "security" configure the same workspace.
+func withWorkspaceDefaultTags(cfg *config.Config, workspaceNorm string, tags []string) []string {
+	merged := tags
+	for _, key := range slices.Sorted(maps.Keys(cfg.WorkspaceDefaultTags)) {
+		if capsule.Normalize(key) != workspaceNorm {
+			continue
+		}
+		for _, tag := range cfg.WorkspaceDefaultTags[key] {
+			if slices.Contains(merged, tag) {
+				continue
+			}
+			if len(merged) == len(tags) {
+				merged = slices.Clone(tags) // don't append into the caller's slice
+			}
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
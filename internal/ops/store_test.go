@@ -4,7 +4,9 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/config"
 	"github.com/hpungsan/moss/internal/db"
 	"github.com/hpungsan/moss/internal/errors"
@@ -34,6 +36,10 @@ func stringPtr(s string) *string {
 	return &s
 }
 
+func boolPtr(b bool) *bool {
+	return &b
+}
+
 func TestStore_HappyPath_Named(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -172,6 +178,43 @@ func TestStore_TooLarge(t *testing.T) {
 	}
 }
 
+func TestStore_TooLargeBytes(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	// 80 chars, 240 bytes: under the char limit, over the byte limit
+	multibyte := strings.Repeat("日", 80)
+
+	cfg := &config.Config{CapsuleMaxChars: 100, CapsuleMaxBytes: 200}
+	_, err = Store(ctx, database, cfg, StoreInput{CapsuleText: multibyte, AllowThin: true})
+	if !errors.Is(err, errors.ErrCapsuleTooLarge) {
+		t.Fatalf("Store should return ErrCapsuleTooLarge, got: %v", err)
+	}
+	mossErr, ok := err.(*errors.MossError)
+	if !ok || mossErr.Details["actual_bytes"] != 240 || mossErr.Details["max_bytes"] != 200 {
+		t.Errorf("error details = %v, want actual_bytes 240 and max_bytes 200", err)
+	}
+
+	// The char limit still applies when it is the stricter one
+	cfg = &config.Config{CapsuleMaxChars: 50, CapsuleMaxBytes: 1000}
+	_, err = Store(ctx, database, cfg, StoreInput{CapsuleText: multibyte, AllowThin: true})
+	mossErr, ok = err.(*errors.MossError)
+	if !ok || mossErr.Details["max_chars"] != 50 {
+		t.Errorf("Store error = %v, want the char limit error", err)
+	}
+
+	// 0 disables the byte limit
+	cfg = &config.Config{CapsuleMaxChars: 100}
+	if _, err := Store(ctx, database, cfg, StoreInput{CapsuleText: multibyte, AllowThin: true}); err != nil {
+		t.Errorf("Store without a byte limit failed: %v", err)
+	}
+}
+
 func TestStore_TooThin(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -373,6 +416,140 @@ func TestStore_NameCollision_ModeReplace_Concurrent(t *testing.T) {
 	}
 }
 
+func TestStore_NameCollision_ModeRename_Concurrent(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+
+	// Run concurrent mode:rename stores of the same name. Every store should
+	// succeed under its own name even when the availability check races.
+	const numGoroutines = 8
+	errChan := make(chan error, numGoroutines)
+	nameChan := make(chan string, numGoroutines)
+
+	for i := 0; i < numGoroutines; i++ {
+		go func(idx int) {
+			input := StoreInput{
+				Workspace:   "default",
+				Name:        stringPtr("Plan"),
+				CapsuleText: validCapsuleText + "\nGoroutine " + string(rune('A'+idx)),
+				Mode:        StoreModeRename,
+			}
+			output, err := Store(context.Background(), database, cfg, input)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			nameChan <- output.FetchKey.MossCapsule
+			errChan <- nil
+		}(i)
+	}
+
+	var errs []error
+	for i := 0; i < numGoroutines; i++ {
+		if err := <-errChan; err != nil {
+			errs = append(errs, err)
+		}
+	}
+	close(nameChan)
+	if len(errs) > 0 {
+		t.Fatalf("Expected 0 errors, got %d: %v", len(errs), errs)
+	}
+
+	// One store keeps the requested name; the rest get distinct suffixes
+	seen := make(map[string]bool)
+	for name := range nameChan {
+		if seen[name] {
+			t.Errorf("name %q returned twice", name)
+		}
+		seen[name] = true
+	}
+	if !seen["Plan"] {
+		t.Errorf("names = %v, want one store to keep Plan", seen)
+	}
+	for i := 1; i < numGoroutines; i++ {
+		if name := "plan-" + string(rune('0'+i)); !seen[name] {
+			t.Errorf("names = %v, missing %s", seen, name)
+		}
+	}
+
+	count, err := db.CountActiveInWorkspace(context.Background(), database, "default")
+	if err != nil {
+		t.Fatalf("CountActiveInWorkspace failed: %v", err)
+	}
+	if count != numGoroutines {
+		t.Errorf("active capsules = %d, want %d", count, numGoroutines)
+	}
+}
+
+func TestStore_TextMetrics(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	output, err := Store(ctx, database, cfg, StoreInput{Name: stringPtr("metrics"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	fetched, err := Fetch(ctx, database, FetchInput{ID: output.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	// 6 headers + 6 body lines + 5 blank separators; "##" counts as a word
+	if fetched.LineCount != 17 || fetched.WordCount != 37 || fetched.SectionCount != 6 {
+		t.Errorf("(lines, words, sections) = (%d, %d, %d), want (17, 37, 6)", fetched.LineCount, fetched.WordCount, fetched.SectionCount)
+	}
+	if fetched.ReadingMinutes != 1 {
+		t.Errorf("ReadingMinutes = %d, want 1", fetched.ReadingMinutes)
+	}
+
+	// Summaries carry the same counts
+	list, err := List(ctx, database, ListInput{Workspace: "default"})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].LineCount != 17 || list.Items[0].WordCount != 37 || list.Items[0].SectionCount != 6 {
+		t.Errorf("list items = %+v, want one item with (17, 37, 6)", list.Items)
+	}
+
+	// Update recomputes; empty sections still count
+	thin := "## Objective\n## Decisions\n"
+	if _, err := Update(ctx, database, cfg, UpdateInput{ID: output.ID, CapsuleText: &thin, AllowThin: true}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	fetched, err = Fetch(ctx, database, FetchInput{ID: output.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.LineCount != 2 || fetched.WordCount != 4 || fetched.SectionCount != 2 {
+		t.Errorf("after update (lines, words, sections) = (%d, %d, %d), want (2, 4, 2)", fetched.LineCount, fetched.WordCount, fetched.SectionCount)
+	}
+
+	// Append recomputes
+	if _, err := Append(ctx, database, cfg, AppendInput{ID: output.ID, Section: "Decisions", Content: "Use JWT"}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	fetched, err = Fetch(ctx, database, FetchInput{ID: output.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.WordCount != 6 || fetched.SectionCount != 2 {
+		t.Errorf("after append (words, sections) = (%d, %d), want (6, 2)", fetched.WordCount, fetched.SectionCount)
+	}
+}
+
 func TestStore_TitleDefaultsToName(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -468,3 +645,642 @@ func TestStore_NormalizesWorkspaceAndName(t *testing.T) {
 		t.Errorf("NameNorm = %v, want %q", capsule.NameNorm, "auth system")
 	}
 }
+
+func TestStore_NormalizeOnStore(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	// Messy input: CRLF, trailing spaces, a run of blank lines
+	messy := strings.ReplaceAll(validCapsuleText, "\n", "  \r\n")
+	messy = strings.Replace(messy, "## Decisions", "\r\n\r\n\r\n## Decisions", 1)
+	want := validCapsuleText
+
+	cfg := config.DefaultConfig()
+
+	// Default: text stored byte-for-byte
+	raw, err := Store(context.Background(), database, cfg, StoreInput{
+		Name:        stringPtr("raw"),
+		CapsuleText: messy,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	c, err := db.GetByID(context.Background(), database, raw.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if c.CapsuleText != messy {
+		t.Errorf("CapsuleText = %q, want input unchanged", c.CapsuleText)
+	}
+
+	// Enabled: text normalized and metrics computed on the normalized text
+	cfg.NormalizeOnStore = true
+	normalized, err := Store(context.Background(), database, cfg, StoreInput{
+		Name:        stringPtr("normalized"),
+		CapsuleText: messy,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	c, err = db.GetByID(context.Background(), database, normalized.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if c.CapsuleText != want {
+		t.Errorf("CapsuleText = %q, want %q", c.CapsuleText, want)
+	}
+	if c.CapsuleChars != capsule.CountChars(want) {
+		t.Errorf("CapsuleChars = %d, want %d", c.CapsuleChars, capsule.CountChars(want))
+	}
+
+	// Update normalizes too
+	_, err = Update(context.Background(), database, cfg, UpdateInput{
+		ID:          normalized.ID,
+		CapsuleText: stringPtr(messy),
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	c, err = db.GetByID(context.Background(), database, normalized.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if c.CapsuleText != want {
+		t.Errorf("after Update: CapsuleText = %q, want %q", c.CapsuleText, want)
+	}
+}
+
+func TestStore_WorkspaceQuota(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.MaxCapsulesPerWorkspace = 2
+	ctx := context.Background()
+
+	for _, name := range []string{"one", "two"} {
+		if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "quota", Name: stringPtr(name), CapsuleText: validCapsuleText}); err != nil {
+			t.Fatalf("Store(%s) failed: %v", name, err)
+		}
+	}
+
+	// At the limit: new capsules are rejected, named or not
+	_, err = Store(ctx, database, cfg, StoreInput{Workspace: "Quota", Name: stringPtr("three"), CapsuleText: validCapsuleText})
+	if !errors.Is(err, errors.ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got: %v", err)
+	}
+	_, err = Store(ctx, database, cfg, StoreInput{Workspace: "quota", CapsuleText: validCapsuleText})
+	if !errors.Is(err, errors.ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded for unnamed capsule, got: %v", err)
+	}
+
+	// Replace of an existing capsule is net zero
+	if _, err := Store(ctx, database, cfg, StoreInput{
+		Workspace:   "quota",
+		Name:        stringPtr("two"),
+		CapsuleText: validCapsuleText,
+		Mode:        StoreModeReplace,
+	}); err != nil {
+		t.Errorf("replace at limit failed: %v", err)
+	}
+
+	// A colliding mode:error store inserts nothing, so it reports the collision
+	_, err = Store(ctx, database, cfg, StoreInput{Workspace: "quota", Name: stringPtr("two"), CapsuleText: validCapsuleText})
+	if !errors.Is(err, errors.ErrNameAlreadyExists) {
+		t.Errorf("store onto existing name at limit: expected ErrNameAlreadyExists, got: %v", err)
+	}
+
+	// Other workspaces have their own quota
+	if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "other", CapsuleText: validCapsuleText}); err != nil {
+		t.Errorf("Store in other workspace failed: %v", err)
+	}
+
+	// Deleting frees a slot
+	if _, err := Delete(ctx, database, DeleteInput{Workspace: "quota", Name: "one"}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "quota", Name: stringPtr("three"), CapsuleText: validCapsuleText}); err != nil {
+		t.Errorf("Store after delete failed: %v", err)
+	}
+}
+
+func TestStore_WorkspaceQuotaExpiredHolder(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.MaxCapsulesPerWorkspace = 1
+	ctx := context.Background()
+
+	// A workspace at its limit of one, held by an expired, unswept capsule.
+	// mode:error sweeps it and mode:replace overwrites it, so neither adds a
+	// row; mode:rename sweeps it too, then inserts into the freed slot.
+	for _, mode := range []StoreMode{StoreModeError, StoreModeReplace, StoreModeRename} {
+		t.Run(string(mode), func(t *testing.T) {
+			database, err := db.Init(t.TempDir())
+			if err != nil {
+				t.Fatalf("db.Init failed: %v", err)
+			}
+			defer database.Close()
+
+			if _, err := Store(ctx, database, cfg, StoreInput{
+				Workspace: "quota", Name: stringPtr("daily"), CapsuleText: validCapsuleText,
+				ExpiresAt: int64Ptr(time.Now().Unix() - 1),
+			}); err != nil {
+				t.Fatalf("Store failed: %v", err)
+			}
+			if _, err := Store(ctx, database, cfg, StoreInput{
+				Workspace: "quota", Name: stringPtr("daily"), CapsuleText: validCapsuleText, Mode: mode,
+			}); err != nil {
+				t.Fatalf("store over expired holder at limit failed: %v", err)
+			}
+
+			// The workspace is full again: a further new row is rejected
+			_, err = Store(ctx, database, cfg, StoreInput{Workspace: "quota", CapsuleText: validCapsuleText})
+			if !errors.Is(err, errors.ErrQuotaExceeded) {
+				t.Errorf("expected ErrQuotaExceeded, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestStore_SourceProvenance(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	output, err := Store(ctx, database, cfg, StoreInput{
+		Name:        stringPtr("provenance"),
+		CapsuleText: validCapsuleText,
+		Source:      stringPtr("claude-code"),
+		SourceType:  stringPtr(" agent "),
+		SourceRef:   stringPtr("https://github.com/hpungsan/moss/pull/42"),
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	fetched, err := Fetch(ctx, database, FetchInput{ID: output.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.SourceType == nil || *fetched.SourceType != SourceTypeAgent {
+		t.Errorf("SourceType = %v, want agent (trimmed)", fetched.SourceType)
+	}
+	if fetched.SourceRef == nil || *fetched.SourceRef != "https://github.com/hpungsan/moss/pull/42" {
+		t.Errorf("SourceRef = %v, want PR URL", fetched.SourceRef)
+	}
+	if fetched.Source == nil || *fetched.Source != "claude-code" {
+		t.Errorf("Source = %v, want legacy source preserved", fetched.Source)
+	}
+
+	// Export record carries the provenance fields
+	record, err := FetchRecord(ctx, database, FetchRecordInput{ID: output.ID})
+	if err != nil {
+		t.Fatalf("FetchRecord failed: %v", err)
+	}
+	if record.SourceType == nil || *record.SourceType != SourceTypeAgent || record.SourceRef == nil {
+		t.Errorf("export record provenance = %v/%v, want agent/ref", record.SourceType, record.SourceRef)
+	}
+
+	// Unknown source type is rejected
+	_, err = Store(ctx, database, cfg, StoreInput{
+		CapsuleText: validCapsuleText,
+		SourceType:  stringPtr("robot"),
+	})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("unknown source_type: err = %v, want INVALID_REQUEST", err)
+	}
+}
+
+func TestStore_WorkspaceDefaultTags(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.WorkspaceDefaultTags = map[string][]string{
+		"Security": {"sec", "review"},
+		"other":    {"unrelated"},
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		workspace string
+		tags      []string
+		want      []string
+	}{
+		{"defaults only", "security", nil, []string{"sec", "review"}},
+		{"explicit kept first", "SECURITY", []string{"auth"}, []string{"auth", "sec", "review"}},
+		{"explicit default deduped", "security", []string{"review", "auth"}, []string{"review", "auth", "sec"}},
+		{"no defaults for workspace", "default", []string{"auth"}, []string{"auth"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := StoreInput{Workspace: tt.workspace, CapsuleText: validCapsuleText, Tags: tt.tags}
+			out, err := Store(ctx, database, cfg, input)
+			if err != nil {
+				t.Fatalf("Store failed: %v", err)
+			}
+			c, err := db.GetByID(ctx, database, out.ID, false)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if strings.Join(c.Tags, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Tags = %v, want %v", c.Tags, tt.want)
+			}
+		})
+	}
+
+	// Clearing tags on update does not re-add defaults
+	out, err := Store(ctx, database, cfg, StoreInput{Workspace: "security", Name: stringPtr("cleared"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	empty := []string{}
+	if _, err := Update(ctx, database, cfg, UpdateInput{ID: out.ID, Tags: &empty}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	c, err := db.GetByID(ctx, database, out.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if len(c.Tags) != 0 {
+		t.Errorf("Tags after clearing = %v, want none", c.Tags)
+	}
+
+	// Re-storing applies them again
+	if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "security", Name: stringPtr("cleared"), CapsuleText: validCapsuleText, Mode: StoreModeReplace}); err != nil {
+		t.Fatalf("Store replace failed: %v", err)
+	}
+	c, err = db.GetByID(ctx, database, out.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if strings.Join(c.Tags, ",") != "sec,review" {
+		t.Errorf("Tags after re-store = %v, want [sec review]", c.Tags)
+	}
+}
+
+func TestStore_NormalizeTags(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.NormalizeTags = true
+	cfg.WorkspaceDefaultTags = map[string][]string{"proj": {"Team A"}}
+	ctx := context.Background()
+
+	out, err := Store(ctx, database, cfg, StoreInput{
+		Workspace:   "proj",
+		Name:        stringPtr("mixed"),
+		CapsuleText: validCapsuleText,
+		Tags:        []string{"Security", " security ", "Auth"},
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	c, err := db.GetByID(ctx, database, out.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if strings.Join(c.Tags, ",") != "security,auth,team a" {
+		t.Errorf("Tags = %v, want [security auth team a]", c.Tags)
+	}
+
+	// Filters are normalized the same way
+	tag := "  SECURITY "
+	inv, err := Inventory(ctx, database, InventoryInput{Tag: &tag, NormalizeTags: true})
+	if err != nil {
+		t.Fatalf("Inventory failed: %v", err)
+	}
+	if len(inv.Items) != 1 || inv.Items[0].ID != out.ID {
+		t.Errorf("Inventory(tag=%q) = %d items, want the stored capsule", tag, len(inv.Items))
+	}
+	search, err := Search(ctx, database, SearchInput{Query: "authentication", Tag: &tag, NormalizeTags: true})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(search.Items) != 1 {
+		t.Errorf("Search(tag=%q) = %d items, want 1", tag, len(search.Items))
+	}
+
+	// Off: filters are only trimmed, so a different case misses
+	inv, err = Inventory(ctx, database, InventoryInput{Tag: &tag})
+	if err != nil {
+		t.Fatalf("Inventory failed: %v", err)
+	}
+	if len(inv.Items) != 0 {
+		t.Errorf("Inventory without normalization = %d items, want 0", len(inv.Items))
+	}
+
+	// Update normalizes replacement tags
+	tags := []string{"Backend", "BACKEND"}
+	if _, err := Update(ctx, database, cfg, UpdateInput{ID: out.ID, Tags: &tags}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	c, err = db.GetByID(ctx, database, out.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if strings.Join(c.Tags, ",") != "backend" {
+		t.Errorf("Tags after update = %v, want [backend]", c.Tags)
+	}
+
+	// BulkUpdate normalizes both the filter and the new tags
+	filterTag := "Backend"
+	setTags := []string{"Ops", "ops"}
+	bulk, err := BulkUpdate(ctx, database, BulkUpdateInput{Tag: &filterTag, SetTags: &setTags, NormalizeTags: true})
+	if err != nil {
+		t.Fatalf("BulkUpdate failed: %v", err)
+	}
+	if bulk.Updated != 1 {
+		t.Errorf("Updated = %d, want 1", bulk.Updated)
+	}
+	c, err = db.GetByID(ctx, database, out.ID, false)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if strings.Join(c.Tags, ",") != "ops" {
+		t.Errorf("Tags after bulk update = %v, want [ops]", c.Tags)
+	}
+}
+
+func TestStore_MaxSections(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	cfg := config.DefaultConfig()
+	cfg.MaxSections = 7
+
+	// validCapsuleText has 6 "##" sections; a title and subheadings don't count
+	atLimit := "# Auth\n\n" + validCapsuleText + "\n## Risks\nToken theft.\n\n### Mitigations\nShort expiry.\n"
+	if _, err := Store(ctx, database, cfg, StoreInput{CapsuleText: atLimit}); err != nil {
+		t.Fatalf("Store at the limit failed: %v", err)
+	}
+
+	// Neither do headings inside code fences
+	fenced := validCapsuleText + "\n## Risks\nToken theft.\n\n```\n## not a section\n```\n"
+	if _, err := Store(ctx, database, cfg, StoreInput{CapsuleText: fenced}); err != nil {
+		t.Fatalf("Store with a fenced heading failed: %v", err)
+	}
+
+	overLimit := atLimit + "\n## Extra\nOne too many.\n"
+	_, err = Store(ctx, database, cfg, StoreInput{CapsuleText: overLimit})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Fatalf("Store over the limit should return INVALID_REQUEST, got: %v", err)
+	}
+	mossErr, ok := err.(*errors.MossError)
+	if !ok {
+		t.Fatalf("expected *MossError, got %T", err)
+	}
+	if mossErr.Details["actual_sections"] != 8 || mossErr.Details["max_sections"] != 7 {
+		t.Errorf("Details = %v, want actual_sections 8 and max_sections 7", mossErr.Details)
+	}
+
+	// allow_thin bypasses the check
+	if _, err := Store(ctx, database, cfg, StoreInput{CapsuleText: overLimit, AllowThin: true}); err != nil {
+		t.Errorf("Store with allow_thin failed: %v", err)
+	}
+
+	// 0 means unlimited
+	cfg.MaxSections = 0
+	if _, err := Store(ctx, database, cfg, StoreInput{CapsuleText: overLimit}); err != nil {
+		t.Errorf("Store with max_sections 0 failed: %v", err)
+	}
+}
+
+func TestStore_CompressText(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.CompressText = true
+	db.ConfigureTextCompression(cfg)
+	t.Cleanup(func() { db.ConfigureTextCompression(nil) })
+	ctx := context.Background()
+
+	// Highly compressible: the same line many times over
+	large := validCapsuleText + "\n## Notes\n" + strings.Repeat("Rotate the signing keys every quarter.\n", 250)
+	out, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", Name: stringPtr("keys"), CapsuleText: large})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	var plain string
+	var gzLen int
+	if err := database.QueryRow("SELECT capsule_text, length(capsule_text_gz) FROM capsules WHERE id = ?", out.ID).Scan(&plain, &gzLen); err != nil {
+		t.Fatalf("raw read failed: %v", err)
+	}
+	if plain != "" || gzLen == 0 || gzLen >= len(large)/10 {
+		t.Errorf("stored plain %d chars, gz %d bytes; want compressed (text %d chars)", len(plain), gzLen, len(large))
+	}
+
+	fetched, err := Fetch(ctx, database, FetchInput{ID: out.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.CapsuleText != large {
+		t.Error("fetched text differs from stored text")
+	}
+
+	search, err := Search(ctx, database, SearchInput{Query: "signing"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(search.Items) != 1 || !strings.Contains(search.Items[0].Snippet, "<b>signing</b>") {
+		t.Errorf("search items = %+v, want one match with a snippet", search.Items)
+	}
+
+	// Update replaces the compressed text and the index follows
+	updated := strings.Replace(large, "signing keys", "TLS certificates", -1)
+	if _, err := Update(ctx, database, cfg, UpdateInput{ID: out.ID, CapsuleText: &updated}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	fetched, err = Fetch(ctx, database, FetchInput{ID: out.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.CapsuleText != updated {
+		t.Error("fetched text differs from updated text")
+	}
+	for query, want := range map[string]int{"signing": 0, "certificates": 1} {
+		search, err := Search(ctx, database, SearchInput{Query: query})
+		if err != nil {
+			t.Fatalf("Search %q failed: %v", query, err)
+		}
+		if len(search.Items) != want {
+			t.Errorf("Search %q = %d items, want %d", query, len(search.Items), want)
+		}
+	}
+
+	// Short text that gzip would grow is stored as is
+	short, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", CapsuleText: "tiny", AllowThin: true})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if err := database.QueryRow("SELECT capsule_text FROM capsules WHERE id = ?", short.ID).Scan(&plain); err != nil {
+		t.Fatalf("raw read failed: %v", err)
+	}
+	if plain != "tiny" {
+		t.Error("short capsule should be stored uncompressed")
+	}
+}
+
+func TestStore_DedupeWindow(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	first, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", CapsuleText: validCapsuleText, DedupeWindowSeconds: 60})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if first.WasDuplicate {
+		t.Error("first store should not be a duplicate")
+	}
+
+	// A retry within the window returns the first capsule
+	retry, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", CapsuleText: validCapsuleText, DedupeWindowSeconds: 60})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if !retry.WasDuplicate || retry.ID != first.ID {
+		t.Errorf("retry = %+v, want was_duplicate with ID %s", retry, first.ID)
+	}
+
+	// Other workspaces, other text, no window, and named capsules all insert
+	for _, input := range []StoreInput{
+		{Workspace: "other", CapsuleText: validCapsuleText, DedupeWindowSeconds: 60},
+		{Workspace: "proj", CapsuleText: validCapsuleText + "\nMore.", DedupeWindowSeconds: 60},
+		{Workspace: "proj", CapsuleText: validCapsuleText},
+		{Workspace: "proj", Name: stringPtr("auth"), CapsuleText: validCapsuleText, DedupeWindowSeconds: 60},
+	} {
+		out, err := Store(ctx, database, cfg, input)
+		if err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		if out.WasDuplicate || out.ID == first.ID {
+			t.Errorf("store %+v deduped, want a new capsule", input)
+		}
+	}
+
+	// Outside the window: the existing capsule is too old, so a new one is stored
+	if _, err := database.Exec("UPDATE capsules SET updated_at = updated_at - 600 WHERE workspace_norm = 'proj' AND name_norm IS NULL"); err != nil {
+		t.Fatalf("age capsules failed: %v", err)
+	}
+	late, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", CapsuleText: validCapsuleText, DedupeWindowSeconds: 60})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if late.WasDuplicate || late.ID == first.ID {
+		t.Errorf("late store = %+v, want a new capsule outside the window", late)
+	}
+
+	// The config default applies when the store doesn't set a window
+	cfg.StoreDedupeWindowSeconds = 60
+	again, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if !again.WasDuplicate || again.ID != late.ID {
+		t.Errorf("store with config window = %+v, want duplicate of %s", again, late.ID)
+	}
+
+	if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "proj", CapsuleText: validCapsuleText, DedupeWindowSeconds: -1}); !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("negative window err = %v, want INVALID_REQUEST", err)
+	}
+}
+
+func TestStore_DedupeRequiresMatchingMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	base := StoreInput{
+		Workspace:           "proj",
+		CapsuleText:         validCapsuleText,
+		Title:               stringPtr("Auth notes"),
+		Tags:                []string{"auth", "review"},
+		RunID:               stringPtr("run-1"),
+		Phase:               stringPtr("design"),
+		Role:                stringPtr("planner"),
+		DedupeWindowSeconds: 60,
+	}
+	first, err := Store(ctx, database, cfg, base)
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	// An exact retry is deduplicated
+	retry, err := Store(ctx, database, cfg, base)
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if !retry.WasDuplicate || retry.ID != first.ID {
+		t.Errorf("retry = %+v, want was_duplicate with ID %s", retry, first.ID)
+	}
+
+	// Same text with different metadata stores a new capsule
+	variants := map[string]func(*StoreInput){
+		"title":    func(in *StoreInput) { in.Title = stringPtr("Other title") },
+		"no title": func(in *StoreInput) { in.Title = nil },
+		"tags":     func(in *StoreInput) { in.Tags = []string{"auth"} },
+		"run_id":   func(in *StoreInput) { in.RunID = stringPtr("run-2") },
+		"phase":    func(in *StoreInput) { in.Phase = stringPtr("review") },
+		"role":     func(in *StoreInput) { in.Role = nil },
+	}
+	for name, mutate := range variants {
+		t.Run(name, func(t *testing.T) {
+			input := base
+			mutate(&input)
+			out, err := Store(ctx, database, cfg, input)
+			if err != nil {
+				t.Fatalf("Store failed: %v", err)
+			}
+			if out.WasDuplicate || out.ID == first.ID {
+				t.Errorf("store with different %s = %+v, want a new capsule", name, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package ops
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hpungsan/moss/internal/db"
+)
+
+// SweepExpiredOutput contains the result of the SweepExpired operation.
+type SweepExpiredOutput struct {
+	Swept   int    `json:"swept"`
+	Message string `json:"message"`
+}
+
+// SweepExpired soft-deletes active capsules whose expires_at has passed.
+// Reads already hide expired capsules; the sweep makes that durable so they
+// show up as deleted everywhere (list, inventory, search) and can be purged.
+func SweepExpired(ctx context.Context, database db.Querier) (*SweepExpiredOutput, error) {
+	defer invalidateSearchCache()
+
+	count, err := db.SweepExpired(ctx, database, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	message := "No expired capsules"
+	if count == 1 {
+		message = "Soft-deleted 1 expired capsule"
+	} else if count > 1 {
+		message = fmt.Sprintf("Soft-deleted %d expired capsules", count)
+	}
+
+	return &SweepExpiredOutput{
+		Swept:   count,
+		Message: message,
+	}, nil
+}
@@ -0,0 +1,210 @@
+package ops
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+func int64Ptr(n int64) *int64 {
+	return &n
+}
+
+func TestSweepExpired_PastExpiry(t *testing.T) {
+	database, err := db.Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	stored, err := Store(ctx, database, cfg, StoreInput{
+		Workspace:   "default",
+		Name:        stringPtr("today"),
+		CapsuleText: validCapsuleText,
+		ExpiresAt:   int64Ptr(time.Now().Unix() - 60),
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	// Invisible to fetch before any sweep runs
+	if _, err := Fetch(ctx, database, FetchInput{ID: stored.ID}); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("Fetch by id of expired capsule should return ErrNotFound, got: %v", err)
+	}
+	if _, err := Fetch(ctx, database, FetchInput{Workspace: "default", Name: "today"}); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("Fetch by name of expired capsule should return ErrNotFound, got: %v", err)
+	}
+
+	output, err := SweepExpired(ctx, database)
+	if err != nil {
+		t.Fatalf("SweepExpired failed: %v", err)
+	}
+	if output.Swept != 1 {
+		t.Errorf("Swept = %d, want 1", output.Swept)
+	}
+
+	fetched, err := Fetch(ctx, database, FetchInput{ID: stored.ID, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Fetch(include_deleted) failed: %v", err)
+	}
+	if fetched.DeletedAt == nil {
+		t.Error("sweep should set deleted_at")
+	}
+	if fetched.ExpiresAt == nil {
+		t.Error("expires_at should be reported")
+	}
+}
+
+func TestStore_ReusesNameOfExpiredCapsule(t *testing.T) {
+	database, err := db.Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	input := StoreInput{Workspace: "default", Name: stringPtr("daily"), CapsuleText: validCapsuleText}
+	input.ExpiresAt = int64Ptr(time.Now().Unix() - 1)
+	if _, err := Store(ctx, database, cfg, input); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	// The expired capsule hasn't been swept, but its name is free again
+	input.ExpiresAt = nil
+	second, err := Store(ctx, database, cfg, input)
+	if err != nil {
+		t.Fatalf("Store over expired name failed: %v", err)
+	}
+	fetched, err := Fetch(ctx, database, FetchInput{Workspace: "default", Name: "daily"})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.ID != second.ID || fetched.ExpiresAt != nil {
+		t.Errorf("fetched = %s (expires %v), want %s with no expiry", fetched.ID, fetched.ExpiresAt, second.ID)
+	}
+}
+
+func TestStore_SweepsCollidingHandleOnlyWhenExpired(t *testing.T) {
+	database, err := db.Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// mode:rename over an expired holder takes the name itself, with no suffix
+	if _, err := Store(ctx, database, cfg, StoreInput{
+		Workspace: "default", Name: stringPtr("daily"), CapsuleText: validCapsuleText,
+		ExpiresAt: int64Ptr(time.Now().Unix() - 1),
+	}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	renamed, err := Store(ctx, database, cfg, StoreInput{
+		Workspace: "default", Name: stringPtr("daily"), CapsuleText: validCapsuleText, Mode: StoreModeRename,
+	})
+	if err != nil {
+		t.Fatalf("Store mode:rename failed: %v", err)
+	}
+	if renamed.FetchKey.MossCapsule != "daily" {
+		t.Errorf("mode:rename name = %q, want daily", renamed.FetchKey.MossCapsule)
+	}
+
+	// An unexpired holder is left alone: mode:error still collides
+	_, err = Store(ctx, database, cfg, StoreInput{Workspace: "default", Name: stringPtr("daily"), CapsuleText: validCapsuleText})
+	if !errors.Is(err, errors.ErrNameAlreadyExists) {
+		t.Fatalf("Store over active name: err = %v, want NAME_ALREADY_EXISTS", err)
+	}
+	c, err := db.GetByID(ctx, database, renamed.ID, true)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if c.DeletedAt != nil {
+		t.Error("an unexpired capsule must not be swept by a colliding store")
+	}
+}
+
+func TestStore_SweepsOnlyTargetHandle(t *testing.T) {
+	database, err := db.Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	// An expired capsule under another name stays unswept by the store
+	other, err := Store(ctx, database, cfg, StoreInput{
+		Workspace: "default", Name: stringPtr("other"), CapsuleText: validCapsuleText,
+		ExpiresAt: int64Ptr(time.Now().Unix() - 1),
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if _, err := Store(ctx, database, cfg, StoreInput{Workspace: "default", Name: stringPtr("daily"), CapsuleText: validCapsuleText}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	c, err := db.GetByID(ctx, database, other.ID, true)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if c.DeletedAt != nil {
+		t.Errorf("DeletedAt = %v, want nil: store should only sweep its own handle", *c.DeletedAt)
+	}
+}
+
+func TestUpdate_ExpiresAt(t *testing.T) {
+	database, err := db.Init(t.TempDir())
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	stored, err := Store(ctx, database, cfg, StoreInput{CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	future := time.Now().Unix() + 3600
+	if _, err := Update(ctx, database, cfg, UpdateInput{ID: stored.ID, ExpiresAt: &future}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	fetched, err := Fetch(ctx, database, FetchInput{ID: stored.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.ExpiresAt == nil || *fetched.ExpiresAt != future {
+		t.Errorf("ExpiresAt = %v, want %d", fetched.ExpiresAt, future)
+	}
+
+	// 0 clears
+	if _, err := Update(ctx, database, cfg, UpdateInput{ID: stored.ID, ExpiresAt: int64Ptr(0)}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	fetched, err = Fetch(ctx, database, FetchInput{ID: stored.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.ExpiresAt != nil {
+		t.Errorf("ExpiresAt = %v, want nil after clearing", *fetched.ExpiresAt)
+	}
+
+	if _, err := Update(ctx, database, cfg, UpdateInput{ID: stored.ID, ExpiresAt: int64Ptr(-5)}); !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("negative expires_at should be INVALID_REQUEST, got: %v", err)
+	}
+}
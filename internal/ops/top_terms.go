@@ -0,0 +1,66 @@
+package ops
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/hpungsan/moss/internal/capsule"
+	"github.com/hpungsan/moss/internal/db"
+)
+
+// TopTerms limits
+const (
+	DefaultTopTermsLimit = 20
+	MaxTopTermsLimit     = 100
+)
+
+// topTermsSectionWords are the words of the six required section headings,
+// which every linted capsule contains.
+var topTermsSectionWords = map[string]bool{
+	"objective": true, "current": true, "status": true, "decisions": true, "next": true,
+	"actions": true, "key": true, "locations": true, "open": true, "questions": true,
+}
+
+// TopTermsInput contains parameters for the TopTerms operation.
+type TopTermsInput struct {
+	Workspace string // defaults to "default"
+	Limit     int    // default: 20, max: 100
+}
+
+// TopTermsOutput contains the result of the TopTerms operation.
+type TopTermsOutput struct {
+	Workspace string             `json:"workspace"`
+	Terms     []db.TermFrequency `json:"terms"` // most capsules first, ties alphabetical
+}
+
+// TopTerms returns the most common meaningful terms across a workspace's active
+// capsules, counted by how many capsules contain each term (title, body, and name).
+// Stopwords, section heading words, numbers, and terms shorter than three
+// characters are left out, as in Related.
+func TopTerms(ctx context.Context, database db.Querier, input TopTermsInput) (*TopTermsOutput, error) {
+	workspace := capsule.Normalize(input.Workspace)
+	if workspace == "" {
+		workspace = "default"
+	}
+
+	limit := input.Limit
+	if limit <= 0 {
+		limit = DefaultTopTermsLimit
+	}
+	if limit > MaxTopTermsLimit {
+		limit = MaxTopTermsLimit
+	}
+
+	terms, err := db.TopTerms(ctx, database, workspace, limit, func(term string) bool {
+		return utf8.RuneCountInString(term) < relatedMinTermChars ||
+			relatedStopwords[term] || topTermsSectionWords[term] || isDigits(term)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if terms == nil {
+		terms = []db.TermFrequency{}
+	}
+
+	return &TopTermsOutput{Workspace: workspace, Terms: terms}, nil
+}
@@ -0,0 +1,70 @@
+package ops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+)
+
+func TestTopTerms_RanksByDocumentFrequency(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	texts := []struct{ workspace, text string }{
+		{"auth", "## Objective\nRotate refresh tokens for the gateway."},
+		{"auth", "## Objective\nRefresh tokens are hashed at rest."},
+		{"auth", "## Objective\nAudit refresh token reuse and the kerberos bridge."},
+		{"billing", "## Objective\nKerberos invoices, kerberos payments, kerberos everywhere."},
+		{"billing", "## Objective\nKerberos reconciliation."},
+	}
+	for _, c := range texts {
+		if _, err := Store(ctx, database, cfg, StoreInput{Workspace: c.workspace, CapsuleText: c.text, AllowThin: true}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	output, err := TopTerms(ctx, database, TopTermsInput{Workspace: "Auth"})
+	if err != nil {
+		t.Fatalf("TopTerms failed: %v", err)
+	}
+	if output.Workspace != "auth" {
+		t.Errorf("Workspace = %q, want auth", output.Workspace)
+	}
+
+	rank := map[string]int{}
+	for i, tf := range output.Terms {
+		rank[tf.Term] = i
+		switch tf.Term {
+		case "objective", "the", "for":
+			t.Errorf("Terms contain %q, should skip section headings and stopwords", tf.Term)
+		}
+	}
+	if len(output.Terms) == 0 || output.Terms[0].Term != "refresh" || output.Terms[0].Documents != 3 {
+		t.Fatalf("Terms[0] = %+v, want refresh in 3 capsules", output.Terms)
+	}
+	// Kerberos is common in billing but appears in only one auth capsule
+	kerberos, ok := rank["kerberos"]
+	if !ok {
+		t.Fatal("kerberos missing from terms")
+	}
+	if output.Terms[kerberos].Documents != 1 || rank["tokens"] > kerberos {
+		t.Errorf("Terms = %+v, want tokens (2) ranked above kerberos (1)", output.Terms)
+	}
+
+	limited, err := TopTerms(ctx, database, TopTermsInput{Workspace: "auth", Limit: 2})
+	if err != nil {
+		t.Fatalf("TopTerms failed: %v", err)
+	}
+	if len(limited.Terms) != 2 {
+		t.Errorf("len(Terms) = %d, want 2", len(limited.Terms))
+	}
+}
@@ -0,0 +1,44 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/hpungsan/moss/internal/db"
+	"github.com/hpungsan/moss/internal/errors"
+)
+
+// WithTx runs fn inside a single transaction, for embedders composing several
+// ops atomically. Ops that take a db.Querier (Store, Update, Delete, Fetch, ...)
+// accept the *sql.Tx directly. If fn returns an error or panics, every write
+// it made is rolled back and the error is returned unchanged; otherwise the
+// transaction is committed and only then replayed on the mirror, if any.
+//
+// Ops that manage their own transaction (Batch, Compose, Patch, ...) still take
+// *sql.DB; calling them from inside fn runs them outside the transaction, where
+// they can block on its write lock.
+func WithTx(ctx context.Context, database *sql.DB, fn func(tx *sql.Tx) error) error {
+	// Deferred first so the bump lands after commit or rollback, not before
+	defer invalidateSearchCache()
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return errors.NewCancelled("transaction")
+		}
+		return errors.NewInternal(err)
+	}
+	defer db.Rollback(tx) //nolint:errcheck
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := db.Commit(tx); err != nil {
+		if ctx.Err() != nil {
+			return errors.NewCancelled("transaction")
+		}
+		return errors.NewInternal(err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package ops
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/hpungsan/moss/internal/config"
+	"github.com/hpungsan/moss/internal/db"
+)
+
+func TestWithTx_RollsBackOnError(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	existing, err := Store(ctx, database, cfg, StoreInput{Workspace: "ws", Name: stringPtr("existing"), CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	injected := stderrors.New("injected failure")
+	err = WithTx(ctx, database, func(tx *sql.Tx) error {
+		if _, err := Store(ctx, tx, cfg, StoreInput{Workspace: "ws", Name: stringPtr("one"), CapsuleText: validCapsuleText}); err != nil {
+			return err
+		}
+		if _, err := Store(ctx, tx, cfg, StoreInput{Workspace: "ws", Name: stringPtr("two"), CapsuleText: validCapsuleText}); err != nil {
+			return err
+		}
+		if _, err := Delete(ctx, tx, DeleteInput{ID: existing.ID}); err != nil {
+			return err
+		}
+		return injected
+	})
+	if !stderrors.Is(err, injected) {
+		t.Fatalf("WithTx error = %v, want the injected error", err)
+	}
+
+	// Neither store landed
+	for _, name := range []string{"one", "two"} {
+		if _, err := db.GetByName(ctx, database, "ws", name, true); err == nil {
+			t.Errorf("capsule %q should not exist after rollback", name)
+		}
+	}
+	// The delete was undone
+	if _, err := db.GetByID(ctx, database, existing.ID, false); err != nil {
+		t.Errorf("existing capsule should still be active after rollback: %v", err)
+	}
+}
+
+func TestWithTx_Commits(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	var storedID string
+	err = WithTx(ctx, database, func(tx *sql.Tx) error {
+		out, err := Store(ctx, tx, cfg, StoreInput{Workspace: "ws", Name: stringPtr("one"), CapsuleText: validCapsuleText})
+		if err != nil {
+			return err
+		}
+		storedID = out.ID
+
+		// Reads inside the transaction see its own writes
+		_, err = Fetch(ctx, tx, FetchInput{ID: out.ID})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+
+	if _, err := db.GetByID(ctx, database, storedID, false); err != nil {
+		t.Errorf("capsule should exist after commit: %v", err)
+	}
+}
+
+func TestWithTx_MirrorsOnlyCommittedWrites(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.MirrorPath = filepath.Join(t.TempDir(), "mirror.db")
+	if err := db.ConfigureMirror(database, cfg); err != nil {
+		t.Fatalf("ConfigureMirror failed: %v", err)
+	}
+	t.Cleanup(func() { db.CloseMirror() }) //nolint:errcheck
+	ctx := context.Background()
+
+	injected := stderrors.New("injected failure")
+	err = WithTx(ctx, database, func(tx *sql.Tx) error {
+		if _, err := Store(ctx, tx, cfg, StoreInput{Workspace: "ws", Name: stringPtr("rolled"), CapsuleText: validCapsuleText}); err != nil {
+			return err
+		}
+		return injected
+	})
+	if !stderrors.Is(err, injected) {
+		t.Fatalf("WithTx error = %v, want the injected error", err)
+	}
+	err = WithTx(ctx, database, func(tx *sql.Tx) error {
+		_, err := Store(ctx, tx, cfg, StoreInput{Workspace: "ws", Name: stringPtr("kept"), CapsuleText: validCapsuleText})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+
+	if err := db.CloseMirror(); err != nil {
+		t.Fatalf("CloseMirror failed: %v", err)
+	}
+	mirror, err := sql.Open("sqlite", cfg.MirrorPath)
+	if err != nil {
+		t.Fatalf("open mirror failed: %v", err)
+	}
+	defer mirror.Close()
+
+	if _, err := db.GetByName(ctx, mirror, "ws", "rolled", true); err == nil {
+		t.Error("store from a rolled-back transaction reached the mirror")
+	}
+	if _, err := db.GetByName(ctx, mirror, "ws", "kept", true); err != nil {
+		t.Errorf("committed store missing from mirror: %v", err)
+	}
+}
@@ -2,7 +2,6 @@ package ops
 
 import (
 	"context"
-	"database/sql"
 
 	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/config"
@@ -22,9 +21,13 @@ type UpdateInput struct {
 	Title       *string
 	Tags        *[]string
 	Source      *string
+	SourceType  *string // "agent", "human", or "import"; "" clears
+	SourceRef   *string // optional URL/reference; "" clears
+	Note        *string // free-form annotation outside capsule_text; "" clears
 	RunID       *string // orchestration run ID
 	Phase       *string // workflow phase
 	Role        *string // agent role
+	ExpiresAt   *int64  // Unix seconds; 0 clears
 
 	AllowThin bool
 }
@@ -36,7 +39,13 @@ type UpdateOutput struct {
 }
 
 // Update modifies an existing capsule.
-func Update(ctx context.Context, database *sql.DB, cfg *config.Config, input UpdateInput) (*UpdateOutput, error) {
+func Update(ctx context.Context, database db.Querier, cfg *config.Config, input UpdateInput) (*UpdateOutput, error) {
+	defer invalidateSearchCache()
+	return update(ctx, database, cfg, input)
+}
+
+// update is Update without the search-cache bump; Batch bumps once after its transaction commits.
+func update(ctx context.Context, q db.Querier, cfg *config.Config, input UpdateInput) (*UpdateOutput, error) {
 	// Validate address
 	addr, err := ValidateAddress(input.ID, input.Workspace, input.Name)
 	if err != nil {
@@ -45,16 +54,28 @@ func Update(ctx context.Context, database *sql.DB, cfg *config.Config, input Upd
 
 	// Validate at least one editable field is provided
 	if input.CapsuleText == nil && input.Title == nil && input.Tags == nil && input.Source == nil &&
-		input.RunID == nil && input.Phase == nil && input.Role == nil {
+		input.RunID == nil && input.Phase == nil && input.Role == nil &&
+		input.SourceType == nil && input.SourceRef == nil && input.Note == nil && input.ExpiresAt == nil {
 		return nil, errors.NewInvalidRequest("at least one editable field must be provided")
 	}
 
+	if input.SourceType != nil {
+		if err := validateSourceType(cleanOptionalString(input.SourceType)); err != nil {
+			return nil, err
+		}
+	}
+
+	expiresAt, err := cleanExpiresAt(input.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
 	// Fetch existing capsule (active only)
 	var c *capsule.Capsule
 	if addr.ByID {
-		c, err = db.GetByID(ctx, database, addr.ID, false)
+		c, err = db.GetByID(ctx, q, addr.ID, false)
 	} else {
-		c, err = db.GetByName(ctx, database, addr.Workspace, addr.Name, false)
+		c, err = db.GetByName(ctx, q, addr.Workspace, addr.Name, false)
 	}
 	if err != nil {
 		return nil, err
@@ -62,6 +83,12 @@ func Update(ctx context.Context, database *sql.DB, cfg *config.Config, input Upd
 
 	// Apply updates
 	if input.CapsuleText != nil {
+		// Normalize text formatting before lint and metrics
+		if cfg.NormalizeOnStore {
+			normalized := capsule.NormalizeText(*input.CapsuleText)
+			input.CapsuleText = &normalized
+		}
+
 		// Lint new content
 		lintResult := capsule.Lint(capsule.LintInput{
 			CapsuleText: *input.CapsuleText,
@@ -72,14 +99,19 @@ func Update(ctx context.Context, database *sql.DB, cfg *config.Config, input Upd
 		if lintResult.TooLarge {
 			return nil, errors.NewCapsuleTooLarge(lintResult.MaxChars, lintResult.ActualChars)
 		}
+		if err := checkCapsuleBytes(cfg, *input.CapsuleText); err != nil {
+			return nil, err
+		}
 
 		if len(lintResult.MissingSections) > 0 {
 			return nil, errors.NewCapsuleTooThin(lintResult.MissingSections)
 		}
+		if err := checkSectionCount(cfg, *input.CapsuleText, input.AllowThin); err != nil {
+			return nil, err
+		}
 
 		c.CapsuleText = *input.CapsuleText
-		c.CapsuleChars = capsule.CountChars(*input.CapsuleText)
-		c.TokensEstimate = capsule.EstimateTokens(*input.CapsuleText)
+		c.RecomputeMetrics()
 	}
 
 	if input.Title != nil {
@@ -88,6 +120,9 @@ func Update(ctx context.Context, database *sql.DB, cfg *config.Config, input Upd
 
 	if input.Tags != nil {
 		c.Tags = *input.Tags
+		if cfg.NormalizeTags {
+			c.Tags = capsule.NormalizeTags(c.Tags)
+		}
 	}
 
 	if input.Source != nil {
@@ -106,8 +141,24 @@ func Update(ctx context.Context, database *sql.DB, cfg *config.Config, input Upd
 		c.Role = cleanOptionalString(input.Role)
 	}
 
+	if input.SourceType != nil {
+		c.SourceType = cleanOptionalString(input.SourceType)
+	}
+
+	if input.SourceRef != nil {
+		c.SourceRef = cleanOptionalString(input.SourceRef)
+	}
+
+	if input.Note != nil {
+		c.Note = cleanOptionalString(input.Note)
+	}
+
+	if input.ExpiresAt != nil {
+		c.ExpiresAt = expiresAt
+	}
+
 	// Persist update
-	if err := db.UpdateByID(ctx, database, c); err != nil {
+	if err := db.UpdateByID(ctx, q, c); err != nil {
 		return nil, err
 	}
 
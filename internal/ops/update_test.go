@@ -178,6 +178,64 @@ func TestUpdate_CapsuleText_TooLarge(t *testing.T) {
 	}
 }
 
+func TestUpdate_MaxSections(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	cfg := config.DefaultConfig()
+	cfg.MaxSections = 6
+
+	storeOutput, err := Store(ctx, database, cfg, StoreInput{CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store at the limit failed: %v", err)
+	}
+
+	overLimit := validCapsuleText + "\n## Risks\nToken theft.\n"
+	_, err = Update(ctx, database, cfg, UpdateInput{ID: storeOutput.ID, CapsuleText: &overLimit})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("Update over the limit should return INVALID_REQUEST, got: %v", err)
+	}
+
+	if _, err := Update(ctx, database, cfg, UpdateInput{ID: storeOutput.ID, CapsuleText: &overLimit, AllowThin: true}); err != nil {
+		t.Errorf("Update with allow_thin failed: %v", err)
+	}
+}
+
+func TestUpdate_CapsuleText_TooLargeBytes(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := &config.Config{CapsuleMaxChars: 100, CapsuleMaxBytes: 200}
+
+	storeOutput, err := Store(context.Background(), database, cfg, StoreInput{
+		CapsuleText: "Small text",
+		AllowThin:   true,
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	// 90 chars but 360 bytes
+	multibyte := strings.Repeat("🔑", 90)
+	_, err = Update(context.Background(), database, cfg, UpdateInput{
+		ID:          storeOutput.ID,
+		CapsuleText: &multibyte,
+		AllowThin:   true,
+	})
+	if !errors.Is(err, errors.ErrCapsuleTooLarge) {
+		t.Errorf("Update should return ErrCapsuleTooLarge, got: %v", err)
+	}
+}
+
 func TestUpdate_CapsuleText_TooThin(t *testing.T) {
 	tmpDir := t.TempDir()
 	database, err := db.Init(tmpDir)
@@ -406,3 +464,141 @@ func TestUpdate_ClearSource(t *testing.T) {
 		t.Errorf("Source = %v, want empty string", fetched.Source)
 	}
 }
+
+func TestUpdate_SourceProvenance(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	storeOutput, err := Store(ctx, database, cfg, StoreInput{
+		CapsuleText: validCapsuleText,
+		SourceType:  stringPtr(SourceTypeAgent),
+		SourceRef:   stringPtr("session-1"),
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	// Provenance alone is a valid update
+	_, err = Update(ctx, database, cfg, UpdateInput{
+		ID:         storeOutput.ID,
+		SourceType: stringPtr(SourceTypeHuman),
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	fetched, err := Fetch(ctx, database, FetchInput{ID: storeOutput.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.SourceType == nil || *fetched.SourceType != SourceTypeHuman {
+		t.Errorf("SourceType = %v, want human", fetched.SourceType)
+	}
+	if fetched.SourceRef == nil || *fetched.SourceRef != "session-1" {
+		t.Errorf("SourceRef = %v, want unchanged session-1", fetched.SourceRef)
+	}
+
+	// Empty string clears
+	_, err = Update(ctx, database, cfg, UpdateInput{
+		ID:        storeOutput.ID,
+		SourceRef: stringPtr(""),
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	fetched, err = Fetch(ctx, database, FetchInput{ID: storeOutput.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.SourceRef != nil {
+		t.Errorf("SourceRef = %v, want nil after clear", *fetched.SourceRef)
+	}
+
+	// Unknown source type is rejected
+	_, err = Update(ctx, database, cfg, UpdateInput{
+		ID:         storeOutput.ID,
+		SourceType: stringPtr("robot"),
+	})
+	if !errors.Is(err, errors.ErrInvalidRequest) {
+		t.Errorf("unknown source_type: err = %v, want INVALID_REQUEST", err)
+	}
+}
+
+func TestUpdate_Note(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.Init(tmpDir)
+	if err != nil {
+		t.Fatalf("db.Init failed: %v", err)
+	}
+	defer database.Close()
+
+	cfg := config.DefaultConfig()
+	ctx := context.Background()
+
+	storeOutput, err := Store(ctx, database, cfg, StoreInput{
+		CapsuleText: validCapsuleText,
+		Note:        stringPtr("  waiting on infra review  "),
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	fetched, err := Fetch(ctx, database, FetchInput{ID: storeOutput.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.Note == nil || *fetched.Note != "waiting on infra review" {
+		t.Errorf("Note = %v, want trimmed note from store", fetched.Note)
+	}
+
+	// Note alone is a valid update and leaves the text alone
+	_, err = Update(ctx, database, cfg, UpdateInput{
+		ID:   storeOutput.ID,
+		Note: stringPtr("infra approved"),
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	fetched, err = Fetch(ctx, database, FetchInput{ID: storeOutput.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.Note == nil || *fetched.Note != "infra approved" {
+		t.Errorf("Note = %v, want infra approved", fetched.Note)
+	}
+	if fetched.CapsuleText != validCapsuleText {
+		t.Error("CapsuleText changed by a note-only update")
+	}
+
+	// The note is not part of the search index
+	results, err := Search(ctx, database, SearchInput{Query: "infra"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results.Items) != 0 {
+		t.Errorf("search for note text found %d capsules, want 0", len(results.Items))
+	}
+
+	// Empty string clears to NULL
+	_, err = Update(ctx, database, cfg, UpdateInput{
+		ID:   storeOutput.ID,
+		Note: stringPtr(""),
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	fetched, err = Fetch(ctx, database, FetchInput{ID: storeOutput.ID})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.Note != nil {
+		t.Errorf("Note = %q, want nil after clear", *fetched.Note)
+	}
+}
@@ -0,0 +1,72 @@
+// Package testutil holds helpers shared by tests across packages.
+package testutil
+
+import (
+	"context"
+	"sync"
+)
+
+// DoneCountdownCtx is a context that cancels itself on the cancelAfter-th call
+// to Done, so tests can land a cancellation partway through a loop that checks
+// ctx.Done() once per item. It also cancels when its parent does.
+type DoneCountdownCtx struct {
+	context.Context
+	cancelAfter int
+
+	mu    sync.Mutex
+	calls int
+	err   error
+
+	done chan struct{}
+	once sync.Once
+}
+
+// CancelAfterDoneCalls returns a DoneCountdownCtx wrapping ctx. A cancelAfter
+// of 0 or less never cancels on its own.
+func CancelAfterDoneCalls(ctx context.Context, cancelAfter int) *DoneCountdownCtx {
+	c := &DoneCountdownCtx{
+		Context:     ctx,
+		cancelAfter: cancelAfter,
+		done:        make(chan struct{}),
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.cancel(ctx.Err())
+		case <-c.done:
+		}
+	}()
+	return c
+}
+
+func (c *DoneCountdownCtx) cancel(err error) {
+	c.once.Do(func() {
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+		close(c.done)
+	})
+}
+
+// Done counts the call and cancels once cancelAfter calls have been made.
+func (c *DoneCountdownCtx) Done() <-chan struct{} {
+	c.mu.Lock()
+	c.calls++
+	shouldCancel := c.cancelAfter > 0 && c.calls >= c.cancelAfter
+	c.mu.Unlock()
+
+	if shouldCancel {
+		c.cancel(context.Canceled)
+	}
+	return c.done
+}
+
+// Err reports the parent's error, else context.Canceled once cancelled.
+func (c *DoneCountdownCtx) Err() error {
+	if err := c.Context.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/hpungsan/moss/internal/capsule"
 	"github.com/hpungsan/moss/internal/config"
 	"github.com/hpungsan/moss/internal/errors"
 	"github.com/hpungsan/moss/internal/ops"
@@ -19,6 +20,30 @@ type Handlers struct {
 	renderer *Renderer
 }
 
+// HandleIndex handles GET / — recent activity across all workspaces.
+func (h *Handlers) HandleIndex(w http.ResponseWriter, r *http.Request) {
+	input := ops.ActivityInput{
+		Limit:          parseIntParam(r, "limit", ops.DefaultActivityLimit),
+		IncludeDeleted: parseBoolParam(r, "include_deleted"),
+	}
+
+	result, err := ops.Activity(r.Context(), h.db, input)
+	if err != nil {
+		h.renderer.renderError(w, r, err)
+		return
+	}
+
+	h.renderer.renderPage(w, r, "index", IndexPageData{
+		PageData: PageData{
+			Title:   "Recent activity",
+			Version: h.renderer.version,
+			Nav:     "activity",
+		},
+		Items:   result.Items,
+		Deleted: input.IncludeDeleted,
+	})
+}
+
 // HandleList handles GET /capsules — list capsules in a workspace.
 func (h *Handlers) HandleList(w http.ResponseWriter, r *http.Request) {
 	workspace := r.URL.Query().Get("workspace")
@@ -33,7 +58,7 @@ func (h *Handlers) HandleList(w http.ResponseWriter, r *http.Request) {
 		Role:           ptrString(r.URL.Query().Get("role")),
 		Limit:          parseIntParam(r, "limit", 20),
 		Offset:         parseIntParam(r, "offset", 0),
-		IncludeDeleted: parseBoolParam(r, "include_deleted"),
+		IncludeDeleted: h.includeDeleted(r),
 	}
 
 	result, err := ops.List(r.Context(), h.db, input)
@@ -49,6 +74,7 @@ func (h *Handlers) HandleList(w http.ResponseWriter, r *http.Request) {
 			Nav:     "capsules",
 		},
 		Items:      result.Items,
+		Columns:    tableColumns(h.cfg.ListColumns, defaultListColumns),
 		Pagination: result.Pagination,
 		Workspace:  workspace,
 		RunID:      r.URL.Query().Get("run_id"),
@@ -79,11 +105,11 @@ func (h *Handlers) HandleSearch(w http.ResponseWriter, r *http.Request) {
 		RunID:     runID,
 		Phase:     phase,
 		Role:      role,
-		Deleted:   parseBoolParam(r, "include_deleted"),
-		HasQuery:  query != "",
+		Deleted:   h.includeDeleted(r),
+		HasQuery:  query != "" || h.cfg.EmptyQueryListsAll,
 	}
 
-	if query == "" {
+	if !data.HasQuery {
 		// If htmx targets #results (user cleared the search box), return just the results fragment
 		if r.Header.Get("HX-Target") == "results" {
 			h.renderer.renderBlock(w, http.StatusOK, "search", "search-results", data)
@@ -94,15 +120,18 @@ func (h *Handlers) HandleSearch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	input := ops.SearchInput{
-		Query:          query,
-		Workspace:      ptrString(workspace),
-		Tag:            ptrString(tag),
-		RunID:          ptrString(runID),
-		Phase:          ptrString(phase),
-		Role:           ptrString(role),
-		Limit:          parseIntParam(r, "limit", 20),
-		Offset:         parseIntParam(r, "offset", 0),
-		IncludeDeleted: data.Deleted,
+		Query:              query,
+		Workspace:          ptrString(workspace),
+		Tag:                ptrString(tag),
+		RunID:              ptrString(runID),
+		Phase:              ptrString(phase),
+		Role:               ptrString(role),
+		Limit:              parseIntParam(r, "limit", 20),
+		Offset:             parseIntParam(r, "offset", 0),
+		IncludeDeleted:     data.Deleted,
+		MinTermLen:         h.cfg.MinSearchTermLen,
+		NormalizeTags:      h.cfg.NormalizeTags,
+		EmptyQueryListsAll: h.cfg.EmptyQueryListsAll,
 	}
 
 	result, err := ops.Search(r.Context(), h.db, input)
@@ -141,7 +170,8 @@ func (h *Handlers) HandleInventory(w http.ResponseWriter, r *http.Request) {
 		Role:           ptrString(role),
 		Limit:          parseIntParam(r, "limit", 100),
 		Offset:         parseIntParam(r, "offset", 0),
-		IncludeDeleted: parseBoolParam(r, "include_deleted"),
+		IncludeDeleted: h.includeDeleted(r),
+		NormalizeTags:  h.cfg.NormalizeTags,
 	}
 
 	result, err := ops.Inventory(r.Context(), h.db, input)
@@ -157,6 +187,7 @@ func (h *Handlers) HandleInventory(w http.ResponseWriter, r *http.Request) {
 			Nav:     "inventory",
 		},
 		Items:      result.Items,
+		Columns:    tableColumns(h.cfg.ListColumns, defaultInventoryColumns),
 		Pagination: result.Pagination,
 		Workspace:  workspace,
 		Tag:        tag,
@@ -168,6 +199,86 @@ func (h *Handlers) HandleInventory(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleNewForm handles GET /capsules/new — show the create form.
+func (h *Handlers) HandleNewForm(w http.ResponseWriter, r *http.Request) {
+	h.renderer.renderPage(w, r, "new", NewPageData{
+		PageData: PageData{
+			Title:   "New capsule",
+			Version: h.renderer.version,
+			Nav:     "capsules",
+		},
+		Workspace: r.URL.Query().Get("workspace"),
+	})
+}
+
+// HandleCreate handles POST /capsules — store a new capsule from the create form.
+func (h *Handlers) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.renderer.renderError(w, r, errors.NewInvalidRequest("invalid form data"))
+		return
+	}
+
+	data := NewPageData{
+		PageData: PageData{
+			Title:   "New capsule",
+			Version: h.renderer.version,
+			Nav:     "capsules",
+		},
+		Workspace:    strings.TrimSpace(r.FormValue("workspace")),
+		Name:         strings.TrimSpace(r.FormValue("name")),
+		CapsuleTitle: strings.TrimSpace(r.FormValue("title")),
+		Tags:         r.FormValue("tags"),
+		Phase:        strings.TrimSpace(r.FormValue("phase")),
+		Role:         strings.TrimSpace(r.FormValue("role")),
+		// Browsers submit textarea content with CRLF line endings
+		CapsuleText: strings.ReplaceAll(r.FormValue("capsule_text"), "\r\n", "\n"),
+		AllowThin:   r.FormValue("allow_thin") == "true",
+	}
+
+	htmx := r.Header.Get("HX-Request") == "true"
+	wantsJSON := strings.Contains(r.Header.Get("Accept"), "application/json")
+
+	result, err := ops.Store(r.Context(), h.db, h.cfg, ops.StoreInput{
+		Workspace:   data.Workspace,
+		Name:        ptrString(data.Name),
+		Title:       ptrString(data.CapsuleTitle),
+		CapsuleText: data.CapsuleText,
+		Tags:        parseTags(data.Tags),
+		Phase:       ptrString(data.Phase),
+		Role:        ptrString(data.Role),
+		AllowThin:   data.AllowThin,
+	})
+	if err != nil {
+		// Full-page submission: re-render the form with the submitted values
+		if !htmx && !wantsJSON {
+			mErr := asMossError(err)
+			data.Error = mErr.Message
+			h.renderer.renderPageStatus(w, r, mErr.Status, "new", data)
+			return
+		}
+		h.renderer.renderError(w, r, err)
+		return
+	}
+
+	location := "/capsules/" + result.ID
+
+	// HTMX request: redirect via HX-Redirect header
+	if htmx {
+		w.Header().Set("HX-Redirect", location)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// JSON request
+	if wantsJSON {
+		renderJSON(w, http.StatusCreated, result)
+		return
+	}
+
+	// Default: redirect to the new capsule (post/redirect/get)
+	http.Redirect(w, r, location, http.StatusSeeOther)
+}
+
 // HandleDetail handles GET /capsules/{id} — view a single capsule.
 func (h *Handlers) HandleDetail(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -179,7 +290,7 @@ func (h *Handlers) HandleDetail(w http.ResponseWriter, r *http.Request) {
 	includeText := true
 	input := ops.FetchInput{
 		ID:             id,
-		IncludeDeleted: parseBoolParam(r, "include_deleted"),
+		IncludeDeleted: h.includeDeleted(r),
 		IncludeText:    &includeText,
 	}
 
@@ -189,18 +300,99 @@ func (h *Handlers) HandleDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rendered := renderMarkdown(capsule.CapsuleText)
+	h.renderer.renderPage(w, r, "detail", h.detailPageData(capsule))
+}
 
-	h.renderer.renderPage(w, r, "detail", DetailPageData{
-		PageData: PageData{
-			Title:   displayName(capsule.Name, capsule.ID),
-			Version: h.renderer.version,
-			Nav:     "capsules",
-		},
-		Capsule:      capsule,
-		RenderedHTML: rendered,
-		DisplayName:  displayName(capsule.Name, capsule.ID),
+// HandleEditForm handles GET /capsules/{id}/edit — show the edit form for a capsule.
+func (h *Handlers) HandleEditForm(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.renderer.renderError(w, r, errors.NewInvalidRequest("capsule ID is required"))
+		return
+	}
+
+	capsule, err := h.fetchWithText(r, id)
+	if err != nil {
+		h.renderer.renderError(w, r, err)
+		return
+	}
+
+	title := ""
+	if capsule.Title != nil {
+		title = *capsule.Title
+	}
+
+	h.renderer.renderPage(w, r, "edit", h.editPageData(capsule, capsule.CapsuleText, title, strings.Join(capsule.Tags, ", ")))
+}
+
+// HandleUpdate handles POST /capsules/{id} — apply the edit form via ops.Update.
+func (h *Handlers) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.renderer.renderError(w, r, errors.NewInvalidRequest("capsule ID is required"))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.renderer.renderError(w, r, errors.NewInvalidRequest("invalid form data"))
+		return
+	}
+
+	// Browsers submit textarea content with CRLF line endings
+	text := strings.ReplaceAll(r.FormValue("capsule_text"), "\r\n", "\n")
+	title := strings.TrimSpace(r.FormValue("title"))
+	tagsRaw := r.FormValue("tags")
+	tags := parseTags(tagsRaw)
+
+	htmx := r.Header.Get("HX-Request") == "true"
+	wantsJSON := strings.Contains(r.Header.Get("Accept"), "application/json")
+
+	// Title and tags are always sent so the form can clear them
+	_, err := ops.Update(r.Context(), h.db, h.cfg, ops.UpdateInput{
+		ID:          id,
+		CapsuleText: &text,
+		Title:       &title,
+		Tags:        &tags,
 	})
+	if err != nil {
+		// Full-page submission: re-render the form with the submitted values
+		if !htmx && !wantsJSON {
+			capsule, fetchErr := h.fetchWithText(r, id)
+			if fetchErr != nil {
+				h.renderer.renderError(w, r, err)
+				return
+			}
+			mErr := asMossError(err)
+			data := h.editPageData(capsule, text, title, tagsRaw)
+			data.Error = mErr.Message
+			h.renderer.renderPageStatus(w, r, mErr.Status, "edit", data)
+			return
+		}
+		h.renderer.renderError(w, r, err)
+		return
+	}
+
+	// Default: redirect to the detail page (post/redirect/get)
+	if !htmx && !wantsJSON {
+		http.Redirect(w, r, "/capsules/"+id, http.StatusSeeOther)
+		return
+	}
+
+	capsule, err := h.fetchWithText(r, id)
+	if err != nil {
+		h.renderer.renderError(w, r, err)
+		return
+	}
+
+	// HTMX request: swap in the updated detail view
+	if htmx {
+		w.Header().Set("HX-Push-Url", "/capsules/"+id)
+		h.renderer.renderPage(w, r, "detail", h.detailPageData(capsule))
+		return
+	}
+
+	// JSON request
+	renderJSON(w, http.StatusOK, capsule)
 }
 
 // HandleDelete handles DELETE /capsules/{id} — soft-delete a capsule.
@@ -251,6 +443,7 @@ func (h *Handlers) HandlePurge(w http.ResponseWriter, r *http.Request) {
 
 	input := ops.PurgeInput{
 		Workspace: ptrString(r.FormValue("workspace")),
+		Archive:   h.cfg.ArchiveOnPurge,
 	}
 
 	if days := r.FormValue("older_than_days"); days != "" {
@@ -289,6 +482,73 @@ func (h *Handlers) HandlePurge(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/capsules?include_deleted=true", http.StatusFound)
 }
 
+// HandleRenderPreview handles POST /render — render capsule_text as markdown
+// for the create/edit form preview. Returns the same HTML the detail page
+// would show (raw HTML in the text is omitted, not passed through); nothing is stored.
+func (h *Handlers) HandleRenderPreview(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.renderer.renderError(w, r, errors.NewInvalidRequest("invalid form data"))
+		return
+	}
+
+	text := strings.ReplaceAll(r.FormValue("capsule_text"), "\r\n", "\n")
+	if chars := capsule.CountChars(text); chars > h.cfg.CapsuleMaxChars {
+		h.renderer.renderError(w, r, errors.NewCapsuleTooLarge(h.cfg.CapsuleMaxChars, chars))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`<div class="rendered-content">` + string(renderMarkdown(text)) + `</div>`))
+}
+
+// fetchWithText fetches an active capsule by ID, including its text.
+func (h *Handlers) fetchWithText(r *http.Request, id string) (*ops.FetchOutput, error) {
+	includeText := true
+	return ops.Fetch(r.Context(), h.db, ops.FetchInput{ID: id, IncludeText: &includeText})
+}
+
+// detailPageData builds the template data for the capsule detail page.
+func (h *Handlers) detailPageData(capsule *ops.FetchOutput) DetailPageData {
+	return DetailPageData{
+		PageData: PageData{
+			Title:   h.renderer.displayName(capsule.Name, capsule.ID),
+			Version: h.renderer.version,
+			Nav:     "capsules",
+		},
+		Capsule:      capsule,
+		RenderedHTML: renderMarkdown(capsule.CapsuleText),
+		DisplayName:  h.renderer.displayName(capsule.Name, capsule.ID),
+	}
+}
+
+// editPageData builds the template data for the edit form with the given field values.
+func (h *Handlers) editPageData(capsule *ops.FetchOutput, text, title, tags string) EditPageData {
+	return EditPageData{
+		PageData: PageData{
+			Title:   "Edit " + h.renderer.displayName(capsule.Name, capsule.ID),
+			Version: h.renderer.version,
+			Nav:     "capsules",
+		},
+		Capsule:      capsule,
+		DisplayName:  h.renderer.displayName(capsule.Name, capsule.ID),
+		CapsuleText:  text,
+		CapsuleTitle: title,
+		Tags:         tags,
+	}
+}
+
+// parseTags splits a comma-separated tag list, dropping blank entries.
+func parseTags(s string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
 // parseIntParam parses an integer query parameter with a default value.
 func parseIntParam(r *http.Request, name string, defaultVal int) int {
 	s := r.URL.Query().Get(name)
@@ -308,6 +568,16 @@ func parseBoolParam(r *http.Request, name string) bool {
 	return s == "true" || s == "1"
 }
 
+// includeDeleted reads the include_deleted query parameter, falling back to
+// config.DefaultIncludeDeleted when it is absent. Filter forms send an explicit
+// "false" alongside the checkbox so unchecking it overrides the default.
+func (h *Handlers) includeDeleted(r *http.Request) bool {
+	if r.URL.Query().Get("include_deleted") == "" {
+		return h.cfg.DefaultIncludeDeleted
+	}
+	return parseBoolParam(r, "include_deleted")
+}
+
 // ptrString returns a pointer to s if non-empty, nil otherwise.
 func ptrString(s string) *string {
 	if s == "" {
@@ -315,14 +585,3 @@ func ptrString(s string) *string {
 	}
 	return &s
 }
-
-// displayName returns the capsule name if present, or a truncated ID.
-func displayName(name *string, id string) string {
-	if name != nil && *name != "" {
-		return *name
-	}
-	if len(id) > 10 {
-		return id[:10] + "..."
-	}
-	return id
-}
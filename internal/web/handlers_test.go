@@ -51,7 +51,7 @@ func setupTest(t *testing.T) *Handlers {
 	if err != nil {
 		t.Fatalf("template sub-FS: %v", err)
 	}
-	renderer := NewRenderer(templateSub, "test")
+	renderer := NewRenderer(templateSub, "test", cfg.IDDisplayLen)
 
 	return &Handlers{
 		db:       database,
@@ -76,6 +76,62 @@ func seedCapsule(t *testing.T, h *Handlers, name, workspace string) string {
 	return out.ID
 }
 
+// --- HandleIndex ---
+
+func TestHandleIndex_RecentActivity(t *testing.T) {
+	h := setupTest(t)
+	seedCapsule(t, h, "act-alpha", "alpha")
+	deletedID := seedCapsule(t, h, "act-beta", "beta")
+	if _, err := ops.Delete(context.Background(), h.db, ops.DeleteInput{ID: deletedID}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+	h.HandleIndex(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "Recent activity") || !strings.Contains(body, "act-alpha") {
+		t.Error("expected activity feed with seeded capsule")
+	}
+	if !strings.Contains(body, "badge-action-created") {
+		t.Error("expected created action badge")
+	}
+	if strings.Contains(body, "act-beta") {
+		t.Error("deleted capsule should be hidden by default")
+	}
+
+	req = httptest.NewRequest("GET", "/?include_deleted=true", nil)
+	rec = httptest.NewRecorder()
+	h.HandleIndex(rec, req)
+
+	body = rec.Body.String()
+	if !strings.Contains(body, "act-beta") || !strings.Contains(body, "badge-action-deleted") {
+		t.Error("expected deleted capsule with deleted action when include_deleted=true")
+	}
+	if !strings.Contains(body, "/capsules/"+deletedID+"?include_deleted=true") {
+		t.Error("expected deleted capsule link to include include_deleted")
+	}
+}
+
+func TestHandleIndex_Empty(t *testing.T) {
+	h := setupTest(t)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+	h.HandleIndex(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "No activity yet") {
+		t.Error("expected empty state message")
+	}
+}
+
 // --- HandleList ---
 
 func TestHandleList_Default(t *testing.T) {
@@ -96,6 +152,14 @@ func TestHandleList_Default(t *testing.T) {
 	if !strings.Contains(body, "Capsules") {
 		t.Error("expected page title 'Capsules' in response")
 	}
+	if !strings.Contains(body, `<div class="row-abstract">Build a user authentication system.</div>`) {
+		t.Error("expected the capsule abstract under its name")
+	}
+	for _, header := range []string{"<th>Title</th>", "<th>Chars</th>", "<th>Created</th>", "<th>Updated</th>", "<th>Actions</th>"} {
+		if !strings.Contains(body, header) {
+			t.Errorf("expected default column %s", header)
+		}
+	}
 }
 
 func TestHandleList_WithWorkspaceFilter(t *testing.T) {
@@ -119,6 +183,49 @@ func TestHandleList_WithWorkspaceFilter(t *testing.T) {
 	}
 }
 
+func TestHandleList_ConfiguredColumns(t *testing.T) {
+	h := setupTest(t)
+	h.cfg.ListColumns = []string{"phase", "bogus", "role", "updated", "phase"}
+	_, err := ops.Store(context.Background(), h.db, h.cfg, ops.StoreInput{
+		Name:        stringPtr("cols"),
+		CapsuleText: validCapsuleText,
+		Phase:       stringPtr("implement"),
+	})
+	if err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	for _, path := range []string{"/capsules", "/capsules/inventory"} {
+		req := httptest.NewRequest("GET", path, nil)
+		rec := httptest.NewRecorder()
+		if path == "/capsules" {
+			h.HandleList(rec, req)
+		} else {
+			h.HandleInventory(rec, req)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200", path, rec.Code)
+		}
+		body := rec.Body.String()
+		phase, role, updated := strings.Index(body, "<th>Phase</th>"), strings.Index(body, "<th>Role</th>"), strings.Index(body, "<th>Updated</th>")
+		if phase < 0 || !(phase < role && role < updated) {
+			t.Errorf("%s: expected Phase, Role, Updated headers in configured order", path)
+		}
+		if !strings.Contains(body, "<td>implement</td>") {
+			t.Errorf("%s: expected the phase cell", path)
+		}
+		for _, dropped := range []string{"<th>Title</th>", "<th>Chars</th>", "<th>Created</th>", "<th>Workspace</th>", "bogus"} {
+			if strings.Contains(body, dropped) {
+				t.Errorf("%s: did not expect %q in table", path, dropped)
+			}
+		}
+		if strings.Count(body, "<th>Phase</th>") != 1 {
+			t.Errorf("%s: repeated column should render once", path)
+		}
+	}
+}
+
 func TestHandleList_Empty(t *testing.T) {
 	h := setupTest(t)
 
@@ -187,6 +294,27 @@ func TestHandleSearch_EmptyQuery(t *testing.T) {
 	}
 }
 
+func TestHandleSearch_EmptyQueryListsAll(t *testing.T) {
+	h := setupTest(t)
+	h.cfg.EmptyQueryListsAll = true
+	seedCapsule(t, h, "listed-capsule", "default")
+
+	req := httptest.NewRequest("GET", "/capsules/search", nil)
+	rec := httptest.NewRecorder()
+	h.HandleSearch(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "listed-capsule") {
+		t.Error("expected every capsule to be listed for an empty query")
+	}
+	if strings.Contains(body, "Enter a search query") {
+		t.Error("empty search prompt should not be shown")
+	}
+}
+
 func TestHandleSearch_WithQuery(t *testing.T) {
 	h := setupTest(t)
 	seedCapsule(t, h, "auth-capsule", "default")
@@ -326,6 +454,36 @@ func TestHandleInventory_WorkspaceFilter(t *testing.T) {
 	}
 }
 
+func TestHandleInventory_DefaultIncludeDeleted(t *testing.T) {
+	h := setupTest(t)
+	h.cfg.DefaultIncludeDeleted = true
+	id := seedCapsule(t, h, "inv-deleted", "default")
+	if _, err := ops.Delete(context.Background(), h.db, ops.DeleteInput{ID: id}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},                        // config default
+		{"?include_deleted=false", false}, // unchecked box sends only the hidden false
+		{"?include_deleted=true&include_deleted=false", true}, // checked box comes first
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/capsules/inventory"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		h.HandleInventory(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d, want 200", tt.query, rec.Code)
+		}
+		if got := strings.Contains(rec.Body.String(), "inv-deleted"); got != tt.want {
+			t.Errorf("%q: deleted capsule shown = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
 func TestHandleInventory_Empty(t *testing.T) {
 	h := setupTest(t)
 
@@ -367,6 +525,9 @@ func TestHandleDetail_Found(t *testing.T) {
 	if !strings.Contains(body, "Metadata") {
 		t.Error("expected metadata section")
 	}
+	if !strings.Contains(body, "Reading time") || !strings.Contains(body, "~1 min") {
+		t.Error("expected reading time estimate")
+	}
 	// Check raw text toggle
 	if !strings.Contains(body, "Raw capsule text") {
 		t.Error("expected raw text toggle")
@@ -399,6 +560,361 @@ func TestHandleDetail_EmptyID(t *testing.T) {
 	}
 }
 
+// --- HandleNewForm / HandleCreate ---
+
+func TestHandleNewForm(t *testing.T) {
+	h := setupTest(t)
+
+	req := httptest.NewRequest("GET", "/capsules/new?workspace=proj", nil)
+	rec := httptest.NewRecorder()
+	h.HandleNewForm(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `action="/capsules"`) || !strings.Contains(body, "<textarea") {
+		t.Error("expected create form posting to /capsules")
+	}
+	if !strings.Contains(body, `name="allow_thin"`) {
+		t.Error("expected allow_thin checkbox")
+	}
+	if !strings.Contains(body, `value="proj"`) {
+		t.Error("expected workspace prefilled from query")
+	}
+}
+
+func TestHandleCreate_Success(t *testing.T) {
+	h := setupTest(t)
+
+	form := url.Values{
+		"workspace":    {"proj"},
+		"name":         {"new-cap"},
+		"title":        {"New capsule"},
+		"tags":         {"auth, backend"},
+		"phase":        {"design"},
+		"role":         {"planner"},
+		"capsule_text": {strings.ReplaceAll(validCapsuleText, "\n", "\r\n")},
+	}
+
+	req := httptest.NewRequest("POST", "/capsules", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	rec := httptest.NewRecorder()
+	h.HandleCreate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body.String())
+	}
+
+	capsule, err := ops.Fetch(context.Background(), h.db, ops.FetchInput{Workspace: "proj", Name: "new-cap"})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := rec.Header().Get("HX-Redirect"); got != "/capsules/"+capsule.ID {
+		t.Errorf("HX-Redirect = %q, want /capsules/%s", got, capsule.ID)
+	}
+	if capsule.CapsuleText != validCapsuleText {
+		t.Error("expected capsule text stored with LF line endings")
+	}
+	if capsule.Title == nil || *capsule.Title != "New capsule" {
+		t.Errorf("Title = %v, want %q", capsule.Title, "New capsule")
+	}
+	if len(capsule.Tags) != 2 || capsule.Phase == nil || *capsule.Phase != "design" || capsule.Role == nil || *capsule.Role != "planner" {
+		t.Errorf("tags/phase/role not stored: %v %v %v", capsule.Tags, capsule.Phase, capsule.Role)
+	}
+
+	// Plain form post: redirect to the new capsule
+	form.Set("name", "new-cap-2")
+	req = httptest.NewRequest("POST", "/capsules", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	h.HandleCreate(rec, req)
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want 303", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); !strings.HasPrefix(got, "/capsules/") {
+		t.Errorf("Location = %q, want /capsules/<id>", got)
+	}
+
+	// JSON: 201 with the store result
+	form.Set("name", "new-cap-3")
+	req = httptest.NewRequest("POST", "/capsules", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	rec = httptest.NewRecorder()
+	h.HandleCreate(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201", rec.Code)
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if resp["id"] == nil || resp["id"] == "" {
+		t.Errorf("response = %v, want id", resp)
+	}
+}
+
+func TestHandleCreate_TooThinPreservesForm(t *testing.T) {
+	h := setupTest(t)
+
+	form := url.Values{
+		"workspace":    {"proj"},
+		"name":         {"thin-new"},
+		"title":        {"Draft <1>"},
+		"tags":         {"wip, notes"},
+		"phase":        {"explore"},
+		"role":         {"scout"},
+		"capsule_text": {"just a note"},
+	}
+
+	req := httptest.NewRequest("POST", "/capsules", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.HandleCreate(rec, req)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `class="error-message"`) {
+		t.Error("expected inline error message")
+	}
+	for _, want := range []string{
+		`value="proj"`,
+		`value="thin-new"`,
+		`value="Draft &lt;1&gt;"`,
+		`value="wip, notes"`,
+		`value="explore"`,
+		`value="scout"`,
+		"just a note</textarea>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected preserved form value %q", want)
+		}
+	}
+
+	if _, err := ops.Fetch(context.Background(), h.db, ops.FetchInput{Workspace: "proj", Name: "thin-new"}); err == nil {
+		t.Error("thin capsule should not be stored")
+	}
+
+	// allow_thin lets the same capsule through
+	form.Set("allow_thin", "true")
+	req = httptest.NewRequest("POST", "/capsules", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	h.HandleCreate(rec, req)
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("allow_thin status = %d, want 303; body: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// --- HandleEditForm / HandleUpdate ---
+
+func TestHandleEditForm_PrefilledFields(t *testing.T) {
+	h := setupTest(t)
+	out, err := ops.Store(context.Background(), h.db, h.cfg, ops.StoreInput{
+		Workspace:   "default",
+		Name:        stringPtr("edit-cap"),
+		Title:       stringPtr("Auth <plan>"),
+		CapsuleText: validCapsuleText,
+		Tags:        []string{"auth", "backend"},
+	})
+	if err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/capsules/"+out.ID+"/edit", nil)
+	req.SetPathValue("id", out.ID)
+	rec := httptest.NewRecorder()
+	h.HandleEditForm(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `action="/capsules/`+out.ID+`"`) {
+		t.Error("expected form posting to the capsule URL")
+	}
+	if !strings.Contains(body, "<textarea") || !strings.Contains(body, "## Objective") {
+		t.Error("expected textarea prefilled with capsule text")
+	}
+	if !strings.Contains(body, `value="Auth &lt;plan&gt;"`) {
+		t.Error("expected escaped title value")
+	}
+	if !strings.Contains(body, `value="auth, backend"`) {
+		t.Error("expected comma-separated tags value")
+	}
+}
+
+func TestHandleEditForm_NotFound(t *testing.T) {
+	h := setupTest(t)
+
+	req := httptest.NewRequest("GET", "/capsules/NONEXISTENT/edit", nil)
+	req.SetPathValue("id", "NONEXISTENT")
+	rec := httptest.NewRecorder()
+	h.HandleEditForm(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestHandleUpdate_Success(t *testing.T) {
+	h := setupTest(t)
+	id := seedCapsule(t, h, "upd-cap", "default")
+
+	newText := strings.ReplaceAll(validCapsuleText, "Using JWT for tokens.", "Switched to opaque session tokens.")
+	form := url.Values{
+		"capsule_text": {strings.ReplaceAll(newText, "\n", "\r\n")},
+		"title":        {"  Session plan  "},
+		"tags":         {"auth, , sessions"},
+	}
+
+	// htmx: updated detail fragment
+	req := httptest.NewRequest("POST", "/capsules/"+id, strings.NewReader(form.Encode()))
+	req.SetPathValue("id", id)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	rec := httptest.NewRecorder()
+	h.HandleUpdate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "<!DOCTYPE html>") {
+		t.Error("htmx response should not include layout")
+	}
+	if !strings.Contains(body, "opaque session tokens") {
+		t.Error("expected updated content in detail fragment")
+	}
+	if got := rec.Header().Get("HX-Push-Url"); got != "/capsules/"+id {
+		t.Errorf("HX-Push-Url = %q, want /capsules/%s", got, id)
+	}
+
+	capsule, err := ops.Fetch(context.Background(), h.db, ops.FetchInput{ID: id})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if strings.Contains(capsule.CapsuleText, "\r") {
+		t.Error("CRLF line endings should be normalized")
+	}
+	if capsule.Title == nil || *capsule.Title != "Session plan" {
+		t.Errorf("Title = %v, want %q", capsule.Title, "Session plan")
+	}
+	if len(capsule.Tags) != 2 || capsule.Tags[0] != "auth" || capsule.Tags[1] != "sessions" {
+		t.Errorf("Tags = %v, want [auth sessions]", capsule.Tags)
+	}
+
+	// Plain form post: redirect back to the detail page
+	req = httptest.NewRequest("POST", "/capsules/"+id, strings.NewReader(form.Encode()))
+	req.SetPathValue("id", id)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	h.HandleUpdate(rec, req)
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want 303", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); got != "/capsules/"+id {
+		t.Errorf("Location = %q, want /capsules/%s", got, id)
+	}
+
+	// JSON: updated capsule
+	req = httptest.NewRequest("POST", "/capsules/"+id, strings.NewReader(form.Encode()))
+	req.SetPathValue("id", id)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	rec = httptest.NewRecorder()
+	h.HandleUpdate(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if resp["id"] != id || resp["title"] != "Session plan" {
+		t.Errorf("response = %v, want id %s with updated title", resp, id)
+	}
+}
+
+func TestHandleUpdate_TooThin(t *testing.T) {
+	h := setupTest(t)
+	id := seedCapsule(t, h, "thin-cap", "default")
+
+	form := url.Values{
+		"capsule_text": {"just a note"},
+		"title":        {"Draft"},
+		"tags":         {"wip"},
+	}
+	post := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/capsules/"+id, strings.NewReader(form.Encode()))
+		req.SetPathValue("id", id)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		h.HandleUpdate(rec, req)
+		return rec
+	}
+
+	// htmx: error fragment
+	rec := post(map[string]string{"HX-Request": "true"})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("htmx status = %d, want 422", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `class="error-message"`) {
+		t.Error("expected error fragment for htmx request")
+	}
+
+	// JSON: error object
+	rec = post(map[string]string{"Accept": "application/json"})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("JSON status = %d, want 422", rec.Code)
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	errObj, ok := resp["error"].(map[string]any)
+	if !ok || errObj["code"] != "CAPSULE_TOO_THIN" {
+		t.Errorf("error = %v, want CAPSULE_TOO_THIN", resp["error"])
+	}
+
+	// Full page: form re-rendered with the submitted values and the error
+	rec = post(nil)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("page status = %d, want 422", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "<textarea") || !strings.Contains(body, "just a note") {
+		t.Error("expected form re-rendered with submitted text")
+	}
+	if !strings.Contains(body, `value="Draft"`) {
+		t.Error("expected submitted title preserved")
+	}
+	if !strings.Contains(body, `class="error-message"`) {
+		t.Error("expected inline error message")
+	}
+
+	// Capsule is unchanged
+	capsule, err := ops.Fetch(context.Background(), h.db, ops.FetchInput{ID: id})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if capsule.CapsuleText != validCapsuleText {
+		t.Error("capsule text should be unchanged after failed update")
+	}
+}
+
 // --- HandleDelete ---
 
 func TestHandleDelete_HtmxRequest(t *testing.T) {
@@ -561,6 +1077,49 @@ func TestHandleDelete_EmptyID(t *testing.T) {
 
 // --- HandlePurge ---
 
+// --- HandleRenderPreview ---
+
+func TestHandleRenderPreview(t *testing.T) {
+	h := setupTest(t)
+
+	form := url.Values{"capsule_text": {"## Status\r\nShipped **login**.\r\n\r\n<script>alert(1)</script>\r\n\r\nInline <script>x()</script> too"}}
+	req := httptest.NewRequest("POST", "/render", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	rec := httptest.NewRecorder()
+	h.HandleRenderPreview(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{`<div class="rendered-content">`, "<h2>Status</h2>", "<strong>login</strong>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("preview missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "<script") {
+		t.Errorf("preview passed a script tag through:\n%s", body)
+	}
+	if strings.Contains(body, "<html") {
+		t.Error("preview should be a fragment, not a full page")
+	}
+}
+
+func TestHandleRenderPreview_TooLarge(t *testing.T) {
+	h := setupTest(t)
+
+	form := url.Values{"capsule_text": {strings.Repeat("x", h.cfg.CapsuleMaxChars+1)}}
+	req := httptest.NewRequest("POST", "/render", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.HandleRenderPreview(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", rec.Code)
+	}
+}
+
 func TestHandlePurge_MissingConfirm(t *testing.T) {
 	h := setupTest(t)
 
@@ -782,6 +1341,8 @@ func TestParseBoolParam(t *testing.T) {
 }
 
 func TestDisplayName(t *testing.T) {
+	h := setupTest(t)
+
 	tests := []struct {
 		name     *string
 		id       string
@@ -793,13 +1354,67 @@ func TestDisplayName(t *testing.T) {
 		{stringPtr(""), "01ABCDEFGHIJK", "01ABCDEFGH..."},
 	}
 	for _, tt := range tests {
-		got := displayName(tt.name, tt.id)
+		got := h.renderer.displayName(tt.name, tt.id)
 		if got != tt.expected {
 			t.Errorf("displayName(%v, %q) = %q, want %q", tt.name, tt.id, got, tt.expected)
 		}
 	}
 }
 
+func TestDisplayName_ConfiguredLength(t *testing.T) {
+	templateSub, err := fs.Sub(templateFS, "templates")
+	if err != nil {
+		t.Fatalf("template sub-FS: %v", err)
+	}
+	const ulid = "01HQXJ5K7Z3M9N2P4R6S8T0V1W" // 26 chars
+
+	tests := []struct {
+		idDisplayLen int
+		expected     string
+	}{
+		{6, "01HQXJ..."},
+		{40, ulid},           // longer than a ULID: full ID, no ellipsis
+		{26, ulid},           // exactly a ULID
+		{0, "01HQXJ5K7Z..."}, // unset falls back to the default
+	}
+	for _, tt := range tests {
+		r := NewRenderer(templateSub, "test", tt.idDisplayLen)
+		if got := r.displayName(nil, ulid); got != tt.expected {
+			t.Errorf("idDisplayLen=%d: displayName = %q, want %q", tt.idDisplayLen, got, tt.expected)
+		}
+	}
+}
+
+func TestHandleList_ConfiguredIDDisplayLen(t *testing.T) {
+	h := setupTest(t)
+	templateSub, err := fs.Sub(templateFS, "templates")
+	if err != nil {
+		t.Fatalf("template sub-FS: %v", err)
+	}
+
+	out, err := ops.Store(context.Background(), h.db, h.cfg, ops.StoreInput{CapsuleText: validCapsuleText})
+	if err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	list := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/capsules", nil)
+		w := httptest.NewRecorder()
+		h.HandleList(w, req)
+		return w.Body.String()
+	}
+
+	h.renderer = NewRenderer(templateSub, "test", 6)
+	if body := list(); !strings.Contains(body, out.ID[:6]+"...") {
+		t.Errorf("list should show the ID truncated to 6 chars: %s...", out.ID[:6])
+	}
+
+	h.renderer = NewRenderer(templateSub, "test", 40)
+	if body := list(); strings.Contains(body, "...") {
+		t.Error("ID shorter than the display length should not be truncated")
+	}
+}
+
 func TestPtrString(t *testing.T) {
 	if got := ptrString(""); got != nil {
 		t.Error("ptrString(\"\") should return nil")
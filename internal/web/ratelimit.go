@@ -0,0 +1,124 @@
+package web
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hpungsan/moss/internal/config"
+)
+
+// rateLimitSweepInterval controls how often idle client buckets are dropped.
+const rateLimitSweepInterval = time.Minute
+
+// rateLimiter is a per-client token bucket limiter.
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64 // tokens added per second
+	burst     float64 // bucket capacity
+	clients   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time // injectable for tests
+}
+
+// bucket holds the token state for one client.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a limiter from config.
+// Burst defaults to the per-second rate (minimum 1) if unset.
+func newRateLimiter(cfg config.RateLimit) *rateLimiter {
+	burst := float64(cfg.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, math.Ceil(cfg.RequestsPerSecond))
+	}
+	return &rateLimiter{
+		rate:    cfg.RequestsPerSecond,
+		burst:   burst,
+		clients: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// allow consumes a token for key. If none is available, it returns false and
+// how long until the next token is available.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.clients[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.clients[key] = b
+	}
+
+	// Refill based on elapsed time, capped at burst
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets that have been idle long enough to refill completely.
+// Caller must hold l.mu.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	fullAfter := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, b := range l.clients {
+		if now.Sub(b.last) >= fullAfter {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// rateLimit wraps next with per-client-IP rate limiting.
+// Requests over the limit get 429 with a Retry-After header. /healthz is exempt.
+func rateLimit(l *rateLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ok, wait := l.allow(clientIP(r))
+		if !ok {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the remote IP of the request without the port.
+// Forwarding headers are ignored: moss serve is not meant to sit behind a proxy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
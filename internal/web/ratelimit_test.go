@@ -0,0 +1,123 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hpungsan/moss/internal/config"
+)
+
+func TestRateLimit_BurstThenRecover(t *testing.T) {
+	limiter := newRateLimiter(config.RateLimit{RequestsPerSecond: 2, Burst: 3})
+	now := time.Unix(1700000000, 0)
+	limiter.now = func() time.Time { return now }
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := rateLimit(limiter, ok)
+
+	do := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Burst is allowed
+	for i := 0; i < 3; i++ {
+		if w := do("/capsules", "10.0.0.1:5000"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, w.Code)
+		}
+	}
+
+	// Past the burst: 429 with Retry-After
+	for i := 0; i < 2; i++ {
+		w := do("/capsules/search", "10.0.0.1:5001")
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("over-burst request %d: status = %d, want 429", i+1, w.Code)
+		}
+		if got := w.Header().Get("Retry-After"); got != "1" {
+			t.Errorf("Retry-After = %q, want 1", got)
+		}
+	}
+
+	// Other clients have their own bucket
+	if w := do("/capsules", "10.0.0.2:5000"); w.Code != http.StatusOK {
+		t.Errorf("other client: status = %d, want 200", w.Code)
+	}
+
+	// Health checks are exempt
+	if w := do("/healthz", "10.0.0.1:5002"); w.Code != http.StatusOK {
+		t.Errorf("/healthz: status = %d, want 200", w.Code)
+	}
+
+	// After the window, tokens refill (2/s → 1 token after 500ms)
+	now = now.Add(500 * time.Millisecond)
+	if w := do("/capsules", "10.0.0.1:5003"); w.Code != http.StatusOK {
+		t.Errorf("after refill: status = %d, want 200", w.Code)
+	}
+	if w := do("/capsules", "10.0.0.1:5004"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("after single refill: status = %d, want 429", w.Code)
+	}
+
+	// A long idle period refills to burst, not beyond
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if w := do("/capsules", "10.0.0.1:5005"); w.Code != http.StatusOK {
+			t.Fatalf("after idle, request %d: status = %d, want 200", i+1, w.Code)
+		}
+	}
+	if w := do("/capsules", "10.0.0.1:5006"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("after idle burst: status = %d, want 429", w.Code)
+	}
+}
+
+func TestRateLimit_SweepsIdleClients(t *testing.T) {
+	limiter := newRateLimiter(config.RateLimit{RequestsPerSecond: 1, Burst: 1})
+	now := time.Unix(1700000000, 0)
+	limiter.now = func() time.Time { return now }
+
+	limiter.allow("10.0.0.1")
+	limiter.allow("10.0.0.2")
+
+	now = now.Add(2 * rateLimitSweepInterval)
+	limiter.allow("10.0.0.3")
+
+	if len(limiter.clients) != 1 {
+		t.Errorf("clients = %d, want 1 (idle buckets swept)", len(limiter.clients))
+	}
+}
+
+func TestNewServer_RateLimitDisabledByDefault(t *testing.T) {
+	h := setupTest(t)
+	srv := NewServer(h.db, config.DefaultConfig(), "test", "127.0.0.1", 0)
+
+	for i := 0; i < 50; i++ {
+		req := httptest.NewRequest("GET", "/healthz", nil)
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, w.Code)
+		}
+	}
+
+	// Enabled via config: non-exempt routes are limited
+	cfg := config.DefaultConfig()
+	cfg.WebRateLimit = config.RateLimit{RequestsPerSecond: 1, Burst: 1}
+	srv = NewServer(h.db, cfg, "test", "127.0.0.1", 0)
+
+	codes := make([]int, 2)
+	for i := range codes {
+		req := httptest.NewRequest("GET", "/capsules", nil)
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, req)
+		codes[i] = w.Code
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("codes = %v, want [200 429]", codes)
+	}
+}
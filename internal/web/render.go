@@ -23,13 +23,21 @@ import (
 type PageData struct {
 	Title   string
 	Version string
-	Nav     string // active nav item: "capsules", "inventory", "search"
+	Nav     string // active nav item: "activity", "capsules", "inventory", "search"
+}
+
+// IndexPageData is the template data for the recent activity page.
+type IndexPageData struct {
+	PageData
+	Items   []ops.ActivityItem
+	Deleted bool
 }
 
 // ListPageData is the template data for the capsule list page.
 type ListPageData struct {
 	PageData
 	Items      []ops.SummaryItem
+	Columns    []TableColumn
 	Pagination ops.Pagination
 	Workspace  string
 	RunID      string
@@ -46,6 +54,33 @@ type DetailPageData struct {
 	DisplayName  string
 }
 
+// EditPageData is the template data for the capsule edit form.
+// Form fields hold the submitted values when re-rendering after a validation error.
+type EditPageData struct {
+	PageData
+	Capsule      *ops.FetchOutput
+	DisplayName  string
+	CapsuleText  string
+	CapsuleTitle string
+	Tags         string
+	Error        string
+}
+
+// NewPageData is the template data for the capsule create form.
+// Form fields hold the submitted values when re-rendering after a validation error.
+type NewPageData struct {
+	PageData
+	Workspace    string
+	Name         string
+	CapsuleTitle string
+	Tags         string
+	Phase        string
+	Role         string
+	CapsuleText  string
+	AllowThin    bool
+	Error        string
+}
+
 // SearchPageData is the template data for the search page.
 type SearchPageData struct {
 	PageData
@@ -65,6 +100,7 @@ type SearchPageData struct {
 type InventoryPageData struct {
 	PageData
 	Items      []ops.SummaryItem
+	Columns    []TableColumn
 	Pagination ops.Pagination
 	Workspace  string
 	Tag        string
@@ -75,6 +111,62 @@ type InventoryPageData struct {
 	Deleted    bool
 }
 
+// TableColumn is one configurable data column of the list and inventory
+// tables (config list_columns). The Name / ID column and the list's actions
+// are always shown.
+type TableColumn struct {
+	Key    string
+	Header string
+}
+
+// TableCell is the data for the "table-cell" template: one column of one row.
+type TableCell struct {
+	Key  string
+	Item ops.SummaryItem
+}
+
+// tableColumnHeaders are the allowed list_columns keys and their headers.
+var tableColumnHeaders = map[string]string{
+	"title":       "Title",
+	"workspace":   "Workspace",
+	"chars":       "Chars",
+	"tokens":      "Tokens",
+	"words":       "Words",
+	"phase":       "Phase",
+	"role":        "Role",
+	"run_id":      "Run ID",
+	"source":      "Source",
+	"source_type": "Source Type",
+	"created":     "Created",
+	"updated":     "Updated",
+}
+
+// Default data columns when list_columns is unset.
+var (
+	defaultListColumns      = []string{"title", "chars", "created", "updated"}
+	defaultInventoryColumns = []string{"title", "workspace", "chars", "created", "updated"}
+)
+
+// tableColumns resolves configured column keys in order, ignoring unknown and
+// repeated ones. If none are left, defaults are used.
+func tableColumns(configured, defaults []string) []TableColumn {
+	var columns []TableColumn
+	seen := make(map[string]bool, len(configured))
+	for _, key := range configured {
+		key = strings.ToLower(strings.TrimSpace(key))
+		header, ok := tableColumnHeaders[key]
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		columns = append(columns, TableColumn{Key: key, Header: header})
+	}
+	if len(columns) == 0 && len(defaults) > 0 {
+		return tableColumns(defaults, nil)
+	}
+	return columns
+}
+
 // ErrorPageData is the template data for the error page.
 type ErrorPageData struct {
 	PageData
@@ -89,14 +181,23 @@ type PurgeResultData struct {
 	Message string
 }
 
+// defaultIDDisplayLen is the ID truncation length used when none is configured.
+const defaultIDDisplayLen = 10
+
 // Renderer manages template parsing and rendering.
 type Renderer struct {
-	templates map[string]*template.Template
-	version   string
+	templates    map[string]*template.Template
+	version      string
+	idDisplayLen int // ID characters shown for unnamed capsules
 }
 
 // NewRenderer creates a Renderer by parsing templates from the given FS.
-func NewRenderer(templateFS fs.FS, version string) *Renderer {
+// idDisplayLen sets how many ID characters unnamed capsules show; <= 0 uses the default.
+func NewRenderer(templateFS fs.FS, version string, idDisplayLen int) *Renderer {
+	if idDisplayLen <= 0 {
+		idDisplayLen = defaultIDDisplayLen
+	}
+
 	funcMap := template.FuncMap{
 		"add":            func(a, b int) int { return a + b },
 		"sub":            func(a, b int) int { return a - b },
@@ -105,14 +206,19 @@ func NewRenderer(templateFS fs.FS, version string) *Renderer {
 		"trustedSnippet": func(s string) template.HTML { return template.HTML(s) },
 		"deref":          deref,
 		"hasValue":       hasValue,
+		"shortID":        func(id string) string { return truncateID(id, idDisplayLen) },
+		"tableCell":      func(key string, item ops.SummaryItem) TableCell { return TableCell{Key: key, Item: item} },
 	}
 
 	// Parse layout as the base template
 	layoutTmpl := template.Must(template.New("layout").Funcs(funcMap).ParseFS(templateFS, "layout.html"))
 
 	pages := map[string]string{
+		"index":     "index.html",
 		"list":      "list.html",
 		"detail":    "detail.html",
+		"edit":      "edit.html",
+		"new":       "new.html",
 		"search":    "search.html",
 		"inventory": "inventory.html",
 		"error":     "error.html",
@@ -126,8 +232,9 @@ func NewRenderer(templateFS fs.FS, version string) *Renderer {
 	}
 
 	return &Renderer{
-		templates: templates,
-		version:   version,
+		templates:    templates,
+		version:      version,
+		idDisplayLen: idDisplayLen,
 	}
 }
 
@@ -187,11 +294,7 @@ func (r *Renderer) renderBlock(w http.ResponseWriter, status int, page, block st
 
 // renderError renders an error response with content negotiation.
 func (r *Renderer) renderError(w http.ResponseWriter, req *http.Request, err error) {
-	var mErr *errors.MossError
-	if !stderrors.As(err, &mErr) {
-		mErr = errors.NewInternal(err)
-	}
-
+	mErr := asMossError(err)
 	status := mErr.Status
 	message := mErr.Message
 
@@ -228,6 +331,15 @@ func (r *Renderer) renderError(w http.ResponseWriter, req *http.Request, err err
 	})
 }
 
+// asMossError unwraps err to a MossError, wrapping unknown errors as internal.
+func asMossError(err error) *errors.MossError {
+	var mErr *errors.MossError
+	if !stderrors.As(err, &mErr) {
+		mErr = errors.NewInternal(err)
+	}
+	return mErr
+}
+
 // renderJSON writes a JSON response.
 func renderJSON(w http.ResponseWriter, status int, data any) {
 	w.Header().Set("Content-Type", "application/json")
@@ -273,6 +385,23 @@ func formatChars(n int) string {
 	return result.String()
 }
 
+// displayName returns the capsule name if present, or the ID truncated to the configured length.
+func (r *Renderer) displayName(name *string, id string) string {
+	if name != nil && *name != "" {
+		return *name
+	}
+	return truncateID(id, r.idDisplayLen)
+}
+
+// truncateID shortens id to maxLen characters with a "..." suffix.
+// IDs that already fit are returned whole.
+func truncateID(id string, maxLen int) string {
+	if len(id) > maxLen {
+		return id[:maxLen] + "..."
+	}
+	return id
+}
+
 // deref dereferences a pointer, returning the zero value if nil.
 // Supports *string and *int64 (the pointer types used in templates).
 func deref(v any) any {
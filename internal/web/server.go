@@ -8,7 +8,6 @@ import (
 	"log"
 	"net"
 	"net/http"
-	"os"
 	"os/signal"
 	"strconv"
 	"syscall"
@@ -37,7 +36,7 @@ func NewServer(db *sql.DB, cfg *config.Config, version, bind string, port int) *
 		log.Fatalf("failed to create static sub-FS: %v", err)
 	}
 
-	renderer := NewRenderer(templateSub, version)
+	renderer := NewRenderer(templateSub, version, cfg.IDDisplayLen)
 
 	h := &Handlers{
 		db:       db,
@@ -48,21 +47,33 @@ func NewServer(db *sql.DB, cfg *config.Config, version, bind string, port int) *
 	mux := http.NewServeMux()
 
 	// Routes using Go 1.22+ pattern syntax
-	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, "/capsules", http.StatusFound)
-	})
+	mux.HandleFunc("GET /{$}", h.HandleIndex)
 	mux.HandleFunc("GET /capsules", h.HandleList)
 	mux.HandleFunc("GET /capsules/search", h.HandleSearch)
 	mux.HandleFunc("GET /capsules/inventory", h.HandleInventory)
+	mux.HandleFunc("GET /capsules/new", h.HandleNewForm)
+	mux.HandleFunc("POST /capsules", h.HandleCreate)
 	mux.HandleFunc("GET /capsules/{id}", h.HandleDetail)
+	mux.HandleFunc("GET /capsules/{id}/edit", h.HandleEditForm)
+	mux.HandleFunc("POST /capsules/{id}", h.HandleUpdate)
 	mux.HandleFunc("DELETE /capsules/{id}", h.HandleDelete)
 	mux.HandleFunc("POST /capsules/purge", h.HandlePurge)
+	mux.HandleFunc("POST /render", h.HandleRenderPreview)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("ok"))
+	})
 
 	// Static file server
 	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(staticSub)))
 
-	// Wrap with security headers
-	handler := securityHeaders(mux)
+	// Reject cross-site writes, rate limit per client IP (if configured), then
+	// wrap with security headers
+	handler := crossOriginGuard(mux)
+	if cfg.WebRateLimit.RequestsPerSecond > 0 {
+		handler = rateLimit(newRateLimiter(cfg.WebRateLimit), handler)
+	}
+	handler = securityHeaders(handler)
 
 	return &http.Server{
 		Addr:    net.JoinHostPort(bind, strconv.Itoa(port)),
@@ -70,6 +81,15 @@ func NewServer(db *sql.DB, cfg *config.Config, version, bind string, port int) *
 	}
 }
 
+// crossOriginGuard rejects state-changing requests (POST, DELETE, ...) that a
+// browser sent from another site with 403, so a page the user visits can't
+// forge capsule writes against the localhost UI. Browsers mark those with
+// Sec-Fetch-Site, or with an Origin that doesn't match Host; requests that
+// carry neither (curl, scripts) are not from a browser page and pass.
+func crossOriginGuard(next http.Handler) http.Handler {
+	return http.NewCrossOriginProtection().Handler(next)
+}
+
 // securityHeaders adds security-related HTTP headers to all responses.
 func securityHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -82,14 +102,15 @@ func securityHeaders(next http.Handler) http.Handler {
 
 // Run starts the HTTP server and handles graceful shutdown on SIGINT/SIGTERM.
 // The bind parameter is the original bind address (before port joining) used for warning checks.
-func Run(srv *http.Server, bind string) error {
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+// It returns once in-flight requests have drained, so the caller can close the database.
+func Run(srv *http.Server, bind string, shutdownTimeout time.Duration) error {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
 
-	errCh := make(chan error, 1)
-	go func() {
-		errCh <- srv.ListenAndServe()
-	}()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	log.Printf("Moss UI running at http://%s", srv.Addr)
 
@@ -97,13 +118,30 @@ func Run(srv *http.Server, bind string) error {
 		log.Printf("WARNING: Server is binding to all interfaces and may be accessible from the network")
 	}
 
+	return Serve(ctx, srv, ln, shutdownTimeout)
+}
+
+// Serve serves srv on ln until ctx is done, then stops accepting connections
+// and waits up to shutdownTimeout for in-flight requests to finish. Requests
+// still running after the timeout are closed and the deadline error is returned.
+func Serve(ctx context.Context, srv *http.Server, ln net.Listener, shutdownTimeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+
 	select {
 	case err := <-errCh:
 		return err
-	case <-sigCh:
-		log.Println("Shutting down...")
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		return srv.Shutdown(ctx)
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		_ = srv.Close()
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,176 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hpungsan/moss/internal/db"
+)
+
+// startSlowServer serves a handler that signals started, then sleeps for delay before replying.
+func startSlowServer(t *testing.T, delay, shutdownTimeout time.Duration) (url string, started <-chan struct{}, finished *atomic.Bool, cancel context.CancelFunc, serveErr <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+
+	startedCh := make(chan struct{})
+	finished = &atomic.Bool{}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(startedCh)
+		time.Sleep(delay)
+		_, _ = w.Write([]byte("done"))
+		finished.Store(true)
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Serve(ctx, srv, ln, shutdownTimeout)
+	}()
+	t.Cleanup(cancel)
+
+	return "http://" + ln.Addr().String() + "/", startedCh, finished, cancel, errCh
+}
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	url, started, finished, cancel, serveErr := startSlowServer(t, 300*time.Millisecond, 5*time.Second)
+
+	type result struct {
+		body string
+		err  error
+	}
+	respCh := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			respCh <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		respCh <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			t.Fatalf("Serve returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after shutdown")
+	}
+	if !finished.Load() {
+		t.Error("Serve returned before the in-flight request completed")
+	}
+
+	res := <-respCh
+	if res.err != nil {
+		t.Fatalf("in-flight request failed: %v", res.err)
+	}
+	if res.body != "done" {
+		t.Errorf("body = %q, want %q", res.body, "done")
+	}
+}
+
+func TestServe_ShutdownTimeout(t *testing.T) {
+	url, started, _, cancel, serveErr := startSlowServer(t, 2*time.Second, 50*time.Millisecond)
+
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Serve error = %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not give up after the shutdown timeout")
+	}
+}
+
+func TestNewServer_RejectsCrossOriginWrites(t *testing.T) {
+	h := setupTest(t)
+	srv := NewServer(h.db, h.cfg, "test", "127.0.0.1", 0)
+
+	tests := []struct {
+		name    string
+		capsule string
+		header  map[string]string
+		allowed bool
+	}{
+		{"cross-site fetch metadata", "forged-fetch", map[string]string{"Sec-Fetch-Site": "cross-site"}, false},
+		{"foreign origin", "forged-origin", map[string]string{"Origin": "https://evil.example"}, false},
+		{"same origin", "same-origin", map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://localhost:8080"}, true},
+		{"no browser headers", "scripted", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"workspace": {"default"}, "name": {tt.capsule}, "capsule_text": {validCapsuleText}}
+			req := httptest.NewRequest("POST", "http://localhost:8080/capsules", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(rec, req)
+
+			if tt.allowed && rec.Code != http.StatusSeeOther {
+				t.Errorf("status = %d, want 303; body: %s", rec.Code, rec.Body.String())
+			}
+			if !tt.allowed && rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want 403", rec.Code)
+			}
+			_, err := db.GetByName(context.Background(), h.db, "default", tt.capsule, false)
+			if stored := err == nil; stored != tt.allowed {
+				t.Errorf("capsule stored = %v, want %v", stored, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestNewServer_RejectsCrossOriginUpdate(t *testing.T) {
+	h := setupTest(t)
+	srv := NewServer(h.db, h.cfg, "test", "127.0.0.1", 0)
+	id := seedCapsule(t, h, "victim", "default")
+
+	forged := strings.ReplaceAll(validCapsuleText, "Using JWT for tokens.", "Forged decision.")
+	form := url.Values{"capsule_text": {forged}}
+	req := httptest.NewRequest("POST", "http://localhost:8080/capsules/"+id, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	req.Header.Set("Origin", "https://evil.example")
+	rec := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+	got, err := db.GetByID(context.Background(), h.db, id, false)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.CapsuleText != validCapsuleText {
+		t.Error("cross-origin update overwrote the capsule text")
+	}
+}
@@ -12,3 +12,15 @@ document.addEventListener("click", function (e) {
     history.back();
   }
 });
+
+// Swap 4xx responses from forms with data-error-target into that element
+// (htmx drops error responses by default), leaving the form in place.
+document.addEventListener("htmx:beforeSwap", function (e) {
+  var form = e.detail.elt && e.detail.elt.closest("[data-error-target]");
+  var status = e.detail.xhr.status;
+  if (form && status >= 400 && status < 500) {
+    e.detail.shouldSwap = true;
+    e.detail.isError = false;
+    e.detail.target = document.querySelector(form.getAttribute("data-error-target"));
+  }
+});